@@ -4,17 +4,19 @@ go 1.21
 
 require (
 	github.com/gin-gonic/gin v1.9.1
-	github.com/gorilla/websocket v1.5.1
-	github.com/redis/go-redis/v9 v9.3.0
-	github.com/lib/pq v1.10.9
-	github.com/joho/godotenv v1.4.0
+	github.com/go-playground/validator/v10 v10.16.0
 	github.com/golang-migrate/migrate/v4 v4.16.2
+	github.com/gorilla/websocket v1.5.1
 	github.com/jackc/pgx/v5 v5.5.0
+	github.com/joho/godotenv v1.4.0
+	github.com/lib/pq v1.10.9
+	github.com/redis/go-redis/v9 v9.3.0
 	github.com/shopspring/decimal v1.3.1
-	github.com/go-playground/validator/v10 v10.16.0
-	github.com/stretchr/testify v1.8.4
 	github.com/sirupsen/logrus v1.9.3
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/exp v0.0.0-20230321023759-10a507213a29
 	gonum.org/v1/gonum v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -47,6 +49,6 @@ require (
 	golang.org/x/sync v0.4.0 // indirect
 	golang.org/x/sys v0.13.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
+	golang.org/x/tools v0.9.1 // indirect
 	google.golang.org/protobuf v1.30.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
-)
\ No newline at end of file
+)
@@ -0,0 +1,633 @@
+package algorithms
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+)
+
+// DonchianBreakoutStrategy implements the Turtle channel breakout: enter when
+// the close clears the prior entry_period high or low, exit on the shorter
+// exit channel, and size each unit so a stop of stop_atr * N (ATR) risks
+// risk_per_trade of equity. There is no fixed target; the exit channel trails.
+type DonchianBreakoutStrategy struct {
+	EntryPeriod  int     `json:"entry_period"`
+	ExitPeriod   int     `json:"exit_period"`
+	ATRPeriod    int     `json:"atr_period"`
+	StopATR      float64 `json:"stop_atr"`
+	RiskPerTrade float64 `json:"risk_per_trade"`
+	MaxUnits     int     `json:"max_units"`
+	PyramidATR   float64 `json:"pyramid_atr"`
+}
+
+func NewDonchianBreakoutStrategy() *DonchianBreakoutStrategy {
+	return &DonchianBreakoutStrategy{
+		EntryPeriod:  20,
+		ExitPeriod:   10,
+		ATRPeriod:    20,
+		StopATR:      2.0,
+		RiskPerTrade: 0.01, // 1% of equity per unit
+		MaxUnits:     4,
+		PyramidATR:   0.5,
+	}
+}
+
+func (d *DonchianBreakoutStrategy) Name() string {
+	return "Donchian Breakout Strategy"
+}
+
+func (d *DonchianBreakoutStrategy) GetParameters() map[string]interface{} {
+	return map[string]interface{}{
+		"entry_period":   d.EntryPeriod,
+		"exit_period":    d.ExitPeriod,
+		"atr_period":     d.ATRPeriod,
+		"stop_atr":       d.StopATR,
+		"risk_per_trade": d.RiskPerTrade,
+		"max_units":      d.MaxUnits,
+		"pyramid_atr":    d.PyramidATR,
+	}
+}
+
+var donchianBreakoutSchema = []ParameterSpec{
+	{Name: "entry_period", Type: ParamInt, Min: 5, Max: 200, Description: "Breakout channel length in bars (20 for Turtle System 1, 55 for System 2)"},
+	{Name: "exit_period", Type: ParamInt, Min: 2, Max: 100, Description: "Exit channel length in bars"},
+	{Name: "atr_period", Type: ParamInt, Min: 2, Max: 100, Description: "ATR (N) lookback in bars"},
+	{Name: "stop_atr", Type: ParamFloat, Min: 0.5, Max: 10, Description: "Stop distance in multiples of N"},
+	{Name: "risk_per_trade", Type: ParamFloat, Min: 0.001, Max: 0.1, Description: "Fraction of equity risked per unit"},
+	{Name: "max_units", Type: ParamInt, Min: 1, Max: 10, Description: "Maximum units including pyramided additions"},
+	{Name: "pyramid_atr", Type: ParamFloat, Min: 0.1, Max: 5, Description: "Price move in multiples of N between unit additions"},
+}
+
+func (d *DonchianBreakoutStrategy) ParameterSchema() []ParameterSpec {
+	return withDefaults(donchianBreakoutSchema, NewDonchianBreakoutStrategy().GetParameters())
+}
+
+func (d *DonchianBreakoutStrategy) SetParameters(params map[string]interface{}) error {
+	updated := *d
+	err := applyParameters(donchianBreakoutSchema, params, map[string]interface{}{
+		"entry_period":   &updated.EntryPeriod,
+		"exit_period":    &updated.ExitPeriod,
+		"atr_period":     &updated.ATRPeriod,
+		"stop_atr":       &updated.StopATR,
+		"risk_per_trade": &updated.RiskPerTrade,
+		"max_units":      &updated.MaxUnits,
+		"pyramid_atr":    &updated.PyramidATR,
+	})
+	if err != nil {
+		return err
+	}
+	if err := requireLess("exit_period", float64(updated.ExitPeriod), "entry_period", float64(updated.EntryPeriod)); err != nil {
+		return err
+	}
+
+	*d = updated
+	return nil
+}
+
+func (d *DonchianBreakoutStrategy) Clone() TradingAlgorithm {
+	clone := *d
+	return &clone
+}
+
+func (d *DonchianBreakoutStrategy) Analyze(data []models.HistoricalData) (*models.TradingSignal, error) {
+	if len(data) < d.EntryPeriod+1 || len(data) < d.ATRPeriod+1 {
+		return nil, fmt.Errorf("insufficient data for Donchian breakout analysis")
+	}
+
+	bars := indicators.NewBars(data)
+	last := bars.Len() - 1
+
+	// Channels are built from the bars before the latest one so the latest
+	// close can break out of them
+	entryHigh, _, entryLow, err := indicators.DonchianChannelsFloat(bars.High[:last], bars.Low[:last], d.EntryPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("entry channel calculation failed: %v", err)
+	}
+	exitHigh, _, exitLow, err := indicators.DonchianChannelsFloat(bars.High[:last], bars.Low[:last], d.ExitPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("exit channel calculation failed: %v", err)
+	}
+	atr, err := indicators.ATRFloat(bars.High, bars.Low, bars.Close, d.ATRPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("ATR calculation failed: %v", err)
+	}
+
+	price := bars.Close[last]
+	upper, lower := entryHigh[len(entryHigh)-1], entryLow[len(entryLow)-1]
+	trailHigh, trailLow := exitHigh[len(exitHigh)-1], exitLow[len(exitLow)-1]
+	n := atr[len(atr)-1]
+
+	signal := &models.TradingSignal{
+		Symbol:    data[last].Symbol,
+		Price:     data[last].Close,
+		Algorithm: d.Name(),
+		CreatedAt: time.Now(),
+		Indicators: map[string]interface{}{
+			"entry_high": upper,
+			"entry_low":  lower,
+			"exit_high":  trailHigh,
+			"exit_low":   trailLow,
+			"n":          n,
+		},
+	}
+
+	direction := 0
+	switch {
+	case price > upper:
+		direction = 1
+	case price < lower:
+		direction = -1
+	}
+
+	var why explanation
+	if direction == 0 || n <= 0 {
+		signal.Type = "HOLD"
+		signal.Strength = decimal.NewFromFloat(0.3)
+		signal.Confidence = decimal.NewFromFloat(0.5)
+		signal.RiskLevel = "LOW"
+		signal.Indicators["exit_long"] = price < trailLow
+		signal.Indicators["exit_short"] = price > trailHigh
+		why.rule("no_breakout", "Close inside the entry channel", 0.3, 0.5,
+			condition("price", ">", price, upper, "entry_high"),
+			condition("price", "<", price, lower, "entry_low"),
+			condition("n", ">", n, 0, ""))
+	} else {
+		stopDistance := d.StopATR * n
+		breakout := upper
+		signal.Type = "BUY"
+		description, crossed := "Close above the entry channel high", condition("price", ">", price, upper, "entry_high")
+		if direction < 0 {
+			breakout = lower
+			signal.Type = "SELL"
+			description, crossed = "Close below the entry channel low", condition("price", "<", price, lower, "entry_low")
+		}
+		why.rule("channel_breakout", description, 0.5, 0.65, crossed)
+
+		// Breakouts that clear the channel by more ATRs are stronger
+		strength := math.Min(1, 0.5+math.Abs(price-breakout)/n/2)
+		why.adjust("breakout_distance", "Distance beyond the channel in ATRs", strength-0.5, 0,
+			condition("breakout_atrs", ">", math.Abs(price-breakout)/n, 0, ""))
+
+		signal.StopLoss = decimal.NewFromFloat(price - float64(direction)*stopDistance)
+		signal.Strength = decimal.NewFromFloat(strength)
+		signal.Confidence = decimal.NewFromFloat(0.65)
+		signal.RiskLevel = "MEDIUM"
+		if n/price > 0.03 {
+			signal.RiskLevel = "HIGH"
+		}
+
+		// Unit notional as a fraction of equity: risk_per_trade / stop distance in percent
+		signal.Indicators["unit_size"] = d.RiskPerTrade * price / stopDistance
+		signal.Indicators["max_units"] = d.MaxUnits
+		pyramid := make([]float64, 0, d.MaxUnits-1)
+		for k := 1; k < d.MaxUnits; k++ {
+			pyramid = append(pyramid, price+float64(direction*k)*d.PyramidATR*n)
+		}
+		signal.Indicators["pyramid_levels"] = pyramid
+		signal.Indicators["trailing_exit"] = trailLow
+		if direction < 0 {
+			signal.Indicators["trailing_exit"] = trailHigh
+		}
+	}
+	why.attach(signal)
+
+	signal.ExpirationTime = time.Now().Add(time.Hour * 24)
+	signal.TimeFrame = "1d"
+
+	return signal, nil
+}
+
+// VolatilitySqueezeStrategy trades the release of a volatility squeeze: the
+// Bollinger Bands contracting inside the Keltner Channels for at least
+// min_squeeze_bars, then expanding back out. Direction comes from close
+// momentum relative to the channel midpoint.
+type VolatilitySqueezeStrategy struct {
+	BBPeriod       int     `json:"bb_period"`
+	BBMultiplier   float64 `json:"bb_multiplier"`
+	KCPeriod       int     `json:"kc_period"`
+	KCMultiplier   float64 `json:"kc_multiplier"`
+	MinSqueezeBars int     `json:"min_squeeze_bars"`
+	ATRPeriod      int     `json:"atr_period"`
+	StopATR        float64 `json:"stop_atr"`
+	TargetATR      float64 `json:"target_atr"`
+}
+
+func NewVolatilitySqueezeStrategy() *VolatilitySqueezeStrategy {
+	return &VolatilitySqueezeStrategy{
+		BBPeriod:       20,
+		BBMultiplier:   2.0,
+		KCPeriod:       20,
+		KCMultiplier:   1.5,
+		MinSqueezeBars: 6,
+		ATRPeriod:      14,
+		StopATR:        1.5,
+		TargetATR:      3.0,
+	}
+}
+
+func (vs *VolatilitySqueezeStrategy) Name() string {
+	return "Volatility Squeeze Strategy"
+}
+
+func (vs *VolatilitySqueezeStrategy) GetParameters() map[string]interface{} {
+	return map[string]interface{}{
+		"bb_period":        vs.BBPeriod,
+		"bb_multiplier":    vs.BBMultiplier,
+		"kc_period":        vs.KCPeriod,
+		"kc_multiplier":    vs.KCMultiplier,
+		"min_squeeze_bars": vs.MinSqueezeBars,
+		"atr_period":       vs.ATRPeriod,
+		"stop_atr":         vs.StopATR,
+		"target_atr":       vs.TargetATR,
+	}
+}
+
+var volatilitySqueezeSchema = []ParameterSpec{
+	{Name: "bb_period", Type: ParamInt, Min: 5, Max: 100, Description: "Bollinger Bands period"},
+	{Name: "bb_multiplier", Type: ParamFloat, Min: 0.5, Max: 5, Description: "Bollinger Bands standard deviation multiplier"},
+	{Name: "kc_period", Type: ParamInt, Min: 5, Max: 100, Description: "Keltner Channels period"},
+	{Name: "kc_multiplier", Type: ParamFloat, Min: 0.5, Max: 5, Description: "Keltner Channels ATR multiplier"},
+	{Name: "min_squeeze_bars", Type: ParamInt, Min: 1, Max: 50, Description: "Bars the squeeze must last before a release counts"},
+	{Name: "atr_period", Type: ParamInt, Min: 2, Max: 100, Description: "ATR lookback for stops and targets"},
+	{Name: "stop_atr", Type: ParamFloat, Min: 0.5, Max: 10, Description: "Stop distance in ATRs"},
+	{Name: "target_atr", Type: ParamFloat, Min: 0.5, Max: 20, Description: "Target distance in ATRs"},
+}
+
+func (vs *VolatilitySqueezeStrategy) ParameterSchema() []ParameterSpec {
+	return withDefaults(volatilitySqueezeSchema, NewVolatilitySqueezeStrategy().GetParameters())
+}
+
+func (vs *VolatilitySqueezeStrategy) SetParameters(params map[string]interface{}) error {
+	updated := *vs
+	err := applyParameters(volatilitySqueezeSchema, params, map[string]interface{}{
+		"bb_period":        &updated.BBPeriod,
+		"bb_multiplier":    &updated.BBMultiplier,
+		"kc_period":        &updated.KCPeriod,
+		"kc_multiplier":    &updated.KCMultiplier,
+		"min_squeeze_bars": &updated.MinSqueezeBars,
+		"atr_period":       &updated.ATRPeriod,
+		"stop_atr":         &updated.StopATR,
+		"target_atr":       &updated.TargetATR,
+	})
+	if err != nil {
+		return err
+	}
+
+	*vs = updated
+	return nil
+}
+
+func (vs *VolatilitySqueezeStrategy) Clone() TradingAlgorithm {
+	clone := *vs
+	return &clone
+}
+
+func (vs *VolatilitySqueezeStrategy) Analyze(data []models.HistoricalData) (*models.TradingSignal, error) {
+	warmup := int(math.Max(float64(vs.BBPeriod), math.Max(float64(vs.KCPeriod), float64(vs.ATRPeriod))+1))
+	if len(data) < warmup+vs.MinSqueezeBars+1 {
+		return nil, fmt.Errorf("insufficient data for volatility squeeze analysis")
+	}
+
+	bars := indicators.NewBars(data)
+	last := bars.Len() - 1
+
+	bbUpper, _, bbLower, err := indicators.BollingerBandsFloat(bars.Close, vs.BBPeriod, vs.BBMultiplier)
+	if err != nil {
+		return nil, fmt.Errorf("Bollinger Bands calculation failed: %v", err)
+	}
+	kcUpper, _, kcLower, err := indicators.KeltnerChannelsFloat(bars.High, bars.Low, bars.Close, vs.KCPeriod, vs.KCMultiplier)
+	if err != nil {
+		return nil, fmt.Errorf("Keltner Channels calculation failed: %v", err)
+	}
+	atr, err := indicators.ATRFloat(bars.High, bars.Low, bars.Close, vs.ATRPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("ATR calculation failed: %v", err)
+	}
+	momentum, err := squeezeMomentum(bars, vs.KCPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("momentum calculation failed: %v", err)
+	}
+
+	// Squeeze state per bar, aligned to the end of the series
+	m := len(bbUpper)
+	if len(kcUpper) < m {
+		m = len(kcUpper)
+	}
+	squeezeOn := make([]bool, m)
+	for i := 0; i < m; i++ {
+		bu, bl := bbUpper[len(bbUpper)-m+i], bbLower[len(bbLower)-m+i]
+		ku, kl := kcUpper[len(kcUpper)-m+i], kcLower[len(kcLower)-m+i]
+		squeezeOn[i] = bu < ku && bl > kl
+	}
+
+	// Length of the squeeze run ending on the previous bar
+	priorRun := 0
+	for i := m - 2; i >= 0 && squeezeOn[i]; i-- {
+		priorRun++
+	}
+	currentRun := 0
+	if squeezeOn[m-1] {
+		currentRun = priorRun + 1
+	}
+	fired := !squeezeOn[m-1] && priorRun >= vs.MinSqueezeBars
+
+	price := bars.Close[last]
+	latestATR := atr[len(atr)-1]
+	mom := momentum[len(momentum)-1]
+	prevMom := momentum[len(momentum)-2]
+
+	signal := &models.TradingSignal{
+		Symbol:    data[last].Symbol,
+		Price:     data[last].Close,
+		Algorithm: vs.Name(),
+		CreatedAt: time.Now(),
+		Indicators: map[string]interface{}{
+			"squeeze_on":      squeezeOn[m-1],
+			"squeeze_bars":    currentRun,
+			"prior_squeeze":   priorRun,
+			"squeeze_fired":   fired,
+			"momentum":        mom,
+			"momentum_rising": mom > prevMom,
+			"bollinger_upper": bbUpper[len(bbUpper)-1],
+			"bollinger_lower": bbLower[len(bbLower)-1],
+			"keltner_upper":   kcUpper[len(kcUpper)-1],
+			"keltner_lower":   kcLower[len(kcLower)-1],
+			"atr":             latestATR,
+		},
+	}
+
+	direction := 0
+	if fired && mom > 0 {
+		direction = 1
+	} else if fired && mom < 0 {
+		direction = -1
+	}
+
+	var why explanation
+	if direction == 0 {
+		signal.Type = "HOLD"
+		signal.Strength = decimal.NewFromFloat(0.3)
+		signal.Confidence = decimal.NewFromFloat(0.5)
+		signal.RiskLevel = "LOW"
+		why.rule("no_release", "No squeeze released on this bar", 0.3, 0.5,
+			flag("squeeze_fired", fired),
+			condition("prior_squeeze", ">=", float64(priorRun), float64(vs.MinSqueezeBars), "min_squeeze_bars"))
+	} else {
+		signal.Type = "BUY"
+		momentumSide := condition("momentum", ">", mom, 0, "")
+		if direction < 0 {
+			signal.Type = "SELL"
+			momentumSide = condition("momentum", "<", mom, 0, "")
+		}
+		signal.StopLoss = decimal.NewFromFloat(price - float64(direction)*vs.StopATR*latestATR)
+		signal.TargetPrice = decimal.NewFromFloat(price + float64(direction)*vs.TargetATR*latestATR)
+		why.rule("squeeze_release", "Bollinger Bands expanded out of the Keltner Channels after a squeeze", 0.5, 0.6,
+			flag("squeeze_fired", fired),
+			condition("prior_squeeze", ">=", float64(priorRun), float64(vs.MinSqueezeBars), "min_squeeze_bars"),
+			momentumSide)
+
+		// Longer squeezes store more energy; momentum accelerating in the
+		// trade direction adds confidence
+		strength := math.Min(1, 0.5+float64(priorRun)/float64(4*vs.MinSqueezeBars))
+		why.adjust("squeeze_length", "Longer squeezes release more energy", strength-0.5, 0,
+			condition("prior_squeeze", ">", float64(priorRun), 0, ""))
+		signal.Strength = decimal.NewFromFloat(strength)
+		confidence := 0.6
+		if (direction > 0) == (mom > prevMom) {
+			confidence = 0.75
+			accelerating := condition("momentum", ">", mom, prevMom, "previous_momentum")
+			if direction < 0 {
+				accelerating = condition("momentum", "<", mom, prevMom, "previous_momentum")
+			}
+			why.adjust("momentum_acceleration", "Momentum accelerating in the trade direction", 0, 0.15, accelerating)
+		}
+		signal.Confidence = decimal.NewFromFloat(confidence)
+		signal.RiskLevel = "MEDIUM"
+	}
+	why.attach(signal)
+
+	signal.ExpirationTime = time.Now().Add(time.Hour * 24)
+	signal.TimeFrame = "1d"
+
+	return signal, nil
+}
+
+// squeezeMomentum is the close relative to the average of the Donchian
+// midpoint and the SMA over period bars
+func squeezeMomentum(bars *indicators.Bars, period int) ([]float64, error) {
+	_, donchianMid, _, err := indicators.DonchianChannelsFloat(bars.High, bars.Low, period)
+	if err != nil {
+		return nil, err
+	}
+	sma, err := indicators.SMAFloat(bars.Close, period)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := bars.Len() - len(sma)
+	momentum := make([]float64, len(sma))
+	for i := range sma {
+		momentum[i] = bars.Close[offset+i] - (donchianMid[i]+sma[i])/2
+	}
+	return momentum, nil
+}
+
+// OpeningRangeBreakoutStrategy trades intraday breakouts of the high or low
+// set in the first range_minutes of the current session. A session is the
+// run of bars sharing the latest bar's calendar day.
+type OpeningRangeBreakoutStrategy struct {
+	RangeMinutes int     `json:"range_minutes"`
+	ATRPeriod    int     `json:"atr_period"`
+	StopATR      float64 `json:"stop_atr"`
+	TargetATR    float64 `json:"target_atr"`
+}
+
+func NewOpeningRangeBreakoutStrategy() *OpeningRangeBreakoutStrategy {
+	return &OpeningRangeBreakoutStrategy{
+		RangeMinutes: 30,
+		ATRPeriod:    14,
+		StopATR:      1.0,
+		TargetATR:    2.0,
+	}
+}
+
+func (o *OpeningRangeBreakoutStrategy) Name() string {
+	return "Opening Range Breakout Strategy"
+}
+
+func (o *OpeningRangeBreakoutStrategy) GetParameters() map[string]interface{} {
+	return map[string]interface{}{
+		"range_minutes": o.RangeMinutes,
+		"atr_period":    o.ATRPeriod,
+		"stop_atr":      o.StopATR,
+		"target_atr":    o.TargetATR,
+	}
+}
+
+var openingRangeBreakoutSchema = []ParameterSpec{
+	{Name: "range_minutes", Type: ParamInt, Min: 1, Max: 240, Description: "Length of the opening range from the session's first bar"},
+	{Name: "atr_period", Type: ParamInt, Min: 2, Max: 100, Description: "ATR lookback in intraday bars"},
+	{Name: "stop_atr", Type: ParamFloat, Min: 0.1, Max: 10, Description: "Stop distance in ATRs"},
+	{Name: "target_atr", Type: ParamFloat, Min: 0.1, Max: 20, Description: "Target distance in ATRs"},
+}
+
+func (o *OpeningRangeBreakoutStrategy) ParameterSchema() []ParameterSpec {
+	return withDefaults(openingRangeBreakoutSchema, NewOpeningRangeBreakoutStrategy().GetParameters())
+}
+
+func (o *OpeningRangeBreakoutStrategy) SetParameters(params map[string]interface{}) error {
+	updated := *o
+	err := applyParameters(openingRangeBreakoutSchema, params, map[string]interface{}{
+		"range_minutes": &updated.RangeMinutes,
+		"atr_period":    &updated.ATRPeriod,
+		"stop_atr":      &updated.StopATR,
+		"target_atr":    &updated.TargetATR,
+	})
+	if err != nil {
+		return err
+	}
+
+	*o = updated
+	return nil
+}
+
+func (o *OpeningRangeBreakoutStrategy) Clone() TradingAlgorithm {
+	clone := *o
+	return &clone
+}
+
+func (o *OpeningRangeBreakoutStrategy) Analyze(data []models.HistoricalData) (*models.TradingSignal, error) {
+	if len(data) < o.ATRPeriod+2 {
+		return nil, fmt.Errorf("insufficient data for opening range breakout analysis")
+	}
+
+	last := len(data) - 1
+	interval := data[last].Date.Sub(data[last-1].Date)
+	if interval <= 0 || interval >= 24*time.Hour {
+		return nil, fmt.Errorf("opening range breakout needs intraday bars")
+	}
+
+	// Find the first bar of the latest session
+	year, month, day := data[last].Date.Date()
+	start := last
+	for start > 0 {
+		y, m, d := data[start-1].Date.Date()
+		if y != year || m != month || d != day {
+			break
+		}
+		start--
+	}
+
+	rangeEnd := data[start].Date.Add(time.Duration(o.RangeMinutes) * time.Minute)
+	end := start // First bar after the opening range
+	for end <= last && data[end].Date.Before(rangeEnd) {
+		end++
+	}
+
+	bars := indicators.NewBars(data)
+	atr, err := indicators.ATRFloat(bars.High, bars.Low, bars.Close, o.ATRPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("ATR calculation failed: %v", err)
+	}
+	latestATR := atr[len(atr)-1]
+
+	signal := &models.TradingSignal{
+		Symbol:    data[last].Symbol,
+		Price:     data[last].Close,
+		Algorithm: o.Name(),
+		CreatedAt: time.Now(),
+		Indicators: map[string]interface{}{
+			"session_start": data[start].Date,
+			"range_end":     rangeEnd,
+			"atr":           latestATR,
+		},
+		TimeFrame: fmt.Sprintf("%dm", int(interval.Minutes())),
+	}
+
+	if end > last {
+		// Still inside the opening range
+		signal.Type = "HOLD"
+		signal.Strength = decimal.NewFromFloat(0.2)
+		signal.Confidence = decimal.NewFromFloat(0.5)
+		signal.RiskLevel = "LOW"
+		signal.Indicators["range_forming"] = true
+		signal.ExpirationTime = rangeEnd
+		var why explanation
+		why.rule("range_forming", fmt.Sprintf("Opening range still forming until %s", rangeEnd.Format("15:04")), 0.2, 0.5)
+		why.attach(signal)
+		return signal, nil
+	}
+
+	rangeHigh, rangeLow := bars.High[start], bars.Low[start]
+	for i := start + 1; i < end; i++ {
+		rangeHigh = math.Max(rangeHigh, bars.High[i])
+		rangeLow = math.Min(rangeLow, bars.Low[i])
+	}
+	signal.Indicators["range_high"] = rangeHigh
+	signal.Indicators["range_low"] = rangeLow
+	signal.Indicators["range_size"] = rangeHigh - rangeLow
+
+	price := bars.Close[last]
+	direction := 0
+	switch {
+	case price > rangeHigh:
+		direction = 1
+	case price < rangeLow:
+		direction = -1
+	}
+
+	var why explanation
+	if direction == 0 {
+		signal.Type = "HOLD"
+		signal.Strength = decimal.NewFromFloat(0.3)
+		signal.Confidence = decimal.NewFromFloat(0.5)
+		signal.RiskLevel = "LOW"
+		why.rule("inside_range", "Close inside the opening range", 0.3, 0.5,
+			condition("price", ">", price, rangeHigh, "range_high"),
+			condition("price", "<", price, rangeLow, "range_low"))
+	} else {
+		// The first close beyond the range is the cleanest entry; later
+		// bars are continuation
+		first := true
+		for i := end; i < last; i++ {
+			if (direction > 0 && bars.Close[i] > rangeHigh) || (direction < 0 && bars.Close[i] < rangeLow) {
+				first = false
+				break
+			}
+		}
+		signal.Indicators["first_breakout"] = first
+
+		signal.Type = "BUY"
+		if direction < 0 {
+			signal.Type = "SELL"
+		}
+		signal.StopLoss = decimal.NewFromFloat(price - float64(direction)*o.StopATR*latestATR)
+		signal.TargetPrice = decimal.NewFromFloat(price + float64(direction)*o.TargetATR*latestATR)
+
+		crossed := condition("price", ">", price, rangeHigh, "range_high")
+		if direction < 0 {
+			crossed = condition("price", "<", price, rangeLow, "range_low")
+		}
+		strength, confidence := 0.7, 0.7
+		if first {
+			why.rule("first_breakout", "First close beyond the opening range", strength, confidence, crossed, flag("first_breakout", first))
+		} else {
+			strength, confidence = 0.5, 0.55
+			why.rule("continuation", "Close beyond the opening range after an earlier breakout", strength, confidence, crossed, flag("first_breakout", first))
+		}
+		signal.Strength = decimal.NewFromFloat(strength)
+		signal.Confidence = decimal.NewFromFloat(confidence)
+		signal.RiskLevel = "MEDIUM"
+		if latestATR > 0 && (rangeHigh-rangeLow) > 3*latestATR {
+			signal.RiskLevel = "HIGH" // A wide range puts the stop far from the breakout level
+		}
+	}
+	why.attach(signal)
+
+	signal.ExpirationTime = time.Now().Add(time.Hour)
+	return signal, nil
+}
@@ -0,0 +1,271 @@
+package algorithms
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/models"
+)
+
+// candles builds bars step apart that open at the previous close and
+// reach spread beyond the body on each side
+func candles(start time.Time, step time.Duration, closes []float64, spread float64) []models.HistoricalData {
+	data := make([]models.HistoricalData, len(closes))
+	open := closes[0]
+	for i, c := range closes {
+		data[i] = models.HistoricalData{
+			Symbol: "TEST",
+			Date:   start.Add(time.Duration(i) * step),
+			Open:   decimal.NewFromFloat(open),
+			High:   decimal.NewFromFloat(math.Max(open, c) + spread),
+			Low:    decimal.NewFromFloat(math.Min(open, c) - spread),
+			Close:  decimal.NewFromFloat(c),
+			Volume: 1000,
+		}
+		open = c
+	}
+	return data
+}
+
+// sawtooth alternates between a-1 and a+1 for n bars, then appends last
+func sawtooth(a float64, n int, last ...float64) []float64 {
+	closes := make([]float64, n, n+len(last))
+	for i := range closes {
+		closes[i] = a - 1 + 2*float64(i%2)
+	}
+	return append(closes, last...)
+}
+
+var breakoutStart = time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+
+func TestDonchianBreakout(t *testing.T) {
+	d := NewDonchianBreakoutStrategy()
+
+	tests := []struct {
+		name      string
+		last      float64
+		want      string
+		direction float64
+	}{
+		{"upside breakout", 110, "BUY", 1},
+		{"downside breakout", 90, "SELL", -1},
+		{"inside the channel", 100.5, "HOLD", 0},
+	}
+	for _, tt := range tests {
+		signal, err := d.Analyze(candles(breakoutStart, 24*time.Hour, sawtooth(100, 30, tt.last), 0.5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signal.Type != tt.want {
+			t.Fatalf("%s: signal %s, want %s", tt.name, signal.Type, tt.want)
+		}
+		// The channels come from the sawtooth before the latest bar
+		if signal.Indicators["entry_high"] != 101.5 || signal.Indicators["entry_low"] != 98.5 {
+			t.Fatalf("%s: entry channel %v to %v, want 98.5 to 101.5", tt.name, signal.Indicators["entry_low"], signal.Indicators["entry_high"])
+		}
+		if tt.direction == 0 {
+			if !signal.StopLoss.IsZero() || signal.Indicators["exit_long"] != false || signal.Indicators["exit_short"] != false {
+				t.Fatalf("%s: %+v, want no stop and no exit", tt.name, signal)
+			}
+			continue
+		}
+
+		n := signal.Indicators["n"].(float64)
+		if stop := signal.StopLoss.InexactFloat64(); math.Abs(stop-(tt.last-tt.direction*2*n)) > 1e-9 {
+			t.Fatalf("%s: stop %v, want 2N (%v) from %v", tt.name, stop, n, tt.last)
+		}
+		if unit := signal.Indicators["unit_size"].(float64); math.Abs(unit-0.01*tt.last/(2*n)) > 1e-9 {
+			t.Fatalf("%s: unit size %v, want 1%% risk over a 2N stop", tt.name, unit)
+		}
+		pyramid := signal.Indicators["pyramid_levels"].([]float64)
+		if len(pyramid) != 3 || math.Abs(pyramid[2]-(tt.last+tt.direction*1.5*n)) > 1e-9 {
+			t.Fatalf("%s: pyramid %v, want 3 additions every N/2", tt.name, pyramid)
+		}
+		wantExit := 98.5
+		if tt.direction < 0 {
+			wantExit = 101.5
+		}
+		if signal.Indicators["trailing_exit"] != wantExit {
+			t.Fatalf("%s: trailing exit %v, want %v", tt.name, signal.Indicators["trailing_exit"], wantExit)
+		}
+		if strength := signal.Strength.InexactFloat64(); strength <= 0.5 || strength > 1 {
+			t.Fatalf("%s: strength %v, want above 0.5 for a clear breakout", tt.name, strength)
+		}
+	}
+
+	// A close below the exit channel but inside the entry channel flags a
+	// long exit without a new entry
+	d.EntryPeriod = 25
+	closes := append(sawtooth(92, 15), sawtooth(100, 12, 97)...)
+	signal, err := d.Analyze(candles(breakoutStart, 24*time.Hour, closes, 0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signal.Type != "HOLD" || signal.Indicators["exit_long"] != true {
+		t.Fatalf("signal %s exit_long %v, want HOLD with a long exit", signal.Type, signal.Indicators["exit_long"])
+	}
+
+	if _, err := d.Analyze(candles(breakoutStart, 24*time.Hour, sawtooth(100, 25), 0.5)); err == nil {
+		t.Fatal("25 bars are too few for a 25-bar channel")
+	}
+}
+
+// squeezeCloses alternates between 100 and 100.2
+func squeezeCloses(n int) []float64 {
+	closes := make([]float64, n)
+	for i := range closes {
+		closes[i] = 100 + 0.2*float64(i%2)
+	}
+	return closes
+}
+
+func TestVolatilitySqueezeRelease(t *testing.T) {
+	vs := NewVolatilitySqueezeStrategy()
+
+	// Tight closes with wide ranges keep the bands inside the channels; a
+	// large close then blows the bands out
+	tests := []struct {
+		name string
+		last float64
+		want string
+	}{
+		{"upside release", 130, "BUY"},
+		{"downside release", 70, "SELL"},
+		{"still squeezed", 100.1, "HOLD"},
+	}
+	for _, tt := range tests {
+		closes := append(squeezeCloses(40), tt.last)
+		signal, err := vs.Analyze(candles(breakoutStart, 24*time.Hour, closes, 2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signal.Type != tt.want {
+			t.Fatalf("%s: signal %s (%v), want %s", tt.name, signal.Type, signal.Indicators, tt.want)
+		}
+		prior := signal.Indicators["prior_squeeze"].(int)
+		if prior < vs.MinSqueezeBars {
+			t.Fatalf("%s: prior squeeze %d bars, want at least %d", tt.name, prior, vs.MinSqueezeBars)
+		}
+		if tt.want == "HOLD" {
+			if signal.Indicators["squeeze_on"] != true || signal.Indicators["squeeze_bars"] != prior+1 {
+				t.Fatalf("%s: %v, want the squeeze still running", tt.name, signal.Indicators)
+			}
+			continue
+		}
+
+		if signal.Indicators["squeeze_fired"] != true || signal.Indicators["squeeze_on"] != false {
+			t.Fatalf("%s: %v, want the squeeze fired", tt.name, signal.Indicators)
+		}
+		atr := signal.Indicators["atr"].(float64)
+		direction := 1.0
+		if tt.want == "SELL" {
+			direction = -1
+		}
+		if math.Abs(signal.StopLoss.InexactFloat64()-(tt.last-direction*1.5*atr)) > 1e-9 ||
+			math.Abs(signal.TargetPrice.InexactFloat64()-(tt.last+direction*3*atr)) > 1e-9 {
+			t.Fatalf("%s: stop %v target %v, want 1.5 and 3 ATRs (%v) from %v", tt.name, signal.StopLoss, signal.TargetPrice, atr, tt.last)
+		}
+		wantStrength := math.Min(1, 0.5+float64(prior)/float64(4*vs.MinSqueezeBars))
+		if math.Abs(signal.Strength.InexactFloat64()-wantStrength) > 1e-9 {
+			t.Fatalf("%s: strength %v, want %v", tt.name, signal.Strength, wantStrength)
+		}
+	}
+
+	// A release after too short a squeeze doesn't count: the bands stay
+	// wide while a steep rally is in their window
+	vs.MinSqueezeBars = 20
+	closes := make([]float64, 25)
+	for i := range closes {
+		closes[i] = 40 + 5*float64(i)
+	}
+	closes = append(closes, squeezeCloses(30)...)
+	signal, err := vs.Analyze(candles(breakoutStart, 24*time.Hour, append(closes, 200), 0.1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prior := signal.Indicators["prior_squeeze"].(int); signal.Type != "HOLD" || signal.Indicators["squeeze_on"] != false || prior == 0 || prior >= 20 {
+		t.Fatalf("signal %s after a %d-bar squeeze, want HOLD on the release of a short squeeze", signal.Type, prior)
+	}
+
+	if _, err := vs.Analyze(candles(breakoutStart, 24*time.Hour, squeezeCloses(20), 2)); err == nil {
+		t.Fatal("20 bars are too few to detect a squeeze")
+	}
+}
+
+// session returns 5-minute bars from 09:30 on the day
+func session(day time.Time, closes []float64) []models.HistoricalData {
+	return candles(day.Add(9*time.Hour+30*time.Minute), 5*time.Minute, closes, 0.5)
+}
+
+func TestOpeningRangeBreakout(t *testing.T) {
+	o := NewOpeningRangeBreakoutStrategy()
+	previous := session(breakoutStart, sawtooth(100, 78))
+	today := breakoutStart.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name   string
+		closes []float64
+		want   string
+		first  bool
+	}{
+		{"upside breakout", sawtooth(100, 10, 102), "BUY", true},
+		{"downside breakout", sawtooth(100, 10, 97), "SELL", true},
+		{"continuation", sawtooth(100, 10, 102, 101, 103), "BUY", false},
+		{"inside the range", sawtooth(100, 10, 100.5), "HOLD", false},
+	}
+	for _, tt := range tests {
+		data := append(append([]models.HistoricalData(nil), previous...), session(today, tt.closes)...)
+		signal, err := o.Analyze(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signal.Type != tt.want || signal.TimeFrame != "5m" {
+			t.Fatalf("%s: signal %s on %s, want %s on 5m", tt.name, signal.Type, signal.TimeFrame, tt.want)
+		}
+		// The range is today's first six bars only
+		if start := signal.Indicators["session_start"].(time.Time); !start.Equal(today.Add(9*time.Hour + 30*time.Minute)) {
+			t.Fatalf("%s: session starts %v, want today 09:30", tt.name, start)
+		}
+		if signal.Indicators["range_high"] != 101.5 || signal.Indicators["range_low"] != 98.5 {
+			t.Fatalf("%s: range %v to %v, want 98.5 to 101.5", tt.name, signal.Indicators["range_low"], signal.Indicators["range_high"])
+		}
+		if tt.want == "HOLD" {
+			continue
+		}
+
+		if signal.Indicators["first_breakout"] != tt.first {
+			t.Fatalf("%s: first breakout %v, want %v", tt.name, signal.Indicators["first_breakout"], tt.first)
+		}
+		price := tt.closes[len(tt.closes)-1]
+		atr := signal.Indicators["atr"].(float64)
+		direction := 1.0
+		if tt.want == "SELL" {
+			direction = -1
+		}
+		if math.Abs(signal.StopLoss.InexactFloat64()-(price-direction*atr)) > 1e-9 ||
+			math.Abs(signal.TargetPrice.InexactFloat64()-(price+direction*2*atr)) > 1e-9 {
+			t.Fatalf("%s: stop %v target %v, want 1 and 2 ATRs (%v) from %v", tt.name, signal.StopLoss, signal.TargetPrice, atr, price)
+		}
+	}
+
+	// Three bars in, the range is still forming
+	data := append(append([]models.HistoricalData(nil), previous...), session(today, []float64{100, 103, 104})...)
+	signal, err := o.Analyze(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signal.Type != "HOLD" || signal.Indicators["range_forming"] != true || !signal.ExpirationTime.Equal(today.Add(10*time.Hour)) {
+		t.Fatalf("signal %s expiring %v, want HOLD while the range forms until 10:00", signal.Type, signal.ExpirationTime)
+	}
+
+	_, err = o.Analyze(candles(breakoutStart, 24*time.Hour, sawtooth(100, 30), 0.5))
+	if err == nil || !strings.Contains(err.Error(), "intraday") {
+		t.Fatalf("daily bars error = %v, want intraday bars required", err)
+	}
+	if _, err := o.Analyze(session(today, sawtooth(100, 10))); err == nil {
+		t.Fatal("10 bars are too few for a 14-bar ATR")
+	}
+}
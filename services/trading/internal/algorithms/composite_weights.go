@@ -0,0 +1,135 @@
+package algorithms
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/stat"
+	"trading-service/internal/models"
+)
+
+// Composite weighting modes
+const (
+	WeightingFixed   = 0
+	WeightingHitRate = 1
+	WeightingSharpe  = 2
+)
+
+var weightingNames = map[int]string{
+	WeightingFixed:   "fixed",
+	WeightingHitRate: "hit_rate",
+	WeightingSharpe:  "sharpe",
+}
+
+// StrategyPerformance is a sub-strategy's realized record over the adaptive
+// window. Each directional signal is judged by the close-to-close return
+// over the following AdaptiveHorizon bars.
+type StrategyPerformance struct {
+	Signals    int     `json:"signals"`    // Directional (BUY/SELL) signals scored
+	HitRate    float64 `json:"hit_rate"`   // Share of signals whose direction matched the return
+	Sharpe     float64 `json:"sharpe"`     // Mean over standard deviation of signed returns, per signal
+	Multiplier float64 `json:"multiplier"` // Factor applied to the base weight
+	Scored     bool    `json:"scored"`     // False when there were fewer than MinSignals signals
+}
+
+// effectiveWeights returns the weight for each member, aligned with
+// cs.Members. With fixed weighting they are the base weights. Otherwise each
+// base weight is scaled by the member's edge: hit rate above 50% or positive
+// Sharpe. Members with too few signals get the average multiplier of those
+// that were scored, and the base weights are used if no member shows an edge.
+func (cs *CompositeStrategy) effectiveWeights(data []models.HistoricalData) ([]float64, map[string]StrategyPerformance) {
+	base := make([]float64, len(cs.Members))
+	for i, member := range cs.Members {
+		base[i] = member.Weight
+	}
+	if cs.Weighting == WeightingFixed {
+		return base, nil
+	}
+
+	performance := make(map[string]StrategyPerformance, len(cs.Members))
+	records := make([]StrategyPerformance, len(cs.Members))
+	var scoredTotal float64
+	var scored int
+	for i, member := range cs.Members {
+		record := cs.scoreStrategy(member.Strategy, data)
+		if record.Scored {
+			edge := record.HitRate - 0.5
+			if cs.Weighting == WeightingSharpe {
+				edge = record.Sharpe
+			}
+			record.Multiplier = math.Max(edge, 0)
+			scoredTotal += record.Multiplier
+			scored++
+		}
+		records[i] = record
+	}
+
+	if scored == 0 || scoredTotal == 0 {
+		for i, member := range cs.Members {
+			records[i].Multiplier = 1
+			performance[member.Key] = records[i]
+		}
+		return base, performance
+	}
+
+	neutral := scoredTotal / float64(scored)
+	weights := make([]float64, len(cs.Members))
+	for i, member := range cs.Members {
+		if !records[i].Scored {
+			records[i].Multiplier = neutral
+		}
+		weights[i] = base[i] * records[i].Multiplier
+		performance[member.Key] = records[i]
+	}
+	return weights, performance
+}
+
+// scoreStrategy replays the strategy over the trailing window, using only
+// the bars available at each point, and judges its directional signals
+func (cs *CompositeStrategy) scoreStrategy(strategy TradingAlgorithm, data []models.HistoricalData) StrategyPerformance {
+	var record StrategyPerformance
+	var signedReturns []float64
+	var hits int
+
+	last := len(data) - 1 - cs.AdaptiveHorizon
+	for t := last; t >= 0 && t > last-cs.AdaptiveWindow; t -= cs.AdaptiveStep {
+		signal, err := strategy.Analyze(data[:t+1])
+		if err != nil {
+			continue // Not enough history this far back
+		}
+
+		var direction float64
+		switch signal.Type {
+		case "BUY":
+			direction = 1
+		case "SELL":
+			direction = -1
+		default:
+			continue
+		}
+
+		entry, _ := data[t].Close.Float64()
+		exit, _ := data[t+cs.AdaptiveHorizon].Close.Float64()
+		if entry <= 0 {
+			continue
+		}
+		signed := direction * (exit/entry - 1)
+		signedReturns = append(signedReturns, signed)
+		if signed > 0 {
+			hits++
+		}
+	}
+
+	record.Signals = len(signedReturns)
+	if record.Signals == 0 {
+		return record
+	}
+	record.HitRate = float64(hits) / float64(record.Signals)
+	if record.Signals > 1 {
+		mean, std := stat.MeanStdDev(signedReturns, nil)
+		if std > 0 {
+			record.Sharpe = mean / std
+		}
+	}
+	record.Scored = record.Signals >= cs.MinSignals
+	return record
+}
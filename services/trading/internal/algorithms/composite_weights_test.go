@@ -0,0 +1,177 @@
+package algorithms
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/models"
+)
+
+// stubStrategy always returns the same signal type, or fails
+type stubStrategy struct {
+	name       string
+	signalType string
+	confidence float64
+	err        error
+}
+
+func (s *stubStrategy) Name() string { return s.name }
+
+func (s *stubStrategy) Analyze(data []models.HistoricalData) (*models.TradingSignal, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.TradingSignal{
+		Symbol:     data[len(data)-1].Symbol,
+		Type:       s.signalType,
+		Strength:   decimal.NewFromFloat(s.confidence),
+		Confidence: decimal.NewFromFloat(s.confidence),
+		Algorithm:  s.name,
+		RiskLevel:  "LOW",
+	}, nil
+}
+
+func (s *stubStrategy) GetParameters() map[string]interface{}             { return map[string]interface{}{} }
+func (s *stubStrategy) SetParameters(params map[string]interface{}) error { return nil }
+func (s *stubStrategy) ParameterSchema() []ParameterSpec                  { return nil }
+func (s *stubStrategy) Clone() TradingAlgorithm {
+	clone := *s
+	return &clone
+}
+
+// zigzagBars grows 1% a bar with every odd bar 2% higher, so returns over
+// an odd horizon alternate between two known values
+func zigzagBars(n int) []models.HistoricalData {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := make([]models.HistoricalData, n)
+	for i := range data {
+		price := 100 * math.Pow(1.01, float64(i)) * (1 + 0.02*float64(i%2))
+		data[i] = models.HistoricalData{
+			Symbol: "TEST",
+			Date:   start.AddDate(0, 0, i),
+			Open:   decimal.NewFromFloat(price),
+			High:   decimal.NewFromFloat(price),
+			Low:    decimal.NewFromFloat(price),
+			Close:  decimal.NewFromFloat(price),
+		}
+	}
+	return data
+}
+
+func compositeWeights(t *testing.T, signal *models.TradingSignal) map[string]float64 {
+	t.Helper()
+	weights, ok := signal.Indicators["weights"].(map[string]float64)
+	if !ok {
+		t.Fatalf("weights indicator = %#v", signal.Indicators["weights"])
+	}
+	return weights
+}
+
+func TestCompositeWeightsSkipFailedMember(t *testing.T) {
+	cs := NewCompositeStrategy()
+	cs.Members = []CompositeMember{
+		{Key: "buyer", Strategy: &stubStrategy{name: "buyer", signalType: "BUY", confidence: 1}, Weight: 0.5},
+		{Key: "broken", Strategy: &stubStrategy{name: "broken", err: errors.New("insufficient data")}, Weight: 0.3},
+		{Key: "seller", Strategy: &stubStrategy{name: "seller", signalType: "SELL", confidence: 1}, Weight: 0.2},
+	}
+
+	signal, err := cs.Analyze(zigzagBars(50))
+	if err != nil {
+		t.Fatal(err)
+	}
+	weights := compositeWeights(t, signal)
+	if _, ok := weights["broken"]; ok || len(weights) != 2 {
+		t.Fatalf("weights = %v, want only the members that produced a signal", weights)
+	}
+	// The seller keeps its own weight rather than inheriting the failed member's
+	if math.Abs(weights["buyer"]-0.5/0.7) > 1e-9 || math.Abs(weights["seller"]-0.2/0.7) > 1e-9 {
+		t.Fatalf("weights = %v, want buyer 5/7 and seller 2/7", weights)
+	}
+	if signal.Type != "BUY" || math.Abs(signal.Strength.InexactFloat64()-0.5/0.7) > 1e-9 {
+		t.Fatalf("signal %s strength %v, want BUY with strength 5/7", signal.Type, signal.Strength)
+	}
+	if len(signal.Rationale.Components) != 2 || signal.Rationale.Components[1].Key != "seller" {
+		t.Fatalf("components = %+v, want buyer and seller", signal.Rationale.Components)
+	}
+
+	cs.Members = cs.Members[1:2]
+	if _, err := cs.Analyze(zigzagBars(50)); err == nil {
+		t.Fatal("a composite whose only member fails should fail")
+	}
+}
+
+func adaptiveComposite(weighting int) *CompositeStrategy {
+	cs := NewCompositeStrategy()
+	cs.Weighting = weighting
+	cs.Members = []CompositeMember{
+		{Key: "bull", Strategy: &stubStrategy{name: "bull", signalType: "BUY", confidence: 0.6}, Weight: 1},
+		{Key: "bear", Strategy: &stubStrategy{name: "bear", signalType: "SELL", confidence: 0.6}, Weight: 1},
+		{Key: "idle", Strategy: &stubStrategy{name: "idle", signalType: "HOLD", confidence: 0.6}, Weight: 1},
+	}
+	return cs
+}
+
+func TestCompositeHitRateWeighting(t *testing.T) {
+	cs := adaptiveComposite(WeightingHitRate)
+	data := zigzagBars(200)
+
+	weights, performance := cs.effectiveWeights(data)
+	bull, bear, idle := performance["bull"], performance["bear"], performance["idle"]
+	// 120 bars scored every 5 bars
+	if bull.Signals != 24 || !bull.Scored || bull.HitRate != 1 || bear.HitRate != 0 {
+		t.Fatalf("bull %+v bear %+v, want 24 signals each, always right and always wrong", bull, bear)
+	}
+	if idle.Scored || idle.Signals != 0 {
+		t.Fatalf("idle %+v, want unscored without directional signals", idle)
+	}
+	// Edges over 50%: bull 0.5, bear none; idle takes their average
+	if bull.Multiplier != 0.5 || bear.Multiplier != 0 || idle.Multiplier != 0.25 {
+		t.Fatalf("multipliers %v %v %v, want 0.5, 0 and 0.25", bull.Multiplier, bear.Multiplier, idle.Multiplier)
+	}
+	if weights[0] != 0.5 || weights[1] != 0 || weights[2] != 0.25 {
+		t.Fatalf("weights = %v, aligned with members, want [0.5 0 0.25]", weights)
+	}
+
+	signal, err := cs.Analyze(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := compositeWeights(t, signal)
+	if math.Abs(used["bull"]-2.0/3) > 1e-9 || used["bear"] != 0 || math.Abs(used["idle"]-1.0/3) > 1e-9 {
+		t.Fatalf("used weights = %v, want bull 2/3, bear 0, idle 1/3", used)
+	}
+	if signal.Type != "BUY" || signal.Indicators["weighting"] != "hit_rate" {
+		t.Fatalf("signal %s weighting %v, want a hit-rate weighted BUY", signal.Type, signal.Indicators["weighting"])
+	}
+}
+
+func TestCompositeSharpeWeighting(t *testing.T) {
+	cs := adaptiveComposite(WeightingSharpe)
+
+	// Signals are scored from odd and even bars in turn, so 5-bar returns
+	// alternate between a and b
+	a := math.Pow(1.01, 5)/1.02 - 1
+	b := math.Pow(1.01, 5)*1.02 - 1
+	mean := (a + b) / 2
+	std := math.Sqrt(24 * math.Pow((a-b)/2, 2) / 23)
+	wantSharpe := mean / std
+
+	weights, performance := cs.effectiveWeights(zigzagBars(200))
+	bull, bear := performance["bull"], performance["bear"]
+	if math.Abs(bull.Sharpe-wantSharpe) > 1e-9 || math.Abs(bear.Sharpe+wantSharpe) > 1e-9 {
+		t.Fatalf("Sharpe bull %v bear %v, want ±%v", bull.Sharpe, bear.Sharpe, wantSharpe)
+	}
+	if math.Abs(weights[0]-wantSharpe) > 1e-9 || weights[1] != 0 || math.Abs(weights[2]-wantSharpe/2) > 1e-9 {
+		t.Fatalf("weights = %v, want [%v 0 %v]", weights, wantSharpe, wantSharpe/2)
+	}
+
+	// Without an edge anywhere the base weights are kept
+	cs.Members = cs.Members[1:]
+	weights, performance = cs.effectiveWeights(zigzagBars(200))
+	if weights[0] != 1 || weights[1] != 1 || performance["bear"].Multiplier != 1 {
+		t.Fatalf("weights = %v performance %+v, want the base weights", weights, performance)
+	}
+}
@@ -0,0 +1,108 @@
+package algorithms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Definition formats
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// ParseStrategyDefinition decodes a JSON or YAML definition. Unknown fields
+// are rejected so typos such as "stoploss" don't silently disable a rule.
+func ParseStrategyDefinition(data []byte, format string) (*StrategyDefinition, error) {
+	var def StrategyDefinition
+
+	switch format {
+	case FormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&def); err != nil {
+			return nil, &DefinitionError{Problems: []string{fmt.Sprintf("invalid JSON: %v", err)}}
+		}
+	case FormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&def); err != nil {
+			return nil, &DefinitionError{Problems: []string{fmt.Sprintf("invalid YAML: %v", err)}}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported definition format '%s'", format)
+	}
+
+	return &def, nil
+}
+
+// FormatFromPath returns the definition format implied by a file extension
+func FormatFromPath(path string) (string, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, true
+	case ".yaml", ".yml":
+		return FormatYAML, true
+	}
+	return "", false
+}
+
+// LoadStrategyDefinitions builds rule strategies from every .json, .yaml and
+// .yml file in dir. A bad file doesn't stop the others from loading; its
+// error is returned alongside the strategies that did load.
+func LoadStrategyDefinitions(dir string) ([]*RuleStrategy, []error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, []error{fmt.Errorf("failed to read strategies directory: %v", err)}
+	}
+
+	var strategies []*RuleStrategy
+	var errs []error
+	seen := make(map[string]string)
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		format, ok := FormatFromPath(entry.Name())
+		if !ok {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", path, err))
+			continue
+		}
+
+		def, err := ParseStrategyDefinition(data, format)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", path, err))
+			continue
+		}
+
+		strategy, err := NewRuleStrategy(*def)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", path, err))
+			continue
+		}
+
+		if other, dup := seen[strategy.ID()]; dup {
+			errs = append(errs, fmt.Errorf("%s: strategy id '%s' already defined in %s", path, strategy.ID(), other))
+			continue
+		}
+		seen[strategy.ID()] = path
+		strategies = append(strategies, strategy)
+	}
+
+	sort.Slice(strategies, func(i, j int) bool { return strategies[i].ID() < strategies[j].ID() })
+	return strategies, errs
+}
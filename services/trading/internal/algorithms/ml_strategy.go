@@ -0,0 +1,169 @@
+package algorithms
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/indicators"
+	"trading-service/internal/ml"
+	"trading-service/internal/models"
+)
+
+// MLStrategy serves a trained ml.Model. The model's calibrated probability
+// of a positive forward return drives the signal and becomes its Confidence.
+// Without a model Analyze returns an error, so the strategy is skipped.
+type MLStrategy struct {
+	BuyThreshold  float64 `json:"buy_threshold"`
+	SellThreshold float64 `json:"sell_threshold"`
+	ATRPeriod     int     `json:"atr_period"`
+	StopATR       float64 `json:"stop_atr"`
+	TargetATR     float64 `json:"target_atr"`
+
+	model *ml.Model // Shared by clones; never mutated after training
+}
+
+func NewMLStrategy(model *ml.Model) *MLStrategy {
+	return &MLStrategy{
+		BuyThreshold:  0.55,
+		SellThreshold: 0.45,
+		ATRPeriod:     14,
+		StopATR:       2.0,
+		TargetATR:     3.0,
+		model:         model,
+	}
+}
+
+func (s *MLStrategy) Name() string {
+	return "ML Signal Strategy"
+}
+
+// Model returns the model being served, or nil if none is trained
+func (s *MLStrategy) Model() *ml.Model {
+	return s.model
+}
+
+func (s *MLStrategy) GetParameters() map[string]interface{} {
+	return map[string]interface{}{
+		"buy_threshold":  s.BuyThreshold,
+		"sell_threshold": s.SellThreshold,
+		"atr_period":     s.ATRPeriod,
+		"stop_atr":       s.StopATR,
+		"target_atr":     s.TargetATR,
+	}
+}
+
+var mlSchema = []ParameterSpec{
+	{Name: "buy_threshold", Type: ParamFloat, Min: 0.5, Max: 0.95, Description: "Calibrated up-probability at or above which to BUY"},
+	{Name: "sell_threshold", Type: ParamFloat, Min: 0.05, Max: 0.5, Description: "Calibrated up-probability at or below which to SELL"},
+	{Name: "atr_period", Type: ParamInt, Min: 2, Max: 100, Description: "ATR lookback for stops and targets"},
+	{Name: "stop_atr", Type: ParamFloat, Min: 0.5, Max: 10, Description: "Stop distance in ATRs"},
+	{Name: "target_atr", Type: ParamFloat, Min: 0.5, Max: 20, Description: "Target distance in ATRs"},
+}
+
+func (s *MLStrategy) ParameterSchema() []ParameterSpec {
+	return withDefaults(mlSchema, NewMLStrategy(nil).GetParameters())
+}
+
+func (s *MLStrategy) SetParameters(params map[string]interface{}) error {
+	updated := *s
+	err := applyParameters(mlSchema, params, map[string]interface{}{
+		"buy_threshold":  &updated.BuyThreshold,
+		"sell_threshold": &updated.SellThreshold,
+		"atr_period":     &updated.ATRPeriod,
+		"stop_atr":       &updated.StopATR,
+		"target_atr":     &updated.TargetATR,
+	})
+	if err != nil {
+		return err
+	}
+	if err := requireLess("sell_threshold", updated.SellThreshold, "buy_threshold", updated.BuyThreshold); err != nil {
+		return err
+	}
+
+	*s = updated
+	return nil
+}
+
+func (s *MLStrategy) Clone() TradingAlgorithm {
+	clone := *s
+	return &clone
+}
+
+func (s *MLStrategy) Analyze(data []models.HistoricalData) (*models.TradingSignal, error) {
+	if s.model == nil {
+		return nil, fmt.Errorf("ML model not trained")
+	}
+
+	prediction, err := s.model.Predict(data)
+	if err != nil {
+		return nil, err
+	}
+
+	bars := indicators.NewBars(data)
+	atr, err := indicators.ATRFloat(bars.High, bars.Low, bars.Close, s.ATRPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("ATR calculation failed: %v", err)
+	}
+	latestATR := atr[len(atr)-1]
+
+	last := len(data) - 1
+	price := bars.Close[last]
+	p := prediction.Probability
+
+	signal := &models.TradingSignal{
+		Symbol:    data[last].Symbol,
+		Price:     data[last].Close,
+		Algorithm: s.Name(),
+		CreatedAt: time.Now(),
+		Indicators: map[string]interface{}{
+			"probability_up":     p,
+			"raw_probability_up": prediction.RawProbability,
+			"horizon":            s.model.Config.Horizon,
+			"features":           prediction.Features,
+			"model_trained_at":   s.model.TrainedAt,
+			"model_auc":          s.model.Validation.AUC,
+		},
+	}
+
+	// Confidence is the calibrated probability of the call being right
+	strength := math.Min(1, math.Abs(p-0.5)*2)
+	var why explanation
+	switch {
+	case p >= s.BuyThreshold:
+		signal.Type = "BUY"
+		signal.Confidence = decimal.NewFromFloat(p)
+		signal.StopLoss = decimal.NewFromFloat(price - s.StopATR*latestATR)
+		signal.TargetPrice = decimal.NewFromFloat(price + s.TargetATR*latestATR)
+		why.rule("predicted_up", "Model predicts a rise over the horizon", strength, p,
+			condition("probability_up", ">=", p, s.BuyThreshold, "buy_threshold"))
+	case p <= s.SellThreshold:
+		signal.Type = "SELL"
+		signal.Confidence = decimal.NewFromFloat(1 - p)
+		signal.StopLoss = decimal.NewFromFloat(price + s.StopATR*latestATR)
+		signal.TargetPrice = decimal.NewFromFloat(price - s.TargetATR*latestATR)
+		why.rule("predicted_down", "Model predicts a fall over the horizon", strength, 1-p,
+			condition("probability_up", "<=", p, s.SellThreshold, "sell_threshold"))
+	default:
+		signal.Type = "HOLD"
+		signal.Confidence = decimal.NewFromFloat(math.Max(p, 1-p))
+		why.rule("uncertain", "Model probability between the buy and sell thresholds", strength, math.Max(p, 1-p),
+			condition("probability_up", ">=", p, s.BuyThreshold, "buy_threshold"),
+			condition("probability_up", "<=", p, s.SellThreshold, "sell_threshold"))
+	}
+
+	signal.Strength = decimal.NewFromFloat(strength)
+	signal.RiskLevel = "MEDIUM"
+	if signal.Type == "HOLD" {
+		signal.RiskLevel = "LOW"
+	} else if s.model.Validation.AUC < 0.55 {
+		signal.RiskLevel = "HIGH" // The model barely beats chance out of sample
+	}
+
+	signal.ExpirationTime = time.Now().Add(time.Duration(s.model.Config.Horizon) * 24 * time.Hour)
+	signal.TimeFrame = "1d"
+	why.attach(signal)
+
+	return signal, nil
+}
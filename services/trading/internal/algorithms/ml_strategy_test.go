@@ -0,0 +1,76 @@
+package algorithms
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"trading-service/internal/indicators"
+	"trading-service/internal/ml"
+	"trading-service/internal/models"
+)
+
+func TestMLStrategyAnalyze(t *testing.T) {
+	var training [][]models.HistoricalData
+	for seed := int64(100); seed < 104; seed++ {
+		training = append(training, testBars(400, seed))
+	}
+	model, err := ml.Train(training, ml.TrainConfig{Horizon: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := testBars(300, 1)
+	prediction, err := model.Predict(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := prediction.Probability
+	bars := indicators.NewBars(data)
+	atr, _ := indicators.ATRFloat(bars.High, bars.Low, bars.Close, 14)
+	price, n := bars.Close[len(bars.Close)-1], atr[len(atr)-1]
+
+	// Thresholds placed around the model's probability pick each branch
+	tests := []struct {
+		name       string
+		buy, sell  float64
+		want       string
+		confidence float64
+		stop       float64
+		target     float64
+	}{
+		{"buy", p, p - 0.1, "BUY", p, price - 2*n, price + 3*n},
+		{"sell", p + 0.1, p, "SELL", 1 - p, price + 2*n, price - 3*n},
+		{"hold", p + 0.05, p - 0.05, "HOLD", math.Max(p, 1-p), 0, 0},
+	}
+	for _, tt := range tests {
+		s := NewMLStrategy(model)
+		s.BuyThreshold, s.SellThreshold = tt.buy, tt.sell
+		signal, err := s.Analyze(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signal.Type != tt.want || math.Abs(signal.Confidence.InexactFloat64()-tt.confidence) > 1e-9 {
+			t.Fatalf("%s: %s with confidence %v, want %s with %v", tt.name, signal.Type, signal.Confidence, tt.want, tt.confidence)
+		}
+		if math.Abs(signal.StopLoss.InexactFloat64()-tt.stop) > 1e-6 || math.Abs(signal.TargetPrice.InexactFloat64()-tt.target) > 1e-6 {
+			t.Fatalf("%s: stop %v target %v, want %v and %v", tt.name, signal.StopLoss, signal.TargetPrice, tt.stop, tt.target)
+		}
+		if strength := math.Min(1, math.Abs(p-0.5)*2); math.Abs(signal.Strength.InexactFloat64()-strength) > 1e-9 {
+			t.Fatalf("%s: strength %v, want %v", tt.name, signal.Strength, strength)
+		}
+		if signal.Indicators["probability_up"] != p || signal.Indicators["horizon"] != 3 {
+			t.Fatalf("%s: indicators %v, want the model's probability and horizon", tt.name, signal.Indicators)
+		}
+		if len(signal.Indicators["features"].(map[string]float64)) != len(ml.FeatureNames) {
+			t.Fatalf("%s: features %v, want every model feature", tt.name, signal.Indicators["features"])
+		}
+	}
+
+	if _, err := NewMLStrategy(nil).Analyze(data); err == nil || !strings.Contains(err.Error(), "not trained") {
+		t.Fatalf("error = %v, want an untrained model error", err)
+	}
+	if _, err := NewMLStrategy(model).Analyze(data[:20]); err == nil {
+		t.Fatal("20 bars are too few for the model's features")
+	}
+}
@@ -0,0 +1,379 @@
+package algorithms
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gonum.org/v1/gonum/stat"
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+)
+
+// Pair actions
+const (
+	PairLongSpread      = "LONG_SPREAD"  // Buy the first symbol, sell the hedge
+	PairShortSpread     = "SHORT_SPREAD" // Sell the first symbol, buy the hedge
+	PairExit            = "EXIT"         // Spread back near its mean; close an open pair
+	PairStop            = "STOP"         // Spread diverged past the stop; close an open pair
+	PairHold            = "HOLD"
+	PairNotCointegrated = "NOT_COINTEGRATED"
+)
+
+// PairsTradingStrategy trades the spread between two cointegrated series.
+// The first series is regressed on the second (Engle-Granger); the spread's
+// z-score against its recent mean drives paired entries and exits.
+type PairsTradingStrategy struct {
+	FormationPeriod int     `json:"formation_period"`
+	ZScorePeriod    int     `json:"zscore_period"`
+	EntryZ          float64 `json:"entry_z"`
+	ExitZ           float64 `json:"exit_z"`
+	StopZ           float64 `json:"stop_z"`
+	ADFLags         int     `json:"adf_lags"`
+	Significance    float64 `json:"significance"`
+}
+
+// PairAnalysis is the full result of analysing one pair
+type PairAnalysis struct {
+	SymbolY       string                          `json:"symbol_y"`
+	SymbolX       string                          `json:"symbol_x"`
+	Cointegration *indicators.CointegrationResult `json:"cointegration"`
+	Cointegrated  bool                            `json:"cointegrated"`
+	Significance  string                          `json:"significance"`
+	Spread        float64                         `json:"spread"`
+	SpreadMean    float64                         `json:"spread_mean"`
+	SpreadStdDev  float64                         `json:"spread_std_dev"`
+	ZScore        float64                         `json:"z_score"`
+	Action        string                          `json:"action"`
+	Signals       []*models.TradingSignal         `json:"signals"` // First symbol's leg, then the hedge leg
+}
+
+func NewPairsTradingStrategy() *PairsTradingStrategy {
+	return &PairsTradingStrategy{
+		FormationPeriod: 250,
+		ZScorePeriod:    30,
+		EntryZ:          2.0,
+		ExitZ:           0.5,
+		StopZ:           3.5,
+		ADFLags:         1,
+		Significance:    0.05,
+	}
+}
+
+func (p *PairsTradingStrategy) Name() string {
+	return "Pairs Trading Strategy"
+}
+
+// Assets returns the number of series AnalyzeMulti expects
+func (p *PairsTradingStrategy) Assets() int {
+	return 2
+}
+
+func (p *PairsTradingStrategy) GetParameters() map[string]interface{} {
+	return map[string]interface{}{
+		"formation_period": p.FormationPeriod,
+		"zscore_period":    p.ZScorePeriod,
+		"entry_z":          p.EntryZ,
+		"exit_z":           p.ExitZ,
+		"stop_z":           p.StopZ,
+		"adf_lags":         p.ADFLags,
+		"significance":     p.Significance,
+	}
+}
+
+var pairsTradingSchema = []ParameterSpec{
+	{Name: "formation_period", Type: ParamInt, Min: 30, Max: 2000, Description: "Bars used to estimate the hedge ratio and test cointegration"},
+	{Name: "zscore_period", Type: ParamInt, Min: 5, Max: 500, Description: "Bars used for the spread's mean and standard deviation"},
+	{Name: "entry_z", Type: ParamFloat, Min: 0.5, Max: 5, Description: "Absolute z-score at which a spread position is opened"},
+	{Name: "exit_z", Type: ParamFloat, Min: 0, Max: 3, Description: "Absolute z-score below which an open spread is closed"},
+	{Name: "stop_z", Type: ParamFloat, Min: 1, Max: 10, Description: "Absolute z-score beyond which the pair is abandoned"},
+	{Name: "adf_lags", Type: ParamInt, Min: 0, Max: 12, Description: "Lagged differences in the ADF regression"},
+	{Name: "significance", Type: ParamFloat, Min: 0.01, Max: 0.1, Description: "Cointegration test level, rounded up to 0.01, 0.05 or 0.10"},
+}
+
+func (p *PairsTradingStrategy) ParameterSchema() []ParameterSpec {
+	return withDefaults(pairsTradingSchema, NewPairsTradingStrategy().GetParameters())
+}
+
+func (p *PairsTradingStrategy) SetParameters(params map[string]interface{}) error {
+	updated := *p
+	err := applyParameters(pairsTradingSchema, params, map[string]interface{}{
+		"formation_period": &updated.FormationPeriod,
+		"zscore_period":    &updated.ZScorePeriod,
+		"entry_z":          &updated.EntryZ,
+		"exit_z":           &updated.ExitZ,
+		"stop_z":           &updated.StopZ,
+		"adf_lags":         &updated.ADFLags,
+		"significance":     &updated.Significance,
+	})
+	if err != nil {
+		return err
+	}
+	if err := requireLess("exit_z", updated.ExitZ, "entry_z", updated.EntryZ); err != nil {
+		return err
+	}
+	if err := requireLess("entry_z", updated.EntryZ, "stop_z", updated.StopZ); err != nil {
+		return err
+	}
+	if updated.ZScorePeriod > updated.FormationPeriod {
+		return paramErrorf("zscore_period", "must not exceed formation_period (%d)", updated.FormationPeriod)
+	}
+
+	*p = updated
+	return nil
+}
+
+func (p *PairsTradingStrategy) Clone() MultiAssetAlgorithm {
+	clone := *p
+	return &clone
+}
+
+// AnalyzeMulti returns one signal per leg for exactly two series
+func (p *PairsTradingStrategy) AnalyzeMulti(series [][]models.HistoricalData) ([]*models.TradingSignal, error) {
+	if len(series) != 2 {
+		return nil, fmt.Errorf("pairs trading needs exactly 2 series, got %d", len(series))
+	}
+	analysis, err := p.AnalyzePair(series[0], series[1])
+	if err != nil {
+		return nil, err
+	}
+	return analysis.Signals, nil
+}
+
+// AnalyzePair tests y and x for cointegration over the formation period and
+// signals on the latest spread z-score. Bars are matched by trading day.
+func (p *PairsTradingStrategy) AnalyzePair(y, x []models.HistoricalData) (*PairAnalysis, error) {
+	barsY, barsX := alignByDay(y, x)
+	minBars := int(math.Max(float64(p.ZScorePeriod), float64(p.ADFLags+30)))
+	if len(barsY) < minBars {
+		return nil, fmt.Errorf("insufficient overlapping data for pairs trading: need %d bars, got %d", minBars, len(barsY))
+	}
+	if len(barsY) > p.FormationPeriod {
+		barsY = barsY[len(barsY)-p.FormationPeriod:]
+		barsX = barsX[len(barsX)-p.FormationPeriod:]
+	}
+
+	closesY := make([]float64, len(barsY))
+	closesX := make([]float64, len(barsX))
+	for i := range barsY {
+		closesY[i] = barsY[i].Close.InexactFloat64()
+		closesX[i] = barsX[i].Close.InexactFloat64()
+	}
+
+	coint, err := indicators.EngleGranger(closesY, closesX, p.ADFLags)
+	if err != nil {
+		return nil, fmt.Errorf("cointegration test failed: %v", err)
+	}
+
+	window := coint.Residuals[len(coint.Residuals)-p.ZScorePeriod:]
+	mean, stdDev := stat.MeanStdDev(window, nil)
+	spread := coint.Residuals[len(coint.Residuals)-1]
+	zScore := 0.0
+	if stdDev > 0 {
+		zScore = (spread - mean) / stdDev
+	}
+
+	level := p.significanceLevel()
+	analysis := &PairAnalysis{
+		SymbolY:       barsY[len(barsY)-1].Symbol,
+		SymbolX:       barsX[len(barsX)-1].Symbol,
+		Cointegration: coint,
+		Cointegrated:  coint.Cointegrated(level),
+		Significance:  level,
+		Spread:        spread,
+		SpreadMean:    mean,
+		SpreadStdDev:  stdDev,
+		ZScore:        zScore,
+	}
+
+	absZ := math.Abs(zScore)
+	switch {
+	case !analysis.Cointegrated:
+		analysis.Action = PairNotCointegrated
+	case absZ >= p.StopZ:
+		analysis.Action = PairStop
+	case zScore <= -p.EntryZ:
+		analysis.Action = PairLongSpread
+	case zScore >= p.EntryZ:
+		analysis.Action = PairShortSpread
+	case absZ <= p.ExitZ:
+		analysis.Action = PairExit
+	default:
+		analysis.Action = PairHold
+	}
+
+	analysis.Signals = p.legSignals(analysis, barsY[len(barsY)-1], barsX[len(barsX)-1])
+	return analysis, nil
+}
+
+// significanceLevel rounds the configured significance up to a tabulated level
+func (p *PairsTradingStrategy) significanceLevel() string {
+	switch {
+	case p.Significance <= 0.01:
+		return indicators.Significance1
+	case p.Significance <= 0.05:
+		return indicators.Significance5
+	}
+	return indicators.Significance10
+}
+
+// legSignals builds the two leg signals: BUY or SELL legs on entry, EXIT
+// legs on an exit or stop, HOLD otherwise. Targets are the leg prices at which
+// the spread returns to its mean and stops those at which it reaches stop_z,
+// each holding the other leg's price fixed.
+func (p *PairsTradingStrategy) legSignals(a *PairAnalysis, lastY, lastX models.HistoricalData) []*models.TradingSignal {
+	coint := a.Cointegration
+	priceY := lastY.Close.InexactFloat64()
+	priceX := lastX.Close.InexactFloat64()
+
+	typeY, typeX := "HOLD", "HOLD"
+	stopSpread := a.SpreadMean
+	switch a.Action {
+	case PairLongSpread:
+		typeY, typeX = "BUY", "SELL"
+		stopSpread = a.SpreadMean - p.StopZ*a.SpreadStdDev
+	case PairShortSpread:
+		typeY, typeX = "SELL", "BUY"
+		stopSpread = a.SpreadMean + p.StopZ*a.SpreadStdDev
+	case PairExit, PairStop:
+		// Close both legs of an open pair, whichever side it is on
+		typeY, typeX = "EXIT", "EXIT"
+	}
+	entry := typeY == "BUY" || typeY == "SELL"
+	if coint.HedgeRatio < 0 && entry {
+		// A negative hedge ratio means both legs move the spread the same way
+		typeX = typeY
+	}
+
+	strength, confidence, risk := 0.3, 0.5, "LOW"
+	why := p.explainAction(a)
+	if a.Action == PairStop {
+		risk = "HIGH" // The spread is behaving unlike the cointegrated pair it was traded as
+	}
+	if entry {
+		strength = math.Min(1, math.Abs(a.ZScore)/p.StopZ)
+		confidence = 0.6
+		why.rule(strings.ToLower(a.Action), "Spread stretched past the entry z-score", strength, confidence,
+			flag("cointegrated", a.Cointegrated),
+			condition("abs_z_score", ">=", math.Abs(a.ZScore), p.EntryZ, "entry_z"),
+			condition("abs_z_score", "<", math.Abs(a.ZScore), p.StopZ, "stop_z"))
+		if coint.Cointegrated(indicators.Significance5) {
+			confidence = 0.7
+			why.adjust("cointegrated_5pct", "Cointegrated at the 5% level", 0, 0.1, flag("cointegrated_5pct", true))
+		}
+		if coint.Cointegrated(indicators.Significance1) {
+			confidence = 0.8
+			why.adjust("cointegrated_1pct", "Cointegrated at the 1% level", 0, 0.1, flag("cointegrated_1pct", true))
+		}
+		risk = "MEDIUM"
+		if coint.HalfLife == 0 || coint.HalfLife > float64(p.ZScorePeriod) {
+			risk = "HIGH" // Slow reversion relative to the z-score window
+		}
+	}
+
+	common := map[string]interface{}{
+		"hedge_ratio":   coint.HedgeRatio,
+		"intercept":     coint.Intercept,
+		"adf_statistic": coint.ADFStatistic,
+		"cointegrated":  a.Cointegrated,
+		"half_life":     coint.HalfLife,
+		"spread":        a.Spread,
+		"z_score":       a.ZScore,
+		"pair_action":   a.Action,
+	}
+
+	legY := p.legSignal(lastY, typeY, strength, confidence, risk, common)
+	legY.Indicators["leg"] = "primary"
+	legY.Indicators["pair_symbol"] = a.SymbolX
+	legY.Indicators["quantity_ratio"] = 1.0
+
+	legX := p.legSignal(lastX, typeX, strength, confidence, risk, common)
+	legX.Indicators["leg"] = "hedge"
+	legX.Indicators["pair_symbol"] = a.SymbolY
+	legX.Indicators["quantity_ratio"] = math.Abs(coint.HedgeRatio)
+
+	why.attach(legY)
+	why.attach(legX)
+
+	if entry {
+		legY.TargetPrice = positivePrice(coint.Intercept + coint.HedgeRatio*priceX + a.SpreadMean)
+		legY.StopLoss = positivePrice(coint.Intercept + coint.HedgeRatio*priceX + stopSpread)
+		if math.Abs(coint.HedgeRatio) > 1e-9 {
+			legX.TargetPrice = positivePrice((priceY - coint.Intercept - a.SpreadMean) / coint.HedgeRatio)
+			legX.StopLoss = positivePrice((priceY - coint.Intercept - stopSpread) / coint.HedgeRatio)
+		}
+	}
+
+	return []*models.TradingSignal{legY, legX}
+}
+
+// explainAction records why a pair is not traded. Entries are explained by
+// legSignals along with their confidence adjustments.
+func (p *PairsTradingStrategy) explainAction(a *PairAnalysis) *explanation {
+	why := &explanation{}
+	absZ := math.Abs(a.ZScore)
+	switch a.Action {
+	case PairNotCointegrated:
+		why.rule("not_cointegrated", "The pair is not cointegrated at the configured significance", 0.3, 0.5,
+			flag("cointegrated", false))
+	case PairStop:
+		why.rule("stop", "Spread diverged past the stop z-score", 0.3, 0.5,
+			condition("abs_z_score", ">=", absZ, p.StopZ, "stop_z"))
+	case PairExit:
+		why.rule("exit", "Spread back near its mean", 0.3, 0.5,
+			condition("abs_z_score", "<=", absZ, p.ExitZ, "exit_z"))
+	case PairHold:
+		why.rule("hold", "Spread between the exit and entry z-scores", 0.3, 0.5,
+			condition("abs_z_score", ">", absZ, p.ExitZ, "exit_z"),
+			condition("abs_z_score", "<", absZ, p.EntryZ, "entry_z"))
+	}
+	return why
+}
+
+func (p *PairsTradingStrategy) legSignal(bar models.HistoricalData, signalType string, strength, confidence float64, risk string, common map[string]interface{}) *models.TradingSignal {
+	indicatorValues := make(map[string]interface{}, len(common)+3)
+	for k, v := range common {
+		indicatorValues[k] = v
+	}
+
+	return &models.TradingSignal{
+		Symbol:         bar.Symbol,
+		Type:           signalType,
+		Strength:       decimal.NewFromFloat(strength),
+		Price:          bar.Close,
+		Confidence:     decimal.NewFromFloat(confidence),
+		Algorithm:      p.Name(),
+		Indicators:     indicatorValues,
+		RiskLevel:      risk,
+		TimeFrame:      "1d",
+		ExpirationTime: time.Now().Add(24 * time.Hour),
+		CreatedAt:      time.Now(),
+	}
+}
+
+func positivePrice(v float64) decimal.Decimal {
+	if v <= 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return decimal.Zero
+	}
+	return decimal.NewFromFloat(v).Round(4)
+}
+
+// alignByDay keeps the bars present in both series, matched on calendar day,
+// in date order
+func alignByDay(y, x []models.HistoricalData) ([]models.HistoricalData, []models.HistoricalData) {
+	byDay := make(map[string]models.HistoricalData, len(x))
+	for _, bar := range x {
+		byDay[bar.Date.UTC().Format("2006-01-02")] = bar
+	}
+
+	var alignedY, alignedX []models.HistoricalData
+	for _, bar := range y {
+		if match, ok := byDay[bar.Date.UTC().Format("2006-01-02")]; ok {
+			alignedY = append(alignedY, bar)
+			alignedX = append(alignedX, match)
+		}
+	}
+	return alignedY, alignedX
+}
@@ -0,0 +1,90 @@
+package algorithms
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+)
+
+func TestPairLegSignalTypes(t *testing.T) {
+	p := NewPairsTradingStrategy()
+	lastY := models.HistoricalData{Symbol: "Y", Close: decimal.NewFromInt(110)}
+	lastX := models.HistoricalData{Symbol: "X", Close: decimal.NewFromInt(60)}
+
+	tests := []struct {
+		action       string
+		hedgeRatio   float64
+		typeY, typeX string
+	}{
+		{PairLongSpread, 1.5, "BUY", "SELL"},
+		{PairShortSpread, 1.5, "SELL", "BUY"},
+		{PairLongSpread, -1.5, "BUY", "BUY"},
+		{PairExit, 1.5, "EXIT", "EXIT"},
+		{PairStop, 1.5, "EXIT", "EXIT"},
+		{PairStop, -1.5, "EXIT", "EXIT"},
+		{PairHold, 1.5, "HOLD", "HOLD"},
+		{PairNotCointegrated, 1.5, "HOLD", "HOLD"},
+	}
+	for _, tt := range tests {
+		a := &PairAnalysis{
+			SymbolY:       "Y",
+			SymbolX:       "X",
+			Cointegration: &indicators.CointegrationResult{HedgeRatio: tt.hedgeRatio, Intercept: 200},
+			SpreadStdDev:  1,
+			ZScore:        4,
+			Action:        tt.action,
+		}
+		legs := p.legSignals(a, lastY, lastX)
+		if legs[0].Type != tt.typeY || legs[1].Type != tt.typeX {
+			t.Fatalf("%s (hedge %v): legs %s/%s, want %s/%s", tt.action, tt.hedgeRatio, legs[0].Type, legs[1].Type, tt.typeY, tt.typeX)
+		}
+		for _, leg := range legs {
+			if leg.Indicators["pair_action"] != tt.action {
+				t.Fatalf("%s: pair_action = %v", tt.action, leg.Indicators["pair_action"])
+			}
+		}
+		entry := tt.typeY == "BUY" || tt.typeY == "SELL"
+		if entry != !legs[0].StopLoss.IsZero() {
+			t.Fatalf("%s (hedge %v): primary leg stop loss = %s", tt.action, tt.hedgeRatio, legs[0].StopLoss)
+		}
+		if tt.action == PairStop && legs[0].RiskLevel != "HIGH" {
+			t.Fatalf("stop legs risk = %s, want HIGH", legs[0].RiskLevel)
+		}
+	}
+}
+
+func TestAnalyzePairStop(t *testing.T) {
+	// y tracks 1.5x plus stationary noise until a final shock far past stop_z
+	rng := rand.New(rand.NewSource(11))
+	x := testBars(300, 5)
+	y := make([]models.HistoricalData, len(x))
+	for i, bar := range x {
+		price := 20 + 1.5*bar.Close.InexactFloat64() + rng.NormFloat64()*0.5
+		if i == len(x)-1 {
+			price += 15
+		}
+		y[i] = bar
+		y[i].Symbol = "Y"
+		y[i].Close = decimal.NewFromFloat(price)
+	}
+
+	analysis, err := NewPairsTradingStrategy().AnalyzePair(y, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !analysis.Cointegrated {
+		t.Fatalf("constructed pair not cointegrated (ADF %v)", analysis.Cointegration.ADFStatistic)
+	}
+	if analysis.Action != PairStop || math.Abs(analysis.ZScore) < 3.5 {
+		t.Fatalf("action = %s at z %.2f, want STOP", analysis.Action, analysis.ZScore)
+	}
+	for _, leg := range analysis.Signals {
+		if leg.Type != "EXIT" {
+			t.Fatalf("%s leg type = %s, want EXIT", leg.Indicators["leg"], leg.Type)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package algorithms
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Parameter types
+const (
+	ParamInt   = "int"
+	ParamFloat = "float"
+)
+
+// ParameterSpec describes one tunable strategy parameter
+type ParameterSpec struct {
+	Name        string  `json:"name"`
+	Type        string  `json:"type"` // int, float
+	Default     float64 `json:"default"`
+	Min         float64 `json:"min"`
+	Max         float64 `json:"max"`
+	Description string  `json:"description"`
+}
+
+// ParameterError reports an invalid parameter value. Parameter is empty when
+// the error concerns a combination of parameters.
+type ParameterError struct {
+	Algorithm string `json:"algorithm,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Msg       string `json:"message"`
+}
+
+func (e *ParameterError) Error() string {
+	var where []string
+	if e.Algorithm != "" {
+		where = append(where, e.Algorithm)
+	}
+	if e.Parameter != "" {
+		where = append(where, e.Parameter)
+	}
+	if len(where) == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(where, "."), e.Msg)
+}
+
+func paramErrorf(parameter, format string, args ...interface{}) *ParameterError {
+	return &ParameterError{Parameter: parameter, Msg: fmt.Sprintf(format, args...)}
+}
+
+// applyParameters validates params against the schema and writes them
+// through bindings (parameter name to *int or *float64). Nothing is written
+// unless every parameter is valid.
+func applyParameters(schema []ParameterSpec, params map[string]interface{}, bindings map[string]interface{}) error {
+	specs := make(map[string]ParameterSpec, len(schema))
+	for _, spec := range schema {
+		specs[spec.Name] = spec
+	}
+
+	// Sort keys so the first reported error is deterministic
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make(map[string]float64, len(params))
+	for _, key := range keys {
+		spec, ok := specs[key]
+		if !ok {
+			return paramErrorf(key, "unknown parameter")
+		}
+		value, err := validateParameter(spec, params[key])
+		if err != nil {
+			return err
+		}
+		values[key] = value
+	}
+
+	for key, value := range values {
+		switch target := bindings[key].(type) {
+		case *int:
+			*target = int(value)
+		case *float64:
+			*target = value
+		default:
+			return paramErrorf(key, "parameter is not settable")
+		}
+	}
+	return nil
+}
+
+// validateParameter converts a raw value (JSON numbers decode as float64)
+// and checks its type and range
+func validateParameter(spec ParameterSpec, raw interface{}) (float64, error) {
+	value, ok := toFloat(raw)
+	if !ok {
+		return 0, paramErrorf(spec.Name, "expected a number, got %T", raw)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, paramErrorf(spec.Name, "must be a finite number")
+	}
+	if spec.Type == ParamInt && value != math.Trunc(value) {
+		return 0, paramErrorf(spec.Name, "must be a whole number, got %v", value)
+	}
+	if value < spec.Min || value > spec.Max {
+		return 0, paramErrorf(spec.Name, "must be between %v and %v, got %v", spec.Min, spec.Max, value)
+	}
+	return value, nil
+}
+
+func toFloat(raw interface{}) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// requireLess reports a combination error unless a < b
+func requireLess(aName string, a float64, bName string, b float64) error {
+	if a >= b {
+		return paramErrorf("", "%s (%v) must be less than %s (%v)", aName, a, bName, b)
+	}
+	return nil
+}
+
+// withDefaults copies the schema filling Default from a freshly constructed
+// strategy's parameters, so constructors stay the single source of defaults
+func withDefaults(schema []ParameterSpec, defaults map[string]interface{}) []ParameterSpec {
+	filled := make([]ParameterSpec, len(schema))
+	for i, spec := range schema {
+		if value, ok := toFloat(defaults[spec.Name]); ok {
+			spec.Default = value
+		}
+		filled[i] = spec
+	}
+	return filled
+}
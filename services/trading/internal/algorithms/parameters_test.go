@@ -0,0 +1,214 @@
+package algorithms
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateParameter(t *testing.T) {
+	period := ParameterSpec{Name: "period", Type: ParamInt, Min: 2, Max: 100}
+	ratio := ParameterSpec{Name: "ratio", Type: ParamFloat, Min: 0.5, Max: 5}
+
+	tests := []struct {
+		name  string
+		spec  ParameterSpec
+		raw   interface{}
+		want  float64
+		valid bool
+	}{
+		{"JSON number", period, float64(14), 14, true},
+		{"int", period, 14, 14, true},
+		{"int32", period, int32(14), 14, true},
+		{"int64", period, int64(14), 14, true},
+		{"float32", ratio, float32(1.5), 1.5, true},
+		{"json.Number", ratio, json.Number("2.5"), 2.5, true},
+		{"minimum", period, 2, 2, true},
+		{"maximum", ratio, 5.0, 5, true},
+		{"whole float for an int", period, 20.0, 20, true},
+		{"fraction for an int", period, 14.5, 0, false},
+		{"string", period, "14", 0, false},
+		{"bool", period, true, 0, false},
+		{"nil", period, nil, 0, false},
+		{"malformed json.Number", ratio, json.Number("1.2.3"), 0, false},
+		{"NaN", ratio, math.NaN(), 0, false},
+		{"infinity", ratio, math.Inf(1), 0, false},
+		{"below minimum", period, 1, 0, false},
+		{"above maximum", ratio, 5.01, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := validateParameter(tt.spec, tt.raw)
+		if (err == nil) != tt.valid {
+			t.Fatalf("%s: error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+		if err != nil {
+			if paramErr, ok := err.(*ParameterError); !ok || paramErr.Parameter != tt.spec.Name {
+				t.Fatalf("%s: error = %#v, want a ParameterError for %s", tt.name, err, tt.spec.Name)
+			}
+			continue
+		}
+		if got != tt.want {
+			t.Fatalf("%s: value = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyParameters(t *testing.T) {
+	schema := []ParameterSpec{
+		{Name: "period", Type: ParamInt, Min: 2, Max: 100},
+		{Name: "ratio", Type: ParamFloat, Min: 0.5, Max: 5},
+		{Name: "unbound", Type: ParamInt, Min: 0, Max: 1},
+	}
+	period, ratio := 14, 2.0
+	bindings := map[string]interface{}{"period": &period, "ratio": &ratio}
+
+	if err := applyParameters(schema, map[string]interface{}{"period": 20.0, "ratio": 3}, bindings); err != nil {
+		t.Fatal(err)
+	}
+	if period != 20 || ratio != 3 {
+		t.Fatalf("period %d ratio %v, want 20 and 3", period, ratio)
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]interface{}
+		parameter string
+	}{
+		{"unknown parameter", map[string]interface{}{"period": 30, "lookback": 5}, "lookback"},
+		{"one invalid value", map[string]interface{}{"period": 30, "ratio": 50}, "ratio"},
+		// Keys are checked in order, so the first reported error is stable
+		{"several invalid values", map[string]interface{}{"ratio": 0, "period": 0}, "period"},
+		{"no binding", map[string]interface{}{"unbound": 1}, "unbound"},
+	}
+	for _, tt := range tests {
+		err := applyParameters(schema, tt.params, bindings)
+		paramErr, ok := err.(*ParameterError)
+		if !ok || paramErr.Parameter != tt.parameter {
+			t.Fatalf("%s: error = %v, want a ParameterError for %s", tt.name, err, tt.parameter)
+		}
+		if tt.name != "no binding" && (period != 20 || ratio != 3) {
+			t.Fatalf("%s: period %d ratio %v were written despite the error", tt.name, period, ratio)
+		}
+	}
+}
+
+func TestRequireLess(t *testing.T) {
+	if err := requireLess("fast", 12, "slow", 26); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []float64{12, 5} {
+		err := requireLess("fast", 12, "slow", b)
+		paramErr, ok := err.(*ParameterError)
+		if !ok || paramErr.Parameter != "" || !strings.Contains(err.Error(), "fast (12) must be less than slow") {
+			t.Fatalf("slow %v: error = %v, want a combination error", b, err)
+		}
+	}
+}
+
+func TestSetParametersCrossFieldChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm TradingAlgorithm
+		params    map[string]interface{}
+		want      string
+	}{
+		{"momentum RSI", NewMomentumStrategy(), map[string]interface{}{"rsi_oversold": 50, "rsi_overbought": 50}, "rsi_oversold"},
+		{"momentum MACD", NewMomentumStrategy(), map[string]interface{}{"macd_fast": 30}, "macd_fast"},
+		{"mean reversion", NewMeanReversionStrategy(), map[string]interface{}{"rsi_extreme_oversold": 50, "rsi_extreme_overbought": 50}, "rsi_extreme_oversold"},
+		{"trend following", NewTrendFollowingStrategy(), map[string]interface{}{"ema_fast": 60, "ema_slow": 50}, "ema_fast"},
+		{"donchian", NewDonchianBreakoutStrategy(), map[string]interface{}{"entry_period": 10, "exit_period": 10}, "exit_period"},
+		{"ml", NewMLStrategy(nil), map[string]interface{}{"buy_threshold": 0.5, "sell_threshold": 0.5}, "sell_threshold"},
+	}
+	for _, tt := range tests {
+		before := tt.algorithm.GetParameters()
+		err := tt.algorithm.SetParameters(tt.params)
+		paramErr, ok := err.(*ParameterError)
+		if !ok || paramErr.Parameter != "" || !strings.HasPrefix(paramErr.Msg, tt.want) {
+			t.Fatalf("%s: error = %v, want a combination error about %s", tt.name, err, tt.want)
+		}
+		if after := tt.algorithm.GetParameters(); !reflect.DeepEqual(before, after) {
+			t.Fatalf("%s: parameters changed to %v after a failed update", tt.name, after)
+		}
+	}
+
+	pairs := NewPairsTradingStrategy()
+	before := pairs.GetParameters()
+	for _, params := range []map[string]interface{}{
+		{"exit_z": 2.5, "entry_z": 2},
+		{"entry_z": 4, "stop_z": 3},
+	} {
+		if err := pairs.SetParameters(params); err == nil {
+			t.Fatalf("pairs %v: no error", params)
+		}
+	}
+	if !reflect.DeepEqual(before, pairs.GetParameters()) {
+		t.Fatal("pairs parameters changed after a failed update")
+	}
+}
+
+func TestSetParametersLeavesAlgorithmUnchanged(t *testing.T) {
+	m := NewMomentumStrategy()
+	before := m.GetParameters()
+	// rsi_period alone is valid, but rsi_oversold is out of range
+	if err := m.SetParameters(map[string]interface{}{"rsi_period": 10, "rsi_oversold": 80}); err == nil {
+		t.Fatal("rsi_oversold 80 accepted")
+	}
+	if !reflect.DeepEqual(before, m.GetParameters()) {
+		t.Fatalf("parameters = %v after a failed update, want %v", m.GetParameters(), before)
+	}
+	if err := m.SetParameters(map[string]interface{}{"rsi_period": 10.0}); err != nil || m.RSIPeriod != 10 {
+		t.Fatalf("rsi_period = %d (%v), want 10", m.RSIPeriod, err)
+	}
+
+	cs := NewCompositeStrategy()
+	member := cs.Members[0].Strategy.Name()
+	before = cs.GetParameters()
+	for name, params := range map[string]map[string]interface{}{
+		"member parameter out of range": {"weight_momentum": 0.8, member + "_rsi_period": 500},
+		"member cross-field check":      {"min_signals": 10, member + "_macd_fast": 40},
+		"every weight zero":             {"weight_momentum": 0, "weight_mean_reversion": 0, "weight_trend_following": 0},
+		"unknown parameter":             {"weight_momentum": 0.8, "leverage": 3},
+	} {
+		if err := cs.SetParameters(params); err == nil {
+			t.Fatalf("composite %s: no error", name)
+		}
+		if after := cs.GetParameters(); !reflect.DeepEqual(before, after) {
+			t.Fatalf("composite %s: parameters changed to %v", name, after)
+		}
+	}
+
+	err := cs.SetParameters(map[string]interface{}{member + "_rsi_period": 500})
+	if paramErr, ok := err.(*ParameterError); !ok || paramErr.Parameter != member+"_rsi_period" {
+		t.Fatalf("error = %v, want it reported against %s_rsi_period", err, member)
+	}
+	if err := cs.SetParameters(map[string]interface{}{"regime_routing": 1, member + "_rsi_period": 21}); err != nil {
+		t.Fatal(err)
+	}
+	if cs.RegimeRouting != 1 || cs.Members[0].Strategy.(*MomentumStrategy).RSIPeriod != 21 {
+		t.Fatalf("composite parameters = %v, want regime routing and RSI 21", cs.GetParameters())
+	}
+}
+
+func TestGetAlgorithmWithParameters(t *testing.T) {
+	am := NewAlgorithmManager()
+	shared, _ := am.GetAlgorithm("momentum")
+
+	tuned, err := am.GetAlgorithmWithParameters("momentum", map[string]interface{}{"rsi_period": 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tuned.(*MomentumStrategy).RSIPeriod != 7 || shared.(*MomentumStrategy).RSIPeriod != 14 {
+		t.Fatalf("tuned RSI %d shared %d, want the override on a copy only", tuned.(*MomentumStrategy).RSIPeriod, shared.(*MomentumStrategy).RSIPeriod)
+	}
+
+	_, err = am.GetAlgorithmWithParameters("momentum", map[string]interface{}{"rsi_period": 1})
+	paramErr, ok := err.(*ParameterError)
+	if !ok || paramErr.Algorithm != "momentum" || !strings.HasPrefix(err.Error(), "momentum.rsi_period: ") {
+		t.Fatalf("error = %v, want it attributed to momentum.rsi_period", err)
+	}
+	if _, err := am.GetMultiAssetAlgorithmWithParameters("pairs_trading", map[string]interface{}{"entry_z": 9}); err == nil || !strings.HasPrefix(err.Error(), "pairs_trading.entry_z: ") {
+		t.Fatalf("error = %v, want it attributed to pairs_trading.entry_z", err)
+	}
+}
@@ -0,0 +1,82 @@
+package algorithms
+
+import (
+	"fmt"
+	"strings"
+
+	"trading-service/internal/models"
+)
+
+// explanation accumulates the reasons behind a signal. Base rules set
+// strength and confidence and adjustments record the change they made, so
+// the contributions add up to the final values.
+type explanation struct {
+	reasons []models.SignalReason
+}
+
+// rule records the rule that set the signal's base strength and confidence
+func (e *explanation) rule(name, description string, strength, confidence float64, conditions ...models.SignalCondition) {
+	e.reasons = append(e.reasons, models.SignalReason{
+		Rule:        name,
+		Description: description,
+		Conditions:  conditions,
+		Strength:    strength,
+		Confidence:  confidence,
+	})
+}
+
+// adjust records a change to strength or confidence made after the base rule
+func (e *explanation) adjust(name, description string, strengthDelta, confidenceDelta float64, conditions ...models.SignalCondition) {
+	e.rule(name, description, strengthDelta, confidenceDelta, conditions...)
+}
+
+// attach sets the signal's rationale, summarizing the recorded reasons
+func (e *explanation) attach(signal *models.TradingSignal) {
+	descriptions := make([]string, len(e.reasons))
+	for i, reason := range e.reasons {
+		descriptions[i] = reason.Description
+	}
+	strength, _ := signal.Strength.Float64()
+	confidence, _ := signal.Confidence.Float64()
+
+	signal.Rationale = &models.SignalRationale{
+		Summary: fmt.Sprintf("%s (strength %.2f, confidence %.2f): %s",
+			signal.Type, strength, confidence, strings.Join(descriptions, "; ")),
+		Reasons: e.reasons,
+	}
+}
+
+// condition compares an observed value with a threshold. Reference names
+// the parameter or indicator the threshold comes from.
+func condition(indicator, operator string, observed, threshold float64, reference string) models.SignalCondition {
+	var passed bool
+	switch operator {
+	case "<":
+		passed = observed < threshold
+	case "<=":
+		passed = observed <= threshold
+	case ">":
+		passed = observed > threshold
+	case ">=":
+		passed = observed >= threshold
+	case "==":
+		passed = observed == threshold
+	}
+	return models.SignalCondition{
+		Indicator: indicator,
+		Operator:  operator,
+		Threshold: threshold,
+		Reference: reference,
+		Observed:  observed,
+		Passed:    passed,
+	}
+}
+
+// flag records a true/false state as a condition that holds when true
+func flag(indicator string, value bool) models.SignalCondition {
+	observed := 0.0
+	if value {
+		observed = 1
+	}
+	return condition(indicator, "==", observed, 1, "")
+}
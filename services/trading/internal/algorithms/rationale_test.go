@@ -0,0 +1,166 @@
+package algorithms
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/ml"
+	"trading-service/internal/models"
+)
+
+// checkRationale fails unless the signal's reason contributions sum to its
+// strength and confidence, recursing into composite components
+func checkRationale(t *testing.T, name string, signal *models.TradingSignal) {
+	t.Helper()
+	if signal.Rationale == nil || len(signal.Rationale.Reasons) == 0 {
+		t.Fatalf("%s: %s signal has no rationale", name, signal.Type)
+	}
+	var strength, confidence float64
+	for _, reason := range signal.Rationale.Reasons {
+		strength += reason.Strength
+		confidence += reason.Confidence
+	}
+	if math.Abs(strength-signal.Strength.InexactFloat64()) > 1e-9 || math.Abs(confidence-signal.Confidence.InexactFloat64()) > 1e-9 {
+		t.Fatalf("%s: %s reasons sum to strength %v confidence %v, signal has %v and %v: %+v",
+			name, signal.Type, strength, confidence, signal.Strength, signal.Confidence, signal.Rationale.Reasons)
+	}
+
+	if len(signal.Rationale.Components) == 0 {
+		return
+	}
+	var weight, votes float64
+	for _, component := range signal.Rationale.Components {
+		if math.Abs(component.Vote-component.Weight*component.Confidence) > 1e-9 {
+			t.Fatalf("%s: component %s vote %v, want weight × confidence", name, component.Key, component.Vote)
+		}
+		weight += component.Weight
+		votes += component.Vote
+		checkRationale(t, name+"/"+component.Key, &models.TradingSignal{
+			Type:       component.Type,
+			Strength:   decimal.NewFromFloat(component.Strength),
+			Confidence: decimal.NewFromFloat(component.Confidence),
+			Rationale:  component.Rationale,
+		})
+	}
+	if math.Abs(weight-1) > 1e-9 || math.Abs(votes-signal.Confidence.InexactFloat64()) > 1e-9 {
+		t.Fatalf("%s: component weights sum to %v and votes to %v, want 1 and the confidence %v", name, weight, votes, signal.Confidence)
+	}
+}
+
+// intradaySessions is a random walk of 5-minute bars over full sessions
+func intradaySessions(days int, seed int64) []models.HistoricalData {
+	rng := rand.New(rand.NewSource(seed))
+	var data []models.HistoricalData
+	price := 100.0
+	for d := 0; d < days; d++ {
+		closes := make([]float64, 78)
+		for i := range closes {
+			price *= 1 + rng.NormFloat64()*0.003
+			closes[i] = price
+		}
+		data = append(data, session(breakoutStart.AddDate(0, 0, d), closes)...)
+	}
+	return data
+}
+
+// stretchedBars ends a random walk with six bars moving by move each, far
+// enough to reach the Bollinger Bands and RSI extremes
+func stretchedBars(seed int64, move float64) []models.HistoricalData {
+	data := testBars(300, seed)
+	for i := len(data) - 6; i < len(data); i++ {
+		price := data[i-1].Close.InexactFloat64() * (1 + move)
+		data[i].Open = data[i-1].Close
+		data[i].Close = decimal.NewFromFloat(price)
+		data[i].High = decimal.NewFromFloat(math.Max(price, data[i].Open.InexactFloat64()))
+		data[i].Low = decimal.NewFromFloat(math.Min(price, data[i].Open.InexactFloat64()))
+	}
+	return data
+}
+
+func TestRationaleSumsToSignal(t *testing.T) {
+	var training [][]models.HistoricalData
+	for seed := int64(100); seed < 104; seed++ {
+		training = append(training, testBars(400, seed))
+	}
+	model, err := ml.Train(training, ml.TrainConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	am := NewAlgorithmManager()
+	am.SetMLModel(model)
+
+	// Squeeze releases are rare in a random walk
+	extra := map[string][][]models.HistoricalData{
+		"volatility_squeeze": {
+			candles(breakoutStart, 24*time.Hour, append(squeezeCloses(40), 130), 2),
+			candles(breakoutStart, 24*time.Hour, append(squeezeCloses(40), 70), 2),
+		},
+	}
+
+	for _, name := range am.ListAlgorithms() {
+		algorithm, err := am.GetAlgorithm(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		series := extra[name]
+		for seed := int64(1); seed <= 40; seed++ {
+			if name == "opening_range_breakout" {
+				// Cut each walk at a different time of day
+				data := intradaySessions(3, seed)
+				series = append(series, data[:len(data)-int(seed)])
+				continue
+			}
+			series = append(series, testBars(300, seed))
+			if seed <= 10 {
+				series = append(series, stretchedBars(seed, -0.03), stretchedBars(seed, 0.03))
+			}
+		}
+
+		types := make(map[string]int)
+		for i, data := range series {
+			signal, err := algorithm.Analyze(data)
+			if err != nil {
+				t.Fatalf("%s series %d: %v", name, i, err)
+			}
+			checkRationale(t, name, signal)
+			types[signal.Type]++
+		}
+		if types["BUY"]+types["SELL"] == 0 {
+			t.Fatalf("%s: signals %v, want the fixtures to reach a BUY or SELL", name, types)
+		}
+	}
+}
+
+func TestPairsRationaleSumsToSignal(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	x, spread := 50.0, 0.0
+	var ySeries, xSeries []models.HistoricalData
+	for i := 0; i < 400; i++ {
+		x *= 1 + rng.NormFloat64()*0.01
+		spread = 0.8*spread + rng.NormFloat64()
+		y := 10 + 2*x + spread
+		date := start.AddDate(0, 0, i)
+		ySeries = append(ySeries, models.HistoricalData{Symbol: "Y", Date: date, Close: decimal.NewFromFloat(y), High: decimal.NewFromFloat(y), Low: decimal.NewFromFloat(y)})
+		xSeries = append(xSeries, models.HistoricalData{Symbol: "X", Date: date, Close: decimal.NewFromFloat(x), High: decimal.NewFromFloat(x), Low: decimal.NewFromFloat(x)})
+	}
+
+	p := NewPairsTradingStrategy()
+	actions := make(map[string]int)
+	for end := 300; end <= 400; end += 5 {
+		signals, err := p.AnalyzeMulti([][]models.HistoricalData{ySeries[:end], xSeries[:end]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, signal := range signals {
+			checkRationale(t, "pairs_trading", signal)
+		}
+		actions[signals[0].Type]++
+	}
+	if actions["BUY"]+actions["SELL"] == 0 {
+		t.Fatalf("pair signals %v, want at least one entry", actions)
+	}
+}
@@ -0,0 +1,64 @@
+package algorithms
+
+import (
+	"sort"
+
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+)
+
+// regimeAlgorithms lists the built-in algorithms suited to each market
+// regime. Trend and breakout systems need directional markets, mean
+// reversion needs ranges, and in high volatility only the systems with wide
+// ATR-based stops stay active.
+var regimeAlgorithms = map[string][]string{
+	indicators.RegimeTrending:       {"momentum", "trend_following", "donchian_breakout"},
+	indicators.RegimeRanging:        {"mean_reversion", "volatility_squeeze"},
+	indicators.RegimeHighVolatility: {"trend_following", "donchian_breakout", "opening_range_breakout"},
+}
+
+// AlgorithmsForRegime returns the built-in algorithms suited to a regime
+func AlgorithmsForRegime(regime string) []string {
+	return append([]string(nil), regimeAlgorithms[regime]...)
+}
+
+// SuitsRegime reports whether the named algorithm is suited to a regime
+func SuitsRegime(name, regime string) bool {
+	for _, suited := range regimeAlgorithms[regime] {
+		if suited == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteByRegime keeps the candidates suited to the regime. With no
+// candidates it returns every algorithm suited to the regime; if none of
+// the candidates suit, they are returned unchanged.
+func RouteByRegime(candidates []string, regime string) []string {
+	if len(candidates) == 0 {
+		routed := AlgorithmsForRegime(regime)
+		sort.Strings(routed)
+		return routed
+	}
+
+	var routed []string
+	for _, name := range candidates {
+		if SuitsRegime(name, regime) {
+			routed = append(routed, name)
+		}
+	}
+	if len(routed) == 0 {
+		return candidates
+	}
+	return routed
+}
+
+// currentRegime classifies the latest bar with the default threshold method
+func currentRegime(data []models.HistoricalData) (string, error) {
+	analysis, err := indicators.DetectRegimes(indicators.NewBars(data), indicators.DefaultRegimeConfig())
+	if err != nil {
+		return "", err
+	}
+	return analysis.Current, nil
+}
@@ -0,0 +1,93 @@
+package algorithms
+
+import (
+	"strings"
+	"testing"
+
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+)
+
+func hasReason(reasons []models.SignalReason, rule string) bool {
+	for _, reason := range reasons {
+		if reason.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRouteByRegime(t *testing.T) {
+	tests := []struct {
+		name       string
+		candidates []string
+		regime     string
+		want       string
+	}{
+		{"no candidates", nil, indicators.RegimeTrending, "donchian_breakout,momentum,trend_following"},
+		{"no candidates, ranging", nil, indicators.RegimeRanging, "mean_reversion,volatility_squeeze"},
+		{"keeps suited candidates in order", []string{"trend_following", "mean_reversion", "momentum"}, indicators.RegimeTrending, "trend_following,momentum"},
+		{"none suited", []string{"mean_reversion", "ml_model"}, indicators.RegimeHighVolatility, "mean_reversion,ml_model"},
+		{"unknown regime", []string{"momentum"}, "sideways", "momentum"},
+		{"unknown regime, no candidates", nil, "sideways", ""},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(RouteByRegime(tt.candidates, tt.regime), ","); got != tt.want {
+			t.Fatalf("%s: routed %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// The table itself isn't handed out
+	routed := AlgorithmsForRegime(indicators.RegimeRanging)
+	routed[0] = "astrology"
+	if !SuitsRegime("mean_reversion", indicators.RegimeRanging) || SuitsRegime("astrology", indicators.RegimeRanging) {
+		t.Fatal("AlgorithmsForRegime exposed the routing table")
+	}
+}
+
+func TestCompositeRegimeRouting(t *testing.T) {
+	data := zigzagBars(200)
+	regime, err := currentRegime(data)
+	if err != nil || regime != indicators.RegimeTrending {
+		t.Fatalf("fixture regime = %q (%v), want trending", regime, err)
+	}
+
+	cs := NewCompositeStrategy()
+	cs.RegimeRouting = 1
+	cs.Members = []CompositeMember{
+		{Key: "momentum", Strategy: &stubStrategy{name: "momentum", signalType: "BUY", confidence: 0.6}, Weight: 0.2},
+		{Key: "mean_reversion", Strategy: &stubStrategy{name: "mean_reversion", signalType: "SELL", confidence: 0.9}, Weight: 0.5},
+		{Key: "trend_following", Strategy: &stubStrategy{name: "trend_following", signalType: "HOLD", confidence: 0.6}, Weight: 0.2},
+	}
+	signal, err := cs.Analyze(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weights := compositeWeights(t, signal)
+	if _, ok := weights["mean_reversion"]; ok || weights["momentum"] != 0.5 || weights["trend_following"] != 0.5 {
+		t.Fatalf("weights = %v, want momentum and trend_following only", weights)
+	}
+	if signal.Indicators["market_regime"] != indicators.RegimeTrending {
+		t.Fatalf("market_regime = %v, want trending", signal.Indicators["market_regime"])
+	}
+	if !hasReason(signal.Rationale.Reasons, "regime_routing") {
+		t.Fatalf("reasons = %+v, want regime_routing", signal.Rationale.Reasons)
+	}
+
+	// Unrouted, the mean reversion SELL outweighs the rest
+	cs.RegimeRouting = 0
+	if signal, err = cs.Analyze(data); err != nil || signal.Type != "SELL" || signal.Indicators["market_regime"] != nil {
+		t.Fatalf("unrouted signal %v (%v), want an unrouted SELL", signal.Type, err)
+	}
+
+	// When no member suits the regime every member votes
+	cs.RegimeRouting = 1
+	cs.Members = cs.Members[1:2]
+	signal, err = cs.Analyze(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signal.Type != "SELL" || signal.Indicators["market_regime"] != nil || hasReason(signal.Rationale.Reasons, "regime_routing") {
+		t.Fatalf("signal %s regime %v, want the unsuited member's SELL without routing", signal.Type, signal.Indicators["market_regime"])
+	}
+}
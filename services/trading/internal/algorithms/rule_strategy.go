@@ -0,0 +1,518 @@
+package algorithms
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/expression"
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+)
+
+// Price rule types for stop loss and take profit
+const (
+	PriceRulePercent    = "percent"    // Fraction of entry price, e.g. 0.03
+	PriceRuleATR        = "atr"        // Multiple of ATR(period)
+	PriceRuleExpression = "expression" // Price level given by a series expression
+)
+
+// StrategyDefinition declares a rule-based strategy whose conditions are
+// written in the expression language, e.g.
+//
+//	entry:
+//	  buy: crossover(EMA(close, fast), EMA(close, slow)) and RSI(close, 14) < 60
+//
+// Parameters become named constants in every expression.
+type StrategyDefinition struct {
+	ID          string            `json:"id" yaml:"id"`
+	Name        string            `json:"name" yaml:"name"`
+	Description string            `json:"description,omitempty" yaml:"description,omitempty"`
+	TimeFrame   string            `json:"timeframe,omitempty" yaml:"timeframe,omitempty"`   // Default 1h
+	Expiration  string            `json:"expiration,omitempty" yaml:"expiration,omitempty"` // Signal lifetime, default 4h
+	RiskLevel   string            `json:"risk_level,omitempty" yaml:"risk_level,omitempty"` // LOW, MEDIUM, HIGH; default MEDIUM
+	Strength    float64           `json:"strength,omitempty" yaml:"strength,omitempty"`     // BUY/SELL strength, default 0.7
+	Confidence  float64           `json:"confidence,omitempty" yaml:"confidence,omitempty"` // BUY/SELL confidence, default 0.7
+	MinBars     int               `json:"min_bars,omitempty" yaml:"min_bars,omitempty"`
+	Parameters  []ParameterSpec   `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	Entry       RuleConditions    `json:"entry" yaml:"entry"`
+	Exit        RuleConditions    `json:"exit,omitempty" yaml:"exit,omitempty"`
+	StopLoss    *PriceRule        `json:"stop_loss,omitempty" yaml:"stop_loss,omitempty"`
+	TakeProfit  *PriceRule        `json:"take_profit,omitempty" yaml:"take_profit,omitempty"`
+	Indicators  map[string]string `json:"indicators,omitempty" yaml:"indicators,omitempty"` // Reported in the signal
+}
+
+// RuleConditions holds boolean expressions for each side. Entry conditions
+// produce BUY/SELL signals; exit conditions close a long (buy) or short
+// (sell) position and are reported as exit_long/exit_short.
+type RuleConditions struct {
+	Buy  string `json:"buy,omitempty" yaml:"buy,omitempty"`
+	Sell string `json:"sell,omitempty" yaml:"sell,omitempty"`
+}
+
+// PriceRule derives a stop loss or take profit price from the entry price
+type PriceRule struct {
+	Type       string  `json:"type" yaml:"type"`
+	Value      float64 `json:"value,omitempty" yaml:"value,omitempty"`
+	Period     int     `json:"period,omitempty" yaml:"period,omitempty"`         // ATR period, default 14
+	Expression string  `json:"expression,omitempty" yaml:"expression,omitempty"` // For type expression
+}
+
+// DefinitionError lists every problem found in a strategy definition
+type DefinitionError struct {
+	ID       string   `json:"id,omitempty"`
+	Problems []string `json:"problems"`
+}
+
+func (e *DefinitionError) Error() string {
+	prefix := "invalid strategy definition"
+	if e.ID != "" {
+		prefix = fmt.Sprintf("invalid strategy definition %q", e.ID)
+	}
+	return fmt.Sprintf("%s: %s", prefix, strings.Join(e.Problems, "; "))
+}
+
+var strategyIDPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// compiledRules holds the parsed expressions for one set of parameter values
+type compiledRules struct {
+	entryBuy, entrySell *expression.Expression
+	exitBuy, exitSell   *expression.Expression
+	stopLoss            *expression.Expression
+	takeProfit          *expression.Expression
+	indicators          map[string]*expression.Expression
+}
+
+// RuleStrategy is a TradingAlgorithm driven by a StrategyDefinition
+type RuleStrategy struct {
+	definition StrategyDefinition
+	values     map[string]float64
+	rules      *compiledRules
+	expiration time.Duration
+}
+
+// NewRuleStrategy validates the definition and compiles its expressions
+// with the parameter defaults
+func NewRuleStrategy(def StrategyDefinition) (*RuleStrategy, error) {
+	applyDefinitionDefaults(&def)
+
+	var problems []string
+	problems = append(problems, validateDefinitionFields(def)...)
+	problems = append(problems, validateDefinitionParameters(def)...)
+
+	values := make(map[string]float64, len(def.Parameters))
+	for _, spec := range def.Parameters {
+		values[spec.Name] = spec.Default
+	}
+
+	// Only compile once the parameters are sound, otherwise expression
+	// errors would just repeat parameter problems
+	var rules *compiledRules
+	if len(problems) == 0 {
+		var compileProblems []string
+		rules, compileProblems = compileRules(def, values)
+		problems = append(problems, compileProblems...)
+	}
+
+	if len(problems) > 0 {
+		return nil, &DefinitionError{ID: def.ID, Problems: problems}
+	}
+
+	expiration, _ := time.ParseDuration(def.Expiration)
+	return &RuleStrategy{definition: def, values: values, rules: rules, expiration: expiration}, nil
+}
+
+func applyDefinitionDefaults(def *StrategyDefinition) {
+	if def.TimeFrame == "" {
+		def.TimeFrame = "1h"
+	}
+	if def.Expiration == "" {
+		def.Expiration = "4h"
+	}
+	if def.RiskLevel == "" {
+		def.RiskLevel = "MEDIUM"
+	}
+	def.RiskLevel = strings.ToUpper(def.RiskLevel)
+	if def.Strength == 0 {
+		def.Strength = 0.7
+	}
+	if def.Confidence == 0 {
+		def.Confidence = 0.7
+	}
+	for _, rule := range []*PriceRule{def.StopLoss, def.TakeProfit} {
+		if rule != nil {
+			rule.Type = strings.ToLower(rule.Type)
+			if rule.Type == PriceRuleATR && rule.Period == 0 {
+				rule.Period = 14
+			}
+		}
+	}
+}
+
+func validateDefinitionFields(def StrategyDefinition) []string {
+	var problems []string
+
+	if !strategyIDPattern.MatchString(def.ID) {
+		problems = append(problems, "id: must start with a lowercase letter and contain only a-z, 0-9 and _")
+	}
+	if strings.TrimSpace(def.Name) == "" {
+		problems = append(problems, "name: is required")
+	}
+	if def.Entry.Buy == "" && def.Entry.Sell == "" {
+		problems = append(problems, "entry: at least one of buy or sell is required")
+	}
+	if def.RiskLevel != "LOW" && def.RiskLevel != "MEDIUM" && def.RiskLevel != "HIGH" {
+		problems = append(problems, fmt.Sprintf("risk_level: must be LOW, MEDIUM or HIGH, got %q", def.RiskLevel))
+	}
+	if d, err := time.ParseDuration(def.Expiration); err != nil || d <= 0 {
+		problems = append(problems, fmt.Sprintf("expiration: must be a positive duration such as 4h, got %q", def.Expiration))
+	}
+	if def.Strength < 0 || def.Strength > 1 {
+		problems = append(problems, fmt.Sprintf("strength: must be between 0 and 1, got %v", def.Strength))
+	}
+	if def.Confidence < 0 || def.Confidence > 1 {
+		problems = append(problems, fmt.Sprintf("confidence: must be between 0 and 1, got %v", def.Confidence))
+	}
+	if def.MinBars < 0 {
+		problems = append(problems, "min_bars: must not be negative")
+	}
+
+	problems = append(problems, validatePriceRule("stop_loss", def.StopLoss)...)
+	problems = append(problems, validatePriceRule("take_profit", def.TakeProfit)...)
+
+	return problems
+}
+
+func validatePriceRule(field string, rule *PriceRule) []string {
+	if rule == nil {
+		return nil
+	}
+
+	switch rule.Type {
+	case PriceRulePercent:
+		if rule.Value <= 0 || rule.Value >= 1 {
+			return []string{fmt.Sprintf("%s.value: percent must be a fraction between 0 and 1, got %v", field, rule.Value)}
+		}
+	case PriceRuleATR:
+		if rule.Value <= 0 {
+			return []string{fmt.Sprintf("%s.value: ATR multiple must be positive, got %v", field, rule.Value)}
+		}
+		if rule.Period < 1 {
+			return []string{fmt.Sprintf("%s.period: must be positive, got %d", field, rule.Period)}
+		}
+	case PriceRuleExpression:
+		if rule.Expression == "" {
+			return []string{fmt.Sprintf("%s.expression: is required for type expression", field)}
+		}
+	default:
+		return []string{fmt.Sprintf("%s.type: must be percent, atr or expression, got %q", field, rule.Type)}
+	}
+	return nil
+}
+
+func validateDefinitionParameters(def StrategyDefinition) []string {
+	var problems []string
+	seen := make(map[string]bool)
+
+	for i, spec := range def.Parameters {
+		field := fmt.Sprintf("parameters[%d]", i)
+		if spec.Name != "" {
+			field = fmt.Sprintf("parameters.%s", spec.Name)
+		}
+
+		name := strings.ToLower(spec.Name)
+		switch {
+		case !strategyIDPattern.MatchString(name):
+			problems = append(problems, field+": name must be an identifier such as rsi_period")
+		case expression.IsKeyword(name):
+			problems = append(problems, field+": name is a reserved word (and, or, not, true, false)")
+		case expression.IsField(name) || expression.IsFunction(name):
+			problems = append(problems, field+": name clashes with a built-in field or function")
+		case seen[name]:
+			problems = append(problems, field+": duplicate parameter")
+		}
+		seen[name] = true
+
+		if spec.Type != ParamInt && spec.Type != ParamFloat {
+			problems = append(problems, fmt.Sprintf("%s: type must be int or float, got %q", field, spec.Type))
+			continue
+		}
+		if spec.Min > spec.Max {
+			problems = append(problems, fmt.Sprintf("%s: min (%v) is greater than max (%v)", field, spec.Min, spec.Max))
+			continue
+		}
+		if _, err := validateParameter(spec, spec.Default); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: default %s", field, err.(*ParameterError).Msg))
+		}
+	}
+
+	return problems
+}
+
+// compileRules parses every expression with the given parameter values
+func compileRules(def StrategyDefinition, values map[string]float64) (*compiledRules, []string) {
+	var problems []string
+	compile := func(field, src string, want expression.Type) *expression.Expression {
+		if src == "" {
+			return nil
+		}
+		expr, err := expression.ParseWithConstants(src, values)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", field, err))
+			return nil
+		}
+		numeric := expr.Type() == expression.TypeSeries || expr.Type() == expression.TypeNumber
+		if want == expression.TypeBool && expr.Type() != expression.TypeBool {
+			problems = append(problems, fmt.Sprintf("%s: must be a condition, found %s", field, expr.Type()))
+		} else if want != expression.TypeBool && !numeric {
+			problems = append(problems, fmt.Sprintf("%s: must be a number or series, found %s", field, expr.Type()))
+		}
+		return expr
+	}
+
+	rules := &compiledRules{
+		entryBuy:   compile("entry.buy", def.Entry.Buy, expression.TypeBool),
+		entrySell:  compile("entry.sell", def.Entry.Sell, expression.TypeBool),
+		exitBuy:    compile("exit.buy", def.Exit.Buy, expression.TypeBool),
+		exitSell:   compile("exit.sell", def.Exit.Sell, expression.TypeBool),
+		indicators: make(map[string]*expression.Expression),
+	}
+	if def.StopLoss != nil && def.StopLoss.Type == PriceRuleExpression {
+		rules.stopLoss = compile("stop_loss.expression", def.StopLoss.Expression, expression.TypeSeries)
+	}
+	if def.TakeProfit != nil && def.TakeProfit.Type == PriceRuleExpression {
+		rules.takeProfit = compile("take_profit.expression", def.TakeProfit.Expression, expression.TypeSeries)
+	}
+	for name, src := range def.Indicators {
+		if expr := compile("indicators."+name, src, expression.TypeSeries); expr != nil {
+			rules.indicators[name] = expr
+		}
+	}
+
+	return rules, problems
+}
+
+// Definition returns a copy of the definition with defaults applied
+func (r *RuleStrategy) Definition() StrategyDefinition {
+	return r.definition
+}
+
+// ID returns the identifier the strategy is registered under
+func (r *RuleStrategy) ID() string {
+	return r.definition.ID
+}
+
+func (r *RuleStrategy) Name() string {
+	return r.definition.Name
+}
+
+func (r *RuleStrategy) GetParameters() map[string]interface{} {
+	params := make(map[string]interface{}, len(r.values))
+	for _, spec := range r.definition.Parameters {
+		if spec.Type == ParamInt {
+			params[spec.Name] = int(r.values[spec.Name])
+		} else {
+			params[spec.Name] = r.values[spec.Name]
+		}
+	}
+	return params
+}
+
+func (r *RuleStrategy) ParameterSchema() []ParameterSpec {
+	return append([]ParameterSpec(nil), r.definition.Parameters...)
+}
+
+// SetParameters validates the values and recompiles the expressions with them
+func (r *RuleStrategy) SetParameters(params map[string]interface{}) error {
+	values := make(map[string]float64, len(r.values))
+	bindings := make(map[string]interface{}, len(r.values))
+	for name, value := range r.values {
+		values[name] = value
+	}
+	for _, spec := range r.definition.Parameters {
+		value := values[spec.Name]
+		bindings[spec.Name] = &value
+	}
+
+	if err := applyParameters(r.definition.Parameters, params, bindings); err != nil {
+		return err
+	}
+	for name, binding := range bindings {
+		values[name] = *binding.(*float64)
+	}
+
+	rules, problems := compileRules(r.definition, values)
+	if len(problems) > 0 {
+		return paramErrorf("", "%s", strings.Join(problems, "; "))
+	}
+
+	r.values = values
+	r.rules = rules
+	return nil
+}
+
+func (r *RuleStrategy) Clone() TradingAlgorithm {
+	clone := *r
+	clone.values = make(map[string]float64, len(r.values))
+	for name, value := range r.values {
+		clone.values[name] = value
+	}
+	return &clone
+}
+
+func (r *RuleStrategy) Analyze(data []models.HistoricalData) (*models.TradingSignal, error) {
+	if len(data) == 0 || len(data) < r.definition.MinBars {
+		return nil, fmt.Errorf("insufficient data for %s analysis", r.definition.ID)
+	}
+
+	bars := indicators.NewBars(data)
+	latestPrice := data[len(data)-1].Close
+
+	entryBuy, err := lastCondition(r.rules.entryBuy, bars)
+	if err != nil {
+		return nil, fmt.Errorf("entry.buy evaluation failed: %v", err)
+	}
+	entrySell, err := lastCondition(r.rules.entrySell, bars)
+	if err != nil {
+		return nil, fmt.Errorf("entry.sell evaluation failed: %v", err)
+	}
+	exitLong, err := lastCondition(r.rules.exitBuy, bars)
+	if err != nil {
+		return nil, fmt.Errorf("exit.buy evaluation failed: %v", err)
+	}
+	exitShort, err := lastCondition(r.rules.exitSell, bars)
+	if err != nil {
+		return nil, fmt.Errorf("exit.sell evaluation failed: %v", err)
+	}
+
+	signal := &models.TradingSignal{
+		Symbol:    data[len(data)-1].Symbol,
+		Price:     latestPrice,
+		Algorithm: r.Name(),
+		CreatedAt: time.Now(),
+		Indicators: map[string]interface{}{
+			"entry_buy":  entryBuy,
+			"entry_sell": entrySell,
+			"exit_long":  exitLong,
+			"exit_short": exitShort,
+		},
+	}
+
+	for name, expr := range r.rules.indicators {
+		result, err := expr.EvaluateBars(bars)
+		if err != nil {
+			return nil, fmt.Errorf("indicator %s evaluation failed: %v", name, err)
+		}
+		if value, ok := result.Last(); ok {
+			signal.Indicators[name] = decimal.NewFromFloat(value)
+		}
+	}
+
+	switch {
+	case entryBuy && !entrySell:
+		signal.Type = "BUY"
+	case entrySell && !entryBuy:
+		signal.Type = "SELL"
+	default:
+		// No entry, or conflicting entries
+		signal.Type = "HOLD"
+	}
+
+	// The entry conditions are reported as true/false checks on the latest bar
+	var entries []models.SignalCondition
+	if r.rules.entryBuy != nil {
+		entries = append(entries, flag(r.rules.entryBuy.String(), entryBuy))
+	}
+	if r.rules.entrySell != nil {
+		entries = append(entries, flag(r.rules.entrySell.String(), entrySell))
+	}
+
+	var why explanation
+	if signal.Type == "HOLD" {
+		signal.Strength = decimal.NewFromFloat(0.3)
+		signal.RiskLevel = "LOW"
+		signal.Confidence = decimal.NewFromFloat(0.5)
+		description := "No entry condition holds"
+		if entryBuy && entrySell {
+			description = "Buy and sell entry conditions both hold"
+		}
+		why.rule("no_entry", description, 0.3, 0.5, entries...)
+	} else {
+		signal.Strength = decimal.NewFromFloat(r.definition.Strength)
+		signal.RiskLevel = r.definition.RiskLevel
+		signal.Confidence = decimal.NewFromFloat(r.definition.Confidence)
+		why.rule("entry_"+strings.ToLower(signal.Type), fmt.Sprintf("Entry %s condition holds", strings.ToLower(signal.Type)),
+			r.definition.Strength, r.definition.Confidence, entries...)
+
+		price := latestPrice.InexactFloat64()
+		if stop, ok, err := r.priceLevel(r.definition.StopLoss, r.rules.stopLoss, bars, signal.Type, price, false); err != nil {
+			return nil, fmt.Errorf("stop_loss evaluation failed: %v", err)
+		} else if ok {
+			signal.StopLoss = decimal.NewFromFloat(stop)
+		}
+		if target, ok, err := r.priceLevel(r.definition.TakeProfit, r.rules.takeProfit, bars, signal.Type, price, true); err != nil {
+			return nil, fmt.Errorf("take_profit evaluation failed: %v", err)
+		} else if ok {
+			signal.TargetPrice = decimal.NewFromFloat(target)
+		}
+	}
+
+	signal.ExpirationTime = time.Now().Add(r.expiration)
+	signal.TimeFrame = r.definition.TimeFrame
+	why.attach(signal)
+
+	return signal, nil
+}
+
+// priceLevel resolves a stop (profit false) or target (profit true) price.
+// Distances move with the trade for targets and against it for stops.
+func (r *RuleStrategy) priceLevel(rule *PriceRule, expr *expression.Expression, bars *indicators.Bars, signalType string, price float64, profit bool) (float64, bool, error) {
+	if rule == nil {
+		return 0, false, nil
+	}
+
+	direction := 1.0
+	if (signalType == "BUY") != profit {
+		direction = -1.0
+	}
+
+	switch rule.Type {
+	case PriceRulePercent:
+		return price * (1 + direction*rule.Value), true, nil
+
+	case PriceRuleATR:
+		atr, err := indicators.ATRFloat(bars.High, bars.Low, bars.Close, rule.Period)
+		if err != nil {
+			return 0, false, err
+		}
+		return price + direction*rule.Value*atr[len(atr)-1], true, nil
+
+	case PriceRuleExpression:
+		result, err := expr.EvaluateBars(bars)
+		if err != nil {
+			return 0, false, err
+		}
+		level, ok := result.Last()
+		if !ok || math.IsInf(level, 0) {
+			return 0, false, nil
+		}
+		return level, true, nil
+	}
+
+	return 0, false, nil
+}
+
+// lastCondition reports whether an optional condition holds on the latest bar
+func lastCondition(expr *expression.Expression, bars *indicators.Bars) (bool, error) {
+	if expr == nil {
+		return false, nil
+	}
+	result, err := expr.EvaluateBars(bars)
+	if err != nil {
+		return false, err
+	}
+	return result.Bool(result.Len() - 1), nil
+}
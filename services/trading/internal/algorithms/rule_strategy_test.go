@@ -0,0 +1,47 @@
+package algorithms
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func ruleDefinition(param string) StrategyDefinition {
+	return StrategyDefinition{
+		ID:   "rsi_rule",
+		Name: "RSI rule",
+		Parameters: []ParameterSpec{
+			{Name: param, Type: ParamInt, Default: 14, Min: 2, Max: 50},
+		},
+		Entry: RuleConditions{
+			Buy:  "RSI(close, " + param + ") < 30",
+			Sell: "RSI(close, " + param + ") > 70",
+		},
+	}
+}
+
+func TestRuleStrategyParameterNames(t *testing.T) {
+	strategy, err := NewRuleStrategy(ruleDefinition("rsi_period"))
+	if err != nil {
+		t.Fatalf("valid definition rejected: %v", err)
+	}
+	if _, err := strategy.Analyze(testBars(120, 3)); err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	for _, name := range []string{"and", "or", "not", "true", "false", "close", "sma", "2fast"} {
+		_, err := NewRuleStrategy(ruleDefinition(name))
+		var defErr *DefinitionError
+		if !errors.As(err, &defErr) {
+			t.Fatalf("parameter %q: error = %v, want a DefinitionError", name, err)
+		}
+		if !strings.Contains(defErr.Error(), "parameters."+name) {
+			t.Fatalf("parameter %q: problems %v don't name the parameter", name, defErr.Problems)
+		}
+	}
+
+	_, err = NewRuleStrategy(ruleDefinition("not"))
+	if err == nil || !strings.Contains(err.Error(), "reserved word") {
+		t.Fatalf("keyword parameter error = %v, want a reserved word problem", err)
+	}
+}
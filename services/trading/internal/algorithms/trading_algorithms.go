@@ -3,10 +3,14 @@ package algorithms
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 	"github.com/shopspring/decimal"
 	"trading-service/internal/models"
 	"trading-service/internal/indicators"
+	"trading-service/internal/ml"
 )
 
 // TradingAlgorithm interface for all trading algorithms
@@ -15,6 +19,21 @@ type TradingAlgorithm interface {
 	Analyze(data []models.HistoricalData) (*models.TradingSignal, error)
 	GetParameters() map[string]interface{}
 	SetParameters(params map[string]interface{}) error
+	ParameterSchema() []ParameterSpec
+	Clone() TradingAlgorithm
+}
+
+// MultiAssetAlgorithm is the companion interface for strategies that analyze
+// several symbols together. Series are passed in the order the strategy
+// documents and one signal is returned per series.
+type MultiAssetAlgorithm interface {
+	Name() string
+	Assets() int
+	AnalyzeMulti(series [][]models.HistoricalData) ([]*models.TradingSignal, error)
+	GetParameters() map[string]interface{}
+	SetParameters(params map[string]interface{}) error
+	ParameterSchema() []ParameterSpec
+	Clone() MultiAssetAlgorithm
 }
 
 // MomentumStrategy implements momentum-based trading
@@ -26,6 +45,9 @@ type MomentumStrategy struct {
 	MACDSlow       int     `json:"macd_slow"`
 	MACDSignal     int     `json:"macd_signal"`
 	VolumeThreshold float64 `json:"volume_threshold"`
+	LevelSwingStrength int  `json:"level_swing_strength"`
+	LevelTolerance  float64 `json:"level_tolerance"`
+	DivergenceLookback int  `json:"divergence_lookback"` // Bars; 0 disables divergence confirmation
 }
 
 func NewMomentumStrategy() *MomentumStrategy {
@@ -37,6 +59,9 @@ func NewMomentumStrategy() *MomentumStrategy {
 		MACDSlow:        26,
 		MACDSignal:      9,
 		VolumeThreshold: 1.5, // 50% above average volume
+		LevelSwingStrength: 3,
+		LevelTolerance:  0.01, // Cluster levels within 1% of each other
+		DivergenceLookback: 10,
 	}
 }
 
@@ -53,58 +78,95 @@ func (m *MomentumStrategy) GetParameters() map[string]interface{} {
 		"macd_slow":        m.MACDSlow,
 		"macd_signal":      m.MACDSignal,
 		"volume_threshold": m.VolumeThreshold,
+		"level_swing_strength": m.LevelSwingStrength,
+		"level_tolerance":  m.LevelTolerance,
+		"divergence_lookback": m.DivergenceLookback,
 	}
 }
 
+var momentumSchema = []ParameterSpec{
+	{Name: "rsi_period", Type: ParamInt, Min: 2, Max: 100, Description: "RSI lookback in bars"},
+	{Name: "rsi_overbought", Type: ParamFloat, Min: 50, Max: 100, Description: "RSI level above which a strong SELL is possible"},
+	{Name: "rsi_oversold", Type: ParamFloat, Min: 0, Max: 50, Description: "RSI level below which a strong BUY is possible"},
+	{Name: "macd_fast", Type: ParamInt, Min: 2, Max: 100, Description: "MACD fast EMA period"},
+	{Name: "macd_slow", Type: ParamInt, Min: 3, Max: 200, Description: "MACD slow EMA period"},
+	{Name: "macd_signal", Type: ParamInt, Min: 2, Max: 100, Description: "MACD signal line period"},
+	{Name: "volume_threshold", Type: ParamFloat, Min: 1, Max: 10, Description: "Volume to 20-bar average ratio that boosts confidence"},
+	{Name: "level_swing_strength", Type: ParamInt, Min: 1, Max: 20, Description: "Bars on each side of a swing used for support/resistance"},
+	{Name: "level_tolerance", Type: ParamFloat, Min: 0.001, Max: 0.1, Description: "Relative distance within which levels are merged"},
+	{Name: "divergence_lookback", Type: ParamInt, Min: 0, Max: 100, Description: "Bars of divergences used as confirmation, 0 disables"},
+}
+
+func (m *MomentumStrategy) ParameterSchema() []ParameterSpec {
+	return withDefaults(momentumSchema, NewMomentumStrategy().GetParameters())
+}
+
 func (m *MomentumStrategy) SetParameters(params map[string]interface{}) error {
-	if val, ok := params["rsi_period"].(int); ok {
-		m.RSIPeriod = val
+	updated := *m
+	err := applyParameters(momentumSchema, params, map[string]interface{}{
+		"rsi_period":           &updated.RSIPeriod,
+		"rsi_overbought":       &updated.RSIOverBought,
+		"rsi_oversold":         &updated.RSIOverSold,
+		"macd_fast":            &updated.MACDFast,
+		"macd_slow":            &updated.MACDSlow,
+		"macd_signal":          &updated.MACDSignal,
+		"volume_threshold":     &updated.VolumeThreshold,
+		"level_swing_strength": &updated.LevelSwingStrength,
+		"level_tolerance":      &updated.LevelTolerance,
+		"divergence_lookback":  &updated.DivergenceLookback,
+	})
+	if err != nil {
+		return err
 	}
-	if val, ok := params["rsi_overbought"].(float64); ok {
-		m.RSIOverBought = val
+	if err := requireLess("rsi_oversold", updated.RSIOverSold, "rsi_overbought", updated.RSIOverBought); err != nil {
+		return err
 	}
-	if val, ok := params["rsi_oversold"].(float64); ok {
-		m.RSIOverSold = val
+	if err := requireLess("macd_fast", float64(updated.MACDFast), "macd_slow", float64(updated.MACDSlow)); err != nil {
+		return err
 	}
+
+	*m = updated
 	return nil
 }
 
+func (m *MomentumStrategy) Clone() TradingAlgorithm {
+	clone := *m
+	return &clone
+}
+
 func (m *MomentumStrategy) Analyze(data []models.HistoricalData) (*models.TradingSignal, error) {
 	if len(data) < m.MACDSlow+m.MACDSignal {
 		return nil, fmt.Errorf("insufficient data for momentum analysis")
 	}
 
-	// Extract price data
-	var closes []decimal.Decimal
-	var volumes []int64
-	for _, d := range data {
-		closes = append(closes, d.Close)
-		volumes = append(volumes, d.Volume)
-	}
+	bars := indicators.NewBars(data)
 
 	// Calculate RSI
-	rsiValues, err := indicators.RSI(closes, m.RSIPeriod)
+	rsiValues, err := indicators.RSIFloat(bars.Close, m.RSIPeriod)
 	if err != nil {
 		return nil, fmt.Errorf("RSI calculation failed: %v", err)
 	}
 
 	// Calculate MACD
-	macdLine, signalLine, histogram, err := indicators.MACD(closes, m.MACDFast, m.MACDSlow, m.MACDSignal)
+	macdLine, signalLine, histogram, err := indicators.MACDFloat(bars.Close, m.MACDFast, m.MACDSlow, m.MACDSignal)
 	if err != nil {
 		return nil, fmt.Errorf("MACD calculation failed: %v", err)
 	}
 
 	// Get latest values
-	latestRSI := rsiValues[len(rsiValues)-1]
-	latestMACD := macdLine[len(macdLine)-1]
-	latestSignal := signalLine[len(signalLine)-1]
-	latestHistogram := histogram[len(histogram)-1]
-	latestPrice := closes[len(closes)-1]
-	latestVolume := volumes[len(volumes)-1]
+	latestRSI := decimal.NewFromFloat(rsiValues[len(rsiValues)-1])
+	latestMACD := decimal.NewFromFloat(macdLine[len(macdLine)-1])
+	latestSignal := decimal.NewFromFloat(signalLine[len(signalLine)-1])
+	latestHistogram := decimal.NewFromFloat(histogram[len(histogram)-1])
+	latestPrice := data[len(data)-1].Close
+	latestVolume := data[len(data)-1].Volume
 
 	// Calculate average volume
-	avgVolume := calculateAverageVolume(volumes, 20)
-	volumeRatio := float64(latestVolume) / float64(avgVolume)
+	avgVolume := calculateAverageVolume(bars.Volume, 20)
+	volumeRatio := 0.0
+	if avgVolume > 0 {
+		volumeRatio = float64(latestVolume) / avgVolume
+	}
 
 	// Generate signal
 	signal := &models.TradingSignal{
@@ -123,6 +185,10 @@ func (m *MomentumStrategy) Analyze(data []models.HistoricalData) (*models.Tradin
 
 	// Determine signal type and strength
 	rsiFloat, _ := latestRSI.Float64()
+	macdFloat, _ := latestMACD.Float64()
+	signalFloat, _ := latestSignal.Float64()
+	histogramFloat, _ := latestHistogram.Float64()
+	var why explanation
 	
 	if rsiFloat < m.RSIOverSold && latestMACD.GreaterThan(latestSignal) && latestHistogram.GreaterThan(decimal.Zero) {
 		// Strong BUY signal
@@ -130,10 +196,14 @@ func (m *MomentumStrategy) Analyze(data []models.HistoricalData) (*models.Tradin
 		signal.Strength = decimal.NewFromFloat(0.8)
 		signal.RiskLevel = "MEDIUM"
 		signal.Confidence = decimal.NewFromFloat(0.85)
+		why.rule("strong_buy", "RSI oversold with MACD above its signal line and a rising histogram", 0.8, 0.85,
+			condition("rsi", "<", rsiFloat, m.RSIOverSold, "rsi_oversold"),
+			condition("macd", ">", macdFloat, signalFloat, "macd_signal"),
+			condition("macd_histogram", ">", histogramFloat, 0, ""))
 		
-		// Set target and stop loss
-		signal.TargetPrice = latestPrice.Mul(decimal.NewFromFloat(1.05)) // 5% target
-		signal.StopLoss = latestPrice.Mul(decimal.NewFromFloat(0.97))    // 3% stop loss
+		// Target the nearest resistance and stop below the nearest support,
+		// falling back to a 5% target / 3% stop when no level brackets price
+		signal.TargetPrice, signal.StopLoss = keyLevelTargets(data, "BUY", latestPrice, m.LevelSwingStrength, m.LevelTolerance, 0.05, 0.03)
 		
 	} else if rsiFloat > m.RSIOverBought && latestMACD.LessThan(latestSignal) && latestHistogram.LessThan(decimal.Zero) {
 		// Strong SELL signal
@@ -141,10 +211,13 @@ func (m *MomentumStrategy) Analyze(data []models.HistoricalData) (*models.Tradin
 		signal.Strength = decimal.NewFromFloat(0.8)
 		signal.RiskLevel = "MEDIUM"
 		signal.Confidence = decimal.NewFromFloat(0.85)
+		why.rule("strong_sell", "RSI overbought with MACD below its signal line and a falling histogram", 0.8, 0.85,
+			condition("rsi", ">", rsiFloat, m.RSIOverBought, "rsi_overbought"),
+			condition("macd", "<", macdFloat, signalFloat, "macd_signal"),
+			condition("macd_histogram", "<", histogramFloat, 0, ""))
 		
-		// Set target and stop loss for short
-		signal.TargetPrice = latestPrice.Mul(decimal.NewFromFloat(0.95)) // 5% target down
-		signal.StopLoss = latestPrice.Mul(decimal.NewFromFloat(1.03))    // 3% stop loss up
+		// Set target and stop loss for short from key levels
+		signal.TargetPrice, signal.StopLoss = keyLevelTargets(data, "SELL", latestPrice, m.LevelSwingStrength, m.LevelTolerance, 0.05, 0.03)
 		
 	} else if rsiFloat < 50 && latestMACD.GreaterThan(latestSignal) {
 		// Weak BUY signal
@@ -152,6 +225,9 @@ func (m *MomentumStrategy) Analyze(data []models.HistoricalData) (*models.Tradin
 		signal.Strength = decimal.NewFromFloat(0.5)
 		signal.RiskLevel = "LOW"
 		signal.Confidence = decimal.NewFromFloat(0.65)
+		why.rule("weak_buy", "RSI below 50 with MACD above its signal line", 0.5, 0.65,
+			condition("rsi", "<", rsiFloat, 50, ""),
+			condition("macd", ">", macdFloat, signalFloat, "macd_signal"))
 		
 	} else if rsiFloat > 50 && latestMACD.LessThan(latestSignal) {
 		// Weak SELL signal
@@ -159,6 +235,9 @@ func (m *MomentumStrategy) Analyze(data []models.HistoricalData) (*models.Tradin
 		signal.Strength = decimal.NewFromFloat(0.5)
 		signal.RiskLevel = "LOW"
 		signal.Confidence = decimal.NewFromFloat(0.65)
+		why.rule("weak_sell", "RSI above 50 with MACD below its signal line", 0.5, 0.65,
+			condition("rsi", ">", rsiFloat, 50, ""),
+			condition("macd", "<", macdFloat, signalFloat, "macd_signal"))
 		
 	} else {
 		// HOLD signal
@@ -166,14 +245,26 @@ func (m *MomentumStrategy) Analyze(data []models.HistoricalData) (*models.Tradin
 		signal.Strength = decimal.NewFromFloat(0.3)
 		signal.RiskLevel = "LOW"
 		signal.Confidence = decimal.NewFromFloat(0.5)
+		why.rule("no_setup", "RSI and MACD disagree on direction", 0.3, 0.5,
+			condition("rsi", "<", rsiFloat, 50, ""),
+			condition("macd", ">", macdFloat, signalFloat, "macd_signal"))
 	}
 
 	// Adjust confidence based on volume
 	if volumeRatio > m.VolumeThreshold {
 		currentConf, _ := signal.Confidence.Float64()
-		signal.Confidence = decimal.NewFromFloat(math.Min(currentConf*1.2, 1.0))
+		adjusted := math.Min(currentConf*1.2, 1.0)
+		signal.Confidence = decimal.NewFromFloat(adjusted)
+		why.adjust("volume_confirmation", "Volume well above its 20-bar average", 0, adjusted-currentConf,
+			condition("volume_ratio", ">", volumeRatio, m.VolumeThreshold, "volume_threshold"))
 	}
 
+	// Use recent price/oscillator divergences as confirmation
+	if m.DivergenceLookback > 0 {
+		m.applyDivergenceConfirmation(signal, bars, &why)
+	}
+	why.attach(signal)
+
 	// Set expiration time
 	signal.ExpirationTime = time.Now().Add(time.Hour * 4)
 	signal.TimeFrame = "1h"
@@ -181,6 +272,44 @@ func (m *MomentumStrategy) Analyze(data []models.HistoricalData) (*models.Tradin
 	return signal, nil
 }
 
+// applyDivergenceConfirmation raises confidence when recent divergences agree
+// with the signal direction and lowers it when they contradict it
+func (m *MomentumStrategy) applyDivergenceConfirmation(signal *models.TradingSignal, bars *indicators.Bars, why *explanation) {
+	cfg := indicators.DefaultDivergenceConfig()
+	cfg.RSIPeriod = m.RSIPeriod
+	cfg.MACDFast = m.MACDFast
+	cfg.MACDSlow = m.MACDSlow
+	cfg.MACDSignal = m.MACDSignal
+
+	divergences, err := indicators.DetectDivergences(bars, cfg)
+	if err != nil {
+		return
+	}
+
+	recent := indicators.RecentDivergences(divergences, m.DivergenceLookback)
+	bias := indicators.DivergenceBias(recent)
+	signal.Indicators["divergence_bias"] = bias
+	signal.Indicators["divergences"] = recent
+
+	// A positive bias is bullish and a negative one bearish
+	observed := condition("divergence_bias", ">", float64(bias), 0, "")
+	if bias < 0 {
+		observed = condition("divergence_bias", "<", float64(bias), 0, "")
+	}
+
+	previous, _ := signal.Confidence.Float64()
+	confidence := previous
+	switch {
+	case signal.Type == "BUY" && bias > 0, signal.Type == "SELL" && bias < 0:
+		confidence = math.Min(confidence*1.1, 1.0)
+		why.adjust("divergence_confirmation", "Recent divergences agree with the signal", 0, confidence-previous, observed)
+	case signal.Type == "BUY" && bias < 0, signal.Type == "SELL" && bias > 0:
+		confidence *= 0.8
+		why.adjust("divergence_conflict", "Recent divergences contradict the signal", 0, confidence-previous, observed)
+	}
+	signal.Confidence = decimal.NewFromFloat(confidence)
+}
+
 // MeanReversionStrategy implements mean reversion trading
 type MeanReversionStrategy struct {
 	BollingerPeriod     int     `json:"bollinger_period"`
@@ -217,40 +346,75 @@ func (mr *MeanReversionStrategy) GetParameters() map[string]interface{} {
 	}
 }
 
+var meanReversionSchema = []ParameterSpec{
+	{Name: "bollinger_period", Type: ParamInt, Min: 5, Max: 200, Description: "Bollinger Band moving average period"},
+	{Name: "bollinger_std_dev", Type: ParamFloat, Min: 0.5, Max: 5, Description: "Band width in standard deviations"},
+	{Name: "rsi_period", Type: ParamInt, Min: 2, Max: 100, Description: "RSI lookback in bars"},
+	{Name: "rsi_extreme_overbought", Type: ParamFloat, Min: 50, Max: 100, Description: "RSI level confirming a SELL above the upper band"},
+	{Name: "rsi_extreme_oversold", Type: ParamFloat, Min: 0, Max: 50, Description: "RSI level confirming a BUY below the lower band"},
+	{Name: "mean_reversion_period", Type: ParamInt, Min: 10, Max: 500, Description: "Minimum bars of history required"},
+}
+
+func (mr *MeanReversionStrategy) ParameterSchema() []ParameterSpec {
+	return withDefaults(meanReversionSchema, NewMeanReversionStrategy().GetParameters())
+}
+
 func (mr *MeanReversionStrategy) SetParameters(params map[string]interface{}) error {
-	// Implementation similar to MomentumStrategy
+	updated := *mr
+	err := applyParameters(meanReversionSchema, params, map[string]interface{}{
+		"bollinger_period":       &updated.BollingerPeriod,
+		"bollinger_std_dev":      &updated.BollingerStdDev,
+		"rsi_period":             &updated.RSIPeriod,
+		"rsi_extreme_overbought": &updated.RSIExtremeOverbought,
+		"rsi_extreme_oversold":   &updated.RSIExtremeOversold,
+		"mean_reversion_period":  &updated.MeanReversionPeriod,
+	})
+	if err != nil {
+		return err
+	}
+	if err := requireLess("rsi_extreme_oversold", updated.RSIExtremeOversold, "rsi_extreme_overbought", updated.RSIExtremeOverbought); err != nil {
+		return err
+	}
+	// Analyze only checks for mean_reversion_period bars, which must cover both indicators
+	if updated.BollingerPeriod > updated.MeanReversionPeriod || updated.RSIPeriod >= updated.MeanReversionPeriod {
+		return paramErrorf("", "mean_reversion_period (%d) must cover bollinger_period (%d) and exceed rsi_period (%d)",
+			updated.MeanReversionPeriod, updated.BollingerPeriod, updated.RSIPeriod)
+	}
+
+	*mr = updated
 	return nil
 }
 
+func (mr *MeanReversionStrategy) Clone() TradingAlgorithm {
+	clone := *mr
+	return &clone
+}
+
 func (mr *MeanReversionStrategy) Analyze(data []models.HistoricalData) (*models.TradingSignal, error) {
 	if len(data) < mr.MeanReversionPeriod {
 		return nil, fmt.Errorf("insufficient data for mean reversion analysis")
 	}
 
-	// Extract price data
-	var closes []decimal.Decimal
-	for _, d := range data {
-		closes = append(closes, d.Close)
-	}
+	bars := indicators.NewBars(data)
 
 	// Calculate Bollinger Bands
-	upperBand, middleBand, lowerBand, err := indicators.BollingerBands(closes, mr.BollingerPeriod, mr.BollingerStdDev)
+	upperBand, middleBand, lowerBand, err := indicators.BollingerBandsFloat(bars.Close, mr.BollingerPeriod, mr.BollingerStdDev)
 	if err != nil {
 		return nil, fmt.Errorf("Bollinger Bands calculation failed: %v", err)
 	}
 
 	// Calculate RSI
-	rsiValues, err := indicators.RSI(closes, mr.RSIPeriod)
+	rsiValues, err := indicators.RSIFloat(bars.Close, mr.RSIPeriod)
 	if err != nil {
 		return nil, fmt.Errorf("RSI calculation failed: %v", err)
 	}
 
 	// Get latest values
-	latestPrice := closes[len(closes)-1]
-	latestUpper := upperBand[len(upperBand)-1]
-	latestMiddle := middleBand[len(middleBand)-1]
-	latestLower := lowerBand[len(lowerBand)-1]
-	latestRSI := rsiValues[len(rsiValues)-1]
+	latestPrice := data[len(data)-1].Close
+	latestUpper := decimal.NewFromFloat(upperBand[len(upperBand)-1])
+	latestMiddle := decimal.NewFromFloat(middleBand[len(middleBand)-1])
+	latestLower := decimal.NewFromFloat(lowerBand[len(lowerBand)-1])
+	latestRSI := decimal.NewFromFloat(rsiValues[len(rsiValues)-1])
 
 	// Calculate distance from bands
 	upperDistance := latestPrice.Sub(latestUpper).Div(latestUpper)
@@ -272,6 +436,9 @@ func (mr *MeanReversionStrategy) Analyze(data []models.HistoricalData) (*models.
 	}
 
 	rsiFloat, _ := latestRSI.Float64()
+	priceFloat := latestPrice.InexactFloat64()
+	upperFloat, lowerFloat := upperBand[len(upperBand)-1], lowerBand[len(lowerBand)-1]
+	var why explanation
 
 	// Mean reversion logic
 	if latestPrice.LessThan(latestLower) && rsiFloat < mr.RSIExtremeOversold {
@@ -282,6 +449,9 @@ func (mr *MeanReversionStrategy) Analyze(data []models.HistoricalData) (*models.
 		signal.Confidence = decimal.NewFromFloat(0.8)
 		signal.TargetPrice = latestMiddle // Target middle band
 		signal.StopLoss = latestPrice.Mul(decimal.NewFromFloat(0.95))
+		why.rule("oversold_reversion", "Price below the lower Bollinger Band with RSI at an extreme low", 0.9, 0.8,
+			condition("price", "<", priceFloat, lowerFloat, "lower_band"),
+			condition("rsi", "<", rsiFloat, mr.RSIExtremeOversold, "rsi_extreme_oversold"))
 		
 	} else if latestPrice.GreaterThan(latestUpper) && rsiFloat > mr.RSIExtremeOverbought {
 		// Strong SELL signal - price above upper band and RSI overbought
@@ -291,6 +461,9 @@ func (mr *MeanReversionStrategy) Analyze(data []models.HistoricalData) (*models.
 		signal.Confidence = decimal.NewFromFloat(0.8)
 		signal.TargetPrice = latestMiddle // Target middle band
 		signal.StopLoss = latestPrice.Mul(decimal.NewFromFloat(1.05))
+		why.rule("overbought_reversion", "Price above the upper Bollinger Band with RSI at an extreme high", 0.9, 0.8,
+			condition("price", ">", priceFloat, upperFloat, "upper_band"),
+			condition("rsi", ">", rsiFloat, mr.RSIExtremeOverbought, "rsi_extreme_overbought"))
 		
 	} else {
 		// HOLD signal
@@ -298,7 +471,13 @@ func (mr *MeanReversionStrategy) Analyze(data []models.HistoricalData) (*models.
 		signal.Strength = decimal.NewFromFloat(0.3)
 		signal.RiskLevel = "LOW"
 		signal.Confidence = decimal.NewFromFloat(0.5)
+		why.rule("no_setup", "Price not stretched beyond a band with an extreme RSI", 0.3, 0.5,
+			condition("price", "<", priceFloat, lowerFloat, "lower_band"),
+			condition("price", ">", priceFloat, upperFloat, "upper_band"),
+			condition("rsi", "<", rsiFloat, mr.RSIExtremeOversold, "rsi_extreme_oversold"),
+			condition("rsi", ">", rsiFloat, mr.RSIExtremeOverbought, "rsi_extreme_overbought"))
 	}
+	why.attach(signal)
 
 	signal.ExpirationTime = time.Now().Add(time.Hour * 2)
 	signal.TimeFrame = "30m"
@@ -342,48 +521,76 @@ func (tf *TrendFollowingStrategy) GetParameters() map[string]interface{} {
 	}
 }
 
+var trendFollowingSchema = []ParameterSpec{
+	{Name: "ema_fast", Type: ParamInt, Min: 2, Max: 100, Description: "Fast EMA period"},
+	{Name: "ema_slow", Type: ParamInt, Min: 3, Max: 200, Description: "Slow EMA period"},
+	{Name: "adx_period", Type: ParamInt, Min: 2, Max: 100, Description: "ADX lookback in bars"},
+	{Name: "adx_threshold", Type: ParamFloat, Min: 0, Max: 100, Description: "ADX level above which a trend is tradeable"},
+	{Name: "atr_period", Type: ParamInt, Min: 2, Max: 100, Description: "ATR lookback in bars"},
+	{Name: "atr_multiplier", Type: ParamFloat, Min: 0.5, Max: 10, Description: "ATR multiple for stop distance"},
+}
+
+func (tf *TrendFollowingStrategy) ParameterSchema() []ParameterSpec {
+	return withDefaults(trendFollowingSchema, NewTrendFollowingStrategy().GetParameters())
+}
+
 func (tf *TrendFollowingStrategy) SetParameters(params map[string]interface{}) error {
-	// Implementation similar to other strategies
+	updated := *tf
+	err := applyParameters(trendFollowingSchema, params, map[string]interface{}{
+		"ema_fast":       &updated.EMAFast,
+		"ema_slow":       &updated.EMASlow,
+		"adx_period":     &updated.ADXPeriod,
+		"adx_threshold":  &updated.ADXThreshold,
+		"atr_period":     &updated.ATRPeriod,
+		"atr_multiplier": &updated.ATRMultiplier,
+	})
+	if err != nil {
+		return err
+	}
+	if err := requireLess("ema_fast", float64(updated.EMAFast), "ema_slow", float64(updated.EMASlow)); err != nil {
+		return err
+	}
+
+	*tf = updated
 	return nil
 }
 
+func (tf *TrendFollowingStrategy) Clone() TradingAlgorithm {
+	clone := *tf
+	return &clone
+}
+
 func (tf *TrendFollowingStrategy) Analyze(data []models.HistoricalData) (*models.TradingSignal, error) {
 	if len(data) < tf.EMASlow+tf.ADXPeriod {
 		return nil, fmt.Errorf("insufficient data for trend following analysis")
 	}
 
-	// Extract OHLC data
-	var highs, lows, closes []decimal.Decimal
-	for _, d := range data {
-		highs = append(highs, d.High)
-		lows = append(lows, d.Low)
-		closes = append(closes, d.Close)
-	}
+	bars := indicators.NewBars(data)
 
 	// Calculate EMAs
-	fastEMA, err := indicators.EMA(closes, tf.EMAFast)
+	fastEMA, err := indicators.EMAFloat(bars.Close, tf.EMAFast)
 	if err != nil {
 		return nil, fmt.Errorf("Fast EMA calculation failed: %v", err)
 	}
 
-	slowEMA, err := indicators.EMA(closes, tf.EMASlow)
+	slowEMA, err := indicators.EMAFloat(bars.Close, tf.EMASlow)
 	if err != nil {
 		return nil, fmt.Errorf("Slow EMA calculation failed: %v", err)
 	}
 
 	// Calculate ADX for trend strength
-	adx, plusDI, minusDI, err := indicators.ADX(highs, lows, closes, tf.ADXPeriod)
+	adx, plusDI, minusDI, err := indicators.ADXFloat(bars.High, bars.Low, bars.Close, tf.ADXPeriod)
 	if err != nil {
 		return nil, fmt.Errorf("ADX calculation failed: %v", err)
 	}
 
 	// Get latest values
-	latestPrice := closes[len(closes)-1]
-	latestFastEMA := fastEMA[len(fastEMA)-1]
-	latestSlowEMA := slowEMA[len(slowEMA)-1]
-	latestADX := adx[len(adx)-1]
-	latestPlusDI := plusDI[len(plusDI)-1]
-	latestMinusDI := minusDI[len(minusDI)-1]
+	latestPrice := data[len(data)-1].Close
+	latestFastEMA := decimal.NewFromFloat(fastEMA[len(fastEMA)-1])
+	latestSlowEMA := decimal.NewFromFloat(slowEMA[len(slowEMA)-1])
+	latestADX := decimal.NewFromFloat(adx[len(adx)-1])
+	latestPlusDI := decimal.NewFromFloat(plusDI[len(plusDI)-1])
+	latestMinusDI := decimal.NewFromFloat(minusDI[len(minusDI)-1])
 
 	signal := &models.TradingSignal{
 		Symbol:    data[len(data)-1].Symbol,
@@ -400,6 +607,9 @@ func (tf *TrendFollowingStrategy) Analyze(data []models.HistoricalData) (*models
 	}
 
 	adxFloat, _ := latestADX.Float64()
+	fastFloat, slowFloat := fastEMA[len(fastEMA)-1], slowEMA[len(slowEMA)-1]
+	plusFloat, minusFloat := plusDI[len(plusDI)-1], minusDI[len(minusDI)-1]
+	var why explanation
 
 	// Trend following logic
 	if latestFastEMA.GreaterThan(latestSlowEMA) && adxFloat > tf.ADXThreshold && latestPlusDI.GreaterThan(latestMinusDI) {
@@ -408,6 +618,10 @@ func (tf *TrendFollowingStrategy) Analyze(data []models.HistoricalData) (*models
 		signal.Strength = decimal.NewFromFloat(0.8)
 		signal.RiskLevel = "MEDIUM"
 		signal.Confidence = decimal.NewFromFloat(0.85)
+		why.rule("uptrend", "Fast EMA above slow EMA in a strong trend led by +DI", 0.8, 0.85,
+			condition("fast_ema", ">", fastFloat, slowFloat, "slow_ema"),
+			condition("adx", ">", adxFloat, tf.ADXThreshold, "adx_threshold"),
+			condition("plus_di", ">", plusFloat, minusFloat, "minus_di"))
 		
 	} else if latestFastEMA.LessThan(latestSlowEMA) && adxFloat > tf.ADXThreshold && latestMinusDI.GreaterThan(latestPlusDI) {
 		// Strong downtrend - SELL signal
@@ -415,6 +629,10 @@ func (tf *TrendFollowingStrategy) Analyze(data []models.HistoricalData) (*models
 		signal.Strength = decimal.NewFromFloat(0.8)
 		signal.RiskLevel = "MEDIUM"
 		signal.Confidence = decimal.NewFromFloat(0.85)
+		why.rule("downtrend", "Fast EMA below slow EMA in a strong trend led by -DI", 0.8, 0.85,
+			condition("fast_ema", "<", fastFloat, slowFloat, "slow_ema"),
+			condition("adx", ">", adxFloat, tf.ADXThreshold, "adx_threshold"),
+			condition("minus_di", ">", minusFloat, plusFloat, "plus_di"))
 		
 	} else {
 		// No clear trend - HOLD
@@ -422,7 +640,12 @@ func (tf *TrendFollowingStrategy) Analyze(data []models.HistoricalData) (*models
 		signal.Strength = decimal.NewFromFloat(0.3)
 		signal.RiskLevel = "LOW"
 		signal.Confidence = decimal.NewFromFloat(0.5)
+		why.rule("no_trend", "EMAs, ADX and directional indicators do not agree on a strong trend", 0.3, 0.5,
+			condition("fast_ema", ">", fastFloat, slowFloat, "slow_ema"),
+			condition("adx", ">", adxFloat, tf.ADXThreshold, "adx_threshold"),
+			condition("plus_di", ">", plusFloat, minusFloat, "minus_di"))
 	}
+	why.attach(signal)
 
 	signal.ExpirationTime = time.Now().Add(time.Hour * 6)
 	signal.TimeFrame = "1h"
@@ -432,18 +655,41 @@ func (tf *TrendFollowingStrategy) Analyze(data []models.HistoricalData) (*models
 
 // CompositeStrategy combines multiple algorithms
 type CompositeStrategy struct {
-	Strategies []TradingAlgorithm
-	Weights    []float64
+	Members []CompositeMember
+
+	// Adaptive weighting scales each member's base weight by its realized
+	// performance over the trailing AdaptiveWindow bars
+	Weighting       int `json:"weighting"`        // WeightingFixed, WeightingHitRate or WeightingSharpe
+	AdaptiveWindow  int `json:"adaptive_window"`  // Bars of history to score
+	AdaptiveHorizon int `json:"adaptive_horizon"` // Bars ahead each past signal is judged over
+	AdaptiveStep    int `json:"adaptive_step"`    // Bars between scored signals
+	MinSignals      int `json:"min_signals"`      // Directional signals needed before a score is trusted
+
+	// RegimeRouting limits the consensus to members suited to the current
+	// market regime (see AlgorithmsForRegime)
+	RegimeRouting int `json:"regime_routing"` // 1 enables
+}
+
+// CompositeMember is a sub-strategy and its base weight. Key identifies the
+// member in weight_<key> parameters and in the reported weights.
+type CompositeMember struct {
+	Key      string
+	Strategy TradingAlgorithm
+	Weight   float64
 }
 
 func NewCompositeStrategy() *CompositeStrategy {
 	return &CompositeStrategy{
-		Strategies: []TradingAlgorithm{
-			NewMomentumStrategy(),
-			NewMeanReversionStrategy(),
-			NewTrendFollowingStrategy(),
+		Members: []CompositeMember{
+			{Key: "momentum", Strategy: NewMomentumStrategy(), Weight: 0.4},
+			{Key: "mean_reversion", Strategy: NewMeanReversionStrategy(), Weight: 0.3},
+			{Key: "trend_following", Strategy: NewTrendFollowingStrategy(), Weight: 0.3},
 		},
-		Weights: []float64{0.4, 0.3, 0.3}, // Equal-ish weighting
+		Weighting:       WeightingFixed,
+		AdaptiveWindow:  120,
+		AdaptiveHorizon: 5,
+		AdaptiveStep:    5,
+		MinSignals:      5,
 	}
 }
 
@@ -452,39 +698,192 @@ func (cs *CompositeStrategy) Name() string {
 }
 
 func (cs *CompositeStrategy) GetParameters() map[string]interface{} {
-	params := make(map[string]interface{})
-	for i, strategy := range cs.Strategies {
-		strategyParams := strategy.GetParameters()
+	params := map[string]interface{}{
+		"weighting":        cs.Weighting,
+		"adaptive_window":  cs.AdaptiveWindow,
+		"adaptive_horizon": cs.AdaptiveHorizon,
+		"adaptive_step":    cs.AdaptiveStep,
+		"min_signals":      cs.MinSignals,
+		"regime_routing":   cs.RegimeRouting,
+	}
+	for _, member := range cs.Members {
+		strategyParams := member.Strategy.GetParameters()
 		for key, value := range strategyParams {
-			params[fmt.Sprintf("%s_%s", strategy.Name(), key)] = value
+			params[fmt.Sprintf("%s_%s", member.Strategy.Name(), key)] = value
 		}
-		params[fmt.Sprintf("weight_%d", i)] = cs.Weights[i]
+		params["weight_"+member.Key] = member.Weight
 	}
 	return params
 }
 
+var compositeSchema = []ParameterSpec{
+	{Name: "weighting", Type: ParamInt, Min: WeightingFixed, Max: WeightingSharpe, Description: "0 = fixed weights, 1 = scale by rolling hit rate, 2 = scale by rolling Sharpe"},
+	{Name: "adaptive_window", Type: ParamInt, Min: 20, Max: 1000, Description: "Bars of history used to score each strategy"},
+	{Name: "adaptive_horizon", Type: ParamInt, Min: 1, Max: 60, Description: "Bars ahead each past signal is judged over"},
+	{Name: "adaptive_step", Type: ParamInt, Min: 1, Max: 50, Description: "Bars between scored signals"},
+	{Name: "min_signals", Type: ParamInt, Min: 1, Max: 100, Description: "Directional signals a strategy needs before its score is used"},
+	{Name: "regime_routing", Type: ParamInt, Min: 0, Max: 1, Description: "1 = only combine strategies suited to the current market regime"},
+}
+
+// weightSpecs describes the weight_<key> parameters
+func (cs *CompositeStrategy) weightSpecs() []ParameterSpec {
+	specs := make([]ParameterSpec, len(cs.Members))
+	for i, member := range cs.Members {
+		specs[i] = ParameterSpec{
+			Name:        "weight_" + member.Key,
+			Type:        ParamFloat,
+			Default:     member.Weight,
+			Min:         0,
+			Max:         1,
+			Description: fmt.Sprintf("Base weight of %s in the consensus", member.Strategy.Name()),
+		}
+	}
+	return specs
+}
+
+// ParameterSchema lists the weighting parameters and weights followed by
+// each sub-strategy's parameters, prefixed with the strategy name as in
+// GetParameters
+func (cs *CompositeStrategy) ParameterSchema() []ParameterSpec {
+	schema := withDefaults(compositeSchema, NewCompositeStrategy().GetParameters())
+	schema = append(schema, cs.weightSpecs()...)
+	for _, member := range cs.Members {
+		for _, spec := range member.Strategy.ParameterSchema() {
+			spec.Name = fmt.Sprintf("%s_%s", member.Strategy.Name(), spec.Name)
+			schema = append(schema, spec)
+		}
+	}
+	return schema
+}
+
+// SetParameters routes prefixed parameters to the sub-strategies and applies
+// the rest to the composite. Nothing changes unless every parameter is valid.
 func (cs *CompositeStrategy) SetParameters(params map[string]interface{}) error {
-	// Implementation to set parameters for individual strategies
+	updated := cs.Clone().(*CompositeStrategy)
+
+	schema := append(append([]ParameterSpec(nil), compositeSchema...), updated.weightSpecs()...)
+	bindings := map[string]interface{}{
+		"weighting":        &updated.Weighting,
+		"adaptive_window":  &updated.AdaptiveWindow,
+		"adaptive_horizon": &updated.AdaptiveHorizon,
+		"adaptive_step":    &updated.AdaptiveStep,
+		"min_signals":      &updated.MinSignals,
+		"regime_routing":   &updated.RegimeRouting,
+	}
+	for i := range updated.Members {
+		bindings["weight_"+updated.Members[i].Key] = &updated.Members[i].Weight
+	}
+
+	own := make(map[string]interface{})
+	strategyParams := make([]map[string]interface{}, len(updated.Members))
+	for key, value := range params {
+		if _, ok := bindings[key]; ok {
+			own[key] = value
+			continue
+		}
+
+		routed := false
+		for i, member := range updated.Members {
+			prefix := member.Strategy.Name() + "_"
+			if strings.HasPrefix(key, prefix) {
+				if strategyParams[i] == nil {
+					strategyParams[i] = make(map[string]interface{})
+				}
+				strategyParams[i][strings.TrimPrefix(key, prefix)] = value
+				routed = true
+				break
+			}
+		}
+		if !routed {
+			return paramErrorf(key, "unknown parameter")
+		}
+	}
+
+	if err := applyParameters(schema, own, bindings); err != nil {
+		return err
+	}
+	totalWeight := 0.0
+	for _, member := range updated.Members {
+		totalWeight += member.Weight
+	}
+	if totalWeight <= 0 {
+		return paramErrorf("", "at least one weight must be positive")
+	}
+
+	for i, member := range updated.Members {
+		if strategyParams[i] == nil {
+			continue
+		}
+		if err := member.Strategy.SetParameters(strategyParams[i]); err != nil {
+			if paramErr, ok := err.(*ParameterError); ok && paramErr.Parameter != "" {
+				paramErr.Parameter = fmt.Sprintf("%s_%s", member.Strategy.Name(), paramErr.Parameter)
+			}
+			return err
+		}
+	}
+
+	*cs = *updated
 	return nil
 }
 
+func (cs *CompositeStrategy) Clone() TradingAlgorithm {
+	clone := *cs
+	clone.Members = make([]CompositeMember, len(cs.Members))
+	for i, member := range cs.Members {
+		member.Strategy = member.Strategy.Clone()
+		clone.Members[i] = member
+	}
+	return &clone
+}
+
 func (cs *CompositeStrategy) Analyze(data []models.HistoricalData) (*models.TradingSignal, error) {
-	var signals []*models.TradingSignal
-	var validSignals []*models.TradingSignal
+	weights, performance := cs.effectiveWeights(data)
+
+	// Route to the members suited to the current regime, unless none of them
+	// carries weight
+	regime := ""
+	if cs.RegimeRouting == 1 {
+		if detected, err := currentRegime(data); err == nil {
+			routed := make([]float64, len(weights))
+			var routedWeight float64
+			for i, member := range cs.Members {
+				if SuitsRegime(member.Key, detected) {
+					routed[i] = weights[i]
+					routedWeight += weights[i]
+				}
+			}
+			if routedWeight > 0 {
+				weights, regime = routed, detected
+			}
+		}
+	}
 
-	// Get signals from all strategies
-	for _, strategy := range cs.Strategies {
-		signal, err := strategy.Analyze(data)
+	// Get signals from all strategies, keeping each with its own weight
+	type weightedSignal struct {
+		key    string
+		signal *models.TradingSignal
+		weight float64
+	}
+	var validSignals []weightedSignal
+	var validWeight float64
+	for i, member := range cs.Members {
+		if regime != "" && weights[i] == 0 {
+			continue // Not suited to the current regime
+		}
+		signal, err := member.Strategy.Analyze(data)
 		if err != nil {
 			continue // Skip failed strategies
 		}
-		signals = append(signals, signal)
-		validSignals = append(validSignals, signal)
+		validSignals = append(validSignals, weightedSignal{member.Key, signal, weights[i]})
+		validWeight += weights[i]
 	}
 
 	if len(validSignals) == 0 {
 		return nil, fmt.Errorf("no valid signals from any strategy")
 	}
+	if validWeight <= 0 {
+		return nil, fmt.Errorf("no weighted signals: every strategy that produced a signal has zero weight")
+	}
 
 	// Combine signals using weighted average
 	compositeSignal := &models.TradingSignal{
@@ -495,13 +894,17 @@ func (cs *CompositeStrategy) Analyze(data []models.HistoricalData) (*models.Trad
 		Indicators: make(map[string]interface{}),
 	}
 
-	// Calculate weighted consensus
+	// Calculate weighted consensus, renormalizing over the strategies that
+	// produced a signal
 	var buyWeight, sellWeight, holdWeight float64
 	var totalConfidence float64
 	var riskLevels []string
+	usedWeights := make(map[string]float64, len(validSignals))
 
-	for i, signal := range validSignals {
-		weight := cs.Weights[i]
+	for _, ws := range validSignals {
+		weight := ws.weight / validWeight
+		usedWeights[ws.key] = weight
+		signal := ws.signal
 		confidence, _ := signal.Confidence.Float64()
 		
 		switch signal.Type {
@@ -522,6 +925,15 @@ func (cs *CompositeStrategy) Analyze(data []models.HistoricalData) (*models.Trad
 		}
 	}
 
+	compositeSignal.Indicators["weighting"] = weightingNames[cs.Weighting]
+	compositeSignal.Indicators["weights"] = usedWeights
+	if performance != nil {
+		compositeSignal.Indicators["strategy_performance"] = performance
+	}
+	if regime != "" {
+		compositeSignal.Indicators["market_regime"] = regime
+	}
+
 	// Determine final signal
 	if buyWeight > sellWeight && buyWeight > holdWeight {
 		compositeSignal.Type = "BUY"
@@ -534,28 +946,136 @@ func (cs *CompositeStrategy) Analyze(data []models.HistoricalData) (*models.Trad
 		compositeSignal.Strength = decimal.NewFromFloat(holdWeight)
 	}
 
-	compositeSignal.Confidence = decimal.NewFromFloat(totalConfidence / float64(len(validSignals)))
+	// Explain the vote: each sub-signal adds weight × confidence to its
+	// type's tally, the winning tally is the strength and the sum of all
+	// votes the confidence
+	var why explanation
+	switch cs.Weighting {
+	case WeightingHitRate:
+		why.rule("adaptive_weighting", "Weights scaled by each strategy's rolling hit rate", 0, 0)
+	case WeightingSharpe:
+		why.rule("adaptive_weighting", "Weights scaled by each strategy's rolling Sharpe ratio", 0, 0)
+	}
+	if regime != "" {
+		why.rule("regime_routing", fmt.Sprintf("Only strategies suited to the %s regime vote", regime), 0, 0)
+	}
+	components := make([]models.SignalComponent, 0, len(validSignals))
+	for _, ws := range validSignals {
+		signal := ws.signal
+		strength, _ := signal.Strength.Float64()
+		confidence, _ := signal.Confidence.Float64()
+		vote := usedWeights[ws.key] * confidence
+
+		agreeing := 0.0
+		if signal.Type == compositeSignal.Type {
+			agreeing = vote
+		}
+		why.rule("vote_"+ws.key, fmt.Sprintf("%s votes %s with weight %.2f", signal.Algorithm, signal.Type, usedWeights[ws.key]), agreeing, vote)
+
+		components = append(components, models.SignalComponent{
+			Key:        ws.key,
+			Algorithm:  signal.Algorithm,
+			Type:       signal.Type,
+			Strength:   strength,
+			Confidence: confidence,
+			Weight:     usedWeights[ws.key],
+			Vote:       vote,
+			Rationale:  signal.Rationale,
+		})
+	}
+
+	compositeSignal.Confidence = decimal.NewFromFloat(totalConfidence)
 	compositeSignal.RiskLevel = determineOverallRisk(riskLevels)
 	compositeSignal.ExpirationTime = time.Now().Add(time.Hour * 3)
 	compositeSignal.TimeFrame = "1h"
+	why.attach(compositeSignal)
+	compositeSignal.Rationale.Components = components
 
 	return compositeSignal, nil
 }
 
 // Helper functions
 
-func calculateAverageVolume(volumes []int64, period int) int64 {
+// ATR period and multiple used to place key level stops beyond their zone
+const (
+	levelStopATRPeriod = 14
+	levelStopATRBuffer = 0.25
+)
+
+// keyLevelTargets sets target and stop from the nearest support/resistance
+// zones around price. Each side falls back to the given fixed percentage when
+// no level is found on that side, or for the target, when the nearest level
+// is within tolerance of price.
+func keyLevelTargets(data []models.HistoricalData, signalType string, price decimal.Decimal, swingStrength int, tolerance, targetPct, stopPct float64) (target, stop decimal.Decimal) {
+	one := decimal.NewFromInt(1)
+	if signalType == "BUY" {
+		target = price.Mul(one.Add(decimal.NewFromFloat(targetPct)))
+		stop = price.Mul(one.Sub(decimal.NewFromFloat(stopPct)))
+	} else {
+		target = price.Mul(one.Sub(decimal.NewFromFloat(targetPct)))
+		stop = price.Mul(one.Add(decimal.NewFromFloat(stopPct)))
+	}
+
+	var highs, lows, closes []decimal.Decimal
+	var volumes []int64
+	for _, d := range data {
+		highs = append(highs, d.High)
+		lows = append(lows, d.Low)
+		closes = append(closes, d.Close)
+		volumes = append(volumes, d.Volume)
+	}
+
+	levels, err := indicators.SupportResistanceLevels(highs, lows, closes, volumes, swingStrength, tolerance)
+	if err != nil {
+		return target, stop
+	}
+
+	// Stops sit a fraction of an ATR beyond the zone so a retest of its
+	// edge doesn't trigger them
+	buffer := decimal.Zero
+	bars := indicators.NewBars(data)
+	if atr, err := indicators.ATRFloat(bars.High, bars.Low, bars.Close, levelStopATRPeriod); err == nil {
+		buffer = decimal.NewFromFloat(atr[len(atr)-1] * levelStopATRBuffer)
+	}
+
+	// A level within tolerance of price offers no reward, so targets only
+	// use levels beyond that band
+	band := price.Mul(decimal.NewFromFloat(tolerance))
+	support, resistance := indicators.NearestLevels(levels, price)
+	if signalType == "BUY" {
+		if resistance != nil && resistance.Price.GreaterThan(price.Add(band)) {
+			target = resistance.Price
+		}
+		if support != nil {
+			stop = support.Low.Sub(buffer)
+		}
+	} else {
+		if support != nil && support.Price.LessThan(price.Sub(band)) {
+			target = support.Price
+		}
+		if resistance != nil {
+			stop = resistance.High.Add(buffer)
+		}
+	}
+
+	return target, stop
+}
+
+func calculateAverageVolume(volumes []float64, period int) float64 {
 	if len(volumes) < period {
 		period = len(volumes)
 	}
+	if period == 0 {
+		return 0
+	}
 	
-	var sum int64
+	var sum float64
 	start := len(volumes) - period
 	for i := start; i < len(volumes); i++ {
 		sum += volumes[i]
 	}
 	
-	return sum / int64(period)
+	return sum / float64(period)
 }
 
 func determineOverallRisk(riskLevels []string) string {
@@ -582,23 +1102,36 @@ func determineOverallRisk(riskLevels []string) string {
 	return "LOW"
 }
 
-// AlgorithmManager manages all trading algorithms
+// AlgorithmManager manages all trading algorithms. Built-in algorithms are
+// fixed; rule strategies can be registered and removed at runtime.
 type AlgorithmManager struct {
+	mu         sync.RWMutex
 	algorithms map[string]TradingAlgorithm
+	multiAsset map[string]MultiAssetAlgorithm
 }
 
 func NewAlgorithmManager() *AlgorithmManager {
 	return &AlgorithmManager{
 		algorithms: map[string]TradingAlgorithm{
-			"momentum":       NewMomentumStrategy(),
-			"mean_reversion": NewMeanReversionStrategy(),
-			"trend_following": NewTrendFollowingStrategy(),
-			"composite":      NewCompositeStrategy(),
+			"momentum":               NewMomentumStrategy(),
+			"mean_reversion":         NewMeanReversionStrategy(),
+			"trend_following":        NewTrendFollowingStrategy(),
+			"composite":              NewCompositeStrategy(),
+			"donchian_breakout":      NewDonchianBreakoutStrategy(),
+			"volatility_squeeze":     NewVolatilitySqueezeStrategy(),
+			"opening_range_breakout": NewOpeningRangeBreakoutStrategy(),
+			"ml_model":               NewMLStrategy(nil),
+		},
+		multiAsset: map[string]MultiAssetAlgorithm{
+			"pairs_trading": NewPairsTradingStrategy(),
 		},
 	}
 }
 
 func (am *AlgorithmManager) GetAlgorithm(name string) (TradingAlgorithm, error) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
 	algorithm, exists := am.algorithms[name]
 	if !exists {
 		return nil, fmt.Errorf("algorithm '%s' not found", name)
@@ -606,7 +1139,137 @@ func (am *AlgorithmManager) GetAlgorithm(name string) (TradingAlgorithm, error)
 	return algorithm, nil
 }
 
+// GetAlgorithmWithParameters returns the named algorithm with parameter
+// overrides applied to a copy, leaving the shared instance untouched
+func (am *AlgorithmManager) GetAlgorithmWithParameters(name string, params map[string]interface{}) (TradingAlgorithm, error) {
+	algorithm, err := am.GetAlgorithm(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) == 0 {
+		return algorithm, nil
+	}
+
+	clone := algorithm.Clone()
+	if err := clone.SetParameters(params); err != nil {
+		if paramErr, ok := err.(*ParameterError); ok {
+			paramErr.Algorithm = name
+		}
+		return nil, err
+	}
+	return clone, nil
+}
+
+// GetMultiAssetAlgorithm returns a multi-asset algorithm by name
+func (am *AlgorithmManager) GetMultiAssetAlgorithm(name string) (MultiAssetAlgorithm, error) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	algorithm, exists := am.multiAsset[name]
+	if !exists {
+		return nil, fmt.Errorf("multi-asset algorithm '%s' not found", name)
+	}
+	return algorithm, nil
+}
+
+// GetMultiAssetAlgorithmWithParameters is GetAlgorithmWithParameters for
+// multi-asset algorithms
+func (am *AlgorithmManager) GetMultiAssetAlgorithmWithParameters(name string, params map[string]interface{}) (MultiAssetAlgorithm, error) {
+	algorithm, err := am.GetMultiAssetAlgorithm(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) == 0 {
+		return algorithm, nil
+	}
+
+	clone := algorithm.Clone()
+	if err := clone.SetParameters(params); err != nil {
+		if paramErr, ok := err.(*ParameterError); ok {
+			paramErr.Algorithm = name
+		}
+		return nil, err
+	}
+	return clone, nil
+}
+
+// ListMultiAssetAlgorithms returns the names of the multi-asset algorithms
+func (am *AlgorithmManager) ListMultiAssetAlgorithms() []string {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	var names []string
+	for name := range am.multiAsset {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetMLModel swaps the model served by the ml_model algorithm, keeping its
+// current parameters
+func (am *AlgorithmManager) SetMLModel(model *ml.Model) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	strategy := NewMLStrategy(model)
+	if existing, ok := am.algorithms["ml_model"].(*MLStrategy); ok {
+		*strategy = *existing
+		strategy.model = model
+	}
+	am.algorithms["ml_model"] = strategy
+}
+
+// RegisterRuleStrategy adds a rule strategy under its ID, replacing an
+// existing rule strategy with the same ID. Built-in algorithms can't be replaced.
+func (am *AlgorithmManager) RegisterRuleStrategy(strategy *RuleStrategy) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if existing, exists := am.algorithms[strategy.ID()]; exists {
+		if _, isRule := existing.(*RuleStrategy); !isRule {
+			return fmt.Errorf("algorithm '%s' is built in and cannot be replaced", strategy.ID())
+		}
+	}
+	am.algorithms[strategy.ID()] = strategy
+	return nil
+}
+
+// UnregisterRuleStrategy removes a rule strategy
+func (am *AlgorithmManager) UnregisterRuleStrategy(id string) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	existing, exists := am.algorithms[id]
+	if !exists {
+		return fmt.Errorf("algorithm '%s' not found", id)
+	}
+	if _, isRule := existing.(*RuleStrategy); !isRule {
+		return fmt.Errorf("algorithm '%s' is built in and cannot be removed", id)
+	}
+	delete(am.algorithms, id)
+	return nil
+}
+
+// RuleStrategies returns the registered rule strategies sorted by ID
+func (am *AlgorithmManager) RuleStrategies() []*RuleStrategy {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	var strategies []*RuleStrategy
+	for _, algorithm := range am.algorithms {
+		if strategy, ok := algorithm.(*RuleStrategy); ok {
+			strategies = append(strategies, strategy)
+		}
+	}
+	sort.Slice(strategies, func(i, j int) bool { return strategies[i].ID() < strategies[j].ID() })
+	return strategies
+}
+
 func (am *AlgorithmManager) ListAlgorithms() []string {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
 	var names []string
 	for name := range am.algorithms {
 		names = append(names, name)
@@ -615,9 +1278,16 @@ func (am *AlgorithmManager) ListAlgorithms() []string {
 }
 
 func (am *AlgorithmManager) AnalyzeWithAll(data []models.HistoricalData) (map[string]*models.TradingSignal, error) {
+	am.mu.RLock()
+	snapshot := make(map[string]TradingAlgorithm, len(am.algorithms))
+	for name, algorithm := range am.algorithms {
+		snapshot[name] = algorithm
+	}
+	am.mu.RUnlock()
+
 	results := make(map[string]*models.TradingSignal)
 	
-	for name, algorithm := range am.algorithms {
+	for name, algorithm := range snapshot {
 		signal, err := algorithm.Analyze(data)
 		if err != nil {
 			continue // Skip failed analyses
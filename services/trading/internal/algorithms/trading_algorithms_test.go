@@ -0,0 +1,97 @@
+package algorithms
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+)
+
+// testBars is a random walk of daily bars starting at 100
+func testBars(n int, seed int64) []models.HistoricalData {
+	rng := rand.New(rand.NewSource(seed))
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	price := 100.0
+	data := make([]models.HistoricalData, n)
+	for i := range data {
+		open := price
+		price *= 1 + rng.NormFloat64()*0.015
+		high := math.Max(open, price) * (1 + rng.Float64()*0.005)
+		low := math.Min(open, price) * (1 - rng.Float64()*0.005)
+		data[i] = models.HistoricalData{
+			Symbol: "TEST",
+			Date:   start.AddDate(0, 0, i),
+			Open:   decimal.NewFromFloat(open),
+			High:   decimal.NewFromFloat(high),
+			Low:    decimal.NewFromFloat(low),
+			Close:  decimal.NewFromFloat(price),
+			Volume: 1000000 + rng.Int63n(500000),
+		}
+	}
+	return data
+}
+
+func TestKeyLevelStopsBeyondZone(t *testing.T) {
+	data := testBars(250, 7)
+	price := data[len(data)-1].Close
+
+	var highs, lows, closes []decimal.Decimal
+	var volumes []int64
+	for _, d := range data {
+		highs = append(highs, d.High)
+		lows = append(lows, d.Low)
+		closes = append(closes, d.Close)
+		volumes = append(volumes, d.Volume)
+	}
+	levels, err := indicators.SupportResistanceLevels(highs, lows, closes, volumes, 3, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	support, resistance := indicators.NearestLevels(levels, price)
+	if support == nil || resistance == nil {
+		t.Fatal("test series should have levels on both sides")
+	}
+
+	_, stop := keyLevelTargets(data, "BUY", price, 3, 0.01, 0.05, 0.03)
+	if !stop.LessThan(support.Low) {
+		t.Fatalf("BUY stop %v not below support zone low %v", stop, support.Low)
+	}
+	_, stop = keyLevelTargets(data, "SELL", price, 3, 0.01, 0.05, 0.03)
+	if !stop.GreaterThan(resistance.High) {
+		t.Fatalf("SELL stop %v not above resistance zone high %v", stop, resistance.High)
+	}
+}
+
+func TestKeyLevelTargetsSkipLevelAtPrice(t *testing.T) {
+	data := testBars(250, 7)
+
+	var highs, lows, closes []decimal.Decimal
+	var volumes []int64
+	for _, d := range data {
+		highs = append(highs, d.High)
+		lows = append(lows, d.Low)
+		closes = append(closes, d.Close)
+		volumes = append(volumes, d.Volume)
+	}
+	levels, err := indicators.SupportResistanceLevels(highs, lows, closes, volumes, 3, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, level := range levels {
+		// With price sitting on a level, NearestLevels calls it resistance
+		price := level.Price
+		target, stop := keyLevelTargets(data, "BUY", price, 3, 0.01, 0.05, 0.03)
+		if !target.GreaterThan(price.Mul(decimal.NewFromFloat(1.01))) || !stop.LessThan(price) {
+			t.Fatalf("BUY at level %v: target %v stop %v, want a target beyond the level", price, target, stop)
+		}
+		target, stop = keyLevelTargets(data, "SELL", price, 3, 0.01, 0.05, 0.03)
+		if !target.LessThan(price.Mul(decimal.NewFromFloat(0.99))) || !stop.GreaterThan(price) {
+			t.Fatalf("SELL at level %v: target %v stop %v, want a target beyond the level", price, target, stop)
+		}
+	}
+}
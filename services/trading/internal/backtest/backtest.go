@@ -0,0 +1,274 @@
+package backtest
+
+import (
+	"fmt"
+	"time"
+
+	"trading-service/internal/algorithms"
+	"trading-service/internal/models"
+)
+
+// Exit reasons
+const (
+	ExitSignal = "SIGNAL"
+	ExitStop   = "STOP_LOSS"
+	ExitTarget = "TARGET"
+	ExitEnd    = "END_OF_DATA"
+)
+
+// Config controls how signals are turned into positions
+type Config struct {
+	InitialCapital float64 `json:"initial_capital"`  // Default 100000
+	Commission     float64 `json:"commission"`       // Fraction of notional per side, default 0.001
+	Lookback       int     `json:"lookback"`         // Bars passed to Analyze, default 250, -1 for all
+	Warmup         int     `json:"warmup"`           // First bar a signal is generated on, default 50
+	AllowShort     bool    `json:"allow_short"`      // SELL opens a short instead of going flat
+	IgnoreStops    bool    `json:"ignore_stops"`     // Don't exit on signal stop loss / target
+	RiskFreeRate   float64 `json:"risk_free_rate"`   // Annual, used for Sharpe
+	PeriodsPerYear float64 `json:"periods_per_year"` // Default 252 (daily bars)
+}
+
+// DefaultConfig returns settings for daily bars
+func DefaultConfig() Config {
+	return Config{
+		InitialCapital: 100000,
+		Commission:     0.001,
+		Lookback:       250,
+		Warmup:         50,
+		PeriodsPerYear: 252,
+	}
+}
+
+// withDefaults fills zero values from DefaultConfig
+func (c Config) withDefaults() Config {
+	d := DefaultConfig()
+	if c.InitialCapital <= 0 {
+		c.InitialCapital = d.InitialCapital
+	}
+	if c.Commission < 0 {
+		c.Commission = 0
+	}
+	if c.Lookback == 0 {
+		c.Lookback = d.Lookback
+	}
+	if c.Warmup <= 0 {
+		c.Warmup = d.Warmup
+	}
+	if c.PeriodsPerYear <= 0 {
+		c.PeriodsPerYear = d.PeriodsPerYear
+	}
+	return c
+}
+
+// Trade is a completed round trip
+type Trade struct {
+	Side       string    `json:"side"` // LONG, SHORT
+	EntryDate  time.Time `json:"entry_date"`
+	EntryPrice float64   `json:"entry_price"`
+	ExitDate   time.Time `json:"exit_date"`
+	ExitPrice  float64   `json:"exit_price"`
+	Return     float64   `json:"return"` // Net of commission
+	Bars       int       `json:"bars"`
+	ExitReason string    `json:"exit_reason"`
+}
+
+// Result holds per-bar returns and equity aligned to the input data
+type Result struct {
+	Dates    []time.Time `json:"-"`
+	Returns  []float64   `json:"-"` // Strategy return on each bar
+	Equity   []float64   `json:"-"`
+	Position []int       `json:"-"` // Position held at the close of each bar
+	Trades   []Trade     `json:"trades"`
+	Metrics  Metrics     `json:"metrics"`
+	config   Config
+}
+
+// position tracks the open trade while simulating
+type position struct {
+	side       int // 1 long, -1 short
+	entryIndex int
+	entryPrice float64
+	stop       float64
+	target     float64
+}
+
+// Run simulates an algorithm over the data. The signal produced at the close
+// of bar i is executed at the open of bar i+1; stops and targets from the
+// signal are checked against each later bar's range.
+func Run(algorithm algorithms.TradingAlgorithm, data []models.HistoricalData, cfg Config) (*Result, error) {
+	cfg = cfg.withDefaults()
+	n := len(data)
+	if n < cfg.Warmup+2 {
+		return nil, fmt.Errorf("insufficient data for backtest: need more than %d bars, got %d", cfg.Warmup+1, n)
+	}
+
+	open := make([]float64, n)
+	high := make([]float64, n)
+	low := make([]float64, n)
+	closes := make([]float64, n)
+	result := &Result{
+		Dates:    make([]time.Time, n),
+		Returns:  make([]float64, n),
+		Equity:   make([]float64, n),
+		Position: make([]int, n),
+		config:   cfg,
+	}
+	for i, d := range data {
+		open[i] = d.Open.InexactFloat64()
+		high[i] = d.High.InexactFloat64()
+		low[i] = d.Low.InexactFloat64()
+		closes[i] = d.Close.InexactFloat64()
+		result.Dates[i] = d.Date
+		if open[i] <= 0 {
+			open[i] = closes[i] // Some providers omit the open
+		}
+	}
+
+	var pos *position
+	desired := 0
+	var pendingStop, pendingTarget float64
+
+	closeTrade := func(i int, price float64, reason string) {
+		gross := float64(pos.side) * (price/pos.entryPrice - 1)
+		result.Trades = append(result.Trades, Trade{
+			Side:       sideName(pos.side),
+			EntryDate:  result.Dates[pos.entryIndex],
+			EntryPrice: pos.entryPrice,
+			ExitDate:   result.Dates[i],
+			ExitPrice:  price,
+			Return:     gross - 2*cfg.Commission,
+			Bars:       i - pos.entryIndex,
+			ExitReason: reason,
+		})
+		pos = nil
+	}
+
+	for i := 1; i < n; i++ {
+		ret := 0.0
+		ref := closes[i-1] // Price the current holding was last marked at
+
+		// Execute yesterday's decision at today's open
+		current := 0
+		if pos != nil {
+			current = pos.side
+		}
+		if desired != current {
+			if pos != nil {
+				ret += float64(pos.side) * (open[i]/ref - 1)
+				ret -= cfg.Commission
+				closeTrade(i, open[i], ExitSignal)
+			}
+			if desired != 0 {
+				pos = &position{side: desired, entryIndex: i, entryPrice: open[i], stop: pendingStop, target: pendingTarget}
+				ret -= cfg.Commission
+			}
+			ref = open[i]
+		}
+
+		// Mark to market, exiting on stops and targets within the bar
+		if pos != nil {
+			exitPrice, reason := 0.0, ""
+			if !cfg.IgnoreStops {
+				exitPrice, reason = checkStops(pos, open[i], high[i], low[i])
+			}
+			if reason != "" {
+				ret += float64(pos.side) * (exitPrice/ref - 1)
+				ret -= cfg.Commission
+				closeTrade(i, exitPrice, reason)
+				desired = 0 // Stay flat until the next signal
+			} else {
+				ret += float64(pos.side) * (closes[i]/ref - 1)
+			}
+		}
+
+		result.Returns[i] = ret
+		if pos != nil {
+			result.Position[i] = pos.side
+		}
+
+		// Decide at today's close for tomorrow's open
+		if i >= cfg.Warmup && i < n-1 {
+			from := 0
+			if cfg.Lookback > 0 && i+1 > cfg.Lookback {
+				from = i + 1 - cfg.Lookback
+			}
+			signal, err := algorithm.Analyze(data[from : i+1])
+			if err != nil {
+				continue // Not enough history for this configuration yet
+			}
+
+			switch signal.Type {
+			case "BUY":
+				if desired != 1 {
+					desired = 1
+					pendingStop, pendingTarget = signal.StopLoss.InexactFloat64(), signal.TargetPrice.InexactFloat64()
+				}
+			case "SELL":
+				if cfg.AllowShort {
+					if desired != -1 {
+						desired = -1
+						pendingStop, pendingTarget = signal.StopLoss.InexactFloat64(), signal.TargetPrice.InexactFloat64()
+					}
+				} else {
+					desired = 0
+				}
+			}
+		}
+	}
+
+	if pos != nil {
+		closeTrade(n-1, closes[n-1], ExitEnd)
+	}
+
+	equity := cfg.InitialCapital
+	for i := range result.Returns {
+		equity *= 1 + result.Returns[i]
+		result.Equity[i] = equity
+	}
+
+	result.Metrics = result.MetricsForRange(0, n)
+	return result, nil
+}
+
+// checkStops returns the fill price and reason when the bar hits the stop
+// or target. Gaps through a level fill at the open; if both levels are
+// inside the range the stop is assumed to have been hit first.
+func checkStops(pos *position, open, high, low float64) (float64, string) {
+	if pos.side > 0 {
+		if pos.stop > 0 && low <= pos.stop {
+			return minFloat(open, pos.stop), ExitStop
+		}
+		if pos.target > 0 && high >= pos.target {
+			return maxFloat(open, pos.target), ExitTarget
+		}
+	} else {
+		if pos.stop > 0 && high >= pos.stop {
+			return maxFloat(open, pos.stop), ExitStop
+		}
+		if pos.target > 0 && low <= pos.target {
+			return minFloat(open, pos.target), ExitTarget
+		}
+	}
+	return 0, ""
+}
+
+func sideName(side int) string {
+	if side < 0 {
+		return "SHORT"
+	}
+	return "LONG"
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,139 @@
+package backtest
+
+import (
+	"math"
+)
+
+// Metrics summarises performance over a range of bars
+type Metrics struct {
+	TotalReturn float64 `json:"total_return"`
+	CAGR        float64 `json:"cagr"`
+	Volatility  float64 `json:"volatility"` // Annualized
+	Sharpe      float64 `json:"sharpe"`
+	MaxDrawdown float64 `json:"max_drawdown"` // Positive fraction of peak equity
+	Calmar      float64 `json:"calmar"`
+	Trades      int     `json:"trades"`
+	WinRate     float64 `json:"win_rate"`
+	Exposure    float64 `json:"exposure"` // Fraction of bars in the market
+	Bars        int     `json:"bars"`
+}
+
+// MetricsForRange computes metrics over bars [from, to). Trades count when
+// they exit inside the range.
+func (r *Result) MetricsForRange(from, to int) Metrics {
+	if from < 0 {
+		from = 0
+	}
+	if to > len(r.Returns) {
+		to = len(r.Returns)
+	}
+	if to <= from {
+		return Metrics{}
+	}
+
+	m := computeMetrics(r.Returns[from:to], r.config.PeriodsPerYear, r.config.RiskFreeRate)
+
+	inMarket := 0
+	for _, p := range r.Position[from:to] {
+		if p != 0 {
+			inMarket++
+		}
+	}
+	m.Exposure = float64(inMarket) / float64(to-from)
+
+	start, end := r.Dates[from], r.Dates[to-1]
+	wins := 0
+	for _, t := range r.Trades {
+		if t.ExitDate.Before(start) || t.ExitDate.After(end) {
+			continue
+		}
+		m.Trades++
+		if t.Return > 0 {
+			wins++
+		}
+	}
+	if m.Trades > 0 {
+		m.WinRate = float64(wins) / float64(m.Trades)
+	}
+
+	return m
+}
+
+// computeMetrics derives return and risk statistics from per-bar returns
+func computeMetrics(returns []float64, periodsPerYear, riskFreeRate float64) Metrics {
+	m := Metrics{Bars: len(returns)}
+	if len(returns) == 0 {
+		return m
+	}
+
+	equity, peak := 1.0, 1.0
+	var sum float64
+	for _, r := range returns {
+		equity *= 1 + r
+		sum += r
+		if equity > peak {
+			peak = equity
+		}
+		if dd := (peak - equity) / peak; dd > m.MaxDrawdown {
+			m.MaxDrawdown = dd
+		}
+	}
+
+	m.TotalReturn = equity - 1
+	years := float64(len(returns)) / periodsPerYear
+	if equity > 0 && years > 0 {
+		m.CAGR = math.Pow(equity, 1/years) - 1
+	} else if equity <= 0 {
+		m.CAGR = -1
+	}
+
+	mean := sum / float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	if len(returns) > 1 {
+		variance /= float64(len(returns) - 1)
+	}
+	stdDev := math.Sqrt(variance)
+	m.Volatility = stdDev * math.Sqrt(periodsPerYear)
+
+	if stdDev > 0 {
+		excess := mean - riskFreeRate/periodsPerYear
+		m.Sharpe = excess / stdDev * math.Sqrt(periodsPerYear)
+	}
+	if m.MaxDrawdown > 0 {
+		m.Calmar = m.CAGR / m.MaxDrawdown
+	}
+
+	return m
+}
+
+// Objectives for ranking configurations
+const (
+	ObjectiveSharpe      = "sharpe"
+	ObjectiveCAGR        = "cagr"
+	ObjectiveMaxDrawdown = "max_drawdown"
+	ObjectiveCalmar      = "calmar"
+)
+
+// Score maps metrics to a single number where higher is better
+func Score(m Metrics, objective string) float64 {
+	switch objective {
+	case ObjectiveCAGR:
+		return m.CAGR
+	case ObjectiveMaxDrawdown:
+		return -m.MaxDrawdown
+	case ObjectiveCalmar:
+		return m.Calmar
+	}
+	return m.Sharpe
+}
+
+func validObjective(objective string) bool {
+	switch objective {
+	case ObjectiveSharpe, ObjectiveCAGR, ObjectiveMaxDrawdown, ObjectiveCalmar:
+		return true
+	}
+	return false
+}
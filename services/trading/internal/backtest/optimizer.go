@@ -0,0 +1,505 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"trading-service/internal/algorithms"
+	"trading-service/internal/models"
+)
+
+// Search methods
+const (
+	MethodGrid   = "grid"
+	MethodRandom = "random"
+)
+
+// MaxSearchRuns caps max_runs and samples, and so the configurations one
+// search can backtest
+const MaxSearchRuns = 5000
+
+// ParameterRange is the search space for one parameter: explicit values, or
+// Min..Max in Step increments (grid) / uniformly sampled (random)
+type ParameterRange struct {
+	Min    float64   `json:"min"`
+	Max    float64   `json:"max"`
+	Step   float64   `json:"step,omitempty"`
+	Values []float64 `json:"values,omitempty"`
+}
+
+// OptimizeConfig describes a parameter search
+type OptimizeConfig struct {
+	Method     string                    `json:"method"`     // grid (default), random
+	Parameters map[string]ParameterRange `json:"parameters"` // Names from the algorithm's schema
+	Samples    int                       `json:"samples"`    // Random search draws, default 50, at most MaxSearchRuns
+	MaxRuns    int                       `json:"max_runs"`   // Cap on configurations, default 500, at most MaxSearchRuns
+	Objective  string                    `json:"objective"`  // sharpe (default), cagr, max_drawdown, calmar
+	Folds      int                       `json:"folds"`      // Walk-forward folds, default 4, -1 disables
+	MinTrades  int                       `json:"min_trades"` // Configurations with fewer trades rank last
+	TopN       int                       `json:"top_n"`      // Ranked results returned, default 20
+	Workers    int                       `json:"workers"`    // Parallel backtests, default and at most the number of CPUs
+	Seed       int64                     `json:"seed"`       // Random search seed, 0 for time-based
+	Backtest   Config                    `json:"backtest"`
+}
+
+// CandidateResult is one parameter configuration scored over the full period.
+// Configurations with fewer than MinTrades trades are not Eligible and rank
+// after every eligible one whatever their score.
+type CandidateResult struct {
+	Rank       int                    `json:"rank"`
+	Parameters map[string]interface{} `json:"parameters"`
+	Score      float64                `json:"score"`
+	Eligible   bool                   `json:"eligible"`
+	Metrics    Metrics                `json:"metrics"`
+}
+
+// WalkForwardFold is one train/test split. The best configuration on the
+// training window is scored on the following, unseen test window.
+type WalkForwardFold struct {
+	Fold        int                    `json:"fold"`
+	TrainStart  time.Time              `json:"train_start"`
+	TrainEnd    time.Time              `json:"train_end"`
+	TestStart   time.Time              `json:"test_start"`
+	TestEnd     time.Time              `json:"test_end"`
+	Parameters  map[string]interface{} `json:"parameters"`
+	TrainScore  float64                `json:"train_score"`
+	TestScore   float64                `json:"test_score"`
+	TestMetrics Metrics                `json:"test_metrics"`
+}
+
+// WalkForwardSummary aggregates the folds. Efficiency is the mean test score
+// over the mean train score; values well below 1 suggest overfitting.
+type WalkForwardSummary struct {
+	Folds          []WalkForwardFold `json:"folds"`
+	MeanTrainScore float64           `json:"mean_train_score"`
+	MeanTestScore  float64           `json:"mean_test_score"`
+	Efficiency     float64           `json:"efficiency"`
+	OutOfSample    Metrics           `json:"out_of_sample"` // Test windows stitched together
+}
+
+// OptimizationResult is the ranked outcome of a search
+type OptimizationResult struct {
+	Algorithm   string              `json:"algorithm"`
+	Method      string              `json:"method"`
+	Objective   string              `json:"objective"`
+	Evaluated   int                 `json:"evaluated"`
+	Invalid     int                 `json:"invalid"` // Combinations rejected by parameter validation
+	Failed      int                 `json:"failed"`  // Backtests that returned an error
+	Best        *CandidateResult    `json:"best"`
+	Results     []CandidateResult   `json:"results"`
+	WalkForward *WalkForwardSummary `json:"walk_forward,omitempty"`
+	Duration    string              `json:"duration"`
+}
+
+// evaluated pairs a configuration with its full backtest
+type evaluated struct {
+	params map[string]interface{}
+	result *Result
+}
+
+// Optimize backtests every configuration in the search space in parallel,
+// ranks them and runs walk-forward validation. progress, if set, is called
+// after each backtest with the number done and the total.
+func Optimize(ctx context.Context, algorithm algorithms.TradingAlgorithm, data []models.HistoricalData, cfg OptimizeConfig, progress func(done, total int)) (*OptimizationResult, error) {
+	started := time.Now()
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	schema := make(map[string]algorithms.ParameterSpec)
+	for _, spec := range algorithm.ParameterSchema() {
+		schema[spec.Name] = spec
+	}
+
+	configs, err := searchSpace(cfg, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate each configuration up front so invalid combinations such as
+	// fast >= slow are skipped rather than counted as failed backtests
+	var candidates []algorithms.TradingAlgorithm
+	var candidateParams []map[string]interface{}
+	invalid := 0
+	for _, params := range configs {
+		clone := algorithm.Clone()
+		if err := clone.SetParameters(params); err != nil {
+			invalid++
+			continue
+		}
+		candidates = append(candidates, clone)
+		candidateParams = append(candidateParams, params)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("all %d parameter combinations were rejected by validation", len(configs))
+	}
+
+	runs := runParallel(ctx, candidates, candidateParams, data, cfg, progress)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var results []evaluated
+	for _, run := range runs {
+		if run.result != nil {
+			results = append(results, run)
+		}
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("every backtest failed; check that the data covers the warmup period")
+	}
+
+	ranked := rankCandidates(results, 0, len(data), cfg)
+	optimization := &OptimizationResult{
+		Algorithm: algorithm.Name(),
+		Method:    cfg.Method,
+		Objective: cfg.Objective,
+		Evaluated: len(results),
+		Invalid:   invalid,
+		Failed:    len(candidates) - len(results),
+	}
+
+	top := ranked
+	if len(top) > cfg.TopN {
+		top = top[:cfg.TopN]
+	}
+	for i, idx := range top {
+		candidate := CandidateResult{
+			Rank:       i + 1,
+			Parameters: results[idx].params,
+			Metrics:    results[idx].result.Metrics,
+		}
+		candidate.Score, candidate.Eligible = rankingScore(candidate.Metrics, cfg)
+		optimization.Results = append(optimization.Results, candidate)
+	}
+	optimization.Best = &optimization.Results[0]
+
+	if cfg.Folds > 0 {
+		optimization.WalkForward = walkForward(results, len(data), cfg)
+	}
+
+	optimization.Duration = time.Since(started).Round(time.Millisecond).String()
+	return optimization, nil
+}
+
+// Validate checks the search settings and parameter ranges against the
+// algorithm's schema without running any backtests
+func (cfg OptimizeConfig) Validate(algorithm algorithms.TradingAlgorithm) error {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return err
+	}
+	schema := make(map[string]algorithms.ParameterSpec)
+	for _, spec := range algorithm.ParameterSchema() {
+		schema[spec.Name] = spec
+	}
+	_, err = searchSpace(cfg, schema)
+	return err
+}
+
+func (cfg OptimizeConfig) withDefaults() (OptimizeConfig, error) {
+	cfg.Method = strings.ToLower(cfg.Method)
+	if cfg.Method == "" {
+		cfg.Method = MethodGrid
+	}
+	if cfg.Method != MethodGrid && cfg.Method != MethodRandom {
+		return cfg, fmt.Errorf("unknown search method '%s' (use grid or random)", cfg.Method)
+	}
+	cfg.Objective = strings.ToLower(cfg.Objective)
+	if cfg.Objective == "" {
+		cfg.Objective = ObjectiveSharpe
+	}
+	if !validObjective(cfg.Objective) {
+		return cfg, fmt.Errorf("unknown objective '%s' (use sharpe, cagr, max_drawdown or calmar)", cfg.Objective)
+	}
+	if len(cfg.Parameters) == 0 {
+		return cfg, fmt.Errorf("at least one parameter range is required")
+	}
+	if cfg.Samples <= 0 {
+		cfg.Samples = 50
+	}
+	if cfg.MaxRuns <= 0 {
+		cfg.MaxRuns = 500
+	}
+	if cfg.Samples > MaxSearchRuns || cfg.MaxRuns > MaxSearchRuns {
+		return cfg, fmt.Errorf("samples and max_runs must be at most %d", MaxSearchRuns)
+	}
+	if cfg.Folds == 0 {
+		cfg.Folds = 4
+	}
+	if cfg.TopN <= 0 {
+		cfg.TopN = 20
+	}
+	if cfg.Workers <= 0 || cfg.Workers > runtime.NumCPU() {
+		cfg.Workers = runtime.NumCPU()
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	cfg.Backtest = cfg.Backtest.withDefaults()
+	return cfg, nil
+}
+
+// searchSpace expands the ranges into parameter maps
+func searchSpace(cfg OptimizeConfig, schema map[string]algorithms.ParameterSpec) ([]map[string]interface{}, error) {
+	names := make([]string, 0, len(cfg.Parameters))
+	for name := range cfg.Parameters {
+		if _, ok := schema[name]; !ok {
+			return nil, fmt.Errorf("unknown parameter '%s'", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if cfg.Method == MethodRandom {
+		return randomSpace(names, cfg, schema)
+	}
+
+	values := make([][]float64, len(names))
+	total := 1
+	for i, name := range names {
+		vals, err := gridValues(name, cfg.Parameters[name], schema[name], cfg.MaxRuns)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = vals
+		total *= len(vals)
+		if total > cfg.MaxRuns {
+			return nil, fmt.Errorf("grid has more than %d combinations; narrow the ranges, raise max_runs or use random search", cfg.MaxRuns)
+		}
+	}
+
+	configs := make([]map[string]interface{}, 0, total)
+	indices := make([]int, len(names))
+	for {
+		params := make(map[string]interface{}, len(names))
+		for i, name := range names {
+			params[name] = typedValue(values[i][indices[i]], schema[name])
+		}
+		configs = append(configs, params)
+
+		// Odometer increment over the value lists
+		k := len(indices) - 1
+		for k >= 0 {
+			indices[k]++
+			if indices[k] < len(values[k]) {
+				break
+			}
+			indices[k] = 0
+			k--
+		}
+		if k < 0 {
+			return configs, nil
+		}
+	}
+}
+
+// gridValues lists a parameter's grid points, failing rather than building a
+// list of more than limit values
+func gridValues(name string, r ParameterRange, spec algorithms.ParameterSpec, limit int) ([]float64, error) {
+	tooMany := fmt.Errorf("parameter '%s' has more than %d grid values; raise the step or narrow the range", name, limit)
+	if len(r.Values) > 0 {
+		if len(r.Values) > limit {
+			return nil, tooMany
+		}
+		return r.Values, nil
+	}
+	if r.Max < r.Min {
+		return nil, fmt.Errorf("parameter '%s': max is less than min", name)
+	}
+
+	step := r.Step
+	if step <= 0 {
+		step = (r.Max - r.Min) / 4
+		if spec.Type == algorithms.ParamInt {
+			step = math.Max(1, math.Round(step))
+		}
+	}
+	if step <= 0 {
+		return []float64{r.Min}, nil // Min == Max
+	}
+	if (r.Max-r.Min)/step >= float64(limit) {
+		return nil, tooMany
+	}
+
+	var values []float64
+	for v := r.Min; v <= r.Max+step*1e-9; v += step {
+		values = append(values, math.Round(v*1e9)/1e9)
+	}
+	return values, nil
+}
+
+func randomSpace(names []string, cfg OptimizeConfig, schema map[string]algorithms.ParameterSpec) ([]map[string]interface{}, error) {
+	for _, name := range names {
+		r := cfg.Parameters[name]
+		if len(r.Values) == 0 && r.Max < r.Min {
+			return nil, fmt.Errorf("parameter '%s': max is less than min", name)
+		}
+	}
+
+	samples := cfg.Samples
+	if samples > cfg.MaxRuns {
+		samples = cfg.MaxRuns
+	}
+
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	seen := make(map[string]bool)
+	var configs []map[string]interface{}
+
+	// Duplicates are likely for small integer ranges, so allow extra draws
+	for attempt := 0; attempt < samples*10 && len(configs) < samples; attempt++ {
+		params := make(map[string]interface{}, len(names))
+		var key strings.Builder
+		for _, name := range names {
+			r := cfg.Parameters[name]
+			var v float64
+			if len(r.Values) > 0 {
+				v = r.Values[rng.Intn(len(r.Values))]
+			} else {
+				v = r.Min + rng.Float64()*(r.Max-r.Min)
+			}
+			params[name] = typedValue(v, schema[name])
+			fmt.Fprintf(&key, "%v|", params[name])
+		}
+		if seen[key.String()] {
+			continue
+		}
+		seen[key.String()] = true
+		configs = append(configs, params)
+	}
+	return configs, nil
+}
+
+// typedValue rounds integer parameters so they pass schema validation
+func typedValue(v float64, spec algorithms.ParameterSpec) interface{} {
+	if spec.Type == algorithms.ParamInt {
+		return int(math.Round(v))
+	}
+	return v
+}
+
+// runParallel backtests candidates on a worker pool, keeping input order
+func runParallel(ctx context.Context, candidates []algorithms.TradingAlgorithm, params []map[string]interface{}, data []models.HistoricalData, cfg OptimizeConfig, progress func(done, total int)) []evaluated {
+	runs := make([]evaluated, len(candidates))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	done := 0
+
+	for w := 0; w < cfg.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result, err := Run(candidates[i], data, cfg.Backtest)
+				runs[i] = evaluated{params: params[i]}
+				if err == nil {
+					runs[i].result = result
+				}
+
+				if progress != nil {
+					mu.Lock()
+					done++
+					progress(done, len(candidates))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return runs
+}
+
+// rankingScore returns the objective score and whether the configuration
+// traded often enough to be ranked on it
+func rankingScore(m Metrics, cfg OptimizeConfig) (float64, bool) {
+	return Score(m, cfg.Objective), m.Trades >= cfg.MinTrades
+}
+
+// rankCandidates orders result indices by score over bars [from, to),
+// eligible configurations first
+func rankCandidates(results []evaluated, from, to int, cfg OptimizeConfig) []int {
+	scores := make([]float64, len(results))
+	eligible := make([]bool, len(results))
+	order := make([]int, len(results))
+	for i, r := range results {
+		scores[i], eligible[i] = rankingScore(r.result.MetricsForRange(from, to), cfg)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if eligible[order[a]] != eligible[order[b]] {
+			return eligible[order[a]]
+		}
+		return scores[order[a]] > scores[order[b]]
+	})
+	return order
+}
+
+// walkForward splits the bars after warmup into Folds+1 segments. Fold k
+// trains on segments 0..k (anchored) and tests on segment k+1. Because every
+// signal only sees past bars, scoring slices of each full backtest is
+// equivalent to re-running it on the window.
+func walkForward(results []evaluated, n int, cfg OptimizeConfig) *WalkForwardSummary {
+	start := cfg.Backtest.Warmup + 1
+	segment := (n - start) / (cfg.Folds + 1)
+	if segment < 2 {
+		return nil
+	}
+
+	summary := &WalkForwardSummary{}
+	var oosReturns []float64
+
+	for k := 0; k < cfg.Folds; k++ {
+		trainEnd := start + (k+1)*segment
+		testEnd := trainEnd + segment
+		if k == cfg.Folds-1 {
+			testEnd = n
+		}
+
+		best := rankCandidates(results, start, trainEnd, cfg)[0]
+		chosen := results[best].result
+		trainMetrics := chosen.MetricsForRange(start, trainEnd)
+		testMetrics := chosen.MetricsForRange(trainEnd, testEnd)
+
+		summary.Folds = append(summary.Folds, WalkForwardFold{
+			Fold:        k + 1,
+			TrainStart:  chosen.Dates[start],
+			TrainEnd:    chosen.Dates[trainEnd-1],
+			TestStart:   chosen.Dates[trainEnd],
+			TestEnd:     chosen.Dates[testEnd-1],
+			Parameters:  results[best].params,
+			TrainScore:  Score(trainMetrics, cfg.Objective),
+			TestScore:   Score(testMetrics, cfg.Objective),
+			TestMetrics: testMetrics,
+		})
+		summary.MeanTrainScore += Score(trainMetrics, cfg.Objective)
+		summary.MeanTestScore += Score(testMetrics, cfg.Objective)
+		oosReturns = append(oosReturns, chosen.Returns[trainEnd:testEnd]...)
+	}
+
+	summary.MeanTrainScore /= float64(cfg.Folds)
+	summary.MeanTestScore /= float64(cfg.Folds)
+	if summary.MeanTrainScore != 0 {
+		summary.Efficiency = summary.MeanTestScore / summary.MeanTrainScore
+	}
+	summary.OutOfSample = computeMetrics(oosReturns, cfg.Backtest.PeriodsPerYear, cfg.Backtest.RiskFreeRate)
+
+	return summary
+}
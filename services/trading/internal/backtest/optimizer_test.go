@@ -0,0 +1,212 @@
+package backtest
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/algorithms"
+	"trading-service/internal/models"
+)
+
+// testBars is a random walk of daily bars starting at 100
+func testBars(n int, seed int64) []models.HistoricalData {
+	rng := rand.New(rand.NewSource(seed))
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	price := 100.0
+	data := make([]models.HistoricalData, n)
+	for i := range data {
+		open := price
+		price *= 1 + rng.NormFloat64()*0.015
+		data[i] = models.HistoricalData{
+			Symbol: "TEST",
+			Date:   start.AddDate(0, 0, i),
+			Open:   decimal.NewFromFloat(open),
+			High:   decimal.NewFromFloat(math.Max(open, price) * (1 + rng.Float64()*0.005)),
+			Low:    decimal.NewFromFloat(math.Min(open, price) * (1 - rng.Float64()*0.005)),
+			Close:  decimal.NewFromFloat(price),
+			Volume: 1000000 + rng.Int63n(500000),
+		}
+	}
+	return data
+}
+
+func TestGridValues(t *testing.T) {
+	intSpec := algorithms.ParameterSpec{Name: "p", Type: algorithms.ParamInt}
+	floatSpec := algorithms.ParameterSpec{Name: "p", Type: algorithms.ParamFloat}
+
+	tests := []struct {
+		name  string
+		r     ParameterRange
+		spec  algorithms.ParameterSpec
+		limit int
+		want  []float64
+		err   string
+	}{
+		{"step", ParameterRange{Min: 10, Max: 20, Step: 5}, intSpec, 100, []float64{10, 15, 20}, ""},
+		{"float step", ParameterRange{Min: 0.1, Max: 0.3, Step: 0.1}, floatSpec, 100, []float64{0.1, 0.2, 0.3}, ""},
+		{"default step", ParameterRange{Min: 10, Max: 18}, intSpec, 100, []float64{10, 12, 14, 16, 18}, ""},
+		{"single", ParameterRange{Min: 7, Max: 7}, intSpec, 100, []float64{7}, ""},
+		{"values", ParameterRange{Values: []float64{3, 1, 2}}, intSpec, 100, []float64{3, 1, 2}, ""},
+		{"inverted", ParameterRange{Min: 5, Max: 1, Step: 1}, intSpec, 100, nil, "max is less than min"},
+		{"at limit", ParameterRange{Min: 1, Max: 10, Step: 1}, intSpec, 10, []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, ""},
+		{"over limit", ParameterRange{Min: 1, Max: 11, Step: 1}, intSpec, 10, nil, "more than 10 grid values"},
+		{"tiny step", ParameterRange{Min: 0, Max: 1, Step: 1e-12}, floatSpec, 5000, nil, "more than 5000 grid values"},
+		{"too many values", ParameterRange{Values: []float64{1, 2, 3}}, intSpec, 2, nil, "more than 2 grid values"},
+	}
+	for _, tt := range tests {
+		got, err := gridValues("p", tt.r, tt.spec, tt.limit)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("%s: error = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestSearchSpaceGrid(t *testing.T) {
+	schema := map[string]algorithms.ParameterSpec{
+		"a": {Name: "a", Type: algorithms.ParamInt},
+		"b": {Name: "b", Type: algorithms.ParamFloat},
+	}
+	cfg := OptimizeConfig{
+		MaxRuns: 6,
+		Parameters: map[string]ParameterRange{
+			"a": {Min: 1, Max: 3, Step: 1},
+			"b": {Values: []float64{0.5, 1.5}},
+		},
+	}
+	configs, err := searchSpace(cfg, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 6 {
+		t.Fatalf("got %d configurations, want 6", len(configs))
+	}
+	seen := make(map[[2]float64]bool)
+	for _, params := range configs {
+		a, ok := params["a"].(int)
+		if !ok {
+			t.Fatalf("int parameter a is %T", params["a"])
+		}
+		seen[[2]float64{float64(a), params["b"].(float64)}] = true
+	}
+	if len(seen) != 6 {
+		t.Fatalf("configurations repeat: %v", configs)
+	}
+
+	cfg.MaxRuns = 5
+	if _, err := searchSpace(cfg, schema); err == nil {
+		t.Fatal("expected a grid over max_runs to be rejected")
+	}
+	cfg.Parameters["c"] = ParameterRange{Min: 1, Max: 2}
+	if _, err := searchSpace(cfg, schema); err == nil || !strings.Contains(err.Error(), "unknown parameter") {
+		t.Fatalf("error = %v, want unknown parameter", err)
+	}
+}
+
+func TestWithDefaultsBounds(t *testing.T) {
+	params := map[string]ParameterRange{"p": {Min: 1, Max: 2}}
+	cfg, err := OptimizeConfig{Parameters: params, Workers: 1 << 20}.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Workers != runtime.NumCPU() {
+		t.Fatalf("workers = %d, want capped at the CPU count", cfg.Workers)
+	}
+	if _, err := (OptimizeConfig{Parameters: params, MaxRuns: MaxSearchRuns + 1}).withDefaults(); err == nil {
+		t.Fatal("expected max_runs over the cap to be rejected")
+	}
+	if _, err := (OptimizeConfig{Parameters: params, Method: MethodRandom, Samples: MaxSearchRuns + 1}).withDefaults(); err == nil {
+		t.Fatal("expected samples over the cap to be rejected")
+	}
+}
+
+func TestOptimizeMinTrades(t *testing.T) {
+	data := testBars(400, 3)
+	algorithm := algorithms.NewTrendFollowingStrategy()
+	cfg := OptimizeConfig{
+		Parameters: map[string]ParameterRange{
+			"ema_fast": {Min: 5, Max: 15, Step: 5},
+			"ema_slow": {Min: 30, Max: 50, Step: 10},
+		},
+		Folds:   -1,
+		Workers: 2,
+	}
+
+	all, err := Optimize(context.Background(), algorithm, data, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxTrades := 0
+	for _, candidate := range all.Results {
+		if !candidate.Eligible {
+			t.Fatalf("candidate %v ineligible with no min_trades", candidate.Parameters)
+		}
+		if candidate.Metrics.Trades > maxTrades {
+			maxTrades = candidate.Metrics.Trades
+		}
+	}
+	if maxTrades == 0 {
+		t.Fatal("no candidate traded; the test data is too quiet")
+	}
+
+	// Only the most active configurations clear the threshold; they must
+	// rank ahead of every other, and nothing may score -Inf
+	cfg.MinTrades = maxTrades
+	filtered, err := Optimize(context.Background(), algorithm, data, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seenIneligible := false
+	for i, candidate := range filtered.Results {
+		if math.IsInf(candidate.Score, 0) || math.IsNaN(candidate.Score) {
+			t.Fatalf("candidate %d score = %v", i, candidate.Score)
+		}
+		if candidate.Eligible != (candidate.Metrics.Trades >= cfg.MinTrades) {
+			t.Fatalf("candidate %d eligible = %v with %d trades", i, candidate.Eligible, candidate.Metrics.Trades)
+		}
+		if candidate.Eligible && seenIneligible {
+			t.Fatalf("eligible candidate %d ranked after an ineligible one", i)
+		}
+		if !candidate.Eligible {
+			seenIneligible = true
+		} else if i > 0 && filtered.Results[i-1].Score < candidate.Score {
+			t.Fatalf("candidate %d outscores the one ranked above it", i)
+		}
+	}
+	if !filtered.Best.Eligible {
+		t.Fatal("best candidate is ineligible")
+	}
+
+	// With nothing eligible the result must still marshal
+	cfg.MinTrades = maxTrades + 1000
+	none, err := Optimize(context.Background(), algorithm, data, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if none.Best.Eligible {
+		t.Fatal("best candidate eligible above every trade count")
+	}
+	if _, err := json.Marshal(none); err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+}
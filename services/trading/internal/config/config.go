@@ -62,10 +62,19 @@ type MarketDataAPIConfig struct {
 }
 
 type TradingConfig struct {
-	MaxConnections     int
-	WebSocketBufferSize int
-	RateLimitRequests  int
-	RateLimitWindow    int
+	MaxConnections           int
+	WebSocketBufferSize      int
+	RateLimitRequests        int
+	RateLimitWindow          int
+	StrategiesDir            string        // Rule strategy definitions (.json/.yaml), empty to disable
+	ModelPath                string        // Trained ML model file, empty to keep models in memory only
+	SignalHistoryPath        string        // Signal history file (JSON Lines), empty to keep history in memory only
+	SignalEvaluationInterval time.Duration // How often pending signals are checked against prices
+	RiskFreeRate             float64       // Annual rate used for Sharpe, Sortino and alpha
+	DefaultBenchmark         string        // Benchmark portfolio beta is measured against
+	TargetVolatility         float64       // Annualized volatility signal position sizes aim for
+	LedgerPath               string        // Transaction ledger file (JSON Lines), empty to keep the ledger in memory only
+	CostBasisMethod          string        // fifo, lifo, average or specific; positions derived from the ledger use it
 }
 
 type SecurityConfig struct {
@@ -82,6 +91,7 @@ type PerformanceConfig struct {
 	WorkerPoolSize         int
 	MaxConcurrentRequests  int
 	RequestTimeout         time.Duration
+	HistoryCacheTTL        time.Duration // How long fetched price history is reused, 0 to disable
 }
 
 // LoadConfig loads configuration from environment variables
@@ -121,10 +131,19 @@ func LoadConfig() (*Config, error) {
 		},
 
 		Trading: TradingConfig{
-			MaxConnections:      getEnvAsInt("MAX_CONNECTIONS", 1000),
-			WebSocketBufferSize: getEnvAsInt("WEBSOCKET_BUFFER_SIZE", 1024),
-			RateLimitRequests:   getEnvAsInt("RATE_LIMIT_REQUESTS", 100),
-			RateLimitWindow:     getEnvAsInt("RATE_LIMIT_WINDOW", 60),
+			MaxConnections:           getEnvAsInt("MAX_CONNECTIONS", 1000),
+			WebSocketBufferSize:      getEnvAsInt("WEBSOCKET_BUFFER_SIZE", 1024),
+			RateLimitRequests:        getEnvAsInt("RATE_LIMIT_REQUESTS", 100),
+			RateLimitWindow:          getEnvAsInt("RATE_LIMIT_WINDOW", 60),
+			StrategiesDir:            getEnv("STRATEGIES_DIR", ""),
+			ModelPath:                getEnv("ML_MODEL_PATH", ""),
+			SignalHistoryPath:        getEnv("SIGNAL_HISTORY_PATH", ""),
+			SignalEvaluationInterval: getEnvAsDuration("SIGNAL_EVALUATION_INTERVAL", 5*time.Minute),
+			RiskFreeRate:             getEnvAsFloat("RISK_FREE_RATE", 0.02),
+			DefaultBenchmark:         getEnv("DEFAULT_BENCHMARK", "SPY"),
+			TargetVolatility:         getEnvAsFloat("TARGET_VOLATILITY", 0.15),
+			LedgerPath:               getEnv("LEDGER_PATH", ""),
+			CostBasisMethod:          getEnv("COST_BASIS_METHOD", "fifo"),
 		},
 
 		Security: SecurityConfig{
@@ -141,6 +160,7 @@ func LoadConfig() (*Config, error) {
 			WorkerPoolSize:        getEnvAsInt("WORKER_POOL_SIZE", 50),
 			MaxConcurrentRequests: getEnvAsInt("MAX_CONCURRENT_REQUESTS", 100),
 			RequestTimeout:        getEnvAsDuration("REQUEST_TIMEOUT", 30*time.Second),
+			HistoryCacheTTL:       getEnvAsDuration("HISTORY_CACHE_TTL", 5*time.Minute),
 		},
 	}
 
@@ -164,6 +184,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		// Simple comma-separated parsing
@@ -0,0 +1,264 @@
+package expression
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+)
+
+// Expression is a parsed and type-checked indicator expression, e.g.
+//
+//	crossover(EMA(close, 9), EMA(close, 21)) and RSI(close, 14) < 40
+//
+// It is immutable and safe to evaluate concurrently.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Parse parses and type-checks an expression
+func Parse(src string) (*Expression, error) {
+	return ParseWithConstants(src, nil)
+}
+
+// ParseWithConstants parses an expression in which the named constants
+// (matched case-insensitively) stand for numbers, e.g. RSI(close, rsi_period).
+// Constants are substituted at parse time so they can be used as periods.
+func ParseWithConstants(src string, constants map[string]float64) (*Expression, error) {
+	lowered := make(map[string]float64, len(constants))
+	for name, value := range constants {
+		lowered[strings.ToLower(name)] = value
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, errorf(0, "expression is empty")
+	}
+
+	p := &parser{tokens: tokens, constants: lowered}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorf(t.pos, "unexpected %s", describe(t))
+	}
+
+	return &Expression{source: src, root: root}, nil
+}
+
+// MustParse is like Parse but panics on error, for expressions known at compile time
+func MustParse(src string) *Expression {
+	expr, err := Parse(src)
+	if err != nil {
+		panic(err)
+	}
+	return expr
+}
+
+// IsField reports whether name is a bar field (open, high, low, close, volume)
+func IsField(name string) bool {
+	return fields[strings.ToLower(name)]
+}
+
+// IsKeyword reports whether name lexes as a keyword (and, or, not, true,
+// false) and so can't be used as an identifier
+func IsKeyword(name string) bool {
+	_, ok := keywords[strings.ToLower(name)]
+	return ok
+}
+
+// IsFunction reports whether name is a built-in function
+func IsFunction(name string) bool {
+	_, ok := functions[strings.ToUpper(name)]
+	return ok
+}
+
+// String returns the source text
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Type returns the result type of the expression
+func (e *Expression) Type() Type {
+	return e.root.typ()
+}
+
+// Result holds one value per bar. Conditions are stored as 1 (true) and
+// 0 (false); NaN marks bars where the value is not yet computable.
+type Result struct {
+	Type   Type
+	Dates  []time.Time
+	Values []float64
+}
+
+// Len returns the number of bars
+func (r *Result) Len() int {
+	return len(r.Values)
+}
+
+// Valid reports whether bar i has a computable value
+func (r *Result) Valid(i int) bool {
+	return i >= 0 && i < len(r.Values) && !math.IsNaN(r.Values[i])
+}
+
+// Bool reports whether the condition holds on bar i
+func (r *Result) Bool(i int) bool {
+	return r.Valid(i) && r.Values[i] != 0
+}
+
+// Last returns the latest value and whether it is computable
+func (r *Result) Last() (float64, bool) {
+	i := len(r.Values) - 1
+	if !r.Valid(i) {
+		return 0, false
+	}
+	return r.Values[i], true
+}
+
+// Evaluate runs the expression over historical data, oldest first
+func (e *Expression) Evaluate(data []models.HistoricalData) (*Result, error) {
+	return e.EvaluateBars(indicators.NewBars(data))
+}
+
+// EvaluateBars runs the expression over prepared bars
+func (e *Expression) EvaluateBars(bars *indicators.Bars) (*Result, error) {
+	if bars.Len() == 0 {
+		return nil, errorf(-1, "no data to evaluate")
+	}
+
+	values, err := eval(e.root, bars)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Type: e.Type(), Dates: bars.Dates, Values: values}, nil
+}
+
+// eval computes a node as a series aligned to the bars. Constants are
+// broadcast so every node yields bars.Len() values.
+func eval(n node, bars *indicators.Bars) ([]float64, error) {
+	size := bars.Len()
+
+	switch n := n.(type) {
+	case *numberNode:
+		return constant(n.value, size), nil
+
+	case *boolNode:
+		return constant(boolValue(n.value), size), nil
+
+	case *fieldNode:
+		switch n.name {
+		case "open":
+			return bars.Open, nil
+		case "high":
+			return bars.High, nil
+		case "low":
+			return bars.Low, nil
+		case "volume":
+			return bars.Volume, nil
+		}
+		return bars.Close, nil
+
+	case *unaryNode:
+		x, err := eval(n.x, bars)
+		if err != nil {
+			return nil, err
+		}
+		if n.op == tokNot {
+			return mapSeries(x, func(v float64) float64 {
+				if math.IsNaN(v) {
+					return v
+				}
+				return boolValue(v == 0)
+			}), nil
+		}
+		return mapSeries(x, func(v float64) float64 { return -v }), nil
+
+	case *binaryNode:
+		l, err := eval(n.l, bars)
+		if err != nil {
+			return nil, err
+		}
+		r, err := eval(n.r, bars)
+		if err != nil {
+			return nil, err
+		}
+		return zipSeries(l, r, func(a, b float64) float64 { return applyBinary(n.op, a, b) }), nil
+
+	case *callNode:
+		args := make([]arg, len(n.args))
+		for i, a := range n.args {
+			if num, ok := a.(*numberNode); ok && (n.fn.args[i] == argPeriod || n.fn.args[i] == argConst) {
+				args[i] = arg{num: num.value}
+				continue
+			}
+			series, err := eval(a, bars)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = arg{series: series}
+		}
+
+		values, err := n.fn.eval(bars, args)
+		if err != nil {
+			return nil, errorf(n.at, "%s: %v", n.name, err)
+		}
+		return alignRight(values, size), nil
+	}
+
+	return nil, errorf(n.pos(), "cannot evaluate expression")
+}
+
+// applyBinary evaluates one operator on a bar. Comparisons and logic with
+// a missing operand are not computable rather than false, so warm-up bars
+// never produce spurious signals.
+func applyBinary(op tokenKind, a, b float64) float64 {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.NaN()
+	}
+
+	switch op {
+	case tokLT:
+		return boolValue(a < b)
+	case tokLE:
+		return boolValue(a <= b)
+	case tokGT:
+		return boolValue(a > b)
+	case tokGE:
+		return boolValue(a >= b)
+	case tokEQ:
+		return boolValue(a == b)
+	case tokNE:
+		return boolValue(a != b)
+	case tokAnd:
+		return boolValue(a != 0 && b != 0)
+	case tokOr:
+		return boolValue(a != 0 || b != 0)
+	}
+	return applyArithmetic(op, a, b)
+}
+
+// alignRight pads indicator output with NaN so index i matches bar i
+func alignRight(values []float64, n int) []float64 {
+	if len(values) >= n {
+		return values[len(values)-n:]
+	}
+	padded := nanSeries(n)
+	copy(padded[n-len(values):], values)
+	return padded
+}
+
+func constant(v float64, n int) []float64 {
+	series := make([]float64, n)
+	for i := range series {
+		series[i] = v
+	}
+	return series
+}
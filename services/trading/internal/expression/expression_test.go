@@ -0,0 +1,221 @@
+package expression
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/models"
+)
+
+// closesData builds bars whose open is the previous close
+func closesData(closes ...float64) []models.HistoricalData {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := make([]models.HistoricalData, len(closes))
+	for i, c := range closes {
+		open := c
+		if i > 0 {
+			open = closes[i-1]
+		}
+		data[i] = models.HistoricalData{
+			Date:   start.AddDate(0, 0, i),
+			Open:   decimal.NewFromFloat(open),
+			High:   decimal.NewFromFloat(math.Max(open, c) + 1),
+			Low:    decimal.NewFromFloat(math.Min(open, c) - 1),
+			Close:  decimal.NewFromFloat(c),
+			Volume: 1000,
+		}
+	}
+	return data
+}
+
+func TestLex(t *testing.T) {
+	tokens, err := lex("RSI(close, 14) <= 30.5 && !x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []tokenKind{tokIdent, tokLParen, tokIdent, tokComma, tokNumber, tokRParen, tokLE, tokNumber, tokAnd, tokNot, tokIdent, tokEOF}
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens, want %d", len(tokens), len(want))
+	}
+	for i, tok := range tokens {
+		if tok.kind != want[i] {
+			t.Fatalf("token %d is %s, want %s", i, tok.kind, want[i])
+		}
+	}
+	if tokens[7].num != 30.5 || tokens[7].pos != 18 {
+		t.Fatalf("number token = %v at %d, want 30.5 at 18", tokens[7].num, tokens[7].pos)
+	}
+
+	keywords, err := lex("AND Or not TRUE false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, kind := range []tokenKind{tokAnd, tokOr, tokNot, tokTrue, tokFalse} {
+		if keywords[i].kind != kind {
+			t.Fatalf("keyword %q lexed as %s", keywords[i].text, keywords[i].kind)
+		}
+	}
+
+	for src, pos := range map[string]int{"1.2.3": 0, "close # 2": 6} {
+		_, err := lex(src)
+		var exprErr *Error
+		if !errors.As(err, &exprErr) || exprErr.Pos != pos {
+			t.Fatalf("lex(%q) error = %v, want one at position %d", src, err, pos)
+		}
+	}
+}
+
+func TestParseTypes(t *testing.T) {
+	for src, want := range map[string]Type{
+		"-(2 * 3) + 1":                   TypeNumber,
+		"close":                          TypeSeries,
+		"SMA(close, 20) - close":         TypeSeries,
+		"close > open":                   TypeBool,
+		"not (close > open) or true":     TypeBool,
+		"crossover(EMA(close, 9), open)": TypeBool,
+	} {
+		expr, err := Parse(src)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", src, err)
+		}
+		if expr.Type() != want {
+			t.Fatalf("Parse(%q) type %s, want %s", src, expr.Type(), want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"close and open",
+		"not close",
+		"close < true",
+		"-(close > open)",
+		"price > 1",
+		"FOO(close)",
+		"SMA(close)",
+		"close > open )",
+		"(close",
+	} {
+		if _, err := Parse(src); err == nil {
+			t.Fatalf("Parse(%q) succeeded, want an error", src)
+		}
+	}
+}
+
+func TestParseDepthLimit(t *testing.T) {
+	nest := func(open, close string, n int) string {
+		return strings.Repeat(open, n) + "1" + strings.Repeat(close, n)
+	}
+
+	if _, err := Parse(nest("(", ")", maxDepth-2)); err != nil {
+		t.Fatalf("nesting within the limit failed: %v", err)
+	}
+	for name, src := range map[string]string{
+		"parentheses": nest("(", ")", 100000),
+		"negation":    strings.Repeat("-", 100000) + "close",
+		"not":         strings.Repeat("not ", 100000) + "true",
+		"calls":       nest("ABS(", ")", 100000),
+	} {
+		_, err := Parse(src)
+		if err == nil || !strings.Contains(err.Error(), "nested more than") {
+			t.Fatalf("%s: error = %v, want depth limit", name, err)
+		}
+	}
+}
+
+func TestParseWithConstants(t *testing.T) {
+	expr, err := ParseWithConstants("SMA(close, Period) > level", map[string]float64{"period": 2, "LEVEL": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := expr.Evaluate(closesData(8, 10, 12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid(0) {
+		t.Fatal("warm-up bar should not be computable")
+	}
+	if result.Bool(1) || !result.Bool(2) {
+		t.Fatalf("SMA(close, 2) > 10 = %v, want [NaN 0 1]", result.Values)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	data := closesData(10, 11, 12, 11, 13, 14)
+
+	eval := func(src string) *Result {
+		t.Helper()
+		result, err := MustParse(src).Evaluate(data)
+		if err != nil {
+			t.Fatalf("Evaluate(%q): %v", src, err)
+		}
+		if result.Len() != len(data) {
+			t.Fatalf("Evaluate(%q) has %d values for %d bars", src, result.Len(), len(data))
+		}
+		return result
+	}
+
+	sma := eval("SMA(close, 3)")
+	if last, ok := sma.Last(); !ok || math.Abs(last-38.0/3) > 1e-9 {
+		t.Fatalf("SMA(close, 3) last = %v, want %v", last, 38.0/3)
+	}
+
+	up := eval("close > open")
+	for i, want := range []bool{false, true, true, false, true, true} {
+		if up.Bool(i) != want {
+			t.Fatalf("close > open on bar %d = %v, want %v", i, up.Bool(i), want)
+		}
+	}
+
+	crossed := eval("crossover(close, REF(close, 1) + 0.5)")
+	if !crossed.Bool(4) || crossed.Bool(5) {
+		t.Fatalf("crossover = %v, want true on bar 4 only", crossed.Values)
+	}
+
+	if divided := eval("close / (open - open)"); divided.Valid(3) {
+		t.Fatalf("division by zero = %v, want not computable", divided.Values[3])
+	}
+
+	if _, err := MustParse("close").Evaluate(nil); err == nil {
+		t.Fatal("evaluating no bars should fail")
+	}
+}
+
+func TestPeriodLimit(t *testing.T) {
+	for _, src := range []string{
+		"CHANGE(close, 9223372036854775807) > 0",
+		"ROC(close, 9300000000000000000)",
+		"REF(close, 99999999999999999999)",
+		"SMA(close, 10001)",
+	} {
+		_, err := Parse(src)
+		var exprErr *Error
+		if !errors.As(err, &exprErr) || !strings.Contains(exprErr.Msg, "at most") {
+			t.Fatalf("Parse(%q) error = %v, want a period limit error", src, err)
+		}
+	}
+
+	data := closesData(10, 11, 12)
+	for _, src := range []string{
+		"CHANGE(close, 10000)",
+		"ROC(close, 3)",
+		"REF(close, 500)",
+		"HIGHEST(close, 10000)",
+		"LOWEST(close, 4)",
+	} {
+		result, err := MustParse(src).Evaluate(data)
+		if err != nil {
+			t.Fatalf("Evaluate(%q): %v", src, err)
+		}
+		for i := range data {
+			if result.Valid(i) {
+				t.Fatalf("Evaluate(%q) bar %d = %v, want not computable", src, i, result.Values[i])
+			}
+		}
+	}
+}
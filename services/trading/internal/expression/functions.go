@@ -0,0 +1,391 @@
+package expression
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"trading-service/internal/indicators"
+)
+
+type argKind int
+
+const (
+	argSeries argKind = iota // Number or series, numbers are broadcast
+	argBool                  // Condition
+	argPeriod                // Constant positive integer
+	argConst                 // Constant number
+)
+
+func (k argKind) String() string {
+	switch k {
+	case argSeries:
+		return "series"
+	case argBool:
+		return "condition"
+	case argPeriod:
+		return "period"
+	case argConst:
+		return "constant"
+	}
+	return "unknown"
+}
+
+// maxPeriod bounds period arguments so that converting them to int cannot
+// overflow and no lookback exceeds any realistic history
+const maxPeriod = 10000
+
+// arg is an evaluated argument: series for series and condition arguments,
+// num for periods and constants
+type arg struct {
+	series []float64
+	num    float64
+}
+
+func (a arg) period() int {
+	return int(a.num)
+}
+
+// function describes a built-in callable from expressions
+type function struct {
+	name   string
+	args   []argKind
+	result Type
+	doc    string
+	eval   func(bars *indicators.Bars, args []arg) ([]float64, error)
+}
+
+// check validates argument count and types at parse time
+func (f *function) check(pos int, args []node) error {
+	if len(args) != len(f.args) {
+		return errorf(pos, "%s expects %d argument(s) (%s), got %d", f.name, len(f.args), f.signature(), len(args))
+	}
+
+	for i, kind := range f.args {
+		a := args[i]
+		switch kind {
+		case argSeries:
+			if !a.typ().numeric() {
+				return errorf(a.pos(), "argument %d of %s must be a series, found %s", i+1, f.name, a.typ())
+			}
+		case argBool:
+			if a.typ() != TypeBool {
+				return errorf(a.pos(), "argument %d of %s must be a condition, found %s", i+1, f.name, a.typ())
+			}
+		case argPeriod:
+			num, ok := a.(*numberNode)
+			if !ok || num.value < 1 || num.value != math.Trunc(num.value) {
+				return errorf(a.pos(), "argument %d of %s must be a positive whole number", i+1, f.name)
+			}
+			if num.value > maxPeriod {
+				return errorf(a.pos(), "argument %d of %s must be at most %d", i+1, f.name, maxPeriod)
+			}
+		case argConst:
+			if _, ok := a.(*numberNode); !ok {
+				return errorf(a.pos(), "argument %d of %s must be a constant number", i+1, f.name)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *function) signature() string {
+	kinds := make([]string, len(f.args))
+	for i, k := range f.args {
+		kinds[i] = k.String()
+	}
+	return strings.Join(kinds, ", ")
+}
+
+// FunctionInfo describes a built-in function for API discovery
+type FunctionInfo struct {
+	Name      string `json:"name"`
+	Signature string `json:"signature"`
+	Returns   string `json:"returns"`
+	Doc       string `json:"description"`
+}
+
+// Functions lists the built-in functions sorted by name
+func Functions() []FunctionInfo {
+	infos := make([]FunctionInfo, 0, len(functions))
+	for _, f := range functions {
+		infos = append(infos, FunctionInfo{
+			Name:      f.name,
+			Signature: fmt.Sprintf("%s(%s)", f.name, f.signature()),
+			Returns:   f.result.String(),
+			Doc:       f.doc,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+var functions = map[string]*function{}
+
+func register(f *function) {
+	functions[f.name] = f
+}
+
+func init() {
+	// Moving averages and oscillators on an arbitrary series
+	register(&function{name: "SMA", args: []argKind{argSeries, argPeriod}, result: TypeSeries,
+		doc:  "Simple moving average",
+		eval: seriesIndicator(indicators.SMAFloat)})
+	register(&function{name: "EMA", args: []argKind{argSeries, argPeriod}, result: TypeSeries,
+		doc:  "Exponential moving average",
+		eval: seriesIndicator(indicators.EMAFloat)})
+	register(&function{name: "RSI", args: []argKind{argSeries, argPeriod}, result: TypeSeries,
+		doc:  "Relative Strength Index (0-100)",
+		eval: seriesIndicator(indicators.RSIFloat)})
+
+	for _, out := range []struct {
+		name string
+		doc  string
+		pick int
+	}{
+		{"MACD", "MACD line", 0},
+		{"MACD_SIGNAL", "MACD signal line", 1},
+		{"MACD_HIST", "MACD histogram", 2},
+	} {
+		pick := out.pick
+		register(&function{name: out.name, args: []argKind{argSeries, argPeriod, argPeriod, argPeriod}, result: TypeSeries,
+			doc: out.doc + " (series, fast, slow, signal)",
+			eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+				return onValid(args[0].series, func(values []float64) ([]float64, error) {
+					macd, signal, hist, err := indicators.MACDFloat(values, args[1].period(), args[2].period(), args[3].period())
+					return [][]float64{macd, signal, hist}[pick], err
+				})
+			}})
+	}
+
+	for _, out := range []struct {
+		name string
+		doc  string
+		pick int
+	}{
+		{"BB_UPPER", "Upper Bollinger Band", 0},
+		{"BB_MIDDLE", "Middle Bollinger Band", 1},
+		{"BB_LOWER", "Lower Bollinger Band", 2},
+	} {
+		pick := out.pick
+		register(&function{name: out.name, args: []argKind{argSeries, argPeriod, argConst}, result: TypeSeries,
+			doc: out.doc + " (series, period, standard deviations)",
+			eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+				return onValid(args[0].series, func(values []float64) ([]float64, error) {
+					upper, middle, lower, err := indicators.BollingerBandsFloat(values, args[1].period(), args[2].num)
+					return [][]float64{upper, middle, lower}[pick], err
+				})
+			}})
+	}
+
+	// Indicators computed from the bars themselves
+	register(&function{name: "STOCH_K", args: []argKind{argPeriod}, result: TypeSeries,
+		doc: "Stochastic %K",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			k, _, err := indicators.StochasticFloat(bars.High, bars.Low, bars.Close, args[0].period(), 1)
+			return k, err
+		}})
+	register(&function{name: "STOCH_D", args: []argKind{argPeriod, argPeriod}, result: TypeSeries,
+		doc: "Stochastic %D (k period, d period)",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			_, d, err := indicators.StochasticFloat(bars.High, bars.Low, bars.Close, args[0].period(), args[1].period())
+			return d, err
+		}})
+	register(&function{name: "WILLR", args: []argKind{argPeriod}, result: TypeSeries,
+		doc: "Williams %R (-100-0)",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return indicators.WilliamsRFloat(bars.High, bars.Low, bars.Close, args[0].period())
+		}})
+	for _, out := range []struct {
+		name string
+		doc  string
+		pick int
+	}{
+		{"ADX", "Average Directional Index", 0},
+		{"PLUS_DI", "Positive directional indicator", 1},
+		{"MINUS_DI", "Negative directional indicator", 2},
+	} {
+		pick := out.pick
+		register(&function{name: out.name, args: []argKind{argPeriod}, result: TypeSeries,
+			doc: out.doc,
+			eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+				adx, plusDI, minusDI, err := indicators.ADXFloat(bars.High, bars.Low, bars.Close, args[0].period())
+				return [][]float64{adx, plusDI, minusDI}[pick], err
+			}})
+	}
+	register(&function{name: "CCI", args: []argKind{argPeriod}, result: TypeSeries,
+		doc: "Commodity Channel Index",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return indicators.CCIFloat(bars.High, bars.Low, bars.Close, args[0].period())
+		}})
+	register(&function{name: "ATR", args: []argKind{argPeriod}, result: TypeSeries,
+		doc: "Average True Range",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return indicators.ATRFloat(bars.High, bars.Low, bars.Close, args[0].period())
+		}})
+	register(&function{name: "OBV", args: nil, result: TypeSeries,
+		doc: "On-Balance Volume",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return indicators.OBVFloat(bars.Close, bars.Volume)
+		}})
+
+	// Series helpers
+	register(&function{name: "HIGHEST", args: []argKind{argSeries, argPeriod}, result: TypeSeries,
+		doc: "Highest value over the last period bars",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return window(args[0].series, args[1].period(), math.Max), nil
+		}})
+	register(&function{name: "LOWEST", args: []argKind{argSeries, argPeriod}, result: TypeSeries,
+		doc: "Lowest value over the last period bars",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return window(args[0].series, args[1].period(), math.Min), nil
+		}})
+	register(&function{name: "REF", args: []argKind{argSeries, argPeriod}, result: TypeSeries,
+		doc: "Value period bars ago",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return lagged(args[0].series, args[1].period(), func(now, then float64) float64 { return then }), nil
+		}})
+	register(&function{name: "CHANGE", args: []argKind{argSeries, argPeriod}, result: TypeSeries,
+		doc: "Difference from the value period bars ago",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return lagged(args[0].series, args[1].period(), func(now, then float64) float64 { return now - then }), nil
+		}})
+	register(&function{name: "ROC", args: []argKind{argSeries, argPeriod}, result: TypeSeries,
+		doc: "Percentage change from the value period bars ago",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return lagged(args[0].series, args[1].period(), func(now, then float64) float64 {
+				if then == 0 {
+					return math.NaN()
+				}
+				return (now - then) / then * 100
+			}), nil
+		}})
+	register(&function{name: "ABS", args: []argKind{argSeries}, result: TypeSeries,
+		doc: "Absolute value",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return mapSeries(args[0].series, math.Abs), nil
+		}})
+	register(&function{name: "MIN", args: []argKind{argSeries, argSeries}, result: TypeSeries,
+		doc: "Smaller of two values per bar",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return zipSeries(args[0].series, args[1].series, math.Min), nil
+		}})
+	register(&function{name: "MAX", args: []argKind{argSeries, argSeries}, result: TypeSeries,
+		doc: "Larger of two values per bar",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return zipSeries(args[0].series, args[1].series, math.Max), nil
+		}})
+
+	// Conditions
+	register(&function{name: "CROSSOVER", args: []argKind{argSeries, argSeries}, result: TypeBool,
+		doc: "True on the bar where a crosses above b",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return crossed(args[0].series, args[1].series), nil
+		}})
+	register(&function{name: "CROSSUNDER", args: []argKind{argSeries, argSeries}, result: TypeBool,
+		doc: "True on the bar where a crosses below b",
+		eval: func(bars *indicators.Bars, args []arg) ([]float64, error) {
+			return crossed(args[1].series, args[0].series), nil
+		}})
+}
+
+// seriesIndicator adapts a single-series indicator to skip leading NaN
+func seriesIndicator(calc func([]float64, int) ([]float64, error)) func(*indicators.Bars, []arg) ([]float64, error) {
+	return func(bars *indicators.Bars, args []arg) ([]float64, error) {
+		return onValid(args[0].series, func(values []float64) ([]float64, error) {
+			return calc(values, args[1].period())
+		})
+	}
+}
+
+// onValid runs calc on the series after its leading NaN warm-up so that
+// indicators can be nested, e.g. EMA(RSI(close, 14), 9)
+func onValid(series []float64, calc func([]float64) ([]float64, error)) ([]float64, error) {
+	start := 0
+	for start < len(series) && math.IsNaN(series[start]) {
+		start++
+	}
+	return calc(series[start:])
+}
+
+// window applies a rolling reduction over period bars
+func window(series []float64, period int, reduce func(a, b float64) float64) []float64 {
+	result := nanSeries(len(series))
+	if period < 1 || period > len(series) {
+		return result
+	}
+	for i := period - 1; i < len(series); i++ {
+		acc := series[i-period+1]
+		for j := i - period + 2; j <= i; j++ {
+			acc = reduce(acc, series[j])
+		}
+		result[i] = acc
+	}
+	return result
+}
+
+// lagged combines each value with the value period bars earlier
+func lagged(series []float64, period int, combine func(now, then float64) float64) []float64 {
+	result := nanSeries(len(series))
+	if period < 1 || period >= len(series) {
+		return result
+	}
+	for i := period; i < len(series); i++ {
+		result[i] = combine(series[i], series[i-period])
+	}
+	return result
+}
+
+// crossed marks bars where a moves from at or below b to above b
+func crossed(a, b []float64) []float64 {
+	result := make([]float64, len(a))
+	for i := range result {
+		if i == 0 || anyNaN(a[i], b[i], a[i-1], b[i-1]) {
+			result[i] = math.NaN()
+			continue
+		}
+		result[i] = boolValue(a[i-1] <= b[i-1] && a[i] > b[i])
+	}
+	return result
+}
+
+func mapSeries(series []float64, f func(float64) float64) []float64 {
+	result := make([]float64, len(series))
+	for i, v := range series {
+		result[i] = f(v)
+	}
+	return result
+}
+
+func zipSeries(a, b []float64, f func(x, y float64) float64) []float64 {
+	result := make([]float64, len(a))
+	for i := range a {
+		result[i] = f(a[i], b[i])
+	}
+	return result
+}
+
+func nanSeries(n int) []float64 {
+	series := make([]float64, n)
+	for i := range series {
+		series[i] = math.NaN()
+	}
+	return series
+}
+
+func anyNaN(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,188 @@
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokLParen
+	tokRParen
+	tokComma
+	tokPlus
+	tokMinus
+	tokStar
+	tokSlash
+	tokLT
+	tokLE
+	tokGT
+	tokGE
+	tokEQ
+	tokNE
+	tokAnd
+	tokOr
+	tokNot
+	tokTrue
+	tokFalse
+)
+
+var tokenNames = map[tokenKind]string{
+	tokEOF:    "end of expression",
+	tokNumber: "number",
+	tokIdent:  "identifier",
+	tokLParen: "'('",
+	tokRParen: "')'",
+	tokComma:  "','",
+	tokPlus:   "'+'",
+	tokMinus:  "'-'",
+	tokStar:   "'*'",
+	tokSlash:  "'/'",
+	tokLT:     "'<'",
+	tokLE:     "'<='",
+	tokGT:     "'>'",
+	tokGE:     "'>='",
+	tokEQ:     "'=='",
+	tokNE:     "'!='",
+	tokAnd:    "'and'",
+	tokOr:     "'or'",
+	tokNot:    "'not'",
+	tokTrue:   "'true'",
+	tokFalse:  "'false'",
+}
+
+func (k tokenKind) String() string {
+	return tokenNames[k]
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+// Error is a parse, type or evaluation error with the position in the source
+// where it was detected (0-based byte offset, -1 when not applicable)
+type Error struct {
+	Pos int    `json:"position"`
+	Msg string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Pos < 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("at position %d: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+var keywords = map[string]tokenKind{
+	"and":   tokAnd,
+	"or":    tokOr,
+	"not":   tokNot,
+	"true":  tokTrue,
+	"false": tokFalse,
+}
+
+// lex splits the source into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(src) && unicode.IsDigit(rune(src[i+1]))):
+			start := i
+			for i < len(src) && (unicode.IsDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, errorf(start, "invalid number %q", src[start:i])
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: num, pos: start})
+
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(src) && (unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i])) || src[i] == '_') {
+				i++
+			}
+			text := src[start:i]
+			kind := tokIdent
+			if kw, ok := keywords[strings.ToLower(text)]; ok {
+				kind = kw
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: start})
+
+		default:
+			kind, width := operator(src[i:])
+			if width == 0 {
+				return nil, errorf(i, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{kind: kind, text: src[i : i+width], pos: i})
+			i += width
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(src)})
+	return tokens, nil
+}
+
+// operator matches punctuation at the start of s, returning its width
+func operator(s string) (tokenKind, int) {
+	if len(s) >= 2 {
+		switch s[:2] {
+		case "<=":
+			return tokLE, 2
+		case ">=":
+			return tokGE, 2
+		case "==":
+			return tokEQ, 2
+		case "!=":
+			return tokNE, 2
+		case "&&":
+			return tokAnd, 2
+		case "||":
+			return tokOr, 2
+		}
+	}
+
+	switch s[0] {
+	case '(':
+		return tokLParen, 1
+	case ')':
+		return tokRParen, 1
+	case ',':
+		return tokComma, 1
+	case '+':
+		return tokPlus, 1
+	case '-':
+		return tokMinus, 1
+	case '*':
+		return tokStar, 1
+	case '/':
+		return tokSlash, 1
+	case '<':
+		return tokLT, 1
+	case '>':
+		return tokGT, 1
+	case '=':
+		return tokEQ, 1
+	case '!':
+		return tokNot, 1
+	}
+	return tokEOF, 0
+}
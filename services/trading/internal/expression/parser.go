@@ -0,0 +1,409 @@
+package expression
+
+import (
+	"math"
+	"strings"
+)
+
+// Type is the static type of an expression
+type Type int
+
+const (
+	TypeNumber Type = iota // Constant scalar
+	TypeSeries             // Numeric value per bar
+	TypeBool               // Condition per bar
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeNumber:
+		return "number"
+	case TypeSeries:
+		return "series"
+	case TypeBool:
+		return "bool"
+	}
+	return "unknown"
+}
+
+// numeric reports whether values of the type can be used in arithmetic
+func (t Type) numeric() bool {
+	return t == TypeNumber || t == TypeSeries
+}
+
+// Bar fields available as identifiers
+var fields = map[string]bool{
+	"open":   true,
+	"high":   true,
+	"low":    true,
+	"close":  true,
+	"volume": true,
+}
+
+type node interface {
+	pos() int
+	typ() Type
+}
+
+type numberNode struct {
+	at    int
+	value float64
+}
+
+type boolNode struct {
+	at    int
+	value bool
+}
+
+type fieldNode struct {
+	at   int
+	name string
+}
+
+type callNode struct {
+	at   int
+	name string
+	fn   *function
+	args []node
+}
+
+type unaryNode struct {
+	at int
+	op tokenKind
+	x  node
+	t  Type
+}
+
+type binaryNode struct {
+	at   int
+	op   tokenKind
+	l, r node
+	t    Type
+}
+
+func (n *numberNode) pos() int  { return n.at }
+func (n *numberNode) typ() Type { return TypeNumber }
+func (n *boolNode) pos() int    { return n.at }
+func (n *boolNode) typ() Type   { return TypeBool }
+func (n *fieldNode) pos() int   { return n.at }
+func (n *fieldNode) typ() Type  { return TypeSeries }
+func (n *callNode) pos() int    { return n.at }
+func (n *callNode) typ() Type   { return n.fn.result }
+func (n *unaryNode) pos() int   { return n.at }
+func (n *unaryNode) typ() Type  { return n.t }
+func (n *binaryNode) pos() int  { return n.at }
+func (n *binaryNode) typ() Type { return n.t }
+
+// parser is a recursive descent parser that type-checks as it builds nodes.
+//
+//	expr       := or
+//	or         := and { "or" and }
+//	and        := not { "and" not }
+//	not        := "not" not | comparison
+//	comparison := additive [ ("<"|"<="|">"|">="|"=="|"!=") additive ]
+//	additive   := term { ("+"|"-") term }
+//	term       := unary { ("*"|"/") unary }
+//	unary      := "-" unary | primary
+//	primary    := number | "true" | "false" | field | call | "(" expr ")"
+//	call       := name "(" [ expr { "," expr } ] ")"
+type parser struct {
+	tokens    []token
+	i         int
+	constants map[string]float64
+	depth     int
+}
+
+// maxDepth bounds nesting of parentheses, calls and prefix operators so
+// hostile input can't exhaust the stack
+const maxDepth = 100
+
+// enter descends one nesting level, failing past maxDepth. Each successful
+// call is paired with leave.
+func (p *parser) enter(pos int) error {
+	if p.depth >= maxDepth {
+		return errorf(pos, "expression nested more than %d levels deep", maxDepth)
+	}
+	p.depth++
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, errorf(t.pos, "expected %s, found %s", kind, describe(t))
+	}
+	return t, nil
+}
+
+func describe(t token) string {
+	if t.kind == tokEOF {
+		return t.kind.String()
+	}
+	return "'" + t.text + "'"
+}
+
+func (p *parser) parseOr() (node, error) {
+	if err := p.enter(p.peek().pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		op := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		op := p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = logical(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek().kind == tokNot {
+		op := p.next()
+		if err := p.enter(op.pos); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if x.typ() != TypeBool {
+			return nil, errorf(x.pos(), "'not' needs a condition, found %s", x.typ())
+		}
+		return &unaryNode{at: op.pos, op: tokNot, x: x, t: TypeBool}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.peek().kind {
+	case tokLT, tokLE, tokGT, tokGE, tokEQ, tokNE:
+		op := p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		if !left.typ().numeric() || !right.typ().numeric() {
+			return nil, errorf(op.pos, "cannot compare %s with %s", left.typ(), right.typ())
+		}
+		return &binaryNode{at: op.pos, op: op.kind, l: left, r: right, t: TypeBool}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokPlus || p.peek().kind == tokMinus {
+		op := p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokStar || p.peek().kind == tokSlash {
+		op := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.peek().kind == tokMinus {
+		op := p.next()
+		if err := p.enter(op.pos); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if !x.typ().numeric() {
+			return nil, errorf(op.pos, "cannot negate %s", x.typ())
+		}
+		// Fold negative literals so they still count as constants
+		if num, ok := x.(*numberNode); ok {
+			return &numberNode{at: op.pos, value: -num.value}, nil
+		}
+		return &unaryNode{at: op.pos, op: tokMinus, x: x, t: x.typ()}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return &numberNode{at: t.pos, value: t.num}, nil
+
+	case tokTrue, tokFalse:
+		return &boolNode{at: t.pos, value: t.kind == tokTrue}, nil
+
+	case tokLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return x, nil
+
+	case tokIdent:
+		if p.peek().kind == tokLParen {
+			return p.parseCall(t)
+		}
+		name := strings.ToLower(t.text)
+		if value, ok := p.constants[name]; ok {
+			return &numberNode{at: t.pos, value: value}, nil
+		}
+		if !fields[name] {
+			return nil, errorf(t.pos, "unknown identifier %q (expected open, high, low, close or volume)", t.text)
+		}
+		return &fieldNode{at: t.pos, name: name}, nil
+	}
+
+	return nil, errorf(t.pos, "unexpected %s", describe(t))
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	p.next() // (
+
+	var args []node
+	if p.peek().kind != tokRParen {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+	if _, err := p.expect(tokRParen); err != nil {
+		return nil, err
+	}
+
+	upper := strings.ToUpper(name.text)
+	fn, ok := functions[upper]
+	if !ok {
+		return nil, errorf(name.pos, "unknown function %q", name.text)
+	}
+	if err := fn.check(name.pos, args); err != nil {
+		return nil, err
+	}
+
+	return &callNode{at: name.pos, name: upper, fn: fn, args: args}, nil
+}
+
+// arithmetic type-checks a numeric binary operation, folding constants
+func arithmetic(op token, left, right node) (node, error) {
+	if !left.typ().numeric() || !right.typ().numeric() {
+		return nil, errorf(op.pos, "operator %s needs numbers or series, found %s and %s", op.kind, left.typ(), right.typ())
+	}
+
+	l, lok := left.(*numberNode)
+	r, rok := right.(*numberNode)
+	if lok && rok {
+		return &numberNode{at: l.at, value: applyArithmetic(op.kind, l.value, r.value)}, nil
+	}
+
+	return &binaryNode{at: op.pos, op: op.kind, l: left, r: right, t: TypeSeries}, nil
+}
+
+func logical(op token, left, right node) (node, error) {
+	if left.typ() != TypeBool || right.typ() != TypeBool {
+		return nil, errorf(op.pos, "operator %s needs conditions, found %s and %s", op.kind, left.typ(), right.typ())
+	}
+	return &binaryNode{at: op.pos, op: op.kind, l: left, r: right, t: TypeBool}, nil
+}
+
+func applyArithmetic(op tokenKind, a, b float64) float64 {
+	switch op {
+	case tokPlus:
+		return a + b
+	case tokMinus:
+		return a - b
+	case tokStar:
+		return a * b
+	case tokSlash:
+		if b == 0 {
+			return math.NaN()
+		}
+		return a / b
+	}
+	return math.NaN()
+}
@@ -1,41 +1,70 @@
 package handlers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 	"trading-service/internal/algorithms"
+	"trading-service/internal/backtest"
+	"trading-service/internal/expression"
+	"trading-service/internal/indicators"
 	"trading-service/internal/models"
-	"trading-service/internal/providers"
+	"trading-service/internal/ledger"
+	"trading-service/internal/risk"
 	"trading-service/internal/services"
 )
 
 // TradingHandler handles all trading-related HTTP requests
 type TradingHandler struct {
-	marketDataService *services.MarketDataService
-	analysisService   *services.AnalysisService
-	portfolioService  *services.PortfolioService
-	websocketHub      *WebSocketHub
-	logger            *logrus.Logger
+	marketDataService   *services.MarketDataService
+	analysisService     *services.AnalysisService
+	portfolioService    *services.PortfolioService
+	optimizationService *services.OptimizationService
+	signalHistory       *services.SignalHistoryService
+	scannerService      *services.ScannerService
+	benchmarkService    *services.BenchmarkService
+	stressTestService   *services.StressTestService
+	correlationService  *services.CorrelationService
+	ledgerService       *services.LedgerService
+	websocketHub        *WebSocketHub
+	logger              *logrus.Logger
 }
 
 func NewTradingHandler(
 	marketDataService *services.MarketDataService,
 	analysisService *services.AnalysisService,
 	portfolioService *services.PortfolioService,
+	optimizationService *services.OptimizationService,
+	signalHistory *services.SignalHistoryService,
+	scannerService *services.ScannerService,
+	benchmarkService *services.BenchmarkService,
+	stressTestService *services.StressTestService,
+	correlationService *services.CorrelationService,
+	ledgerService *services.LedgerService,
 	websocketHub *WebSocketHub,
 	logger *logrus.Logger,
 ) *TradingHandler {
 	return &TradingHandler{
-		marketDataService: marketDataService,
-		analysisService:   analysisService,
-		portfolioService:  portfolioService,
-		websocketHub:      websocketHub,
-		logger:            logger,
+		marketDataService:   marketDataService,
+		analysisService:     analysisService,
+		portfolioService:    portfolioService,
+		optimizationService: optimizationService,
+		signalHistory:       signalHistory,
+		scannerService:      scannerService,
+		benchmarkService:    benchmarkService,
+		stressTestService:   stressTestService,
+		correlationService:  correlationService,
+		ledgerService:       ledgerService,
+		websocketHub:        websocketHub,
+		logger:              logger,
 	}
 }
 
@@ -183,46 +212,1391 @@ func (h *TradingHandler) AnalyzeStock(c *gin.Context) {
 	if request.TimeFrame == "" {
 		request.TimeFrame = "1D"
 	}
+	if len(request.Indicators) == 0 {
+		request.Indicators = []string{"RSI", "MACD", "SMA_20", "EMA_12", "Bollinger", "Pivots", "SupportResistance", "Divergence"}
+	}
 	if request.Period == 0 {
 		request.Period = 30
+		// Divergence needs MACD's 34-bar warm-up plus room for swings
+		for _, indicator := range request.Indicators {
+			if indicator == "Divergence" {
+				request.Period = 90
+			}
+		}
 	}
-	if len(request.Indicators) == 0 {
-		request.Indicators = []string{"RSI", "MACD", "SMA_20", "EMA_12", "Bollinger"}
+
+	// Get historical data for analysis
+	from := time.Now().AddDate(0, 0, -request.Period)
+	to := time.Now()
+
+	historicalData, err := h.marketDataService.GetHistoricalData(request.Symbol, from, to)
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", request.Symbol).Error("Failed to get data for analysis")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to fetch data for analysis",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if len(historicalData) < 20 {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Insufficient data for analysis",
+			Error:     "need at least 20 data points",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Perform technical analysis
+	analysisResult, err := h.analysisService.PerformTechnicalAnalysis(request.Symbol, historicalData, request.Indicators)
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", request.Symbol).Error("Technical analysis failed")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Technical analysis failed",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Analysis completed successfully",
+		Data:      analysisResult,
+		Timestamp: time.Now(),
+	})
+}
+
+// GenerateSignals handles POST /api/trading/signals
+func (h *TradingHandler) GenerateSignals(c *gin.Context) {
+	var request struct {
+		Symbol     string   `json:"symbol" binding:"required"`
+		Algorithms []string `json:"algorithms"`
+		TimeFrame  string   `json:"time_frame"`
+		Period     int      `json:"period"`
+		Parameters map[string]map[string]interface{} `json:"parameters"` // Per-algorithm overrides for this request
+		RegimeRouting bool `json:"regime_routing"` // Keep only algorithms suited to the current market regime
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid request format",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Set defaults
+	if request.TimeFrame == "" {
+		request.TimeFrame = "1D"
+	}
+	if request.Period == 0 {
+		request.Period = 50
+	}
+	if len(request.Algorithms) == 0 && !request.RegimeRouting {
+		request.Algorithms = []string{"momentum", "mean_reversion", "trend_following"}
+	}
+
+	// Get historical data; intraday time frames (e.g. opening range
+	// breakout) use the provider's intraday bars instead of daily history
+	from := time.Now().AddDate(0, 0, -request.Period)
+	to := time.Now()
+
+	var historicalData []models.HistoricalData
+	var err error
+	if isIntradayTimeFrame(request.TimeFrame) {
+		historicalData, err = h.marketDataService.GetIntradayData(request.Symbol, request.TimeFrame)
+	} else {
+		historicalData, err = h.marketDataService.GetHistoricalData(request.Symbol, from, to)
+	}
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", request.Symbol).Error("Failed to get data for signal generation")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to fetch data for signal generation",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Route to the algorithms suited to the current regime. Overrides for
+	// algorithms routed out are dropped with them.
+	var regime *indicators.RegimeAnalysis
+	if request.RegimeRouting {
+		var routed []string
+		routed, regime, err = h.analysisService.RouteByRegime(historicalData, request.Algorithms)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, models.APIResponse{
+				Success:   false,
+				Message:   "Regime routing failed",
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		kept := make(map[string]map[string]interface{})
+		for _, name := range routed {
+			if params, ok := request.Parameters[name]; ok {
+				kept[name] = params
+			}
+		}
+		request.Algorithms, request.Parameters = routed, kept
+	}
+
+	// Generate signals using specified algorithms
+	signals, err := h.analysisService.GenerateSignals(request.Symbol, historicalData, request.Algorithms, request.Parameters)
+	var paramErr *algorithms.ParameterError
+	if errors.As(err, &paramErr) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid algorithm parameters",
+			Error:     err.Error(),
+			Data:      paramErr,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", request.Symbol).Error("Signal generation failed")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Signal generation failed",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	message := "Signals generated successfully"
+	if regime != nil {
+		message = fmt.Sprintf("Signals generated for %s regime", regime.Current)
+		for _, signal := range signals {
+			if signal.Indicators == nil {
+				signal.Indicators = make(map[string]interface{})
+			}
+			signal.Indicators["market_regime"] = regime.Current
+			signal.Indicators["market_regime_since"] = regime.Since
+		}
+	}
+
+	// Store the signals so their outcomes can be tracked; this assigns IDs
+	h.signalHistory.Record(signals)
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   message,
+		Data:      signals,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetSignalHistory handles GET /api/trading/signals/history
+func (h *TradingHandler) GetSignalHistory(c *gin.Context) {
+	filter, err := signalFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid filter",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 100
+	}
+
+	records := h.signalHistory.History(filter)
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Signal history retrieved successfully",
+		Data:      records,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetSignalRecord handles GET /api/trading/signals/history/:id
+func (h *TradingHandler) GetSignalRecord(c *gin.Context) {
+	record, err := h.signalHistory.GetRecord(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Message:   "Signal not found",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Signal retrieved successfully",
+		Data:      record,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetSignalStats handles GET /api/trading/signals/stats
+func (h *TradingHandler) GetSignalStats(c *gin.Context) {
+	filter, err := signalFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid filter",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Signal statistics calculated successfully",
+		Data:      h.signalHistory.Stats(filter),
+		Timestamp: time.Now(),
+	})
+}
+
+// signalFilterFromQuery reads symbol, algorithm, outcome, since (RFC 3339
+// or YYYY-MM-DD) and limit query parameters
+func signalFilterFromQuery(c *gin.Context) (services.SignalFilter, error) {
+	filter := services.SignalFilter{
+		Symbol:    strings.ToUpper(c.Query("symbol")),
+		Algorithm: c.Query("algorithm"),
+		Outcome:   strings.ToUpper(c.Query("outcome")),
+	}
+
+	if since := c.Query("since"); since != "" {
+		parsed, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			parsed, err = time.Parse("2006-01-02", since)
+		}
+		if err != nil {
+			return filter, fmt.Errorf("since must be RFC 3339 or YYYY-MM-DD, got %q", since)
+		}
+		filter.Since = parsed
+	}
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 {
+			return filter, fmt.Errorf("limit must be a positive integer, got %q", limit)
+		}
+		filter.Limit = parsed
+	}
+	return filter, nil
+}
+
+// ScanMarket handles POST /api/trading/scan. Symbols are evaluated in
+// parallel; those that fail are listed in the result rather than failing
+// the scan.
+func (h *TradingHandler) ScanMarket(c *gin.Context) {
+	var request services.ScanConfig
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid request format",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	result, err := h.scannerService.Scan(c.Request.Context(), request)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		c.JSON(http.StatusServiceUnavailable, models.APIResponse{
+			Success:   false,
+			Message:   "Scan cancelled",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		response := models.APIResponse{
+			Success:   false,
+			Message:   "Invalid scan request",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		}
+		var paramErr *algorithms.ParameterError
+		var exprErr *expression.Error
+		if errors.As(err, &paramErr) {
+			response.Data = paramErr
+		} else if errors.As(err, &exprErr) {
+			response.Data = exprErr
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   fmt.Sprintf("%d of %d symbols matched", result.Matched, result.Scanned),
+		Data:      result,
+		Timestamp: time.Now(),
+	})
+}
+
+// EvaluateExpression handles POST /api/trading/evaluate
+func (h *TradingHandler) EvaluateExpression(c *gin.Context) {
+	var request models.ExpressionRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid request format",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	expr, err := expression.Parse(request.Expression)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid expression",
+			Error:     err.Error(),
+			Data:      err,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Set defaults
+	if request.Period == 0 {
+		request.Period = 100
+	}
+	if request.Lookback == 0 {
+		request.Lookback = 20
+	}
+
+	from := time.Now().AddDate(0, 0, -request.Period)
+	to := time.Now()
+
+	historicalData, err := h.marketDataService.GetHistoricalData(request.Symbol, from, to)
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", request.Symbol).Error("Failed to get data for expression")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to fetch data for expression",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	result, err := h.analysisService.EvaluateExpression(request.Symbol, historicalData, expr, request.Lookback)
+	if err != nil {
+		// Expression errors (e.g. too little data for a period) are the caller's to fix
+		status := http.StatusInternalServerError
+		var exprErr *expression.Error
+		if errors.As(err, &exprErr) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, models.APIResponse{
+			Success:   false,
+			Message:   "Expression evaluation failed",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Expression evaluated successfully",
+		Data:      result,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetExpressionFunctions handles GET /api/trading/evaluate/functions
+func (h *TradingHandler) GetExpressionFunctions(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success: true,
+		Message: "Expression functions retrieved successfully",
+		Data: map[string]interface{}{
+			"fields":    []string{"open", "high", "low", "close", "volume"},
+			"operators": []string{"+", "-", "*", "/", "<", "<=", ">", ">=", "==", "!=", "and", "or", "not"},
+			"functions": expression.Functions(),
+		},
+		Timestamp: time.Now(),
+	})
+}
+
+// AnalyzePair handles POST /api/trading/pairs. The first symbol is the
+// traded leg and the second its hedge.
+func (h *TradingHandler) AnalyzePair(c *gin.Context) {
+	var request struct {
+		Symbols    []string               `json:"symbols" binding:"required"`
+		Period     int                    `json:"period"` // History in days
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid request format",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if len(request.Symbols) != 2 || request.Symbols[0] == request.Symbols[1] {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Two different symbols are required",
+			Error:     "symbols must contain exactly 2 distinct symbols",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Set defaults
+	if request.Period == 0 {
+		request.Period = 400
+	}
+
+	from := time.Now().AddDate(0, 0, -request.Period)
+	to := time.Now()
+
+	series := make([][]models.HistoricalData, len(request.Symbols))
+	for i, symbol := range request.Symbols {
+		historicalData, err := h.marketDataService.GetHistoricalData(symbol, from, to)
+		if err != nil {
+			h.logger.WithError(err).WithField("symbol", symbol).Error("Failed to get data for pair analysis")
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Message:   "Failed to fetch data for pair analysis",
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		series[i] = historicalData
+	}
+
+	analysis, err := h.analysisService.AnalyzePair(series[0], series[1], request.Parameters)
+	var paramErr *algorithms.ParameterError
+	if errors.As(err, &paramErr) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid algorithm parameters",
+			Error:     err.Error(),
+			Data:      paramErr,
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		// Too little overlap or degenerate series; nothing the server can retry
+		c.JSON(http.StatusUnprocessableEntity, models.APIResponse{
+			Success:   false,
+			Message:   "Pair analysis failed",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Pair analyzed successfully",
+		Data:      analysis,
+		Timestamp: time.Now(),
+	})
+}
+
+// SubmitOptimization handles POST /api/trading/optimize. The search runs in
+// the background; poll GET /api/trading/optimize/{jobId} for the result.
+func (h *TradingHandler) SubmitOptimization(c *gin.Context) {
+	var request struct {
+		Symbol    string `json:"symbol" binding:"required"`
+		Algorithm string `json:"algorithm" binding:"required"`
+		Period    int    `json:"period"` // History in days
+		backtest.OptimizeConfig
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid request format",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Set defaults
+	if request.Period == 0 {
+		request.Period = 730
+	}
+
+	from := time.Now().AddDate(0, 0, -request.Period)
+	to := time.Now()
+
+	historicalData, err := h.marketDataService.GetHistoricalData(request.Symbol, from, to)
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", request.Symbol).Error("Failed to get data for optimization")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to fetch data for optimization",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	job, err := h.optimizationService.Submit(request.Symbol, historicalData, request.Algorithm, request.OptimizeConfig)
+	if errors.Is(err, services.ErrTooManyJobs) {
+		c.JSON(http.StatusTooManyRequests, models.APIResponse{
+			Success:   false,
+			Message:   "Optimization capacity reached",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid optimization request",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.APIResponse{
+		Success:   true,
+		Message:   "Optimization job submitted",
+		Data:      job,
+		Timestamp: time.Now(),
+	})
+}
+
+// ListOptimizations handles GET /api/trading/optimize
+func (h *TradingHandler) ListOptimizations(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Optimization jobs retrieved successfully",
+		Data:      h.optimizationService.ListJobs(),
+		Timestamp: time.Now(),
+	})
+}
+
+// GetOptimization handles GET /api/trading/optimize/{jobId}
+func (h *TradingHandler) GetOptimization(c *gin.Context) {
+	job, err := h.optimizationService.GetJob(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Message:   "Optimization job not found",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Optimization job retrieved successfully",
+		Data:      job,
+		Timestamp: time.Now(),
+	})
+}
+
+// CancelOptimization handles DELETE /api/trading/optimize/{jobId}
+func (h *TradingHandler) CancelOptimization(c *gin.Context) {
+	jobID := c.Param("jobId")
+	if _, err := h.optimizationService.GetJob(jobID); err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Message:   "Optimization job not found",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	job, err := h.optimizationService.CancelJob(jobID)
+	if err != nil {
+		c.JSON(http.StatusConflict, models.APIResponse{
+			Success:   false,
+			Message:   "Optimization job not cancelled",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Optimization job cancelled",
+		Data:      job,
+		Timestamp: time.Now(),
+	})
+}
+
+// TrainModel handles POST /api/trading/ml/train. Training runs on the
+// request goroutine, one at a time; the new model replaces the one served
+// as ml_model.
+func (h *TradingHandler) TrainModel(c *gin.Context) {
+	var request services.TrainRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid request format",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid training request",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	from := time.Now().AddDate(0, 0, -request.Period)
+	to := time.Now()
+
+	series := make([][]models.HistoricalData, 0, len(request.Symbols))
+	for _, symbol := range request.Symbols {
+		historicalData, err := h.marketDataService.GetHistoricalData(symbol, from, to)
+		if err != nil {
+			h.logger.WithError(err).WithField("symbol", symbol).Error("Failed to get data for model training")
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Message:   "Failed to fetch data for model training",
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		series = append(series, historicalData)
+	}
+
+	model, err := h.analysisService.TrainModel(series, request.TrainConfig)
+	if errors.Is(err, services.ErrTrainingInProgress) {
+		c.JSON(http.StatusTooManyRequests, models.APIResponse{
+			Success:   false,
+			Message:   "Model training busy",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		h.logger.WithError(err).Warn("Model training failed")
+		c.JSON(http.StatusUnprocessableEntity, models.APIResponse{
+			Success:   false,
+			Message:   "Model training failed",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Model trained successfully",
+		Data:      model,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetModel handles GET /api/trading/ml/model
+func (h *TradingHandler) GetModel(c *gin.Context) {
+	model := h.analysisService.CurrentModel()
+	if model == nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Message:   "No ML model trained",
+			Error:     "train a model with POST /api/trading/ml/train",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "ML model retrieved successfully",
+		Data:      model,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetPortfolio handles GET /api/trading/portfolio/{portfolioId}
+func (h *TradingHandler) GetPortfolio(c *gin.Context) {
+	portfolioID := c.Param("portfolioId")
+	if portfolioID == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Portfolio ID is required",
+			Error:     "missing portfolio ID parameter",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	portfolio, err := h.portfolioService.GetPortfolioWithBeta(portfolioID)
+	if err != nil {
+		h.logger.WithError(err).WithField("portfolio_id", portfolioID).Error("Failed to get portfolio")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to fetch portfolio",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Portfolio retrieved successfully",
+		Data:      portfolio,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetPortfolioValuation handles GET /api/trading/portfolio/{portfolioId}/valuation.
+// WebSocket clients get the same valuation pushed on the portfolio channel.
+func (h *TradingHandler) GetPortfolioValuation(c *gin.Context) {
+	portfolioID := c.Param("portfolioId")
+
+	valuation, err := h.portfolioService.ValuePortfolio(portfolioID)
+	if err != nil {
+		h.logger.WithError(err).WithField("portfolio_id", portfolioID).Error("Failed to value portfolio")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to value portfolio",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Portfolio valuation retrieved successfully",
+		Data:      valuation,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetPortfolioPerformance handles GET /api/trading/portfolio/{portfolioId}/performance
+func (h *TradingHandler) GetPortfolioPerformance(c *gin.Context) {
+	portfolioID := c.Param("portfolioId")
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil {
+		days = 30
+	}
+
+	performance, err := h.portfolioService.GetPortfolioPerformance(portfolioID, days)
+	if err != nil {
+		h.logger.WithError(err).WithField("portfolio_id", portfolioID).Error("Failed to get portfolio performance")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to fetch portfolio performance",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Portfolio performance retrieved successfully",
+		Data:      performance,
+		Timestamp: time.Now(),
+	})
+}
+
+// ProjectPortfolio handles POST /api/trading/portfolio/{portfolioId}/projection.
+// An empty body projects one year with bootstrapped returns.
+func (h *TradingHandler) ProjectPortfolio(c *gin.Context) {
+	portfolioID := c.Param("portfolioId")
+
+	var req services.ProjectionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Message:   "Invalid request format",
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid projection request",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	projection, err := h.portfolioService.ProjectPortfolio(portfolioID, req)
+	if err != nil {
+		h.logger.WithError(err).WithField("portfolio_id", portfolioID).Error("Portfolio projection failed")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Portfolio projection failed",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Portfolio projection completed successfully",
+		Data:      projection,
+		Timestamp: time.Now(),
+	})
+}
+
+// RecordTransaction handles POST /api/trading/portfolio/{portfolioId}/transactions.
+// Entries are never edited; a mistake is corrected by a later entry.
+func (h *TradingHandler) RecordTransaction(c *gin.Context) {
+	portfolioID := c.Param("portfolioId")
+
+	var tx models.Transaction
+	if err := c.ShouldBindJSON(&tx); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid request format",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	recorded, err := h.ledgerService.Record(portfolioID, tx)
+	if errors.Is(err, services.ErrLedgerWrite) {
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to record transaction",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Transaction rejected",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success:   true,
+		Message:   "Transaction recorded successfully",
+		Data:      recorded,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetTransactions handles GET /api/trading/portfolio/{portfolioId}/transactions
+func (h *TradingHandler) GetTransactions(c *gin.Context) {
+	portfolioID := c.Param("portfolioId")
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Transactions retrieved successfully",
+		Data:      h.ledgerService.Transactions(portfolioID),
+		Timestamp: time.Now(),
+	})
+}
+
+// GetLedgerStatement handles GET /api/trading/portfolio/{portfolioId}/ledger.
+// The method query parameter (fifo, lifo, average or specific) overrides the
+// configured cost basis method.
+func (h *TradingHandler) GetLedgerStatement(c *gin.Context) {
+	portfolioID := c.Param("portfolioId")
+	method := strings.ToLower(c.DefaultQuery("method", h.portfolioService.CostBasisMethod()))
+	if !ledger.ValidMethod(method) {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid cost basis method",
+			Error:     fmt.Sprintf("unknown cost basis method %q", method),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	statement, err := h.ledgerService.Statement(portfolioID, method)
+	if err != nil {
+		h.logger.WithError(err).WithField("portfolio_id", portfolioID).Error("Failed to build ledger statement")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to build ledger statement",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Ledger statement retrieved successfully",
+		Data:      statement,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetRiskAssessment handles GET /api/trading/risk/{symbol}
+func (h *TradingHandler) GetRiskAssessment(c *gin.Context) {
+	symbol := c.Param("symbol")
+	if symbol == "" {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Symbol is required",
+			Error:     "missing symbol parameter",
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	period := c.DefaultQuery("period", "252") // Default to 1 year
+	periodInt, err := strconv.Atoi(period)
+	if err != nil {
+		periodInt = 252
+	}
+
+	riskConfig, err := h.riskConfigFromQuery(c)
+	if err == nil {
+		err = riskConfig.Validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid risk parameters",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	benchmark := c.Query("benchmark")
+
+	// Get historical data for risk calculation
+	from := time.Now().AddDate(0, 0, -periodInt)
+	to := time.Now()
+
+	historicalData, err := h.marketDataService.GetHistoricalData(symbol, from, to)
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", symbol).Error("Failed to get data for risk assessment")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to fetch data for risk assessment",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if benchmark != "" {
+		resolved := h.benchmarkService.Resolve(benchmark)
+		riskConfig.BenchmarkID = resolved.ID
+		riskConfig.Benchmark, err = h.benchmarkService.HistoricalData(resolved, from, to)
+		if err != nil {
+			h.logger.WithError(err).WithField("benchmark", benchmark).Error("Failed to get benchmark data for risk assessment")
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Message:   "Failed to fetch benchmark data for risk assessment",
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
+
+	// Calculate risk metrics
+	riskMetrics, err := h.analysisService.CalculateRiskMetrics(symbol, historicalData, riskConfig)
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", symbol).Error("Risk calculation failed")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Risk calculation failed",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Risk assessment completed successfully",
+		Data:      riskMetrics,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetVolatilityForecast handles GET /api/trading/volatility/{symbol}. Query
+// parameters: model (garch, ewma or historical), lambda (EWMA decay, fitted
+// when omitted), horizons (comma-separated bars) and period (days of
+// history, default 1095).
+func (h *TradingHandler) GetVolatilityForecast(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+
+	periodInt, err := strconv.Atoi(c.DefaultQuery("period", "1095"))
+	if err != nil || periodInt <= 0 {
+		periodInt = 1095
+	}
+
+	cfg, err := volatilityConfigFromQuery(c)
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid volatility parameters",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	historicalData, err := h.marketDataService.GetHistoricalData(symbol, time.Now().AddDate(0, 0, -periodInt), time.Now())
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", symbol).Error("Failed to get data for volatility forecast")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to fetch data for volatility forecast",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	report, err := h.analysisService.ForecastVolatility(symbol, historicalData, cfg)
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", symbol).Error("Volatility forecast failed")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Volatility forecast failed",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Volatility forecast calculated successfully",
+		Data:      report,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetRollingAnalytics handles GET /api/trading/rolling/{symbol}. Query
+// parameters: window (bars, default 63), period (days of history, default
+// 756), benchmark and risk_free_rate.
+func (h *TradingHandler) GetRollingAnalytics(c *gin.Context) {
+	symbol := strings.ToUpper(c.Param("symbol"))
+
+	rollingConfig, err := h.rollingConfigFromQuery(c)
+	if err == nil {
+		err = rollingConfig.Validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid rolling parameters",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	from := time.Now().AddDate(0, 0, -rollingConfig.Period)
+	to := time.Now()
+
+	historicalData, err := h.marketDataService.GetHistoricalData(symbol, from, to)
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", symbol).Error("Failed to get data for rolling analytics")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to fetch data for rolling analytics",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if benchmark := c.Query("benchmark"); benchmark != "" {
+		resolved := h.benchmarkService.Resolve(benchmark)
+		rollingConfig.BenchmarkID = resolved.ID
+		rollingConfig.Benchmark, err = h.benchmarkService.HistoricalData(resolved, from, to)
+		if err != nil {
+			h.logger.WithError(err).WithField("benchmark", resolved.ID).Error("Failed to get benchmark data for rolling analytics")
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Message:   "Failed to fetch benchmark data for rolling analytics",
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
+
+	analytics, err := h.analysisService.RollingAnalytics(symbol, historicalData, rollingConfig)
+	if err != nil {
+		h.logger.WithError(err).WithField("symbol", symbol).Error("Rolling analytics failed")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Rolling analytics failed",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Rolling analytics calculated successfully",
+		Data:      analytics,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetPortfolioRollingAnalytics handles
+// GET /api/trading/portfolio/{portfolioId}/rolling with the same query
+// parameters as GetRollingAnalytics
+func (h *TradingHandler) GetPortfolioRollingAnalytics(c *gin.Context) {
+	portfolioID := c.Param("portfolioId")
+
+	rollingConfig, err := h.rollingConfigFromQuery(c)
+	if err == nil {
+		err = rollingConfig.Validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid rolling parameters",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	valuation, err := h.portfolioService.ValuePortfolio(portfolioID)
+	if err != nil {
+		h.logger.WithError(err).WithField("portfolio_id", portfolioID).Error("Failed to value portfolio")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to fetch portfolio",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	portfolio, positions := valuation.Portfolio, valuation.Positions
+
+	from := time.Now().AddDate(0, 0, -rollingConfig.Period)
+	to := time.Now()
+
+	histories := make(map[string][]models.HistoricalData, len(positions))
+	for _, position := range positions {
+		data, err := h.marketDataService.GetHistoricalData(position.Symbol, from, to)
+		if err != nil {
+			h.logger.WithError(err).WithField("symbol", position.Symbol).Error("Failed to get data for portfolio rolling analytics")
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Message:   "Failed to fetch data for portfolio rolling analytics",
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		histories[position.Symbol] = data
+	}
+
+	if benchmark := c.Query("benchmark"); benchmark != "" {
+		resolved := h.benchmarkService.Resolve(benchmark)
+		rollingConfig.BenchmarkID = resolved.ID
+		rollingConfig.Benchmark, err = h.benchmarkService.HistoricalData(resolved, from, to)
+		if err != nil {
+			h.logger.WithError(err).WithField("benchmark", resolved.ID).Error("Failed to get benchmark data for portfolio rolling analytics")
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Message:   "Failed to fetch benchmark data for portfolio rolling analytics",
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
+
+	analytics, err := h.analysisService.PortfolioRollingAnalytics(portfolio, positions, histories, rollingConfig)
+	if err != nil {
+		h.logger.WithError(err).WithField("portfolio_id", portfolioID).Error("Portfolio rolling analytics failed")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Portfolio rolling analytics failed",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Portfolio rolling analytics calculated successfully",
+		Data:      analytics,
+		Timestamp: time.Now(),
+	})
+}
+
+// GetPortfolioRisk handles GET /api/trading/portfolio/{portfolioId}/risk
+func (h *TradingHandler) GetPortfolioRisk(c *gin.Context) {
+	portfolioID := c.Param("portfolioId")
+
+	periodInt, err := strconv.Atoi(c.DefaultQuery("period", "252"))
+	if err != nil || periodInt <= 0 {
+		periodInt = 252
+	}
+
+	riskConfig, err := h.riskConfigFromQuery(c)
+	if err == nil {
+		err = riskConfig.Validate()
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid risk parameters",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	// Cash and positions come from the same batch of quotes
+	valuation, err := h.portfolioService.ValuePortfolio(portfolioID)
+	if err != nil {
+		h.logger.WithError(err).WithField("portfolio_id", portfolioID).Error("Failed to value portfolio")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
+			Success:   false,
+			Message:   "Failed to fetch portfolio",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+	portfolio, positions := valuation.Portfolio, valuation.Positions
+
+	from := time.Now().AddDate(0, 0, -periodInt)
+	to := time.Now()
+
+	histories := make(map[string][]models.HistoricalData, len(positions))
+	for _, position := range positions {
+		data, err := h.marketDataService.GetHistoricalData(position.Symbol, from, to)
+		if err != nil {
+			h.logger.WithError(err).WithField("symbol", position.Symbol).Error("Failed to get data for portfolio risk")
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Message:   "Failed to fetch data for portfolio risk",
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+		histories[position.Symbol] = data
 	}
 
-	// Get historical data for analysis
-	from := time.Now().AddDate(0, 0, -request.Period)
-	to := time.Now()
+	if benchmark := c.Query("benchmark"); benchmark != "" {
+		resolved := h.benchmarkService.Resolve(benchmark)
+		riskConfig.BenchmarkID = resolved.ID
+		riskConfig.Benchmark, err = h.benchmarkService.HistoricalData(resolved, from, to)
+		if err != nil {
+			h.logger.WithError(err).WithField("benchmark", resolved.ID).Error("Failed to get benchmark data for portfolio risk")
+			c.JSON(http.StatusInternalServerError, models.APIResponse{
+				Success:   false,
+				Message:   "Failed to fetch benchmark data for portfolio risk",
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
 
-	historicalData, err := h.marketDataService.GetHistoricalData(request.Symbol, from, to)
+	riskMetrics, err := h.analysisService.CalculatePortfolioRisk(portfolio, positions, histories, riskConfig)
 	if err != nil {
-		h.logger.WithError(err).WithField("symbol", request.Symbol).Error("Failed to get data for analysis")
+		h.logger.WithError(err).WithField("portfolio_id", portfolioID).Error("Portfolio risk calculation failed")
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
-			Message:   "Failed to fetch data for analysis",
+			Message:   "Portfolio risk calculation failed",
 			Error:     err.Error(),
 			Timestamp: time.Now(),
 		})
 		return
 	}
 
-	if len(historicalData) < 20 {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Portfolio risk calculated successfully",
+		Data:      riskMetrics,
+		Timestamp: time.Now(),
+	})
+}
+
+// StressTestPortfolio handles POST /api/trading/portfolio/{portfolioId}/stress.
+// An empty body runs every built-in historical scenario.
+func (h *TradingHandler) StressTestPortfolio(c *gin.Context) {
+	portfolioID := c.Param("portfolioId")
+
+	var req services.StressTestRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.APIResponse{
+				Success:   false,
+				Message:   "Invalid request format",
+				Error:     err.Error(),
+				Timestamp: time.Now(),
+			})
+			return
+		}
+	}
+
+	valuation, err := h.portfolioService.ValuePortfolio(portfolioID)
+	if err != nil {
+		h.logger.WithError(err).WithField("portfolio_id", portfolioID).Error("Failed to value portfolio")
+		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
-			Message:   "Insufficient data for analysis",
-			Error:     "need at least 20 data points",
+			Message:   "Failed to fetch portfolio",
+			Error:     err.Error(),
 			Timestamp: time.Now(),
 		})
 		return
 	}
+	portfolio, positions := valuation.Portfolio, valuation.Positions
 
-	// Perform technical analysis
-	analysisResult, err := h.analysisService.PerformTechnicalAnalysis(request.Symbol, historicalData, request.Indicators)
+	report, err := h.stressTestService.Run(portfolio, positions, req)
 	if err != nil {
-		h.logger.WithError(err).WithField("symbol", request.Symbol).Error("Technical analysis failed")
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success:   false,
-			Message:   "Technical analysis failed",
+			Message:   "Invalid stress test",
 			Error:     err.Error(),
 			Timestamp: time.Now(),
 		})
@@ -231,22 +1605,26 @@ func (h *TradingHandler) AnalyzeStock(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
-		Message:   "Analysis completed successfully",
-		Data:      analysisResult,
+		Message:   "Stress test completed successfully",
+		Data:      report,
 		Timestamp: time.Now(),
 	})
 }
 
-// GenerateSignals handles POST /api/trading/signals
-func (h *TradingHandler) GenerateSignals(c *gin.Context) {
-	var request struct {
-		Symbol     string   `json:"symbol" binding:"required"`
-		Algorithms []string `json:"algorithms"`
-		TimeFrame  string   `json:"time_frame"`
-		Period     int      `json:"period"`
-	}
+// GetStressScenarios handles GET /api/trading/stress/scenarios
+func (h *TradingHandler) GetStressScenarios(c *gin.Context) {
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Stress scenarios retrieved successfully",
+		Data:      h.stressTestService.Scenarios(),
+		Timestamp: time.Now(),
+	})
+}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
+// GetCorrelations handles POST /api/trading/correlation
+func (h *TradingHandler) GetCorrelations(c *gin.Context) {
+	var req services.CorrelationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success:   false,
 			Message:   "Invalid request format",
@@ -255,41 +1633,22 @@ func (h *TradingHandler) GenerateSignals(c *gin.Context) {
 		})
 		return
 	}
-
-	// Set defaults
-	if request.TimeFrame == "" {
-		request.TimeFrame = "1D"
-	}
-	if request.Period == 0 {
-		request.Period = 50
-	}
-	if len(request.Algorithms) == 0 {
-		request.Algorithms = []string{"momentum", "mean_reversion", "trend_following"}
-	}
-
-	// Get historical data
-	from := time.Now().AddDate(0, 0, -request.Period)
-	to := time.Now()
-
-	historicalData, err := h.marketDataService.GetHistoricalData(request.Symbol, from, to)
-	if err != nil {
-		h.logger.WithError(err).WithField("symbol", request.Symbol).Error("Failed to get data for signal generation")
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success:   false,
-			Message:   "Failed to fetch data for signal generation",
+			Message:   "Invalid correlation request",
 			Error:     err.Error(),
 			Timestamp: time.Now(),
 		})
 		return
 	}
 
-	// Generate signals using specified algorithms
-	signals, err := h.analysisService.GenerateSignals(request.Symbol, historicalData, request.Algorithms)
+	result, err := h.correlationService.Correlate(req)
 	if err != nil {
-		h.logger.WithError(err).WithField("symbol", request.Symbol).Error("Signal generation failed")
+		h.logger.WithError(err).WithField("symbols", req.Symbols).Error("Correlation calculation failed")
 		c.JSON(http.StatusInternalServerError, models.APIResponse{
 			Success:   false,
-			Message:   "Signal generation failed",
+			Message:   "Correlation calculation failed",
 			Error:     err.Error(),
 			Timestamp: time.Now(),
 		})
@@ -298,87 +1657,166 @@ func (h *TradingHandler) GenerateSignals(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
-		Message:   "Signals generated successfully",
-		Data:      signals,
+		Message:   "Correlations calculated successfully",
+		Data:      result,
 		Timestamp: time.Now(),
 	})
 }
 
-// GetPortfolio handles GET /api/trading/portfolio/{portfolioId}
-func (h *TradingHandler) GetPortfolio(c *gin.Context) {
-	portfolioID := c.Param("portfolioId")
-	if portfolioID == "" {
-		c.JSON(http.StatusBadRequest, models.APIResponse{
-			Success:   false,
-			Message:   "Portfolio ID is required",
-			Error:     "missing portfolio ID parameter",
-			Timestamp: time.Now(),
-		})
-		return
+// riskConfigFromQuery reads var_method, var_horizon, confidence, simulations,
+// risk_free_rate, shrinkage and volatility_model over the service defaults
+func (h *TradingHandler) riskConfigFromQuery(c *gin.Context) (services.RiskConfig, error) {
+	cfg := h.analysisService.DefaultRiskConfig()
+	if method := c.Query("var_method"); method != "" {
+		cfg.VaR.Method = method
+	}
+	cfg.VolatilityModel = c.Query("volatility_model")
+	if shrinkage := c.Query("shrinkage"); shrinkage != "" && shrinkage != "auto" {
+		parsed, err := strconv.ParseFloat(shrinkage, 64)
+		if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+			return cfg, fmt.Errorf("shrinkage must be a number or auto")
+		}
+		cfg.Shrinkage = parsed
 	}
 
-	portfolio, err := h.portfolioService.GetPortfolio(portfolioID)
-	if err != nil {
-		h.logger.WithError(err).WithField("portfolio_id", portfolioID).Error("Failed to get portfolio")
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
-			Success:   false,
-			Message:   "Failed to fetch portfolio",
-			Error:     err.Error(),
-			Timestamp: time.Now(),
-		})
-		return
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{{"var_horizon", &cfg.VaR.Horizon}, {"simulations", &cfg.VaR.Simulations}} {
+		if value := c.Query(param.name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return cfg, fmt.Errorf("%s must be a positive integer", param.name)
+			}
+			*param.target = parsed
+		}
+	}
+
+	for _, param := range []struct {
+		name   string
+		target *float64
+	}{{"confidence", &cfg.VaR.Confidence}, {"risk_free_rate", &cfg.RiskFreeRate}} {
+		if value := c.Query(param.name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+				return cfg, fmt.Errorf("%s must be a finite number", param.name)
+			}
+			*param.target = parsed
+		}
+	}
+	return cfg, nil
+}
+
+// rollingConfigFromQuery reads window, period and risk_free_rate over the
+// service defaults
+func (h *TradingHandler) rollingConfigFromQuery(c *gin.Context) (services.RollingConfig, error) {
+	cfg := h.analysisService.DefaultRollingConfig()
+	if window := c.Query("window"); window != "" {
+		parsed, err := strconv.Atoi(window)
+		if err != nil {
+			return cfg, fmt.Errorf("window must be an integer")
+		}
+		cfg.Window = parsed
+	}
+	if period := c.Query("period"); period != "" {
+		parsed, err := strconv.Atoi(period)
+		if err != nil {
+			return cfg, fmt.Errorf("period must be an integer")
+		}
+		cfg.Period = parsed
+	}
+	if rate := c.Query("risk_free_rate"); rate != "" {
+		parsed, err := strconv.ParseFloat(rate, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("risk_free_rate must be a number")
+		}
+		cfg.RiskFreeRate = parsed
+	}
+	return cfg, nil
+}
+
+// volatilityConfigFromQuery reads model, lambda and horizons
+func volatilityConfigFromQuery(c *gin.Context) (risk.VolatilityConfig, error) {
+	cfg := risk.VolatilityConfig{Model: c.Query("model")}
+	if lambda := c.Query("lambda"); lambda != "" {
+		parsed, err := strconv.ParseFloat(lambda, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("lambda must be a number")
+		}
+		cfg.Lambda = parsed
+	}
+	if horizons := c.Query("horizons"); horizons != "" {
+		for _, horizon := range strings.Split(horizons, ",") {
+			parsed, err := strconv.Atoi(strings.TrimSpace(horizon))
+			if err != nil {
+				return cfg, fmt.Errorf("horizons must be comma-separated integers")
+			}
+			cfg.Horizons = append(cfg.Horizons, parsed)
+		}
 	}
+	return cfg, nil
+}
 
+// GetBenchmarks handles GET /api/trading/benchmarks
+func (h *TradingHandler) GetBenchmarks(c *gin.Context) {
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
-		Message:   "Portfolio retrieved successfully",
-		Data:      portfolio,
+		Message:   "Benchmarks retrieved successfully",
+		Data:      h.benchmarkService.List(),
 		Timestamp: time.Now(),
 	})
 }
 
-// GetRiskAssessment handles GET /api/trading/risk/{symbol}
-func (h *TradingHandler) GetRiskAssessment(c *gin.Context) {
-	symbol := c.Param("symbol")
-	if symbol == "" {
+// CreateBenchmark handles POST /api/trading/benchmarks. A custom benchmark
+// with the same id is replaced.
+func (h *TradingHandler) CreateBenchmark(c *gin.Context) {
+	var req services.Benchmark
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success:   false,
-			Message:   "Symbol is required",
-			Error:     "missing symbol parameter",
+			Message:   "Invalid request format",
+			Error:     err.Error(),
 			Timestamp: time.Now(),
 		})
 		return
 	}
 
-	period := c.DefaultQuery("period", "252") // Default to 1 year
-	periodInt, err := strconv.Atoi(period)
+	benchmark, err := h.benchmarkService.Register(req)
 	if err != nil {
-		periodInt = 252
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid benchmark",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
 	}
 
-	// Get historical data for risk calculation
-	from := time.Now().AddDate(0, 0, -periodInt)
-	to := time.Now()
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success:   true,
+		Message:   "Benchmark registered successfully",
+		Data:      benchmark,
+		Timestamp: time.Now(),
+	})
+}
 
-	historicalData, err := h.marketDataService.GetHistoricalData(symbol, from, to)
-	if err != nil {
-		h.logger.WithError(err).WithField("symbol", symbol).Error("Failed to get data for risk assessment")
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+// DeleteBenchmark handles DELETE /api/trading/benchmarks/{id}
+func (h *TradingHandler) DeleteBenchmark(c *gin.Context) {
+	id := c.Param("id")
+	if _, ok := h.benchmarkService.Get(id); !ok {
+		c.JSON(http.StatusNotFound, models.APIResponse{
 			Success:   false,
-			Message:   "Failed to fetch data for risk assessment",
-			Error:     err.Error(),
+			Message:   "Benchmark not found",
+			Error:     fmt.Sprintf("benchmark %s not found", strings.ToUpper(id)),
 			Timestamp: time.Now(),
 		})
 		return
 	}
 
-	// Calculate risk metrics
-	riskMetrics, err := h.analysisService.CalculateRiskMetrics(symbol, historicalData)
-	if err != nil {
-		h.logger.WithError(err).WithField("symbol", symbol).Error("Risk calculation failed")
-		c.JSON(http.StatusInternalServerError, models.APIResponse{
+	if err := h.benchmarkService.Remove(id); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
 			Success:   false,
-			Message:   "Risk calculation failed",
+			Message:   "Benchmark not removed",
 			Error:     err.Error(),
 			Timestamp: time.Now(),
 		})
@@ -387,8 +1825,7 @@ func (h *TradingHandler) GetRiskAssessment(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
-		Message:   "Risk assessment completed successfully",
-		Data:      riskMetrics,
+		Message:   "Benchmark removed successfully",
 		Timestamp: time.Now(),
 	})
 }
@@ -413,10 +1850,12 @@ func (h *TradingHandler) HandleWebSocket(c *gin.Context) {
 	}
 
 	client := &WebSocketClient{
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		hub:    h.websocketHub,
-		logger: h.logger,
+		conn:            conn,
+		send:            make(chan []byte, 256),
+		hub:             h.websocketHub,
+		subscriptions:   make(map[string]bool),
+		portfolioExists: h.portfolioService.HasPortfolio,
+		logger:          h.logger,
 	}
 
 	h.websocketHub.register <- client
@@ -470,7 +1909,7 @@ func (h *TradingHandler) GetSupportedSymbols(c *gin.Context) {
 
 // GetAlgorithms handles GET /api/trading/algorithms
 func (h *TradingHandler) GetAlgorithms(c *gin.Context) {
-	algorithmManager := algorithms.NewAlgorithmManager()
+	algorithmManager := h.analysisService.AlgorithmManager()
 	algorithmNames := algorithmManager.ListAlgorithms()
 
 	algorithmsInfo := make([]map[string]interface{}, 0)
@@ -480,15 +1919,37 @@ func (h *TradingHandler) GetAlgorithms(c *gin.Context) {
 			continue
 		}
 
+		description := getAlgorithmDescription(name)
+		if strategy, ok := algorithm.(*algorithms.RuleStrategy); ok && strategy.Definition().Description != "" {
+			description = strategy.Definition().Description
+		}
+
 		info := map[string]interface{}{
 			"name":        algorithm.Name(),
 			"id":          name,
 			"parameters":  algorithm.GetParameters(),
-			"description": getAlgorithmDescription(name),
+			"schema":      algorithm.ParameterSchema(),
+			"description": description,
 		}
 		algorithmsInfo = append(algorithmsInfo, info)
 	}
 
+	for _, name := range algorithmManager.ListMultiAssetAlgorithms() {
+		algorithm, err := algorithmManager.GetMultiAssetAlgorithm(name)
+		if err != nil {
+			continue
+		}
+
+		algorithmsInfo = append(algorithmsInfo, map[string]interface{}{
+			"name":        algorithm.Name(),
+			"id":          name,
+			"assets":      algorithm.Assets(),
+			"parameters":  algorithm.GetParameters(),
+			"schema":      algorithm.ParameterSchema(),
+			"description": getAlgorithmDescription(name),
+		})
+	}
+
 	c.JSON(http.StatusOK, models.APIResponse{
 		Success:   true,
 		Message:   "Available algorithms retrieved successfully",
@@ -497,12 +1958,136 @@ func (h *TradingHandler) GetAlgorithms(c *gin.Context) {
 	})
 }
 
+// GetStrategies handles GET /api/trading/strategies
+func (h *TradingHandler) GetStrategies(c *gin.Context) {
+	strategies := h.analysisService.AlgorithmManager().RuleStrategies()
+
+	definitions := make([]algorithms.StrategyDefinition, 0, len(strategies))
+	for _, strategy := range strategies {
+		definitions = append(definitions, strategy.Definition())
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Rule strategies retrieved successfully",
+		Data:      definitions,
+		Timestamp: time.Now(),
+	})
+}
+
+// CreateStrategy handles POST /api/trading/strategies. The body is a JSON
+// definition, or YAML when the Content-Type mentions yaml. An existing rule
+// strategy with the same id is replaced.
+func (h *TradingHandler) CreateStrategy(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Invalid request format",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	format := algorithms.FormatJSON
+	if strings.Contains(c.ContentType(), "yaml") {
+		format = algorithms.FormatYAML
+	}
+
+	definition, err := algorithms.ParseStrategyDefinition(body, format)
+	if err != nil {
+		h.invalidStrategy(c, err)
+		return
+	}
+
+	strategy, err := algorithms.NewRuleStrategy(*definition)
+	if err != nil {
+		h.invalidStrategy(c, err)
+		return
+	}
+
+	if err := h.analysisService.AlgorithmManager().RegisterRuleStrategy(strategy); err != nil {
+		h.invalidStrategy(c, err)
+		return
+	}
+
+	h.logger.WithField("strategy", strategy.ID()).Info("Rule strategy registered")
+	c.JSON(http.StatusCreated, models.APIResponse{
+		Success:   true,
+		Message:   "Strategy registered successfully",
+		Data:      strategy.Definition(),
+		Timestamp: time.Now(),
+	})
+}
+
+// invalidStrategy responds 400 with the list of definition problems if any
+func (h *TradingHandler) invalidStrategy(c *gin.Context, err error) {
+	response := models.APIResponse{
+		Success:   false,
+		Message:   "Invalid strategy definition",
+		Error:     err.Error(),
+		Timestamp: time.Now(),
+	}
+	var defErr *algorithms.DefinitionError
+	if errors.As(err, &defErr) {
+		response.Data = defErr
+	}
+	c.JSON(http.StatusBadRequest, response)
+}
+
+// DeleteStrategy handles DELETE /api/trading/strategies/{id}
+func (h *TradingHandler) DeleteStrategy(c *gin.Context) {
+	id := c.Param("id")
+	manager := h.analysisService.AlgorithmManager()
+	if _, err := manager.GetAlgorithm(id); err != nil {
+		c.JSON(http.StatusNotFound, models.APIResponse{
+			Success:   false,
+			Message:   "Strategy not found",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	if err := manager.UnregisterRuleStrategy(id); err != nil {
+		c.JSON(http.StatusBadRequest, models.APIResponse{
+			Success:   false,
+			Message:   "Strategy not removed",
+			Error:     err.Error(),
+			Timestamp: time.Now(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.APIResponse{
+		Success:   true,
+		Message:   "Strategy removed successfully",
+		Timestamp: time.Now(),
+	})
+}
+
+// isIntradayTimeFrame reports whether a time frame such as 5m or 1h is
+// shorter than a day
+func isIntradayTimeFrame(timeFrame string) bool {
+	switch strings.ToLower(timeFrame) {
+	case "1m", "5m", "15m", "30m", "60m", "1h":
+		return true
+	}
+	return false
+}
+
 func getAlgorithmDescription(algorithmID string) string {
 	descriptions := map[string]string{
-		"momentum":        "Momentum-based strategy using RSI and MACD indicators to identify trending opportunities",
-		"mean_reversion":  "Mean reversion strategy using Bollinger Bands and RSI to identify overbought/oversold conditions",
-		"trend_following": "Trend following strategy using EMA crossovers and ADX to ride strong trends",
-		"composite":       "Composite strategy that combines multiple algorithms for robust signal generation",
+		"momentum":               "Momentum-based strategy using RSI and MACD indicators to identify trending opportunities",
+		"mean_reversion":         "Mean reversion strategy using Bollinger Bands and RSI to identify overbought/oversold conditions",
+		"trend_following":        "Trend following strategy using EMA crossovers and ADX to ride strong trends",
+		"composite":              "Composite strategy that combines multiple algorithms with fixed or performance-adaptive weights",
+		"donchian_breakout":      "Turtle-style Donchian channel breakout with ATR (N) stops, unit sizing and pyramid levels",
+		"volatility_squeeze":     "Breakout on the release of a Bollinger-inside-Keltner volatility squeeze with ATR stops and targets",
+		"opening_range_breakout": "Intraday breakout of the session's opening range with ATR stops and targets",
+		"ml_model":               "Logistic regression on indicator features with calibrated probabilities; train via /ml/train",
+		"pairs_trading":          "Statistical arbitrage on two cointegrated symbols using the Engle-Granger hedge ratio and spread z-score",
 	}
 	
 	if desc, exists := descriptions[algorithmID]; exists {
@@ -522,14 +2107,50 @@ func (h *TradingHandler) SetupRoutes(router *gin.Engine) {
 		// Analysis endpoints
 		api.POST("/analyze", h.AnalyzeStock)
 		api.POST("/signals", h.GenerateSignals)
+		api.GET("/signals/history", h.GetSignalHistory)
+		api.GET("/signals/history/:id", h.GetSignalRecord)
+		api.GET("/signals/stats", h.GetSignalStats)
+		api.POST("/scan", h.ScanMarket)
+		api.POST("/evaluate", h.EvaluateExpression)
+		api.GET("/evaluate/functions", h.GetExpressionFunctions)
 		api.GET("/risk/:symbol", h.GetRiskAssessment)
+		api.GET("/volatility/:symbol", h.GetVolatilityForecast)
+		api.GET("/rolling/:symbol", h.GetRollingAnalytics)
+		api.GET("/benchmarks", h.GetBenchmarks)
+		api.POST("/benchmarks", h.CreateBenchmark)
+		api.DELETE("/benchmarks/:id", h.DeleteBenchmark)
+		api.POST("/pairs", h.AnalyzePair)
+		api.POST("/correlation", h.GetCorrelations)
+
+		// Optimization endpoints
+		api.POST("/optimize", h.SubmitOptimization)
+		api.GET("/optimize", h.ListOptimizations)
+		api.GET("/optimize/:jobId", h.GetOptimization)
+		api.DELETE("/optimize/:jobId", h.CancelOptimization)
+
+		// Machine learning endpoints
+		api.POST("/ml/train", h.TrainModel)
+		api.GET("/ml/model", h.GetModel)
 		
 		// Portfolio endpoints
 		api.GET("/portfolio/:portfolioId", h.GetPortfolio)
+		api.GET("/portfolio/:portfolioId/performance", h.GetPortfolioPerformance)
+		api.GET("/portfolio/:portfolioId/valuation", h.GetPortfolioValuation)
+		api.POST("/portfolio/:portfolioId/projection", h.ProjectPortfolio)
+		api.GET("/portfolio/:portfolioId/risk", h.GetPortfolioRisk)
+		api.GET("/portfolio/:portfolioId/rolling", h.GetPortfolioRollingAnalytics)
+		api.POST("/portfolio/:portfolioId/stress", h.StressTestPortfolio)
+		api.POST("/portfolio/:portfolioId/transactions", h.RecordTransaction)
+		api.GET("/portfolio/:portfolioId/transactions", h.GetTransactions)
+		api.GET("/portfolio/:portfolioId/ledger", h.GetLedgerStatement)
+		api.GET("/stress/scenarios", h.GetStressScenarios)
 		
 		// Utility endpoints
 		api.GET("/symbols", h.GetSupportedSymbols)
 		api.GET("/algorithms", h.GetAlgorithms)
+		api.GET("/strategies", h.GetStrategies)
+		api.POST("/strategies", h.CreateStrategy)
+		api.DELETE("/strategies/:id", h.DeleteStrategy)
 		api.GET("/health", h.HealthCheck)
 	}
 
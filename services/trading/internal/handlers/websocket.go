@@ -2,10 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 	"github.com/gorilla/websocket"
 	"github.com/sirupsen/logrus"
 	"trading-service/internal/models"
+	"trading-service/internal/services"
 )
 
 const (
@@ -20,8 +24,25 @@ const (
 
 	// Maximum message size allowed from peer.
 	maxMessageSize = 512
+
+	// Prefix of the subscription keys of portfolio channels, which share the
+	// symbol subscriptions.
+	portfolioChannelPrefix = "portfolio:"
+
+	// Most symbols and portfolios one client may subscribe to.
+	maxSubscriptions = 100
+
+	// Most portfolios one client may subscribe to. Each is revalued on
+	// every streaming tick.
+	maxPortfolioSubscriptions = 10
 )
 
+// PortfolioChannel returns the subscription key valuations of a portfolio
+// are pushed on
+func PortfolioChannel(portfolioID string) string {
+	return portfolioChannelPrefix + portfolioID
+}
+
 // WebSocketClient represents a WebSocket client connection
 type WebSocketClient struct {
 	// The WebSocket connection
@@ -33,9 +54,12 @@ type WebSocketClient struct {
 	// Reference to the hub
 	hub *WebSocketHub
 
-	// Subscribed symbols
+	// Subscribed symbols, guarded by the hub's mutex
 	subscriptions map[string]bool
 
+	// Reports whether a portfolio exists and may be subscribed to
+	portfolioExists func(portfolioID string) bool
+
 	// Client ID
 	id string
 
@@ -60,6 +84,10 @@ type WebSocketHub struct {
 	// Symbol subscriptions
 	subscriptions map[string]map[*WebSocketClient]bool
 
+	// Guards clients, subscriptions and each client's subscriptions, which
+	// client and streaming goroutines use alongside Run
+	mu sync.RWMutex
+
 	// Logger
 	logger *logrus.Logger
 }
@@ -81,8 +109,11 @@ func (hub *WebSocketHub) Run() {
 	for {
 		select {
 		case client := <-hub.register:
+			hub.mu.Lock()
 			hub.clients[client] = true
-			client.subscriptions = make(map[string]bool)
+			if client.subscriptions == nil {
+				client.subscriptions = make(map[string]bool)
+			}
 			hub.logger.WithField("client_id", client.id).Info("Client registered")
 			
 			// Send welcome message
@@ -96,60 +127,84 @@ func (hub *WebSocketHub) Run() {
 				select {
 				case client.send <- data:
 				default:
-					close(client.send)
-					delete(hub.clients, client)
+					hub.remove(client)
 				}
 			}
+			hub.mu.Unlock()
 
 		case client := <-hub.unregister:
+			hub.mu.Lock()
 			if _, ok := hub.clients[client]; ok {
-				delete(hub.clients, client)
-				close(client.send)
-				
-				// Remove from all symbol subscriptions
-				for symbol := range client.subscriptions {
-					if clients, exists := hub.subscriptions[symbol]; exists {
-						delete(clients, client)
-						if len(clients) == 0 {
-							delete(hub.subscriptions, symbol)
-						}
-					}
-				}
-				
+				hub.remove(client)
 				hub.logger.WithField("client_id", client.id).Info("Client unregistered")
 			}
+			hub.mu.Unlock()
 
 		case message := <-hub.broadcast:
 			// Broadcast message to all clients
+			hub.mu.Lock()
 			for client := range hub.clients {
 				select {
 				case client.send <- message:
 				default:
-					close(client.send)
-					delete(hub.clients, client)
+					hub.remove(client)
 				}
 			}
+			hub.mu.Unlock()
+		}
+	}
+}
+
+// remove drops a client and all its subscriptions and closes its send
+// channel. Callers hold hub.mu.
+func (hub *WebSocketHub) remove(client *WebSocketClient) {
+	delete(hub.clients, client)
+	close(client.send)
+	for symbol := range client.subscriptions {
+		if clients, exists := hub.subscriptions[symbol]; exists {
+			delete(clients, client)
+			if len(clients) == 0 {
+				delete(hub.subscriptions, symbol)
+			}
 		}
 	}
 }
 
 // BroadcastToSymbol broadcasts a message to all clients subscribed to a specific symbol
 func (hub *WebSocketHub) BroadcastToSymbol(symbol string, message []byte) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
 	if clients, exists := hub.subscriptions[symbol]; exists {
 		for client := range clients {
 			select {
 			case client.send <- message:
 			default:
-				close(client.send)
-				delete(hub.clients, client)
-				delete(clients, client)
+				hub.remove(client)
 			}
 		}
 	}
 }
 
-// SubscribeToSymbol subscribes a client to a symbol
-func (hub *WebSocketHub) SubscribeToSymbol(client *WebSocketClient, symbol string) {
+// SubscribeToSymbol subscribes a client to a symbol, failing if the client
+// was dropped or already has as many subscriptions as it may
+func (hub *WebSocketHub) SubscribeToSymbol(client *WebSocketClient, symbol string) error {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	// A client the hub dropped must not rejoin a broadcast list
+	if !hub.clients[client] {
+		return fmt.Errorf("client is not connected")
+	}
+	if !client.subscriptions[symbol] {
+		if len(client.subscriptions) >= maxSubscriptions {
+			return fmt.Errorf("at most %d subscriptions are allowed per connection", maxSubscriptions)
+		}
+		if strings.HasPrefix(symbol, portfolioChannelPrefix) && client.portfolioSubscriptions() >= maxPortfolioSubscriptions {
+			return fmt.Errorf("at most %d portfolio subscriptions are allowed per connection", maxPortfolioSubscriptions)
+		}
+	}
+
 	if _, exists := hub.subscriptions[symbol]; !exists {
 		hub.subscriptions[symbol] = make(map[*WebSocketClient]bool)
 	}
@@ -161,10 +216,14 @@ func (hub *WebSocketHub) SubscribeToSymbol(client *WebSocketClient, symbol strin
 		"client_id": client.id,
 		"symbol":    symbol,
 	}).Info("Client subscribed to symbol")
+	return nil
 }
 
 // UnsubscribeFromSymbol unsubscribes a client from a symbol
 func (hub *WebSocketHub) UnsubscribeFromSymbol(client *WebSocketClient, symbol string) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
 	if clients, exists := hub.subscriptions[symbol]; exists {
 		delete(clients, client)
 		if len(clients) == 0 {
@@ -180,6 +239,45 @@ func (hub *WebSocketHub) UnsubscribeFromSymbol(client *WebSocketClient, symbol s
 	}).Info("Client unsubscribed from symbol")
 }
 
+// Subscriptions returns the symbols and portfolio channels a client is
+// subscribed to
+func (hub *WebSocketHub) Subscriptions(client *WebSocketClient) []string {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	var symbols []string
+	for symbol := range client.subscriptions {
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+// portfolioSubscriptions counts the client's portfolio channels. Callers
+// hold hub.mu.
+func (client *WebSocketClient) portfolioSubscriptions() int {
+	count := 0
+	for symbol := range client.subscriptions {
+		if strings.HasPrefix(symbol, portfolioChannelPrefix) {
+			count++
+		}
+	}
+	return count
+}
+
+// SubscribedPortfolios returns the IDs of portfolios with subscribers
+func (hub *WebSocketHub) SubscribedPortfolios() []string {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
+	var portfolioIDs []string
+	for channel, clients := range hub.subscriptions {
+		if strings.HasPrefix(channel, portfolioChannelPrefix) && len(clients) > 0 {
+			portfolioIDs = append(portfolioIDs, strings.TrimPrefix(channel, portfolioChannelPrefix))
+		}
+	}
+	return portfolioIDs
+}
+
 // readPump pumps messages from the WebSocket connection to the hub
 func (client *WebSocketClient) readPump() {
 	defer func() {
@@ -263,7 +361,16 @@ func (client *WebSocketClient) handleMessage(msg *models.WebSocketMessage) {
 	switch msg.Type {
 	case "subscribe":
 		if symbol, ok := msg.Data.(string); ok {
-			client.hub.SubscribeToSymbol(client, symbol)
+			// Portfolio channels are only joined through subscribe_portfolio,
+			// which checks the portfolio exists
+			if strings.HasPrefix(symbol, portfolioChannelPrefix) {
+				client.sendError("Use subscribe_portfolio for portfolio channels", msg.RequestID)
+				return
+			}
+			if err := client.hub.SubscribeToSymbol(client, symbol); err != nil {
+				client.sendError(err.Error(), msg.RequestID)
+				return
+			}
 			
 			// Send subscription confirmation
 			response := models.WebSocketMessage{
@@ -291,6 +398,35 @@ func (client *WebSocketClient) handleMessage(msg *models.WebSocketMessage) {
 			client.sendMessage(response)
 		}
 
+	case "subscribe_portfolio", "unsubscribe_portfolio":
+		if portfolioID, ok := msg.Data.(string); ok && portfolioID != "" {
+			channel := PortfolioChannel(portfolioID)
+			confirmation := "subscription_confirmed"
+			if msg.Type == "subscribe_portfolio" {
+				if client.portfolioExists == nil || !client.portfolioExists(portfolioID) {
+					client.sendError("Unknown portfolio: "+portfolioID, msg.RequestID)
+					return
+				}
+				if err := client.hub.SubscribeToSymbol(client, channel); err != nil {
+					client.sendError(err.Error(), msg.RequestID)
+					return
+				}
+			} else {
+				client.hub.UnsubscribeFromSymbol(client, channel)
+				confirmation = "unsubscription_confirmed"
+			}
+
+			// Valuations follow as prices stream in
+			response := models.WebSocketMessage{
+				Type:      confirmation,
+				Symbol:    channel,
+				Data:      portfolioID,
+				Timestamp: time.Now(),
+				RequestID: msg.RequestID,
+			}
+			client.sendMessage(response)
+		}
+
 	case "ping":
 		// Send pong response
 		response := models.WebSocketMessage{
@@ -303,14 +439,9 @@ func (client *WebSocketClient) handleMessage(msg *models.WebSocketMessage) {
 
 	case "get_subscriptions":
 		// Send current subscriptions
-		var symbols []string
-		for symbol := range client.subscriptions {
-			symbols = append(symbols, symbol)
-		}
-		
 		response := models.WebSocketMessage{
 			Type:      "subscriptions",
-			Data:      symbols,
+			Data:      client.hub.Subscriptions(client),
 			Timestamp: time.Now(),
 			RequestID: msg.RequestID,
 		}
@@ -318,23 +449,35 @@ func (client *WebSocketClient) handleMessage(msg *models.WebSocketMessage) {
 
 	default:
 		// Send error for unknown message type
-		response := models.WebSocketMessage{
-			Type:      "error",
-			Data:      "Unknown message type: " + msg.Type,
-			Timestamp: time.Now(),
-			RequestID: msg.RequestID,
-		}
-		client.sendMessage(response)
+		client.sendError("Unknown message type: "+msg.Type, msg.RequestID)
 	}
 }
 
-// sendMessage sends a message to the client
+// sendError sends an error message in reply to a request
+func (client *WebSocketClient) sendError(message, requestID string) {
+	client.sendMessage(models.WebSocketMessage{
+		Type:      "error",
+		Data:      message,
+		Timestamp: time.Now(),
+		RequestID: requestID,
+	})
+}
+
+// sendMessage sends a message to the client, dropping it if the client's
+// buffer is full. Only the hub closes the send channel, so the client must
+// still be registered.
 func (client *WebSocketClient) sendMessage(msg models.WebSocketMessage) {
 	if data, err := json.Marshal(msg); err == nil {
+		client.hub.mu.RLock()
+		defer client.hub.mu.RUnlock()
+
+		if !client.hub.clients[client] {
+			return
+		}
 		select {
 		case client.send <- data:
 		default:
-			close(client.send)
+			client.logger.WithField("client_id", client.id).Warn("Dropping reply to a slow WebSocket client")
 		}
 	}
 }
@@ -401,13 +544,37 @@ func (broadcaster *MarketDataBroadcaster) BroadcastPriceAlert(alert *models.Aler
 	}
 }
 
+// BroadcastPortfolioValuation pushes a portfolio's valuation to clients
+// subscribed to its channel
+func (broadcaster *MarketDataBroadcaster) BroadcastPortfolioValuation(valuation *services.PortfolioValuation) {
+	channel := PortfolioChannel(valuation.Portfolio.ID)
+	message := models.WebSocketMessage{
+		Type:      "portfolio_update",
+		Symbol:    channel,
+		Data:      valuation,
+		Timestamp: time.Now(),
+	}
+
+	if data, err := json.Marshal(message); err == nil {
+		broadcaster.hub.BroadcastToSymbol(channel, data)
+	} else {
+		broadcaster.logger.WithError(err).Error("Failed to marshal portfolio valuation for broadcast")
+	}
+}
+
 // GetConnectedClientsCount returns the number of connected clients
 func (hub *WebSocketHub) GetConnectedClientsCount() int {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
 	return len(hub.clients)
 }
 
 // GetSubscriptionStats returns subscription statistics
 func (hub *WebSocketHub) GetSubscriptionStats() map[string]int {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+
 	stats := make(map[string]int)
 	for symbol, clients := range hub.subscriptions {
 		stats[symbol] = len(clients)
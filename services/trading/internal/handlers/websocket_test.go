@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/models"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// testClient registers a client without a connection and drains its
+// welcome message. Only the portfolios listed exist.
+func testClient(t *testing.T, hub *WebSocketHub, id string, portfolios ...string) *WebSocketClient {
+	t.Helper()
+	exists := make(map[string]bool, len(portfolios))
+	for _, portfolioID := range portfolios {
+		exists[portfolioID] = true
+	}
+	client := &WebSocketClient{
+		send:            make(chan []byte, 256),
+		hub:             hub,
+		subscriptions:   make(map[string]bool),
+		portfolioExists: func(portfolioID string) bool { return exists[portfolioID] },
+		id:              id,
+		logger:          hub.logger,
+	}
+	hub.register <- client
+	if reply := readReply(t, client); reply.Type != "connected" {
+		t.Fatalf("first message %q, want connected", reply.Type)
+	}
+	return client
+}
+
+// readReply returns the next message sent to the client
+func readReply(t *testing.T, client *WebSocketClient) models.WebSocketMessage {
+	t.Helper()
+	select {
+	case data := <-client.send:
+		var msg models.WebSocketMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no reply")
+	}
+	return models.WebSocketMessage{}
+}
+
+func TestSubscribePortfolio(t *testing.T) {
+	hub := NewWebSocketHub(testLogger())
+	go hub.Run()
+	client := testClient(t, hub, "c1", "p1")
+
+	client.handleMessage(&models.WebSocketMessage{Type: "subscribe_portfolio", Data: "missing"})
+	if reply := readReply(t, client); reply.Type != "error" {
+		t.Fatalf("unknown portfolio reply %q, want error", reply.Type)
+	}
+	if ids := hub.SubscribedPortfolios(); len(ids) != 0 {
+		t.Fatalf("subscribed portfolios %v, want none", ids)
+	}
+
+	// The symbol path can't be used to join a portfolio channel
+	client.handleMessage(&models.WebSocketMessage{Type: "subscribe", Data: PortfolioChannel("missing")})
+	if reply := readReply(t, client); reply.Type != "error" {
+		t.Fatalf("portfolio channel through subscribe replied %q, want error", reply.Type)
+	}
+
+	client.handleMessage(&models.WebSocketMessage{Type: "subscribe_portfolio", Data: "p1"})
+	if reply := readReply(t, client); reply.Type != "subscription_confirmed" {
+		t.Fatalf("existing portfolio reply %q, want subscription_confirmed", reply.Type)
+	}
+	if ids := hub.SubscribedPortfolios(); len(ids) != 1 || ids[0] != "p1" {
+		t.Fatalf("subscribed portfolios %v, want [p1]", ids)
+	}
+
+	client.handleMessage(&models.WebSocketMessage{Type: "unsubscribe_portfolio", Data: "p1"})
+	if reply := readReply(t, client); reply.Type != "unsubscription_confirmed" {
+		t.Fatalf("unsubscribe reply %q, want unsubscription_confirmed", reply.Type)
+	}
+	if ids := hub.SubscribedPortfolios(); len(ids) != 0 {
+		t.Fatalf("subscribed portfolios %v after unsubscribing, want none", ids)
+	}
+}
+
+func TestSubscriptionLimits(t *testing.T) {
+	hub := NewWebSocketHub(testLogger())
+	go hub.Run()
+
+	portfolios := make([]string, maxPortfolioSubscriptions+1)
+	for i := range portfolios {
+		portfolios[i] = fmt.Sprintf("p%d", i)
+	}
+	client := testClient(t, hub, "c1", portfolios...)
+	for _, portfolioID := range portfolios[:maxPortfolioSubscriptions] {
+		client.handleMessage(&models.WebSocketMessage{Type: "subscribe_portfolio", Data: portfolioID})
+		if reply := readReply(t, client); reply.Type != "subscription_confirmed" {
+			t.Fatalf("portfolio %s reply %q, want subscription_confirmed", portfolioID, reply.Type)
+		}
+	}
+	client.handleMessage(&models.WebSocketMessage{Type: "subscribe_portfolio", Data: portfolios[maxPortfolioSubscriptions]})
+	if reply := readReply(t, client); reply.Type != "error" {
+		t.Fatalf("portfolio over the cap replied %q, want error", reply.Type)
+	}
+	// Resubscribing to a channel already held doesn't count against the cap
+	client.handleMessage(&models.WebSocketMessage{Type: "subscribe_portfolio", Data: portfolios[0]})
+	if reply := readReply(t, client); reply.Type != "subscription_confirmed" {
+		t.Fatalf("resubscribe reply %q, want subscription_confirmed", reply.Type)
+	}
+
+	symbols := testClient(t, hub, "c2")
+	for i := 0; i < maxSubscriptions; i++ {
+		if err := hub.SubscribeToSymbol(symbols, fmt.Sprintf("S%d", i)); err != nil {
+			t.Fatalf("symbol %d: %v", i, err)
+		}
+	}
+	if err := hub.SubscribeToSymbol(symbols, "OVER"); err == nil {
+		t.Fatal("symbol over the cap accepted")
+	}
+}
+
+func TestHubDropsClients(t *testing.T) {
+	hub := NewWebSocketHub(testLogger())
+	go hub.Run()
+	client := testClient(t, hub, "c1", "p1")
+	if err := hub.SubscribeToSymbol(client, "AAPL"); err != nil {
+		t.Fatal(err)
+	}
+
+	hub.unregister <- client
+	// Run handles the unregister before it takes the next request
+	testClient(t, hub, "c2")
+
+	if stats := hub.GetSubscriptionStats(); len(stats) != 0 {
+		t.Fatalf("subscriptions %v after unregistering, want none", stats)
+	}
+	if err := hub.SubscribeToSymbol(client, "AAPL"); err == nil {
+		t.Fatal("dropped client resubscribed")
+	}
+	// Replies to a dropped client are discarded rather than sent on its
+	// closed channel
+	client.handleMessage(&models.WebSocketMessage{Type: "ping"})
+}
+
+func TestHubConcurrentAccess(t *testing.T) {
+	hub := NewWebSocketHub(testLogger())
+	go hub.Run()
+
+	var wg sync.WaitGroup
+	for c := 0; c < 4; c++ {
+		client := testClient(t, hub, fmt.Sprintf("c%d", c), "p1", "p2")
+		wg.Add(1)
+		go func(client *WebSocketClient) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				portfolioID := fmt.Sprintf("p%d", i%2+1)
+				client.handleMessage(&models.WebSocketMessage{Type: "subscribe_portfolio", Data: portfolioID})
+				client.handleMessage(&models.WebSocketMessage{Type: "get_subscriptions"})
+				client.handleMessage(&models.WebSocketMessage{Type: "unsubscribe_portfolio", Data: portfolioID})
+				for len(client.send) > 0 {
+					<-client.send
+				}
+			}
+		}(client)
+	}
+
+	// The streaming loop reads subscriptions while clients change them
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			hub.SubscribedPortfolios()
+			hub.GetSubscriptionStats()
+			hub.GetConnectedClientsCount()
+		}
+	}()
+	wg.Wait()
+	<-done
+
+	if ids := hub.SubscribedPortfolios(); len(ids) != 0 {
+		t.Fatalf("subscribed portfolios %v after every client unsubscribed, want none", ids)
+	}
+}
@@ -0,0 +1,87 @@
+package indicators
+
+import (
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/models"
+)
+
+// Bars holds OHLCV history as contiguous float64 columns for the indicator
+// core. Build it once per series and reuse it across indicators.
+type Bars struct {
+	Symbol string
+	Dates  []time.Time
+	Open   []float64
+	High   []float64
+	Low    []float64
+	Close  []float64
+	Volume []float64
+}
+
+// NewBars converts historical data into float64 columns
+func NewBars(data []models.HistoricalData) *Bars {
+	n := len(data)
+	bars := &Bars{
+		Dates:  make([]time.Time, n),
+		Open:   make([]float64, n),
+		High:   make([]float64, n),
+		Low:    make([]float64, n),
+		Close:  make([]float64, n),
+		Volume: make([]float64, n),
+	}
+	if n > 0 {
+		bars.Symbol = data[n-1].Symbol
+	}
+
+	for i, d := range data {
+		bars.Dates[i] = d.Date
+		bars.Open[i] = d.Open.InexactFloat64()
+		bars.High[i] = d.High.InexactFloat64()
+		bars.Low[i] = d.Low.InexactFloat64()
+		bars.Close[i] = d.Close.InexactFloat64()
+		bars.Volume[i] = float64(d.Volume)
+	}
+
+	return bars
+}
+
+// Len returns the number of bars
+func (b *Bars) Len() int {
+	return len(b.Close)
+}
+
+// Slice returns the bars in [from, to) sharing the underlying columns
+func (b *Bars) Slice(from, to int) *Bars {
+	return &Bars{
+		Symbol: b.Symbol,
+		Dates:  b.Dates[from:to],
+		Open:   b.Open[from:to],
+		High:   b.High[from:to],
+		Low:    b.Low[from:to],
+		Close:  b.Close[from:to],
+		Volume: b.Volume[from:to],
+	}
+}
+
+// DecimalsToFloats converts a decimal slice to float64 at the model boundary
+func DecimalsToFloats(decimals []decimal.Decimal) []float64 {
+	floats := make([]float64, len(decimals))
+	for i, d := range decimals {
+		floats[i] = d.InexactFloat64()
+	}
+	return floats
+}
+
+// FloatsToDecimals converts core results back to decimals for API/model use
+func FloatsToDecimals(floats []float64) []decimal.Decimal {
+	decimals := make([]decimal.Decimal, len(floats))
+	for i, f := range floats {
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			continue // decimal cannot represent these; leave as zero
+		}
+		decimals[i] = decimal.NewFromFloat(f)
+	}
+	return decimals
+}
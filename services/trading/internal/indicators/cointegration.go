@@ -0,0 +1,169 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Significance levels reported by EngleGranger
+const (
+	Significance1  = "1%"
+	Significance5  = "5%"
+	Significance10 = "10%"
+)
+
+// mackinnonEG holds MacKinnon (2010) response surface coefficients for the
+// two-variable Engle-Granger test with a constant: c(T) = b0 + b1/T + b2/T^2
+var mackinnonEG = map[string][3]float64{
+	Significance1:  {-3.89644, -10.9519, -22.527},
+	Significance5:  {-3.33613, -6.1101, -6.823},
+	Significance10: {-3.04445, -4.2412, -2.720},
+}
+
+// CointegrationResult is the outcome of an Engle-Granger test of y on x
+type CointegrationResult struct {
+	HedgeRatio     float64            `json:"hedge_ratio"` // Units of x held against one unit of y
+	Intercept      float64            `json:"intercept"`
+	ADFStatistic   float64            `json:"adf_statistic"`
+	CriticalValues map[string]float64 `json:"critical_values"`
+	Lags           int                `json:"lags"`
+	Observations   int                `json:"observations"`
+	HalfLife       float64            `json:"half_life"` // Bars for the spread to revert halfway, 0 if it doesn't revert
+	Residuals      []float64          `json:"-"`         // The spread y - intercept - hedge_ratio * x
+}
+
+// Cointegrated reports whether the ADF statistic rejects a unit root in the
+// spread at the given significance level ("1%", "5%" or "10%")
+func (r *CointegrationResult) Cointegrated(level string) bool {
+	critical, ok := r.CriticalValues[level]
+	return ok && r.ADFStatistic < critical
+}
+
+// EngleGranger runs the two-step Engle-Granger cointegration test: regress y
+// on x, then test the residuals for a unit root with an augmented
+// Dickey-Fuller regression using `lags` lagged differences
+func EngleGranger(y, x []float64, lags int) (*CointegrationResult, error) {
+	if len(y) != len(x) {
+		return nil, fmt.Errorf("series lengths differ: %d and %d", len(y), len(x))
+	}
+	if lags < 0 {
+		return nil, fmt.Errorf("lags must not be negative")
+	}
+	if len(y) < lags+30 {
+		return nil, ErrInsufficientData
+	}
+
+	intercept, hedgeRatio := stat.LinearRegression(x, y, nil, false)
+
+	residuals := make([]float64, len(y))
+	for i := range y {
+		residuals[i] = y[i] - intercept - hedgeRatio*x[i]
+	}
+
+	// A spread with no variance means the series are exact multiples of each
+	// other; the ADF regression is undefined
+	if stat.StdDev(residuals, nil) <= 1e-9*math.Max(1, stat.StdDev(y, nil)) {
+		return nil, fmt.Errorf("series are perfectly collinear; spread has no variance")
+	}
+
+	adf, err := ADFStatistic(residuals, lags)
+	if err != nil {
+		return nil, err
+	}
+
+	n := float64(len(y))
+	critical := make(map[string]float64, len(mackinnonEG))
+	for level, b := range mackinnonEG {
+		critical[level] = b[0] + b[1]/n + b[2]/(n*n)
+	}
+
+	return &CointegrationResult{
+		HedgeRatio:     hedgeRatio,
+		Intercept:      intercept,
+		ADFStatistic:   adf,
+		CriticalValues: critical,
+		Lags:           lags,
+		Observations:   len(y),
+		HalfLife:       HalfLife(residuals),
+		Residuals:      residuals,
+	}, nil
+}
+
+// ADFStatistic returns the Dickey-Fuller t-statistic for gamma in
+// d(s_t) = gamma*s_(t-1) + sum phi_i*d(s_(t-i)) + e_t. No constant is
+// included, which suits regression residuals that already have zero mean.
+func ADFStatistic(series []float64, lags int) (float64, error) {
+	n := len(series)
+	rows := n - 1 - lags
+	cols := 1 + lags
+	if rows <= cols+1 {
+		return 0, ErrInsufficientData
+	}
+
+	diff := make([]float64, n)
+	for t := 1; t < n; t++ {
+		diff[t] = series[t] - series[t-1]
+	}
+
+	X := mat.NewDense(rows, cols, nil)
+	Y := mat.NewVecDense(rows, nil)
+	for r := 0; r < rows; r++ {
+		t := r + lags + 1
+		Y.SetVec(r, diff[t])
+		X.Set(r, 0, series[t-1])
+		for i := 1; i <= lags; i++ {
+			X.Set(r, i, diff[t-i])
+		}
+	}
+
+	var xtx, xtxInv mat.Dense
+	xtx.Mul(X.T(), X)
+	if err := xtxInv.Inverse(&xtx); err != nil {
+		return 0, fmt.Errorf("ADF regression is singular: %v", err)
+	}
+
+	var xty, coef, fitted mat.VecDense
+	xty.MulVec(X.T(), Y)
+	coef.MulVec(&xtxInv, &xty)
+	fitted.MulVec(X, &coef)
+
+	var ssr float64
+	for r := 0; r < rows; r++ {
+		e := Y.AtVec(r) - fitted.AtVec(r)
+		ssr += e * e
+	}
+	variance := ssr / float64(rows-cols)
+	stdErr := math.Sqrt(variance * xtxInv.At(0, 0))
+	if stdErr == 0 {
+		return 0, fmt.Errorf("ADF regression has zero residual variance")
+	}
+
+	return coef.AtVec(0) / stdErr, nil
+}
+
+// HalfLife estimates the mean reversion half-life of a series in bars from
+// an AR(1) fit of its changes on its lagged level. It returns 0 when the
+// series doesn't revert.
+func HalfLife(series []float64) float64 {
+	if len(series) < 3 {
+		return 0
+	}
+
+	lagged := series[:len(series)-1]
+	delta := make([]float64, len(series)-1)
+	for t := 1; t < len(series); t++ {
+		delta[t-1] = series[t] - series[t-1]
+	}
+
+	_, lambda := stat.LinearRegression(lagged, delta, nil, false)
+	if lambda >= 0 {
+		return 0
+	}
+	if lambda <= -1 {
+		return -math.Ln2 / lambda // Reverts within a bar; the log form is undefined
+	}
+	return -math.Ln2 / math.Log(1+lambda)
+}
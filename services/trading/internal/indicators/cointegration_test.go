@@ -0,0 +1,164 @@
+package indicators
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// ar1 returns n values of s_t = phi*s_(t-1) + e_t with standard normal noise
+func ar1(rng *rand.Rand, n int, phi float64) []float64 {
+	s := make([]float64, n)
+	for t := 1; t < n; t++ {
+		s[t] = phi*s[t-1] + rng.NormFloat64()
+	}
+	return s
+}
+
+// randomWalk returns n prices starting at 50 with unit normal steps
+func randomWalk(rng *rand.Rand, n int) []float64 {
+	x := make([]float64, n)
+	x[0] = 50
+	for t := 1; t < n; t++ {
+		x[t] = x[t-1] + rng.NormFloat64()
+	}
+	return x
+}
+
+func TestEngleGrangerCointegratedPair(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		x := randomWalk(rng, 500)
+		spread := ar1(rng, 500, 0.5)
+		y := make([]float64, len(x))
+		for i := range x {
+			y[i] = 5 + 1.5*x[i] + spread[i]
+		}
+
+		result, err := EngleGranger(y, x, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(result.HedgeRatio-1.5) > 0.05 {
+			t.Fatalf("seed %d: hedge ratio %v, want about 1.5", seed, result.HedgeRatio)
+		}
+		if !result.Cointegrated(Significance1) {
+			t.Fatalf("seed %d: ADF %v above the 1%% critical value %v", seed, result.ADFStatistic, result.CriticalValues[Significance1])
+		}
+		// A spread reverting by half each bar has a half-life of one bar
+		if math.Abs(result.HalfLife-1) > 0.3 {
+			t.Fatalf("seed %d: half-life %v, want about 1", seed, result.HalfLife)
+		}
+		if len(result.Residuals) != 500 || result.Observations != 500 || result.Lags != 1 {
+			t.Fatalf("seed %d: %d residuals from %d observations", seed, len(result.Residuals), result.Observations)
+		}
+	}
+}
+
+func TestEngleGrangerCriticalValues(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	x := randomWalk(rng, 200)
+	y := make([]float64, len(x))
+	for i := range x {
+		y[i] = 2*x[i] + rng.NormFloat64()
+	}
+	result, err := EngleGranger(y, x, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// MacKinnon (2010) at 200 observations
+	want := map[string]float64{Significance1: -3.95176, Significance5: -3.36685, Significance10: -3.06572}
+	for level, critical := range want {
+		if math.Abs(result.CriticalValues[level]-critical) > 1e-4 {
+			t.Fatalf("%s critical value %v, want %v", level, result.CriticalValues[level], critical)
+		}
+	}
+	if result.Cointegrated("2%") {
+		t.Fatal("an unknown significance level was accepted")
+	}
+}
+
+func TestEngleGrangerIndependentWalks(t *testing.T) {
+	// Regressing one random walk on another gives a spurious fit whose
+	// residuals still have a unit root
+	var accepted int
+	for seed := int64(1); seed <= 40; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		y, x := randomWalk(rng, 500), randomWalk(rng, 500)
+		result, err := EngleGranger(y, x, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Cointegrated(Significance5) {
+			accepted++
+		}
+	}
+	if accepted > 4 {
+		t.Fatalf("%d of 40 independent pairs accepted at 5%%, want about 2", accepted)
+	}
+}
+
+func TestEngleGrangerErrors(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	x := randomWalk(rng, 100)
+	doubled := make([]float64, len(x))
+	for i := range x {
+		doubled[i] = 3 + 2*x[i]
+	}
+
+	if _, err := EngleGranger(x[:50], x, 1); err == nil {
+		t.Fatal("series of different lengths were tested")
+	}
+	if _, err := EngleGranger(x, x, -1); err == nil {
+		t.Fatal("negative lags were accepted")
+	}
+	if _, err := EngleGranger(x[:30], x[:30], 1); err != ErrInsufficientData {
+		t.Fatalf("error = %v, want ErrInsufficientData", err)
+	}
+	if _, err := EngleGranger(doubled, x, 1); err == nil {
+		t.Fatal("a spread with no variance was tested")
+	}
+}
+
+func TestADFStatistic(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	noise, err := ADFStatistic(ar1(rng, 1000, 0), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walk, err := ADFStatistic(ar1(rng, 1000, 1), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// White noise rejects a unit root emphatically; a random walk doesn't
+	if noise > -15 || walk < -2.5 {
+		t.Fatalf("ADF %v for white noise and %v for a random walk", noise, walk)
+	}
+	if _, err := ADFStatistic(make([]float64, 5), 2); err != ErrInsufficientData {
+		t.Fatalf("error = %v, want ErrInsufficientData", err)
+	}
+}
+
+func TestHalfLife(t *testing.T) {
+	tests := []struct {
+		name string
+		phi  float64
+		want float64
+	}{
+		{"slow reversion", 0.9, math.Ln2 / -math.Log(0.9)},
+		{"fast reversion", 0.5, 1},
+		{"overshooting", -0.5, math.Ln2 / 1.5}, // The change overshoots the mean every bar
+		{"explosive", 1.05, 0},
+	}
+	for _, tt := range tests {
+		rng := rand.New(rand.NewSource(3))
+		got := HalfLife(ar1(rng, 5000, tt.phi))
+		if math.Abs(got-tt.want) > 0.1*math.Max(1, tt.want) {
+			t.Fatalf("%s: half-life %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if HalfLife([]float64{1, 2}) != 0 {
+		t.Fatal("two values have no half-life")
+	}
+}
@@ -0,0 +1,229 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Divergence types
+const (
+	DivergenceRegularBullish = "REGULAR_BULLISH"
+	DivergenceRegularBearish = "REGULAR_BEARISH"
+	DivergenceHiddenBullish  = "HIDDEN_BULLISH"
+	DivergenceHiddenBearish  = "HIDDEN_BEARISH"
+)
+
+// Oscillators checked for divergence
+const (
+	OscillatorRSI        = "RSI"
+	OscillatorMACDHist   = "MACD_HISTOGRAM"
+	OscillatorStochastic = "STOCHASTIC"
+	OscillatorOBV        = "OBV"
+)
+
+// DivergenceConfig controls swing detection and oscillator settings
+type DivergenceConfig struct {
+	SwingStrength int `json:"swing_strength"` // Bars on each side of a swing
+	MaxSpan       int `json:"max_span"`       // Max bars between the two swings
+	RSIPeriod     int `json:"rsi_period"`
+	MACDFast      int `json:"macd_fast"`
+	MACDSlow      int `json:"macd_slow"`
+	MACDSignal    int `json:"macd_signal"`
+	StochK        int `json:"stoch_k"`
+	StochD        int `json:"stoch_d"`
+}
+
+// DefaultDivergenceConfig returns commonly used settings
+func DefaultDivergenceConfig() DivergenceConfig {
+	return DivergenceConfig{
+		SwingStrength: 3,
+		MaxSpan:       60,
+		RSIPeriod:     14,
+		MACDFast:      12,
+		MACDSlow:      26,
+		MACDSignal:    9,
+		StochK:        14,
+		StochD:        3,
+	}
+}
+
+// DivergencePoint is one end of a divergence: a price swing and the
+// oscillator reading on the same bar
+type DivergencePoint struct {
+	Index      int       `json:"index"`
+	Date       time.Time `json:"date"`
+	Price      float64   `json:"price"`
+	Oscillator float64   `json:"oscillator"`
+}
+
+// Divergence represents a disagreement between two price swings and the
+// oscillator readings at those swings
+type Divergence struct {
+	Oscillator string          `json:"oscillator"`
+	Type       string          `json:"type"`
+	Bias       string          `json:"bias"` // BULLISH, BEARISH
+	Hidden     bool            `json:"hidden"`
+	Start      DivergencePoint `json:"start"`
+	End        DivergencePoint `json:"end"`
+	BarsAgo    int             `json:"bars_ago"` // Bars since the confirming swing
+}
+
+// DetectDivergences finds regular and hidden divergences between price swings
+// and RSI, MACD histogram, Stochastic %K and OBV. Only consecutive swings of the
+// same kind are compared. Results are ordered by the bar of the second swing.
+func DetectDivergences(bars *Bars, cfg DivergenceConfig) ([]Divergence, error) {
+	if cfg.SwingStrength < 1 || cfg.MaxSpan < 1 {
+		return nil, fmt.Errorf("swing strength and max span must be positive")
+	}
+	if bars.Len() < 2*cfg.SwingStrength+1 {
+		return nil, ErrInsufficientData
+	}
+
+	oscillators, err := alignedOscillators(bars, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	highIdx, lowIdx := swingIndices(bars.High, bars.Low, cfg.SwingStrength)
+
+	var divergences []Divergence
+	for _, name := range []string{OscillatorRSI, OscillatorMACDHist, OscillatorStochastic, OscillatorOBV} {
+		osc := oscillators[name]
+		divergences = append(divergences, compareSwings(bars, bars.Low, osc, lowIdx, name, false, cfg.MaxSpan)...)
+		divergences = append(divergences, compareSwings(bars, bars.High, osc, highIdx, name, true, cfg.MaxSpan)...)
+	}
+
+	// Stable insertion sort by confirming swing, keeps oscillator order on ties
+	for i := 1; i < len(divergences); i++ {
+		for j := i; j > 0 && divergences[j].End.Index < divergences[j-1].End.Index; j-- {
+			divergences[j], divergences[j-1] = divergences[j-1], divergences[j]
+		}
+	}
+
+	return divergences, nil
+}
+
+// RecentDivergences filters divergences whose second swing is within `bars`
+// of the latest bar
+func RecentDivergences(divergences []Divergence, bars int) []Divergence {
+	var recent []Divergence
+	for _, d := range divergences {
+		if d.BarsAgo <= bars {
+			recent = append(recent, d)
+		}
+	}
+	return recent
+}
+
+// DivergenceBias summarises divergences into a net bias: the count of bullish
+// minus bearish divergences, with regular divergences weighted double
+func DivergenceBias(divergences []Divergence) int {
+	score := 0
+	for _, d := range divergences {
+		weight := 2
+		if d.Hidden {
+			weight = 1
+		}
+		if d.Bias == "BULLISH" {
+			score += weight
+		} else {
+			score -= weight
+		}
+	}
+	return score
+}
+
+// compareSwings checks consecutive swings (lows when highs is false) against
+// the oscillator series aligned to bar indices
+func compareSwings(bars *Bars, prices, osc []float64, swings []int, name string, highs bool, maxSpan int) []Divergence {
+	var divergences []Divergence
+	last := bars.Len() - 1
+
+	for k := 1; k < len(swings); k++ {
+		a, b := swings[k-1], swings[k]
+		if b-a > maxSpan || math.IsNaN(osc[a]) || math.IsNaN(osc[b]) {
+			continue
+		}
+
+		priceUp := prices[b] > prices[a]
+		priceDown := prices[b] < prices[a]
+		oscUp := osc[b] > osc[a]
+		oscDown := osc[b] < osc[a]
+
+		var divType string
+		switch {
+		case !highs && priceDown && oscUp:
+			divType = DivergenceRegularBullish
+		case !highs && priceUp && oscDown:
+			divType = DivergenceHiddenBullish
+		case highs && priceUp && oscDown:
+			divType = DivergenceRegularBearish
+		case highs && priceDown && oscUp:
+			divType = DivergenceHiddenBearish
+		default:
+			continue
+		}
+
+		bias := "BULLISH"
+		if highs {
+			bias = "BEARISH"
+		}
+
+		divergences = append(divergences, Divergence{
+			Oscillator: name,
+			Type:       divType,
+			Bias:       bias,
+			Hidden:     divType == DivergenceHiddenBullish || divType == DivergenceHiddenBearish,
+			Start:      DivergencePoint{Index: a, Date: bars.Dates[a], Price: prices[a], Oscillator: osc[a]},
+			End:        DivergencePoint{Index: b, Date: bars.Dates[b], Price: prices[b], Oscillator: osc[b]},
+			BarsAgo:    last - b,
+		})
+	}
+
+	return divergences
+}
+
+// alignedOscillators computes each oscillator padded with NaN so index i
+// matches bar i
+func alignedOscillators(bars *Bars, cfg DivergenceConfig) (map[string][]float64, error) {
+	n := bars.Len()
+	oscillators := make(map[string][]float64)
+
+	rsi, err := RSIFloat(bars.Close, cfg.RSIPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("RSI calculation failed: %v", err)
+	}
+	oscillators[OscillatorRSI] = padFront(rsi, n)
+
+	_, _, hist, err := MACDFloat(bars.Close, cfg.MACDFast, cfg.MACDSlow, cfg.MACDSignal)
+	if err != nil {
+		return nil, fmt.Errorf("MACD calculation failed: %v", err)
+	}
+	oscillators[OscillatorMACDHist] = padFront(hist, n)
+
+	k, _, err := StochasticFloat(bars.High, bars.Low, bars.Close, cfg.StochK, cfg.StochD)
+	if err != nil {
+		return nil, fmt.Errorf("Stochastic calculation failed: %v", err)
+	}
+	oscillators[OscillatorStochastic] = padFront(k, n)
+
+	obv, err := OBVFloat(bars.Close, bars.Volume)
+	if err != nil {
+		return nil, fmt.Errorf("OBV calculation failed: %v", err)
+	}
+	oscillators[OscillatorOBV] = obv
+
+	return oscillators, nil
+}
+
+// padFront right-aligns values in a slice of length n, filling the head with NaN
+func padFront(values []float64, n int) []float64 {
+	padded := make([]float64, n)
+	offset := n - len(values)
+	for i := 0; i < offset; i++ {
+		padded[i] = math.NaN()
+	}
+	copy(padded[offset:], values)
+	return padded
+}
@@ -0,0 +1,206 @@
+package indicators
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// closeBars builds daily bars half a point either side of each close
+func closeBars(closes []float64) *Bars {
+	n := len(closes)
+	bars := &Bars{
+		Dates:  make([]time.Time, n),
+		Open:   make([]float64, n),
+		High:   make([]float64, n),
+		Low:    make([]float64, n),
+		Close:  append([]float64(nil), closes...),
+		Volume: make([]float64, n),
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, c := range closes {
+		bars.Dates[i] = start.AddDate(0, 0, i)
+		bars.Open[i] = c
+		bars.High[i] = c + 0.5
+		bars.Low[i] = c - 0.5
+		bars.Volume[i] = 1000
+	}
+	return bars
+}
+
+// legs joins straight-line moves through the given prices, steps bars apart
+func legs(steps int, prices ...float64) []float64 {
+	series := []float64{prices[0]}
+	for k := 1; k < len(prices); k++ {
+		for i := 1; i <= steps; i++ {
+			series = append(series, prices[k-1]+(prices[k]-prices[k-1])*float64(i)/float64(steps))
+		}
+	}
+	return series
+}
+
+func TestCompareSwings(t *testing.T) {
+	bars := closeBars([]float64{0, 0, 0, 0, 0, 0})
+	nan := math.NaN()
+
+	tests := []struct {
+		name    string
+		prices  []float64
+		osc     []float64
+		highs   bool
+		maxSpan int
+		want    string
+	}{
+		{"regular bullish", []float64{0, 10, 0, 8, 0, 0}, []float64{0, 20, 0, 30, 0, 0}, false, 60, DivergenceRegularBullish},
+		{"hidden bullish", []float64{0, 10, 0, 12, 0, 0}, []float64{0, 30, 0, 20, 0, 0}, false, 60, DivergenceHiddenBullish},
+		{"regular bearish", []float64{0, 10, 0, 12, 0, 0}, []float64{0, 80, 0, 70, 0, 0}, true, 60, DivergenceRegularBearish},
+		{"hidden bearish", []float64{0, 10, 0, 8, 0, 0}, []float64{0, 70, 0, 80, 0, 0}, true, 60, DivergenceHiddenBearish},
+		{"confirmed higher high", []float64{0, 10, 0, 12, 0, 0}, []float64{0, 70, 0, 80, 0, 0}, true, 60, ""},
+		{"confirmed lower low", []float64{0, 10, 0, 8, 0, 0}, []float64{0, 30, 0, 20, 0, 0}, false, 60, ""},
+		{"swings too far apart", []float64{0, 10, 0, 8, 0, 0}, []float64{0, 20, 0, 30, 0, 0}, false, 1, ""},
+		{"oscillator warming up", []float64{0, 10, 0, 8, 0, 0}, []float64{nan, nan, 0, 30, 0, 0}, false, 60, ""},
+	}
+	for _, tt := range tests {
+		divergences := compareSwings(bars, tt.prices, tt.osc, []int{1, 3}, OscillatorRSI, tt.highs, tt.maxSpan)
+		if tt.want == "" {
+			if len(divergences) != 0 {
+				t.Fatalf("%s: got %+v, want no divergence", tt.name, divergences)
+			}
+			continue
+		}
+		if len(divergences) != 1 {
+			t.Fatalf("%s: got %d divergences, want 1", tt.name, len(divergences))
+		}
+		d := divergences[0]
+		wantBias := "BULLISH"
+		if tt.highs {
+			wantBias = "BEARISH"
+		}
+		wantHidden := tt.want == DivergenceHiddenBullish || tt.want == DivergenceHiddenBearish
+		if d.Type != tt.want || d.Bias != wantBias || d.Hidden != wantHidden {
+			t.Fatalf("%s: got %s %s hidden %v, want %s %s hidden %v", tt.name, d.Type, d.Bias, d.Hidden, tt.want, wantBias, wantHidden)
+		}
+		if d.Start.Index != 1 || d.End.Index != 3 || d.BarsAgo != 2 || d.End.Oscillator != tt.osc[3] || !d.End.Date.Equal(bars.Dates[3]) {
+			t.Fatalf("%s: points %+v to %+v, want bars 1 to 3, 2 bars ago", tt.name, d.Start, d.End)
+		}
+	}
+}
+
+func TestDetectDivergencesRegularBullish(t *testing.T) {
+	// A steep fall to 70, a bounce, then a slow grind to a lower low at 68:
+	// price makes a lower low while momentum makes a higher one
+	closes := append(legs(10, 100, 110, 100, 112, 70, 86), legs(25, 86, 68)[1:]...)
+	closes = append(closes, legs(5, 68, 80)[1:]...)
+	bars := closeBars(closes)
+	secondLow := len(closes) - 6
+
+	divergences, err := DetectDivergences(bars, DefaultDivergenceConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for i, d := range divergences {
+		if i > 0 && d.End.Index < divergences[i-1].End.Index {
+			t.Fatalf("divergences not ordered by confirming swing: %d after %d", d.End.Index, divergences[i-1].End.Index)
+		}
+		if d.Oscillator == OscillatorRSI && d.Type == DivergenceRegularBullish && d.End.Index == secondLow {
+			found = true
+			if d.End.Price >= d.Start.Price || d.End.Oscillator <= d.Start.Oscillator {
+				t.Fatalf("divergence %+v isn't a lower price low with a higher RSI low", d)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("no regular bullish RSI divergence ending at bar %d in %+v", secondLow, divergences)
+	}
+
+	recent := RecentDivergences(divergences, 5)
+	if len(recent) == 0 || DivergenceBias(recent) <= 0 {
+		t.Fatalf("recent divergences %+v, want a bullish bias", recent)
+	}
+}
+
+func TestDetectDivergencesRegularBearish(t *testing.T) {
+	// Mirror image: a steep rally to 130, a dip, then a slow grind to a
+	// higher high at 132 with weaker momentum
+	closes := append(legs(10, 100, 90, 100, 88, 130, 114), legs(25, 114, 132)[1:]...)
+	closes = append(closes, legs(5, 132, 120)[1:]...)
+	bars := closeBars(closes)
+	secondHigh := len(closes) - 6
+
+	divergences, err := DetectDivergences(bars, DefaultDivergenceConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, d := range divergences {
+		if d.Oscillator == OscillatorRSI && d.Type == DivergenceRegularBearish && d.End.Index == secondHigh {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("no regular bearish RSI divergence ending at bar %d in %+v", secondHigh, divergences)
+	}
+	if bias := DivergenceBias(RecentDivergences(divergences, 5)); bias >= 0 {
+		t.Fatalf("recent bias = %d, want bearish", bias)
+	}
+}
+
+func TestDivergenceBias(t *testing.T) {
+	divergences := []Divergence{
+		{Bias: "BULLISH", BarsAgo: 1},
+		{Bias: "BEARISH", Hidden: true, BarsAgo: 3},
+		{Bias: "BEARISH", BarsAgo: 20},
+	}
+	if bias := DivergenceBias(divergences); bias != -1 {
+		t.Fatalf("bias = %d, want 2 - 1 - 2 = -1", bias)
+	}
+	if recent := RecentDivergences(divergences, 3); len(recent) != 2 || DivergenceBias(recent) != 1 {
+		t.Fatalf("recent = %+v, want the two within 3 bars with bias 1", recent)
+	}
+}
+
+func TestDetectDivergencesInsufficientData(t *testing.T) {
+	cfg := DefaultDivergenceConfig()
+	if _, err := DetectDivergences(closeBars(legs(10, 100, 90)), cfg); err == nil {
+		t.Fatal("11 bars are too few for MACD, want an error")
+	}
+	if _, err := DetectDivergences(closeBars([]float64{1, 2, 3}), cfg); !errors.Is(err, ErrInsufficientData) {
+		t.Fatalf("error = %v, want ErrInsufficientData", err)
+	}
+	cfg.SwingStrength = 0
+	if _, err := DetectDivergences(closeBars(legs(30, 100, 90)), cfg); err == nil {
+		t.Fatal("a swing strength of 0 should be rejected")
+	}
+}
+
+func TestDetectDivergencesHidden(t *testing.T) {
+	tests := []struct {
+		name   string
+		closes []float64
+		want   string
+	}{
+		// An uptrend whose slow first pullback bottoms at 110 and whose
+		// sharp second one holds at 115: a higher price low on weaker RSI
+		{"hidden bullish", append(append(legs(10, 100, 130), legs(25, 130, 110)[1:]...), legs(10, 110, 140, 115, 125)[1:]...), DivergenceHiddenBullish},
+		// The mirror image in a downtrend
+		{"hidden bearish", append(append(legs(10, 200, 170), legs(25, 170, 190)[1:]...), legs(10, 190, 160, 185, 175)[1:]...), DivergenceHiddenBearish},
+	}
+	for _, tt := range tests {
+		secondSwing := len(tt.closes) - 11
+		divergences, err := DetectDivergences(closeBars(tt.closes), DefaultDivergenceConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found bool
+		for _, d := range divergences {
+			if d.Oscillator == OscillatorRSI && d.Type == tt.want && d.End.Index == secondSwing {
+				found = d.Hidden
+			}
+		}
+		if !found {
+			t.Fatalf("%s: no hidden RSI divergence ending at bar %d in %+v", tt.name, secondSwing, divergences)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+package indicators
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// The functions in this file are the indicator core. They operate on
+// contiguous float64 columns and return slices with the same length and
+// alignment as their decimal counterparts in technical_indicators.go, which
+// are thin wrappers kept for API/model boundaries.
+
+// SMAFloat calculates Simple Moving Average
+func SMAFloat(prices []float64, period int) ([]float64, error) {
+	if period < 1 || len(prices) < period {
+		return nil, ErrInsufficientData
+	}
+
+	results := make([]float64, len(prices)-period+1)
+	sum := 0.0
+	for i := 0; i < period; i++ {
+		sum += prices[i]
+	}
+	results[0] = sum / float64(period)
+
+	for i := period; i < len(prices); i++ {
+		sum += prices[i] - prices[i-period]
+		results[i-period+1] = sum / float64(period)
+	}
+
+	return results, nil
+}
+
+// EMAFloat calculates Exponential Moving Average seeded with the SMA of the
+// first period
+func EMAFloat(prices []float64, period int) ([]float64, error) {
+	if period < 1 || len(prices) < period {
+		return nil, ErrInsufficientData
+	}
+
+	multiplier := 2.0 / float64(period+1)
+	results := make([]float64, len(prices)-period+1)
+
+	sum := 0.0
+	for i := 0; i < period; i++ {
+		sum += prices[i]
+	}
+	results[0] = sum / float64(period)
+
+	for i := period; i < len(prices); i++ {
+		prev := results[i-period]
+		results[i-period+1] = (prices[i]-prev)*multiplier + prev
+	}
+
+	return results, nil
+}
+
+// RSIFloat calculates the Relative Strength Index using Wilder smoothing
+func RSIFloat(prices []float64, period int) ([]float64, error) {
+	if period < 1 || len(prices) < period+1 {
+		return nil, ErrInsufficientData
+	}
+
+	results := make([]float64, len(prices)-period)
+
+	var avgGain, avgLoss float64
+	for i := 1; i <= period; i++ {
+		change := prices[i] - prices[i-1]
+		if change > 0 {
+			avgGain += change
+		} else {
+			avgLoss -= change
+		}
+	}
+	avgGain /= float64(period)
+	avgLoss /= float64(period)
+	results[0] = rsiFromAverages(avgGain, avgLoss)
+
+	for i := period + 1; i < len(prices); i++ {
+		change := prices[i] - prices[i-1]
+		gain, loss := 0.0, 0.0
+		if change > 0 {
+			gain = change
+		} else {
+			loss = -change
+		}
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		results[i-period] = rsiFromAverages(avgGain, avgLoss)
+	}
+
+	return results, nil
+}
+
+func rsiFromAverages(avgGain, avgLoss float64) float64 {
+	if avgLoss == 0 {
+		return 100
+	}
+	rs := avgGain / avgLoss
+	return 100 - 100/(1+rs)
+}
+
+// MACDFloat calculates the Moving Average Convergence Divergence
+func MACDFloat(prices []float64, fastPeriod, slowPeriod, signalPeriod int) (macdLine, signalLine, histogram []float64, err error) {
+	if len(prices) < slowPeriod || fastPeriod > slowPeriod {
+		return nil, nil, nil, ErrInsufficientData
+	}
+
+	fastEMA, err := EMAFloat(prices, fastPeriod)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	slowEMA, err := EMAFloat(prices, slowPeriod)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Align the fast EMA with the start of the slow EMA
+	startIndex := slowPeriod - fastPeriod
+	macdLine = make([]float64, len(fastEMA)-startIndex)
+	for i := startIndex; i < len(fastEMA); i++ {
+		macdLine[i-startIndex] = fastEMA[i] - slowEMA[i-startIndex]
+	}
+
+	signalLine, err = EMAFloat(macdLine, signalPeriod)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	startSignalIndex := signalPeriod - 1
+	histogram = make([]float64, len(macdLine)-startSignalIndex)
+	for i := startSignalIndex; i < len(macdLine); i++ {
+		histogram[i-startSignalIndex] = macdLine[i] - signalLine[i-startSignalIndex]
+	}
+
+	return macdLine, signalLine, histogram, nil
+}
+
+// BollingerBandsFloat calculates Bollinger Bands using the population
+// standard deviation of each window
+func BollingerBandsFloat(prices []float64, period int, multiplier float64) (upper, middle, lower []float64, err error) {
+	middle, err = SMAFloat(prices, period)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	upper = make([]float64, len(middle))
+	lower = make([]float64, len(middle))
+	for i := range middle {
+		mean := middle[i]
+		sum := 0.0
+		for _, p := range prices[i : i+period] {
+			diff := p - mean
+			sum += diff * diff
+		}
+		stdDev := math.Sqrt(sum / float64(period))
+		upper[i] = mean + stdDev*multiplier
+		lower[i] = mean - stdDev*multiplier
+	}
+
+	return upper, middle, lower, nil
+}
+
+// KeltnerChannelsFloat calculates Keltner Channels: an EMA of the close
+// plus and minus a multiple of ATR. Results align with ATRFloat.
+func KeltnerChannelsFloat(highs, lows, closes []float64, period int, multiplier float64) (upper, middle, lower []float64, err error) {
+	atr, err := ATRFloat(highs, lows, closes, period)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ema, err := EMAFloat(closes, period)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	middle = ema[len(ema)-len(atr):]
+	upper = make([]float64, len(atr))
+	lower = make([]float64, len(atr))
+	for i := range atr {
+		upper[i] = middle[i] + multiplier*atr[i]
+		lower[i] = middle[i] - multiplier*atr[i]
+	}
+
+	return upper, middle, lower, nil
+}
+
+// DonchianChannelsFloat calculates the highest high and lowest low over each
+// window of `period` bars, including the bar itself
+func DonchianChannelsFloat(highs, lows []float64, period int) (upper, middle, lower []float64, err error) {
+	if len(highs) != len(lows) || period < 1 || len(highs) < period {
+		return nil, nil, nil, ErrInsufficientData
+	}
+
+	n := len(highs) - period + 1
+	upper = make([]float64, n)
+	middle = make([]float64, n)
+	lower = make([]float64, n)
+	for i := 0; i < n; i++ {
+		upper[i], lower[i] = windowExtremes(highs, lows, i, i+period-1)
+		middle[i] = (upper[i] + lower[i]) / 2
+	}
+
+	return upper, middle, lower, nil
+}
+
+// StochasticFloat calculates the Stochastic Oscillator %K and %D
+func StochasticFloat(highs, lows, closes []float64, kPeriod, dPeriod int) (kPercent, dPercent []float64, err error) {
+	if len(highs) != len(lows) || len(lows) != len(closes) || kPeriod < 1 || len(closes) < kPeriod {
+		return nil, nil, ErrInsufficientData
+	}
+
+	kPercent = make([]float64, len(closes)-kPeriod+1)
+	for i := kPeriod - 1; i < len(closes); i++ {
+		highestHigh, lowestLow := windowExtremes(highs, lows, i-kPeriod+1, i)
+
+		k := 50.0 // Default to 50 if no range
+		if rng := highestHigh - lowestLow; rng != 0 {
+			k = (closes[i] - lowestLow) / rng * 100
+		}
+		kPercent[i-kPeriod+1] = k
+	}
+
+	dPercent, err = SMAFloat(kPercent, dPeriod)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return kPercent, dPercent, nil
+}
+
+// WilliamsRFloat calculates Williams %R
+func WilliamsRFloat(highs, lows, closes []float64, period int) ([]float64, error) {
+	if len(highs) != len(lows) || len(lows) != len(closes) || period < 1 || len(closes) < period {
+		return nil, ErrInsufficientData
+	}
+
+	results := make([]float64, len(closes)-period+1)
+	for i := period - 1; i < len(closes); i++ {
+		highestHigh, lowestLow := windowExtremes(highs, lows, i-period+1, i)
+
+		wr := -50.0 // Default to -50 if no range
+		if rng := highestHigh - lowestLow; rng != 0 {
+			wr = (highestHigh - closes[i]) / rng * -100
+		}
+		results[i-period+1] = wr
+	}
+
+	return results, nil
+}
+
+// ADXFloat calculates the Average Directional Index
+func ADXFloat(highs, lows, closes []float64, period int) (adx, plusDI, minusDI []float64, err error) {
+	if len(highs) != len(lows) || len(lows) != len(closes) || len(closes) < period+1 {
+		return nil, nil, nil, ErrInsufficientData
+	}
+
+	n := len(closes) - 1
+	trueRanges := make([]float64, n)
+	plusDMs := make([]float64, n)
+	minusDMs := make([]float64, n)
+
+	for i := 1; i < len(closes); i++ {
+		trueRanges[i-1] = math.Max(highs[i]-lows[i], math.Max(math.Abs(highs[i]-closes[i-1]), math.Abs(lows[i]-closes[i-1])))
+
+		upMove := highs[i] - highs[i-1]
+		downMove := lows[i-1] - lows[i]
+		if upMove > downMove && upMove > 0 {
+			plusDMs[i-1] = upMove
+		}
+		if downMove > upMove && downMove > 0 {
+			minusDMs[i-1] = downMove
+		}
+	}
+
+	smoothedTR, err := EMAFloat(trueRanges, period)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	smoothedPlusDM, _ := EMAFloat(plusDMs, period)
+	smoothedMinusDM, _ := EMAFloat(minusDMs, period)
+
+	plusDI = make([]float64, len(smoothedTR))
+	minusDI = make([]float64, len(smoothedTR))
+	dxValues := make([]float64, len(smoothedTR))
+	for i := range smoothedTR {
+		if smoothedTR[i] != 0 {
+			plusDI[i] = smoothedPlusDM[i] / smoothedTR[i] * 100
+			minusDI[i] = smoothedMinusDM[i] / smoothedTR[i] * 100
+		}
+		if diSum := plusDI[i] + minusDI[i]; diSum != 0 {
+			dxValues[i] = math.Abs(plusDI[i]-minusDI[i]) / diSum * 100
+		}
+	}
+
+	// ADX is EMA of DX
+	adx, err = EMAFloat(dxValues, period)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return adx, plusDI, minusDI, nil
+}
+
+// CCIFloat calculates the Commodity Channel Index
+func CCIFloat(highs, lows, closes []float64, period int) ([]float64, error) {
+	if len(highs) != len(lows) || len(lows) != len(closes) || period < 1 || len(closes) < period {
+		return nil, ErrInsufficientData
+	}
+
+	typicalPrices := make([]float64, len(closes))
+	for i := range closes {
+		typicalPrices[i] = (highs[i] + lows[i] + closes[i]) / 3
+	}
+
+	smaTP, err := SMAFloat(typicalPrices, period)
+	if err != nil {
+		return nil, err
+	}
+
+	const constant = 0.015
+	results := make([]float64, len(smaTP))
+	for i := range smaTP {
+		meanDev := 0.0
+		for _, tp := range typicalPrices[i : i+period] {
+			meanDev += math.Abs(tp - smaTP[i])
+		}
+		meanDev /= float64(period)
+
+		if meanDev != 0 {
+			results[i] = (typicalPrices[i+period-1] - smaTP[i]) / (constant * meanDev)
+		}
+	}
+
+	return results, nil
+}
+
+// ATRFloat calculates Average True Range using Wilder smoothing
+func ATRFloat(highs, lows, closes []float64, period int) ([]float64, error) {
+	if len(highs) != len(lows) || len(lows) != len(closes) || period < 1 || len(closes) < period+1 {
+		return nil, ErrInsufficientData
+	}
+
+	trueRanges := make([]float64, len(closes)-1)
+	for i := 1; i < len(closes); i++ {
+		trueRanges[i-1] = math.Max(highs[i]-lows[i], math.Max(math.Abs(highs[i]-closes[i-1]), math.Abs(lows[i]-closes[i-1])))
+	}
+
+	results := make([]float64, len(trueRanges)-period+1)
+	sum := 0.0
+	for _, tr := range trueRanges[:period] {
+		sum += tr
+	}
+	results[0] = sum / float64(period)
+	for i := period; i < len(trueRanges); i++ {
+		results[i-period+1] = (results[i-period]*float64(period-1) + trueRanges[i]) / float64(period)
+	}
+
+	return results, nil
+}
+
+// OBVFloat calculates On-Balance Volume starting from zero at the first bar
+func OBVFloat(closes, volumes []float64) ([]float64, error) {
+	if len(closes) != len(volumes) || len(closes) == 0 {
+		return nil, ErrInsufficientData
+	}
+
+	results := make([]float64, len(closes))
+	for i := 1; i < len(closes); i++ {
+		results[i] = results[i-1]
+		if closes[i] > closes[i-1] {
+			results[i] += volumes[i]
+		} else if closes[i] < closes[i-1] {
+			results[i] -= volumes[i]
+		}
+	}
+
+	return results, nil
+}
+
+// Returns calculates simple period-over-period returns
+func Returns(prices []float64) []float64 {
+	if len(prices) < 2 {
+		return nil
+	}
+
+	returns := make([]float64, len(prices)-1)
+	for i := 1; i < len(prices); i++ {
+		if prices[i-1] != 0 {
+			returns[i-1] = prices[i]/prices[i-1] - 1
+		}
+	}
+	return returns
+}
+
+// VolatilityFloat calculates annualized historical volatility over the last
+// `period` returns (assuming 252 trading days)
+func VolatilityFloat(prices []float64, period int) (float64, error) {
+	if len(prices) < period+1 {
+		return 0, ErrInsufficientData
+	}
+
+	returns := Returns(prices)
+	if len(returns) > period {
+		returns = returns[len(returns)-period:]
+	}
+
+	return math.Sqrt(stat.Variance(returns, nil)) * math.Sqrt(252), nil
+}
+
+// BetaFloat calculates beta of stock returns relative to market returns over
+// the last `period` returns
+func BetaFloat(stockPrices, marketPrices []float64, period int) (float64, error) {
+	if len(stockPrices) != len(marketPrices) || len(stockPrices) < period+1 {
+		return 0, ErrInsufficientData
+	}
+
+	stockReturns := Returns(stockPrices)
+	marketReturns := Returns(marketPrices)
+	if len(stockReturns) > period {
+		stockReturns = stockReturns[len(stockReturns)-period:]
+		marketReturns = marketReturns[len(marketReturns)-period:]
+	}
+
+	marketVariance := stat.Variance(marketReturns, nil)
+	if marketVariance == 0 {
+		return 0, nil
+	}
+
+	return stat.Covariance(stockReturns, marketReturns, nil) / marketVariance, nil
+}
+
+// windowExtremes returns the highest high and lowest low over [from, to]
+func windowExtremes(highs, lows []float64, from, to int) (highest, lowest float64) {
+	highest, lowest = highs[from], lows[from]
+	for j := from + 1; j <= to; j++ {
+		if highs[j] > highest {
+			highest = highs[j]
+		}
+		if lows[j] < lowest {
+			lowest = lows[j]
+		}
+	}
+	return highest, lowest
+}
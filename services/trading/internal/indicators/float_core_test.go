@@ -0,0 +1,418 @@
+package indicators
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// Benchmarks compare the float64 core against the decimal wrappers and the
+// original decimal-only implementations (kept below as legacy* for reference).
+// Run with: go test -bench . -benchmem ./internal/indicators
+
+// benchBars is roughly 5 years of daily bars
+const benchBars = 1260
+
+func benchSeries(n int) (highs, lows, closes []float64) {
+	rng := rand.New(rand.NewSource(42))
+	price := 100.0
+	for i := 0; i < n; i++ {
+		price *= 1 + rng.NormFloat64()*0.01
+		spread := price * 0.01 * rng.Float64()
+		highs = append(highs, price+spread)
+		lows = append(lows, price-spread)
+		closes = append(closes, price)
+	}
+	return highs, lows, closes
+}
+
+func benchDecimalSeries(n int) (highs, lows, closes []decimal.Decimal) {
+	h, l, c := benchSeries(n)
+	return FloatsToDecimals(h), FloatsToDecimals(l), FloatsToDecimals(c)
+}
+
+func BenchmarkRSI(b *testing.B) {
+	_, _, closes := benchSeries(benchBars)
+	_, _, decCloses := benchDecimalSeries(benchBars)
+
+	b.Run("float64", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			RSIFloat(closes, 14)
+		}
+	})
+	b.Run("decimal_wrapper", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			RSI(decCloses, 14)
+		}
+	})
+	b.Run("decimal_legacy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			legacyRSI(decCloses, 14)
+		}
+	})
+}
+
+func BenchmarkMACD(b *testing.B) {
+	_, _, closes := benchSeries(benchBars)
+	_, _, decCloses := benchDecimalSeries(benchBars)
+
+	b.Run("float64", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			MACDFloat(closes, 12, 26, 9)
+		}
+	})
+	b.Run("decimal_wrapper", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			MACD(decCloses, 12, 26, 9)
+		}
+	})
+	b.Run("decimal_legacy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			legacyMACD(decCloses, 12, 26, 9)
+		}
+	})
+}
+
+func BenchmarkADX(b *testing.B) {
+	highs, lows, closes := benchSeries(benchBars)
+	decHighs, decLows, decCloses := benchDecimalSeries(benchBars)
+
+	b.Run("float64", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ADXFloat(highs, lows, closes, 14)
+		}
+	})
+	b.Run("decimal_wrapper", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ADX(decHighs, decLows, decCloses, 14)
+		}
+	})
+	b.Run("decimal_legacy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			legacyADX(decHighs, decLows, decCloses, 14)
+		}
+	})
+}
+
+func BenchmarkCCI(b *testing.B) {
+	highs, lows, closes := benchSeries(benchBars)
+	decHighs, decLows, decCloses := benchDecimalSeries(benchBars)
+
+	b.Run("float64", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			CCIFloat(highs, lows, closes, 20)
+		}
+	})
+	b.Run("decimal_wrapper", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			CCI(decHighs, decLows, decCloses, 20)
+		}
+	})
+	b.Run("decimal_legacy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			legacyCCI(decHighs, decLows, decCloses, 20)
+		}
+	})
+}
+
+// TestFloatCoreMatchesLegacy checks the float64 core against the original
+// decimal implementations
+func TestFloatCoreMatchesLegacy(t *testing.T) {
+	// Legacy results carry unbounded decimal precision, so keep this short
+	highs, lows, closes := benchSeries(120)
+	decHighs, decLows, decCloses := benchDecimalSeries(120)
+
+	assertClose := func(name string, got []float64, want []decimal.Decimal) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: length %d, want %d", name, len(got), len(want))
+		}
+		for i := range got {
+			w := want[i].InexactFloat64()
+			if math.Abs(got[i]-w) > 1e-6*math.Max(1, math.Abs(w)) {
+				t.Fatalf("%s[%d] = %v, want %v", name, i, got[i], w)
+			}
+		}
+	}
+
+	rsi, _ := RSIFloat(closes, 14)
+	legacyRSIValues, _ := legacyRSI(decCloses, 14)
+	assertClose("RSI", rsi, legacyRSIValues)
+
+	macd, signal, hist, _ := MACDFloat(closes, 12, 26, 9)
+	lMACD, lSignal, lHist, _ := legacyMACD(decCloses, 12, 26, 9)
+	assertClose("MACD", macd, lMACD)
+	assertClose("MACD signal", signal, lSignal)
+	assertClose("MACD histogram", hist, lHist)
+
+	adx, plusDI, minusDI, _ := ADXFloat(highs, lows, closes, 14)
+	lADX, lPlusDI, lMinusDI, _ := legacyADX(decHighs, decLows, decCloses, 14)
+	assertClose("ADX", adx, lADX)
+	assertClose("+DI", plusDI, lPlusDI)
+	assertClose("-DI", minusDI, lMinusDI)
+
+	cci, _ := CCIFloat(highs, lows, closes, 20)
+	lCCI, _ := legacyCCI(decHighs, decLows, decCloses, 20)
+	assertClose("CCI", cci, lCCI)
+}
+
+// Original decimal-only implementations, used as the benchmark baseline
+
+func legacyRSI(prices []decimal.Decimal, period int) ([]decimal.Decimal, error) {
+	if len(prices) < period+1 {
+		return nil, ErrInsufficientData
+	}
+
+	var results []decimal.Decimal
+	var gains, losses []decimal.Decimal
+	var avgGain, avgLoss decimal.Decimal
+
+	// Calculate initial gains and losses
+	for i := 1; i < len(prices); i++ {
+		change := prices[i].Sub(prices[i-1])
+		if change.GreaterThan(decimal.Zero) {
+			gains = append(gains, change)
+			losses = append(losses, decimal.Zero)
+		} else {
+			gains = append(gains, decimal.Zero)
+			losses = append(losses, change.Abs())
+		}
+	}
+
+	// Calculate RSI values
+	for i := period - 1; i < len(gains); i++ {
+		if i == period-1 {
+			// Initial average
+			sumGains := decimal.Zero
+			sumLosses := decimal.Zero
+			for j := 0; j < period; j++ {
+				sumGains = sumGains.Add(gains[j])
+				sumLosses = sumLosses.Add(losses[j])
+			}
+			avgGain = sumGains.Div(decimal.NewFromInt(int64(period)))
+			avgLoss = sumLosses.Div(decimal.NewFromInt(int64(period)))
+		} else {
+			// Smoothed average (Wilder)
+			avgGain = avgGain.Mul(decimal.NewFromInt(int64(period - 1))).Add(gains[i]).Div(decimal.NewFromInt(int64(period)))
+			avgLoss = avgLoss.Mul(decimal.NewFromInt(int64(period - 1))).Add(losses[i]).Div(decimal.NewFromInt(int64(period)))
+		}
+
+		var rsi decimal.Decimal
+		if avgLoss.IsZero() {
+			rsi = decimal.NewFromInt(100)
+		} else {
+			rs := avgGain.Div(avgLoss)
+			rsi = decimal.NewFromInt(100).Sub(decimal.NewFromInt(100).Div(decimal.NewFromInt(1).Add(rs)))
+		}
+
+		results = append(results, rsi)
+	}
+
+	return results, nil
+}
+
+func legacyMACD(prices []decimal.Decimal, fastPeriod, slowPeriod, signalPeriod int) (macdLine, signalLine, histogram []decimal.Decimal, err error) {
+	if len(prices) < slowPeriod {
+		return nil, nil, nil, ErrInsufficientData
+	}
+
+	// Calculate EMAs
+	fastEMA, err := legacyEMA(prices, fastPeriod)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	slowEMA, err := legacyEMA(prices, slowPeriod)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Calculate MACD line
+	startIndex := slowPeriod - fastPeriod
+	for i := startIndex; i < len(fastEMA); i++ {
+		macdValue := fastEMA[i].Sub(slowEMA[i-startIndex])
+		macdLine = append(macdLine, macdValue)
+	}
+
+	// Calculate signal line (EMA of MACD line)
+	signalLine, err = legacyEMA(macdLine, signalPeriod)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Calculate histogram
+	startSignalIndex := signalPeriod - 1
+	for i := startSignalIndex; i < len(macdLine); i++ {
+		histValue := macdLine[i].Sub(signalLine[i-startSignalIndex])
+		histogram = append(histogram, histValue)
+	}
+
+	return macdLine, signalLine, histogram, nil
+}
+
+func legacySMA(prices []decimal.Decimal, period int) ([]decimal.Decimal, error) {
+	if len(prices) < period {
+		return nil, ErrInsufficientData
+	}
+
+	var results []decimal.Decimal
+	for i := period - 1; i < len(prices); i++ {
+		sum := decimal.Zero
+		for j := i - period + 1; j <= i; j++ {
+			sum = sum.Add(prices[j])
+		}
+		average := sum.Div(decimal.NewFromInt(int64(period)))
+		results = append(results, average)
+	}
+
+	return results, nil
+}
+
+func legacyEMA(prices []decimal.Decimal, period int) ([]decimal.Decimal, error) {
+	if len(prices) < period {
+		return nil, ErrInsufficientData
+	}
+
+	multiplier := decimal.NewFromFloat(2.0).Div(decimal.NewFromInt(int64(period + 1)))
+	var results []decimal.Decimal
+
+	// Initialize with SMA
+	sma, err := legacySMA(prices[:period], period)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, sma[0])
+
+	// Calculate EMA
+	for i := period; i < len(prices); i++ {
+		ema := prices[i].Sub(results[len(results)-1]).Mul(multiplier).Add(results[len(results)-1])
+		results = append(results, ema)
+	}
+
+	return results, nil
+}
+
+func legacyADX(highs, lows, closes []decimal.Decimal, period int) (adx, plusDI, minusDI []decimal.Decimal, err error) {
+	if len(highs) != len(lows) || len(lows) != len(closes) || len(closes) < period+1 {
+		return nil, nil, nil, ErrInsufficientData
+	}
+
+	var trueRanges, plusDMs, minusDMs []decimal.Decimal
+
+	// Calculate True Range, +DM, -DM
+	for i := 1; i < len(closes); i++ {
+		// True Range
+		tr1 := highs[i].Sub(lows[i])
+		tr2 := highs[i].Sub(closes[i-1]).Abs()
+		tr3 := lows[i].Sub(closes[i-1]).Abs()
+
+		tr := tr1
+		if tr2.GreaterThan(tr) {
+			tr = tr2
+		}
+		if tr3.GreaterThan(tr) {
+			tr = tr3
+		}
+		trueRanges = append(trueRanges, tr)
+
+		// +DM and -DM
+		upMove := highs[i].Sub(highs[i-1])
+		downMove := lows[i-1].Sub(lows[i])
+
+		var plusDM, minusDM decimal.Decimal
+		if upMove.GreaterThan(downMove) && upMove.GreaterThan(decimal.Zero) {
+			plusDM = upMove
+		}
+		if downMove.GreaterThan(upMove) && downMove.GreaterThan(decimal.Zero) {
+			minusDM = downMove
+		}
+
+		plusDMs = append(plusDMs, plusDM)
+		minusDMs = append(minusDMs, minusDM)
+	}
+
+	// Calculate smoothed TR, +DM, -DM
+	smoothedTR, _ := legacyEMA(trueRanges, period)
+	smoothedPlusDM, _ := legacyEMA(plusDMs, period)
+	smoothedMinusDM, _ := legacyEMA(minusDMs, period)
+
+	// Calculate +DI and -DI
+	for i := 0; i < len(smoothedTR); i++ {
+		var plusDIValue, minusDIValue decimal.Decimal
+
+		if !smoothedTR[i].IsZero() {
+			plusDIValue = smoothedPlusDM[i].Div(smoothedTR[i]).Mul(decimal.NewFromInt(100))
+			minusDIValue = smoothedMinusDM[i].Div(smoothedTR[i]).Mul(decimal.NewFromInt(100))
+		}
+
+		plusDI = append(plusDI, plusDIValue)
+		minusDI = append(minusDI, minusDIValue)
+	}
+
+	// Calculate DX and ADX
+	var dxValues []decimal.Decimal
+	for i := 0; i < len(plusDI); i++ {
+		diSum := plusDI[i].Add(minusDI[i])
+		diDiff := plusDI[i].Sub(minusDI[i]).Abs()
+
+		var dx decimal.Decimal
+		if !diSum.IsZero() {
+			dx = diDiff.Div(diSum).Mul(decimal.NewFromInt(100))
+		}
+		dxValues = append(dxValues, dx)
+	}
+
+	// ADX is EMA of DX
+	adx, err = legacyEMA(dxValues, period)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return adx, plusDI, minusDI, nil
+}
+
+func legacyCCI(highs, lows, closes []decimal.Decimal, period int) ([]decimal.Decimal, error) {
+	if len(highs) != len(lows) || len(lows) != len(closes) || len(closes) < period {
+		return nil, ErrInsufficientData
+	}
+
+	// Calculate Typical Price
+	var typicalPrices []decimal.Decimal
+	for i := 0; i < len(closes); i++ {
+		tp := highs[i].Add(lows[i]).Add(closes[i]).Div(decimal.NewFromInt(3))
+		typicalPrices = append(typicalPrices, tp)
+	}
+
+	// Calculate SMA of Typical Price
+	smaTP, err := legacySMA(typicalPrices, period)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []decimal.Decimal
+	constant := decimal.NewFromFloat(0.015)
+
+	// Calculate CCI
+	for i := period - 1; i < len(typicalPrices); i++ {
+		// Calculate Mean Deviation
+		var meanDev decimal.Decimal
+		smaValue := smaTP[i-period+1]
+
+		for j := i - period + 1; j <= i; j++ {
+			meanDev = meanDev.Add(typicalPrices[j].Sub(smaValue).Abs())
+		}
+		meanDev = meanDev.Div(decimal.NewFromInt(int64(period)))
+
+		// Calculate CCI
+		var cci decimal.Decimal
+		if !meanDev.IsZero() {
+			cci = typicalPrices[i].Sub(smaValue).Div(constant.Mul(meanDev))
+		}
+
+		results = append(results, cci)
+	}
+
+	return results, nil
+}
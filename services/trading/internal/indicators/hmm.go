@@ -0,0 +1,247 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// GaussianHMM is a hidden Markov model with one-dimensional Gaussian
+// emissions, e.g. a return distribution per market state
+type GaussianHMM struct {
+	Initial    []float64   `json:"initial"`
+	Transition [][]float64 `json:"transition"` // Transition[i][j] = P(state j next | state i now)
+	Means      []float64   `json:"means"`
+	Variances  []float64   `json:"variances"`
+}
+
+// FitGaussianHMM estimates a model with the given number of states by
+// Baum-Welch (EM). States start as quantile groups of |x|, so state 0 begins
+// as the calmest and the last as the most volatile. It returns the model and
+// the final log-likelihood.
+func FitGaussianHMM(x []float64, states, maxIterations int) (*GaussianHMM, float64, error) {
+	if states < 2 {
+		return nil, 0, fmt.Errorf("need at least 2 states")
+	}
+	if len(x) < states*10 {
+		return nil, 0, ErrInsufficientData
+	}
+
+	_, overallVar := stat.MeanVariance(x, nil)
+	if overallVar == 0 {
+		return nil, 0, fmt.Errorf("series is constant")
+	}
+	// Floor variances so no state collapses onto a handful of observations
+	varianceFloor := overallVar * 1e-2
+
+	// Initialize from groups of observations sorted by magnitude
+	order := make([]int, len(x))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return math.Abs(x[order[a]]) < math.Abs(x[order[b]]) })
+
+	model := &GaussianHMM{
+		Initial:    make([]float64, states),
+		Transition: make([][]float64, states),
+		Means:      make([]float64, states),
+		Variances:  make([]float64, states),
+	}
+	group := make([]float64, 0, len(x)/states+1)
+	for s := 0; s < states; s++ {
+		group = group[:0]
+		for _, i := range order[s*len(x)/states : (s+1)*len(x)/states] {
+			group = append(group, x[i])
+		}
+		model.Means[s], model.Variances[s] = stat.MeanVariance(group, nil)
+		model.Variances[s] = math.Max(model.Variances[s], varianceFloor)
+		model.Initial[s] = 1 / float64(states)
+		model.Transition[s] = make([]float64, states)
+		for t := range model.Transition[s] {
+			model.Transition[s][t] = 0.1 / float64(states-1)
+		}
+		model.Transition[s][s] = 0.9
+	}
+
+	logLikelihood := math.Inf(-1)
+	for iter := 0; iter < maxIterations; iter++ {
+		gamma, xi, ll := model.forwardBackward(x)
+
+		// Re-estimate parameters from the expected state occupancy
+		for s := 0; s < states; s++ {
+			model.Initial[s] = gamma[0][s]
+
+			var occupancy, weighted float64
+			for t := range x {
+				occupancy += gamma[t][s]
+				weighted += gamma[t][s] * x[t]
+			}
+			if occupancy < 1e-12 {
+				continue // Empty state; keep its previous parameters
+			}
+			model.Means[s] = weighted / occupancy
+
+			var spread float64
+			for t := range x {
+				d := x[t] - model.Means[s]
+				spread += gamma[t][s] * d * d
+			}
+			model.Variances[s] = math.Max(spread/occupancy, varianceFloor)
+
+			var leaving float64
+			for u := 0; u < states; u++ {
+				leaving += xi[s][u]
+			}
+			if leaving > 0 {
+				for u := 0; u < states; u++ {
+					model.Transition[s][u] = xi[s][u] / leaving
+				}
+			}
+		}
+
+		converged := ll-logLikelihood < 1e-6
+		logLikelihood = ll
+		if converged {
+			break
+		}
+	}
+	return model, logLikelihood, nil
+}
+
+// density is the Gaussian emission density of state s at v
+func (m *GaussianHMM) density(s int, v float64) float64 {
+	d := v - m.Means[s]
+	return math.Exp(-d*d/(2*m.Variances[s])) / math.Sqrt(2*math.Pi*m.Variances[s])
+}
+
+// forwardBackward runs the scaled forward-backward pass, returning state
+// posteriors per observation, expected transition counts and the
+// log-likelihood
+func (m *GaussianHMM) forwardBackward(x []float64) (gamma [][]float64, xi [][]float64, logLikelihood float64) {
+	n, states := len(x), len(m.Means)
+	alpha := make([][]float64, n)
+	beta := make([][]float64, n)
+	scale := make([]float64, n)
+
+	for t := 0; t < n; t++ {
+		alpha[t] = make([]float64, states)
+		for s := 0; s < states; s++ {
+			if t == 0 {
+				alpha[t][s] = m.Initial[s]
+			} else {
+				for u := 0; u < states; u++ {
+					alpha[t][s] += alpha[t-1][u] * m.Transition[u][s]
+				}
+			}
+			alpha[t][s] *= m.density(s, x[t])
+			scale[t] += alpha[t][s]
+		}
+		if scale[t] == 0 {
+			scale[t] = 1e-300 // Observation is impossible under every state; avoid dividing by zero
+		}
+		for s := range alpha[t] {
+			alpha[t][s] /= scale[t]
+		}
+		logLikelihood += math.Log(scale[t])
+	}
+
+	beta[n-1] = make([]float64, states)
+	for s := range beta[n-1] {
+		beta[n-1][s] = 1
+	}
+	for t := n - 2; t >= 0; t-- {
+		beta[t] = make([]float64, states)
+		for s := 0; s < states; s++ {
+			for u := 0; u < states; u++ {
+				beta[t][s] += m.Transition[s][u] * m.density(u, x[t+1]) * beta[t+1][u]
+			}
+			beta[t][s] /= scale[t+1]
+		}
+	}
+
+	gamma = make([][]float64, n)
+	xi = make([][]float64, states)
+	for s := range xi {
+		xi[s] = make([]float64, states)
+	}
+	for t := 0; t < n; t++ {
+		gamma[t] = make([]float64, states)
+		var total float64
+		for s := 0; s < states; s++ {
+			gamma[t][s] = alpha[t][s] * beta[t][s]
+			total += gamma[t][s]
+		}
+		for s := range gamma[t] {
+			if total > 0 {
+				gamma[t][s] /= total
+			}
+		}
+
+		if t+1 < n {
+			for s := 0; s < states; s++ {
+				for u := 0; u < states; u++ {
+					xi[s][u] += alpha[t][s] * m.Transition[s][u] * m.density(u, x[t+1]) * beta[t+1][u] / scale[t+1]
+				}
+			}
+		}
+	}
+	return gamma, xi, logLikelihood
+}
+
+// Posteriors returns P(state | all observations) for each observation
+func (m *GaussianHMM) Posteriors(x []float64) [][]float64 {
+	if len(x) == 0 {
+		return nil
+	}
+	gamma, _, _ := m.forwardBackward(x)
+	return gamma
+}
+
+// Viterbi returns the most likely state sequence
+func (m *GaussianHMM) Viterbi(x []float64) []int {
+	n, states := len(x), len(m.Means)
+	if n == 0 {
+		return nil
+	}
+
+	logOf := func(v float64) float64 {
+		if v <= 0 {
+			return math.Inf(-1)
+		}
+		return math.Log(v)
+	}
+
+	score := make([]float64, states)
+	for s := 0; s < states; s++ {
+		score[s] = logOf(m.Initial[s]) + logOf(m.density(s, x[0]))
+	}
+	backPointers := make([][]int, n)
+	for t := 1; t < n; t++ {
+		backPointers[t] = make([]int, states)
+		next := make([]float64, states)
+		for s := 0; s < states; s++ {
+			best, bestFrom := math.Inf(-1), 0
+			for u := 0; u < states; u++ {
+				if v := score[u] + logOf(m.Transition[u][s]); v > best {
+					best, bestFrom = v, u
+				}
+			}
+			next[s] = best + logOf(m.density(s, x[t]))
+			backPointers[t][s] = bestFrom
+		}
+		score = next
+	}
+
+	path := make([]int, n)
+	for s := 1; s < states; s++ {
+		if score[s] > score[path[n-1]] {
+			path[n-1] = s
+		}
+	}
+	for t := n - 1; t > 0; t-- {
+		path[t-1] = backPointers[t][path[t]]
+	}
+	return path
+}
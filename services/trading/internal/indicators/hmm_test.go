@@ -0,0 +1,110 @@
+package indicators
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// regimeSwitching draws observations from three states in runs of 100:
+// calm, drifting up with the same noise, and volatile
+func regimeSwitching(seed int64) (x []float64, states []int) {
+	means := []float64{0, 0.003, 0}
+	stdDevs := []float64{0.001, 0.001, 0.01}
+	rng := rand.New(rand.NewSource(seed))
+	for _, s := range []int{0, 1, 2, 0, 2, 1, 0, 1, 2} {
+		for i := 0; i < 100; i++ {
+			x = append(x, means[s]+stdDevs[s]*rng.NormFloat64())
+			states = append(states, s)
+		}
+	}
+	return x, states
+}
+
+func TestFitGaussianHMMRecoversStates(t *testing.T) {
+	x, truth := regimeSwitching(3)
+	model, logLikelihood, err := FitGaussianHMM(x, 3, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.IsInf(logLikelihood, 0) || math.IsNaN(logLikelihood) {
+		t.Fatalf("log-likelihood = %v", logLikelihood)
+	}
+
+	// The labels name the fitted states after the generating ones
+	labels := hmmRegimeLabels(model)
+	want := []string{RegimeRanging, RegimeTrending, RegimeHighVolatility}
+	path := model.Viterbi(x)
+	var correct int
+	for i := range x {
+		if labels[path[i]] == want[truth[i]] {
+			correct++
+		}
+	}
+	if accuracy := float64(correct) / float64(len(x)); accuracy < 0.95 {
+		t.Fatalf("decoded %.1f%% of states correctly, want at least 95%%", accuracy*100)
+	}
+
+	for s := range model.Means {
+		switch labels[s] {
+		case RegimeTrending:
+			if math.Abs(model.Means[s]-0.003) > 0.0005 {
+				t.Fatalf("trending mean = %v, want about 0.003", model.Means[s])
+			}
+		case RegimeHighVolatility:
+			if sd := math.Sqrt(model.Variances[s]); math.Abs(sd-0.01) > 0.002 {
+				t.Fatalf("volatile standard deviation = %v, want about 0.01", sd)
+			}
+		}
+		var row float64
+		for _, p := range model.Transition[s] {
+			row += p
+		}
+		if math.Abs(row-1) > 1e-9 || model.Transition[s][s] < 0.9 {
+			t.Fatalf("transitions from %d = %v, want a sticky distribution", s, model.Transition[s])
+		}
+	}
+
+	for i, p := range model.Posteriors(x) {
+		var total float64
+		for _, v := range p {
+			total += v
+		}
+		if math.Abs(total-1) > 1e-9 {
+			t.Fatalf("posteriors at %d sum to %v", i, total)
+		}
+	}
+}
+
+func TestViterbi(t *testing.T) {
+	model := &GaussianHMM{
+		Initial:    []float64{0.5, 0.5},
+		Transition: [][]float64{{0.9, 0.1}, {0.1, 0.9}},
+		Means:      []float64{0, 1},
+		Variances:  []float64{0.04, 0.04},
+	}
+	// The lone 0.6 is closer to state 1 but not worth two switches
+	path := model.Viterbi([]float64{0.1, -0.1, 0.6, 0, 0.9, 1.1, 1})
+	want := []int{0, 0, 0, 0, 1, 1, 1}
+	for i := range want {
+		if path[i] != want[i] {
+			t.Fatalf("path = %v, want %v", path, want)
+		}
+	}
+	if model.Viterbi(nil) != nil || model.Posteriors(nil) != nil {
+		t.Fatal("an empty series should decode to nothing")
+	}
+}
+
+func TestFitGaussianHMMErrors(t *testing.T) {
+	x, _ := regimeSwitching(1)
+	if _, _, err := FitGaussianHMM(x, 1, 10); err == nil {
+		t.Fatal("a single state should be rejected")
+	}
+	if _, _, err := FitGaussianHMM(x[:29], 3, 10); err != ErrInsufficientData {
+		t.Fatalf("error = %v, want ErrInsufficientData", err)
+	}
+	if _, _, err := FitGaussianHMM(make([]float64, 100), 3, 10); err == nil {
+		t.Fatal("a constant series should be rejected")
+	}
+}
@@ -0,0 +1,285 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// Market regimes
+const (
+	RegimeTrending       = "trending"
+	RegimeRanging        = "ranging"
+	RegimeHighVolatility = "high_volatility"
+)
+
+// Regime classification methods
+const (
+	RegimeMethodThreshold = "threshold" // ADX and relative volatility thresholds
+	RegimeMethodHMM       = "hmm"       // Three-state Gaussian HMM on returns
+)
+
+const hmmStates = 3
+
+// RegimeConfig controls regime classification
+type RegimeConfig struct {
+	Method              string  `json:"method"`                // threshold (default) or hmm
+	ADXPeriod           int     `json:"adx_period"`            // Default 14
+	TrendADX            float64 `json:"trend_adx"`             // ADX at or above which a market is trending, default 25
+	RangeADX            float64 `json:"range_adx"`             // ADX below which a market is ranging, default 20; in between the previous label holds
+	VolatilityPeriod    int     `json:"volatility_period"`     // Returns in the volatility window, default 20
+	VolatilityLookback  int     `json:"volatility_lookback"`   // Bars in the baseline volatility median, default 250
+	HighVolatilityRatio float64 `json:"high_volatility_ratio"` // Volatility over baseline that marks high volatility, default 1.5
+	HMMSmoothing        int     `json:"hmm_smoothing"`         // Returns averaged into each HMM observation, default 5
+	HMMIterations       int     `json:"hmm_iterations"`        // Baum-Welch iterations, default 100
+	CompareHMM          bool    `json:"compare_hmm"`           // Also fit the HMM under the threshold method to report both views
+}
+
+// DefaultRegimeConfig returns the default classification settings
+func DefaultRegimeConfig() RegimeConfig {
+	return RegimeConfig{
+		Method:              RegimeMethodThreshold,
+		ADXPeriod:           14,
+		TrendADX:            25,
+		RangeADX:            20,
+		VolatilityPeriod:    20,
+		VolatilityLookback:  250,
+		HighVolatilityRatio: 1.5,
+		HMMSmoothing:        5,
+		HMMIterations:       100,
+	}
+}
+
+// withDefaults fills zero fields from DefaultRegimeConfig
+func (c RegimeConfig) withDefaults() RegimeConfig {
+	d := DefaultRegimeConfig()
+	if c.Method == "" {
+		c.Method = d.Method
+	}
+	if c.ADXPeriod <= 0 {
+		c.ADXPeriod = d.ADXPeriod
+	}
+	if c.TrendADX <= 0 {
+		c.TrendADX = d.TrendADX
+	}
+	if c.RangeADX <= 0 {
+		c.RangeADX = d.RangeADX
+	}
+	if c.VolatilityPeriod <= 1 {
+		c.VolatilityPeriod = d.VolatilityPeriod
+	}
+	if c.VolatilityLookback <= 0 {
+		c.VolatilityLookback = d.VolatilityLookback
+	}
+	if c.HighVolatilityRatio <= 0 {
+		c.HighVolatilityRatio = d.HighVolatilityRatio
+	}
+	if c.HMMSmoothing <= 0 {
+		c.HMMSmoothing = d.HMMSmoothing
+	}
+	if c.HMMIterations <= 0 {
+		c.HMMIterations = d.HMMIterations
+	}
+	return c
+}
+
+// RegimeBar is the classification of one bar. Regime comes from the
+// configured method; the HMM fields are only set when the HMM is fitted.
+type RegimeBar struct {
+	Date            time.Time `json:"date"`
+	Regime          string    `json:"regime"`
+	Direction       string    `json:"direction,omitempty"` // up or down when trending
+	ADX             float64   `json:"adx"`
+	Volatility      float64   `json:"volatility"`       // Standard deviation of recent returns
+	VolatilityRatio float64   `json:"volatility_ratio"` // Volatility over its baseline median
+	ThresholdRegime string    `json:"threshold_regime"`
+	HMMState        int       `json:"hmm_state"`
+	HMMRegime       string    `json:"hmm_regime,omitempty"`
+	HMMProbability  float64   `json:"hmm_probability,omitempty"` // Posterior probability of HMMState
+}
+
+// RegimeAnalysis labels each bar with a market regime
+type RegimeAnalysis struct {
+	Method       string             `json:"method"`
+	Current      string             `json:"current"`
+	Direction    string             `json:"direction,omitempty"`
+	Since        time.Time          `json:"since"` // First bar of the current regime run
+	Distribution map[string]float64 `json:"distribution"`
+	Config       RegimeConfig       `json:"config"`
+	HMM          *GaussianHMM       `json:"hmm,omitempty"`
+	HMMRegimes   []string           `json:"hmm_regimes,omitempty"` // Regime assigned to each HMM state
+	Bars         []RegimeBar        `json:"bars"`
+}
+
+// DetectRegimes classifies every bar once ADX is available. The threshold
+// method marks high volatility first, then trending or ranging by ADX with
+// hysteresis between RangeADX and TrendADX. The HMM is only fitted for the
+// hmm method, where it decides the label, or when CompareHMM asks for it.
+func DetectRegimes(bars *Bars, cfg RegimeConfig) (*RegimeAnalysis, error) {
+	cfg = cfg.withDefaults()
+	if cfg.Method != RegimeMethodThreshold && cfg.Method != RegimeMethodHMM {
+		return nil, fmt.Errorf("unknown regime method %q (expected %s or %s)", cfg.Method, RegimeMethodThreshold, RegimeMethodHMM)
+	}
+	if cfg.RangeADX > cfg.TrendADX {
+		return nil, fmt.Errorf("range_adx (%v) must not exceed trend_adx (%v)", cfg.RangeADX, cfg.TrendADX)
+	}
+
+	n := bars.Len()
+	adx, plusDI, minusDI, err := ADXFloat(bars.High, bars.Low, bars.Close, cfg.ADXPeriod)
+	if err != nil || len(adx) == 0 || n < cfg.VolatilityPeriod+1 {
+		return nil, ErrInsufficientData
+	}
+	adxStart := n - len(adx)
+	diStart := n - len(plusDI)
+
+	returns := Returns(bars.Close)
+	volatility := make([]float64, n)
+	for i := cfg.VolatilityPeriod; i < n; i++ {
+		volatility[i] = stat.StdDev(returns[i-cfg.VolatilityPeriod:i], nil)
+	}
+
+	start := adxStart
+	if cfg.VolatilityPeriod > start {
+		start = cfg.VolatilityPeriod
+	}
+
+	analysis := &RegimeAnalysis{
+		Method:       cfg.Method,
+		Config:       cfg,
+		Distribution: make(map[string]float64),
+	}
+
+	// The HMM observes the mean of the last HMMSmoothing returns, which lets
+	// a persistent drift stand out from bar-to-bar noise. Observation k ends
+	// at bar k+HMMSmoothing.
+	var observations []float64
+	if cfg.Method == RegimeMethodHMM || cfg.CompareHMM {
+		for k := cfg.HMMSmoothing; k <= len(returns); k++ {
+			observations = append(observations, stat.Mean(returns[k-cfg.HMMSmoothing:k], nil))
+		}
+	}
+	var hmmStatesByBar []int
+	var posteriors [][]float64
+	if len(observations) >= hmmStates*20 {
+		model, _, err := FitGaussianHMM(observations, hmmStates, cfg.HMMIterations)
+		if err == nil {
+			analysis.HMM = model
+			analysis.HMMRegimes = hmmRegimeLabels(model)
+			hmmStatesByBar = model.Viterbi(observations)
+			posteriors = model.Posteriors(observations)
+		}
+	}
+	if cfg.Method == RegimeMethodHMM && analysis.HMM == nil {
+		return nil, fmt.Errorf("HMM regime detection needs at least %d bars", hmmStates*20+cfg.HMMSmoothing)
+	}
+
+	window := make([]float64, 0, cfg.VolatilityLookback)
+	previous := ""
+	for i := start; i < n; i++ {
+		bar := RegimeBar{
+			Date:       bars.Dates[i],
+			ADX:        adx[i-adxStart],
+			Volatility: volatility[i],
+		}
+
+		// Baseline is the median volatility over the lookback, this bar included
+		window = window[:0]
+		for j := i; j >= cfg.VolatilityPeriod && j > i-cfg.VolatilityLookback; j-- {
+			window = append(window, volatility[j])
+		}
+		sort.Float64s(window)
+		if median := window[len(window)/2]; median > 0 {
+			bar.VolatilityRatio = volatility[i] / median
+		}
+
+		switch {
+		case bar.VolatilityRatio >= cfg.HighVolatilityRatio:
+			bar.ThresholdRegime = RegimeHighVolatility
+		case bar.ADX >= cfg.TrendADX:
+			bar.ThresholdRegime = RegimeTrending
+		case bar.ADX < cfg.RangeADX:
+			bar.ThresholdRegime = RegimeRanging
+		case previous == RegimeTrending:
+			bar.ThresholdRegime = RegimeTrending
+		default:
+			bar.ThresholdRegime = RegimeRanging
+		}
+		previous = bar.ThresholdRegime
+		bar.Regime = bar.ThresholdRegime
+
+		if k := i - cfg.HMMSmoothing; hmmStatesByBar != nil && k >= 0 {
+			state := hmmStatesByBar[k]
+			bar.HMMState = state
+			bar.HMMRegime = analysis.HMMRegimes[state]
+			bar.HMMProbability = posteriors[k][state]
+			if cfg.Method == RegimeMethodHMM {
+				bar.Regime = bar.HMMRegime
+			}
+		}
+
+		if bar.Regime == RegimeTrending {
+			bar.Direction = "up"
+			if cfg.Method == RegimeMethodHMM {
+				if analysis.HMM.Means[bar.HMMState] < 0 {
+					bar.Direction = "down"
+				}
+			} else if plusDI[i-diStart] < minusDI[i-diStart] {
+				bar.Direction = "down"
+			}
+		}
+
+		analysis.Bars = append(analysis.Bars, bar)
+		analysis.Distribution[bar.Regime]++
+	}
+
+	for regime := range analysis.Distribution {
+		analysis.Distribution[regime] /= float64(len(analysis.Bars))
+	}
+
+	last := analysis.Bars[len(analysis.Bars)-1]
+	analysis.Current = last.Regime
+	analysis.Direction = last.Direction
+	analysis.Since = last.Date
+	for i := len(analysis.Bars) - 1; i >= 0 && analysis.Bars[i].Regime == last.Regime; i-- {
+		analysis.Since = analysis.Bars[i].Date
+	}
+	return analysis, nil
+}
+
+// hmmRegimeLabels names the HMM states: the most volatile is high
+// volatility, and of the others the one with the larger drift relative to
+// its noise is trending
+func hmmRegimeLabels(model *GaussianHMM) []string {
+	labels := make([]string, len(model.Means))
+	volatile := 0
+	for s := range model.Variances {
+		if model.Variances[s] > model.Variances[volatile] {
+			volatile = s
+		}
+	}
+
+	trending, bestDrift := -1, -1.0
+	for s := range model.Means {
+		if s == volatile {
+			continue
+		}
+		if drift := math.Abs(model.Means[s]) / math.Sqrt(model.Variances[s]); drift > bestDrift {
+			trending, bestDrift = s, drift
+		}
+	}
+
+	for s := range labels {
+		switch s {
+		case volatile:
+			labels[s] = RegimeHighVolatility
+		case trending:
+			labels[s] = RegimeTrending
+		default:
+			labels[s] = RegimeRanging
+		}
+	}
+	return labels
+}
@@ -0,0 +1,216 @@
+package indicators
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// chopTrendChop random-walks for 80 bars, climbs a point a bar for 80,
+// then random-walks again, so ADX rises and decays through the 20-25 band
+func chopTrendChop() []float64 {
+	rng := rand.New(rand.NewSource(1))
+	closes := make([]float64, 300)
+	p := 100.0
+	for i := range closes {
+		if i >= 80 && i < 160 {
+			p += 1 + rng.NormFloat64()*0.3
+		} else {
+			p += rng.NormFloat64() * 0.5
+		}
+		closes[i] = p
+	}
+	return closes
+}
+
+func TestDetectRegimesHysteresis(t *testing.T) {
+	cfg := DefaultRegimeConfig()
+	cfg.HighVolatilityRatio = 100 // Never volatile enough to override
+	analysis, err := DetectRegimes(closeBars(chopTrendChop()), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var heldTrend, heldRange int
+	previous := ""
+	for i, bar := range analysis.Bars {
+		want := RegimeRanging
+		switch {
+		case bar.ADX >= cfg.TrendADX:
+			want = RegimeTrending
+		case bar.ADX >= cfg.RangeADX && previous == RegimeTrending:
+			want = RegimeTrending
+			heldTrend++
+		case bar.ADX >= cfg.RangeADX:
+			heldRange++
+		}
+		if bar.ThresholdRegime != want || bar.Regime != want {
+			t.Fatalf("bar %d: ADX %.1f after %q labelled %q, want %q", i, bar.ADX, previous, bar.Regime, want)
+		}
+		if bar.Regime == RegimeTrending && bar.Direction == "" || bar.Regime != RegimeTrending && bar.Direction != "" {
+			t.Fatalf("bar %d: %s with direction %q", i, bar.Regime, bar.Direction)
+		}
+		previous = bar.Regime
+	}
+	if heldTrend == 0 || heldRange == 0 {
+		t.Fatalf("%d bars held trending and %d held ranging in the band, want both", heldTrend, heldRange)
+	}
+
+	// Without a band the same bars split at 25
+	cfg.RangeADX = cfg.TrendADX
+	flat, err := DetectRegimes(closeBars(chopTrendChop()), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, bar := range flat.Bars {
+		if (bar.ADX >= cfg.TrendADX) != (bar.Regime == RegimeTrending) {
+			t.Fatalf("bar %d: ADX %.1f labelled %q without hysteresis", i, bar.ADX, bar.Regime)
+		}
+	}
+
+	var total float64
+	for _, share := range analysis.Distribution {
+		total += share
+	}
+	if total < 1-1e-9 || total > 1+1e-9 {
+		t.Fatalf("distribution %v sums to %v, want 1", analysis.Distribution, total)
+	}
+	last := analysis.Bars[len(analysis.Bars)-1]
+	if analysis.Current != last.Regime || analysis.Since.After(last.Date) {
+		t.Fatalf("current %q since %v, want the last bar's %q", analysis.Current, analysis.Since, last.Regime)
+	}
+}
+
+func TestDetectRegimesHighVolatilityOverride(t *testing.T) {
+	// 250 calm bars, then a rally three times as noisy
+	rng := rand.New(rand.NewSource(2))
+	closes := make([]float64, 320)
+	p := 100.0
+	for i := range closes {
+		if i < 250 {
+			p *= 1 + rng.NormFloat64()*0.005
+		} else {
+			p *= 1 + 0.01 + rng.NormFloat64()*0.015
+		}
+		closes[i] = p
+	}
+	cfg := DefaultRegimeConfig()
+	analysis, err := DetectRegimes(closeBars(closes), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var overridden int
+	for i, bar := range analysis.Bars {
+		volatile := bar.VolatilityRatio >= cfg.HighVolatilityRatio
+		if volatile != (bar.Regime == RegimeHighVolatility) {
+			t.Fatalf("bar %d: volatility ratio %.2f labelled %q", i, bar.VolatilityRatio, bar.Regime)
+		}
+		if volatile && bar.ADX >= cfg.TrendADX {
+			overridden++
+		}
+	}
+	if overridden == 0 || analysis.Current != RegimeHighVolatility {
+		t.Fatalf("%d trending bars overridden, current %q, want the rally labelled high volatility", overridden, analysis.Current)
+	}
+
+	// Raising the bar lets the rally count as an up trend
+	cfg.HighVolatilityRatio = 100
+	analysis, err = DetectRegimes(closeBars(closes), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.Current != RegimeTrending || analysis.Direction != "up" {
+		t.Fatalf("current %q %q, want an up trend", analysis.Current, analysis.Direction)
+	}
+}
+
+func TestDetectRegimesHMMMethod(t *testing.T) {
+	closes := chopTrendChop()
+	analysis, err := DetectRegimes(closeBars(closes), RegimeConfig{Method: RegimeMethodHMM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.HMM == nil || len(analysis.HMMRegimes) != hmmStates {
+		t.Fatalf("hmm %+v regimes %v, want a fitted three-state model", analysis.HMM, analysis.HMMRegimes)
+	}
+	for i, bar := range analysis.Bars {
+		if bar.Regime != bar.HMMRegime || bar.HMMRegime != analysis.HMMRegimes[bar.HMMState] || bar.HMMProbability <= 0 || bar.HMMProbability > 1 {
+			t.Fatalf("bar %d = %+v, want the HMM's label and a posterior probability", i, bar)
+		}
+		if bar.Regime == RegimeTrending && (bar.Direction == "down") != (analysis.HMM.Means[bar.HMMState] < 0) {
+			t.Fatalf("bar %d: direction %q with state mean %v", i, bar.Direction, analysis.HMM.Means[bar.HMMState])
+		}
+	}
+}
+
+func TestDetectRegimesFitsHMMOnRequest(t *testing.T) {
+	bars := closeBars(chopTrendChop())
+	threshold, err := DetectRegimes(bars, DefaultRegimeConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if threshold.HMM != nil || threshold.HMMRegimes != nil {
+		t.Fatalf("threshold method fitted an HMM: %+v", threshold.HMM)
+	}
+	for i, bar := range threshold.Bars {
+		if bar.HMMRegime != "" || bar.HMMProbability != 0 {
+			t.Fatalf("bar %d = %+v, want no HMM view", i, bar)
+		}
+	}
+
+	cfg := DefaultRegimeConfig()
+	cfg.CompareHMM = true
+	compared, err := DetectRegimes(bars, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compared.HMM == nil || compared.Current != threshold.Current {
+		t.Fatalf("compared current %q hmm %v, want the threshold label %q beside a fitted HMM", compared.Current, compared.HMM, threshold.Current)
+	}
+	for i, bar := range compared.Bars {
+		if bar.Regime != threshold.Bars[i].Regime || bar.HMMRegime == "" {
+			t.Fatalf("bar %d = %+v, want the threshold label and an HMM label", i, bar)
+		}
+	}
+}
+
+func TestDetectRegimesErrors(t *testing.T) {
+	bars := closeBars(chopTrendChop())
+	tests := []struct {
+		name string
+		bars *Bars
+		cfg  RegimeConfig
+	}{
+		{"unknown method", bars, RegimeConfig{Method: "tea_leaves"}},
+		{"range above trend", bars, RegimeConfig{TrendADX: 20, RangeADX: 25}},
+		{"too few bars", closeBars(chopTrendChop()[:20]), RegimeConfig{}},
+		{"too few for the HMM", closeBars(chopTrendChop()[:60]), RegimeConfig{Method: RegimeMethodHMM}},
+	}
+	for _, tt := range tests {
+		if _, err := DetectRegimes(tt.bars, tt.cfg); err == nil {
+			t.Fatalf("%s: no error", tt.name)
+		}
+	}
+}
+
+func TestHMMRegimeLabels(t *testing.T) {
+	tests := []struct {
+		name      string
+		means     []float64
+		variances []float64
+		want      []string
+	}{
+		// State 1 drifts two noise units a step, state 0 one
+		{"drift over noise", []float64{0.001, -0.004, 0}, []float64{1e-6, 4e-6, 1e-4}, []string{RegimeRanging, RegimeTrending, RegimeHighVolatility}},
+		// The most volatile state is never trending, however strong its drift
+		{"volatile first", []float64{0.05, 0, 0.002}, []float64{1e-4, 1e-6, 1e-6}, []string{RegimeHighVolatility, RegimeRanging, RegimeTrending}},
+	}
+	for _, tt := range tests {
+		got := hmmRegimeLabels(&GaussianHMM{Means: tt.means, Variances: tt.variances})
+		for s := range tt.want {
+			if got[s] != tt.want[s] {
+				t.Fatalf("%s: labels = %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// Pivot point calculation methods
+const (
+	PivotClassic   = "classic"
+	PivotFibonacci = "fibonacci"
+	PivotCamarilla = "camarilla"
+)
+
+// Price level types
+const (
+	LevelSupport    = "SUPPORT"
+	LevelResistance = "RESISTANCE"
+)
+
+// Swing point types
+const (
+	SwingHigh = "HIGH"
+	SwingLow  = "LOW"
+)
+
+// PivotPoints represents floor pivot levels projected from a completed bar.
+// R4/S4 are only populated by the Camarilla method.
+type PivotPoints struct {
+	Method string          `json:"method"`
+	Pivot  decimal.Decimal `json:"pivot"`
+	R1     decimal.Decimal `json:"r1"`
+	R2     decimal.Decimal `json:"r2"`
+	R3     decimal.Decimal `json:"r3"`
+	R4     decimal.Decimal `json:"r4,omitempty"`
+	S1     decimal.Decimal `json:"s1"`
+	S2     decimal.Decimal `json:"s2"`
+	S3     decimal.Decimal `json:"s3"`
+	S4     decimal.Decimal `json:"s4,omitempty"`
+}
+
+// SwingPoint represents a local price extreme
+type SwingPoint struct {
+	Index int             `json:"index"`
+	Price decimal.Decimal `json:"price"`
+	Type  string          `json:"type"` // HIGH, LOW
+}
+
+// VolumeNode represents traded volume aggregated into a price bin
+type VolumeNode struct {
+	Price  decimal.Decimal `json:"price"`
+	Volume int64           `json:"volume"`
+}
+
+// PriceLevel represents a support or resistance zone
+type PriceLevel struct {
+	Price    decimal.Decimal `json:"price"`
+	Low      decimal.Decimal `json:"low"`  // Lower edge of the zone
+	High     decimal.Decimal `json:"high"` // Upper edge of the zone
+	Type     string          `json:"type"` // SUPPORT, RESISTANCE
+	Touches  int             `json:"touches"`
+	Volume   int64           `json:"volume"`
+	Strength float64         `json:"strength"` // 0-1, relative to the strongest level found
+	Sources  []string        `json:"sources"`  // SWING, VOLUME
+}
+
+// CalculatePivotPoints calculates pivot levels from a bar's high, low and close
+func CalculatePivotPoints(high, low, close decimal.Decimal, method string) (*PivotPoints, error) {
+	if high.LessThan(low) {
+		return nil, fmt.Errorf("high %s is below low %s", high, low)
+	}
+
+	three := decimal.NewFromInt(3)
+	two := decimal.NewFromInt(2)
+	pivot := high.Add(low).Add(close).Div(three)
+	rng := high.Sub(low)
+
+	result := &PivotPoints{Method: method, Pivot: pivot}
+
+	switch method {
+	case PivotClassic:
+		result.R1 = pivot.Mul(two).Sub(low)
+		result.S1 = pivot.Mul(two).Sub(high)
+		result.R2 = pivot.Add(rng)
+		result.S2 = pivot.Sub(rng)
+		result.R3 = high.Add(pivot.Sub(low).Mul(two))
+		result.S3 = low.Sub(high.Sub(pivot).Mul(two))
+
+	case PivotFibonacci:
+		result.R1 = pivot.Add(rng.Mul(decimal.NewFromFloat(0.382)))
+		result.R2 = pivot.Add(rng.Mul(decimal.NewFromFloat(0.618)))
+		result.R3 = pivot.Add(rng)
+		result.S1 = pivot.Sub(rng.Mul(decimal.NewFromFloat(0.382)))
+		result.S2 = pivot.Sub(rng.Mul(decimal.NewFromFloat(0.618)))
+		result.S3 = pivot.Sub(rng)
+
+	case PivotCamarilla:
+		factor := rng.Mul(decimal.NewFromFloat(1.1))
+		result.R1 = close.Add(factor.Div(decimal.NewFromInt(12)))
+		result.R2 = close.Add(factor.Div(decimal.NewFromInt(6)))
+		result.R3 = close.Add(factor.Div(decimal.NewFromInt(4)))
+		result.R4 = close.Add(factor.Div(two))
+		result.S1 = close.Sub(factor.Div(decimal.NewFromInt(12)))
+		result.S2 = close.Sub(factor.Div(decimal.NewFromInt(6)))
+		result.S3 = close.Sub(factor.Div(decimal.NewFromInt(4)))
+		result.S4 = close.Sub(factor.Div(two))
+
+	default:
+		return nil, fmt.Errorf("unsupported pivot method: %s", method)
+	}
+
+	return result, nil
+}
+
+// SwingPoints finds swing highs and lows. A bar is a swing high (low) when its
+// high (low) is the extreme of the `strength` bars on either side of it.
+func SwingPoints(highs, lows []decimal.Decimal, strength int) ([]SwingPoint, error) {
+	if strength < 1 {
+		return nil, fmt.Errorf("swing strength must be at least 1")
+	}
+	if len(highs) != len(lows) || len(highs) < 2*strength+1 {
+		return nil, ErrInsufficientData
+	}
+
+	highIdx, lowIdx := swingIndices(DecimalsToFloats(highs), DecimalsToFloats(lows), strength)
+
+	// Merge both sides back into bar order
+	swings := make([]SwingPoint, 0, len(highIdx)+len(lowIdx))
+	h, l := 0, 0
+	for h < len(highIdx) || l < len(lowIdx) {
+		if l >= len(lowIdx) || (h < len(highIdx) && highIdx[h] <= lowIdx[l]) {
+			swings = append(swings, SwingPoint{Index: highIdx[h], Price: highs[highIdx[h]], Type: SwingHigh})
+			h++
+		} else {
+			swings = append(swings, SwingPoint{Index: lowIdx[l], Price: lows[lowIdx[l]], Type: SwingLow})
+			l++
+		}
+	}
+
+	return swings, nil
+}
+
+// swingIndices returns the bar indices of swing highs and swing lows
+func swingIndices(highs, lows []float64, strength int) (highIdx, lowIdx []int) {
+	for i := strength; i < len(highs)-strength; i++ {
+		isHigh, isLow := true, true
+		for j := i - strength; j <= i+strength && (isHigh || isLow); j++ {
+			if j == i {
+				continue
+			}
+			// Ties on the left side disqualify so a flat top is reported once
+			if highs[j] > highs[i] || (j < i && highs[j] == highs[i]) {
+				isHigh = false
+			}
+			if lows[j] < lows[i] || (j < i && lows[j] == lows[i]) {
+				isLow = false
+			}
+		}
+
+		if isHigh {
+			highIdx = append(highIdx, i)
+		}
+		if isLow {
+			lowIdx = append(lowIdx, i)
+		}
+	}
+	return highIdx, lowIdx
+}
+
+// VolumeProfile distributes each bar's volume into equal-width price bins
+// using the bar's typical price
+func VolumeProfile(highs, lows, closes []decimal.Decimal, volumes []int64, bins int) ([]VolumeNode, error) {
+	if len(highs) != len(lows) || len(lows) != len(closes) || len(closes) != len(volumes) || len(closes) == 0 {
+		return nil, ErrInsufficientData
+	}
+	if bins < 1 {
+		return nil, fmt.Errorf("bin count must be positive")
+	}
+
+	minPrice, maxPrice := math.Inf(1), math.Inf(-1)
+	for i := range closes {
+		minPrice = math.Min(minPrice, lows[i].InexactFloat64())
+		maxPrice = math.Max(maxPrice, highs[i].InexactFloat64())
+	}
+
+	width := (maxPrice - minPrice) / float64(bins)
+	if width <= 0 {
+		return []VolumeNode{{Price: closes[0], Volume: sumVolume(volumes)}}, nil
+	}
+
+	binVolumes := make([]int64, bins)
+	for i := range closes {
+		typical := (highs[i].InexactFloat64() + lows[i].InexactFloat64() + closes[i].InexactFloat64()) / 3
+		bin := int((typical - minPrice) / width)
+		if bin >= bins {
+			bin = bins - 1
+		}
+		if bin < 0 {
+			bin = 0
+		}
+		binVolumes[bin] += volumes[i]
+	}
+
+	nodes := make([]VolumeNode, bins)
+	for i := range nodes {
+		nodes[i] = VolumeNode{
+			Price:  decimal.NewFromFloat(minPrice + width*(float64(i)+0.5)),
+			Volume: binVolumes[i],
+		}
+	}
+
+	return nodes, nil
+}
+
+// SupportResistanceLevels detects support and resistance zones by clustering
+// swing highs/lows and high-volume price nodes that lie within `tolerance`
+// (a fraction of price, e.g. 0.01 for 1%) of each other. Levels below the
+// latest close are support, levels at or above it are resistance.
+func SupportResistanceLevels(highs, lows, closes []decimal.Decimal, volumes []int64, swingStrength int, tolerance float64) ([]PriceLevel, error) {
+	if tolerance <= 0 {
+		return nil, fmt.Errorf("tolerance must be positive")
+	}
+
+	swings, err := SwingPoints(highs, lows, swingStrength)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := VolumeProfile(highs, lows, closes, volumes, volumeProfileBins(len(closes)))
+	if err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		price  float64
+		source string
+		volume int64
+	}
+
+	var candidates []candidate
+	for _, swing := range swings {
+		candidates = append(candidates, candidate{price: swing.Price.InexactFloat64(), source: "SWING"})
+	}
+	for _, node := range highVolumeNodes(nodes) {
+		candidates = append(candidates, candidate{price: node.Price.InexactFloat64(), source: "VOLUME", volume: node.Volume})
+	}
+
+	if len(candidates) == 0 {
+		return []PriceLevel{}, nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].price < candidates[j].price
+	})
+
+	type cluster struct {
+		sum, low, high float64
+		count, touches int
+		volume         int64
+		sources        map[string]bool
+	}
+
+	var clusters []*cluster
+	for _, c := range candidates {
+		last := len(clusters) - 1
+		if last >= 0 {
+			mean := clusters[last].sum / float64(clusters[last].count)
+			if math.Abs(c.price-mean) <= mean*tolerance {
+				cl := clusters[last]
+				cl.sum += c.price
+				cl.count++
+				cl.high = math.Max(cl.high, c.price)
+				cl.volume += c.volume
+				cl.sources[c.source] = true
+				if c.source == "SWING" {
+					cl.touches++
+				}
+				continue
+			}
+		}
+
+		cl := &cluster{sum: c.price, low: c.price, high: c.price, count: 1, volume: c.volume, sources: map[string]bool{c.source: true}}
+		if c.source == "SWING" {
+			cl.touches = 1
+		}
+		clusters = append(clusters, cl)
+	}
+
+	// Score clusters by touches plus relative volume, then normalise to 0-1
+	var maxVolume int64
+	for _, cl := range clusters {
+		if cl.volume > maxVolume {
+			maxVolume = cl.volume
+		}
+	}
+
+	scores := make([]float64, len(clusters))
+	maxScore := 0.0
+	for i, cl := range clusters {
+		scores[i] = float64(cl.touches)
+		if maxVolume > 0 {
+			scores[i] += 2 * float64(cl.volume) / float64(maxVolume)
+		}
+		maxScore = math.Max(maxScore, scores[i])
+	}
+
+	currentPrice := closes[len(closes)-1]
+	levels := make([]PriceLevel, 0, len(clusters))
+	for i, cl := range clusters {
+		price := decimal.NewFromFloat(cl.sum / float64(cl.count))
+
+		levelType := LevelResistance
+		if price.LessThan(currentPrice) {
+			levelType = LevelSupport
+		}
+
+		var sources []string
+		for _, source := range []string{"SWING", "VOLUME"} {
+			if cl.sources[source] {
+				sources = append(sources, source)
+			}
+		}
+
+		strength := 0.0
+		if maxScore > 0 {
+			strength = scores[i] / maxScore
+		}
+
+		levels = append(levels, PriceLevel{
+			Price:    price,
+			Low:      decimal.NewFromFloat(cl.low),
+			High:     decimal.NewFromFloat(cl.high),
+			Type:     levelType,
+			Touches:  cl.touches,
+			Volume:   cl.volume,
+			Strength: strength,
+			Sources:  sources,
+		})
+	}
+
+	return levels, nil
+}
+
+// NearestLevels returns the closest support below and resistance at or above
+// price, matching how SupportResistanceLevels types a level at the current
+// price. Either result may be nil when no level exists on that side.
+func NearestLevels(levels []PriceLevel, price decimal.Decimal) (support, resistance *PriceLevel) {
+	for i := range levels {
+		level := &levels[i]
+		if level.Price.LessThan(price) {
+			if support == nil || level.Price.GreaterThan(support.Price) {
+				support = level
+			}
+		} else {
+			if resistance == nil || level.Price.LessThan(resistance.Price) {
+				resistance = level
+			}
+		}
+	}
+	return support, resistance
+}
+
+// highVolumeNodes returns bins that are local volume peaks above the mean
+func highVolumeNodes(nodes []VolumeNode) []VolumeNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	mean := float64(sumVolume(nodeVolumes(nodes))) / float64(len(nodes))
+
+	var peaks []VolumeNode
+	for i, node := range nodes {
+		if float64(node.Volume) <= mean {
+			continue
+		}
+		if i > 0 && nodes[i-1].Volume > node.Volume {
+			continue
+		}
+		if i < len(nodes)-1 && nodes[i+1].Volume > node.Volume {
+			continue
+		}
+		peaks = append(peaks, node)
+	}
+	return peaks
+}
+
+// volumeProfileBins picks a bin count that scales with the sample size
+func volumeProfileBins(n int) int {
+	bins := int(math.Sqrt(float64(n)))
+	if bins < 5 {
+		bins = 5
+	}
+	if bins > 50 {
+		bins = 50
+	}
+	return bins
+}
+
+func nodeVolumes(nodes []VolumeNode) []int64 {
+	volumes := make([]int64, len(nodes))
+	for i, node := range nodes {
+		volumes[i] = node.Volume
+	}
+	return volumes
+}
+
+func sumVolume(volumes []int64) int64 {
+	var sum int64
+	for _, v := range volumes {
+		sum += v
+	}
+	return sum
+}
@@ -0,0 +1,87 @@
+package indicators
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNearestLevelsAtPrice(t *testing.T) {
+	d := decimal.NewFromFloat
+	levels := []PriceLevel{
+		{Price: d(90), Low: d(89), High: d(91), Type: LevelSupport},
+		{Price: d(95), Low: d(94), High: d(96), Type: LevelSupport},
+		{Price: d(100), Low: d(99), High: d(101), Type: LevelResistance},
+		{Price: d(110), Low: d(109), High: d(111), Type: LevelResistance},
+	}
+
+	support, resistance := NearestLevels(levels, d(100))
+	if support == nil || !support.Price.Equal(d(95)) {
+		t.Fatalf("support = %v, want 95", support)
+	}
+	// A level at the price is typed resistance, so it is the nearest one
+	if resistance == nil || !resistance.Price.Equal(d(100)) {
+		t.Fatalf("resistance = %v, want 100", resistance)
+	}
+
+	support, resistance = NearestLevels(levels, d(85))
+	if support != nil {
+		t.Fatalf("support = %v, want none below every level", support.Price)
+	}
+	if resistance == nil || !resistance.Price.Equal(d(90)) {
+		t.Fatalf("resistance = %v, want 90", resistance)
+	}
+}
+
+// swingSeries random-walks n bars whose highs and lows spread up to 1% from
+// the close
+func swingSeries(n int) (highs, lows, closes []decimal.Decimal) {
+	rng := rand.New(rand.NewSource(42))
+	price := 100.0
+	for i := 0; i < n; i++ {
+		price *= 1 + rng.NormFloat64()*0.01
+		spread := price * 0.01 * rng.Float64()
+		highs = append(highs, decimal.NewFromFloat(price+spread))
+		lows = append(lows, decimal.NewFromFloat(price-spread))
+		closes = append(closes, decimal.NewFromFloat(price))
+	}
+	return highs, lows, closes
+}
+
+func TestSupportResistanceLevelTypes(t *testing.T) {
+	highs, lows, closes := swingSeries(300)
+	volumes := make([]int64, len(closes))
+	for i := range volumes {
+		volumes[i] = 1000 + int64(i%7)*100
+	}
+
+	levels, err := SupportResistanceLevels(highs, lows, closes, volumes, 3, 0.01)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(levels) == 0 {
+		t.Fatal("no levels found")
+	}
+
+	price := closes[len(closes)-1]
+	support, resistance := NearestLevels(levels, price)
+	for _, level := range levels {
+		if level.Low.GreaterThan(level.Price) || level.High.LessThan(level.Price) {
+			t.Fatalf("level %v outside its zone [%v, %v]", level.Price, level.Low, level.High)
+		}
+		wantType := LevelResistance
+		if level.Price.LessThan(price) {
+			wantType = LevelSupport
+		}
+		if level.Type != wantType {
+			t.Fatalf("level %v typed %s at price %v", level.Price, level.Type, price)
+		}
+	}
+	if support != nil && support.Type != LevelSupport {
+		t.Fatalf("nearest support typed %s", support.Type)
+	}
+	if resistance != nil && resistance.Type != LevelResistance {
+		t.Fatalf("nearest resistance typed %s", resistance.Type)
+	}
+}
@@ -0,0 +1,346 @@
+package ledger
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/models"
+)
+
+// Cost basis methods
+const (
+	MethodFIFO     = "fifo"     // Sells close the oldest lots first
+	MethodLIFO     = "lifo"     // Sells close the newest lots first
+	MethodAverage  = "average"  // Every share of a symbol carries the pooled average cost
+	MethodSpecific = "specific" // Sells close the lots they name, the oldest first when they name none
+)
+
+// ValidMethod reports whether method is a known cost basis method
+func ValidMethod(method string) bool {
+	switch method {
+	case MethodFIFO, MethodLIFO, MethodAverage, MethodSpecific:
+		return true
+	}
+	return false
+}
+
+// Lot is shares bought together, identified by the buy transaction. Cost
+// per share includes the buy's commission and is adjusted by splits.
+type Lot struct {
+	ID           string          `json:"id"`
+	Symbol       string          `json:"symbol"`
+	Acquired     time.Time       `json:"acquired"`
+	Quantity     decimal.Decimal `json:"quantity"`
+	CostPerShare decimal.Decimal `json:"cost_per_share"`
+}
+
+// Cost is the lot's remaining cost basis
+func (l Lot) Cost() decimal.Decimal {
+	return l.Quantity.Mul(l.CostPerShare)
+}
+
+// ClosedLot is the part of a lot a sell closed
+type ClosedLot struct {
+	LotID    string          `json:"lot_id"`
+	Acquired time.Time       `json:"acquired"`
+	Quantity decimal.Decimal `json:"quantity"`
+	Cost     decimal.Decimal `json:"cost"`
+}
+
+// Realization is the gain or loss a sell locked in. Proceeds are net of
+// the sell's commission.
+type Realization struct {
+	TransactionID string          `json:"transaction_id"`
+	Symbol        string          `json:"symbol"`
+	Date          time.Time       `json:"date"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Proceeds      decimal.Decimal `json:"proceeds"`
+	CostBasis     decimal.Decimal `json:"cost_basis"`
+	Gain          decimal.Decimal `json:"gain"`
+	Lots          []ClosedLot     `json:"lots"`
+}
+
+// Holding is everything the ledger knows about one symbol. Open lots are
+// oldest first.
+type Holding struct {
+	Symbol     string          `json:"symbol"`
+	Quantity   decimal.Decimal `json:"quantity"`
+	CostBasis  decimal.Decimal `json:"cost_basis"`
+	RealizedPL decimal.Decimal `json:"realized_pl"`
+	Dividends  decimal.Decimal `json:"dividends"`
+	LastPrice  decimal.Decimal `json:"last_price"` // Of the latest buy or sell, adjusted by splits
+	Lots       []Lot           `json:"lots"`
+}
+
+// Book is a portfolio's state after replaying its ledger
+type Book struct {
+	Method      string              `json:"method"`
+	Cash        decimal.Decimal     `json:"cash"`
+	Deposits    decimal.Decimal     `json:"deposits"`
+	Withdrawals decimal.Decimal     `json:"withdrawals"`
+	Dividends   decimal.Decimal     `json:"dividends"`
+	Fees        decimal.Decimal     `json:"fees"` // Commissions and standalone fees
+	RealizedPL  decimal.Decimal     `json:"realized_pl"`
+	Holdings    map[string]*Holding `json:"holdings"`
+	Realized    []Realization       `json:"realized"`
+}
+
+// Open returns the holdings with shares, by symbol
+func (b *Book) Open() []*Holding {
+	var open []*Holding
+	for _, holding := range b.Holdings {
+		if holding.Quantity.IsPositive() {
+			open = append(open, holding)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].Symbol < open[j].Symbol })
+	return open
+}
+
+// Validate checks a transaction on its own; whether it fits the ledger is
+// only known on replay
+func Validate(tx models.Transaction) error {
+	switch tx.Type {
+	case models.TransactionBuy, models.TransactionSell:
+		if tx.Symbol == "" {
+			return fmt.Errorf("%s needs a symbol", tx.Type)
+		}
+		if !tx.Quantity.IsPositive() {
+			return fmt.Errorf("%s quantity must be positive", tx.Type)
+		}
+		if tx.Price.IsNegative() || tx.Fee.IsNegative() {
+			return fmt.Errorf("%s price and fee must not be negative", tx.Type)
+		}
+		if len(tx.Lots) > 0 {
+			if tx.Type != models.TransactionSell {
+				return fmt.Errorf("only sells can select lots")
+			}
+			total := decimal.Zero
+			for _, lot := range tx.Lots {
+				if lot.LotID == "" || !lot.Quantity.IsPositive() {
+					return fmt.Errorf("each selected lot needs a lot_id and a positive quantity")
+				}
+				total = total.Add(lot.Quantity)
+			}
+			if !total.Equal(tx.Quantity) {
+				return fmt.Errorf("selected lots add up to %s shares, not the %s sold", total, tx.Quantity)
+			}
+		}
+	case models.TransactionDividend:
+		if tx.Symbol == "" {
+			return fmt.Errorf("%s needs a symbol", tx.Type)
+		}
+		if !tx.Amount.IsPositive() {
+			return fmt.Errorf("%s amount must be positive", tx.Type)
+		}
+	case models.TransactionFee, models.TransactionDeposit, models.TransactionWithdrawal:
+		if !tx.Amount.IsPositive() {
+			return fmt.Errorf("%s amount must be positive", tx.Type)
+		}
+	case models.TransactionSplit:
+		if tx.Symbol == "" {
+			return fmt.Errorf("%s needs a symbol", tx.Type)
+		}
+		if !tx.Ratio.IsPositive() {
+			return fmt.Errorf("split ratio must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown transaction type %q", tx.Type)
+	}
+	return nil
+}
+
+// Replay applies transactions, which must be in the order they took
+// effect, under a cost basis method. It fails on the first transaction that
+// doesn't fit, such as selling more shares than are held.
+func Replay(transactions []models.Transaction, method string) (*Book, error) {
+	if !ValidMethod(method) {
+		return nil, fmt.Errorf("unknown cost basis method %q (expected %s, %s, %s or %s)", method, MethodFIFO, MethodLIFO, MethodAverage, MethodSpecific)
+	}
+	book := &Book{Method: method, Holdings: make(map[string]*Holding)}
+	for _, tx := range transactions {
+		if err := book.apply(tx); err != nil {
+			return nil, fmt.Errorf("transaction %s on %s: %v", tx.ID, tx.Date.Format("2006-01-02"), err)
+		}
+	}
+	return book, nil
+}
+
+// apply updates the book for one transaction
+func (b *Book) apply(tx models.Transaction) error {
+	if err := Validate(tx); err != nil {
+		return err
+	}
+
+	switch tx.Type {
+	case models.TransactionDeposit:
+		b.Cash = b.Cash.Add(tx.Amount)
+		b.Deposits = b.Deposits.Add(tx.Amount)
+
+	case models.TransactionWithdrawal:
+		b.Cash = b.Cash.Sub(tx.Amount)
+		b.Withdrawals = b.Withdrawals.Add(tx.Amount)
+
+	case models.TransactionFee:
+		b.Cash = b.Cash.Sub(tx.Amount)
+		b.Fees = b.Fees.Add(tx.Amount)
+
+	case models.TransactionDividend:
+		b.Cash = b.Cash.Add(tx.Amount)
+		b.Dividends = b.Dividends.Add(tx.Amount)
+		holding := b.holding(tx.Symbol)
+		holding.Dividends = holding.Dividends.Add(tx.Amount)
+
+	case models.TransactionSplit:
+		holding := b.holding(tx.Symbol)
+		for i := range holding.Lots {
+			holding.Lots[i].Quantity = holding.Lots[i].Quantity.Mul(tx.Ratio)
+			holding.Lots[i].CostPerShare = holding.Lots[i].CostPerShare.Div(tx.Ratio)
+		}
+		holding.Quantity = holding.Quantity.Mul(tx.Ratio)
+		holding.LastPrice = holding.LastPrice.Div(tx.Ratio)
+
+	case models.TransactionBuy:
+		cost := tx.Quantity.Mul(tx.Price).Add(tx.Fee)
+		b.Cash = b.Cash.Sub(cost)
+		b.Fees = b.Fees.Add(tx.Fee)
+		holding := b.holding(tx.Symbol)
+		holding.Lots = append(holding.Lots, Lot{
+			ID:           tx.ID,
+			Symbol:       tx.Symbol,
+			Acquired:     tx.Date,
+			Quantity:     tx.Quantity,
+			CostPerShare: cost.Div(tx.Quantity),
+		})
+		holding.Quantity = holding.Quantity.Add(tx.Quantity)
+		holding.CostBasis = holding.CostBasis.Add(cost)
+		holding.LastPrice = tx.Price
+		if b.Method == MethodAverage {
+			holding.pool()
+		}
+
+	case models.TransactionSell:
+		holding := b.holding(tx.Symbol)
+		if tx.Quantity.GreaterThan(holding.Quantity) {
+			return fmt.Errorf("selling %s %s but only %s held", tx.Quantity, tx.Symbol, holding.Quantity)
+		}
+		closed, err := holding.close(tx, b.Method)
+		if err != nil {
+			return err
+		}
+
+		proceeds := tx.Quantity.Mul(tx.Price).Sub(tx.Fee)
+		realization := Realization{
+			TransactionID: tx.ID,
+			Symbol:        tx.Symbol,
+			Date:          tx.Date,
+			Quantity:      tx.Quantity,
+			Proceeds:      proceeds,
+			Lots:          closed,
+		}
+		for _, lot := range closed {
+			realization.CostBasis = realization.CostBasis.Add(lot.Cost)
+		}
+		realization.Gain = proceeds.Sub(realization.CostBasis)
+
+		b.Cash = b.Cash.Add(proceeds)
+		b.Fees = b.Fees.Add(tx.Fee)
+		b.RealizedPL = b.RealizedPL.Add(realization.Gain)
+		b.Realized = append(b.Realized, realization)
+		holding.RealizedPL = holding.RealizedPL.Add(realization.Gain)
+		holding.Quantity = holding.Quantity.Sub(tx.Quantity)
+		holding.CostBasis = holding.CostBasis.Sub(realization.CostBasis)
+		holding.LastPrice = tx.Price
+		if holding.Quantity.IsZero() {
+			holding.CostBasis = decimal.Zero // Drop rounding left by pooled costs
+		}
+	}
+	return nil
+}
+
+// holding returns the symbol's holding, creating it if needed
+func (b *Book) holding(symbol string) *Holding {
+	holding, ok := b.Holdings[symbol]
+	if !ok {
+		holding = &Holding{Symbol: symbol}
+		b.Holdings[symbol] = holding
+	}
+	return holding
+}
+
+// pool gives every open lot the holding's average cost per share
+func (h *Holding) pool() {
+	if !h.Quantity.IsPositive() {
+		return
+	}
+	average := h.CostBasis.Div(h.Quantity)
+	for i := range h.Lots {
+		h.Lots[i].CostPerShare = average
+	}
+}
+
+// close removes the sold shares from the holding's lots in the order the
+// method picks them, returning what was closed
+func (h *Holding) close(tx models.Transaction, method string) ([]ClosedLot, error) {
+	type take struct {
+		index    int
+		quantity decimal.Decimal
+	}
+	var takes []take
+
+	if method == MethodSpecific && len(tx.Lots) > 0 {
+		remaining := make(map[string]decimal.Decimal, len(h.Lots))
+		index := make(map[string]int, len(h.Lots))
+		for i, lot := range h.Lots {
+			remaining[lot.ID], index[lot.ID] = lot.Quantity, i
+		}
+		for _, selection := range tx.Lots {
+			available, ok := remaining[selection.LotID]
+			if !ok {
+				return nil, fmt.Errorf("lot %s is not an open %s lot", selection.LotID, tx.Symbol)
+			}
+			if selection.Quantity.GreaterThan(available) {
+				return nil, fmt.Errorf("lot %s has %s shares, %s selected", selection.LotID, available, selection.Quantity)
+			}
+			remaining[selection.LotID] = available.Sub(selection.Quantity)
+			takes = append(takes, take{index[selection.LotID], selection.Quantity})
+		}
+	} else {
+		order := make([]int, len(h.Lots))
+		for i := range order {
+			order[i] = i
+		}
+		if method == MethodLIFO {
+			for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+				order[i], order[j] = order[j], order[i]
+			}
+		}
+		remaining := tx.Quantity
+		for _, i := range order {
+			if !remaining.IsPositive() {
+				break
+			}
+			quantity := decimal.Min(remaining, h.Lots[i].Quantity)
+			takes = append(takes, take{i, quantity})
+			remaining = remaining.Sub(quantity)
+		}
+	}
+
+	closed := make([]ClosedLot, 0, len(takes))
+	for _, t := range takes {
+		lot := &h.Lots[t.index]
+		closed = append(closed, ClosedLot{LotID: lot.ID, Acquired: lot.Acquired, Quantity: t.quantity, Cost: t.quantity.Mul(lot.CostPerShare)})
+		lot.Quantity = lot.Quantity.Sub(t.quantity)
+	}
+
+	open := h.Lots[:0]
+	for _, lot := range h.Lots {
+		if lot.Quantity.IsPositive() {
+			open = append(open, lot)
+		}
+	}
+	h.Lots = open
+	return closed, nil
+}
@@ -0,0 +1,247 @@
+package ledger
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/models"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func dec(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v)
+}
+
+func deposit(day int, amount float64) models.Transaction {
+	return models.Transaction{ID: "dep", Type: models.TransactionDeposit, Amount: dec(amount), Date: start.AddDate(0, 0, day)}
+}
+
+func buy(id string, day int, quantity, price, fee float64) models.Transaction {
+	return models.Transaction{ID: id, Type: models.TransactionBuy, Symbol: "AAA", Quantity: dec(quantity), Price: dec(price), Fee: dec(fee), Date: start.AddDate(0, 0, day)}
+}
+
+func sell(id string, day int, quantity, price, fee float64, lots ...models.LotSelection) models.Transaction {
+	return models.Transaction{ID: id, Type: models.TransactionSell, Symbol: "AAA", Quantity: dec(quantity), Price: dec(price), Fee: dec(fee), Lots: lots, Date: start.AddDate(0, 0, day)}
+}
+
+func split(day int, ratio float64) models.Transaction {
+	return models.Transaction{ID: "split", Type: models.TransactionSplit, Symbol: "AAA", Ratio: dec(ratio), Date: start.AddDate(0, 0, day)}
+}
+
+// assertDecimal fails unless got equals want to 8 decimal places
+func assertDecimal(t *testing.T, what string, got decimal.Decimal, want float64) {
+	t.Helper()
+	if !got.Round(8).Equal(dec(want).Round(8)) {
+		t.Fatalf("%s = %s, want %v", what, got, want)
+	}
+}
+
+// assertLots fails unless the open lots have the IDs and quantities given
+func assertLots(t *testing.T, lots []Lot, want map[string]float64) {
+	t.Helper()
+	if len(lots) != len(want) {
+		t.Fatalf("got %d open lots %+v, want %d", len(lots), lots, len(want))
+	}
+	for _, lot := range lots {
+		quantity, ok := want[lot.ID]
+		if !ok {
+			t.Fatalf("unexpected open lot %s", lot.ID)
+		}
+		assertDecimal(t, "lot "+lot.ID+" quantity", lot.Quantity, quantity)
+	}
+}
+
+func TestReplayCostBasisMethods(t *testing.T) {
+	// Two lots at 100 and 120, then 5 shares sold at 130
+	base := []models.Transaction{
+		deposit(0, 10000),
+		buy("a", 1, 10, 100, 0),
+		buy("b", 2, 10, 120, 0),
+	}
+
+	tests := []struct {
+		method    string
+		sale      models.Transaction
+		costBasis float64 // Of the shares sold
+		lots      map[string]float64
+	}{
+		{MethodFIFO, sell("s", 3, 5, 130, 0), 500, map[string]float64{"a": 5, "b": 10}},
+		{MethodLIFO, sell("s", 3, 5, 130, 0), 600, map[string]float64{"a": 10, "b": 5}},
+		{MethodAverage, sell("s", 3, 5, 130, 0), 550, map[string]float64{"a": 5, "b": 10}},
+		{MethodSpecific, sell("s", 3, 5, 130, 0,
+			models.LotSelection{LotID: "b", Quantity: dec(3)},
+			models.LotSelection{LotID: "a", Quantity: dec(2)}), 560, map[string]float64{"a": 8, "b": 7}},
+		// Naming no lots falls back to the oldest first
+		{MethodSpecific, sell("s", 3, 5, 130, 0), 500, map[string]float64{"a": 5, "b": 10}},
+	}
+	for _, tt := range tests {
+		book, err := Replay(append(append([]models.Transaction(nil), base...), tt.sale), tt.method)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.method, err)
+		}
+		if len(book.Realized) != 1 {
+			t.Fatalf("%s: %d realizations, want 1", tt.method, len(book.Realized))
+		}
+		realization := book.Realized[0]
+		assertDecimal(t, tt.method+" cost basis", realization.CostBasis, tt.costBasis)
+		assertDecimal(t, tt.method+" gain", realization.Gain, 650-tt.costBasis)
+		assertDecimal(t, tt.method+" realized P&L", book.RealizedPL, 650-tt.costBasis)
+
+		holding := book.Holdings["AAA"]
+		assertDecimal(t, tt.method+" quantity", holding.Quantity, 15)
+		assertDecimal(t, tt.method+" remaining basis", holding.CostBasis, 2200-tt.costBasis)
+		assertDecimal(t, tt.method+" cash", book.Cash, 10000-2200+650)
+		assertLots(t, holding.Lots, tt.lots)
+	}
+}
+
+func TestReplayAverageCost(t *testing.T) {
+	book, err := Replay([]models.Transaction{
+		buy("a", 1, 10, 100, 0),
+		buy("b", 2, 10, 120, 0),
+		sell("s", 3, 5, 130, 0),
+	}, MethodAverage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lot := range book.Holdings["AAA"].Lots {
+		assertDecimal(t, "pooled cost per share", lot.CostPerShare, 110)
+	}
+
+	// Selling everything leaves no basis behind
+	book, err = Replay([]models.Transaction{
+		buy("a", 1, 3, 100, 0),
+		buy("b", 2, 3, 101, 0),
+		sell("s", 3, 6, 90, 0),
+	}, MethodAverage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holding := book.Holdings["AAA"]; !holding.CostBasis.IsZero() || len(holding.Lots) != 0 || len(book.Open()) != 0 {
+		t.Fatalf("closed holding %+v, want no basis or lots", holding)
+	}
+	assertDecimal(t, "gain", book.RealizedPL, 540-603)
+}
+
+func TestReplayPartialCloses(t *testing.T) {
+	book, err := Replay([]models.Transaction{
+		buy("a", 1, 10, 100, 0),
+		buy("b", 2, 10, 120, 0),
+		sell("s1", 3, 15, 130, 0),
+		sell("s2", 4, 3, 140, 0),
+	}, MethodFIFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first sell closes all of a and half of b, the second more of b
+	first := book.Realized[0]
+	if len(first.Lots) != 2 || first.Lots[0].LotID != "a" || first.Lots[1].LotID != "b" {
+		t.Fatalf("first sell closed %+v, want a then b", first.Lots)
+	}
+	assertDecimal(t, "first a closed", first.Lots[0].Quantity, 10)
+	assertDecimal(t, "first b closed", first.Lots[1].Quantity, 5)
+	assertDecimal(t, "first cost basis", first.CostBasis, 1600)
+
+	second := book.Realized[1]
+	if len(second.Lots) != 1 || second.Lots[0].LotID != "b" {
+		t.Fatalf("second sell closed %+v, want b", second.Lots)
+	}
+	assertDecimal(t, "second cost basis", second.CostBasis, 360)
+	assertDecimal(t, "realized P&L", book.RealizedPL, 1950-1600+420-360)
+	assertLots(t, book.Holdings["AAA"].Lots, map[string]float64{"b": 2})
+}
+
+func TestReplayFees(t *testing.T) {
+	book, err := Replay([]models.Transaction{
+		deposit(0, 2000),
+		buy("a", 1, 10, 100, 5),
+		sell("s", 2, 10, 110, 5),
+		{ID: "fee", Type: models.TransactionFee, Amount: dec(2), Date: start.AddDate(0, 0, 3)},
+	}, MethodFIFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The buy's commission is part of the cost, the sell's comes off the proceeds
+	assertDecimal(t, "cost basis", book.Realized[0].CostBasis, 1005)
+	assertDecimal(t, "proceeds", book.Realized[0].Proceeds, 1095)
+	assertDecimal(t, "gain", book.Realized[0].Gain, 90)
+	assertDecimal(t, "fees", book.Fees, 12)
+	assertDecimal(t, "cash", book.Cash, 2000-1005+1095-2)
+}
+
+func TestReplaySplits(t *testing.T) {
+	// A 2-for-1 split before the sell halves each lot's cost per share
+	book, err := Replay([]models.Transaction{
+		buy("a", 1, 10, 100, 0),
+		split(2, 2),
+		sell("s", 3, 5, 60, 0),
+	}, MethodFIFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	holding := book.Holdings["AAA"]
+	assertDecimal(t, "cost basis sold", book.Realized[0].CostBasis, 250)
+	assertDecimal(t, "gain", book.Realized[0].Gain, 50)
+	assertDecimal(t, "quantity", holding.Quantity, 15)
+	assertDecimal(t, "lot cost per share", holding.Lots[0].CostPerShare, 50)
+	assertDecimal(t, "remaining basis", holding.CostBasis, 750)
+
+	// A 3-for-1 split after the sell leaves the realized gain alone and
+	// keeps the remaining basis
+	book, err = Replay([]models.Transaction{
+		buy("a", 1, 10, 100, 0),
+		sell("s", 2, 4, 110, 0),
+		split(3, 3),
+	}, MethodFIFO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	holding = book.Holdings["AAA"]
+	assertDecimal(t, "gain", book.RealizedPL, 40)
+	assertDecimal(t, "quantity", holding.Quantity, 18)
+	assertDecimal(t, "remaining lot cost", holding.Lots[0].Cost(), 600)
+	assertDecimal(t, "last price", holding.LastPrice, 110.0/3)
+
+	// Selling the split-adjusted shares needs the adjusted count
+	if _, err := Replay([]models.Transaction{
+		buy("a", 1, 10, 100, 0),
+		split(2, 2),
+		sell("s", 3, 20, 60, 0),
+	}, MethodFIFO); err != nil {
+		t.Fatalf("selling all split-adjusted shares: %v", err)
+	}
+}
+
+func TestReplayRejects(t *testing.T) {
+	held := []models.Transaction{buy("a", 1, 10, 100, 0), buy("b", 2, 5, 100, 0)}
+	tests := []struct {
+		name   string
+		method string
+		tx     models.Transaction
+		want   string
+	}{
+		{"oversell", MethodFIFO, sell("s", 3, 16, 100, 0), "only 15 held"},
+		{"oversell a lot", MethodSpecific, sell("s", 3, 6, 100, 0, models.LotSelection{LotID: "b", Quantity: dec(6)}), "lot b has 5 shares"},
+		{"unknown lot", MethodSpecific, sell("s", 3, 1, 100, 0, models.LotSelection{LotID: "z", Quantity: dec(1)}), "not an open"},
+		{"lots not adding up", MethodSpecific, sell("s", 3, 2, 100, 0, models.LotSelection{LotID: "a", Quantity: dec(1)}), "add up to"},
+		{"sell before the buy", MethodFIFO, sell("s", 0, 1, 100, 0), "only 0 held"},
+	}
+	for _, tt := range tests {
+		transactions := append(append([]models.Transaction(nil), held...), tt.tx)
+		if tt.tx.Date.Before(held[0].Date) {
+			transactions = append([]models.Transaction{tt.tx}, held...)
+		}
+		_, err := Replay(transactions, tt.method)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: error = %v, want it to mention %q", tt.name, err, tt.want)
+		}
+	}
+
+	if _, err := Replay(held, "hifo"); err == nil {
+		t.Fatal("unknown method accepted")
+	}
+}
@@ -0,0 +1,135 @@
+package ml
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/stat"
+	"trading-service/internal/indicators"
+)
+
+// Indicator periods used for features. Changing them changes the meaning of
+// a trained model's weights, so they're fixed rather than configurable.
+const (
+	rsiPeriod        = 14
+	macdFast         = 12
+	macdSlow         = 26
+	macdSignal       = 9
+	adxPeriod        = 14
+	volatilityPeriod = 20
+	volumePeriod     = 20
+)
+
+// FeatureNames lists the feature columns in order
+var FeatureNames = []string{
+	"return_1",     // Close-to-close return over 1 bar
+	"return_5",     // ... over 5 bars
+	"return_20",    // ... over 20 bars
+	"rsi",          // RSI(14)
+	"macd_hist",    // MACD histogram as a fraction of price
+	"adx",          // ADX(14)
+	"di_spread",    // +DI minus -DI
+	"volatility",   // Standard deviation of the last 20 one-bar returns
+	"volume_ratio", // Volume over its prior 20-bar average
+}
+
+// BuildFeatures returns one row per bar in FeatureNames order. Rows are NaN
+// where an indicator doesn't have enough history yet; see RowValid.
+func BuildFeatures(bars *indicators.Bars) [][]float64 {
+	n := bars.Len()
+	columns := make([][]float64, len(FeatureNames))
+	for j := range columns {
+		columns[j] = nanColumn(n)
+	}
+
+	closes := bars.Close
+	for i := 0; i < n; i++ {
+		for j, k := range []int{1, 5, 20} {
+			if i >= k && closes[i-k] != 0 {
+				columns[j][i] = closes[i]/closes[i-k] - 1
+			}
+		}
+	}
+
+	if rsi, err := indicators.RSIFloat(closes, rsiPeriod); err == nil {
+		placeRight(columns[3], rsi)
+	}
+	if _, _, hist, err := indicators.MACDFloat(closes, macdFast, macdSlow, macdSignal); err == nil {
+		offset := n - len(hist)
+		for i, h := range hist {
+			if closes[offset+i] != 0 {
+				columns[4][offset+i] = h / closes[offset+i]
+			}
+		}
+	}
+	if adx, plusDI, minusDI, err := indicators.ADXFloat(bars.High, bars.Low, closes, adxPeriod); err == nil {
+		placeRight(columns[5], adx)
+		spread := make([]float64, len(plusDI))
+		for i := range plusDI {
+			spread[i] = plusDI[i] - minusDI[len(minusDI)-len(plusDI)+i]
+		}
+		placeRight(columns[6], spread)
+	}
+
+	returns := indicators.Returns(closes)
+	for i := volatilityPeriod; i < n; i++ {
+		// returns[i-1] is the return into bar i
+		columns[7][i] = stat.StdDev(returns[i-volatilityPeriod:i], nil)
+	}
+
+	for i := volumePeriod; i < n; i++ {
+		avg := stat.Mean(bars.Volume[i-volumePeriod:i], nil)
+		columns[8][i] = 1
+		if avg > 0 {
+			columns[8][i] = bars.Volume[i] / avg
+		}
+	}
+
+	rows := make([][]float64, n)
+	for i := range rows {
+		rows[i] = make([]float64, len(FeatureNames))
+		for j := range columns {
+			rows[i][j] = columns[j][i]
+		}
+	}
+	return rows
+}
+
+// ForwardLabels marks bar i as 1 when the return from its close to the
+// close `horizon` bars later exceeds threshold, else 0. The last `horizon`
+// labels are NaN.
+func ForwardLabels(closes []float64, horizon int, threshold float64) []float64 {
+	labels := nanColumn(len(closes))
+	for i := 0; i+horizon < len(closes); i++ {
+		if closes[i] == 0 {
+			continue
+		}
+		labels[i] = 0
+		if closes[i+horizon]/closes[i]-1 > threshold {
+			labels[i] = 1
+		}
+	}
+	return labels
+}
+
+// RowValid reports whether every feature in the row is a finite number
+func RowValid(row []float64) bool {
+	for _, v := range row {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func nanColumn(n int) []float64 {
+	column := make([]float64, n)
+	for i := range column {
+		column[i] = math.NaN()
+	}
+	return column
+}
+
+// placeRight copies an end-aligned indicator series into a full-length column
+func placeRight(column, values []float64) {
+	copy(column[len(column)-len(values):], values)
+}
@@ -0,0 +1,144 @@
+package ml
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// LogisticRegression is a binary classifier over standardized features
+type LogisticRegression struct {
+	Weights []float64 `json:"weights"` // Per standardized feature
+	Bias    float64   `json:"bias"`
+	Mean    []float64 `json:"mean"`  // Feature means from training
+	Scale   []float64 `json:"scale"` // Feature standard deviations from training
+}
+
+// FitLogistic trains an L2-regularized logistic regression with iteratively
+// reweighted least squares (Newton's method). Features are standardized
+// first; the bias isn't penalized.
+func FitLogistic(X [][]float64, y []float64, l2 float64, maxIterations int) (*LogisticRegression, error) {
+	if len(X) == 0 || len(X) != len(y) {
+		return nil, fmt.Errorf("need matching, non-empty features and labels")
+	}
+	features := len(X[0])
+
+	model := &LogisticRegression{
+		Weights: make([]float64, features),
+		Mean:    make([]float64, features),
+		Scale:   make([]float64, features),
+	}
+	column := make([]float64, len(X))
+	for j := 0; j < features; j++ {
+		for i := range X {
+			column[i] = X[i][j]
+		}
+		model.Mean[j], model.Scale[j] = stat.MeanStdDev(column, nil)
+		if model.Scale[j] == 0 || math.IsNaN(model.Scale[j]) {
+			model.Scale[j] = 1 // Constant feature; leave it centred only
+		}
+	}
+
+	// Design matrix with a leading bias column
+	dims := features + 1
+	design := mat.NewDense(len(X), dims, nil)
+	for i, row := range X {
+		design.Set(i, 0, 1)
+		for j, v := range row {
+			design.Set(i, j+1, (v-model.Mean[j])/model.Scale[j])
+		}
+	}
+
+	beta := mat.NewVecDense(dims, nil)
+	var logits mat.VecDense
+	for iter := 0; iter < maxIterations; iter++ {
+		logits.MulVec(design, beta)
+
+		gradient := mat.NewVecDense(dims, nil)
+		hessian := mat.NewSymDense(dims, nil)
+		for i := 0; i < len(X); i++ {
+			p := sigmoid(logits.AtVec(i))
+			w := math.Max(p*(1-p), 1e-10)
+			residual := p - y[i]
+			for a := 0; a < dims; a++ {
+				xa := design.At(i, a)
+				gradient.SetVec(a, gradient.AtVec(a)+residual*xa)
+				for b := a; b < dims; b++ {
+					hessian.SetSym(a, b, hessian.At(a, b)+w*xa*design.At(i, b))
+				}
+			}
+		}
+		for a := 1; a < dims; a++ {
+			gradient.SetVec(a, gradient.AtVec(a)+l2*beta.AtVec(a))
+			hessian.SetSym(a, a, hessian.At(a, a)+l2)
+		}
+
+		var step mat.VecDense
+		if err := step.SolveVec(hessian, gradient); err != nil {
+			return nil, fmt.Errorf("logistic regression failed to converge: %v", err)
+		}
+		beta.SubVec(beta, &step)
+
+		if mat.Norm(&step, math.Inf(1)) < 1e-8 {
+			break
+		}
+	}
+
+	model.Bias = beta.AtVec(0)
+	for j := 0; j < features; j++ {
+		model.Weights[j] = beta.AtVec(j + 1)
+	}
+	return model, nil
+}
+
+// Logit returns the log-odds for a raw feature row
+func (m *LogisticRegression) Logit(x []float64) float64 {
+	z := m.Bias
+	for j, v := range x {
+		z += m.Weights[j] * (v - m.Mean[j]) / m.Scale[j]
+	}
+	return z
+}
+
+// Probability returns P(label = 1) for a raw feature row
+func (m *LogisticRegression) Probability(x []float64) float64 {
+	return sigmoid(m.Logit(x))
+}
+
+// PlattScaling maps raw log-odds to calibrated probabilities:
+// p = sigmoid(A*logit + B)
+type PlattScaling struct {
+	A float64 `json:"a"`
+	B float64 `json:"b"`
+}
+
+// FitPlatt fits Platt scaling on held-out log-odds and labels. A small
+// penalty keeps A finite when the holdout is perfectly separated.
+func FitPlatt(logits, y []float64) (PlattScaling, error) {
+	X := make([][]float64, len(logits))
+	for i, z := range logits {
+		X[i] = []float64{z}
+	}
+	fit, err := FitLogistic(X, y, 1e-3, 50)
+	if err != nil {
+		return PlattScaling{A: 1}, err
+	}
+	// Undo the standardization so the scaling applies to raw logits
+	a := fit.Weights[0] / fit.Scale[0]
+	return PlattScaling{A: a, B: fit.Bias - a*fit.Mean[0]}, nil
+}
+
+// Probability returns the calibrated probability for a raw logit
+func (p PlattScaling) Probability(logit float64) float64 {
+	return sigmoid(p.A*logit + p.B)
+}
+
+func sigmoid(z float64) float64 {
+	if z >= 0 {
+		return 1 / (1 + math.Exp(-z))
+	}
+	e := math.Exp(z)
+	return e / (1 + e)
+}
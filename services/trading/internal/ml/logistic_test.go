@@ -0,0 +1,116 @@
+package ml
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// logisticSample draws features from a standard normal and labels from a
+// logistic model with the given bias and weights
+func logisticSample(rng *rand.Rand, n int, bias float64, weights []float64) ([][]float64, []float64) {
+	X := make([][]float64, n)
+	y := make([]float64, n)
+	for i := range X {
+		X[i] = make([]float64, len(weights))
+		z := bias
+		for j, w := range weights {
+			X[i][j] = 2 + rng.NormFloat64()*3 // Off-centre and scaled to exercise standardization
+			z += w * X[i][j]
+		}
+		if rng.Float64() < sigmoid(z) {
+			y[i] = 1
+		}
+	}
+	return X, y
+}
+
+func TestFitLogisticRecoversCoefficients(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	bias, weights := -0.5, []float64{0.8, -0.4, 0}
+	X, y := logisticSample(rng, 20000, bias, weights)
+
+	model, err := FitLogistic(X, y, 1e-6, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Map the standardized fit back to raw features
+	rawBias := model.Bias
+	for j := range weights {
+		raw := model.Weights[j] / model.Scale[j]
+		if math.Abs(raw-weights[j]) > 0.05 {
+			t.Fatalf("weight %d = %.3f, want %.3f", j, raw, weights[j])
+		}
+		rawBias -= raw * model.Mean[j]
+	}
+	if math.Abs(rawBias-bias) > 0.1 {
+		t.Fatalf("bias = %.3f, want %.3f", rawBias, bias)
+	}
+
+	x := []float64{1, 2, 3}
+	want := sigmoid(bias + 0.8*1 - 0.4*2)
+	if got := model.Probability(x); math.Abs(got-want) > 0.02 {
+		t.Fatalf("probability = %.3f, want %.3f", got, want)
+	}
+}
+
+func TestFitLogisticRidgeShrinks(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	X, y := logisticSample(rng, 2000, 0, []float64{0.5})
+
+	loose, err := FitLogistic(X, y, 1e-6, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tight, err := FitLogistic(X, y, 1000, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(tight.Weights[0]) >= math.Abs(loose.Weights[0]) {
+		t.Fatalf("penalized weight %.3f not smaller than %.3f", tight.Weights[0], loose.Weights[0])
+	}
+}
+
+func TestFitLogisticErrors(t *testing.T) {
+	if _, err := FitLogistic(nil, nil, 1, 10); err == nil {
+		t.Fatal("expected an error for empty data")
+	}
+	if _, err := FitLogistic([][]float64{{1}, {2}}, []float64{1}, 1, 10); err == nil {
+		t.Fatal("expected an error for mismatched labels")
+	}
+}
+
+func TestFitPlattMonotonic(t *testing.T) {
+	// Raw logits that are overconfident by a factor of three
+	rng := rand.New(rand.NewSource(3))
+	logits := make([]float64, 5000)
+	labels := make([]float64, len(logits))
+	for i := range logits {
+		z := rng.NormFloat64()
+		logits[i] = 3 * z
+		if rng.Float64() < sigmoid(z) {
+			labels[i] = 1
+		}
+	}
+
+	platt, err := FitPlatt(logits, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if platt.A <= 0 {
+		t.Fatalf("A = %.3f, want positive", platt.A)
+	}
+	if math.Abs(platt.A-1.0/3) > 0.05 || math.Abs(platt.B) > 0.1 {
+		t.Fatalf("scaling = %+v, want A near 1/3 and B near 0", platt)
+	}
+
+	previous := -1.0
+	for z := -10.0; z <= 10; z += 0.25 {
+		p := platt.Probability(z)
+		if p < 0 || p > 1 || p <= previous {
+			t.Fatalf("probability %.4f at logit %.2f not increasing from %.4f", p, z, previous)
+		}
+		previous = p
+	}
+}
@@ -0,0 +1,322 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+)
+
+// ModelVersion is bumped when the feature set or file format changes;
+// models saved with another version are rejected on load
+const ModelVersion = 1
+
+// TrainConfig controls dataset construction and fitting
+type TrainConfig struct {
+	Horizon            int     `json:"horizon"`             // Bars ahead the label looks, default 5
+	Threshold          float64 `json:"threshold"`           // Forward return a positive label must exceed, default 0
+	ValidationFraction float64 `json:"validation_fraction"` // Most recent share of each series held out, default 0.2
+	L2                 float64 `json:"l2"`                  // Ridge penalty, default 1
+	MaxIterations      int     `json:"max_iterations"`      // Newton iterations, default 50
+}
+
+func (c TrainConfig) withDefaults() TrainConfig {
+	if c.Horizon <= 0 {
+		c.Horizon = 5
+	}
+	if c.ValidationFraction <= 0 || c.ValidationFraction >= 1 {
+		c.ValidationFraction = 0.2
+	}
+	if c.L2 <= 0 {
+		c.L2 = 1
+	}
+	if c.MaxIterations <= 0 {
+		c.MaxIterations = 50
+	}
+	return c
+}
+
+// Evaluation scores calibrated probabilities on data unseen by both the
+// regression and the calibration
+type Evaluation struct {
+	Samples     int              `json:"samples"`
+	BaseRate    float64          `json:"base_rate"` // Share of positive labels
+	Accuracy    float64          `json:"accuracy"`
+	LogLoss     float64          `json:"log_loss"`
+	Brier       float64          `json:"brier"`
+	AUC         float64          `json:"auc"`
+	Calibration []CalibrationBin `json:"calibration"`
+}
+
+// CalibrationBin compares predicted and observed frequency in one
+// probability decile
+type CalibrationBin struct {
+	Lower     float64 `json:"lower"`
+	Upper     float64 `json:"upper"`
+	Count     int     `json:"count"`
+	Predicted float64 `json:"predicted"`
+	Observed  float64 `json:"observed"`
+}
+
+// Model is a trained signal model ready to persist and serve
+type Model struct {
+	Version      int                 `json:"version"`
+	Symbols      []string            `json:"symbols"`
+	FeatureNames []string            `json:"feature_names"`
+	Config       TrainConfig         `json:"config"`
+	TrainedAt    time.Time           `json:"trained_at"`
+	TrainSamples int                 `json:"train_samples"`
+	Regression   *LogisticRegression `json:"regression"`
+	Calibration  PlattScaling        `json:"calibration"`
+	Validation   Evaluation          `json:"validation"`
+}
+
+// Train builds features and forward-return labels for each series and holds
+// out the most recent ValidationFraction of each. The regression is fitted on
+// the older bars, calibrated on the first half of the holdout and evaluated
+// on the second half.
+func Train(series [][]models.HistoricalData, cfg TrainConfig) (*Model, error) {
+	cfg = cfg.withDefaults()
+
+	var trainX, calibX, validX [][]float64
+	var trainY, calibY, validY []float64
+	var symbols []string
+
+	for _, data := range series {
+		if len(data) == 0 {
+			continue
+		}
+		bars := indicators.NewBars(data)
+		rows := BuildFeatures(bars)
+		labels := ForwardLabels(bars.Close, cfg.Horizon, cfg.Threshold)
+
+		var X [][]float64
+		var y []float64
+		for i, row := range rows {
+			if RowValid(row) && !math.IsNaN(labels[i]) {
+				X = append(X, row)
+				y = append(y, labels[i])
+			}
+		}
+
+		// Leave a gap of Horizon rows so training labels don't overlap the
+		// validation period
+		split := int(float64(len(X)) * (1 - cfg.ValidationFraction))
+		trainEnd := split - cfg.Horizon
+		if trainEnd < 1 || len(X)-split < 2 {
+			return nil, fmt.Errorf("insufficient data for %s: %d usable bars", bars.Symbol, len(X))
+		}
+		middle := split + (len(X)-split)/2
+		trainX, trainY = append(trainX, X[:trainEnd]...), append(trainY, y[:trainEnd]...)
+		calibX, calibY = append(calibX, X[split:middle]...), append(calibY, y[split:middle]...)
+		validX, validY = append(validX, X[middle:]...), append(validY, y[middle:]...)
+		symbols = append(symbols, bars.Symbol)
+	}
+
+	if len(trainX) < 10*len(FeatureNames) {
+		return nil, fmt.Errorf("insufficient training data: %d samples, need at least %d", len(trainX), 10*len(FeatureNames))
+	}
+	if !hasBothClasses(trainY) || !hasBothClasses(calibY) || !hasBothClasses(validY) {
+		return nil, fmt.Errorf("labels are all one class; adjust horizon or threshold")
+	}
+
+	regression, err := FitLogistic(trainX, trainY, cfg.L2, cfg.MaxIterations)
+	if err != nil {
+		return nil, err
+	}
+
+	logits := make([]float64, len(calibX))
+	for i, row := range calibX {
+		logits[i] = regression.Logit(row)
+	}
+	calibration, err := FitPlatt(logits, calibY)
+	if err != nil {
+		return nil, fmt.Errorf("calibration failed: %v", err)
+	}
+
+	probabilities := make([]float64, len(validX))
+	for i, row := range validX {
+		probabilities[i] = calibration.Probability(regression.Logit(row))
+	}
+
+	sort.Strings(symbols)
+	return &Model{
+		Version:      ModelVersion,
+		Symbols:      symbols,
+		FeatureNames: append([]string(nil), FeatureNames...),
+		Config:       cfg,
+		TrainedAt:    time.Now(),
+		TrainSamples: len(trainX),
+		Regression:   regression,
+		Calibration:  calibration,
+		Validation:   evaluate(probabilities, validY),
+	}, nil
+}
+
+// Prediction is the model output for the latest bar
+type Prediction struct {
+	Probability    float64            `json:"probability"`     // Calibrated P(forward return > threshold)
+	RawProbability float64            `json:"raw_probability"` // Before calibration
+	Features       map[string]float64 `json:"features"`
+}
+
+// Predict scores the latest bar of the data
+func (m *Model) Predict(data []models.HistoricalData) (*Prediction, error) {
+	rows := BuildFeatures(indicators.NewBars(data))
+	if len(rows) == 0 || !RowValid(rows[len(rows)-1]) {
+		return nil, fmt.Errorf("insufficient data for ML features")
+	}
+	row := rows[len(rows)-1]
+
+	logit := m.Regression.Logit(row)
+	prediction := &Prediction{
+		Probability:    m.Calibration.Probability(logit),
+		RawProbability: sigmoid(logit),
+		Features:       make(map[string]float64, len(row)),
+	}
+	for j, name := range m.FeatureNames {
+		prediction.Features[name] = row[j]
+	}
+	return prediction, nil
+}
+
+// Save writes the model as JSON, replacing the file atomically
+func (m *Model) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode model: %v", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create model directory: %v", err)
+		}
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write model: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write model: %v", err)
+	}
+	return nil
+}
+
+// Load reads a model saved by Save
+func Load(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var model Model
+	if err := json.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("invalid model file: %v", err)
+	}
+	if model.Version != ModelVersion {
+		return nil, fmt.Errorf("model version %d is not supported (expected %d); retrain the model", model.Version, ModelVersion)
+	}
+	if model.Regression == nil || len(model.Regression.Weights) != len(FeatureNames) || len(model.FeatureNames) != len(FeatureNames) {
+		return nil, fmt.Errorf("model features don't match this build; retrain the model")
+	}
+	return &model, nil
+}
+
+// evaluate computes validation metrics for calibrated probabilities
+func evaluate(probabilities, labels []float64) Evaluation {
+	eval := Evaluation{Samples: len(labels)}
+	if len(labels) == 0 {
+		return eval
+	}
+
+	var positives, correct float64
+	bins := make([]CalibrationBin, 10)
+	for b := range bins {
+		bins[b].Lower = float64(b) / 10
+		bins[b].Upper = float64(b+1) / 10
+	}
+
+	for i, p := range probabilities {
+		y := labels[i]
+		positives += y
+		if (p >= 0.5) == (y == 1) {
+			correct++
+		}
+		clipped := math.Min(math.Max(p, 1e-15), 1-1e-15)
+		eval.LogLoss -= y*math.Log(clipped) + (1-y)*math.Log(1-clipped)
+		eval.Brier += (p - y) * (p - y)
+
+		b := int(p * 10)
+		if b > 9 {
+			b = 9
+		}
+		bins[b].Count++
+		bins[b].Predicted += p
+		bins[b].Observed += y
+	}
+
+	n := float64(len(labels))
+	eval.BaseRate = positives / n
+	eval.Accuracy = correct / n
+	eval.LogLoss /= n
+	eval.Brier /= n
+	eval.AUC = auc(probabilities, labels)
+
+	for _, bin := range bins {
+		if bin.Count == 0 {
+			continue
+		}
+		bin.Predicted /= float64(bin.Count)
+		bin.Observed /= float64(bin.Count)
+		eval.Calibration = append(eval.Calibration, bin)
+	}
+	return eval
+}
+
+// auc is the probability that a random positive scores above a random
+// negative (Mann-Whitney U), with ties counted as half
+func auc(scores, labels []float64) float64 {
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return scores[order[a]] < scores[order[b]] })
+
+	var rankSum, positives float64
+	for i := 0; i < len(order); {
+		j := i
+		for j < len(order) && scores[order[j]] == scores[order[i]] {
+			j++
+		}
+		avgRank := float64(i+j+1) / 2 // Ranks are 1-based
+		for k := i; k < j; k++ {
+			if labels[order[k]] == 1 {
+				rankSum += avgRank
+				positives++
+			}
+		}
+		i = j
+	}
+
+	negatives := float64(len(labels)) - positives
+	if positives == 0 || negatives == 0 {
+		return 0.5
+	}
+	return (rankSum - positives*(positives+1)/2) / (positives * negatives)
+}
+
+func hasBothClasses(labels []float64) bool {
+	var ones int
+	for _, y := range labels {
+		if y == 1 {
+			ones++
+		}
+	}
+	return ones > 0 && ones < len(labels)
+}
@@ -0,0 +1,226 @@
+package ml
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+)
+
+// randomWalk returns n daily bars of a 1.5% random walk
+func randomWalk(symbol string, n int, seed int64) []models.HistoricalData {
+	rng := rand.New(rand.NewSource(seed))
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	price := 100.0
+	data := make([]models.HistoricalData, n)
+	for i := range data {
+		open := price
+		price *= 1 + rng.NormFloat64()*0.015
+		data[i] = models.HistoricalData{
+			Symbol: symbol,
+			Date:   start.AddDate(0, 0, i),
+			Open:   decimal.NewFromFloat(open),
+			High:   decimal.NewFromFloat(math.Max(open, price) * (1 + rng.Float64()*0.005)),
+			Low:    decimal.NewFromFloat(math.Min(open, price) * (1 - rng.Float64()*0.005)),
+			Close:  decimal.NewFromFloat(price),
+			Volume: 1000000 + rng.Int63n(500000),
+		}
+	}
+	return data
+}
+
+// usableRows counts the bars with a full feature row and a label
+func usableRows(data []models.HistoricalData, horizon int) int {
+	bars := indicators.NewBars(data)
+	labels := ForwardLabels(bars.Close, horizon, 0)
+	var n int
+	for i, row := range BuildFeatures(bars) {
+		if RowValid(row) && !math.IsNaN(labels[i]) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBuildFeaturesAlignment(t *testing.T) {
+	data := randomWalk("TEST", 120, 1)
+	bars := indicators.NewBars(data)
+	rows := BuildFeatures(bars)
+	if len(rows) != len(data) {
+		t.Fatalf("%d rows for %d bars", len(rows), len(data))
+	}
+
+	// The slowest feature, MACD, needs 26+9-1 bars
+	first := -1
+	for i, row := range rows {
+		if RowValid(row) {
+			first = i
+			break
+		}
+	}
+	if first != macdSlow+macdSignal-2 {
+		t.Fatalf("first complete row %d, want %d", first, macdSlow+macdSignal-2)
+	}
+	for i := first; i < len(rows); i++ {
+		if !RowValid(rows[i]) {
+			t.Fatalf("row %d incomplete after the warm-up", i)
+		}
+	}
+
+	// Row i describes bar i and nothing after it
+	truncated := BuildFeatures(indicators.NewBars(data[:80]))
+	for i := first; i < 80; i++ {
+		for j, name := range FeatureNames {
+			if math.Abs(truncated[i][j]-rows[i][j]) > 1e-9 {
+				t.Fatalf("%s at bar %d is %v with later bars and %v without", name, i, rows[i][j], truncated[i][j])
+			}
+		}
+	}
+	closes := bars.Close
+	if got := rows[100][1]; math.Abs(got-(closes[100]/closes[95]-1)) > 1e-12 {
+		t.Fatalf("return_5 at bar 100 = %v, want the return from bar 95", got)
+	}
+}
+
+func TestForwardLabels(t *testing.T) {
+	closes := []float64{100, 101, 0, 103, 102, 104}
+	labels := ForwardLabels(closes, 2, 0.01)
+	// 100→0 falls, 101→103 rises 2%, the zero close has no return,
+	// 103→104 rises under 1% and the last two have no future
+	want := []float64{0, 1, math.NaN(), 0, math.NaN(), math.NaN()}
+	for i := range want {
+		if labels[i] != want[i] && !(math.IsNaN(labels[i]) && math.IsNaN(want[i])) {
+			t.Fatalf("labels = %v, want %v", labels, want)
+		}
+	}
+}
+
+func TestTrainHoldsOutAGap(t *testing.T) {
+	series := [][]models.HistoricalData{randomWalk("AAA", 500, 1), randomWalk("BBB", 400, 2)}
+	for _, horizon := range []int{1, 5, 10} {
+		model, err := Train(series, TrainConfig{Horizon: horizon})
+		if err != nil {
+			t.Fatalf("horizon %d: %v", horizon, err)
+		}
+
+		// Each series trains on its first 80% less Horizon rows, so no
+		// training label looks into the held-out rows; the second half of
+		// the holdout is the validation set
+		var train, validation int
+		for _, data := range series {
+			n := usableRows(data, horizon)
+			split := int(float64(n) * 0.8)
+			train += split - horizon
+			validation += n - (split + (n-split)/2)
+		}
+		if model.TrainSamples != train || model.Validation.Samples != validation {
+			t.Fatalf("horizon %d: %d training and %d validation samples, want %d and %d",
+				horizon, model.TrainSamples, model.Validation.Samples, train, validation)
+		}
+		if strings.Join(model.Symbols, ",") != "AAA,BBB" || model.Config.Horizon != horizon || model.Version != ModelVersion {
+			t.Fatalf("horizon %d: model %+v", horizon, model)
+		}
+		if model.Validation.AUC < 0 || model.Validation.AUC > 1 || model.Validation.Brier > 0.5 {
+			t.Fatalf("horizon %d: validation %+v", horizon, model.Validation)
+		}
+	}
+}
+
+func TestTrainErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		series [][]models.HistoricalData
+		cfg    TrainConfig
+		want   string
+	}{
+		{"short series", [][]models.HistoricalData{randomWalk("AAA", 500, 1), randomWalk("BBB", 40, 2)}, TrainConfig{}, "insufficient data for BBB"},
+		{"too few samples", [][]models.HistoricalData{randomWalk("AAA", 100, 1)}, TrainConfig{}, "insufficient training data"},
+		{"one class", [][]models.HistoricalData{randomWalk("AAA", 500, 1)}, TrainConfig{Threshold: 1}, "one class"},
+		{"no series", nil, TrainConfig{}, "insufficient training data"},
+	}
+	for _, tt := range tests {
+		if _, err := Train(tt.series, tt.cfg); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	data := randomWalk("AAA", 500, 1)
+	model, err := Train([][]models.HistoricalData{data}, TrainConfig{Horizon: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "models", "signal.json")
+	if err := model.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file left behind: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := model.Predict(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := loaded.Predict(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Probability != after.Probability || loaded.Config != model.Config || loaded.Validation.AUC != model.Validation.AUC {
+		t.Fatalf("loaded model predicts %v, want %v", after.Probability, before.Probability)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(map[string]interface{})
+		want   string
+	}{
+		{"other version", func(m map[string]interface{}) { m["version"] = ModelVersion + 1 }, "version"},
+		{"missing feature", func(m map[string]interface{}) {
+			m["feature_names"] = m["feature_names"].([]interface{})[1:]
+		}, "features"},
+		{"missing weight", func(m map[string]interface{}) {
+			regression := m["regression"].(map[string]interface{})
+			regression["weights"] = regression["weights"].([]interface{})[1:]
+		}, "features"},
+		{"no regression", func(m map[string]interface{}) { delete(m, "regression") }, "features"},
+	}
+	for _, tt := range tests {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			t.Fatal(err)
+		}
+		tt.tamper(fields)
+		tampered := filepath.Join(t.TempDir(), "tampered.json")
+		raw, _ = json.Marshal(fields)
+		if err := os.WriteFile(tampered, raw, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(tampered); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("%s: error = %v, want it rejected for %s", tt.name, err, tt.want)
+		}
+	}
+
+	garbage := filepath.Join(t.TempDir(), "garbage.json")
+	os.WriteFile(garbage, []byte("{"), 0o644)
+	if _, err := Load(garbage); err == nil || !strings.Contains(err.Error(), "invalid model file") {
+		t.Fatalf("error = %v, want an invalid model file", err)
+	}
+}
@@ -61,7 +61,7 @@ type TechnicalIndicator struct {
 type TradingSignal struct {
 	ID            string                 `json:"id" db:"id"`
 	Symbol        string                 `json:"symbol" db:"symbol" validate:"required"`
-	Type          string                 `json:"type" db:"type" validate:"required"` // BUY, SELL, HOLD
+	Type          string                 `json:"type" db:"type" validate:"required"` // BUY, SELL, HOLD; EXIT closes a pairs position
 	Strength      decimal.Decimal        `json:"strength" db:"strength"`              // Signal strength 0-1
 	Price         decimal.Decimal        `json:"price" db:"price"`
 	TargetPrice   decimal.Decimal        `json:"target_price" db:"target_price"`
@@ -69,6 +69,7 @@ type TradingSignal struct {
 	Confidence    decimal.Decimal        `json:"confidence" db:"confidence"` // 0-1
 	Algorithm     string                 `json:"algorithm" db:"algorithm"`   // Algorithm that generated the signal
 	Indicators    map[string]interface{} `json:"indicators" db:"indicators"` // Supporting indicators
+	Rationale     *SignalRationale       `json:"rationale,omitempty" db:"rationale"` // Why the signal was generated
 	RiskLevel     string                 `json:"risk_level" db:"risk_level"` // LOW, MEDIUM, HIGH
 	TimeFrame     string                 `json:"time_frame" db:"time_frame"` // 1m, 5m, 15m, 1h, 1d
 	ExpirationTime time.Time             `json:"expiration_time" db:"expiration_time"`
@@ -76,6 +77,46 @@ type TradingSignal struct {
 	UpdatedAt     time.Time              `json:"updated_at" db:"updated_at"`
 }
 
+// SignalRationale explains a signal: the rules that fired and how each
+// contributed. Contributions across Reasons add up to the signal's strength
+// and confidence.
+type SignalRationale struct {
+	Summary    string            `json:"summary"`
+	Reasons    []SignalReason    `json:"reasons"`
+	Components []SignalComponent `json:"components,omitempty"` // Sub-signals combined by a composite
+}
+
+// SignalReason is a rule that fired or an adjustment that was applied
+type SignalReason struct {
+	Rule        string            `json:"rule"` // e.g. strong_buy, volume_confirmation
+	Description string            `json:"description"`
+	Conditions  []SignalCondition `json:"conditions,omitempty"`
+	Strength    float64           `json:"strength_contribution"`
+	Confidence  float64           `json:"confidence_contribution"`
+}
+
+// SignalCondition compares an observed value with a threshold
+type SignalCondition struct {
+	Indicator string  `json:"indicator"`
+	Operator  string  `json:"operator"` // <, <=, >, >= or ==
+	Threshold float64 `json:"threshold"`
+	Reference string  `json:"reference,omitempty"` // Parameter or indicator the threshold comes from
+	Observed  float64 `json:"observed"`
+	Passed    bool    `json:"passed"`
+}
+
+// SignalComponent is one sub-signal of a composite and its share of the vote
+type SignalComponent struct {
+	Key        string           `json:"key"`
+	Algorithm  string           `json:"algorithm"`
+	Type       string           `json:"type"`
+	Strength   float64          `json:"strength"`
+	Confidence float64          `json:"confidence"`
+	Weight     float64          `json:"weight"` // Share of the consensus after renormalization
+	Vote       float64          `json:"vote"`   // Weight times confidence, added to Type's tally
+	Rationale  *SignalRationale `json:"rationale,omitempty"`
+}
+
 // Portfolio represents a trading portfolio
 type Portfolio struct {
 	ID           string          `json:"id" db:"id"`
@@ -86,6 +127,8 @@ type Portfolio struct {
 	TotalReturn  decimal.Decimal `json:"total_return" db:"total_return"`
 	ReturnPercent decimal.Decimal `json:"return_percent" db:"return_percent"`
 	Beta         decimal.Decimal `json:"beta" db:"beta"`
+	BetaMeasured bool            `json:"beta_measured" db:"-"` // False when beta couldn't be measured and is left at zero
+	BetaExcluded []string        `json:"beta_excluded,omitempty" db:"-"` // Positions left out of beta, lacking history
 	Sharpe       decimal.Decimal `json:"sharpe" db:"sharpe"`
 	MaxDrawdown  decimal.Decimal `json:"max_drawdown" db:"max_drawdown"`
 	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
@@ -109,12 +152,51 @@ type Position struct {
 	UpdatedAt    time.Time       `json:"updated_at" db:"updated_at"`
 }
 
+// Transaction types
+const (
+	TransactionBuy        = "BUY"
+	TransactionSell       = "SELL"
+	TransactionDividend   = "DIVIDEND"
+	TransactionFee        = "FEE"
+	TransactionSplit      = "SPLIT"
+	TransactionDeposit    = "DEPOSIT"
+	TransactionWithdrawal = "WITHDRAWAL"
+)
+
+// Transaction is an entry in a portfolio's append-only ledger. Buys and
+// sells use Quantity, Price and Fee; dividends, fees, deposits and
+// withdrawals use Amount; splits use Ratio.
+type Transaction struct {
+	ID          string          `json:"id" db:"id"`
+	PortfolioID string          `json:"portfolio_id" db:"portfolio_id"`
+	Type        string          `json:"type" db:"type"` // BUY, SELL, DIVIDEND, FEE, SPLIT, DEPOSIT, WITHDRAWAL
+	Symbol      string          `json:"symbol,omitempty" db:"symbol"`
+	Quantity    decimal.Decimal `json:"quantity" db:"quantity"`
+	Price       decimal.Decimal `json:"price" db:"price"`
+	Fee         decimal.Decimal `json:"fee" db:"fee"`       // Commission on a buy or sell
+	Amount      decimal.Decimal `json:"amount" db:"amount"` // Cash moved by the other types, always positive
+	Ratio       decimal.Decimal `json:"ratio" db:"ratio"`   // Split: new shares per old share, e.g. 2 or 0.1
+	Lots        []LotSelection  `json:"lots,omitempty" db:"-"` // Sell: lots to close under specific-lot accounting
+	Note        string          `json:"note,omitempty" db:"note"`
+	Date        time.Time       `json:"date" db:"date"` // When the transaction took effect
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// LotSelection closes part of a lot, identified by the ID of the buy that
+// opened it
+type LotSelection struct {
+	LotID    string          `json:"lot_id"`
+	Quantity decimal.Decimal `json:"quantity"`
+}
+
 // RiskMetrics represents risk analysis for symbols or portfolios
 type RiskMetrics struct {
 	ID              string          `json:"id" db:"id"`
 	Symbol          string          `json:"symbol" db:"symbol"`
 	PortfolioID     string          `json:"portfolio_id" db:"portfolio_id"`
 	Volatility      decimal.Decimal `json:"volatility" db:"volatility"`           // Standard deviation
+	VolatilityModel string          `json:"volatility_model,omitempty" db:"volatility_model"`     // ewma or garch when VaR uses forecast volatility
+	ForecastVolatility decimal.Decimal `json:"forecast_volatility" db:"forecast_volatility"` // Annualized, over the VaR horizon
 	Beta            decimal.Decimal `json:"beta" db:"beta"`                       // Market beta
 	Alpha           decimal.Decimal `json:"alpha" db:"alpha"`                     // Jensen's alpha
 	SharpeRatio     decimal.Decimal `json:"sharpe_ratio" db:"sharpe_ratio"`       // Risk-adjusted return
@@ -123,14 +205,35 @@ type RiskMetrics struct {
 	VaR95           decimal.Decimal `json:"var_95" db:"var_95"`                   // Value at Risk 95%
 	VaR99           decimal.Decimal `json:"var_99" db:"var_99"`                   // Value at Risk 99%
 	ConditionalVaR  decimal.Decimal `json:"conditional_var" db:"conditional_var"` // Expected Shortfall
+	VaRMethod       string          `json:"var_method" db:"var_method"`           // historical, parametric, cornish_fisher or monte_carlo
+	VaRHorizon      int             `json:"var_horizon" db:"var_horizon"`         // Bars VaR and CVaR are measured over
+	VaRConfidence   float64         `json:"var_confidence" db:"var_confidence"`   // Confidence of ConditionalVaR
 	InformationRatio decimal.Decimal `json:"information_ratio" db:"information_ratio"`
 	TrackingError   decimal.Decimal `json:"tracking_error" db:"tracking_error"`
 	Correlation     decimal.Decimal `json:"correlation" db:"correlation"` // Market correlation
+	UpCapture       decimal.Decimal `json:"up_capture" db:"up_capture"`     // Share of benchmark gains captured, 1 = all
+	DownCapture     decimal.Decimal `json:"down_capture" db:"down_capture"` // Share of benchmark losses taken
+	Benchmark       string          `json:"benchmark,omitempty" db:"benchmark"` // Benchmark the relative metrics are measured against
+	DiversificationRatio decimal.Decimal `json:"diversification_ratio" db:"diversification_ratio"` // Portfolios: weighted position volatility over portfolio volatility
+	CovarianceShrinkage  float64         `json:"covariance_shrinkage,omitempty" db:"covariance_shrinkage"` // Portfolios: shrinkage intensity applied
+	RiskContributions    []RiskContribution `json:"risk_contributions,omitempty" db:"-"`               // Portfolios: each position's share of risk
 	RiskLevel       string          `json:"risk_level" db:"risk_level"`   // LOW, MEDIUM, HIGH
 	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
 }
 
+// RiskContribution is a position's share of portfolio risk. Component risk
+// and component VaR add up to the portfolio's volatility and VaR.
+type RiskContribution struct {
+	Symbol        string          `json:"symbol"`
+	Weight        decimal.Decimal `json:"weight"`         // Fraction of portfolio value
+	Volatility    decimal.Decimal `json:"volatility"`     // Annualized
+	MarginalRisk  decimal.Decimal `json:"marginal_risk"`  // Portfolio volatility added per unit of weight
+	ComponentRisk decimal.Decimal `json:"component_risk"` // Weight × marginal risk
+	PercentOfRisk decimal.Decimal `json:"percent_of_risk"`
+	ComponentVaR  decimal.Decimal `json:"component_var"`
+}
+
 // Alert represents price or condition-based alerts
 type Alert struct {
 	ID          string    `json:"id" db:"id"`
@@ -159,10 +262,18 @@ type MarketDataRequest struct {
 type TechnicalAnalysisRequest struct {
 	Symbol     string   `json:"symbol" validate:"required"`
 	TimeFrame  string   `json:"time_frame"`  // 1m, 5m, 15m, 1h, 1d
-	Indicators []string `json:"indicators"`  // RSI, MACD, SMA_20, EMA_12, etc.
+	Indicators []string `json:"indicators"`  // RSI, MACD, SMA_20, EMA_12, Pivots, SupportResistance, Divergence, Regime, RegimeHMM, etc.
 	Period     int      `json:"period"`      // Analysis period in days
 }
 
+// ExpressionRequest represents a request to evaluate an indicator expression
+type ExpressionRequest struct {
+	Symbol     string `json:"symbol" binding:"required"`
+	Expression string `json:"expression" binding:"required"` // e.g. crossover(EMA(close,9), EMA(close,21)) and RSI(close,14) < 40
+	Period     int    `json:"period"`                        // History in days
+	Lookback   int    `json:"lookback"`                      // Recent bars to return
+}
+
 // WebSocketMessage represents WebSocket message structure
 type WebSocketMessage struct {
 	Type      string      `json:"type"`      // subscribe, unsubscribe, data, error
@@ -479,7 +479,7 @@ func (mp *MockProvider) IsReady() bool {
 func (mp *MockProvider) GetRealtimeData(symbol string) (*models.MarketData, error) {
 	// Generate mock data
 	basePrice := decimal.NewFromFloat(150.0)
-	change := decimal.NewFromFloat(-2.5 + (5.0 * time.Now().Unix() % 100 / 100.0))
+	change := decimal.NewFromFloat(-2.5 + 5.0*float64(time.Now().Unix()%100)/100.0)
 	
 	return &models.MarketData{
 		Symbol:        symbol,
@@ -0,0 +1,178 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// Correlation methods
+const (
+	CorrelationPearson  = "pearson"  // Linear correlation of returns
+	CorrelationSpearman = "spearman" // Correlation of return ranks, robust to outliers
+)
+
+// Cluster linkage methods
+const (
+	LinkageSingle   = "single"   // Distance between the closest members
+	LinkageComplete = "complete" // Distance between the farthest members
+	LinkageAverage  = "average"  // Mean distance between members
+)
+
+// Correlation is the Pearson correlation of two equal-length series, 0 when
+// either doesn't vary
+func Correlation(a, b []float64) float64 {
+	if len(a) < 2 || stat.StdDev(a, nil) == 0 || stat.StdDev(b, nil) == 0 {
+		return 0
+	}
+	return stat.Correlation(a, b, nil)
+}
+
+// CorrelationMatrix correlates every pair of series, which must be aligned
+// bar for bar
+func CorrelationMatrix(series [][]float64, method string) ([][]float64, error) {
+	switch method {
+	case CorrelationPearson:
+	case CorrelationSpearman:
+		ranked := make([][]float64, len(series))
+		for i, values := range series {
+			ranked[i] = Ranks(values)
+		}
+		series = ranked
+	default:
+		return nil, fmt.Errorf("unknown correlation method %q (expected %s or %s)", method, CorrelationPearson, CorrelationSpearman)
+	}
+
+	matrix := make([][]float64, len(series))
+	for i := range series {
+		matrix[i] = make([]float64, len(series))
+		matrix[i][i] = 1
+	}
+	for i := range series {
+		for j := i + 1; j < len(series); j++ {
+			matrix[i][j] = Correlation(series[i], series[j])
+			matrix[j][i] = matrix[i][j]
+		}
+	}
+	return matrix, nil
+}
+
+// Ranks returns the 1-based rank of each value, ties sharing their average
+// rank
+func Ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+
+	ranks := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+		rank := float64(start+end+1) / 2 // Mean of ranks start+1 .. end
+		for _, i := range order[start:end] {
+			ranks[i] = rank
+		}
+		start = end
+	}
+	return ranks
+}
+
+// RollingCorrelation returns the Pearson correlation of the trailing window
+// ending at each bar from window-1 on
+func RollingCorrelation(a, b []float64, window int) []float64 {
+	if window < 2 || len(a) != len(b) || len(a) < window {
+		return nil
+	}
+	out := make([]float64, 0, len(a)-window+1)
+	for end := window; end <= len(a); end++ {
+		out = append(out, Correlation(a[end-window:end], b[end-window:end]))
+	}
+	return out
+}
+
+// ClusterMerge is one step of agglomerative clustering. Clusters 0..n-1 are
+// the original items and the i-th merge creates cluster n+i.
+type ClusterMerge struct {
+	Left     int     `json:"left"`
+	Right    int     `json:"right"`
+	Distance float64 `json:"distance"`
+	Size     int     `json:"size"` // Items in the merged cluster
+}
+
+// ClusterOrder clusters items by the correlation distance sqrt((1 - ρ) / 2)
+// and returns the dendrogram's leaf order, which places correlated items
+// next to each other, along with the merges that built it
+func ClusterOrder(correlation [][]float64, linkage string) ([]int, []ClusterMerge, error) {
+	switch linkage {
+	case LinkageSingle, LinkageComplete, LinkageAverage:
+	default:
+		return nil, nil, fmt.Errorf("unknown linkage %q (expected %s, %s or %s)", linkage, LinkageSingle, LinkageComplete, LinkageAverage)
+	}
+	n := len(correlation)
+	if n == 0 {
+		return nil, nil, nil
+	}
+
+	// Distances between active clusters, keyed by cluster ID
+	distance := make(map[int]map[int]float64, n)
+	members := make(map[int][]int, 2*n-1)
+	for i := 0; i < n; i++ {
+		members[i] = []int{i}
+		distance[i] = make(map[int]float64, n)
+		for j := 0; j < n; j++ {
+			if i != j {
+				distance[i][j] = math.Sqrt(math.Max(0, (1-correlation[i][j])/2))
+			}
+		}
+	}
+
+	merges := make([]ClusterMerge, 0, n-1)
+	for next := n; len(distance) > 1; next++ {
+		// Closest pair; ties go to the lowest IDs so results are repeatable
+		left, right, closest := -1, -1, math.Inf(1)
+		for a, row := range distance {
+			for b, d := range row {
+				if a < b && (d < closest || d == closest && (a < left || a == left && b < right)) {
+					left, right, closest = a, b, d
+				}
+			}
+		}
+
+		merged := append(append([]int(nil), members[left]...), members[right]...)
+		members[next] = merged
+		merges = append(merges, ClusterMerge{Left: left, Right: right, Distance: closest, Size: len(merged)})
+
+		row := make(map[int]float64, len(distance))
+		for other := range distance {
+			if other == left || other == right {
+				continue
+			}
+			dl, dr := distance[left][other], distance[right][other]
+			var d float64
+			switch linkage {
+			case LinkageSingle:
+				d = math.Min(dl, dr)
+			case LinkageComplete:
+				d = math.Max(dl, dr)
+			case LinkageAverage:
+				nl, nr := float64(len(members[left])), float64(len(members[right]))
+				d = (dl*nl + dr*nr) / (nl + nr)
+			}
+			row[other] = d
+			distance[other][next] = d
+			delete(distance[other], left)
+			delete(distance[other], right)
+		}
+		delete(distance, left)
+		delete(distance, right)
+		distance[next] = row
+	}
+
+	return members[2*n-2], merges, nil
+}
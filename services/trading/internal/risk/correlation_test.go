@@ -0,0 +1,165 @@
+package risk
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRanks(t *testing.T) {
+	got := Ranks([]float64{30, 10, 20, 10, 50, 20, 20})
+	want := []float64{6, 1.5, 4, 1.5, 7, 4, 4}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("ranks = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCorrelationMatrix(t *testing.T) {
+	x := normalReturns(200, 0, 0.02, 1)
+	linear := make([]float64, len(x))
+	cubed := make([]float64, len(x))
+	flat := make([]float64, len(x))
+	for i, v := range x {
+		linear[i] = 3*v + 0.01
+		cubed[i] = v * v * v
+	}
+	series := [][]float64{x, linear, cubed, flat}
+
+	pearson, err := CorrelationMatrix(series, CorrelationPearson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spearman, err := CorrelationMatrix(series, CorrelationSpearman)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range series {
+		for j := range series {
+			if pearson[i][j] != pearson[j][i] || spearman[i][j] != spearman[j][i] {
+				t.Fatalf("matrices not symmetric at [%d,%d]", i, j)
+			}
+		}
+		if pearson[i][i] != 1 || spearman[i][i] != 1 {
+			t.Fatalf("diagonal [%d] not 1", i)
+		}
+	}
+	if math.Abs(pearson[0][1]-1) > 1e-12 {
+		t.Fatalf("pearson of a linear transform = %v, want 1", pearson[0][1])
+	}
+	// Cubing keeps the ranks but bends the relationship
+	if math.Abs(spearman[0][2]-1) > 1e-12 || pearson[0][2] >= 0.99 {
+		t.Fatalf("cubed: spearman %v (want 1), pearson %v (want below 1)", spearman[0][2], pearson[0][2])
+	}
+	if pearson[0][3] != 0 || spearman[0][3] != 0 {
+		t.Fatalf("correlation with a flat series = %v / %v, want 0", pearson[0][3], spearman[0][3])
+	}
+
+	if _, err := CorrelationMatrix(series, "kendall"); err == nil {
+		t.Fatal("expected an unknown method to be rejected")
+	}
+}
+
+func TestRollingCorrelation(t *testing.T) {
+	a := normalReturns(100, 0, 0.01, 2)
+	b := make([]float64, len(a))
+	for i := range a {
+		b[i] = a[i]
+		if i >= 50 {
+			b[i] = -a[i] // The relationship flips halfway through
+		}
+	}
+	rolling := RollingCorrelation(a, b, 20)
+	if len(rolling) != 81 {
+		t.Fatalf("%d values, want 81", len(rolling))
+	}
+	if math.Abs(rolling[0]-1) > 1e-12 || math.Abs(rolling[30]-1) > 1e-12 {
+		t.Fatalf("windows before the flip = %v, %v, want 1", rolling[0], rolling[30])
+	}
+	if math.Abs(rolling[80]+1) > 1e-12 {
+		t.Fatalf("last window = %v, want -1", rolling[80])
+	}
+	if want := Correlation(a[40:60], b[40:60]); rolling[40] != want {
+		t.Fatalf("window ending at bar 59 = %v, want %v", rolling[40], want)
+	}
+
+	if RollingCorrelation(a, b[:50], 20) != nil || RollingCorrelation(a[:10], b[:10], 20) != nil {
+		t.Fatal("expected nil for mismatched or short series")
+	}
+}
+
+func TestClusterOrderGroupsCorrelatedItems(t *testing.T) {
+	// Items 0, 2, 4 move together, as do 1, 3, 5; the two groups are
+	// unrelated
+	correlation := make([][]float64, 6)
+	for i := range correlation {
+		correlation[i] = make([]float64, 6)
+		for j := range correlation[i] {
+			switch {
+			case i == j:
+				correlation[i][j] = 1
+			case i%2 == j%2:
+				correlation[i][j] = 0.8
+			default:
+				correlation[i][j] = 0.1
+			}
+		}
+	}
+
+	for _, linkage := range []string{LinkageSingle, LinkageComplete, LinkageAverage} {
+		order, merges, err := ClusterOrder(correlation, linkage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(order) != 6 || len(merges) != 5 {
+			t.Fatalf("%s: %d leaves and %d merges", linkage, len(order), len(merges))
+		}
+		for i := 0; i < 3; i++ {
+			if order[i]%2 != order[0]%2 || order[i+3]%2 != order[3]%2 || order[0]%2 == order[3]%2 {
+				t.Fatalf("%s: order %v splits the groups", linkage, order)
+			}
+		}
+		for i := 1; i < len(merges); i++ {
+			if merges[i].Distance < merges[i-1].Distance {
+				t.Fatalf("%s: merge distances decrease: %+v", linkage, merges)
+			}
+		}
+		last := merges[len(merges)-1]
+		if last.Size != 6 || math.Abs(last.Distance-math.Sqrt(0.45)) > 1e-12 {
+			t.Fatalf("%s: final merge %+v, want size 6 at distance sqrt((1-0.1)/2)", linkage, last)
+		}
+	}
+}
+
+func TestClusterOrderLinkage(t *testing.T) {
+	// 0 and 1 merge first; their distance to 2 depends on the linkage
+	correlation := [][]float64{
+		{1, 0.9, 0.5},
+		{0.9, 1, -0.5},
+		{0.5, -0.5, 1},
+	}
+	d02, d12 := math.Sqrt(0.25), math.Sqrt(0.75)
+	for linkage, want := range map[string]float64{
+		LinkageSingle:   d02,
+		LinkageComplete: d12,
+		LinkageAverage:  (d02 + d12) / 2,
+	} {
+		_, merges, err := ClusterOrder(correlation, linkage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if merges[0].Left != 0 || merges[0].Right != 1 || merges[0].Size != 2 {
+			t.Fatalf("%s: first merge %+v, want 0 and 1", linkage, merges[0])
+		}
+		if merges[1].Left != 2 || merges[1].Right != 3 || math.Abs(merges[1].Distance-want) > 1e-12 {
+			t.Fatalf("%s: second merge %+v, want 2 and 3 at %v", linkage, merges[1], want)
+		}
+	}
+
+	if _, _, err := ClusterOrder(correlation, "ward"); err == nil {
+		t.Fatal("expected an unknown linkage to be rejected")
+	}
+	if order, merges, err := ClusterOrder(nil, LinkageAverage); err != nil || order != nil || merges != nil {
+		t.Fatalf("empty input: %v %v %v", order, merges, err)
+	}
+}
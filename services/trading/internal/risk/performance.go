@@ -0,0 +1,112 @@
+package risk
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/stat"
+	"trading-service/internal/indicators"
+)
+
+// SharpeRatio is the annualized mean excess return over its volatility.
+// The risk-free rate is annual.
+func SharpeRatio(returns []float64, riskFreeRate, periodsPerYear float64) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+	mean, stdDev := stat.MeanStdDev(returns, nil)
+	if stdDev == 0 {
+		return 0
+	}
+	return (mean - riskFreeRate/periodsPerYear) / stdDev * math.Sqrt(periodsPerYear)
+}
+
+// SortinoRatio is the annualized mean excess return over the downside
+// deviation, the root mean square of returns below the risk-free rate
+func SortinoRatio(returns []float64, riskFreeRate, periodsPerYear float64) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+	target := riskFreeRate / periodsPerYear
+	var downside float64
+	for _, r := range returns {
+		if r < target {
+			downside += (r - target) * (r - target)
+		}
+	}
+	downside = math.Sqrt(downside / float64(len(returns)))
+	if downside == 0 {
+		return 0
+	}
+	return (stat.Mean(returns, nil) - target) / downside * math.Sqrt(periodsPerYear)
+}
+
+// RelativeMetrics compares returns with a benchmark's over the same bars
+type RelativeMetrics struct {
+	Beta             float64 `json:"beta"`
+	Alpha            float64 `json:"alpha"` // Jensen's alpha, annualized
+	Correlation      float64 `json:"correlation"`
+	TrackingError    float64 `json:"tracking_error"`    // Annualized volatility of active returns
+	InformationRatio float64 `json:"information_ratio"` // Annualized active return over tracking error
+	UpCapture        float64 `json:"up_capture"`        // Share of the benchmark's gains captured on its up bars, 1 = all
+	DownCapture      float64 `json:"down_capture"`      // Share of the benchmark's losses taken on its down bars
+	Observations     int     `json:"observations"`      // Returns compared
+}
+
+// CompareToBenchmark computes benchmark-relative metrics from closing
+// prices. Both series must be aligned bar for bar.
+func CompareToBenchmark(closes, benchmarkCloses []float64, riskFreeRate, periodsPerYear float64) (*RelativeMetrics, error) {
+	if len(closes) != len(benchmarkCloses) || len(closes) <= minReturns {
+		return nil, ErrInsufficientData
+	}
+	beta, err := indicators.BetaFloat(closes, benchmarkCloses, len(closes)-1)
+	if err != nil {
+		return nil, err
+	}
+	returns := indicators.Returns(closes)
+	benchmark := indicators.Returns(benchmarkCloses)
+
+	m := &RelativeMetrics{Beta: beta, Observations: len(returns)}
+	if stat.StdDev(returns, nil) > 0 && stat.StdDev(benchmark, nil) > 0 {
+		m.Correlation = stat.Correlation(returns, benchmark, nil)
+	}
+
+	rf := riskFreeRate / periodsPerYear
+	m.Alpha = ((stat.Mean(returns, nil) - rf) - m.Beta*(stat.Mean(benchmark, nil)-rf)) * periodsPerYear
+
+	active := make([]float64, len(returns))
+	for i := range returns {
+		active[i] = returns[i] - benchmark[i]
+	}
+	activeMean, activeStdDev := stat.MeanStdDev(active, nil)
+	m.TrackingError = activeStdDev * math.Sqrt(periodsPerYear)
+	if m.TrackingError > 0 {
+		m.InformationRatio = activeMean * periodsPerYear / m.TrackingError
+	}
+
+	m.UpCapture = captureRatio(returns, benchmark, func(r float64) bool { return r > 0 })
+	m.DownCapture = captureRatio(returns, benchmark, func(r float64) bool { return r < 0 })
+	return m, nil
+}
+
+// captureRatio divides the geometric mean return on the bars the benchmark
+// selects by the benchmark's own over those bars, 0 when none are selected
+func captureRatio(returns, benchmark []float64, selected func(float64) bool) float64 {
+	growth, benchmarkGrowth := 1.0, 1.0
+	var n int
+	for i, r := range benchmark {
+		if selected(r) {
+			growth *= 1 + returns[i]
+			benchmarkGrowth *= 1 + r
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	mean := math.Pow(math.Max(growth, 0), 1/float64(n)) - 1
+	benchmarkMean := math.Pow(math.Max(benchmarkGrowth, 0), 1/float64(n)) - 1
+	if benchmarkMean == 0 {
+		return 0
+	}
+	return mean / benchmarkMean
+}
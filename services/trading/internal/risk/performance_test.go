@@ -0,0 +1,93 @@
+package risk
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// compound turns returns into closes starting at 100
+func compound(returns []float64) []float64 {
+	closes := []float64{100}
+	for _, r := range returns {
+		closes = append(closes, closes[len(closes)-1]*(1+r))
+	}
+	return closes
+}
+
+func TestCompareToBenchmark(t *testing.T) {
+	benchmark := normalReturns(250, 0.0005, 0.01, 7)
+	// Twice the benchmark plus 10bp a bar
+	returns := make([]float64, len(benchmark))
+	active := make([]float64, len(benchmark))
+	for i, r := range benchmark {
+		returns[i] = 2*r + 0.001
+		active[i] = returns[i] - r
+	}
+
+	m, err := CompareToBenchmark(compound(returns), compound(benchmark), 0.0252, 252)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Observations != 250 || math.Abs(m.Beta-2) > 1e-6 || math.Abs(m.Correlation-1) > 1e-9 {
+		t.Fatalf("metrics = %+v, want 250 observations with beta 2 and correlation 1", m)
+	}
+	// Excess return over beta times the benchmark's: 10bp plus the
+	// risk-free rate a bar, because beta 2 is financed at it
+	if math.Abs(m.Alpha-0.0011*252) > 1e-6 {
+		t.Fatalf("alpha = %v, want %v", m.Alpha, 0.0011*252)
+	}
+	activeMean, activeStdDev := stat.MeanStdDev(active, nil)
+	wantTE := activeStdDev * math.Sqrt(252)
+	if math.Abs(m.TrackingError-wantTE) > 1e-9 || math.Abs(m.InformationRatio-activeMean*252/wantTE) > 1e-6 {
+		t.Fatalf("tracking error %v information ratio %v, want %v and %v", m.TrackingError, m.InformationRatio, wantTE, activeMean*252/wantTE)
+	}
+	if m.UpCapture <= 2 || m.DownCapture >= 2 || m.DownCapture <= 1 {
+		t.Fatalf("capture up %v down %v, want over 2 up (the 10bp helps) and between 1 and 2 down", m.UpCapture, m.DownCapture)
+	}
+
+	self, err := CompareToBenchmark(compound(benchmark), compound(benchmark), 0.0252, 252)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(self.Beta-1) > 1e-9 || math.Abs(self.Alpha) > 1e-9 || self.TrackingError != 0 || self.InformationRatio != 0 ||
+		math.Abs(self.UpCapture-1) > 1e-9 || math.Abs(self.DownCapture-1) > 1e-9 {
+		t.Fatalf("benchmark against itself = %+v, want beta and captures 1 with no alpha or tracking error", self)
+	}
+
+	if _, err := CompareToBenchmark(compound(returns), compound(benchmark[1:]), 0, 252); !errors.Is(err, ErrInsufficientData) {
+		t.Fatalf("misaligned series error = %v, want ErrInsufficientData", err)
+	}
+	if _, err := CompareToBenchmark(compound(returns[:minReturns-1]), compound(benchmark[:minReturns-1]), 0, 252); !errors.Is(err, ErrInsufficientData) {
+		t.Fatalf("short series error = %v, want ErrInsufficientData", err)
+	}
+}
+
+func TestCaptureRatio(t *testing.T) {
+	up := func(r float64) bool { return r > 0 }
+	down := func(r float64) bool { return r < 0 }
+
+	tests := []struct {
+		name      string
+		returns   []float64
+		benchmark []float64
+		selected  func(float64) bool
+		want      float64
+	}{
+		{"up bar", []float64{0.1, -0.05, 0.02}, []float64{0.05, -0.1, 0}, up, 2},
+		{"down bar", []float64{0.1, -0.05, 0.02}, []float64{0.05, -0.1, 0}, down, 0.5},
+		// 21% then flat grows as much as 10% twice
+		{"geometric mean", []float64{0.21, 0}, []float64{0.1, 0.1}, up, 1},
+		{"nothing selected", []float64{0.1, 0.2}, []float64{0.05, 0.1}, down, 0},
+		{"flat benchmark", []float64{0.1}, []float64{0}, func(float64) bool { return true }, 0},
+		// Losing everything caps the mean at -100%
+		{"wiped out", []float64{-1.5}, []float64{-0.5}, down, 2},
+	}
+	for _, tt := range tests {
+		if got := captureRatio(tt.returns, tt.benchmark, tt.selected); math.Abs(got-tt.want) > 1e-9 {
+			t.Fatalf("%s: capture = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// AutoShrinkage selects the Ledoit-Wolf estimate of the shrinkage intensity
+const AutoShrinkage = -1
+
+// PortfolioRiskConfig controls PortfolioRisk
+type PortfolioRiskConfig struct {
+	VaR            VaRConfig
+	Confidences    []float64 // Further VaR levels, estimated from the same sample as VaR
+	Shrinkage      float64   // Covariance shrinkage toward a scaled identity, 0-1, or AutoShrinkage
+	PeriodsPerYear float64   // Annualizes volatilities, e.g. 252 for daily returns
+}
+
+// Validate checks the configuration
+func (c PortfolioRiskConfig) Validate() error {
+	if err := checkShrinkage(c.Shrinkage); err != nil {
+		return err
+	}
+	if !(c.PeriodsPerYear > 0) || math.IsInf(c.PeriodsPerYear, 0) {
+		return fmt.Errorf("periods per year must be positive")
+	}
+	for _, confidence := range c.Confidences {
+		if err := checkConfidence(confidence); err != nil {
+			return err
+		}
+	}
+	return c.VaR.Validate()
+}
+
+// checkShrinkage accepts AutoShrinkage or an intensity in [0, 1], NaN
+// excluded
+func checkShrinkage(shrinkage float64) error {
+	if shrinkage != AutoShrinkage && !(shrinkage >= 0 && shrinkage <= 1) {
+		return fmt.Errorf("shrinkage must be between 0 and 1, or auto")
+	}
+	return nil
+}
+
+// Contribution is one asset's share of portfolio risk. Component risks add
+// up to the portfolio volatility and component VaRs to the portfolio VaR.
+type Contribution struct {
+	Weight        float64 `json:"weight"`         // Fraction of portfolio value
+	Volatility    float64 `json:"volatility"`     // The asset's annualized volatility under the shrunk covariance
+	MarginalRisk  float64 `json:"marginal_risk"`  // Change in portfolio volatility per unit of weight
+	ComponentRisk float64 `json:"component_risk"` // Weight × marginal risk
+	PercentOfRisk float64 `json:"percent_of_risk"`
+	ComponentVaR  float64 `json:"component_var"` // Portfolio VaR apportioned by share of risk
+}
+
+// PortfolioRiskResult is the risk of a weighted portfolio of assets
+type PortfolioRiskResult struct {
+	Volatility           float64        `json:"volatility"` // Annualized
+	VaR                  *VaRResult     `json:"var"`
+	Levels               []*VaRResult   `json:"levels,omitempty"`      // At the configured Confidences, in order
+	Shrinkage            float64        `json:"shrinkage"`             // Intensity applied to the covariance matrix
+	DiversificationRatio float64        `json:"diversification_ratio"` // Weighted asset volatility over portfolio volatility
+	Contributions        []Contribution `json:"contributions"`         // In asset order
+	Covariance           *mat.SymDense  `json:"-"`                     // Per-bar covariance of asset returns
+	Returns              []float64      `json:"-"`                     // Per-bar portfolio returns at the weights
+}
+
+// ShrunkCovariance estimates the covariance of asset returns (one series per
+// asset, aligned bar for bar), shrunk toward a scaled identity matrix. With
+// AutoShrinkage the intensity is the Ledoit-Wolf estimate, which pulls noisy
+// estimates from short histories toward the average variance.
+func ShrunkCovariance(returns [][]float64, shrinkage float64) (*mat.SymDense, float64, error) {
+	assets := len(returns)
+	if assets == 0 {
+		return nil, 0, fmt.Errorf("no assets")
+	}
+	bars := len(returns[0])
+	for _, series := range returns {
+		if len(series) != bars {
+			return nil, 0, fmt.Errorf("asset return series differ in length")
+		}
+	}
+	if bars < minReturns {
+		return nil, 0, ErrInsufficientData
+	}
+
+	centered := mat.NewDense(bars, assets, nil)
+	for j, series := range returns {
+		mean := stat.Mean(series, nil)
+		for t, r := range series {
+			centered.Set(t, j, r-mean)
+		}
+	}
+	sample := mat.NewSymDense(assets, nil)
+	sample.SymOuterK(1/float64(bars-1), centered.T())
+
+	var target float64
+	for i := 0; i < assets; i++ {
+		target += sample.At(i, i)
+	}
+	target /= float64(assets)
+
+	if shrinkage == AutoShrinkage {
+		shrinkage = ledoitWolfIntensity(centered, sample, target)
+	}
+
+	shrunk := mat.NewSymDense(assets, nil)
+	for i := 0; i < assets; i++ {
+		for j := i; j < assets; j++ {
+			value := (1 - shrinkage) * sample.At(i, j)
+			if i == j {
+				value += shrinkage * target
+			}
+			shrunk.SetSym(i, j, value)
+		}
+	}
+	return shrunk, shrinkage, nil
+}
+
+// ledoitWolfIntensity estimates the optimal shrinkage toward target × I as
+// the variance of the sample covariance over its distance from the target
+// (Ledoit and Wolf, 2004)
+func ledoitWolfIntensity(centered *mat.Dense, sample *mat.SymDense, target float64) float64 {
+	bars, assets := centered.Dims()
+
+	var distance float64
+	for i := 0; i < assets; i++ {
+		for j := 0; j < assets; j++ {
+			d := sample.At(i, j)
+			if i == j {
+				d -= target
+			}
+			distance += d * d
+		}
+	}
+	if distance == 0 {
+		return 0
+	}
+
+	var variance float64
+	for t := 0; t < bars; t++ {
+		row := centered.RawRowView(t)
+		for i := 0; i < assets; i++ {
+			for j := 0; j < assets; j++ {
+				d := row[i]*row[j] - sample.At(i, j)
+				variance += d * d
+			}
+		}
+	}
+	variance /= float64(bars) * float64(bars)
+
+	return math.Min(variance, distance) / distance
+}
+
+// PortfolioRisk measures a portfolio holding the weights of each asset.
+// Weights are fractions of portfolio value and may sum to less than 1 when
+// the rest is cash. Parametric VaR uses the shrunk covariance matrix; the
+// other methods use the portfolio's return series at today's weights.
+func PortfolioRisk(weights []float64, returns [][]float64, cfg PortfolioRiskConfig) (*PortfolioRiskResult, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	m, err := portfolioMoments(weights, returns, cfg.Shrinkage)
+	if err != nil {
+		return nil, err
+	}
+	volatility := math.Sqrt(m.variance)
+	annualize := math.Sqrt(cfg.PeriodsPerYear)
+
+	// Every level comes from one estimate, so Monte Carlo simulates once
+	varCfg := cfg.VaR.withDefaults()
+	levels := append([]float64{varCfg.Confidence}, cfg.Confidences...)
+	var estimates []*VaRResult
+	if varCfg.Method == VaRParametric {
+		h := float64(varCfg.Horizon)
+		mean := stat.Mean(m.returns, nil)
+		for _, confidence := range levels {
+			estimate := &VaRResult{Method: varCfg.Method, Confidence: confidence, Horizon: varCfg.Horizon}
+			estimate.VaR, estimate.CVaR = normalTail(mean*h, volatility*math.Sqrt(h), 1-confidence)
+			estimates = append(estimates, estimate)
+		}
+	} else if estimates, err = ValueAtRiskLevels(m.returns, varCfg, levels...); err != nil {
+		return nil, err
+	}
+	estimate := estimates[0]
+
+	result := &PortfolioRiskResult{
+		Volatility:    volatility * annualize,
+		VaR:           estimate,
+		Levels:        estimates[1:],
+		Shrinkage:     m.shrinkage,
+		Contributions: make([]Contribution, len(weights)),
+		Covariance:    m.covariance,
+		Returns:       m.returns,
+	}
+
+	var weightedVolatility float64
+	for i, weight := range weights {
+		assetVolatility := math.Sqrt(m.covariance.At(i, i))
+		marginal := m.sigmaW.AtVec(i) / volatility
+		component := weight * marginal
+		result.Contributions[i] = Contribution{
+			Weight:        weight,
+			Volatility:    assetVolatility * annualize,
+			MarginalRisk:  marginal * annualize,
+			ComponentRisk: component * annualize,
+			PercentOfRisk: component / volatility,
+			ComponentVaR:  estimate.VaR * component / volatility,
+		}
+		weightedVolatility += math.Abs(weight) * assetVolatility
+	}
+	result.DiversificationRatio = weightedVolatility / volatility
+
+	return result, nil
+}
+
+// PortfolioVolatility is the annualized volatility PortfolioRisk reports,
+// with the per-bar portfolio returns, without estimating VaR
+func PortfolioVolatility(weights []float64, returns [][]float64, shrinkage, periodsPerYear float64) (float64, []float64, error) {
+	if err := checkShrinkage(shrinkage); err != nil {
+		return 0, nil, err
+	}
+	m, err := portfolioMoments(weights, returns, shrinkage)
+	if err != nil {
+		return 0, nil, err
+	}
+	return math.Sqrt(m.variance * periodsPerYear), m.returns, nil
+}
+
+// moments is a weighted portfolio under the shrunk covariance of its assets
+type moments struct {
+	covariance *mat.SymDense
+	shrinkage  float64
+	sigmaW     mat.VecDense // Covariance × weights
+	variance   float64      // Per-bar portfolio variance
+	returns    []float64    // Per-bar portfolio returns
+}
+
+func portfolioMoments(weights []float64, returns [][]float64, shrinkage float64) (*moments, error) {
+	if len(weights) != len(returns) {
+		return nil, fmt.Errorf("%d weights for %d assets", len(weights), len(returns))
+	}
+	covariance, shrinkage, err := ShrunkCovariance(returns, shrinkage)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &moments{covariance: covariance, shrinkage: shrinkage}
+	w := mat.NewVecDense(len(weights), append([]float64(nil), weights...))
+	m.sigmaW.MulVec(covariance, w)
+	m.variance = mat.Dot(w, &m.sigmaW)
+	if m.variance <= 0 {
+		return nil, fmt.Errorf("portfolio has no variance")
+	}
+
+	m.returns = make([]float64, len(returns[0]))
+	for i, series := range returns {
+		for t, r := range series {
+			m.returns[t] += weights[i] * r
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,189 @@
+package risk
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// correlatedReturns draws bars of returns for assets sharing one market
+// factor with the given loading and idiosyncratic volatility
+func correlatedReturns(assets, bars int, loading, noise float64, seed int64) [][]float64 {
+	rng := rand.New(rand.NewSource(seed))
+	returns := make([][]float64, assets)
+	for i := range returns {
+		returns[i] = make([]float64, bars)
+	}
+	for t := 0; t < bars; t++ {
+		market := rng.NormFloat64() * 0.01
+		for i := range returns {
+			returns[i][t] = loading*market + noise*rng.NormFloat64()
+		}
+	}
+	return returns
+}
+
+func TestShrunkCovariance(t *testing.T) {
+	returns := correlatedReturns(3, 200, 1, 0.01, 1)
+	data := mat.NewDense(200, 3, nil)
+	for j, series := range returns {
+		data.SetCol(j, series)
+	}
+	sample := mat.NewSymDense(3, nil)
+	stat.CovarianceMatrix(sample, data, nil)
+
+	unshrunk, intensity, err := ShrunkCovariance(returns, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if intensity != 0 || !mat.EqualApprox(unshrunk, sample, 1e-12) {
+		t.Fatal("zero shrinkage should return the sample covariance")
+	}
+
+	target := (sample.At(0, 0) + sample.At(1, 1) + sample.At(2, 2)) / 3
+	full, _, err := ShrunkCovariance(returns, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			want := 0.0
+			if i == j {
+				want = target
+			}
+			if math.Abs(full.At(i, j)-want) > 1e-15 {
+				t.Fatalf("full shrinkage [%d,%d] = %v, want %v", i, j, full.At(i, j), want)
+			}
+		}
+	}
+
+	if _, _, err := ShrunkCovariance([][]float64{returns[0], returns[1][:100]}, 0); err == nil {
+		t.Fatal("expected an error for series of different lengths")
+	}
+}
+
+func TestLedoitWolfIntensity(t *testing.T) {
+	// Noisier estimates from shorter histories call for more shrinkage
+	_, short, err := ShrunkCovariance(correlatedReturns(10, 30, 0.5, 0.01, 2), AutoShrinkage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, long, err := ShrunkCovariance(correlatedReturns(10, 3000, 0.5, 0.01, 2), AutoShrinkage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if short < 0 || short > 1 || long < 0 || long > 1 {
+		t.Fatalf("intensities %v and %v outside [0, 1]", short, long)
+	}
+	if !(short > long) {
+		t.Fatalf("30-bar intensity %v not above 3000-bar intensity %v", short, long)
+	}
+}
+
+func TestPortfolioRiskDecomposition(t *testing.T) {
+	returns := correlatedReturns(4, 500, 1, 0.01, 3)
+	weights := []float64{0.4, 0.3, 0.2, 0.05} // 5% cash
+	cfg := PortfolioRiskConfig{
+		VaR:            VaRConfig{Method: VaRMonteCarlo, Confidence: 0.975, Simulations: 5000},
+		Confidences:    []float64{0.95, 0.99},
+		Shrinkage:      AutoShrinkage,
+		PeriodsPerYear: 252,
+	}
+	result, err := PortfolioRisk(weights, returns, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var componentRisk, componentVaR, percent float64
+	for _, c := range result.Contributions {
+		componentRisk += c.ComponentRisk
+		componentVaR += c.ComponentVaR
+		percent += c.PercentOfRisk
+	}
+	if math.Abs(componentRisk-result.Volatility) > 1e-12 {
+		t.Fatalf("component risks sum to %v, volatility %v", componentRisk, result.Volatility)
+	}
+	if math.Abs(componentVaR-result.VaR.VaR) > 1e-12 || math.Abs(percent-1) > 1e-12 {
+		t.Fatalf("component VaRs sum to %v of %v, percents to %v", componentVaR, result.VaR.VaR, percent)
+	}
+	if result.DiversificationRatio < 1 {
+		t.Fatalf("diversification ratio %v below 1", result.DiversificationRatio)
+	}
+
+	// The extra levels match separate estimates from the same seed
+	if len(result.Levels) != 2 {
+		t.Fatalf("%d extra levels, want 2", len(result.Levels))
+	}
+	for _, level := range result.Levels {
+		single := cfg
+		single.VaR.Confidence = level.Confidence
+		single.Confidences = nil
+		want, err := PortfolioRisk(weights, returns, single)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *want.VaR != *level {
+			t.Fatalf("level %v = %+v, separate estimate %+v", level.Confidence, *level, *want.VaR)
+		}
+	}
+
+	volatility, portfolioReturns, err := PortfolioVolatility(weights, returns, AutoShrinkage, 252)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(volatility-result.Volatility) > 1e-15 || len(portfolioReturns) != len(result.Returns) {
+		t.Fatalf("PortfolioVolatility = %v, PortfolioRisk volatility %v", volatility, result.Volatility)
+	}
+}
+
+func TestPortfolioRiskUncorrelated(t *testing.T) {
+	// Two independent assets of equal volatility held 50/50 diversify by
+	// sqrt(2); parametric VaR follows the portfolio volatility
+	returns := correlatedReturns(2, 5000, 0, 0.01, 4)
+	result, err := PortfolioRisk([]float64{0.5, 0.5}, returns, PortfolioRiskConfig{
+		VaR:            VaRConfig{Method: VaRParametric, Confidence: 0.99},
+		Confidences:    []float64{0.95},
+		PeriodsPerYear: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(result.DiversificationRatio-math.Sqrt2) > 0.05 {
+		t.Fatalf("diversification ratio %v, want about sqrt(2)", result.DiversificationRatio)
+	}
+	mean := stat.Mean(result.Returns, nil)
+	if want := mean - 2.3263*result.Volatility; math.Abs(result.VaR.VaR-want) > 1e-5 {
+		t.Fatalf("VaR99 %v, want %v", result.VaR.VaR, want)
+	}
+	if want := mean - 1.6449*result.Volatility; math.Abs(result.Levels[0].VaR-want) > 1e-5 {
+		t.Fatalf("VaR95 %v, want %v", result.Levels[0].VaR, want)
+	}
+}
+
+func TestPortfolioRiskConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   PortfolioRiskConfig
+		valid bool
+	}{
+		{"auto", PortfolioRiskConfig{Shrinkage: AutoShrinkage, PeriodsPerYear: 252}, true},
+		{"bounds", PortfolioRiskConfig{Shrinkage: 1, PeriodsPerYear: 252}, true},
+		{"NaN shrinkage", PortfolioRiskConfig{Shrinkage: math.NaN(), PeriodsPerYear: 252}, false},
+		{"negative shrinkage", PortfolioRiskConfig{Shrinkage: -0.5, PeriodsPerYear: 252}, false},
+		{"large shrinkage", PortfolioRiskConfig{Shrinkage: 1.5, PeriodsPerYear: 252}, false},
+		{"NaN periods", PortfolioRiskConfig{PeriodsPerYear: math.NaN()}, false},
+		{"no periods", PortfolioRiskConfig{}, false},
+		{"bad level", PortfolioRiskConfig{PeriodsPerYear: 252, Confidences: []float64{0.95, math.NaN()}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err == nil) != tt.valid {
+			t.Fatalf("%s: error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+	if _, _, err := PortfolioVolatility([]float64{1}, [][]float64{normalReturns(50, 0, 0.01, 1)}, math.NaN(), 252); err == nil {
+		t.Fatal("expected PortfolioVolatility to reject NaN shrinkage")
+	}
+}
@@ -0,0 +1,318 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Projection methods
+const (
+	ProjectionBootstrap  = "bootstrap"  // Resample whole historical bars, keeping co-movement between holdings
+	ProjectionParametric = "parametric" // Draw from a multivariate normal with the holdings' mean and covariance
+)
+
+// maxProjectionSteps caps paths × horizon × holdings
+const maxProjectionSteps = 10000000
+
+// maxProjectionPaths caps paths; every path is kept at each fan point
+const maxProjectionPaths = 20000
+
+// maxPercentiles caps the fan chart percentiles
+const maxPercentiles = 20
+
+// maxFanPoints is roughly how many steps of the horizon the fan chart reports
+const maxFanPoints = 100
+
+// ProjectionConfig controls a Monte Carlo projection
+type ProjectionConfig struct {
+	Method               string    `json:"method"`                // bootstrap (default) or parametric
+	Horizon              int       `json:"horizon"`               // Bars to project, default 252
+	Paths                int       `json:"paths"`                 // Simulated paths, default 5000, at most 20000
+	Contribution         float64   `json:"contribution"`          // Added every interval; negative withdraws
+	ContributionInterval int       `json:"contribution_interval"` // Bars between contributions, default 21
+	Target               float64   `json:"target"`                // Value whose probability of being reached is reported, 0 for none
+	Percentiles          []float64 `json:"percentiles"`           // Fan chart percentiles, default 5, 25, 50, 75, 95, at most 20
+	Seed                 int64     `json:"seed"`                  // Default 1 so results are repeatable
+}
+
+// withDefaults fills unset fields
+func (c ProjectionConfig) withDefaults() ProjectionConfig {
+	if c.Method == "" {
+		c.Method = ProjectionBootstrap
+	}
+	if c.Horizon <= 0 {
+		c.Horizon = 252
+	}
+	if c.Paths <= 0 {
+		c.Paths = 5000
+	}
+	if c.ContributionInterval <= 0 {
+		c.ContributionInterval = 21
+	}
+	if len(c.Percentiles) == 0 {
+		c.Percentiles = []float64{5, 25, 50, 75, 95}
+	}
+	if c.Seed == 0 {
+		c.Seed = 1
+	}
+	return c
+}
+
+// Validate checks the configuration after defaults are applied
+func (c ProjectionConfig) Validate() error {
+	c = c.withDefaults()
+	switch c.Method {
+	case ProjectionBootstrap, ProjectionParametric:
+	default:
+		return fmt.Errorf("unknown projection method %q (expected %s or %s)", c.Method, ProjectionBootstrap, ProjectionParametric)
+	}
+	if c.Horizon > 7560 {
+		return fmt.Errorf("horizon must not exceed 7560 bars")
+	}
+	if c.Paths < 100 || c.Paths > maxProjectionPaths {
+		return fmt.Errorf("paths must be between 100 and %d", maxProjectionPaths)
+	}
+	if c.Paths*c.Horizon > maxProjectionSteps {
+		return fmt.Errorf("paths × horizon must not exceed %d", maxProjectionSteps)
+	}
+	if math.IsNaN(c.Contribution) || math.IsInf(c.Contribution, 0) {
+		return fmt.Errorf("contribution must be a finite number")
+	}
+	if !(c.Target >= 0) || math.IsInf(c.Target, 0) {
+		return fmt.Errorf("target must be a finite, non-negative number")
+	}
+	if len(c.Percentiles) > maxPercentiles {
+		return fmt.Errorf("at most %d percentiles are allowed", maxPercentiles)
+	}
+	for _, p := range c.Percentiles {
+		if !(p > 0 && p < 100) {
+			return fmt.Errorf("percentiles must be between 0 and 100, got %v", p)
+		}
+	}
+	return nil
+}
+
+// FanPoint is the spread of simulated values after Step bars, one value per
+// configured percentile
+type FanPoint struct {
+	Step   int       `json:"step"`
+	Values []float64 `json:"values"`
+}
+
+// Projection summarizes simulated portfolio values. Values include
+// contributions; a path that withdraws everything stays at zero.
+type Projection struct {
+	Method              string     `json:"method"`
+	Paths               int        `json:"paths"`
+	Horizon             int        `json:"horizon"`
+	InitialValue        float64    `json:"initial_value"`
+	TotalContributions  float64    `json:"total_contributions"` // Net cash scheduled to be added over the horizon
+	Percentiles         []float64  `json:"percentiles"`
+	Fan                 []FanPoint `json:"fan"`
+	Final               []float64  `json:"final"` // Final values at each percentile
+	MeanFinal           float64    `json:"mean_final"`
+	ProbabilityOfLoss   float64    `json:"probability_of_loss"`                  // Final value less net cash added is below the initial value
+	ProbabilityOfTarget *float64   `json:"probability_of_target,omitempty"`      // Final value at or above the target
+	ProbabilityTouched  *float64   `json:"probability_target_touched,omitempty"` // Target reached at any step
+	ProbabilityDepleted float64    `json:"probability_depleted"`                 // Withdrawals exhausted the portfolio
+}
+
+// Project simulates a buy-and-hold portfolio: holdings grow with their own
+// returns and cash stays flat. Contributions and withdrawals are spread
+// over holdings and cash in proportion to their current values. Returns
+// holds one aligned series per holding.
+func Project(holdings []float64, cash float64, returns [][]float64, cfg ProjectionConfig) (*Projection, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+	assets := len(holdings)
+	if assets == 0 || len(returns) != assets {
+		return nil, fmt.Errorf("%d holdings for %d return series", assets, len(returns))
+	}
+	bars := len(returns[0])
+	for _, series := range returns {
+		if len(series) != bars {
+			return nil, fmt.Errorf("return series differ in length")
+		}
+	}
+	if bars < minReturns {
+		return nil, ErrInsufficientData
+	}
+	if cfg.Paths*cfg.Horizon*assets > maxProjectionSteps {
+		return nil, fmt.Errorf("paths × horizon × holdings must not exceed %d", maxProjectionSteps)
+	}
+
+	initial := cash
+	for _, value := range holdings {
+		initial += value
+	}
+	if initial <= 0 {
+		return nil, fmt.Errorf("portfolio has no value")
+	}
+
+	draw, err := newReturnSampler(returns, cfg.Method)
+	if err != nil {
+		return nil, err
+	}
+	rng := rand.New(rand.NewSource(cfg.Seed))
+
+	stride := (cfg.Horizon + maxFanPoints - 1) / maxFanPoints
+	var steps []int
+	for step := stride; step < cfg.Horizon; step += stride {
+		steps = append(steps, step)
+	}
+	steps = append(steps, cfg.Horizon)
+	recorded := make([][]float64, len(steps))
+	for i := range recorded {
+		recorded[i] = make([]float64, cfg.Paths)
+	}
+
+	projection := &Projection{
+		Method:             cfg.Method,
+		Paths:              cfg.Paths,
+		Horizon:            cfg.Horizon,
+		InitialValue:       initial,
+		TotalContributions: cfg.Contribution * float64(cfg.Horizon/cfg.ContributionInterval),
+		Percentiles:        cfg.Percentiles,
+	}
+
+	values := make([]float64, assets)
+	step := make([]float64, assets)
+	var touched, depleted, lost, reached int
+	for path := 0; path < cfg.Paths; path++ {
+		copy(values, holdings)
+		pathCash := cash
+		hit := cfg.Target > 0 && initial >= cfg.Target
+		exhausted := false
+		var flows float64
+		next := 0
+
+		for t := 1; t <= cfg.Horizon; t++ {
+			var total float64
+			if !exhausted {
+				draw(rng, step)
+				total = pathCash
+				for i := range values {
+					values[i] *= 1 + step[i]
+					if values[i] < 0 {
+						values[i] = 0 // A holding can't lose more than everything
+					}
+					total += values[i]
+				}
+
+				if cfg.Contribution != 0 && t%cfg.ContributionInterval == 0 {
+					switch {
+					case total+cfg.Contribution <= 0:
+						flows -= total
+						exhausted, total = true, 0
+					case total > 0:
+						scale := (total + cfg.Contribution) / total
+						for i := range values {
+							values[i] *= scale
+						}
+						pathCash *= scale
+						total += cfg.Contribution
+						flows += cfg.Contribution
+					default:
+						pathCash += cfg.Contribution // Holdings are worthless, so new money stays in cash
+						total += cfg.Contribution
+						flows += cfg.Contribution
+					}
+				}
+			}
+
+			if cfg.Target > 0 && total >= cfg.Target {
+				hit = true
+			}
+			if next < len(steps) && t == steps[next] {
+				recorded[next][path] = total
+				next++
+			}
+		}
+
+		final := recorded[len(steps)-1][path]
+		if hit {
+			touched++
+		}
+		if exhausted {
+			depleted++
+		}
+		if final-flows < initial {
+			lost++
+		}
+		if cfg.Target > 0 && final >= cfg.Target {
+			reached++
+		}
+		projection.MeanFinal += final / float64(cfg.Paths)
+	}
+
+	for i, values := range recorded {
+		sort.Float64s(values)
+		point := FanPoint{Step: steps[i], Values: make([]float64, len(cfg.Percentiles))}
+		for j, p := range cfg.Percentiles {
+			point.Values[j] = stat.Quantile(p/100, stat.LinInterp, values, nil)
+		}
+		projection.Fan = append(projection.Fan, point)
+	}
+	projection.Final = projection.Fan[len(projection.Fan)-1].Values
+
+	paths := float64(cfg.Paths)
+	projection.ProbabilityOfLoss = float64(lost) / paths
+	projection.ProbabilityDepleted = float64(depleted) / paths
+	if cfg.Target > 0 {
+		probability, everTouched := float64(reached)/paths, float64(touched)/paths
+		projection.ProbabilityOfTarget = &probability
+		projection.ProbabilityTouched = &everTouched
+	}
+	return projection, nil
+}
+
+// newReturnSampler returns a function filling one bar of returns for every
+// holding
+func newReturnSampler(returns [][]float64, method string) (func(*rand.Rand, []float64), error) {
+	bars := len(returns[0])
+	if method == ProjectionBootstrap {
+		return func(rng *rand.Rand, out []float64) {
+			bar := rng.Intn(bars)
+			for i, series := range returns {
+				out[i] = series[bar]
+			}
+		}, nil
+	}
+
+	covariance, _, err := ShrunkCovariance(returns, AutoShrinkage)
+	if err != nil {
+		return nil, err
+	}
+	var cholesky mat.Cholesky
+	if ok := cholesky.Factorize(covariance); !ok {
+		return nil, fmt.Errorf("covariance of returns is not positive definite")
+	}
+	var lower mat.TriDense
+	cholesky.LTo(&lower)
+
+	assets := len(returns)
+	means := make([]float64, assets)
+	for i, series := range returns {
+		means[i] = stat.Mean(series, nil)
+	}
+	z := make([]float64, assets)
+	return func(rng *rand.Rand, out []float64) {
+		for i := range z {
+			z[i] = rng.NormFloat64()
+		}
+		for i := 0; i < assets; i++ {
+			value := means[i]
+			for j := 0; j <= i; j++ {
+				value += lower.At(i, j) * z[j]
+			}
+			out[i] = value
+		}
+	}, nil
+}
@@ -0,0 +1,139 @@
+package risk
+
+import (
+	"math"
+	"testing"
+)
+
+// constantReturns is a flat return of r on every bar, for each holding
+func constantReturns(holdings, bars int, r float64) [][]float64 {
+	returns := make([][]float64, holdings)
+	for i := range returns {
+		returns[i] = make([]float64, bars)
+		for t := range returns[i] {
+			returns[i][t] = r
+		}
+	}
+	return returns
+}
+
+func TestProjectionConfigValidate(t *testing.T) {
+	many := make([]float64, maxPercentiles+1)
+	for i := range many {
+		many[i] = float64(i + 1)
+	}
+	tests := []struct {
+		name  string
+		cfg   ProjectionConfig
+		valid bool
+	}{
+		{"defaults", ProjectionConfig{}, true},
+		{"unknown method", ProjectionConfig{Method: "garch"}, false},
+		{"long horizon", ProjectionConfig{Horizon: 7561}, false},
+		{"few paths", ProjectionConfig{Paths: 99}, false},
+		{"many paths", ProjectionConfig{Paths: maxProjectionPaths + 1}, false},
+		{"too many steps", ProjectionConfig{Paths: 20000, Horizon: 1000}, false},
+		{"at step cap", ProjectionConfig{Paths: 10000, Horizon: 1000}, true},
+		{"NaN contribution", ProjectionConfig{Contribution: math.NaN()}, false},
+		{"withdrawal", ProjectionConfig{Contribution: -500}, true},
+		{"negative target", ProjectionConfig{Target: -1}, false},
+		{"NaN target", ProjectionConfig{Target: math.NaN()}, false},
+		{"infinite target", ProjectionConfig{Target: math.Inf(1)}, false},
+		{"NaN percentile", ProjectionConfig{Percentiles: []float64{50, math.NaN()}}, false},
+		{"percentile 100", ProjectionConfig{Percentiles: []float64{100}}, false},
+		{"max percentiles", ProjectionConfig{Percentiles: many[:maxPercentiles]}, true},
+		{"too many percentiles", ProjectionConfig{Percentiles: many}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err == nil) != tt.valid {
+			t.Fatalf("%s: error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestProjectDeterministicGrowth(t *testing.T) {
+	// With every return 1% each path compounds identically; cash stays flat
+	returns := constantReturns(2, 50, 0.01)
+	projection, err := Project([]float64{600, 300}, 100, returns, ProjectionConfig{Horizon: 10, Paths: 200, Target: 1100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := 900*math.Pow(1.01, 10) + 100
+	for _, value := range projection.Final {
+		if math.Abs(value-want) > 1e-9 {
+			t.Fatalf("final %v, want %v", projection.Final, want)
+		}
+	}
+	if math.Abs(projection.MeanFinal-want) > 1e-9 || projection.ProbabilityOfLoss != 0 {
+		t.Fatalf("mean %v, loss probability %v", projection.MeanFinal, projection.ProbabilityOfLoss)
+	}
+	if *projection.ProbabilityOfTarget != 0 || *projection.ProbabilityTouched != 0 {
+		t.Fatalf("target 1100 reached from at most %v", want)
+	}
+	if len(projection.Fan) != 10 || projection.Fan[9].Step != 10 {
+		t.Fatalf("%d fan points", len(projection.Fan))
+	}
+}
+
+func TestProjectContributionsAndDepletion(t *testing.T) {
+	returns := constantReturns(1, 50, 0)
+
+	saving, err := Project([]float64{1000}, 0, returns, ProjectionConfig{Horizon: 100, Paths: 100, Contribution: 50, ContributionInterval: 10, Target: 1300})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saving.TotalContributions != 500 || math.Abs(saving.Final[2]-1500) > 1e-9 {
+		t.Fatalf("contributions %v, median final %v, want 500 and 1500", saving.TotalContributions, saving.Final[2])
+	}
+	if *saving.ProbabilityOfTarget != 1 || saving.ProbabilityOfLoss != 0 {
+		t.Fatalf("target probability %v, loss probability %v", *saving.ProbabilityOfTarget, saving.ProbabilityOfLoss)
+	}
+
+	spending, err := Project([]float64{1000}, 0, returns, ProjectionConfig{Horizon: 100, Paths: 100, Contribution: -300, ContributionInterval: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spending.ProbabilityDepleted != 1 || spending.Final[4] != 0 {
+		t.Fatalf("depleted %v, final %v", spending.ProbabilityDepleted, spending.Final)
+	}
+}
+
+func TestProjectRepeatableAndOrdered(t *testing.T) {
+	returns := correlatedReturns(3, 300, 1, 0.01, 5)
+	holdings := []float64{5000, 3000, 2000}
+	for _, method := range []string{ProjectionBootstrap, ProjectionParametric} {
+		cfg := ProjectionConfig{Method: method, Horizon: 252, Paths: 1000, Seed: 9}
+		first, err := Project(holdings, 1000, returns, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := Project(holdings, 1000, returns, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.MeanFinal != second.MeanFinal {
+			t.Fatalf("%s: same seed gave means %v and %v", method, first.MeanFinal, second.MeanFinal)
+		}
+		if len(first.Fan) > maxFanPoints+1 || first.Fan[len(first.Fan)-1].Step != 252 {
+			t.Fatalf("%s: %d fan points ending at %d", method, len(first.Fan), first.Fan[len(first.Fan)-1].Step)
+		}
+		for _, point := range first.Fan {
+			for i := 1; i < len(point.Values); i++ {
+				if point.Values[i] < point.Values[i-1] {
+					t.Fatalf("%s: percentiles out of order at step %d: %v", method, point.Step, point.Values)
+				}
+			}
+		}
+	}
+
+	if _, err := Project(holdings, 0, returns[:2], ProjectionConfig{}); err == nil {
+		t.Fatal("expected an error for mismatched holdings")
+	}
+	if _, err := Project(holdings, 0, constantReturns(3, 10, 0), ProjectionConfig{}); err != ErrInsufficientData {
+		t.Fatalf("error = %v, want ErrInsufficientData", err)
+	}
+	many := make([]float64, 60)
+	if _, err := Project(many, 1, constantReturns(60, 50, 0), ProjectionConfig{Horizon: 1000, Paths: 1000}); err == nil {
+		t.Fatal("expected paths × horizon × holdings over the cap to be rejected")
+	}
+}
@@ -0,0 +1,130 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/stat"
+	"trading-service/internal/indicators"
+)
+
+// RollingMetrics are risk metrics over trailing windows of returns. Entry i
+// covers returns i to i+window-1, so it ends at value i+window.
+type RollingMetrics struct {
+	Window      int       `json:"window"`
+	Volatility  []float64 `json:"volatility"` // Annualized
+	Sharpe      []float64 `json:"sharpe"`
+	Sortino     []float64 `json:"sortino"`
+	Beta        []float64 `json:"beta,omitempty"` // Only with a benchmark
+	BetaValid   []bool    `json:"-"`              // False where the benchmark was flat and beta is undefined
+	MaxDrawdown []float64 `json:"max_drawdown"`   // Worst peak-to-trough fall within the window, positive
+}
+
+// Rolling computes metrics over every trailing window of returns of the
+// values (prices or index levels). Benchmark, if not nil, must be aligned
+// with values bar for bar.
+func Rolling(values, benchmark []float64, window int, riskFreeRate, periodsPerYear float64) (*RollingMetrics, error) {
+	if window < 2 {
+		return nil, fmt.Errorf("window must be at least 2")
+	}
+	if benchmark != nil && len(benchmark) != len(values) {
+		return nil, fmt.Errorf("benchmark has %d values for %d", len(benchmark), len(values))
+	}
+	if len(values) <= window {
+		return nil, ErrInsufficientData
+	}
+
+	returns := indicators.Returns(values)
+	count := len(returns) - window + 1
+	rolling := &RollingMetrics{
+		Window:      window,
+		Volatility:  make([]float64, count),
+		Sharpe:      make([]float64, count),
+		Sortino:     make([]float64, count),
+		MaxDrawdown: make([]float64, count),
+	}
+	var benchmarkReturns []float64
+	if benchmark != nil {
+		benchmarkReturns = indicators.Returns(benchmark)
+		rolling.Beta = make([]float64, count)
+		rolling.BetaValid = make([]bool, count)
+	}
+
+	for i := 0; i < count; i++ {
+		slice := returns[i : i+window]
+		rolling.Volatility[i] = stat.StdDev(slice, nil) * math.Sqrt(periodsPerYear)
+		rolling.Sharpe[i] = SharpeRatio(slice, riskFreeRate, periodsPerYear)
+		rolling.Sortino[i] = SortinoRatio(slice, riskFreeRate, periodsPerYear)
+		rolling.MaxDrawdown[i] = MaxDrawdown(values[i : i+window+1])
+		// A flat benchmark window has no beta
+		if benchmark != nil && stat.Variance(benchmarkReturns[i:i+window], nil) > 0 {
+			beta, err := indicators.BetaFloat(values[i:i+window+1], benchmark[i:i+window+1], window)
+			rolling.Beta[i], rolling.BetaValid[i] = beta, err == nil
+		}
+	}
+	return rolling, nil
+}
+
+// MaxDrawdown is the largest fall from a running peak, as a positive
+// fraction of the peak
+func MaxDrawdown(values []float64) float64 {
+	drawdowns, _ := Underwater(values)
+	var worst float64
+	for _, d := range drawdowns {
+		worst = math.Min(worst, d)
+	}
+	return math.Abs(worst)
+}
+
+// DrawdownPeriod is a fall below a running peak and the climb back to it.
+// Indexes are into the values; Recovery is -1 while still underwater.
+type DrawdownPeriod struct {
+	Peak         int     `json:"peak"`
+	Trough       int     `json:"trough"`
+	Recovery     int     `json:"recovery"`
+	Depth        float64 `json:"depth"`         // Trough below the peak, positive fraction
+	Duration     int     `json:"duration"`      // Bars from the peak to recovery, or to the last bar
+	RecoveryBars int     `json:"recovery_bars"` // Bars from the trough to recovery, or to the last bar
+}
+
+// Underwater returns each value's drawdown from its running peak (zero at a
+// new high, negative below one) and the drawdown periods, oldest first.
+// Values at or below zero count as fully underwater.
+func Underwater(values []float64) ([]float64, []DrawdownPeriod) {
+	drawdowns := make([]float64, len(values))
+	var periods []DrawdownPeriod
+	var current *DrawdownPeriod
+	peak, peakIndex := math.Inf(-1), 0
+	for i, value := range values {
+		if value >= peak {
+			if current != nil {
+				current.Recovery = i
+				current.Duration = i - current.Peak
+				current.RecoveryBars = i - current.Trough
+				periods = append(periods, *current)
+				current = nil
+			}
+			peak, peakIndex = value, i
+			continue
+		}
+
+		drawdown := -1.0
+		if peak > 0 {
+			drawdown = math.Max(-1, value/peak-1)
+		}
+		drawdowns[i] = drawdown
+		if current == nil {
+			current = &DrawdownPeriod{Peak: peakIndex, Trough: i, Recovery: -1}
+		}
+		if -drawdown > current.Depth {
+			current.Trough, current.Depth = i, -drawdown
+		}
+	}
+	if current != nil {
+		last := len(values) - 1
+		current.Duration = last - current.Peak
+		current.RecoveryBars = last - current.Trough
+		periods = append(periods, *current)
+	}
+	return drawdowns, periods
+}
@@ -0,0 +1,122 @@
+package risk
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+func TestUnderwater(t *testing.T) {
+	values := []float64{100, 110, 99, 88, 105, 121, 120, 132, 66}
+	drawdowns, periods := Underwater(values)
+
+	wantDrawdowns := []float64{0, 0, -0.1, -0.2, 105.0/110 - 1, 0, 120.0/121 - 1, 0, -0.5}
+	for i, want := range wantDrawdowns {
+		if math.Abs(drawdowns[i]-want) > 1e-12 {
+			t.Fatalf("drawdown %d = %.4f, want %.4f", i, drawdowns[i], want)
+		}
+	}
+
+	want := []DrawdownPeriod{
+		{Peak: 1, Trough: 3, Recovery: 5, Depth: 0.2, Duration: 4, RecoveryBars: 2},
+		{Peak: 5, Trough: 6, Recovery: 7, Depth: 1.0 / 121, Duration: 2, RecoveryBars: 1},
+		{Peak: 7, Trough: 8, Recovery: -1, Depth: 0.5, Duration: 1, RecoveryBars: 0},
+	}
+	if len(periods) != len(want) {
+		t.Fatalf("got %d periods, want %d: %+v", len(periods), len(want), periods)
+	}
+	for i, p := range periods {
+		w := want[i]
+		if p.Peak != w.Peak || p.Trough != w.Trough || p.Recovery != w.Recovery ||
+			p.Duration != w.Duration || p.RecoveryBars != w.RecoveryBars || math.Abs(p.Depth-w.Depth) > 1e-12 {
+			t.Fatalf("period %d = %+v, want %+v", i, p, w)
+		}
+	}
+
+	if got := MaxDrawdown(values); math.Abs(got-0.5) > 1e-12 {
+		t.Fatalf("max drawdown %.4f, want 0.5", got)
+	}
+}
+
+func TestUnderwaterNonPositive(t *testing.T) {
+	drawdowns, periods := Underwater([]float64{0, -5, 10, -1})
+	want := []float64{0, -1, 0, -1}
+	for i := range want {
+		if drawdowns[i] != want[i] {
+			t.Fatalf("drawdowns %v, want %v", drawdowns, want)
+		}
+	}
+	if len(periods) != 2 || periods[1].Depth != 1 || periods[1].Recovery != -1 {
+		t.Fatalf("periods %+v, want a recovered fall and a full open one", periods)
+	}
+}
+
+func TestRolling(t *testing.T) {
+	returns := normalReturns(300, 0.0005, 0.01, 3)
+	values := make([]float64, len(returns)+1)
+	benchmark := make([]float64, len(values))
+	values[0], benchmark[0] = 100, 100
+	for i, r := range returns {
+		values[i+1] = values[i] * (1 + r)
+		benchmark[i+1] = benchmark[i] * (1 + r/2)
+	}
+
+	const window = 60
+	rolling, err := Rolling(values, benchmark, window, 0.02, 252)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(returns) - window + 1; len(rolling.Volatility) != want || len(rolling.Beta) != want {
+		t.Fatalf("got %d windows, want %d", len(rolling.Volatility), want)
+	}
+
+	// Entry i covers returns i to i+window-1, which end at value i+window
+	for _, i := range []int{0, 100, len(rolling.Volatility) - 1} {
+		slice := make([]float64, window)
+		for k := range slice {
+			slice[k] = values[i+k+1]/values[i+k] - 1
+		}
+		if want := stat.StdDev(slice, nil) * math.Sqrt(252); math.Abs(rolling.Volatility[i]-want) > 1e-12 {
+			t.Fatalf("window %d volatility %.6f, want %.6f", i, rolling.Volatility[i], want)
+		}
+		if want := SharpeRatio(slice, 0.02, 252); math.Abs(rolling.Sharpe[i]-want) > 1e-12 {
+			t.Fatalf("window %d Sharpe %.6f, want %.6f", i, rolling.Sharpe[i], want)
+		}
+		if want := MaxDrawdown(values[i : i+window+1]); rolling.MaxDrawdown[i] != want {
+			t.Fatalf("window %d max drawdown %.6f, want %.6f", i, rolling.MaxDrawdown[i], want)
+		}
+		// The asset moves twice as much as the benchmark every bar
+		if math.Abs(rolling.Beta[i]-2) > 0.01 {
+			t.Fatalf("window %d beta %.4f, want about 2", i, rolling.Beta[i])
+		}
+	}
+
+	for i, valid := range rolling.BetaValid {
+		if !valid {
+			t.Fatalf("window %d beta unmeasured against a moving benchmark", i)
+		}
+	}
+
+	// Windows where the benchmark doesn't move have no beta, rather than zero
+	for i := 0; i <= 100; i++ {
+		benchmark[i] = 100
+	}
+	rolling, err = Rolling(values, benchmark, window, 0.02, 252)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, valid := range rolling.BetaValid {
+		// Window i spans values i to i+window
+		if flat := i+window <= 100; valid == flat {
+			t.Fatalf("window %d beta valid %v, want %v", i, valid, !flat)
+		}
+	}
+
+	if _, err := Rolling(values[:window], nil, window, 0, 252); err != ErrInsufficientData {
+		t.Fatalf("short series: error = %v, want ErrInsufficientData", err)
+	}
+	if _, err := Rolling(values, benchmark[1:], window, 0, 252); err == nil {
+		t.Fatal("misaligned benchmark accepted")
+	}
+}
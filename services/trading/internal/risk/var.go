@@ -0,0 +1,274 @@
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"golang.org/x/exp/rand"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// VaR methods
+const (
+	VaRHistorical    = "historical"     // Empirical quantile of (overlapping) horizon returns
+	VaRParametric    = "parametric"     // Normal distribution fitted to returns
+	VaRCornishFisher = "cornish_fisher" // Normal quantile corrected for skew and kurtosis
+	VaRMonteCarlo    = "monte_carlo"    // Simulated compounded paths of Student-t returns
+)
+
+// ErrInsufficientData is returned when there are too few returns for an estimate
+var ErrInsufficientData = errors.New("insufficient data for risk estimate")
+
+// minReturns is the fewest returns any VaR method accepts
+const minReturns = 20
+
+// maxSimulatedReturns caps Monte Carlo simulations × horizon, the t draws
+// one estimate makes
+const maxSimulatedReturns = 100000
+
+// VaRConfig selects how Value at Risk is estimated
+type VaRConfig struct {
+	Method      string  `json:"method"`      // historical (default), parametric, cornish_fisher or monte_carlo
+	Confidence  float64 `json:"confidence"`  // e.g. 0.95 (default)
+	Horizon     int     `json:"horizon"`     // Bars the loss is measured over, default 1
+	Simulations int     `json:"simulations"` // Monte Carlo paths, default 10000
+	Seed        int64   `json:"seed"`        // Monte Carlo seed, default 1 so results are repeatable
+}
+
+// DefaultVaRConfig returns one-bar historical VaR at 95%
+func DefaultVaRConfig() VaRConfig {
+	return VaRConfig{
+		Method:      VaRHistorical,
+		Confidence:  0.95,
+		Horizon:     1,
+		Simulations: 10000,
+		Seed:        1,
+	}
+}
+
+// withDefaults fills zero fields from DefaultVaRConfig
+func (c VaRConfig) withDefaults() VaRConfig {
+	d := DefaultVaRConfig()
+	if c.Method == "" {
+		c.Method = d.Method
+	}
+	if c.Confidence == 0 {
+		c.Confidence = d.Confidence
+	}
+	if c.Horizon <= 0 {
+		c.Horizon = d.Horizon
+	}
+	if c.Simulations <= 0 {
+		c.Simulations = d.Simulations
+	}
+	if c.Seed == 0 {
+		c.Seed = d.Seed
+	}
+	return c
+}
+
+// Validate checks the configuration after defaults are applied
+func (c VaRConfig) Validate() error {
+	c = c.withDefaults()
+	switch c.Method {
+	case VaRHistorical, VaRParametric, VaRCornishFisher, VaRMonteCarlo:
+	default:
+		return fmt.Errorf("unknown VaR method %q (expected %s, %s, %s or %s)",
+			c.Method, VaRHistorical, VaRParametric, VaRCornishFisher, VaRMonteCarlo)
+	}
+	if err := checkConfidence(c.Confidence); err != nil {
+		return err
+	}
+	if c.Horizon > 252 {
+		return fmt.Errorf("horizon must not exceed 252 bars")
+	}
+	if c.Simulations > maxSimulatedReturns/c.Horizon {
+		return fmt.Errorf("simulations × horizon must not exceed %d", maxSimulatedReturns)
+	}
+	return nil
+}
+
+// checkConfidence rejects confidence levels outside (0.5, 1), NaN included
+func checkConfidence(confidence float64) error {
+	if !(confidence > 0.5 && confidence < 1) {
+		return fmt.Errorf("confidence must be between 0.5 and 1, got %v", confidence)
+	}
+	return nil
+}
+
+// VaRResult is a Value at Risk estimate. VaR and CVaR are horizon returns,
+// negative for a loss: VaR is the return at the 1 - confidence quantile and
+// CVaR (expected shortfall) the mean return beyond it.
+type VaRResult struct {
+	Method     string  `json:"method"`
+	Confidence float64 `json:"confidence"`
+	Horizon    int     `json:"horizon"`
+	VaR        float64 `json:"var"`
+	CVaR       float64 `json:"cvar"`
+}
+
+// ValueAtRisk estimates VaR and CVaR from per-bar simple returns
+func ValueAtRisk(returns []float64, cfg VaRConfig) (*VaRResult, error) {
+	results, err := ValueAtRiskLevels(returns, cfg, cfg.withDefaults().Confidence)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// ValueAtRiskLevels estimates VaR and CVaR at each confidence level, in
+// order, in place of cfg.Confidence; a zero level means cfg.Confidence. The
+// historical and Monte Carlo methods build or simulate their horizon
+// returns once and read every level from that sample.
+func ValueAtRiskLevels(returns []float64, cfg VaRConfig, confidences ...float64) ([]*VaRResult, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+	levels := make([]float64, len(confidences))
+	for i, confidence := range confidences {
+		if confidence == 0 {
+			confidence = cfg.Confidence
+		}
+		if err := checkConfidence(confidence); err != nil {
+			return nil, err
+		}
+		levels[i] = confidence
+	}
+	if len(returns) < minReturns {
+		return nil, ErrInsufficientData
+	}
+
+	// Sorted horizon returns for the empirical methods
+	var sample []float64
+	switch cfg.Method {
+	case VaRHistorical:
+		sample = compoundedReturns(returns, cfg.Horizon)
+		if len(sample) < minReturns {
+			return nil, ErrInsufficientData
+		}
+	case VaRMonteCarlo:
+		sample = simulateStudentT(returns, cfg)
+	}
+	sort.Float64s(sample)
+
+	mean, stdDev := stat.MeanStdDev(returns, nil)
+	h := float64(cfg.Horizon)
+	results := make([]*VaRResult, len(levels))
+	for i, confidence := range levels {
+		result := &VaRResult{Method: cfg.Method, Confidence: confidence, Horizon: cfg.Horizon}
+		tail := 1 - confidence
+
+		switch cfg.Method {
+		case VaRHistorical, VaRMonteCarlo:
+			result.VaR, result.CVaR = empiricalTail(sample, tail)
+
+		case VaRParametric:
+			result.VaR, result.CVaR = normalTail(mean*h, stdDev*math.Sqrt(h), tail)
+
+		case VaRCornishFisher:
+			// Skew and excess kurtosis of a sum of h independent returns
+			// shrink by sqrt(h) and h
+			skew := stat.Skew(returns, nil) / math.Sqrt(h)
+			kurtosis := stat.ExKurtosis(returns, nil) / h
+			quantile := func(p float64) float64 {
+				return mean*h + cornishFisherZ(distuv.UnitNormal.Quantile(p), skew, kurtosis)*stdDev*math.Sqrt(h)
+			}
+			result.VaR = quantile(tail)
+			// Expected shortfall is the average quantile over the tail
+			const steps = 200
+			for i := 0; i < steps; i++ {
+				result.CVaR += quantile(tail * (float64(i) + 0.5) / steps)
+			}
+			result.CVaR /= steps
+		}
+		results[i] = result
+	}
+
+	return results, nil
+}
+
+// normalTail returns the tail quantile of a normal distribution and its
+// mean below that quantile
+func normalTail(mu, sigma, tail float64) (quantile, shortfall float64) {
+	z := distuv.UnitNormal.Quantile(tail)
+	return mu + sigma*z, mu - sigma*distuv.UnitNormal.Prob(z)/tail
+}
+
+// cornishFisherZ adjusts a normal quantile for skew and excess kurtosis
+func cornishFisherZ(z, skew, kurtosis float64) float64 {
+	return z +
+		(z*z-1)*skew/6 +
+		(z*z*z-3*z)*kurtosis/24 -
+		(2*z*z*z-5*z)*skew*skew/36
+}
+
+// compoundedReturns returns overlapping horizon-bar compounded returns
+func compoundedReturns(returns []float64, horizon int) []float64 {
+	if horizon <= 1 {
+		return append([]float64(nil), returns...)
+	}
+	if len(returns) < horizon {
+		return nil
+	}
+	out := make([]float64, 0, len(returns)-horizon+1)
+	for i := horizon; i <= len(returns); i++ {
+		growth := 1.0
+		for _, r := range returns[i-horizon : i] {
+			growth *= 1 + r
+		}
+		out = append(out, growth-1)
+	}
+	return out
+}
+
+// empiricalTail returns the tail quantile of a sorted sample and the mean
+// of the values at or below it
+func empiricalTail(sorted []float64, tail float64) (quantile, shortfall float64) {
+	quantile = stat.Quantile(tail, stat.LinInterp, sorted, nil)
+
+	var sum float64
+	var count int
+	for _, v := range sorted {
+		if v > quantile {
+			break
+		}
+		sum += v
+		count++
+	}
+	if count == 0 {
+		return quantile, sorted[0]
+	}
+	return quantile, sum / float64(count)
+}
+
+// simulateStudentT compounds cfg.Horizon draws per path from a Student-t
+// distribution with the sample mean and variance, its degrees of freedom
+// matched to the sample's excess kurtosis (normal-like when there is none).
+// Each draw is a standard normal over sqrt(chi-squared / nu).
+func simulateStudentT(returns []float64, cfg VaRConfig) []float64 {
+	mean, stdDev := stat.MeanStdDev(returns, nil)
+
+	// Excess kurtosis of a t distribution is 6 / (nu - 4)
+	nu := 100.0
+	if kurtosis := stat.ExKurtosis(returns, nil); kurtosis > 0.06 {
+		nu = math.Max(4.5, 4+6/kurtosis)
+	}
+	scale := stdDev * math.Sqrt((nu-2)/nu) // Unit t variance is nu / (nu - 2)
+
+	rng := rand.New(rand.NewSource(uint64(cfg.Seed)))
+	chiSquared := distuv.ChiSquared{K: nu, Src: rng}
+	paths := make([]float64, cfg.Simulations)
+	for i := range paths {
+		growth := 1.0
+		for step := 0; step < cfg.Horizon; step++ {
+			t := rng.NormFloat64() / math.Sqrt(chiSquared.Rand()/nu)
+			growth *= math.Max(0, 1+mean+scale*t) // A position can't lose more than everything
+		}
+		paths[i] = growth - 1
+	}
+	return paths
+}
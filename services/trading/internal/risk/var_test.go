@@ -0,0 +1,170 @@
+package risk
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// normalReturns draws n returns from a normal distribution
+func normalReturns(n int, mean, stdDev float64, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	returns := make([]float64, n)
+	for i := range returns {
+		returns[i] = mean + stdDev*rng.NormFloat64()
+	}
+	return returns
+}
+
+func TestVaRConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   VaRConfig
+		valid bool
+	}{
+		{"defaults", VaRConfig{}, true},
+		{"monte carlo at cap", VaRConfig{Method: VaRMonteCarlo, Simulations: 10000, Horizon: 10}, true},
+		{"unknown method", VaRConfig{Method: "guess"}, false},
+		{"confidence 0.5", VaRConfig{Confidence: 0.5}, false},
+		{"confidence 1", VaRConfig{Confidence: 1}, false},
+		{"confidence NaN", VaRConfig{Confidence: math.NaN()}, false},
+		{"confidence +Inf", VaRConfig{Confidence: math.Inf(1)}, false},
+		{"confidence -Inf", VaRConfig{Confidence: math.Inf(-1)}, false},
+		{"long horizon", VaRConfig{Horizon: 253}, false},
+		{"over simulation cap", VaRConfig{Method: VaRMonteCarlo, Simulations: 10001, Horizon: 10}, false},
+		{"overflowing simulations", VaRConfig{Simulations: math.MaxInt, Horizon: 2}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err == nil) != tt.valid {
+			t.Fatalf("%s: error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestValueAtRiskParametric(t *testing.T) {
+	returns := normalReturns(1000, 0.001, 0.02, 1)
+	mean, stdDev := stat.MeanStdDev(returns, nil)
+
+	result, err := ValueAtRisk(returns, VaRConfig{Method: VaRParametric, Confidence: 0.95, Horizon: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// z(0.05) = -1.6449, phi(z) / 0.05 = 2.0627
+	wantVaR := 4*mean - 1.6449*stdDev*2
+	wantCVaR := 4*mean - 2.0627*stdDev*2
+	if math.Abs(result.VaR-wantVaR) > 1e-4 || math.Abs(result.CVaR-wantCVaR) > 1e-4 {
+		t.Fatalf("VaR %.5f CVaR %.5f, want %.5f and %.5f", result.VaR, result.CVaR, wantVaR, wantCVaR)
+	}
+}
+
+func TestValueAtRiskHistorical(t *testing.T) {
+	// -0.20, -0.19, ..., -0.01, 0, 0.01, ... so the tail is known exactly
+	returns := make([]float64, 100)
+	for i := range returns {
+		returns[(i*37)%100] = float64(i-20) / 100
+	}
+	result, err := ValueAtRisk(returns, VaRConfig{Method: VaRHistorical, Confidence: 0.95})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(result.VaR-(-0.16)) > 1e-9 {
+		t.Fatalf("VaR = %v, want -0.16", result.VaR)
+	}
+	if math.Abs(result.CVaR-(-0.18)) > 1e-9 {
+		t.Fatalf("CVaR = %v, want -0.18 (mean of -0.20..-0.16)", result.CVaR)
+	}
+
+	if _, err := ValueAtRisk(returns[:10], VaRConfig{}); !errors.Is(err, ErrInsufficientData) {
+		t.Fatalf("error = %v, want ErrInsufficientData", err)
+	}
+	if _, err := ValueAtRisk(returns[:25], VaRConfig{Horizon: 10}); !errors.Is(err, ErrInsufficientData) {
+		t.Fatalf("error = %v, want ErrInsufficientData for too few horizon returns", err)
+	}
+}
+
+func TestValueAtRiskLevels(t *testing.T) {
+	returns := normalReturns(500, 0, 0.015, 2)
+	for _, method := range []string{VaRHistorical, VaRParametric, VaRCornishFisher, VaRMonteCarlo} {
+		cfg := VaRConfig{Method: method, Confidence: 0.975, Horizon: 5, Simulations: 5000}
+		levels, err := ValueAtRiskLevels(returns, cfg, 0.95, 0.99, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+		if levels[2].Confidence != 0.975 {
+			t.Fatalf("%s: zero level confidence = %v, want the configured 0.975", method, levels[2].Confidence)
+		}
+
+		// Each level matches a separate estimate from the same seed
+		for _, level := range levels {
+			single := cfg
+			single.Confidence = level.Confidence
+			want, err := ValueAtRisk(returns, single)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *want != *level {
+				t.Fatalf("%s at %v: levels gave %+v, single estimate %+v", method, level.Confidence, *level, *want)
+			}
+		}
+
+		if !(levels[1].VaR < levels[0].VaR) {
+			t.Fatalf("%s: VaR99 %v not below VaR95 %v", method, levels[1].VaR, levels[0].VaR)
+		}
+		for _, level := range levels {
+			if !(level.CVaR <= level.VaR) {
+				t.Fatalf("%s: CVaR %v above VaR %v", method, level.CVaR, level.VaR)
+			}
+		}
+	}
+
+	if _, err := ValueAtRiskLevels(returns, VaRConfig{}, 0.95, math.NaN()); err == nil {
+		t.Fatal("expected a NaN level to be rejected")
+	}
+}
+
+func TestSimulateStudentT(t *testing.T) {
+	// Heavy-tailed returns: mixture of calm and volatile days
+	rng := rand.New(rand.NewSource(3))
+	returns := make([]float64, 2000)
+	for i := range returns {
+		sigma := 0.01
+		if rng.Float64() < 0.1 {
+			sigma = 0.04
+		}
+		returns[i] = sigma * rng.NormFloat64()
+	}
+	mean, stdDev := stat.MeanStdDev(returns, nil)
+
+	cfg := VaRConfig{Method: VaRMonteCarlo, Horizon: 1, Simulations: 100000, Seed: 7}
+	paths := simulateStudentT(returns, cfg)
+	simMean, simStdDev := stat.MeanStdDev(paths, nil)
+	if math.Abs(simMean-mean) > 0.0005 {
+		t.Fatalf("simulated mean %.5f, want %.5f", simMean, mean)
+	}
+	if math.Abs(simStdDev-stdDev)/stdDev > 0.05 {
+		t.Fatalf("simulated std dev %.5f, want %.5f", simStdDev, stdDev)
+	}
+	if kurtosis := stat.ExKurtosis(paths, nil); kurtosis < 1 {
+		t.Fatalf("simulated excess kurtosis %.2f; tails not heavy", kurtosis)
+	}
+
+	again := simulateStudentT(returns, cfg)
+	for i := range paths {
+		if paths[i] != again[i] {
+			t.Fatal("simulation not repeatable for a fixed seed")
+		}
+	}
+}
+
+func BenchmarkValueAtRiskMonteCarlo(b *testing.B) {
+	returns := normalReturns(1000, 0, 0.02, 1)
+	cfg := VaRConfig{Method: VaRMonteCarlo, Simulations: 10000, Horizon: 10}
+	for i := 0; i < b.N; i++ {
+		if _, err := ValueAtRiskLevels(returns, cfg, 0.95, 0.99); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/optimize"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Volatility models
+const (
+	VolatilityHistorical = "historical" // Sample standard deviation, the same at every horizon
+	VolatilityEWMA       = "ewma"       // RiskMetrics exponentially weighted variance, flat term structure
+	VolatilityGARCH      = "garch"      // GARCH(1,1), reverting to its long-run variance
+)
+
+// RiskMetricsLambda is the RiskMetrics decay for daily returns
+const RiskMetricsLambda = 0.94
+
+// MinGARCHReturns is the fewest returns GARCH(1,1) is fitted to; its
+// persistence is poorly identified on shorter samples
+const MinGARCHReturns = 100
+
+// maxPersistence keeps fitted GARCH models stationary
+const maxPersistence = 0.9999
+
+// VolatilityConfig selects a volatility model and the horizons it forecasts
+type VolatilityConfig struct {
+	Model          string  `json:"model"`            // garch (default), ewma or historical
+	Lambda         float64 `json:"lambda"`           // EWMA decay; 0 fits it by maximum likelihood
+	Horizons       []int   `json:"horizons"`         // Bars ahead, default 1, 5, 21, 63, 126, 252
+	PeriodsPerYear int     `json:"periods_per_year"` // Annualization, default 252
+}
+
+// withDefaults fills unset fields
+func (c VolatilityConfig) withDefaults() VolatilityConfig {
+	if c.Model == "" {
+		c.Model = VolatilityGARCH
+	}
+	if len(c.Horizons) == 0 {
+		c.Horizons = []int{1, 5, 21, 63, 126, 252}
+	}
+	if c.PeriodsPerYear <= 0 {
+		c.PeriodsPerYear = 252
+	}
+	return c
+}
+
+// Validate checks the configuration after defaults are applied
+func (c VolatilityConfig) Validate() error {
+	c = c.withDefaults()
+	switch c.Model {
+	case VolatilityHistorical, VolatilityEWMA, VolatilityGARCH:
+	default:
+		return fmt.Errorf("unknown volatility model %q (expected %s, %s or %s)", c.Model, VolatilityGARCH, VolatilityEWMA, VolatilityHistorical)
+	}
+	if !(c.Lambda == 0 || (c.Lambda > 0 && c.Lambda < 1)) {
+		return fmt.Errorf("lambda must be between 0 and 1, or 0 to fit it")
+	}
+	if len(c.Horizons) > 50 {
+		return fmt.Errorf("at most 50 horizons are allowed")
+	}
+	for _, h := range c.Horizons {
+		if h < 1 || h > 2520 {
+			return fmt.Errorf("horizons must be between 1 and 2520 bars, got %d", h)
+		}
+	}
+	return nil
+}
+
+// VolatilityPoint is the forecast for the next Horizon bars
+type VolatilityPoint struct {
+	Horizon           int     `json:"horizon"`
+	Volatility        float64 `json:"volatility"`         // Annualized from the average variance over the horizon
+	HorizonVolatility float64 `json:"horizon_volatility"` // Of the return over the whole horizon
+}
+
+// VolatilityForecast is a fitted volatility model. Volatilities are
+// annualized unless noted.
+type VolatilityForecast struct {
+	Model             string            `json:"model"`
+	Observations      int               `json:"observations"`
+	PeriodsPerYear    int               `json:"periods_per_year"` // Bars per year the volatilities are annualized with
+	Lambda            float64           `json:"lambda,omitempty"` // EWMA decay
+	Omega             float64           `json:"omega,omitempty"`  // GARCH constant, per-bar variance
+	Alpha             float64           `json:"alpha,omitempty"`  // GARCH weight on the latest squared return
+	Beta              float64           `json:"beta,omitempty"`   // GARCH weight on the latest variance
+	Persistence       float64           `json:"persistence"`      // Share of a variance shock left after one bar
+	HalfLife          float64           `json:"half_life"`        // Bars for a variance shock to halve, 0 if it never decays
+	LongRunVolatility float64           `json:"long_run_volatility,omitempty"`
+	Realized          float64           `json:"realized"`                 // Sample volatility of the fitted returns
+	Current           float64           `json:"current"`                  // Forecast for the next bar
+	LogLikelihood     *float64          `json:"log_likelihood,omitempty"` // Omitted when a fitted variance collapses to zero
+	TermStructure     []VolatilityPoint `json:"term_structure"`
+	Conditional       []float64         `json:"-"` // Per-bar volatility each return was drawn with, not annualized
+
+	mean    float64 // Of the fitted returns
+	next    float64 // Next-bar variance
+	longRun float64 // Variance forecasts revert to
+}
+
+// HorizonVariance is the forecast variance of the return over the next
+// horizon bars: the sum of each bar's expected variance, which decays from
+// the next-bar variance toward the long-run one by the persistence per bar
+func (f *VolatilityForecast) HorizonVariance(horizon int) float64 {
+	h := float64(horizon)
+	if f.Persistence >= 1 || f.next == f.longRun {
+		return h * f.next
+	}
+	p := f.Persistence
+	return h*f.longRun + (f.next-f.longRun)*(1-math.Pow(p, h))/(1-p)
+}
+
+// Rescale returns volatility-updated returns (Hull and White): each of the
+// fitted returns' deviation from the mean is scaled from the volatility it
+// was drawn with to the average forecast volatility over the horizon, so
+// historical scenarios reflect today's volatility
+func (f *VolatilityForecast) Rescale(returns []float64, horizon int) []float64 {
+	target := math.Sqrt(f.HorizonVariance(horizon) / float64(horizon))
+	out := make([]float64, len(returns))
+	for t, r := range returns {
+		out[t] = r
+		if t < len(f.Conditional) && f.Conditional[t] > 0 {
+			out[t] = f.mean + (r-f.mean)*target/f.Conditional[t]
+		}
+	}
+	return out
+}
+
+// ForecastVolatility fits the configured model to per-bar simple returns by
+// maximum likelihood under normal innovations and forecasts each horizon.
+// Returns are demeaned first.
+func ForecastVolatility(returns []float64, cfg VolatilityConfig) (*VolatilityForecast, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+	if len(returns) < minReturns || cfg.Model == VolatilityGARCH && len(returns) < MinGARCHReturns {
+		return nil, ErrInsufficientData
+	}
+
+	mean, variance := stat.MeanVariance(returns, nil)
+	residuals := make([]float64, len(returns))
+	for i, r := range returns {
+		residuals[i] = r - mean
+	}
+
+	forecast := &VolatilityForecast{Model: cfg.Model, Observations: len(returns), PeriodsPerYear: cfg.PeriodsPerYear, mean: mean}
+	var variances []float64
+	switch cfg.Model {
+	case VolatilityHistorical:
+		variances = garchVariances(residuals, variance, variance, 0, 0)
+		forecast.LongRunVolatility = math.Sqrt(variance * float64(cfg.PeriodsPerYear))
+
+	case VolatilityEWMA:
+		if variance == 0 {
+			return nil, errors.New("returns don't vary")
+		}
+		lambda := cfg.Lambda
+		if lambda == 0 {
+			fitted, err := fitEWMA(residuals, variance)
+			if err != nil {
+				return nil, err
+			}
+			lambda = fitted
+		}
+		forecast.Lambda, forecast.Persistence = lambda, 1
+		forecast.HalfLife = math.Log(0.5) / math.Log(lambda)
+		variances = garchVariances(residuals, variance, 0, 1-lambda, lambda)
+
+	case VolatilityGARCH:
+		if variance == 0 {
+			return nil, errors.New("returns don't vary")
+		}
+		omega, alpha, beta, err := fitGARCH(residuals, variance)
+		if err != nil {
+			return nil, err
+		}
+		forecast.Omega, forecast.Alpha, forecast.Beta = omega, alpha, beta
+		forecast.Persistence = alpha + beta
+		if forecast.Persistence > 0 {
+			forecast.HalfLife = math.Log(0.5) / math.Log(forecast.Persistence)
+		}
+		forecast.longRun = omega / (1 - forecast.Persistence)
+		forecast.LongRunVolatility = math.Sqrt(forecast.longRun * float64(cfg.PeriodsPerYear))
+		variances = garchVariances(residuals, variance, omega, alpha, beta)
+	}
+
+	forecast.next = variances[len(residuals)]
+	if cfg.Model != VolatilityGARCH {
+		forecast.longRun = forecast.next // Historical is constant and EWMA never reverts
+	}
+	if ll := gaussianLogLikelihood(residuals, variances); !math.IsInf(ll, 0) && !math.IsNaN(ll) {
+		forecast.LogLikelihood = &ll
+	}
+	forecast.Conditional = make([]float64, len(residuals))
+	for t := range residuals {
+		forecast.Conditional[t] = math.Sqrt(variances[t])
+	}
+	forecast.Realized = math.Sqrt(variance * float64(cfg.PeriodsPerYear))
+	forecast.Current = math.Sqrt(forecast.next * float64(cfg.PeriodsPerYear))
+	for _, h := range cfg.Horizons {
+		total := forecast.HorizonVariance(h)
+		forecast.TermStructure = append(forecast.TermStructure, VolatilityPoint{
+			Horizon:           h,
+			Volatility:        math.Sqrt(total / float64(h) * float64(cfg.PeriodsPerYear)),
+			HorizonVolatility: math.Sqrt(total),
+		})
+	}
+	return forecast, nil
+}
+
+// garchVariances returns the variance of each residual under
+// σ²(t) = omega + alpha × e(t-1)² + beta × σ²(t-1), starting from initial,
+// followed by the forecast for the bar after the last. EWMA is the case
+// omega = 0, alpha = 1 - lambda, beta = lambda.
+func garchVariances(residuals []float64, initial, omega, alpha, beta float64) []float64 {
+	variances := make([]float64, len(residuals)+1)
+	variances[0] = initial
+	for t, e := range residuals {
+		variances[t+1] = omega + alpha*e*e + beta*variances[t]
+	}
+	return variances
+}
+
+// gaussianLogLikelihood is the log likelihood of mean-zero normal residuals
+// with the given per-bar variances, -Inf when a variance isn't positive
+func gaussianLogLikelihood(residuals, variances []float64) float64 {
+	var ll float64
+	for t, e := range residuals {
+		if !(variances[t] > 0) {
+			return math.Inf(-1)
+		}
+		ll -= 0.5 * (math.Log(2*math.Pi) + math.Log(variances[t]) + e*e/variances[t])
+	}
+	return ll
+}
+
+// fitEWMA returns the decay that maximizes the likelihood of the residuals
+func fitEWMA(residuals []float64, variance float64) (float64, error) {
+	// lambda = 0.5 + 0.4999 × logistic(x) keeps the decay in (0.5, 0.9999)
+	decay := func(x float64) float64 { return 0.5 + 0.4999*logistic(x) }
+	x, err := maximizeLikelihood([]float64{logit((RiskMetricsLambda - 0.5) / 0.4999)}, func(x []float64) float64 {
+		lambda := decay(x[0])
+		return gaussianLogLikelihood(residuals, garchVariances(residuals, variance, 0, 1-lambda, lambda))
+	})
+	if err != nil {
+		return 0, fmt.Errorf("EWMA fit failed: %v", err)
+	}
+	return decay(x[0]), nil
+}
+
+// fitGARCH returns the GARCH(1,1) parameters that maximize the likelihood of
+// the residuals. The search runs over unconstrained values mapped to
+// omega > 0, alpha >= 0, beta >= 0 and alpha + beta < maxPersistence,
+// starting from a typical daily fit that matches the sample variance.
+func fitGARCH(residuals []float64, variance float64) (omega, alpha, beta float64, err error) {
+	params := func(x []float64) (omega, alpha, beta float64) {
+		persistence := maxPersistence * logistic(x[0])
+		alpha = persistence * logistic(x[1])
+		return math.Exp(x[2]), alpha, persistence - alpha
+	}
+	const startPersistence, startAlpha = 0.95, 0.08
+	start := []float64{
+		logit(startPersistence / maxPersistence),
+		logit(startAlpha / startPersistence),
+		math.Log(variance * (1 - startPersistence)),
+	}
+
+	x, err := maximizeLikelihood(start, func(x []float64) float64 {
+		omega, alpha, beta := params(x)
+		return gaussianLogLikelihood(residuals, garchVariances(residuals, variance, omega, alpha, beta))
+	})
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("GARCH fit failed: %v", err)
+	}
+	omega, alpha, beta = params(x)
+	return omega, alpha, beta, nil
+}
+
+// maximizeLikelihood searches for the parameters with the highest log
+// likelihood using Nelder-Mead, which needs no gradients
+func maximizeLikelihood(start []float64, logLikelihood func([]float64) float64) ([]float64, error) {
+	problem := optimize.Problem{Func: func(x []float64) float64 {
+		ll := logLikelihood(x)
+		if math.IsNaN(ll) || math.IsInf(ll, 0) {
+			return math.MaxFloat64
+		}
+		return -ll
+	}}
+	result, err := optimize.Minimize(problem, start, &optimize.Settings{FuncEvaluations: 5000}, &optimize.NelderMead{})
+	if err != nil {
+		return nil, err
+	}
+	if result.F == math.MaxFloat64 {
+		return nil, errors.New("no parameters gave a finite likelihood")
+	}
+	return result.X, nil
+}
+
+// logistic maps the real line onto (0, 1)
+func logistic(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// logit is the inverse of logistic
+func logit(p float64) float64 {
+	return math.Log(p / (1 - p))
+}
+
+// TargetWeight is the fraction of equity whose forecast volatility matches
+// an annualized volatility target, capped at maxWeight when it is positive
+func TargetWeight(targetVolatility, forecastVolatility, maxWeight float64) float64 {
+	if forecastVolatility <= 0 || targetVolatility <= 0 {
+		return 0
+	}
+	weight := targetVolatility / forecastVolatility
+	if maxWeight > 0 && weight > maxWeight {
+		return maxWeight
+	}
+	return weight
+}
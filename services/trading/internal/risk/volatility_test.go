@@ -0,0 +1,168 @@
+package risk
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// garchReturns simulates n returns from a GARCH(1,1) process with normal
+// innovations, started at its long-run variance
+func garchReturns(n int, omega, alpha, beta float64, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	returns := make([]float64, n)
+	variance := omega / (1 - alpha - beta)
+	for i := range returns {
+		returns[i] = math.Sqrt(variance) * rng.NormFloat64()
+		variance = omega + alpha*returns[i]*returns[i] + beta*variance
+	}
+	return returns
+}
+
+// ewmaReturns simulates n returns whose variance follows an EWMA with the
+// given decay, floored so the process doesn't collapse
+func ewmaReturns(n int, lambda float64, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	returns := make([]float64, n)
+	variance := 1e-4
+	for i := range returns {
+		returns[i] = math.Sqrt(variance) * rng.NormFloat64()
+		variance = math.Max(lambda*variance+(1-lambda)*returns[i]*returns[i], 1e-6)
+	}
+	return returns
+}
+
+func TestVolatilityConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   VolatilityConfig
+		valid bool
+	}{
+		{"defaults", VolatilityConfig{}, true},
+		{"ewma with lambda", VolatilityConfig{Model: VolatilityEWMA, Lambda: 0.97}, true},
+		{"unknown model", VolatilityConfig{Model: "guess"}, false},
+		{"lambda 1", VolatilityConfig{Lambda: 1}, false},
+		{"negative lambda", VolatilityConfig{Lambda: -0.5}, false},
+		{"lambda NaN", VolatilityConfig{Lambda: math.NaN()}, false},
+		{"lambda +Inf", VolatilityConfig{Lambda: math.Inf(1)}, false},
+		{"zero horizon", VolatilityConfig{Horizons: []int{0}}, false},
+		{"long horizon", VolatilityConfig{Horizons: []int{2521}}, false},
+		{"too many horizons", VolatilityConfig{Horizons: make([]int, 51)}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.cfg.Validate(); (err == nil) != tt.valid {
+			t.Fatalf("%s: error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestForecastVolatilityRecoversGARCH(t *testing.T) {
+	const omega, alpha, beta = 2e-6, 0.08, 0.9
+	returns := garchReturns(5000, omega, alpha, beta, 1)
+
+	forecast, err := ForecastVolatility(returns, VolatilityConfig{Model: VolatilityGARCH})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(forecast.Alpha-alpha) > 0.03 || math.Abs(forecast.Beta-beta) > 0.04 {
+		t.Fatalf("alpha %.4f beta %.4f, want near %.2f and %.2f", forecast.Alpha, forecast.Beta, alpha, beta)
+	}
+	if math.Abs(forecast.Persistence-(alpha+beta)) > 0.02 {
+		t.Fatalf("persistence %.4f, want near %.2f", forecast.Persistence, alpha+beta)
+	}
+	if forecast.Omega < omega/3 || forecast.Omega > omega*3 {
+		t.Fatalf("omega %.3g, want near %.3g", forecast.Omega, omega)
+	}
+	wantLongRun := math.Sqrt(omega / (1 - alpha - beta) * 252)
+	if math.Abs(forecast.LongRunVolatility-wantLongRun) > 0.25*wantLongRun {
+		t.Fatalf("long-run volatility %.4f, want near %.4f", forecast.LongRunVolatility, wantLongRun)
+	}
+	if forecast.LogLikelihood == nil || math.IsInf(*forecast.LogLikelihood, 0) {
+		t.Fatalf("log likelihood %v, want a finite value", forecast.LogLikelihood)
+	}
+	if _, err := json.Marshal(forecast); err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+}
+
+func TestForecastVolatilityRecoversEWMA(t *testing.T) {
+	const lambda = 0.9
+	returns := ewmaReturns(4000, lambda, 2)
+
+	forecast, err := ForecastVolatility(returns, VolatilityConfig{Model: VolatilityEWMA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(forecast.Lambda-lambda) > 0.02 {
+		t.Fatalf("lambda %.4f, want near %.2f", forecast.Lambda, lambda)
+	}
+	// EWMA never reverts, so every horizon carries the next-bar volatility
+	for _, p := range forecast.TermStructure {
+		if math.Abs(p.Volatility-forecast.Current) > 1e-12 {
+			t.Fatalf("horizon %d volatility %.6f, want %.6f", p.Horizon, p.Volatility, forecast.Current)
+		}
+	}
+
+	fixed, err := ForecastVolatility(returns, VolatilityConfig{Model: VolatilityEWMA, Lambda: RiskMetricsLambda})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed.Lambda != RiskMetricsLambda {
+		t.Fatalf("lambda %.4f, want the configured %.2f", fixed.Lambda, RiskMetricsLambda)
+	}
+}
+
+func TestForecastVolatilityConstantReturns(t *testing.T) {
+	returns := make([]float64, 200)
+	for i := range returns {
+		returns[i] = 0.001
+	}
+	forecast, err := ForecastVolatility(returns, VolatilityConfig{Model: VolatilityHistorical})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forecast.LogLikelihood != nil {
+		t.Fatalf("log likelihood %v, want it omitted for a zero variance", *forecast.LogLikelihood)
+	}
+	if _, err := json.Marshal(forecast); err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if _, err := ForecastVolatility(returns, VolatilityConfig{Model: VolatilityGARCH}); err == nil {
+		t.Fatal("GARCH fit to constant returns succeeded")
+	}
+}
+
+func TestHorizonVariance(t *testing.T) {
+	f := &VolatilityForecast{Persistence: 0.9, next: 4e-4, longRun: 1e-4}
+	// Each bar's expected variance is longRun + persistence^k × (next - longRun)
+	var want float64
+	for k := 0; k < 10; k++ {
+		want += f.longRun + math.Pow(f.Persistence, float64(k))*(f.next-f.longRun)
+	}
+	if got := f.HorizonVariance(10); math.Abs(got-want) > 1e-15 {
+		t.Fatalf("horizon variance %.6g, want %.6g", got, want)
+	}
+
+	flat := &VolatilityForecast{Persistence: 1, next: 4e-4, longRun: 4e-4}
+	if got := flat.HorizonVariance(10); math.Abs(got-4e-3) > 1e-15 {
+		t.Fatalf("flat horizon variance %.6g, want 4e-3", got)
+	}
+}
+
+func TestTargetWeight(t *testing.T) {
+	tests := []struct {
+		target, forecast, max, want float64
+	}{
+		{0.1, 0.2, 0, 0.5},
+		{0.2, 0.1, 0, 2},
+		{0.2, 0.1, 1.5, 1.5},
+		{0.1, 0, 1, 0},
+		{0, 0.2, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := TargetWeight(tt.target, tt.forecast, tt.max); math.Abs(got-tt.want) > 1e-12 {
+			t.Fatalf("TargetWeight(%v, %v, %v) = %v, want %v", tt.target, tt.forecast, tt.max, got, tt.want)
+		}
+	}
+}
@@ -1,20 +1,31 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"os"
+	"strings"
+	"sync"
 	"time"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 	"trading-service/internal/algorithms"
+	"trading-service/internal/expression"
 	"trading-service/internal/indicators"
+	"trading-service/internal/ml"
 	"trading-service/internal/models"
+	"trading-service/internal/risk"
 )
 
 // AnalysisService handles technical analysis and signal generation
 type AnalysisService struct {
 	algorithmManager *algorithms.AlgorithmManager
 	logger           *logrus.Logger
+	modelPath        string     // Where trained ML models are saved, empty for memory only
+	riskFreeRate     float64    // Annual default for risk metrics
+	targetVolatility float64    // Annualized volatility signals are sized to
+	training         sync.Mutex // Held while a model trains
 }
 
 // NewAnalysisService creates a new analysis service
@@ -22,9 +33,164 @@ func NewAnalysisService(logger *logrus.Logger) *AnalysisService {
 	return &AnalysisService{
 		algorithmManager: algorithms.NewAlgorithmManager(),
 		logger:           logger,
+		riskFreeRate:     0.02,
+		targetVolatility: 0.15,
 	}
 }
 
+// SetRiskFreeRate sets the annual risk-free rate DefaultRiskConfig uses
+func (s *AnalysisService) SetRiskFreeRate(rate float64) {
+	s.riskFreeRate = rate
+}
+
+// SetTargetVolatility sets the annualized volatility BUY and SELL signals
+// are sized to
+func (s *AnalysisService) SetTargetVolatility(volatility float64) {
+	s.targetVolatility = volatility
+}
+
+// AlgorithmManager returns the shared algorithm registry
+func (s *AnalysisService) AlgorithmManager() *algorithms.AlgorithmManager {
+	return s.algorithmManager
+}
+
+// LoadStrategies registers rule strategies from a definitions directory.
+// Files that fail validation are logged and skipped.
+func (s *AnalysisService) LoadStrategies(dir string) int {
+	strategies, errs := algorithms.LoadStrategyDefinitions(dir)
+	for _, err := range errs {
+		s.logger.WithError(err).WithField("dir", dir).Warn("Failed to load strategy definition")
+	}
+
+	loaded := 0
+	for _, strategy := range strategies {
+		if err := s.algorithmManager.RegisterRuleStrategy(strategy); err != nil {
+			s.logger.WithError(err).WithField("strategy", strategy.ID()).Warn("Failed to register strategy")
+			continue
+		}
+		loaded++
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"dir":        dir,
+		"strategies": loaded,
+	}).Info("Rule strategies loaded")
+
+	return loaded
+}
+
+// LoadModel sets where trained ML models are persisted and serves the model
+// saved there, if any
+func (s *AnalysisService) LoadModel(path string) error {
+	s.modelPath = path
+
+	model, err := ml.Load(path)
+	if os.IsNotExist(err) {
+		s.logger.WithField("path", path).Info("No saved ML model; train one via the API")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.algorithmManager.SetMLModel(model)
+	s.logger.WithFields(logrus.Fields{
+		"path":       path,
+		"trained_at": model.TrainedAt,
+		"symbols":    model.Symbols,
+	}).Info("ML model loaded")
+	return nil
+}
+
+// Training runs on the request goroutine, so its size is capped: IRLS cost
+// grows with the rows fitted times the Newton iterations
+const (
+	maxTrainSymbols    = 20
+	maxTrainDays       = 3650
+	maxTrainIterations = 200
+)
+
+// ErrTrainingInProgress is returned by TrainModel while another model trains
+var ErrTrainingInProgress = errors.New("a model is already training; try again when it finishes")
+
+// TrainRequest selects the history a model is trained on
+type TrainRequest struct {
+	Symbols []string `json:"symbols" binding:"required"`
+	Period  int      `json:"period"` // History in days, default 1095
+	ml.TrainConfig
+}
+
+// Validate normalizes the symbols, fills the default period and checks the
+// request against the training caps
+func (r *TrainRequest) Validate() error {
+	symbols := make([]string, 0, len(r.Symbols))
+	seen := make(map[string]bool, len(r.Symbols))
+	for _, symbol := range r.Symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol != "" && !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	r.Symbols = symbols
+	if r.Period == 0 {
+		r.Period = 1095
+	}
+
+	if len(r.Symbols) == 0 || len(r.Symbols) > maxTrainSymbols {
+		return fmt.Errorf("between 1 and %d distinct symbols are required", maxTrainSymbols)
+	}
+	if r.Period < 0 || r.Period > maxTrainDays {
+		return fmt.Errorf("period must be between 1 and %d days", maxTrainDays)
+	}
+	if r.MaxIterations > maxTrainIterations {
+		return fmt.Errorf("max_iterations must not exceed %d", maxTrainIterations)
+	}
+	return nil
+}
+
+// TrainModel fits a new ML model on the given series, persists it when a
+// model path is configured and starts serving it as ml_model. One model
+// trains at a time; a second call fails with ErrTrainingInProgress.
+func (s *AnalysisService) TrainModel(series [][]models.HistoricalData, cfg ml.TrainConfig) (*ml.Model, error) {
+	if !s.training.TryLock() {
+		return nil, ErrTrainingInProgress
+	}
+	defer s.training.Unlock()
+
+	model, err := ml.Train(series, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.modelPath != "" {
+		if err := model.Save(s.modelPath); err != nil {
+			return nil, err
+		}
+	}
+	s.algorithmManager.SetMLModel(model)
+
+	s.logger.WithFields(logrus.Fields{
+		"symbols":        model.Symbols,
+		"train_samples":  model.TrainSamples,
+		"validation_auc": model.Validation.AUC,
+	}).Info("ML model trained")
+
+	return model, nil
+}
+
+// CurrentModel returns the model served as ml_model, or nil
+func (s *AnalysisService) CurrentModel() *ml.Model {
+	algorithm, err := s.algorithmManager.GetAlgorithm("ml_model")
+	if err != nil {
+		return nil
+	}
+	if strategy, ok := algorithm.(*algorithms.MLStrategy); ok {
+		return strategy.Model()
+	}
+	return nil
+}
+
 // PerformTechnicalAnalysis performs comprehensive technical analysis
 func (s *AnalysisService) PerformTechnicalAnalysis(symbol string, data []models.HistoricalData, indicatorNames []string) (*TechnicalAnalysisResult, error) {
 	s.logger.WithFields(logrus.Fields{
@@ -56,14 +222,20 @@ func (s *AnalysisService) PerformTechnicalAnalysis(symbol string, data []models.
 		volumes = append(volumes, d.Volume)
 	}
 
+	bars := indicators.NewBars(data)
+
 	// Calculate requested indicators
 	for _, indicatorName := range indicatorNames {
-		indicatorResult, err := s.calculateIndicator(indicatorName, highs, lows, closes, opens, volumes)
+		indicatorResult, err := s.calculateIndicator(indicatorName, bars, highs, lows, closes, opens, volumes)
 		if err != nil {
 			s.logger.WithError(err).WithFields(logrus.Fields{
 				"symbol":    symbol,
 				"indicator": indicatorName,
 			}).Warn("Failed to calculate indicator")
+			if result.Unavailable == nil {
+				result.Unavailable = make(map[string]string)
+			}
+			result.Unavailable[indicatorName] = err.Error()
 			continue
 		}
 		result.Indicators[indicatorName] = indicatorResult
@@ -81,7 +253,7 @@ func (s *AnalysisService) PerformTechnicalAnalysis(symbol string, data []models.
 }
 
 // calculateIndicator calculates a specific technical indicator
-func (s *AnalysisService) calculateIndicator(name string, highs, lows, closes, opens []decimal.Decimal, volumes []int64) (interface{}, error) {
+func (s *AnalysisService) calculateIndicator(name string, bars *indicators.Bars, highs, lows, closes, opens []decimal.Decimal, volumes []int64) (interface{}, error) {
 	switch name {
 	case "RSI":
 		rsi, err := indicators.RSI(closes, 14)
@@ -228,6 +400,64 @@ func (s *AnalysisService) calculateIndicator(name string, highs, lows, closes, o
 			"period": 20,
 		}, nil
 
+	case "Pivots":
+		// Project the next session's levels from the latest completed bar
+		last := len(closes) - 1
+		pivots := make(map[string]interface{})
+		for _, method := range []string{indicators.PivotClassic, indicators.PivotFibonacci, indicators.PivotCamarilla} {
+			levels, err := indicators.CalculatePivotPoints(highs[last], lows[last], closes[last], method)
+			if err != nil {
+				return nil, err
+			}
+			pivots[method] = levels
+		}
+		pivots["current_price"] = closes[last]
+		
+		return pivots, nil
+
+	case "SupportResistance":
+		levels, err := indicators.SupportResistanceLevels(highs, lows, closes, volumes, 3, 0.01)
+		if err != nil {
+			return nil, err
+		}
+		
+		currentPrice := closes[len(closes)-1]
+		support, resistance := indicators.NearestLevels(levels, currentPrice)
+		
+		return map[string]interface{}{
+			"levels":             levels,
+			"nearest_support":    support,
+			"nearest_resistance": resistance,
+			"current_price":      currentPrice,
+			"swing_strength":     3,
+			"tolerance":          0.01,
+		}, nil
+
+	case "Divergence":
+		cfg := indicators.DefaultDivergenceConfig()
+		divergences, err := indicators.DetectDivergences(bars, cfg)
+		if err != nil {
+			return nil, err
+		}
+		
+		recent := indicators.RecentDivergences(divergences, 10)
+		
+		return map[string]interface{}{
+			"divergences": divergences,
+			"recent":      recent,
+			"bias":        indicators.DivergenceBias(recent),
+			"signal":      s.getDivergenceSignal(recent),
+			"config":      cfg,
+		}, nil
+
+	case "Regime", "RegimeHMM":
+		cfg := indicators.DefaultRegimeConfig()
+		cfg.CompareHMM = true
+		if name == "RegimeHMM" {
+			cfg.Method = indicators.RegimeMethodHMM
+		}
+		return indicators.DetectRegimes(bars, cfg)
+
 	default:
 		return nil, fmt.Errorf("unsupported indicator: %s", name)
 	}
@@ -258,29 +488,66 @@ func (s *AnalysisService) generateAnalysisSummary(result *TechnicalAnalysisResul
 	result.Summary["overall_signal"] = overallSignal
 	result.Summary["signal_strength"] = s.calculateSignalStrength(signals)
 	result.Summary["recommendation"] = s.generateRecommendation(overallSignal)
+
+	// Market regime, when requested
+	for _, name := range []string{"Regime", "RegimeHMM"} {
+		if regime, ok := result.Indicators[name].(*indicators.RegimeAnalysis); ok {
+			result.Summary["market_regime"] = map[string]interface{}{
+				"regime":            regime.Current,
+				"direction":         regime.Direction,
+				"since":             regime.Since,
+				"method":            regime.Method,
+				"suited_algorithms": algorithms.AlgorithmsForRegime(regime.Current),
+			}
+			break
+		}
+	}
 	
-	// Volatility analysis
+	// Volatility analysis; the level follows the GARCH or EWMA forecast
+	// when one can be fitted
 	if len(closes) >= 20 {
 		volatility, _ := indicators.CalculateVolatility(closes, 20)
-		result.Summary["volatility"] = map[string]interface{}{
+		summary := map[string]interface{}{
 			"value":  volatility,
 			"level":  s.getVolatilityLevel(volatility),
 		}
+		if forecast, err := forecastVolatility(indicators.Returns(indicators.DecimalsToFloats(closes))); err == nil {
+			current := decimal.NewFromFloat(forecast.Current)
+			summary["forecast"] = current
+			summary["model"] = forecast.Model
+			summary["level"] = s.getVolatilityLevel(current)
+		}
+		result.Summary["volatility"] = summary
 	}
 }
 
-// GenerateSignals generates trading signals using specified algorithms
-func (s *AnalysisService) GenerateSignals(symbol string, data []models.HistoricalData, algorithmNames []string) (map[string]*models.TradingSignal, error) {
+// GenerateSignals generates trading signals using specified algorithms.
+// Parameter overrides, keyed by algorithm name, apply to this call only;
+// invalid overrides fail the whole request with an *algorithms.ParameterError.
+func (s *AnalysisService) GenerateSignals(symbol string, data []models.HistoricalData, algorithmNames []string, parameters map[string]map[string]interface{}) (map[string]*models.TradingSignal, error) {
 	s.logger.WithFields(logrus.Fields{
 		"symbol":     symbol,
 		"data_points": len(data),
 		"algorithms": algorithmNames,
 	}).Debug("Generating trading signals")
 
+	requested := make(map[string]bool, len(algorithmNames))
+	for _, algorithmName := range algorithmNames {
+		requested[algorithmName] = true
+	}
+	for algorithmName := range parameters {
+		if !requested[algorithmName] {
+			return nil, &algorithms.ParameterError{Algorithm: algorithmName, Msg: "parameters given for an algorithm that was not requested"}
+		}
+	}
+
 	signals := make(map[string]*models.TradingSignal)
 
 	for _, algorithmName := range algorithmNames {
-		algorithm, err := s.algorithmManager.GetAlgorithm(algorithmName)
+		algorithm, err := s.algorithmManager.GetAlgorithmWithParameters(algorithmName, parameters[algorithmName])
+		if _, invalid := err.(*algorithms.ParameterError); invalid {
+			return nil, err
+		}
 		if err != nil {
 			s.logger.WithError(err).WithFields(logrus.Fields{
 				"symbol":    symbol,
@@ -305,6 +572,8 @@ func (s *AnalysisService) GenerateSignals(symbol string, data []models.Historica
 		return nil, fmt.Errorf("no algorithms produced valid signals")
 	}
 
+	s.sizeSignals(signals, data)
+
 	s.logger.WithFields(logrus.Fields{
 		"symbol":      symbol,
 		"signals_count": len(signals),
@@ -313,22 +582,184 @@ func (s *AnalysisService) GenerateSignals(symbol string, data []models.Historica
 	return signals, nil
 }
 
-// CalculateRiskMetrics calculates comprehensive risk metrics
-func (s *AnalysisService) CalculateRiskMetrics(symbol string, data []models.HistoricalData) (*models.RiskMetrics, error) {
+// sizeSignals suggests a position size for BUY and SELL signals: the
+// fraction of equity, never levered, whose forecast volatility matches the
+// target
+func (s *AnalysisService) sizeSignals(signals map[string]*models.TradingSignal, data []models.HistoricalData) {
+	var forecast *risk.VolatilityForecast
+	for _, signal := range signals {
+		if signal.Type != "BUY" && signal.Type != "SELL" {
+			continue
+		}
+		if forecast == nil {
+			bars := indicators.NewBars(data)
+			fitted, err := forecastVolatility(indicators.Returns(bars.Close))
+			if err != nil {
+				s.logger.WithError(err).WithField("symbol", signal.Symbol).Debug("No volatility forecast for position sizing")
+				return
+			}
+			forecast = fitted
+		}
+		if signal.Indicators == nil {
+			signal.Indicators = make(map[string]interface{})
+		}
+		signal.Indicators["forecast_volatility"] = forecast.Current
+		signal.Indicators["volatility_model"] = forecast.Model
+		signal.Indicators["position_size"] = risk.TargetWeight(s.targetVolatility, forecast.Current, 1)
+	}
+}
+
+// RouteByRegime classifies the current market regime and narrows the
+// candidate algorithms to those suited to it; with no candidates every
+// suited built-in algorithm is returned
+func (s *AnalysisService) RouteByRegime(data []models.HistoricalData, candidates []string) ([]string, *indicators.RegimeAnalysis, error) {
+	regime, err := indicators.DetectRegimes(indicators.NewBars(data), indicators.DefaultRegimeConfig())
+	if err != nil {
+		return nil, nil, fmt.Errorf("regime detection failed: %v", err)
+	}
+	return algorithms.RouteByRegime(candidates, regime.Current), regime, nil
+}
+
+// AnalyzePair runs the pairs trading strategy on two symbols' history. The
+// first symbol is the traded leg, the second its hedge. Invalid parameter
+// overrides return an *algorithms.ParameterError.
+func (s *AnalysisService) AnalyzePair(dataY, dataX []models.HistoricalData, parameters map[string]interface{}) (*algorithms.PairAnalysis, error) {
+	algorithm, err := s.algorithmManager.GetMultiAssetAlgorithmWithParameters("pairs_trading", parameters)
+	if err != nil {
+		return nil, err
+	}
+	strategy, ok := algorithm.(*algorithms.PairsTradingStrategy)
+	if !ok {
+		return nil, fmt.Errorf("pairs_trading is not a pairs trading strategy")
+	}
+
+	analysis, err := strategy.AnalyzePair(dataY, dataX)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"symbol_y":     analysis.SymbolY,
+		"symbol_x":     analysis.SymbolX,
+		"cointegrated": analysis.Cointegrated,
+		"z_score":      analysis.ZScore,
+		"action":       analysis.Action,
+	}).Debug("Pair analyzed")
+
+	return analysis, nil
+}
+
+// EvaluateExpression evaluates a parsed indicator expression over the data,
+// returning the latest value and the last `lookback` bars
+func (s *AnalysisService) EvaluateExpression(symbol string, data []models.HistoricalData, expr *expression.Expression, lookback int) (*ExpressionResult, error) {
+	s.logger.WithFields(logrus.Fields{
+		"symbol":      symbol,
+		"data_points": len(data),
+		"expression":  expr.String(),
+	}).Debug("Evaluating expression")
+
+	result, err := expr.Evaluate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if lookback <= 0 || lookback > result.Len() {
+		lookback = result.Len()
+	}
+
+	evaluation := &ExpressionResult{
+		Symbol:     symbol,
+		Expression: expr.String(),
+		Type:       result.Type.String(),
+		Timestamp:  time.Now(),
+		Value:      expressionValue(result, result.Len()-1),
+		Values:     make([]ExpressionPoint, 0, lookback),
+	}
+
+	for i := result.Len() - lookback; i < result.Len(); i++ {
+		evaluation.Values = append(evaluation.Values, ExpressionPoint{
+			Date:  result.Dates[i],
+			Value: expressionValue(result, i),
+		})
+		if result.Type == expression.TypeBool && result.Bool(i) {
+			evaluation.TrueCount++
+		}
+	}
+
+	return evaluation, nil
+}
+
+// expressionValue converts bar i to a JSON-friendly value: bool for
+// conditions, float for series and nil while not computable
+func expressionValue(result *expression.Result, i int) interface{} {
+	if !result.Valid(i) {
+		return nil
+	}
+	if result.Type == expression.TypeBool {
+		return result.Bool(i)
+	}
+	return result.Values[i]
+}
+
+// RiskConfig controls CalculateRiskMetrics
+type RiskConfig struct {
+	VaR          risk.VaRConfig
+	RiskFreeRate float64                 // Annual
+	BenchmarkID  string                  // Reported with the relative metrics
+	Benchmark    []models.HistoricalData // Optional; enables beta, alpha, correlation, tracking error, information ratio and capture ratios
+	Shrinkage    float64                 // Portfolio covariance shrinkage, 0-1 or risk.AutoShrinkage
+	VolatilityModel string               // ewma or garch rescales returns to forecast volatility for VaR; empty or historical leaves them
+}
+
+// DefaultRiskConfig returns one-day historical VaR at 95% with the
+// configured risk-free rate, Ledoit-Wolf shrinkage and no benchmark
+func (s *AnalysisService) DefaultRiskConfig() RiskConfig {
+	return RiskConfig{VaR: risk.DefaultVaRConfig(), RiskFreeRate: s.riskFreeRate, Shrinkage: risk.AutoShrinkage}
+}
+
+// Validate checks the VaR settings, risk-free rate, shrinkage and
+// volatility model
+func (c RiskConfig) Validate() error {
+	if err := c.VaR.Validate(); err != nil {
+		return err
+	}
+	if math.IsNaN(c.RiskFreeRate) || math.IsInf(c.RiskFreeRate, 0) {
+		return fmt.Errorf("risk_free_rate must be a finite number")
+	}
+	if c.Shrinkage != risk.AutoShrinkage && !(c.Shrinkage >= 0 && c.Shrinkage <= 1) {
+		return fmt.Errorf("shrinkage must be between 0 and 1, or auto")
+	}
+	if c.VolatilityModel != "" {
+		if err := (risk.VolatilityConfig{Model: c.VolatilityModel}).Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CalculateRiskMetrics calculates comprehensive risk metrics from daily bars.
+// VaR95 and VaR99 use the configured method and horizon; ConditionalVaR is
+// at the configured confidence.
+func (s *AnalysisService) CalculateRiskMetrics(symbol string, data []models.HistoricalData, cfg RiskConfig) (*models.RiskMetrics, error) {
 	s.logger.WithFields(logrus.Fields{
 		"symbol":     symbol,
 		"data_points": len(data),
+		"var_method": cfg.VaR.Method,
 	}).Debug("Calculating risk metrics")
 
 	if len(data) < 30 {
 		return nil, fmt.Errorf("insufficient data for risk calculation: need at least 30 data points")
 	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 
 	// Extract price data
 	var closes []decimal.Decimal
 	for _, d := range data {
 		closes = append(closes, d.Close)
 	}
+	returns := indicators.Returns(indicators.DecimalsToFloats(closes))
 
 	riskMetrics := &models.RiskMetrics{
 		Symbol:    symbol,
@@ -337,63 +768,45 @@ func (s *AnalysisService) CalculateRiskMetrics(symbol string, data []models.Hist
 	}
 
 	// Calculate volatility
-	volatility, err := indicators.CalculateVolatility(closes, len(closes))
+	volatility, err := indicators.CalculateVolatility(closes, len(closes)-1)
 	if err == nil {
 		riskMetrics.Volatility = volatility
 	}
 
-	// Calculate returns for additional metrics
-	var returns []decimal.Decimal
-	for i := 1; i < len(closes); i++ {
-		ret := closes[i].Div(closes[i-1]).Sub(decimal.NewFromInt(1))
-		returns = append(returns, ret)
+	// With a volatility model, VaR sees returns rescaled to the forecast
+	// volatility over its horizon
+	varReturns, forecasts, err := forecastReturns([]string{symbol}, [][]float64{returns}, cfg.VolatilityModel, cfg.VaR.Horizon)
+	if err != nil {
+		return nil, err
+	}
+	if forecasts != nil {
+		riskMetrics.VolatilityModel = forecasts[0].Model
+		riskMetrics.ForecastVolatility = decimal.NewFromFloat(forecasts[0].TermStructure[0].Volatility)
 	}
 
-	// Calculate Value at Risk (simple method)
-	if len(returns) >= 20 {
-		var returnFloats []float64
-		for _, ret := range returns {
-			f, _ := ret.Float64()
-			returnFloats = append(returnFloats, f)
-		}
-
-		// Sort returns for percentile calculation
-		for i := 0; i < len(returnFloats)-1; i++ {
-			for j := i + 1; j < len(returnFloats); j++ {
-				if returnFloats[i] > returnFloats[j] {
-					returnFloats[i], returnFloats[j] = returnFloats[j], returnFloats[i]
-				}
-			}
-		}
-
-		// VaR at 95% confidence (5th percentile)
-		var95Index := int(float64(len(returnFloats)) * 0.05)
-		if var95Index < len(returnFloats) {
-			riskMetrics.VaR95 = decimal.NewFromFloat(returnFloats[var95Index])
-		}
-
-		// VaR at 99% confidence (1st percentile)
-		var99Index := int(float64(len(returnFloats)) * 0.01)
-		if var99Index < len(returnFloats) {
-			riskMetrics.VaR99 = decimal.NewFromFloat(returnFloats[var99Index])
-		}
+	// Value at Risk at the standard levels and expected shortfall at the
+	// requested one, all read from one sample
+	estimates, err := risk.ValueAtRiskLevels(varReturns[0], cfg.VaR, 0.95, 0.99, cfg.VaR.Confidence)
+	if err != nil {
+		return nil, fmt.Errorf("VaR calculation failed: %v", err)
 	}
+	riskMetrics.VaR95 = decimal.NewFromFloat(estimates[0].VaR)
+	riskMetrics.VaR99 = decimal.NewFromFloat(estimates[1].VaR)
+	riskMetrics.ConditionalVaR = decimal.NewFromFloat(estimates[2].CVaR)
+	riskMetrics.VaRConfidence = estimates[2].Confidence
+	riskMetrics.VaRMethod = estimates[2].Method
+	riskMetrics.VaRHorizon = estimates[2].Horizon
 
 	// Calculate maximum drawdown
 	maxDrawdown := s.calculateMaxDrawdown(closes)
 	riskMetrics.MaxDrawdown = maxDrawdown
 
-	// Calculate Sharpe ratio (simplified)
-	if len(returns) > 0 {
-		avgReturn := s.calculateAverageReturn(returns)
-		vol, _ := riskMetrics.Volatility.Float64()
-		if vol > 0 {
-			avgRetFloat, _ := avgReturn.Float64()
-			// Assuming risk-free rate of 2% annually
-			riskFreeRate := 0.02 / 252 // Daily risk-free rate
-			sharpe := (avgRetFloat - riskFreeRate) / vol
-			riskMetrics.SharpeRatio = decimal.NewFromFloat(sharpe)
-		}
+	// Annualized risk-adjusted returns
+	riskMetrics.SharpeRatio = decimal.NewFromFloat(risk.SharpeRatio(returns, cfg.RiskFreeRate, tradingDaysPerYear))
+	riskMetrics.SortinoRatio = decimal.NewFromFloat(risk.SortinoRatio(returns, cfg.RiskFreeRate, tradingDaysPerYear))
+
+	if err := applyBenchmark(riskMetrics, data, cfg); err != nil {
+		return nil, err
 	}
 
 	// Determine risk level
@@ -410,6 +823,70 @@ func (s *AnalysisService) CalculateRiskMetrics(symbol string, data []models.Hist
 	return riskMetrics, nil
 }
 
+// tradingDaysPerYear annualizes daily risk metrics
+const tradingDaysPerYear = 252
+
+// applyBenchmark sets the benchmark-relative metrics, if cfg has a
+// benchmark, over the days both series traded
+func applyBenchmark(metrics *models.RiskMetrics, data []models.HistoricalData, cfg RiskConfig) error {
+	if len(cfg.Benchmark) == 0 {
+		return nil
+	}
+	own, benchmark := alignClosesByDay(data, cfg.Benchmark)
+	relative, err := risk.CompareToBenchmark(own, benchmark, cfg.RiskFreeRate, tradingDaysPerYear)
+	if err != nil {
+		return fmt.Errorf("too little overlap with the benchmark: %v", err)
+	}
+	metrics.Beta = decimal.NewFromFloat(relative.Beta)
+	metrics.Alpha = decimal.NewFromFloat(relative.Alpha)
+	metrics.Correlation = decimal.NewFromFloat(relative.Correlation)
+	metrics.TrackingError = decimal.NewFromFloat(relative.TrackingError)
+	metrics.InformationRatio = decimal.NewFromFloat(relative.InformationRatio)
+	metrics.UpCapture = decimal.NewFromFloat(relative.UpCapture)
+	metrics.DownCapture = decimal.NewFromFloat(relative.DownCapture)
+	metrics.Benchmark = cfg.BenchmarkID
+	return nil
+}
+
+// alignClosesByDay returns the closes of both series on the calendar days
+// present in both, in date order
+func alignClosesByDay(a, b []models.HistoricalData) (closesA, closesB []float64) {
+	_, closes := alignCloses(a, b)
+	return closes[0], closes[1]
+}
+
+// alignCloses returns the calendar days present in every series, in the
+// first series' order, and each series' closes on those days
+func alignCloses(series ...[]models.HistoricalData) (days []time.Time, closes [][]float64) {
+	byDay := make([]map[string]float64, len(series))
+	for i, data := range series[1:] {
+		byDay[i+1] = make(map[string]float64, len(data))
+		for _, bar := range data {
+			byDay[i+1][bar.Date.UTC().Format("2006-01-02")] = bar.Close.InexactFloat64()
+		}
+	}
+
+	closes = make([][]float64, len(series))
+	row := make([]float64, len(series))
+bars:
+	for _, bar := range series[0] {
+		key := bar.Date.UTC().Format("2006-01-02")
+		row[0] = bar.Close.InexactFloat64()
+		for i := 1; i < len(series); i++ {
+			value, ok := byDay[i][key]
+			if !ok {
+				continue bars
+			}
+			row[i] = value
+		}
+		days = append(days, bar.Date)
+		for i, value := range row {
+			closes[i] = append(closes[i], value)
+		}
+	}
+	return days, closes
+}
+
 // Helper functions for signal interpretation
 
 func (s *AnalysisService) interpretRSI(rsi float64) string {
@@ -506,6 +983,17 @@ func (s *AnalysisService) getCCISignal(cci float64) string {
 	return "HOLD"
 }
 
+func (s *AnalysisService) getDivergenceSignal(recent []indicators.Divergence) string {
+	bias := indicators.DivergenceBias(recent)
+	
+	if bias >= 2 {
+		return "BUY"
+	} else if bias <= -2 {
+		return "SELL"
+	}
+	return "HOLD"
+}
+
 func (s *AnalysisService) getTrendFromChange(changePercent decimal.Decimal) string {
 	changeFloat, _ := changePercent.Float64()
 	
@@ -617,21 +1105,11 @@ func (s *AnalysisService) calculateMaxDrawdown(prices []decimal.Decimal) decimal
 	return maxDrawdown
 }
 
-func (s *AnalysisService) calculateAverageReturn(returns []decimal.Decimal) decimal.Decimal {
-	if len(returns) == 0 {
-		return decimal.Zero
-	}
-	
-	sum := decimal.Zero
-	for _, ret := range returns {
-		sum = sum.Add(ret)
-	}
-	
-	return sum.Div(decimal.NewFromInt(int64(len(returns))))
-}
-
 func (s *AnalysisService) determineRiskLevel(metrics *models.RiskMetrics) string {
 	vol, _ := metrics.Volatility.Float64()
+	if metrics.VolatilityModel != "" {
+		vol, _ = metrics.ForecastVolatility.Float64()
+	}
 	maxDD, _ := metrics.MaxDrawdown.Float64()
 	
 	riskScore := 0
@@ -660,8 +1138,26 @@ func (s *AnalysisService) determineRiskLevel(metrics *models.RiskMetrics) string
 
 // TechnicalAnalysisResult represents the result of technical analysis
 type TechnicalAnalysisResult struct {
-	Symbol     string                 `json:"symbol"`
-	Timestamp  time.Time              `json:"timestamp"`
-	Indicators map[string]interface{} `json:"indicators"`
-	Summary    map[string]interface{} `json:"summary"`
-}
\ No newline at end of file
+	Symbol      string                 `json:"symbol"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Indicators  map[string]interface{} `json:"indicators"`
+	Summary     map[string]interface{} `json:"summary"`
+	Unavailable map[string]string      `json:"unavailable,omitempty"` // Requested indicators that couldn't be calculated, with the reason
+}
+
+// ExpressionResult represents the evaluation of an indicator expression
+type ExpressionResult struct {
+	Symbol     string            `json:"symbol"`
+	Expression string            `json:"expression"`
+	Type       string            `json:"type"`  // number, series, bool
+	Value      interface{}       `json:"value"` // Latest bar, null if not computable
+	Values     []ExpressionPoint `json:"values"`
+	TrueCount  int               `json:"true_count,omitempty"` // Bars in Values where a condition holds
+	Timestamp  time.Time         `json:"timestamp"`
+}
+
+// ExpressionPoint is an expression value on one bar
+type ExpressionPoint struct {
+	Date  time.Time   `json:"date"`
+	Value interface{} `json:"value"`
+}
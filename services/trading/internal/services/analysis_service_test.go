@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/ml"
+	"trading-service/internal/models"
+)
+
+// dailyBars turns closes into consecutive daily bars
+func dailyBars(symbol string, closes ...float64) []models.HistoricalData {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bars := make([]models.HistoricalData, len(closes))
+	for i, price := range closes {
+		bars[i] = models.HistoricalData{Symbol: symbol, Date: start.AddDate(0, 0, i), Close: decimal.NewFromFloat(price)}
+	}
+	return bars
+}
+
+func TestTrainRequestValidate(t *testing.T) {
+	tooMany := make([]string, maxTrainSymbols+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("S%d", i)
+	}
+
+	tests := []struct {
+		name    string
+		request TrainRequest
+		valid   bool
+	}{
+		{"defaults", TrainRequest{Symbols: []string{"aapl"}}, true},
+		{"duplicates", TrainRequest{Symbols: []string{"AAPL", " aapl "}}, true},
+		{"no symbols", TrainRequest{Symbols: []string{" "}}, false},
+		{"too many symbols", TrainRequest{Symbols: tooMany}, false},
+		{"max period", TrainRequest{Symbols: []string{"AAPL"}, Period: maxTrainDays}, true},
+		{"long period", TrainRequest{Symbols: []string{"AAPL"}, Period: maxTrainDays + 1}, false},
+		{"negative period", TrainRequest{Symbols: []string{"AAPL"}, Period: -1}, false},
+		{"too many iterations", TrainRequest{Symbols: []string{"AAPL"}, TrainConfig: ml.TrainConfig{MaxIterations: maxTrainIterations + 1}}, false},
+	}
+	for _, tt := range tests {
+		err := tt.request.Validate()
+		if (err == nil) != tt.valid {
+			t.Fatalf("%s: error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+
+	request := TrainRequest{Symbols: []string{" msft", "MSFT", "aapl"}}
+	if err := request.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if request.Period != 1095 || len(request.Symbols) != 2 || request.Symbols[0] != "MSFT" || request.Symbols[1] != "AAPL" {
+		t.Fatalf("normalized request = %+v", request)
+	}
+}
+
+func TestTrainModelOneAtATime(t *testing.T) {
+	s := NewAnalysisService(testLogger())
+	s.training.Lock()
+	_, err := s.TrainModel(nil, ml.TrainConfig{})
+	s.training.Unlock()
+	if !errors.Is(err, ErrTrainingInProgress) {
+		t.Fatalf("error = %v, want ErrTrainingInProgress", err)
+	}
+
+	if _, err := s.TrainModel(nil, ml.TrainConfig{}); err == nil || errors.Is(err, ErrTrainingInProgress) {
+		t.Fatalf("error = %v, want insufficient data", err)
+	}
+}
+
+func TestRiskConfigValidate(t *testing.T) {
+	base := NewAnalysisService(testLogger()).DefaultRiskConfig()
+	tests := []struct {
+		name   string
+		modify func(*RiskConfig)
+		valid  bool
+	}{
+		{"defaults", func(c *RiskConfig) {}, true},
+		{"negative rate", func(c *RiskConfig) { c.RiskFreeRate = -0.005 }, true},
+		{"NaN rate", func(c *RiskConfig) { c.RiskFreeRate = math.NaN() }, false},
+		{"infinite rate", func(c *RiskConfig) { c.RiskFreeRate = math.Inf(1) }, false},
+		{"NaN confidence", func(c *RiskConfig) { c.VaR.Confidence = math.NaN() }, false},
+		{"fixed shrinkage", func(c *RiskConfig) { c.Shrinkage = 0.3 }, true},
+		{"NaN shrinkage", func(c *RiskConfig) { c.Shrinkage = math.NaN() }, false},
+		{"large shrinkage", func(c *RiskConfig) { c.Shrinkage = 2 }, false},
+	}
+	for _, tt := range tests {
+		cfg := base
+		tt.modify(&cfg)
+		if err := cfg.Validate(); (err == nil) != tt.valid {
+			t.Fatalf("%s: error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestPerformTechnicalAnalysisReportsUnavailable(t *testing.T) {
+	closes := make([]float64, 25)
+	for i := range closes {
+		closes[i] = 100 + float64(i%5)
+	}
+	result, err := NewAnalysisService(testLogger()).PerformTechnicalAnalysis("AAPL", dailyBars("AAPL", closes...), []string{"RSI", "Divergence"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := result.Indicators["RSI"]; !ok {
+		t.Fatalf("indicators = %v, want RSI", result.Indicators)
+	}
+	if _, ok := result.Indicators["Divergence"]; ok || result.Unavailable["Divergence"] == "" {
+		t.Fatalf("unavailable = %v, want Divergence reported for 25 bars", result.Unavailable)
+	}
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+)
+
+// maxBenchmarks caps the custom benchmarks that can be registered
+const maxBenchmarks = 100
+
+// maxBenchmarkComponents caps the constituents of a composite benchmark
+const maxBenchmarkComponents = 20
+
+// compositeBaseValue is the first close of a composite or portfolio index
+const compositeBaseValue = 100
+
+var benchmarkIDPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_.-]{0,31}$`)
+
+// BenchmarkComponent is a constituent of a benchmark and its weight
+type BenchmarkComponent struct {
+	Symbol string  `json:"symbol"`
+	Weight float64 `json:"weight"` // Normalized so a benchmark's weights sum to 1
+}
+
+// Benchmark is a series returns are compared against: a single symbol such
+// as SPY, or a composite rebalanced to its weights every day
+type Benchmark struct {
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Components  []BenchmarkComponent `json:"components"`
+	BuiltIn     bool                 `json:"built_in"`
+	CreatedAt   time.Time            `json:"created_at"`
+}
+
+// builtInBenchmarks are always registered and can't be replaced or removed
+var builtInBenchmarks = []Benchmark{
+	{ID: "SPY", Name: "S&P 500", Description: "SPDR S&P 500 ETF Trust", Components: []BenchmarkComponent{{Symbol: "SPY", Weight: 1}}},
+	{ID: "QQQ", Name: "Nasdaq-100", Description: "Invesco QQQ Trust", Components: []BenchmarkComponent{{Symbol: "QQQ", Weight: 1}}},
+	{ID: "DIA", Name: "Dow Jones Industrial Average", Description: "SPDR Dow Jones Industrial Average ETF Trust", Components: []BenchmarkComponent{{Symbol: "DIA", Weight: 1}}},
+	{ID: "IWM", Name: "Russell 2000", Description: "iShares Russell 2000 ETF", Components: []BenchmarkComponent{{Symbol: "IWM", Weight: 1}}},
+}
+
+// BenchmarkService keeps the benchmark registry and builds benchmark price
+// series
+type BenchmarkService struct {
+	marketDataService *MarketDataService
+	logger            *logrus.Logger
+
+	mu         sync.RWMutex
+	benchmarks map[string]*Benchmark
+}
+
+// NewBenchmarkService creates a registry holding the built-in benchmarks
+func NewBenchmarkService(marketDataService *MarketDataService, logger *logrus.Logger) *BenchmarkService {
+	s := &BenchmarkService{
+		marketDataService: marketDataService,
+		logger:            logger,
+		benchmarks:        make(map[string]*Benchmark, len(builtInBenchmarks)),
+	}
+	for _, benchmark := range builtInBenchmarks {
+		benchmark := benchmark
+		benchmark.BuiltIn = true
+		benchmark.CreatedAt = time.Now()
+		s.benchmarks[benchmark.ID] = &benchmark
+	}
+	return s
+}
+
+// List returns the registered benchmarks ordered by ID
+func (s *BenchmarkService) List() []Benchmark {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Benchmark, 0, len(s.benchmarks))
+	for _, benchmark := range s.benchmarks {
+		list = append(list, *benchmark)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Get returns a registered benchmark
+func (s *BenchmarkService) Get(id string) (Benchmark, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	benchmark, ok := s.benchmarks[strings.ToUpper(id)]
+	if !ok {
+		return Benchmark{}, false
+	}
+	return *benchmark, true
+}
+
+// Resolve returns the registered benchmark with the ID, or else the symbol
+// itself as a single-component benchmark
+func (s *BenchmarkService) Resolve(id string) Benchmark {
+	id = strings.ToUpper(strings.TrimSpace(id))
+	if benchmark, ok := s.Get(id); ok {
+		return benchmark
+	}
+	return Benchmark{ID: id, Name: id, Components: []BenchmarkComponent{{Symbol: id, Weight: 1}}}
+}
+
+// Register validates and stores a custom benchmark, replacing an existing
+// custom benchmark with the same ID. Weights are normalized to sum to 1.
+func (s *BenchmarkService) Register(benchmark Benchmark) (*Benchmark, error) {
+	benchmark.ID = strings.ToUpper(strings.TrimSpace(benchmark.ID))
+	if !benchmarkIDPattern.MatchString(benchmark.ID) {
+		return nil, fmt.Errorf("benchmark id must be 1-32 letters, digits, '.', '_' or '-'")
+	}
+	if benchmark.Name == "" {
+		benchmark.Name = benchmark.ID
+	}
+	if len(benchmark.Components) == 0 || len(benchmark.Components) > maxBenchmarkComponents {
+		return nil, fmt.Errorf("a benchmark needs between 1 and %d components", maxBenchmarkComponents)
+	}
+
+	components := make([]BenchmarkComponent, 0, len(benchmark.Components))
+	seen := make(map[string]bool, len(benchmark.Components))
+	var total float64
+	for _, component := range benchmark.Components {
+		component.Symbol = strings.ToUpper(strings.TrimSpace(component.Symbol))
+		if component.Symbol == "" {
+			return nil, fmt.Errorf("component symbol is required")
+		}
+		if seen[component.Symbol] {
+			return nil, fmt.Errorf("component %s is listed more than once", component.Symbol)
+		}
+		if component.Weight <= 0 || math.IsInf(component.Weight, 0) || math.IsNaN(component.Weight) {
+			return nil, fmt.Errorf("component %s needs a positive weight", component.Symbol)
+		}
+		seen[component.Symbol] = true
+		total += component.Weight
+		components = append(components, component)
+	}
+	for i := range components {
+		components[i].Weight /= total
+	}
+	benchmark.Components = components
+	benchmark.BuiltIn = false
+	benchmark.CreatedAt = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.benchmarks[benchmark.ID]; ok {
+		if existing.BuiltIn {
+			return nil, fmt.Errorf("benchmark %s is built in and can't be replaced", benchmark.ID)
+		}
+	} else if len(s.benchmarks)-len(builtInBenchmarks) >= maxBenchmarks {
+		return nil, fmt.Errorf("benchmark limit of %d reached", maxBenchmarks)
+	}
+	s.benchmarks[benchmark.ID] = &benchmark
+
+	s.logger.WithFields(logrus.Fields{
+		"benchmark":  benchmark.ID,
+		"components": len(components),
+	}).Info("Benchmark registered")
+
+	registered := benchmark
+	return &registered, nil
+}
+
+// Remove deletes a custom benchmark
+func (s *BenchmarkService) Remove(id string) error {
+	id = strings.ToUpper(id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	benchmark, ok := s.benchmarks[id]
+	if !ok {
+		return fmt.Errorf("benchmark %s not found", id)
+	}
+	if benchmark.BuiltIn {
+		return fmt.Errorf("benchmark %s is built in and can't be removed", id)
+	}
+	delete(s.benchmarks, id)
+	return nil
+}
+
+// HistoricalData returns the benchmark's daily bars. A single-component
+// benchmark is its symbol's history; a composite is an index starting at
+// 100 on the first day all components traded, rebalanced to its weights
+// each day.
+func (s *BenchmarkService) HistoricalData(benchmark Benchmark, from, to time.Time) ([]models.HistoricalData, error) {
+	if len(benchmark.Components) == 1 {
+		return s.marketDataService.GetHistoricalData(benchmark.Components[0].Symbol, from, to)
+	}
+
+	series := make([][]models.HistoricalData, len(benchmark.Components))
+	for i, component := range benchmark.Components {
+		data, err := s.marketDataService.GetHistoricalData(component.Symbol, from, to)
+		if err != nil {
+			return nil, err
+		}
+		series[i] = data
+	}
+	days, closes := alignCloses(series...)
+
+	weights := make([]float64, len(benchmark.Components))
+	for i, component := range benchmark.Components {
+		weights[i] = component.Weight
+	}
+	index := rebalancedIndex(benchmark.ID, "composite", days, closes, weights)
+	if len(index) == 0 {
+		return nil, fmt.Errorf("components of benchmark %s have no days in common", benchmark.ID)
+	}
+	return index, nil
+}
+
+// rebalancedIndex turns aligned closes into the daily bars of an index
+// starting at 100 that holds the weights, rebalanced every day. Weights
+// summing to less than 1 leave the rest in cash.
+func rebalancedIndex(symbol, source string, days []time.Time, closes [][]float64, weights []float64) []models.HistoricalData {
+	index := make([]models.HistoricalData, 0, len(days))
+	value := float64(compositeBaseValue)
+	for t, day := range days {
+		if t > 0 {
+			var dayReturn float64
+			for i, weight := range weights {
+				if previous := closes[i][t-1]; previous != 0 {
+					dayReturn += weight * (closes[i][t]/previous - 1)
+				}
+			}
+			value *= 1 + dayReturn
+		}
+
+		price := decimal.NewFromFloat(value)
+		index = append(index, models.HistoricalData{
+			ID:       fmt.Sprintf("%s_%s", symbol, day.UTC().Format("2006-01-02")),
+			Symbol:   symbol,
+			Date:     day,
+			Open:     price,
+			High:     price,
+			Low:      price,
+			Close:    price,
+			AdjClose: price,
+			Source:   source,
+		})
+	}
+	return index
+}
+
+// Betas measures each symbol's beta against the benchmark over the period.
+// Symbols whose beta can't be measured are left out of betas and returned
+// in unmeasured with the reason; only a benchmark without history fails.
+func (s *BenchmarkService) Betas(symbols []string, benchmark Benchmark, from, to time.Time) (betas map[string]float64, unmeasured map[string]error, err error) {
+	benchmarkData, err := s.HistoricalData(benchmark, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	betas = make(map[string]float64, len(symbols))
+	unmeasured = make(map[string]error)
+	for _, symbol := range symbols {
+		beta, err := s.beta(symbol, benchmark, benchmarkData, from, to)
+		if err != nil {
+			unmeasured[symbol] = err
+			continue
+		}
+		betas[symbol] = beta
+	}
+	return betas, unmeasured, nil
+}
+
+// beta measures one symbol's beta against the benchmark's bars
+func (s *BenchmarkService) beta(symbol string, benchmark Benchmark, benchmarkData []models.HistoricalData, from, to time.Time) (float64, error) {
+	data, err := s.marketDataService.GetHistoricalData(symbol, from, to)
+	if err != nil {
+		return 0, err
+	}
+	stock, market := alignClosesByDay(data, benchmarkData)
+	if len(stock) < 2 {
+		return 0, fmt.Errorf("%s has no history in common with %s", symbol, benchmark.ID)
+	}
+	beta, err := indicators.CalculateBeta(indicators.FloatsToDecimals(stock), indicators.FloatsToDecimals(market), len(stock)-1)
+	if err != nil {
+		return 0, fmt.Errorf("beta for %s: %v", symbol, err)
+	}
+	return beta.InexactFloat64(), nil
+}
+
+// PortfolioBeta is the market-value weighted beta of the positions against
+// the benchmark over the period; cash counts as zero beta. Positions whose
+// beta can't be measured are left out of the weighting and returned by
+// symbol. It fails when no position can be measured.
+func (s *BenchmarkService) PortfolioBeta(positions []*models.Position, cash decimal.Decimal, benchmark Benchmark, from, to time.Time) (decimal.Decimal, []string, error) {
+	symbols := make([]string, len(positions))
+	for i, position := range positions {
+		symbols[i] = position.Symbol
+	}
+	betas, unmeasured, err := s.Betas(symbols, benchmark, from, to)
+	if err != nil {
+		return decimal.Zero, nil, err
+	}
+
+	total := cash
+	var measured int
+	for _, position := range positions {
+		if _, ok := betas[position.Symbol]; ok {
+			total = total.Add(position.MarketValue)
+			measured++
+		}
+	}
+	if len(positions) > 0 && measured == 0 {
+		return decimal.Zero, nil, fmt.Errorf("no position has a measurable beta against %s", benchmark.ID)
+	}
+	if !total.IsPositive() {
+		return decimal.Zero, nil, fmt.Errorf("portfolio has no value")
+	}
+
+	beta := decimal.Zero
+	for _, position := range positions {
+		if b, ok := betas[position.Symbol]; ok {
+			beta = beta.Add(decimal.NewFromFloat(b).Mul(position.MarketValue.Div(total)))
+		}
+	}
+	skipped := make([]string, 0, len(unmeasured))
+	for symbol := range unmeasured {
+		skipped = append(skipped, symbol)
+	}
+	sort.Strings(skipped)
+	return beta, skipped, nil
+}
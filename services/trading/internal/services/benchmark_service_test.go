@@ -0,0 +1,248 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/models"
+	"trading-service/internal/providers"
+)
+
+// closesFromReturns compounds the returns scaled by beta from 100
+func closesFromReturns(returns []float64, beta float64) []float64 {
+	closes := []float64{100}
+	for _, r := range returns {
+		closes = append(closes, closes[len(closes)-1]*(1+beta*r))
+	}
+	return closes
+}
+
+// crashReturns alternates a 4% fall with a 1% rise, losing about 60% over
+// 60 bars
+func crashReturns() []float64 {
+	returns := make([]float64, 59)
+	for i := range returns {
+		returns[i] = 0.01
+		if i%2 == 0 {
+			returns[i] = -0.04
+		}
+	}
+	return returns
+}
+
+// testPositions holds 1000 of each symbol
+func testPositions(symbols ...string) []*models.Position {
+	positions := make([]*models.Position, len(symbols))
+	for i, symbol := range symbols {
+		positions[i] = &models.Position{Symbol: symbol, MarketValue: decimal.NewFromInt(1000)}
+	}
+	return positions
+}
+
+// newTestBenchmarks serves SPY and AAPL with beta 1 and LEV with beta 2
+func newTestBenchmarks() *BenchmarkService {
+	returns := crashReturns()
+	provider := &fixtureProvider{closes: map[string][]float64{
+		"SPY":  closesFromReturns(returns, 1),
+		"AAPL": closesFromReturns(returns, 1),
+		"LEV":  closesFromReturns(returns, 2),
+	}}
+	marketData := NewMarketDataService(providers.NewMarketDataAggregator([]providers.MarketDataProvider{provider}), testLogger())
+	return NewBenchmarkService(marketData, testLogger())
+}
+
+func TestBenchmarkRegister(t *testing.T) {
+	s := newTestBenchmarks()
+	tooMany := make([]BenchmarkComponent, maxBenchmarkComponents+1)
+	for i := range tooMany {
+		tooMany[i] = BenchmarkComponent{Symbol: fmt.Sprintf("S%d", i), Weight: 1}
+	}
+	one := []BenchmarkComponent{{Symbol: "AAPL", Weight: 1}}
+
+	tests := []struct {
+		name      string
+		benchmark Benchmark
+	}{
+		{"blank id", Benchmark{ID: " ", Components: one}},
+		{"id with spaces", Benchmark{ID: "MY INDEX", Components: one}},
+		{"id too long", Benchmark{ID: strings.Repeat("X", 33), Components: one}},
+		{"no components", Benchmark{ID: "EMPTY"}},
+		{"too many components", Benchmark{ID: "WIDE", Components: tooMany}},
+		{"blank symbol", Benchmark{ID: "BLANK", Components: []BenchmarkComponent{{Symbol: " ", Weight: 1}}}},
+		{"duplicate symbol", Benchmark{ID: "DUP", Components: []BenchmarkComponent{{Symbol: "aapl", Weight: 1}, {Symbol: "AAPL", Weight: 1}}}},
+		{"zero weight", Benchmark{ID: "ZERO", Components: []BenchmarkComponent{{Symbol: "AAPL", Weight: 0}}}},
+		{"NaN weight", Benchmark{ID: "NAN", Components: []BenchmarkComponent{{Symbol: "AAPL", Weight: math.NaN()}}}},
+		{"infinite weight", Benchmark{ID: "INF", Components: []BenchmarkComponent{{Symbol: "AAPL", Weight: math.Inf(1)}}}},
+		{"replacing a built-in", Benchmark{ID: "spy", Components: one}},
+	}
+	for _, tt := range tests {
+		if _, err := s.Register(tt.benchmark); err == nil {
+			t.Fatalf("%s: registered, want an error", tt.name)
+		}
+	}
+
+	registered, err := s.Register(Benchmark{ID: " tech.60-40 ", Components: []BenchmarkComponent{{Symbol: " aapl", Weight: 3}, {Symbol: "lev", Weight: 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registered.ID != "TECH.60-40" || registered.Name != "TECH.60-40" || registered.BuiltIn {
+		t.Fatalf("registered = %+v, want an upper-case custom benchmark named after its ID", registered)
+	}
+	if c := registered.Components; c[0].Symbol != "AAPL" || c[0].Weight != 0.6 || c[1].Symbol != "LEV" || c[1].Weight != 0.4 {
+		t.Fatalf("components = %+v, want AAPL 0.6 and LEV 0.4", c)
+	}
+	if _, ok := s.Get("tech.60-40"); !ok {
+		t.Fatal("registered benchmark not found")
+	}
+	if resolved := s.Resolve(" msft "); resolved.ID != "MSFT" || len(resolved.Components) != 1 || resolved.Components[0].Symbol != "MSFT" {
+		t.Fatalf("resolved = %+v, want MSFT as its own benchmark", resolved)
+	}
+
+	for i := 1; i < maxBenchmarks; i++ {
+		if _, err := s.Register(Benchmark{ID: fmt.Sprintf("B%d", i), Components: one}); err != nil {
+			t.Fatalf("benchmark %d: %v", i, err)
+		}
+	}
+	if _, err := s.Register(Benchmark{ID: "ONEMORE", Components: one}); err == nil {
+		t.Fatalf("registered more than %d benchmarks", maxBenchmarks)
+	}
+	if _, err := s.Register(Benchmark{ID: "B1", Name: "Replaced", Components: one}); err != nil {
+		t.Fatalf("replacing a custom benchmark at the limit: %v", err)
+	}
+	if len(s.List()) != maxBenchmarks+len(builtInBenchmarks) {
+		t.Fatalf("%d benchmarks listed, want %d", len(s.List()), maxBenchmarks+len(builtInBenchmarks))
+	}
+
+	if err := s.Remove("SPY"); err == nil {
+		t.Fatal("removed a built-in benchmark")
+	}
+	if err := s.Remove("b1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove("B1"); err == nil {
+		t.Fatal("removed a benchmark twice")
+	}
+}
+
+func TestRebalancedIndex(t *testing.T) {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	days := []time.Time{start, start.AddDate(0, 0, 1), start.AddDate(0, 0, 2)}
+
+	tests := []struct {
+		name    string
+		closes  [][]float64
+		weights []float64
+		want    []float64
+	}{
+		// Half in each: +5%, then -10% and +10% cancel
+		{"rebalanced daily", [][]float64{{100, 110, 99}, {50, 50, 55}}, []float64{0.5, 0.5}, []float64{100, 105, 105}},
+		// The other half is cash
+		{"partly in cash", [][]float64{{100, 120, 60}}, []float64{0.5}, []float64{100, 110, 82.5}},
+		// A zero close can't give a return and is skipped
+		{"zero close", [][]float64{{0, 10, 20}}, []float64{1}, []float64{100, 100, 200}},
+	}
+	for _, tt := range tests {
+		index := rebalancedIndex("IDX", "composite", days, tt.closes, tt.weights)
+		if len(index) != len(days) {
+			t.Fatalf("%s: %d bars, want %d", tt.name, len(index), len(days))
+		}
+		for i, bar := range index {
+			if math.Abs(bar.Close.InexactFloat64()-tt.want[i]) > 1e-9 || !bar.High.Equal(bar.Close) || !bar.Date.Equal(days[i]) {
+				t.Fatalf("%s: bar %d = %+v, want close %v", tt.name, i, bar, tt.want[i])
+			}
+		}
+	}
+
+	bar := rebalancedIndex("IDX", "composite", days, [][]float64{{1, 1, 1}}, []float64{1})[1]
+	if bar.ID != "IDX_2024-03-02" || bar.Symbol != "IDX" || bar.Source != "composite" {
+		t.Fatalf("bar = %+v, want IDX_2024-03-02 from composite", bar)
+	}
+}
+
+func TestBenchmarkCompositeHistory(t *testing.T) {
+	s := newTestBenchmarks()
+	to := time.Now()
+	composite := Benchmark{ID: "MIX", Components: []BenchmarkComponent{{Symbol: "AAPL", Weight: 0.5}, {Symbol: "LEV", Weight: 0.5}}}
+
+	index, err := s.HistoricalData(composite, to.AddDate(0, 0, -90), to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each day moves 1.5 times the market
+	want := closesFromReturns(crashReturns(), 1.5)
+	if len(index) != len(want) {
+		t.Fatalf("%d bars, want %d", len(index), len(want))
+	}
+	for i, bar := range index {
+		if math.Abs(bar.Close.InexactFloat64()-want[i]) > 1e-6 {
+			t.Fatalf("bar %d close %v, want %v", i, bar.Close, want[i])
+		}
+	}
+
+	composite.Components = append(composite.Components, BenchmarkComponent{Symbol: "GONE", Weight: 1})
+	if _, err := s.HistoricalData(composite, to.AddDate(0, 0, -90), to); err == nil {
+		t.Fatal("a composite with a missing component should fail")
+	}
+}
+
+func TestPortfolioBetaSkipsUnmeasurablePositions(t *testing.T) {
+	s := newTestBenchmarks()
+	spy := s.Resolve("SPY")
+	to := time.Now()
+	from := to.AddDate(-1, 0, 0)
+
+	betas, unmeasured, err := s.Betas([]string{"AAPL", "LEV", "GONE"}, spy, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(betas) != 2 || math.Abs(betas["AAPL"]-1) > 1e-6 || math.Abs(betas["LEV"]-2) > 1e-6 {
+		t.Fatalf("betas = %v, want AAPL 1 and LEV 2", betas)
+	}
+	if len(unmeasured) != 1 || unmeasured["GONE"] == nil {
+		t.Fatalf("unmeasured = %v, want GONE", unmeasured)
+	}
+
+	positions := testPositions("AAPL", "LEV", "GONE")
+	beta, excluded, err := s.PortfolioBeta(positions, decimal.NewFromInt(2000), spy, from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (1000×1 + 1000×2) / (1000 + 1000 + 2000 cash), GONE left out
+	if math.Abs(beta.InexactFloat64()-0.75) > 1e-6 || len(excluded) != 1 || excluded[0] != "GONE" {
+		t.Fatalf("beta %v excluding %v, want 0.75 excluding GONE", beta, excluded)
+	}
+
+	if _, _, err := s.PortfolioBeta(testPositions("GONE"), decimal.NewFromInt(2000), spy, from, to); err == nil {
+		t.Fatal("a portfolio with no measurable position should fail")
+	}
+	if _, _, err := s.Betas([]string{"AAPL"}, s.Resolve("NOPE"), from, to); err == nil {
+		t.Fatal("a benchmark without history should fail")
+	}
+
+	portfolios := NewPortfolioService(s.marketDataService, testLogger())
+	portfolio := &models.Portfolio{ID: "p1", Cash: decimal.NewFromInt(2000)}
+	if portfolios.measureBeta(portfolio, positions); portfolio.BetaMeasured || !portfolio.Beta.IsZero() {
+		t.Fatalf("beta without a benchmark = %v (measured %v), want unmeasured", portfolio.Beta, portfolio.BetaMeasured)
+	}
+	portfolios.SetBenchmark(s, "SPY")
+	portfolios.measureBeta(portfolio, positions)
+	if !portfolio.BetaMeasured || math.Abs(portfolio.Beta.InexactFloat64()-0.75) > 1e-6 || len(portfolio.BetaExcluded) != 1 {
+		t.Fatalf("beta = %v excluding %v, want 0.75 excluding GONE", portfolio.Beta, portfolio.BetaExcluded)
+	}
+	if portfolios.measureBeta(portfolio, testPositions("GONE")); portfolio.BetaMeasured || !portfolio.Beta.IsZero() || portfolio.BetaExcluded != nil {
+		t.Fatalf("unmeasurable beta = %v excluding %v, want zero and flagged unmeasured", portfolio.Beta, portfolio.BetaExcluded)
+	}
+
+	// Only the portfolio endpoints pay for measuring beta
+	if plain, err := portfolios.GetPortfolio("p1"); err != nil || plain.BetaMeasured {
+		t.Fatalf("GetPortfolio measured beta (%v)", err)
+	}
+	measured, err := portfolios.GetPortfolioWithBeta("p1")
+	if err != nil || !measured.BetaMeasured || strings.Join(measured.BetaExcluded, ",") != "GOOGL,MSFT" {
+		t.Fatalf("portfolio beta %v measured %v excluding %v (%v), want AAPL measured alone", measured.Beta, measured.BetaMeasured, measured.BetaExcluded, err)
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+	"trading-service/internal/risk"
+)
+
+// maxCorrelationSymbols caps the symbols in one correlation request
+const maxCorrelationSymbols = 50
+
+// CorrelationRequest selects the symbols to correlate and how
+type CorrelationRequest struct {
+	Symbols   []string    `json:"symbols"`
+	Lookback  int         `json:"lookback"`   // Days of history, default 252
+	Pairs     [][2]string `json:"pairs"`      // Pairs to report rolling correlation for
+	Window    int         `json:"window"`     // Rolling window in returns, default 60
+	ClusterBy string      `json:"cluster_by"` // pearson (default) or spearman
+	Linkage   string      `json:"linkage"`    // average (default), single or complete
+}
+
+// withDefaults normalizes symbols and fills unset fields
+func (r CorrelationRequest) withDefaults() CorrelationRequest {
+	symbols := make([]string, 0, len(r.Symbols))
+	seen := make(map[string]bool, len(r.Symbols))
+	for _, symbol := range r.Symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol != "" && !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	r.Symbols = symbols
+
+	pairs := make([][2]string, len(r.Pairs))
+	for i, pair := range r.Pairs {
+		pairs[i] = [2]string{strings.ToUpper(strings.TrimSpace(pair[0])), strings.ToUpper(strings.TrimSpace(pair[1]))}
+	}
+	r.Pairs = pairs
+
+	if r.Lookback <= 0 {
+		r.Lookback = 252
+	}
+	if r.Window <= 0 {
+		r.Window = 60
+	}
+	if r.ClusterBy == "" {
+		r.ClusterBy = risk.CorrelationPearson
+	}
+	if r.Linkage == "" {
+		r.Linkage = risk.LinkageAverage
+	}
+	return r
+}
+
+// Validate checks the request after defaults are applied
+func (r CorrelationRequest) Validate() error {
+	r = r.withDefaults()
+	if len(r.Symbols) < 2 || len(r.Symbols) > maxCorrelationSymbols {
+		return fmt.Errorf("between 2 and %d distinct symbols are required", maxCorrelationSymbols)
+	}
+	if r.Lookback > 3650 {
+		return fmt.Errorf("lookback must not exceed 3650 days")
+	}
+	if r.Window < 3 {
+		return fmt.Errorf("window must be at least 3")
+	}
+	symbols := make(map[string]bool, len(r.Symbols))
+	for _, symbol := range r.Symbols {
+		symbols[symbol] = true
+	}
+	for _, pair := range r.Pairs {
+		if !symbols[pair[0]] || !symbols[pair[1]] || pair[0] == pair[1] {
+			return fmt.Errorf("pair %s/%s must name two different requested symbols", pair[0], pair[1])
+		}
+	}
+	switch r.ClusterBy {
+	case risk.CorrelationPearson, risk.CorrelationSpearman:
+	default:
+		return fmt.Errorf("cluster_by must be %s or %s", risk.CorrelationPearson, risk.CorrelationSpearman)
+	}
+	switch r.Linkage {
+	case risk.LinkageSingle, risk.LinkageComplete, risk.LinkageAverage:
+	default:
+		return fmt.Errorf("linkage must be %s, %s or %s", risk.LinkageAverage, risk.LinkageSingle, risk.LinkageComplete)
+	}
+	return nil
+}
+
+// CorrelationPoint is a rolling correlation at the bar ending its window
+type CorrelationPoint struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
+}
+
+// RollingCorrelation is the rolling correlation of a pair of symbols
+type RollingCorrelation struct {
+	Symbols [2]string          `json:"symbols"`
+	Window  int                `json:"window"`
+	Current float64            `json:"current"`
+	Points  []CorrelationPoint `json:"points"`
+}
+
+// CorrelationResult holds correlation matrices of daily returns. Matrix
+// rows and columns follow Symbols; a symbol whose price doesn't move has
+// zero correlation with the others.
+type CorrelationResult struct {
+	Symbols      []string             `json:"symbols"`
+	Start        time.Time            `json:"start"` // First aligned bar
+	End          time.Time            `json:"end"`
+	Observations int                  `json:"observations"` // Returns on days every symbol traded
+	Pearson      [][]float64          `json:"pearson"`
+	Spearman     [][]float64          `json:"spearman"`
+	ClusterOrder []string             `json:"cluster_order"` // Symbols reordered so related names sit together
+	Dendrogram   []risk.ClusterMerge  `json:"dendrogram"`    // Merges over indexes into Symbols
+	Rolling      []RollingCorrelation `json:"rolling,omitempty"`
+}
+
+// CorrelationService correlates the returns of groups of symbols
+type CorrelationService struct {
+	marketDataService *MarketDataService
+	logger            *logrus.Logger
+}
+
+// NewCorrelationService creates a correlation service
+func NewCorrelationService(marketDataService *MarketDataService, logger *logrus.Logger) *CorrelationService {
+	return &CorrelationService{
+		marketDataService: marketDataService,
+		logger:            logger,
+	}
+}
+
+// Correlate aligns the symbols' daily returns on the days all of them traded
+// and correlates them
+func (s *CorrelationService) Correlate(req CorrelationRequest) (*CorrelationResult, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	req = req.withDefaults()
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -req.Lookback)
+	series := make([][]models.HistoricalData, len(req.Symbols))
+	for i, symbol := range req.Symbols {
+		data, err := s.marketDataService.GetHistoricalData(symbol, from, to)
+		if err != nil {
+			return nil, err
+		}
+		series[i] = data
+	}
+
+	days, closes := alignCloses(series...)
+	if len(days) <= req.Window {
+		return nil, fmt.Errorf("symbols share %d days of history, need more than the %d-day window", len(days), req.Window)
+	}
+	returns := make([][]float64, len(closes))
+	index := make(map[string]int, len(req.Symbols))
+	for i := range closes {
+		returns[i] = indicators.Returns(closes[i])
+		index[req.Symbols[i]] = i
+	}
+
+	result := &CorrelationResult{
+		Symbols:      req.Symbols,
+		Start:        days[0],
+		End:          days[len(days)-1],
+		Observations: len(returns[0]),
+	}
+	var err error
+	if result.Pearson, err = risk.CorrelationMatrix(returns, risk.CorrelationPearson); err != nil {
+		return nil, err
+	}
+	if result.Spearman, err = risk.CorrelationMatrix(returns, risk.CorrelationSpearman); err != nil {
+		return nil, err
+	}
+
+	clusterMatrix := result.Pearson
+	if req.ClusterBy == risk.CorrelationSpearman {
+		clusterMatrix = result.Spearman
+	}
+	order, merges, err := risk.ClusterOrder(clusterMatrix, req.Linkage)
+	if err != nil {
+		return nil, err
+	}
+	result.Dendrogram = merges
+	for _, i := range order {
+		result.ClusterOrder = append(result.ClusterOrder, req.Symbols[i])
+	}
+
+	// Return i runs from day i to day i+1, so a window ending at return i
+	// ends on day i+1
+	for _, pair := range req.Pairs {
+		values := risk.RollingCorrelation(returns[index[pair[0]]], returns[index[pair[1]]], req.Window)
+		rolling := RollingCorrelation{Symbols: pair, Window: req.Window, Points: make([]CorrelationPoint, len(values))}
+		for i, value := range values {
+			rolling.Points[i] = CorrelationPoint{Date: days[i+req.Window], Value: value}
+		}
+		rolling.Current = values[len(values)-1]
+		result.Rolling = append(result.Rolling, rolling)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"symbols":      len(req.Symbols),
+		"observations": result.Observations,
+		"pairs":        len(req.Pairs),
+	}).Debug("Correlations calculated")
+
+	return result, nil
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCorrelationRequestValidate(t *testing.T) {
+	tooMany := make([]string, maxCorrelationSymbols+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("S%d", i)
+	}
+
+	tests := []struct {
+		name  string
+		req   CorrelationRequest
+		valid bool
+	}{
+		{"defaults", CorrelationRequest{Symbols: []string{"aapl", "MSFT"}}, true},
+		{"one distinct symbol", CorrelationRequest{Symbols: []string{"AAPL", " aapl"}}, false},
+		{"too many symbols", CorrelationRequest{Symbols: tooMany}, false},
+		{"long lookback", CorrelationRequest{Symbols: []string{"A", "B"}, Lookback: 3651}, false},
+		{"short window", CorrelationRequest{Symbols: []string{"A", "B"}, Window: 2}, false},
+		{"pair", CorrelationRequest{Symbols: []string{"A", "B"}, Pairs: [][2]string{{"a", "b"}}}, true},
+		{"pair not requested", CorrelationRequest{Symbols: []string{"A", "B"}, Pairs: [][2]string{{"A", "C"}}}, false},
+		{"pair with itself", CorrelationRequest{Symbols: []string{"A", "B"}, Pairs: [][2]string{{"A", "A"}}}, false},
+		{"spearman", CorrelationRequest{Symbols: []string{"A", "B"}, ClusterBy: "spearman", Linkage: "complete"}, true},
+		{"unknown method", CorrelationRequest{Symbols: []string{"A", "B"}, ClusterBy: "kendall"}, false},
+		{"unknown linkage", CorrelationRequest{Symbols: []string{"A", "B"}, Linkage: "ward"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); (err == nil) != tt.valid {
+			t.Fatalf("%s: error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
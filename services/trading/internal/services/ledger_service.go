@@ -0,0 +1,349 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/ledger"
+	"trading-service/internal/models"
+)
+
+// LedgerStatement is a portfolio's ledger replayed under a cost basis
+// method and marked to current prices. Positions hold open lots only;
+// realized P&L covers closed shares and excludes dividends.
+type LedgerStatement struct {
+	PortfolioID  string               `json:"portfolio_id"`
+	Method       string               `json:"method"`
+	Cash         decimal.Decimal      `json:"cash"`
+	MarketValue  decimal.Decimal      `json:"market_value"`
+	TotalValue   decimal.Decimal      `json:"total_value"`
+	Deposits     decimal.Decimal      `json:"deposits"`
+	Withdrawals  decimal.Decimal      `json:"withdrawals"`
+	Dividends    decimal.Decimal      `json:"dividends"`
+	Fees         decimal.Decimal      `json:"fees"`
+	RealizedPL   decimal.Decimal      `json:"realized_pl"`
+	UnrealizedPL decimal.Decimal      `json:"unrealized_pl"`
+	TotalReturn  decimal.Decimal      `json:"total_return"` // Total value less net deposits
+	Positions    []*models.Position   `json:"positions"`
+	Lots         []ledger.Lot         `json:"lots"`
+	Realized     []ledger.Realization `json:"realized"`
+	Transactions int                  `json:"transactions"`
+	AsOf         time.Time            `json:"as_of"`
+}
+
+// ErrLedgerWrite is returned by Record when an entry couldn't be written to
+// the ledger file; the entry is not recorded
+var ErrLedgerWrite = errors.New("failed to write the ledger file")
+
+// LedgerService keeps each portfolio's append-only transaction ledger.
+// Entries can't be changed or removed; corrections are new entries. With a
+// path set, entries are appended to a JSON Lines file and replayed on
+// startup.
+type LedgerService struct {
+	marketDataService *MarketDataService
+	logger            *logrus.Logger
+
+	mu           sync.RWMutex
+	transactions map[string][]models.Transaction // By portfolio, in the order they took effect
+	seq          uint64
+
+	file *os.File
+}
+
+// NewLedgerService creates an in-memory ledger
+func NewLedgerService(marketDataService *MarketDataService, logger *logrus.Logger) *LedgerService {
+	return &LedgerService{
+		marketDataService: marketDataService,
+		logger:            logger,
+		transactions:      make(map[string][]models.Transaction),
+	}
+}
+
+// Open loads the ledger file at path, creating it if missing, and appends
+// every later entry to it
+func (s *LedgerService) Open(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create ledger directory: %v", err)
+		}
+	}
+	if err := s.load(path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open ledger: %v", err)
+	}
+	s.file = file
+	return nil
+}
+
+// load replays the ledger file into memory. Callers hold s.mu.
+func (s *LedgerService) load(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read ledger: %v", err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		lines++
+		var tx models.Transaction
+		if err := json.Unmarshal(scanner.Bytes(), &tx); err != nil || tx.ID == "" || tx.PortfolioID == "" {
+			s.logger.WithField("line", lines).Warn("Skipping invalid ledger line")
+			continue
+		}
+		s.insert(tx)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read ledger: %v", err)
+	}
+	return nil
+}
+
+// Close closes the ledger file
+func (s *LedgerService) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// insert places a transaction after every one that took effect no later.
+// Callers hold s.mu.
+func (s *LedgerService) insert(tx models.Transaction) {
+	entries := s.transactions[tx.PortfolioID]
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Date.After(tx.Date) })
+	entries = append(entries, models.Transaction{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = tx
+	s.transactions[tx.PortfolioID] = entries
+}
+
+// Record appends a transaction to a portfolio's ledger, assigning its ID
+// and defaulting its date to now. It is rejected if it doesn't fit the
+// ledger, such as a sell of shares not held at its date or of lots already
+// closed.
+func (s *LedgerService) Record(portfolioID string, tx models.Transaction) (*models.Transaction, error) {
+	if portfolioID == "" {
+		return nil, fmt.Errorf("portfolio ID cannot be empty")
+	}
+	tx.PortfolioID = portfolioID
+	tx.Type = strings.ToUpper(strings.TrimSpace(tx.Type))
+	tx.Symbol = strings.ToUpper(strings.TrimSpace(tx.Symbol))
+	if err := ledger.Validate(tx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.seq++
+	tx.ID = fmt.Sprintf("txn_%d_%d", now.UnixNano(), s.seq)
+	tx.CreatedAt = now
+	if tx.Date.IsZero() {
+		tx.Date = now
+	}
+	if tx.Date.After(now.Add(time.Minute)) {
+		return nil, fmt.Errorf("transaction date is in the future")
+	}
+
+	// Check the ledger still replays with the entry in place; specific-lot
+	// replay also checks the lots a sell names. insert shifts entries in
+	// place, so keep a copy to restore.
+	existing := append([]models.Transaction(nil), s.transactions[portfolioID]...)
+	s.insert(tx)
+	if _, err := ledger.Replay(s.transactions[portfolioID], ledger.MethodSpecific); err != nil {
+		s.transactions[portfolioID] = existing
+		return nil, err
+	}
+	if err := s.persist(tx); err != nil {
+		s.transactions[portfolioID] = existing
+		s.logger.WithError(err).WithField("transaction_id", tx.ID).Error("Failed to persist transaction")
+		return nil, fmt.Errorf("%w: %v", ErrLedgerWrite, err)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"portfolio_id":   portfolioID,
+		"transaction_id": tx.ID,
+		"type":           tx.Type,
+		"symbol":         tx.Symbol,
+	}).Info("Transaction recorded")
+
+	return &tx, nil
+}
+
+// persist appends a transaction to the file and syncs it to disk. On
+// failure the file is truncated back so no partial line is left for the
+// next entry to run into. Callers hold s.mu.
+func (s *LedgerService) persist(tx models.Transaction) error {
+	if s.file == nil {
+		return nil
+	}
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return err
+	}
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err = s.file.Write(append(data, '\n')); err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		if truncErr := s.file.Truncate(info.Size()); truncErr != nil {
+			s.logger.WithError(truncErr).Error("Failed to truncate ledger after a failed write")
+		}
+		return err
+	}
+	return nil
+}
+
+// Transactions returns a portfolio's ledger in the order entries took
+// effect
+func (s *LedgerService) Transactions(portfolioID string) []models.Transaction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return append([]models.Transaction(nil), s.transactions[portfolioID]...)
+}
+
+// HasLedger reports whether the portfolio has any transactions
+func (s *LedgerService) HasLedger(portfolioID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.transactions[portfolioID]) > 0
+}
+
+// Statement replays a portfolio's ledger under the cost basis method and
+// marks open positions to current prices, falling back to the last trade
+// price for symbols without a quote
+func (s *LedgerService) Statement(portfolioID, method string) (*LedgerStatement, error) {
+	transactions := s.Transactions(portfolioID)
+	book, err := ledger.Replay(transactions, method)
+	if err != nil {
+		return nil, err
+	}
+
+	open := book.Open()
+	prices := make(map[string]decimal.Decimal, len(open))
+	if len(open) > 0 {
+		symbols := make([]string, len(open))
+		for i, holding := range open {
+			symbols[i] = holding.Symbol
+		}
+		quotes, err := s.marketDataService.GetMultipleSymbolsData(symbols)
+		if err != nil {
+			s.logger.WithError(err).WithField("portfolio_id", portfolioID).Warn("No quotes for ledger positions; using last trade prices")
+		}
+		for symbol, quote := range quotes {
+			prices[symbol] = quote.Price
+		}
+	}
+	return s.statement(portfolioID, book, len(transactions), prices), nil
+}
+
+// StatementAt is Statement marked at the given prices rather than current
+// quotes. Symbols missing from prices, or all of them when it is nil, are
+// marked at their last trade price.
+func (s *LedgerService) StatementAt(portfolioID, method string, prices map[string]decimal.Decimal) (*LedgerStatement, error) {
+	transactions := s.Transactions(portfolioID)
+	book, err := ledger.Replay(transactions, method)
+	if err != nil {
+		return nil, err
+	}
+	return s.statement(portfolioID, book, len(transactions), prices), nil
+}
+
+// statement marks a replayed book to prices
+func (s *LedgerService) statement(portfolioID string, book *ledger.Book, transactions int, prices map[string]decimal.Decimal) *LedgerStatement {
+	open := book.Open()
+	statement := &LedgerStatement{
+		PortfolioID:  portfolioID,
+		Method:       book.Method,
+		Cash:         book.Cash,
+		Deposits:     book.Deposits,
+		Withdrawals:  book.Withdrawals,
+		Dividends:    book.Dividends,
+		Fees:         book.Fees,
+		RealizedPL:   book.RealizedPL,
+		Positions:    make([]*models.Position, 0, len(open)),
+		Lots:         []ledger.Lot{},
+		Realized:     book.Realized,
+		Transactions: transactions,
+		AsOf:         time.Now(),
+	}
+	for _, holding := range open {
+		price, ok := prices[holding.Symbol]
+		if !ok {
+			price = holding.LastPrice
+		}
+		position := holdingPosition(portfolioID, holding, price)
+		statement.Positions = append(statement.Positions, position)
+		statement.Lots = append(statement.Lots, holding.Lots...)
+		statement.MarketValue = statement.MarketValue.Add(position.MarketValue)
+		statement.UnrealizedPL = statement.UnrealizedPL.Add(position.UnrealizedPL)
+	}
+	statement.TotalValue = statement.Cash.Add(statement.MarketValue)
+	statement.TotalReturn = statement.TotalValue.Sub(statement.Deposits.Sub(statement.Withdrawals))
+
+	hundred := decimal.NewFromInt(100)
+	for _, position := range statement.Positions {
+		if statement.TotalValue.IsPositive() {
+			position.Weight = position.MarketValue.Div(statement.TotalValue).Mul(hundred)
+		}
+	}
+	return statement
+}
+
+// holdingPosition marks a ledger holding to price. TotalReturn is the
+// unrealized gain as a percentage of cost.
+func holdingPosition(portfolioID string, holding *ledger.Holding, price decimal.Decimal) *models.Position {
+	position := &models.Position{
+		ID:           fmt.Sprintf("%s_%s", portfolioID, holding.Symbol),
+		PortfolioID:  portfolioID,
+		Symbol:       holding.Symbol,
+		Quantity:     holding.Quantity,
+		AveragePrice: holding.CostBasis.Div(holding.Quantity),
+		CurrentPrice: price,
+		MarketValue:  holding.Quantity.Mul(price),
+		RealizedPL:   holding.RealizedPL,
+		UpdatedAt:    time.Now(),
+	}
+	if len(holding.Lots) > 0 {
+		position.CreatedAt = holding.Lots[0].Acquired
+	}
+	position.UnrealizedPL = position.MarketValue.Sub(holding.CostBasis)
+	if holding.CostBasis.IsPositive() {
+		position.TotalReturn = position.UnrealizedPL.Div(holding.CostBasis).Mul(decimal.NewFromInt(100))
+	}
+	return position
+}
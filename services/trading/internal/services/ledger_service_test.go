@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/models"
+)
+
+func TestLedgerServicePersistsAndReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger", "transactions.jsonl")
+	service := NewLedgerService(nil, testLogger())
+	if err := service.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Now().AddDate(0, 0, -10)
+	entries := []models.Transaction{
+		{Type: models.TransactionDeposit, Amount: decimal.NewFromInt(1000), Date: day},
+		{Type: "buy", Symbol: " aapl ", Quantity: decimal.NewFromInt(5), Price: decimal.NewFromInt(100), Date: day.AddDate(0, 0, 1)},
+	}
+	for _, tx := range entries {
+		if _, err := service.Record("p1", tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := service.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewLedgerService(nil, testLogger())
+	if err := reloaded.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer reloaded.Close()
+	transactions := reloaded.Transactions("p1")
+	if len(transactions) != 2 || transactions[1].Type != models.TransactionBuy || transactions[1].Symbol != "AAPL" {
+		t.Fatalf("reloaded %+v, want the deposit and the normalized buy", transactions)
+	}
+	statement, err := reloaded.StatementAt("p1", "fifo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statement.Cash.Equal(decimal.NewFromInt(500)) || !statement.TotalValue.Equal(decimal.NewFromInt(1000)) {
+		t.Fatalf("cash %s total %s, want 500 and 1000", statement.Cash, statement.TotalValue)
+	}
+}
+
+func TestLedgerServiceRollsBackFailedWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.jsonl")
+	service := NewLedgerService(nil, testLogger())
+	if err := service.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Record("p1", models.Transaction{Type: models.TransactionDeposit, Amount: decimal.NewFromInt(100)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writes fail once the file is closed underneath the service
+	service.file.Close()
+	_, err := service.Record("p1", models.Transaction{Type: models.TransactionDeposit, Amount: decimal.NewFromInt(50)})
+	if !errors.Is(err, ErrLedgerWrite) {
+		t.Fatalf("error = %v, want ErrLedgerWrite", err)
+	}
+	if n := len(service.Transactions("p1")); n != 1 {
+		t.Fatalf("%d transactions after a failed write, want 1", n)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 1 {
+		t.Fatalf("ledger file has %d lines, want 1", lines)
+	}
+}
+
+func TestLedgerServiceRejectionKeepsOrder(t *testing.T) {
+	service := NewLedgerService(nil, testLogger())
+	day := time.Now().AddDate(0, 0, -10)
+	for i := 0; i < 3; i++ {
+		if _, err := service.Record("p1", models.Transaction{Type: models.TransactionDeposit, Amount: decimal.NewFromInt(int64(i + 1)), Date: day.AddDate(0, 0, i*2)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := service.Transactions("p1")
+
+	// A sell of shares never held, dated between existing entries, is
+	// rejected and mustn't disturb them
+	_, err := service.Record("p1", models.Transaction{Type: models.TransactionSell, Symbol: "AAPL", Quantity: decimal.NewFromInt(1), Price: decimal.NewFromInt(1), Date: day.AddDate(0, 0, 1)})
+	if err == nil {
+		t.Fatal("oversell accepted")
+	}
+	after := service.Transactions("p1")
+	if len(after) != len(before) {
+		t.Fatalf("%d transactions after a rejection, want %d", len(after), len(before))
+	}
+	for i := range before {
+		if after[i].ID != before[i].ID {
+			t.Fatalf("transaction %d is %s after a rejection, want %s", i, after[i].ID, before[i].ID)
+		}
+	}
+}
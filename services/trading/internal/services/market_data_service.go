@@ -2,23 +2,87 @@ package services
 
 import (
 	"fmt"
+	"sync"
 	"time"
 	"github.com/sirupsen/logrus"
 	"trading-service/internal/models"
 	"trading-service/internal/providers"
 )
 
+// maxHistoryCacheEntries bounds the history cache; expired entries are
+// evicted first, then the oldest
+const maxHistoryCacheEntries = 1000
+
 // MarketDataService handles market data operations
 type MarketDataService struct {
 	aggregator *providers.MarketDataAggregator
 	logger     *logrus.Logger
+
+	cacheMu      sync.Mutex
+	cacheTTL     time.Duration
+	historyCache map[string]historyCacheEntry
+}
+
+type historyCacheEntry struct {
+	data      []models.HistoricalData
+	fetchedAt time.Time
 }
 
 // NewMarketDataService creates a new market data service
 func NewMarketDataService(aggregator *providers.MarketDataAggregator, logger *logrus.Logger) *MarketDataService {
 	return &MarketDataService{
-		aggregator: aggregator,
-		logger:     logger,
+		aggregator:   aggregator,
+		logger:       logger,
+		historyCache: make(map[string]historyCacheEntry),
+	}
+}
+
+// SetHistoryCacheTTL caches historical data per symbol and day range for
+// the given duration; zero disables the cache
+func (s *MarketDataService) SetHistoryCacheTTL(ttl time.Duration) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	s.cacheTTL = ttl
+	s.historyCache = make(map[string]historyCacheEntry)
+}
+
+// cachedHistory returns a copy of cached data for the key, if fresh
+func (s *MarketDataService) cachedHistory(key string) ([]models.HistoricalData, bool) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	entry, ok := s.historyCache[key]
+	if !ok || s.cacheTTL <= 0 || time.Since(entry.fetchedAt) > s.cacheTTL {
+		return nil, false
+	}
+	return append([]models.HistoricalData(nil), entry.data...), true
+}
+
+// storeHistory caches a copy of data under the key
+func (s *MarketDataService) storeHistory(key string, data []models.HistoricalData) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	if s.cacheTTL <= 0 {
+		return
+	}
+	if len(s.historyCache) >= maxHistoryCacheEntries {
+		oldestKey, oldest := "", time.Now()
+		for k, entry := range s.historyCache {
+			if time.Since(entry.fetchedAt) > s.cacheTTL {
+				delete(s.historyCache, k)
+			} else if entry.fetchedAt.Before(oldest) {
+				oldestKey, oldest = k, entry.fetchedAt
+			}
+		}
+		if len(s.historyCache) >= maxHistoryCacheEntries {
+			delete(s.historyCache, oldestKey)
+		}
+	}
+	s.historyCache[key] = historyCacheEntry{
+		data:      append([]models.HistoricalData(nil), data...),
+		fetchedAt: time.Now(),
 	}
 }
 
@@ -44,8 +108,14 @@ func (s *MarketDataService) GetRealTimeData(symbol string) (*models.MarketData,
 	return data, nil
 }
 
-// GetHistoricalData retrieves historical market data for a symbol
+// GetHistoricalData retrieves historical market data for a symbol. Results
+// are cached per symbol and day range when a cache TTL is set.
 func (s *MarketDataService) GetHistoricalData(symbol string, from, to time.Time) ([]models.HistoricalData, error) {
+	cacheKey := fmt.Sprintf("%s|%s|%s", symbol, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if data, ok := s.cachedHistory(cacheKey); ok {
+		return data, nil
+	}
+
 	s.logger.WithFields(logrus.Fields{
 		"symbol": symbol,
 		"from":   from.Format("2006-01-02"),
@@ -72,6 +142,7 @@ func (s *MarketDataService) GetHistoricalData(symbol string, from, to time.Time)
 		"data_points": len(data),
 	}).Debug("Historical data retrieved")
 
+	s.storeHistory(cacheKey, data)
 	return data, nil
 }
 
@@ -167,7 +238,7 @@ func (s *MarketDataService) ValidateSymbol(symbol string) (bool, error) {
 }
 
 // GetMarketStatus returns current market status (open/closed)
-func (s *MarketDataService) GetMarketStatus() *models.MarketStatus {
+func (s *MarketDataService) GetMarketStatus() *MarketStatus {
 	now := time.Now()
 	
 	// Simple market hours check (NYSE/NASDAQ: 9:30 AM - 4:00 PM ET on weekdays)
@@ -206,7 +277,7 @@ func (s *MarketDataService) GetMarketStatus() *models.MarketStatus {
 		}
 	}
 	
-	status := &models.MarketStatus{
+	status := &MarketStatus{
 		IsOpen:       isOpen,
 		MarketHours:  "9:30 AM - 4:00 PM ET",
 		TimeZone:     "America/New_York",
@@ -225,11 +296,11 @@ func (s *MarketDataService) GetMarketStatus() *models.MarketStatus {
 }
 
 // GetProviderStatus returns the status of all configured providers
-func (s *MarketDataService) GetProviderStatus() map[string]models.ProviderStatus {
-	status := make(map[string]models.ProviderStatus)
+func (s *MarketDataService) GetProviderStatus() map[string]ProviderStatus {
+	status := make(map[string]ProviderStatus)
 	
 	for _, provider := range s.aggregator.Providers {
-		providerStatus := models.ProviderStatus{
+		providerStatus := ProviderStatus{
 			Name:      provider.GetProviderName(),
 			IsReady:   provider.IsReady(),
 			LastCheck: time.Now(),
@@ -252,10 +323,10 @@ func (s *MarketDataService) GetProviderStatus() map[string]models.ProviderStatus
 }
 
 // GetSupportedSymbols returns a list of supported symbols
-func (s *MarketDataService) GetSupportedSymbols() []models.SymbolInfo {
+func (s *MarketDataService) GetSupportedSymbols() []SymbolInfo {
 	// For demo purposes, return a curated list of popular symbols
 	// In production, this might come from a database or external service
-	symbols := []models.SymbolInfo{
+	symbols := []SymbolInfo{
 		{Symbol: "AAPL", Name: "Apple Inc.", Exchange: "NASDAQ", Sector: "Technology", MarketCap: "3T+"},
 		{Symbol: "GOOGL", Name: "Alphabet Inc.", Exchange: "NASDAQ", Sector: "Technology", MarketCap: "1T+"},
 		{Symbol: "MSFT", Name: "Microsoft Corporation", Exchange: "NASDAQ", Sector: "Technology", MarketCap: "2T+"},
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/algorithms"
+	"trading-service/internal/backtest"
+	"trading-service/internal/models"
+)
+
+// Optimization job states
+const (
+	JobPending   = "PENDING"
+	JobRunning   = "RUNNING"
+	JobCompleted = "COMPLETED"
+	JobFailed    = "FAILED"
+	JobCancelled = "CANCELLED"
+)
+
+// maxOptimizationJobs caps the in-memory job store; the oldest finished jobs
+// are dropped first
+const maxOptimizationJobs = 100
+
+// maxActiveOptimizationJobs caps jobs pending or running at once, counting
+// cancelled jobs until their backtests stop. Each one already uses every
+// CPU, and active jobs are never pruned.
+const maxActiveOptimizationJobs = 4
+
+// ErrTooManyJobs is returned by Submit while maxActiveOptimizationJobs
+// searches are pending or running
+var ErrTooManyJobs = errors.New("too many optimization jobs running; wait for one to finish or cancel one")
+
+// OptimizationService runs parameter searches as background jobs
+type OptimizationService struct {
+	algorithmManager *algorithms.AlgorithmManager
+	logger           *logrus.Logger
+
+	mu   sync.RWMutex
+	jobs map[string]*OptimizationJob
+}
+
+// OptimizationJob tracks one asynchronous optimization
+type OptimizationJob struct {
+	ID          string                       `json:"id"`
+	Status      string                       `json:"status"`
+	Symbol      string                       `json:"symbol"`
+	Algorithm   string                       `json:"algorithm"`
+	Completed   int                          `json:"completed"` // Backtests finished
+	Total       int                          `json:"total"`
+	Progress    float64                      `json:"progress"` // 0-1
+	Result      *backtest.OptimizationResult `json:"result,omitempty"`
+	Error       string                       `json:"error,omitempty"`
+	CreatedAt   time.Time                    `json:"created_at"`
+	StartedAt   *time.Time                   `json:"started_at,omitempty"`
+	CompletedAt *time.Time                   `json:"completed_at,omitempty"`
+
+	cancel context.CancelFunc
+	// done is set once run returns. A cancelled job keeps running backtests
+	// until it next checks its context, so it stays active until then.
+	done bool
+}
+
+// NewOptimizationService creates a new optimization service
+func NewOptimizationService(algorithmManager *algorithms.AlgorithmManager, logger *logrus.Logger) *OptimizationService {
+	return &OptimizationService{
+		algorithmManager: algorithmManager,
+		logger:           logger,
+		jobs:             make(map[string]*OptimizationJob),
+	}
+}
+
+// Submit validates the request and starts the search in the background
+func (s *OptimizationService) Submit(symbol string, data []models.HistoricalData, algorithmName string, cfg backtest.OptimizeConfig) (*OptimizationJob, error) {
+	algorithm, err := s.algorithmManager.GetAlgorithm(algorithmName)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(algorithm); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &OptimizationJob{
+		ID:        fmt.Sprintf("opt_%d", time.Now().UnixNano()),
+		Status:    JobPending,
+		Symbol:    symbol,
+		Algorithm: algorithm.Name(),
+		CreatedAt: time.Now(),
+		cancel:    cancel,
+	}
+
+	s.mu.Lock()
+	if s.activeJobs() >= maxActiveOptimizationJobs {
+		s.mu.Unlock()
+		cancel()
+		return nil, ErrTooManyJobs
+	}
+	s.pruneJobs()
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	go s.run(ctx, job, algorithm.Clone(), data, cfg)
+
+	s.logger.WithFields(logrus.Fields{
+		"job_id":    job.ID,
+		"symbol":    symbol,
+		"algorithm": job.Algorithm,
+		"method":    cfg.Method,
+	}).Info("Optimization job submitted")
+
+	return s.snapshot(job), nil
+}
+
+func (s *OptimizationService) run(ctx context.Context, job *OptimizationJob, algorithm algorithms.TradingAlgorithm, data []models.HistoricalData, cfg backtest.OptimizeConfig) {
+	s.mu.Lock()
+	if job.Status == JobCancelled {
+		job.done = true
+		s.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	job.Status = JobRunning
+	job.StartedAt = &now
+	s.mu.Unlock()
+
+	result, err := backtest.Optimize(ctx, algorithm, data, cfg, func(done, total int) {
+		s.mu.Lock()
+		job.Completed, job.Total = done, total
+		job.Progress = float64(done) / float64(total)
+		s.mu.Unlock()
+	})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.done = true
+	finished := time.Now()
+	job.CompletedAt = &finished
+	switch {
+	case job.Status == JobCancelled:
+		// Cancelled while running; keep the status set by CancelJob
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+		s.logger.WithError(err).WithField("job_id", job.ID).Warn("Optimization job failed")
+	default:
+		job.Status = JobCompleted
+		job.Result = result
+		job.Progress = 1
+		s.logger.WithFields(logrus.Fields{
+			"job_id":    job.ID,
+			"evaluated": result.Evaluated,
+			"duration":  result.Duration,
+		}).Info("Optimization job completed")
+	}
+}
+
+// GetJob returns a copy of a job
+func (s *OptimizationService) GetJob(id string) (*OptimizationJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, exists := s.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("optimization job %s not found", id)
+	}
+	return s.snapshot(job), nil
+}
+
+// ListJobs returns all jobs, newest first, without their results
+func (s *OptimizationService) ListJobs() []*OptimizationJob {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]*OptimizationJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		summary := s.snapshot(job)
+		summary.Result = nil
+		jobs = append(jobs, summary)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// CancelJob stops a pending or running job
+func (s *OptimizationService) CancelJob(id string) (*OptimizationJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, exists := s.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("optimization job %s not found", id)
+	}
+	if job.Status != JobPending && job.Status != JobRunning {
+		return nil, fmt.Errorf("optimization job %s already %s", id, job.Status)
+	}
+
+	job.cancel()
+	now := time.Now()
+	job.Status = JobCancelled
+	job.CompletedAt = &now
+	return s.snapshot(job), nil
+}
+
+// snapshot copies a job so callers can read it without holding the lock
+func (s *OptimizationService) snapshot(job *OptimizationJob) *OptimizationJob {
+	copied := *job
+	copied.cancel = nil
+	return &copied
+}
+
+// activeJobs counts jobs whose run hasn't returned, including cancelled
+// ones still winding down. Callers must hold the lock.
+func (s *OptimizationService) activeJobs() int {
+	active := 0
+	for _, job := range s.jobs {
+		if !job.done {
+			active++
+		}
+	}
+	return active
+}
+
+// pruneJobs drops the oldest finished jobs once the store is full. Callers
+// must hold the write lock.
+func (s *OptimizationService) pruneJobs() {
+	if len(s.jobs) < maxOptimizationJobs {
+		return
+	}
+
+	var finished []*OptimizationJob
+	for _, job := range s.jobs {
+		if job.done {
+			finished = append(finished, job)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].CreatedAt.Before(finished[j].CreatedAt) })
+
+	for _, job := range finished {
+		if len(s.jobs) < maxOptimizationJobs {
+			break
+		}
+		delete(s.jobs, job.ID)
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/algorithms"
+	"trading-service/internal/backtest"
+)
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func TestOptimizationPruneJobs(t *testing.T) {
+	s := NewOptimizationService(algorithms.NewAlgorithmManager(), testLogger())
+	start := time.Now()
+	for i := 0; i < maxOptimizationJobs; i++ {
+		status, done := JobCompleted, true
+		if i%10 == 0 {
+			status, done = JobRunning, false
+		}
+		id := fmt.Sprintf("job_%03d", i)
+		s.jobs[id] = &OptimizationJob{ID: id, Status: status, CreatedAt: start.Add(time.Duration(i) * time.Second), done: done}
+	}
+
+	s.pruneJobs()
+	if len(s.jobs) != maxOptimizationJobs-1 {
+		t.Fatalf("%d jobs after pruning, want %d", len(s.jobs), maxOptimizationJobs-1)
+	}
+	if _, ok := s.jobs["job_000"]; !ok {
+		t.Fatal("oldest job was pruned while running")
+	}
+	if _, ok := s.jobs["job_001"]; ok {
+		t.Fatal("oldest finished job was kept")
+	}
+	for i := 0; i < maxOptimizationJobs; i += 10 {
+		if _, ok := s.jobs[fmt.Sprintf("job_%03d", i)]; !ok {
+			t.Fatalf("running job %d was pruned", i)
+		}
+	}
+}
+
+func TestOptimizationActiveJobCap(t *testing.T) {
+	s := NewOptimizationService(algorithms.NewAlgorithmManager(), testLogger())
+	for i := 0; i < maxActiveOptimizationJobs; i++ {
+		id := fmt.Sprintf("job_%d", i)
+		s.jobs[id] = &OptimizationJob{ID: id, Status: JobRunning, CreatedAt: time.Now()}
+	}
+	cfg := backtest.OptimizeConfig{Parameters: map[string]backtest.ParameterRange{"ema_fast": {Min: 5, Max: 10}}}
+
+	if _, err := s.Submit("TEST", nil, "trend_following", cfg); !errors.Is(err, ErrTooManyJobs) {
+		t.Fatalf("error = %v, want ErrTooManyJobs", err)
+	}
+	if len(s.jobs) != maxActiveOptimizationJobs {
+		t.Fatalf("rejected job was stored")
+	}
+
+	// Invalid requests are still reported as such
+	if _, err := s.Submit("TEST", nil, "no_such_algorithm", cfg); err == nil || errors.Is(err, ErrTooManyJobs) {
+		t.Fatalf("error = %v, want unknown algorithm", err)
+	}
+
+	s.jobs["job_0"].Status, s.jobs["job_0"].done = JobCancelled, true
+	if s.activeJobs() != maxActiveOptimizationJobs-1 {
+		t.Fatalf("active jobs = %d", s.activeJobs())
+	}
+}
+
+func TestOptimizationCancelledJobStaysActiveUntilStopped(t *testing.T) {
+	s := NewOptimizationService(algorithms.NewAlgorithmManager(), testLogger())
+	for i := 0; i < maxActiveOptimizationJobs; i++ {
+		id := fmt.Sprintf("job_%d", i)
+		_, cancel := context.WithCancel(context.Background())
+		s.jobs[id] = &OptimizationJob{ID: id, Status: JobRunning, CreatedAt: time.Now(), cancel: cancel}
+	}
+	cfg := backtest.OptimizeConfig{Parameters: map[string]backtest.ParameterRange{"ema_fast": {Min: 5, Max: 10}}}
+
+	if _, err := s.CancelJob("job_0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Submit("TEST", nil, "trend_following", cfg); !errors.Is(err, ErrTooManyJobs) {
+		t.Fatalf("error = %v, want ErrTooManyJobs while the cancelled job's backtests run", err)
+	}
+	s.mu.Lock()
+	s.pruneJobs()
+	s.mu.Unlock()
+	if _, ok := s.jobs["job_0"]; !ok {
+		t.Fatal("a cancelled job still running was pruned")
+	}
+
+	// Once its run returns the slot frees up
+	s.jobs["job_0"].done = true
+	job, err := s.Submit("TEST", nil, "trend_following", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.mu.RLock()
+		done := s.jobs[job.ID].done
+		s.mu.RUnlock()
+		if done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("submitted job never finished")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got, _ := s.GetJob(job.ID); got.Status != JobFailed {
+		t.Fatalf("job without data = %s, want %s", got.Status, JobFailed)
+	}
+}
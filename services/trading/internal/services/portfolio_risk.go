@@ -0,0 +1,149 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+	"trading-service/internal/risk"
+)
+
+// CalculatePortfolioRisk measures a portfolio holding its positions at
+// today's weights over the days all of them traded. Histories maps each
+// position's symbol to its daily bars; cash is treated as riskless. With a
+// volatility model, VaR, risk contributions and the forecast volatility use
+// each position's returns rescaled to its forecast volatility, while
+// Volatility and the risk-adjusted returns stay historical.
+func (s *AnalysisService) CalculatePortfolioRisk(portfolio *models.Portfolio, positions []*models.Position, histories map[string][]models.HistoricalData, cfg RiskConfig) (*models.RiskMetrics, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	weights, series, err := portfolioWeights(portfolio, positions, histories)
+	if err != nil {
+		return nil, err
+	}
+
+	days, closes := alignCloses(series...)
+	if len(days) < 30 {
+		return nil, fmt.Errorf("insufficient data for risk calculation: positions share %d days, need at least 30", len(days))
+	}
+	returns := make([][]float64, len(closes))
+	symbols := make([]string, len(positions))
+	for i := range closes {
+		returns[i] = indicators.Returns(closes[i])
+		symbols[i] = positions[i].Symbol
+	}
+	varReturns, forecasts, err := forecastReturns(symbols, returns, cfg.VolatilityModel, cfg.VaR.Horizon)
+	if err != nil {
+		return nil, err
+	}
+
+	riskMetrics := &models.RiskMetrics{
+		PortfolioID: portfolio.ID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	// VaR95 and VaR99 at the configured method, from the same sample as
+	// everything else at the configured confidence
+	result, err := risk.PortfolioRisk(weights, varReturns, risk.PortfolioRiskConfig{
+		VaR:            cfg.VaR,
+		Confidences:    []float64{0.95, 0.99},
+		Shrinkage:      cfg.Shrinkage,
+		PeriodsPerYear: tradingDaysPerYear,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("portfolio risk calculation failed: %v", err)
+	}
+	riskMetrics.VaR95 = decimal.NewFromFloat(result.Levels[0].VaR)
+	riskMetrics.VaR99 = decimal.NewFromFloat(result.Levels[1].VaR)
+
+	riskMetrics.Volatility = decimal.NewFromFloat(result.Volatility)
+	portfolioReturns := result.Returns
+	if forecasts != nil {
+		volatility, realized, err := risk.PortfolioVolatility(weights, returns, cfg.Shrinkage, tradingDaysPerYear)
+		if err != nil {
+			return nil, fmt.Errorf("portfolio risk calculation failed: %v", err)
+		}
+		riskMetrics.VolatilityModel = forecasts[0].Model
+		riskMetrics.ForecastVolatility = riskMetrics.Volatility
+		riskMetrics.Volatility = decimal.NewFromFloat(volatility)
+		portfolioReturns = realized
+	}
+	riskMetrics.ConditionalVaR = decimal.NewFromFloat(result.VaR.CVaR)
+	riskMetrics.VaRMethod = result.VaR.Method
+	riskMetrics.VaRHorizon = result.VaR.Horizon
+	riskMetrics.VaRConfidence = result.VaR.Confidence
+	riskMetrics.DiversificationRatio = decimal.NewFromFloat(result.DiversificationRatio)
+	riskMetrics.CovarianceShrinkage = result.Shrinkage
+
+	riskMetrics.RiskContributions = make([]models.RiskContribution, len(positions))
+	for i, contribution := range result.Contributions {
+		riskMetrics.RiskContributions[i] = models.RiskContribution{
+			Symbol:        positions[i].Symbol,
+			Weight:        decimal.NewFromFloat(contribution.Weight),
+			Volatility:    decimal.NewFromFloat(contribution.Volatility),
+			MarginalRisk:  decimal.NewFromFloat(contribution.MarginalRisk),
+			ComponentRisk: decimal.NewFromFloat(contribution.ComponentRisk),
+			PercentOfRisk: decimal.NewFromFloat(contribution.PercentOfRisk),
+			ComponentVaR:  decimal.NewFromFloat(contribution.ComponentVaR),
+		}
+	}
+
+	// Drawdown, risk-adjusted returns and benchmark metrics come from the
+	// value of the portfolio rebalanced to today's weights
+	index := rebalancedIndex(portfolio.ID, "portfolio", days, closes, weights)
+	indexCloses := make([]decimal.Decimal, len(index))
+	for i, bar := range index {
+		indexCloses[i] = bar.Close
+	}
+	riskMetrics.MaxDrawdown = s.calculateMaxDrawdown(indexCloses)
+	riskMetrics.SharpeRatio = decimal.NewFromFloat(risk.SharpeRatio(portfolioReturns, cfg.RiskFreeRate, tradingDaysPerYear))
+	riskMetrics.SortinoRatio = decimal.NewFromFloat(risk.SortinoRatio(portfolioReturns, cfg.RiskFreeRate, tradingDaysPerYear))
+	if err := applyBenchmark(riskMetrics, index, cfg); err != nil {
+		return nil, err
+	}
+
+	riskMetrics.RiskLevel = s.determineRiskLevel(riskMetrics)
+
+	s.logger.WithFields(logrus.Fields{
+		"portfolio_id":          portfolio.ID,
+		"positions":             len(positions),
+		"days":                  len(days),
+		"volatility":            result.Volatility,
+		"diversification_ratio": result.DiversificationRatio,
+	}).Debug("Portfolio risk calculated")
+
+	return riskMetrics, nil
+}
+
+// portfolioWeights returns each position's share of portfolio value, cash
+// included, and its price history
+func portfolioWeights(portfolio *models.Portfolio, positions []*models.Position, histories map[string][]models.HistoricalData) ([]float64, [][]models.HistoricalData, error) {
+	if len(positions) == 0 {
+		return nil, nil, fmt.Errorf("portfolio has no positions")
+	}
+
+	total := portfolio.Cash
+	for _, position := range positions {
+		total = total.Add(position.MarketValue)
+	}
+	if !total.IsPositive() {
+		return nil, nil, fmt.Errorf("portfolio has no value")
+	}
+
+	weights := make([]float64, len(positions))
+	series := make([][]models.HistoricalData, len(positions))
+	for i, position := range positions {
+		data := histories[position.Symbol]
+		if len(data) == 0 {
+			return nil, nil, fmt.Errorf("no price history for %s", position.Symbol)
+		}
+		series[i] = data
+		weights[i] = position.MarketValue.Div(total).InexactFloat64()
+	}
+	return weights, series, nil
+}
@@ -2,27 +2,108 @@ package services
 
 import (
 	"fmt"
+	"time"
+	"trading-service/internal/indicators"
+	"trading-service/internal/ledger"
 	"trading-service/internal/models"
+	"trading-service/internal/risk"
 	"github.com/shopspring/decimal"
 	"github.com/sirupsen/logrus"
 )
 
 type PortfolioService struct {
-	logger *logrus.Logger
+	marketDataService *MarketDataService
+	logger            *logrus.Logger
+
+	benchmarks  *BenchmarkService
+	benchmarkID string
+
+	ledger          *LedgerService
+	costBasisMethod string
 }
 
-func NewPortfolioService(logger *logrus.Logger) *PortfolioService {
+func NewPortfolioService(marketDataService *MarketDataService, logger *logrus.Logger) *PortfolioService {
 	return &PortfolioService{
-		logger: logger,
+		marketDataService: marketDataService,
+		logger:            logger,
+		costBasisMethod:   ledger.MethodFIFO,
 	}
 }
 
+// SetBenchmark measures portfolio beta against the benchmark. Without one
+// beta is left unmeasured.
+func (s *PortfolioService) SetBenchmark(benchmarks *BenchmarkService, benchmarkID string) {
+	s.benchmarks = benchmarks
+	s.benchmarkID = benchmarkID
+}
+
+// SetLedger derives the cash and positions of portfolios with recorded
+// transactions from their ledger, using the cost basis method. Portfolios
+// without any keep the mock data.
+func (s *PortfolioService) SetLedger(ledgerService *LedgerService, costBasisMethod string) {
+	s.ledger = ledgerService
+	s.costBasisMethod = costBasisMethod
+}
+
+// CostBasisMethod returns the method ledger-derived positions use
+func (s *PortfolioService) CostBasisMethod() string {
+	return s.costBasisMethod
+}
+
+// HasPortfolio reports whether the portfolio has recorded transactions.
+// Any other ID is only ever served mock data.
+func (s *PortfolioService) HasPortfolio(portfolioID string) bool {
+	return s.ledger != nil && s.ledger.HasLedger(portfolioID)
+}
+
+// GetPortfolio returns the portfolio marked to current prices. Beta is left
+// unmeasured; see GetPortfolioWithBeta.
 func (s *PortfolioService) GetPortfolio(portfolioID string) (*models.Portfolio, error) {
+	valuation, err := s.ValuePortfolio(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
+	return valuation.Portfolio, nil
+}
+
+// GetPortfolioWithBeta returns the portfolio marked to current prices with
+// its beta measured against the benchmark, which fetches a year of history
+// for every position
+func (s *PortfolioService) GetPortfolioWithBeta(portfolioID string) (*models.Portfolio, error) {
+	valuation, err := s.ValuePortfolio(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	s.measureBeta(valuation.Portfolio, valuation.Positions)
+
+	return valuation.Portfolio, nil
+}
+
+// book returns the portfolio and its positions as last recorded, before
+// marking to market. Portfolios with a ledger are booked at last trade
+// prices.
+func (s *PortfolioService) book(portfolioID string) (*models.Portfolio, []*models.Position, error) {
 	// In a real implementation, this would fetch from database
 	// For demo purposes, return a mock portfolio
 	
 	if portfolioID == "" {
-		return nil, fmt.Errorf("portfolio ID cannot be empty")
+		return nil, nil, fmt.Errorf("portfolio ID cannot be empty")
+	}
+
+	if s.ledger != nil && s.ledger.HasLedger(portfolioID) {
+		statement, err := s.ledger.StatementAt(portfolioID, s.costBasisMethod, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		portfolio := &models.Portfolio{
+			ID:          portfolioID,
+			Cash:        statement.Cash,
+			TotalValue:  statement.TotalValue,
+			TotalReturn: statement.TotalReturn,
+			UpdatedAt:   statement.AsOf,
+		}
+		return portfolio, statement.Positions, nil
 	}
 
 	// Mock portfolio data
@@ -31,15 +112,48 @@ func (s *PortfolioService) GetPortfolio(portfolioID string) (*models.Portfolio,
 		UserID:       "user_123",
 		Name:         "Growth Portfolio",
 		Cash:         decimal.NewFromFloat(25000.00),
-		TotalValue:   decimal.NewFromFloat(125000.00),
-		TotalReturn:  decimal.NewFromFloat(25000.00),
-		ReturnPercent: decimal.NewFromFloat(25.0),
-		Beta:         decimal.NewFromFloat(1.15),
 		Sharpe:       decimal.NewFromFloat(1.85),
 		MaxDrawdown:  decimal.NewFromFloat(-8.5),
 	}
+	positions := mockPositions(portfolioID)
 
-	return portfolio, nil
+	// Value and return follow from the positions
+	portfolio.TotalValue = portfolio.Cash
+	for _, position := range positions {
+		portfolio.TotalValue = portfolio.TotalValue.Add(position.MarketValue)
+		portfolio.TotalReturn = portfolio.TotalReturn.Add(position.UnrealizedPL).Add(position.RealizedPL)
+	}
+
+	return portfolio, positions, nil
+}
+
+// measureBeta sets the portfolio's beta against the configured benchmark
+// over the last year, and lists the positions left out for lack of
+// history. Beta stays zero and unmeasured when it can't be measured at all.
+func (s *PortfolioService) measureBeta(portfolio *models.Portfolio, positions []*models.Position) {
+	portfolio.Beta, portfolio.BetaMeasured, portfolio.BetaExcluded = decimal.Zero, false, nil
+	if s.benchmarks == nil || s.benchmarkID == "" {
+		return
+	}
+
+	to := time.Now()
+	from := to.AddDate(-1, 0, 0)
+	beta, excluded, err := s.benchmarks.PortfolioBeta(positions, portfolio.Cash, s.benchmarks.Resolve(s.benchmarkID), from, to)
+	if err != nil {
+		s.logger.WithError(err).WithFields(logrus.Fields{
+			"portfolio_id": portfolio.ID,
+			"benchmark":    s.benchmarkID,
+		}).Warn("Failed to calculate portfolio beta")
+		return
+	}
+	if len(excluded) > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"portfolio_id": portfolio.ID,
+			"benchmark":    s.benchmarkID,
+			"excluded":     excluded,
+		}).Warn("Positions without a measurable beta left out of portfolio beta")
+	}
+	portfolio.Beta, portfolio.BetaMeasured, portfolio.BetaExcluded = beta, true, excluded
 }
 
 func (s *PortfolioService) CreatePortfolio(userID, name string, initialCash decimal.Decimal) (*models.Portfolio, error) {
@@ -64,19 +178,38 @@ func (s *PortfolioService) CreatePortfolio(userID, name string, initialCash deci
 		TotalValue:   initialCash,
 		TotalReturn:  decimal.Zero,
 		ReturnPercent: decimal.Zero,
-		Beta:         decimal.NewFromFloat(1.0),
+		Beta:         decimal.Zero, // All cash
+		BetaMeasured: true,
 		Sharpe:       decimal.Zero,
 		MaxDrawdown:  decimal.Zero,
 	}
 
+	// The opening cash is the ledger's first entry
+	if s.ledger != nil && initialCash.IsPositive() {
+		if _, err := s.ledger.Record(portfolio.ID, models.Transaction{
+			Type:   models.TransactionDeposit,
+			Amount: initialCash,
+			Note:   "Initial cash",
+		}); err != nil {
+			return nil, err
+		}
+	}
+
 	return portfolio, nil
 }
 
+// GetPositions returns the portfolio's positions marked to current prices
 func (s *PortfolioService) GetPositions(portfolioID string) ([]*models.Position, error) {
-	if portfolioID == "" {
-		return nil, fmt.Errorf("portfolio ID cannot be empty")
+	valuation, err := s.ValuePortfolio(portfolioID)
+	if err != nil {
+		return nil, err
 	}
 
+	return valuation.Positions, nil
+}
+
+// mockPositions returns demo positions, valued at their purchase-time prices
+func mockPositions(portfolioID string) []*models.Position {
 	// Mock positions data
 	positions := []*models.Position{
 		{
@@ -120,7 +253,7 @@ func (s *PortfolioService) GetPositions(portfolioID string) ([]*models.Position,
 		},
 	}
 
-	return positions, nil
+	return positions
 }
 
 func (s *PortfolioService) AddPosition(portfolioID, symbol string, quantity, price decimal.Decimal) (*models.Position, error) {
@@ -187,42 +320,16 @@ func (s *PortfolioService) RemovePosition(positionID string) error {
 	return nil
 }
 
+// CalculatePortfolioMetrics revalues the portfolio's positions at current
+// prices, recomputing market values, unrealized P&L, weights, total value
+// and return
 func (s *PortfolioService) CalculatePortfolioMetrics(portfolioID string) (*models.Portfolio, error) {
-	portfolio, err := s.GetPortfolio(portfolioID)
+	valuation, err := s.ValuePortfolio(portfolioID)
 	if err != nil {
 		return nil, err
 	}
 
-	positions, err := s.GetPositions(portfolioID)
-	if err != nil {
-		return nil, err
-	}
-
-	// Calculate total market value
-	totalMarketValue := decimal.Zero
-	for _, position := range positions {
-		totalMarketValue = totalMarketValue.Add(position.MarketValue)
-	}
-
-	// Update portfolio with calculated values
-	portfolio.TotalValue = portfolio.Cash.Add(totalMarketValue)
-	
-	// Calculate total return (simplified)
-	initialValue := decimal.NewFromFloat(100000.00) // Would be stored in database
-	portfolio.TotalReturn = portfolio.TotalValue.Sub(initialValue)
-	
-	if !initialValue.IsZero() {
-		portfolio.ReturnPercent = portfolio.TotalReturn.Div(initialValue).Mul(decimal.NewFromFloat(100))
-	}
-
-	// Update position weights
-	for _, position := range positions {
-		if !portfolio.TotalValue.IsZero() {
-			position.Weight = position.MarketValue.Div(portfolio.TotalValue).Mul(decimal.NewFromFloat(100))
-		}
-	}
-
-	return portfolio, nil
+	return valuation.Portfolio, nil
 }
 
 func (s *PortfolioService) GetPortfolioPerformance(portfolioID string, days int) (map[string]interface{}, error) {
@@ -234,6 +341,11 @@ func (s *PortfolioService) GetPortfolioPerformance(portfolioID string, days int)
 		days = 30 // Default to 30 days
 	}
 
+	portfolio, err := s.GetPortfolioWithBeta(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Mock performance data
 	performance := map[string]interface{}{
 		"portfolio_id":     portfolioID,
@@ -244,7 +356,9 @@ func (s *PortfolioService) GetPortfolioPerformance(portfolioID string, days int)
 		"sharpe_ratio":     1.85,
 		"max_drawdown":     "-8.5%",
 		"alpha":            "2.3%",
-		"beta":             1.15,
+		"beta":             portfolio.Beta.Round(4).InexactFloat64(),
+		"beta_measured":    portfolio.BetaMeasured,
+		"benchmark":        s.benchmarkID,
 		"daily_returns":    generateMockReturns(days),
 		"top_performers": []map[string]interface{}{
 			{"symbol": "GOOGL", "return": "8.5%"},
@@ -256,10 +370,100 @@ func (s *PortfolioService) GetPortfolioPerformance(portfolioID string, days int)
 			"Cash":       "14.5%",
 		},
 	}
+	if len(portfolio.BetaExcluded) > 0 {
+		performance["beta_excluded"] = portfolio.BetaExcluded
+	}
 
 	return performance, nil
 }
 
+// ProjectionRequest configures ProjectPortfolio
+type ProjectionRequest struct {
+	risk.ProjectionConfig
+	Lookback int `json:"lookback"` // Days of history returns are drawn from, default 1095
+}
+
+// Validate checks the request
+func (r ProjectionRequest) Validate() error {
+	if r.Lookback < 0 || r.Lookback > 3650 {
+		return fmt.Errorf("lookback must be between 1 and 3650 days, or 0 for the default")
+	}
+	return r.ProjectionConfig.Validate()
+}
+
+// PortfolioProjection is a Monte Carlo projection of a portfolio's value
+type PortfolioProjection struct {
+	PortfolioID string    `json:"portfolio_id"`
+	Symbols     []string  `json:"symbols"`
+	HistoryFrom time.Time `json:"history_from"` // First bar returns were drawn from
+	HistoryTo   time.Time `json:"history_to"`
+	*risk.Projection
+}
+
+// ProjectPortfolio simulates the portfolio's value over the horizon from its
+// positions' daily returns on the days all of them traded
+func (s *PortfolioService) ProjectPortfolio(portfolioID string, req ProjectionRequest) (*PortfolioProjection, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.Lookback == 0 {
+		req.Lookback = 1095
+	}
+
+	valuation, err := s.ValuePortfolio(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	portfolio, positions := valuation.Portfolio, valuation.Positions
+	if len(positions) == 0 {
+		return nil, fmt.Errorf("portfolio has no positions")
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -req.Lookback)
+	series := make([][]models.HistoricalData, len(positions))
+	holdings := make([]float64, len(positions))
+	symbols := make([]string, len(positions))
+	for i, position := range positions {
+		data, err := s.marketDataService.GetHistoricalData(position.Symbol, from, to)
+		if err != nil {
+			return nil, err
+		}
+		series[i] = data
+		holdings[i] = position.MarketValue.InexactFloat64()
+		symbols[i] = position.Symbol
+	}
+
+	days, closes := alignCloses(series...)
+	if len(days) < 2 {
+		return nil, fmt.Errorf("positions share no price history")
+	}
+	returns := make([][]float64, len(closes))
+	for i := range closes {
+		returns[i] = indicators.Returns(closes[i])
+	}
+
+	projection, err := risk.Project(holdings, portfolio.Cash.InexactFloat64(), returns, req.ProjectionConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"portfolio_id": portfolioID,
+		"method":       projection.Method,
+		"paths":        projection.Paths,
+		"horizon":      projection.Horizon,
+	}).Info("Portfolio projection completed")
+
+	return &PortfolioProjection{
+		PortfolioID: portfolioID,
+		Symbols:     symbols,
+		HistoryFrom: days[0],
+		HistoryTo:   days[len(days)-1],
+		Projection:  projection,
+	}, nil
+}
+
 func (s *PortfolioService) OptimizePortfolio(portfolioID string, riskTolerance string, targetReturn decimal.Decimal) (map[string]interface{}, error) {
 	if portfolioID == "" {
 		return nil, fmt.Errorf("portfolio ID cannot be empty")
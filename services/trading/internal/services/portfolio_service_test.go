@@ -0,0 +1,32 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"trading-service/internal/risk"
+)
+
+func TestProjectionRequestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   ProjectionRequest
+		valid bool
+	}{
+		{"defaults", ProjectionRequest{}, true},
+		{"max lookback", ProjectionRequest{Lookback: 3650}, true},
+		{"long lookback", ProjectionRequest{Lookback: 3651}, false},
+		{"negative lookback", ProjectionRequest{Lookback: -1}, false},
+		{"invalid projection", ProjectionRequest{ProjectionConfig: risk.ProjectionConfig{Paths: 10}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.req.Validate(); (err == nil) != tt.valid {
+			t.Fatalf("%s: error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+
+	err := ProjectionRequest{Lookback: -1}.Validate()
+	if !strings.Contains(err.Error(), "0 for the default") {
+		t.Fatalf("error %q doesn't mention that 0 is accepted", err)
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/models"
+)
+
+// PortfolioValuation is a portfolio with its positions marked to market
+type PortfolioValuation struct {
+	Portfolio    *models.Portfolio  `json:"portfolio"`
+	Positions    []*models.Position `json:"positions"`
+	MarketValue  decimal.Decimal    `json:"market_value"`
+	UnrealizedPL decimal.Decimal    `json:"unrealized_pl"`
+	Unpriced     []string           `json:"unpriced,omitempty"` // Symbols without a quote, left at their last price
+	AsOf         time.Time          `json:"as_of"`
+}
+
+// ValuePortfolio marks a portfolio's positions to current prices
+func (s *PortfolioService) ValuePortfolio(portfolioID string) (*PortfolioValuation, error) {
+	portfolio, positions, err := s.book(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	return markToMarket(portfolio, positions, s.quotes(positionSymbols(positions))), nil
+}
+
+// ValuePortfolios marks several portfolios to market with a single batch of
+// quotes for every symbol they hold. Portfolios that can't be valued are
+// logged and left out.
+func (s *PortfolioService) ValuePortfolios(portfolioIDs []string) map[string]*PortfolioValuation {
+	type booked struct {
+		portfolio *models.Portfolio
+		positions []*models.Position
+	}
+	books := make(map[string]booked, len(portfolioIDs))
+	var all []*models.Position
+	for _, portfolioID := range portfolioIDs {
+		portfolio, positions, err := s.book(portfolioID)
+		if err != nil {
+			s.logger.WithError(err).WithField("portfolio_id", portfolioID).Warn("Failed to value portfolio")
+			continue
+		}
+		books[portfolioID] = booked{portfolio, positions}
+		all = append(all, positions...)
+	}
+
+	prices := s.quotes(positionSymbols(all))
+	valuations := make(map[string]*PortfolioValuation, len(books))
+	for portfolioID, b := range books {
+		valuations[portfolioID] = markToMarket(b.portfolio, b.positions, prices)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"portfolios": len(valuations),
+		"symbols":    len(prices),
+	}).Debug("Portfolios marked to market")
+
+	return valuations
+}
+
+// quotes fetches current prices for the symbols in one batch. Symbols that
+// fail are left out.
+func (s *PortfolioService) quotes(symbols []string) map[string]decimal.Decimal {
+	prices := make(map[string]decimal.Decimal, len(symbols))
+	if len(symbols) == 0 {
+		return prices
+	}
+	quotes, err := s.marketDataService.GetMultipleSymbolsData(symbols)
+	if err != nil {
+		s.logger.WithError(err).WithField("symbols", symbols).Warn("No quotes for portfolio valuation; using last prices")
+	}
+	for symbol, quote := range quotes {
+		if quote.Price.IsPositive() {
+			prices[symbol] = quote.Price
+		}
+	}
+	return prices
+}
+
+// positionSymbols returns the distinct symbols held, sorted
+func positionSymbols(positions []*models.Position) []string {
+	seen := make(map[string]bool, len(positions))
+	var symbols []string
+	for _, position := range positions {
+		if !seen[position.Symbol] {
+			seen[position.Symbol] = true
+			symbols = append(symbols, position.Symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// markToMarket revalues the booked positions at prices and updates the
+// portfolio to match. A position's cost is its booked market value less
+// unrealized P&L, and the capital behind the portfolio is its booked value
+// less its return; neither changes with price.
+func markToMarket(portfolio *models.Portfolio, positions []*models.Position, prices map[string]decimal.Decimal) *PortfolioValuation {
+	now := time.Now()
+	hundred := decimal.NewFromInt(100)
+	invested := portfolio.TotalValue.Sub(portfolio.TotalReturn)
+	valuation := &PortfolioValuation{
+		Portfolio: portfolio,
+		Positions: positions,
+		AsOf:      now,
+	}
+
+	var unpriced []string
+	for _, position := range positions {
+		cost := position.MarketValue.Sub(position.UnrealizedPL)
+		if price, ok := prices[position.Symbol]; ok {
+			position.CurrentPrice = price
+			position.MarketValue = position.Quantity.Mul(price)
+			position.UnrealizedPL = position.MarketValue.Sub(cost)
+			position.UpdatedAt = now
+		} else {
+			unpriced = append(unpriced, position.Symbol)
+		}
+		if cost.IsPositive() {
+			position.TotalReturn = position.UnrealizedPL.Div(cost).Mul(hundred)
+		}
+		valuation.MarketValue = valuation.MarketValue.Add(position.MarketValue)
+		valuation.UnrealizedPL = valuation.UnrealizedPL.Add(position.UnrealizedPL)
+	}
+	valuation.Unpriced = unpriced
+
+	portfolio.TotalValue = portfolio.Cash.Add(valuation.MarketValue)
+	portfolio.TotalReturn = portfolio.TotalValue.Sub(invested)
+	portfolio.ReturnPercent = decimal.Zero
+	if invested.IsPositive() {
+		portfolio.ReturnPercent = portfolio.TotalReturn.Div(invested).Mul(hundred)
+	}
+	portfolio.UpdatedAt = now
+
+	for _, position := range positions {
+		position.Weight = decimal.Zero
+		if portfolio.TotalValue.IsPositive() {
+			position.Weight = position.MarketValue.Div(portfolio.TotalValue).Mul(hundred)
+		}
+	}
+	return valuation
+}
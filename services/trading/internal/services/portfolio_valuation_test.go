@@ -0,0 +1,185 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/models"
+	"trading-service/internal/providers"
+)
+
+// quoteProvider serves fixed quotes and counts the requests per symbol
+type quoteProvider struct {
+	fixtureProvider
+	prices map[string]float64
+
+	quoteMu  sync.Mutex
+	requests map[string]int
+}
+
+func (p *quoteProvider) GetRealtimeData(symbol string) (*models.MarketData, error) {
+	p.quoteMu.Lock()
+	p.requests[symbol]++
+	p.quoteMu.Unlock()
+
+	price, ok := p.prices[symbol]
+	if !ok {
+		return nil, fmt.Errorf("no quote for %s", symbol)
+	}
+	return &models.MarketData{Symbol: symbol, Price: decimal.NewFromFloat(price)}, nil
+}
+
+// bookedPortfolio holds 1000 cash, AAPL booked at 100 with a cost of 800,
+// MSFT booked at 100 with a cost of 600 and a closed-out TSLA position.
+// 2400 was invested, so the booked return is 100.
+func bookedPortfolio() (*models.Portfolio, []*models.Position) {
+	d := decimal.NewFromFloat
+	portfolio := &models.Portfolio{ID: "p1", Cash: d(1000), TotalValue: d(2500), TotalReturn: d(100)}
+	positions := []*models.Position{
+		{Symbol: "AAPL", Quantity: d(10), CurrentPrice: d(100), MarketValue: d(1000), UnrealizedPL: d(200)},
+		{Symbol: "MSFT", Quantity: d(5), CurrentPrice: d(100), MarketValue: d(500), UnrealizedPL: d(-100)},
+		{Symbol: "TSLA", Quantity: d(0), CurrentPrice: d(200)},
+	}
+	return portfolio, positions
+}
+
+func TestMarkToMarket(t *testing.T) {
+	type line struct{ price, marketValue, unrealizedPL, totalReturn float64 }
+	tests := []struct {
+		name      string
+		prices    map[string]float64
+		positions map[string]line
+		total     float64
+		ret       float64
+		unpriced  string
+	}{
+		{
+			"every symbol quoted",
+			map[string]float64{"AAPL": 120, "MSFT": 110, "TSLA": 250},
+			map[string]line{
+				"AAPL": {120, 1200, 400, 50},
+				"MSFT": {110, 550, -50, -50.0 / 6},
+				"TSLA": {250, 0, 0, 0},
+			},
+			2750, 350, "",
+		},
+		{
+			"symbol missing from the quotes",
+			map[string]float64{"AAPL": 90, "TSLA": 250},
+			map[string]line{
+				"AAPL": {90, 900, 100, 12.5},
+				"MSFT": {100, 500, -100, -50.0 / 3},
+				"TSLA": {250, 0, 0, 0},
+			},
+			2400, 0, "MSFT",
+		},
+		{
+			"no quotes",
+			nil,
+			map[string]line{
+				"AAPL": {100, 1000, 200, 25},
+				"MSFT": {100, 500, -100, -50.0 / 3},
+				"TSLA": {200, 0, 0, 0},
+			},
+			2500, 100, "AAPL,MSFT,TSLA",
+		},
+	}
+	for _, tt := range tests {
+		prices := make(map[string]decimal.Decimal)
+		for symbol, price := range tt.prices {
+			prices[symbol] = decimal.NewFromFloat(price)
+		}
+		portfolio, positions := bookedPortfolio()
+		valuation := markToMarket(portfolio, positions, prices)
+
+		if got := strings.Join(valuation.Unpriced, ","); got != tt.unpriced {
+			t.Fatalf("%s: unpriced %q, want %q", tt.name, got, tt.unpriced)
+		}
+		var marketValue, unrealizedPL float64
+		weights := portfolio.Cash.Div(portfolio.TotalValue).Mul(decimal.NewFromInt(100)).InexactFloat64()
+		for _, position := range valuation.Positions {
+			want := tt.positions[position.Symbol]
+			got := line{
+				position.CurrentPrice.InexactFloat64(),
+				position.MarketValue.InexactFloat64(),
+				position.UnrealizedPL.InexactFloat64(),
+				position.TotalReturn.InexactFloat64(),
+			}
+			if math.Abs(got.price-want.price) > 1e-9 || math.Abs(got.marketValue-want.marketValue) > 1e-9 ||
+				math.Abs(got.unrealizedPL-want.unrealizedPL) > 1e-9 || math.Abs(got.totalReturn-want.totalReturn) > 1e-9 {
+				t.Fatalf("%s: %s marked at %+v, want %+v", tt.name, position.Symbol, got, want)
+			}
+			if wantWeight := 100 * want.marketValue / tt.total; math.Abs(position.Weight.InexactFloat64()-wantWeight) > 1e-9 {
+				t.Fatalf("%s: %s weight %v, want %v", tt.name, position.Symbol, position.Weight, wantWeight)
+			}
+			marketValue += want.marketValue
+			unrealizedPL += want.unrealizedPL
+			weights += position.Weight.InexactFloat64()
+		}
+		if math.Abs(weights-100) > 1e-9 {
+			t.Fatalf("%s: weights and cash sum to %v%%, want 100%%", tt.name, weights)
+		}
+		if math.Abs(valuation.MarketValue.InexactFloat64()-marketValue) > 1e-9 || math.Abs(valuation.UnrealizedPL.InexactFloat64()-unrealizedPL) > 1e-9 {
+			t.Fatalf("%s: market value %v unrealized %v, want %v and %v", tt.name, valuation.MarketValue, valuation.UnrealizedPL, marketValue, unrealizedPL)
+		}
+
+		// The invested 2400 doesn't move with prices
+		if portfolio.TotalValue.InexactFloat64() != tt.total || portfolio.TotalReturn.InexactFloat64() != tt.ret {
+			t.Fatalf("%s: total %v return %v, want %v and %v", tt.name, portfolio.TotalValue, portfolio.TotalReturn, tt.total, tt.ret)
+		}
+		if wantPercent := 100 * tt.ret / 2400; math.Abs(portfolio.ReturnPercent.InexactFloat64()-wantPercent) > 1e-9 {
+			t.Fatalf("%s: return %v%%, want %v%%", tt.name, portfolio.ReturnPercent, wantPercent)
+		}
+	}
+}
+
+func TestMarkToMarketAllCash(t *testing.T) {
+	portfolio := &models.Portfolio{Cash: decimal.NewFromInt(1000), TotalValue: decimal.NewFromInt(1000)}
+	valuation := markToMarket(portfolio, nil, nil)
+	if !valuation.MarketValue.IsZero() || !portfolio.TotalValue.Equal(decimal.NewFromInt(1000)) || !portfolio.ReturnPercent.IsZero() {
+		t.Fatalf("valuation %+v portfolio %+v, want the cash unchanged", valuation, portfolio)
+	}
+}
+
+func TestValuePortfoliosBatchesQuotes(t *testing.T) {
+	provider := &quoteProvider{
+		prices:   map[string]float64{"AAPL": 160, "GOOGL": 2600},
+		requests: make(map[string]int),
+	}
+	marketData := NewMarketDataService(providers.NewMarketDataAggregator([]providers.MarketDataProvider{provider}), testLogger())
+	s := NewPortfolioService(marketData, testLogger())
+
+	valuations := s.ValuePortfolios([]string{"p1", "p2", ""})
+	if len(valuations) != 2 || valuations["p1"] == nil || valuations["p2"] == nil {
+		t.Fatalf("valued %v, want p1 and p2 with the blank ID left out", valuations)
+	}
+	// Both portfolios hold the same three symbols, quoted once each
+	if len(provider.requests) != 3 || provider.requests["AAPL"] != 1 || provider.requests["GOOGL"] != 1 || provider.requests["MSFT"] != 1 {
+		t.Fatalf("quote requests %v, want one per symbol across portfolios", provider.requests)
+	}
+	for portfolioID, valuation := range valuations {
+		if valuation.Portfolio.ID != portfolioID || strings.Join(valuation.Unpriced, ",") != "MSFT" {
+			t.Fatalf("%s: portfolio %s unpriced %v, want MSFT unpriced", portfolioID, valuation.Portfolio.ID, valuation.Unpriced)
+		}
+		for _, position := range valuation.Positions {
+			if want, ok := provider.prices[position.Symbol]; ok && position.CurrentPrice.InexactFloat64() != want {
+				t.Fatalf("%s: %s at %v, want the quote %v", portfolioID, position.Symbol, position.CurrentPrice, want)
+			}
+		}
+	}
+
+	single, err := s.ValuePortfolio("p3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.requests["AAPL"] != 2 || single.Positions[0].CurrentPrice.InexactFloat64() != 160 {
+		t.Fatalf("requests %v, want ValuePortfolio to quote again", provider.requests)
+	}
+	if _, err := s.ValuePortfolio(""); err == nil {
+		t.Fatal("a blank portfolio ID was valued")
+	}
+}
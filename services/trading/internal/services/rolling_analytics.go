@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/models"
+	"trading-service/internal/risk"
+)
+
+// RollingConfig controls RollingAnalytics
+type RollingConfig struct {
+	Window       int                     // Returns per window, default 63
+	Period       int                     // Days of history, default 756
+	RiskFreeRate float64                 // Annual
+	BenchmarkID  string                  // Reported with rolling beta
+	Benchmark    []models.HistoricalData // Optional; enables rolling beta over the days both traded
+}
+
+// DefaultRollingConfig returns quarterly windows over three years at the
+// configured risk-free rate with no benchmark
+func (s *AnalysisService) DefaultRollingConfig() RollingConfig {
+	return RollingConfig{Window: 63, Period: 756, RiskFreeRate: s.riskFreeRate}
+}
+
+// Validate checks the window, period and risk-free rate
+func (c RollingConfig) Validate() error {
+	if c.Window < 5 || c.Window > 1260 {
+		return fmt.Errorf("window must be between 5 and 1260 bars")
+	}
+	if c.Period < 1 || c.Period > 3650 {
+		return fmt.Errorf("period must be between 1 and 3650 days")
+	}
+	if math.IsNaN(c.RiskFreeRate) || math.IsInf(c.RiskFreeRate, 0) {
+		return fmt.Errorf("risk-free rate must be a finite number")
+	}
+	return nil
+}
+
+// RollingPoint holds the metrics of the trailing window ending on Date
+type RollingPoint struct {
+	Date        time.Time `json:"date"`
+	Volatility  float64   `json:"volatility"`
+	Sharpe      float64   `json:"sharpe"`
+	Sortino     float64   `json:"sortino"`
+	Beta        *float64  `json:"beta,omitempty"`
+	MaxDrawdown float64   `json:"max_drawdown"`
+}
+
+// UnderwaterPoint is how far Value sits below its running peak on Date
+type UnderwaterPoint struct {
+	Date     time.Time `json:"date"`
+	Value    float64   `json:"value"`
+	Drawdown float64   `json:"drawdown"` // Zero at a new high, negative below one
+}
+
+// DrawdownEpisode is a fall from a peak and, if it happened, the recovery.
+// Durations count bars.
+type DrawdownEpisode struct {
+	Peak         time.Time  `json:"peak"`
+	Trough       time.Time  `json:"trough"`
+	Recovery     *time.Time `json:"recovery,omitempty"` // Unset while still underwater
+	Depth        float64    `json:"depth"`
+	Duration     int        `json:"duration"`      // Peak to recovery, or to today
+	RecoveryBars int        `json:"recovery_bars"` // Trough to recovery, or to today
+}
+
+// RollingAnalytics shows how a symbol's or portfolio's risk evolved
+type RollingAnalytics struct {
+	Symbol      string            `json:"symbol,omitempty"`
+	PortfolioID string            `json:"portfolio_id,omitempty"`
+	Benchmark   string            `json:"benchmark,omitempty"`
+	Window      int               `json:"window"`
+	Start       time.Time         `json:"start"`
+	End         time.Time         `json:"end"`
+	Rolling     []RollingPoint    `json:"rolling"`
+	Underwater  []UnderwaterPoint `json:"underwater"`
+	Drawdowns   []DrawdownEpisode `json:"drawdowns"` // Worst first
+}
+
+// RollingAnalytics computes rolling volatility, Sharpe, Sortino, beta and
+// max drawdown over trailing windows of daily bars, along with the
+// underwater curve and its drawdown episodes. With a benchmark, only days
+// both traded are used.
+func (s *AnalysisService) RollingAnalytics(symbol string, data []models.HistoricalData, cfg RollingConfig) (*RollingAnalytics, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var days []time.Time
+	var values, benchmark []float64
+	if len(cfg.Benchmark) > 0 {
+		var closes [][]float64
+		days, closes = alignCloses(data, cfg.Benchmark)
+		if len(closes) == 2 {
+			values, benchmark = closes[0], closes[1]
+		}
+	} else {
+		for _, bar := range data {
+			days = append(days, bar.Date)
+			values = append(values, bar.Close.InexactFloat64())
+		}
+	}
+	if len(values) <= cfg.Window {
+		return nil, fmt.Errorf("insufficient data for rolling analytics: %d bars for a %d-bar window", len(values), cfg.Window)
+	}
+
+	rolling, err := risk.Rolling(values, benchmark, cfg.Window, cfg.RiskFreeRate, tradingDaysPerYear)
+	if err != nil {
+		return nil, fmt.Errorf("rolling analytics failed: %v", err)
+	}
+
+	result := &RollingAnalytics{
+		Symbol:     symbol,
+		Window:     cfg.Window,
+		Start:      days[0],
+		End:        days[len(days)-1],
+		Rolling:    make([]RollingPoint, len(rolling.Volatility)),
+		Underwater: make([]UnderwaterPoint, len(values)),
+	}
+	drawdowns, periods := risk.Underwater(values)
+	result.Drawdowns = make([]DrawdownEpisode, 0, len(periods))
+	if benchmark != nil {
+		result.Benchmark = cfg.BenchmarkID
+	}
+	for i := range rolling.Volatility {
+		result.Rolling[i] = RollingPoint{
+			Date:        days[i+cfg.Window],
+			Volatility:  rolling.Volatility[i],
+			Sharpe:      rolling.Sharpe[i],
+			Sortino:     rolling.Sortino[i],
+			MaxDrawdown: rolling.MaxDrawdown[i],
+		}
+		if rolling.Beta != nil && rolling.BetaValid[i] {
+			result.Rolling[i].Beta = &rolling.Beta[i]
+		}
+	}
+
+	for i, value := range values {
+		result.Underwater[i] = UnderwaterPoint{Date: days[i], Value: value, Drawdown: drawdowns[i]}
+	}
+	sort.SliceStable(periods, func(i, j int) bool { return periods[i].Depth > periods[j].Depth })
+	for _, period := range periods {
+		episode := DrawdownEpisode{
+			Peak:         days[period.Peak],
+			Trough:       days[period.Trough],
+			Depth:        period.Depth,
+			Duration:     period.Duration,
+			RecoveryBars: period.RecoveryBars,
+		}
+		if period.Recovery >= 0 {
+			episode.Recovery = &days[period.Recovery]
+		}
+		result.Drawdowns = append(result.Drawdowns, episode)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"symbol":    symbol,
+		"window":    cfg.Window,
+		"bars":      len(values),
+		"drawdowns": len(periods),
+	}).Debug("Rolling analytics calculated")
+
+	return result, nil
+}
+
+// PortfolioRollingAnalytics computes rolling analytics for a portfolio
+// holding its positions at today's weights, rebalanced daily, over the days
+// all of them traded. Histories maps each position's symbol to its daily
+// bars; cash is treated as riskless.
+func (s *AnalysisService) PortfolioRollingAnalytics(portfolio *models.Portfolio, positions []*models.Position, histories map[string][]models.HistoricalData, cfg RollingConfig) (*RollingAnalytics, error) {
+	weights, series, err := portfolioWeights(portfolio, positions, histories)
+	if err != nil {
+		return nil, err
+	}
+
+	days, closes := alignCloses(series...)
+	result, err := s.RollingAnalytics("", rebalancedIndex(portfolio.ID, "portfolio", days, closes, weights), cfg)
+	if err != nil {
+		return nil, err
+	}
+	result.PortfolioID = portfolio.ID
+	return result, nil
+}
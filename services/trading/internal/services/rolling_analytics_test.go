@@ -0,0 +1,90 @@
+package services
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRollingConfigValidate(t *testing.T) {
+	service := NewAnalysisService(testLogger())
+	tests := []struct {
+		name  string
+		edit  func(*RollingConfig)
+		valid bool
+	}{
+		{"defaults", func(*RollingConfig) {}, true},
+		{"short window", func(c *RollingConfig) { c.Window = 4 }, false},
+		{"long window", func(c *RollingConfig) { c.Window = 1261 }, false},
+		{"max period", func(c *RollingConfig) { c.Period = 3650 }, true},
+		{"long period", func(c *RollingConfig) { c.Period = 3651 }, false},
+		{"zero period", func(c *RollingConfig) { c.Period = 0 }, false},
+		{"negative rate", func(c *RollingConfig) { c.RiskFreeRate = -0.01 }, true},
+		{"rate NaN", func(c *RollingConfig) { c.RiskFreeRate = math.NaN() }, false},
+		{"rate +Inf", func(c *RollingConfig) { c.RiskFreeRate = math.Inf(1) }, false},
+	}
+	for _, tt := range tests {
+		cfg := service.DefaultRollingConfig()
+		tt.edit(&cfg)
+		if err := cfg.Validate(); (err == nil) != tt.valid {
+			t.Fatalf("%s: error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestRollingAnalyticsDrawdowns(t *testing.T) {
+	service := NewAnalysisService(testLogger())
+	data := dailyBars("TEST", 100, 110, 99, 88, 105, 121, 120, 132, 66, 70)
+	cfg := service.DefaultRollingConfig()
+	cfg.Window = 5
+
+	analytics, err := service.RollingAnalytics("TEST", data, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analytics.Rolling) != 5 || !analytics.Rolling[0].Date.Equal(data[5].Date) {
+		t.Fatalf("got %d windows from %v, want 5 from %v", len(analytics.Rolling), analytics.Rolling[0].Date, data[5].Date)
+	}
+	if len(analytics.Underwater) != len(data) || analytics.Underwater[3].Drawdown > -0.199 {
+		t.Fatalf("underwater %+v, want a 20%% drawdown on bar 3", analytics.Underwater)
+	}
+
+	// Worst first; the last one hasn't recovered
+	drawdowns := analytics.Drawdowns
+	if len(drawdowns) != 3 {
+		t.Fatalf("got %d drawdowns, want 3", len(drawdowns))
+	}
+	if drawdowns[0].Depth != 0.5 || drawdowns[0].Recovery != nil || !drawdowns[0].Trough.Equal(data[8].Date) {
+		t.Fatalf("worst drawdown %+v, want the open 50%% fall to bar 8", drawdowns[0])
+	}
+	if math.Abs(drawdowns[1].Depth-0.2) > 1e-12 || drawdowns[1].Recovery == nil || !drawdowns[1].Recovery.Equal(data[5].Date) {
+		t.Fatalf("second drawdown %+v, want 20%% recovered on bar 5", drawdowns[1])
+	}
+
+	cfg.Window = len(data)
+	if _, err := service.RollingAnalytics("TEST", data, cfg); err == nil {
+		t.Fatal("window as long as the data accepted")
+	}
+}
+
+func TestRollingAnalyticsFlatBenchmark(t *testing.T) {
+	service := NewAnalysisService(testLogger())
+	data := dailyBars("TEST", 100, 102, 101, 104, 103, 106, 105, 108, 107, 110)
+	cfg := service.DefaultRollingConfig()
+	cfg.Window = 5
+	cfg.BenchmarkID = "FLAT"
+	// The benchmark only starts moving on the last bar
+	cfg.Benchmark = dailyBars("FLAT", 50, 50, 50, 50, 50, 50, 50, 50, 50, 51)
+
+	analytics, err := service.RollingAnalytics("TEST", data, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, point := range analytics.Rolling {
+		if last := i == len(analytics.Rolling)-1; (point.Beta != nil) != last {
+			t.Fatalf("window %d beta %v, want one only once the benchmark moves", i, point.Beta)
+		}
+	}
+	if analytics.Benchmark != "FLAT" {
+		t.Fatalf("benchmark %q, want FLAT", analytics.Benchmark)
+	}
+}
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/algorithms"
+	"trading-service/internal/expression"
+	"trading-service/internal/models"
+)
+
+// Scan sort keys besides column names
+const (
+	ScanSortSymbol     = "symbol"
+	ScanSortPrice      = "price"
+	ScanSortConfidence = "confidence"
+	ScanSortStrength   = "strength"
+)
+
+// maxScanSymbols caps the symbols in one scan
+const maxScanSymbols = 500
+
+// ScanConfig describes a scan: which symbols, what must hold on each
+// symbol's latest bar, what to report and how to rank the matches
+type ScanConfig struct {
+	Symbols       []string               `json:"symbols"`        // Defaults to the supported symbol list
+	Algorithm     string                 `json:"algorithm"`      // Optional; its latest signal is filtered and reported
+	Parameters    map[string]interface{} `json:"parameters"`     // Overrides for the algorithm
+	SignalTypes   []string               `json:"signal_types"`   // e.g. ["BUY"]; empty accepts any
+	MinConfidence float64                `json:"min_confidence"` // 0-1
+	Condition     string                 `json:"condition"`      // Expression that must be true on the latest bar, e.g. RSI(close, 14) < 40
+	Columns       map[string]string      `json:"columns"`        // Named expressions reported per symbol, e.g. {"rsi": "RSI(close, 14)"}
+	SortBy        string                 `json:"sort_by"`        // symbol, price, confidence, strength or a column name
+	Order         string                 `json:"order"`          // asc or desc
+	Limit         int                    `json:"limit"`          // Default 50
+	Period        int                    `json:"period"`         // Days of history, default 365
+}
+
+// ScanRow is one matching symbol
+type ScanRow struct {
+	Symbol string                `json:"symbol"`
+	Date   time.Time             `json:"date"` // Latest bar
+	Price  float64               `json:"price"`
+	Signal *models.TradingSignal `json:"signal,omitempty"`
+	Values map[string]float64    `json:"values,omitempty"` // Column values; columns not computable yet are omitted
+}
+
+// ScanFailure records a symbol that could not be evaluated
+type ScanFailure struct {
+	Symbol string `json:"symbol"`
+	Error  string `json:"error"`
+}
+
+// ScanResult is the ranked outcome of a scan
+type ScanResult struct {
+	Scanned  int           `json:"scanned"`
+	Matched  int           `json:"matched"` // Before the limit
+	Results  []ScanRow     `json:"results"`
+	Failed   []ScanFailure `json:"failed,omitempty"`
+	Duration string        `json:"duration"`
+}
+
+// ScannerService evaluates conditions and algorithms across many symbols
+type ScannerService struct {
+	marketDataService *MarketDataService
+	analysisService   *AnalysisService
+	workers           int
+	logger            *logrus.Logger
+}
+
+// NewScannerService creates a scanner that evaluates up to `workers`
+// symbols at a time
+func NewScannerService(marketDataService *MarketDataService, analysisService *AnalysisService, workers int, logger *logrus.Logger) *ScannerService {
+	if workers < 1 {
+		workers = 1
+	}
+	return &ScannerService{
+		marketDataService: marketDataService,
+		analysisService:   analysisService,
+		workers:           workers,
+		logger:            logger,
+	}
+}
+
+// preparedScan holds a validated scan configuration
+type preparedScan struct {
+	config      ScanConfig
+	symbols     []string
+	algorithm   algorithms.TradingAlgorithm
+	signalTypes map[string]bool
+	condition   *expression.Expression
+	columns     map[string]*expression.Expression
+}
+
+// prepare applies defaults and validates the configuration. Invalid
+// expressions return an *expression.Error and invalid algorithm parameters
+// an *algorithms.ParameterError.
+func (s *ScannerService) prepare(cfg ScanConfig) (*preparedScan, error) {
+	if cfg.Period <= 0 {
+		cfg.Period = 365
+	}
+	if cfg.Limit <= 0 {
+		cfg.Limit = 50
+	}
+	if cfg.MinConfidence < 0 || cfg.MinConfidence > 1 {
+		return nil, fmt.Errorf("min_confidence must be between 0 and 1")
+	}
+
+	scan := &preparedScan{columns: make(map[string]*expression.Expression, len(cfg.Columns))}
+
+	seen := make(map[string]bool)
+	for _, symbol := range cfg.Symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol != "" && !seen[symbol] {
+			seen[symbol] = true
+			scan.symbols = append(scan.symbols, symbol)
+		}
+	}
+	if len(cfg.Symbols) == 0 {
+		for _, info := range s.marketDataService.GetSupportedSymbols() {
+			scan.symbols = append(scan.symbols, info.Symbol)
+		}
+	}
+	if len(scan.symbols) == 0 {
+		return nil, fmt.Errorf("no symbols to scan")
+	}
+	if len(scan.symbols) > maxScanSymbols {
+		return nil, fmt.Errorf("at most %d symbols can be scanned at once", maxScanSymbols)
+	}
+
+	if cfg.Algorithm != "" {
+		algorithm, err := s.analysisService.AlgorithmManager().GetAlgorithmWithParameters(cfg.Algorithm, cfg.Parameters)
+		if err != nil {
+			return nil, err
+		}
+		scan.algorithm = algorithm
+	} else if len(cfg.Parameters) > 0 || len(cfg.SignalTypes) > 0 || cfg.MinConfidence > 0 {
+		return nil, fmt.Errorf("parameters, signal_types and min_confidence need an algorithm")
+	}
+
+	if len(cfg.SignalTypes) > 0 {
+		scan.signalTypes = make(map[string]bool, len(cfg.SignalTypes))
+		for _, signalType := range cfg.SignalTypes {
+			signalType = strings.ToUpper(signalType)
+			if signalType != "BUY" && signalType != "SELL" && signalType != "HOLD" {
+				return nil, fmt.Errorf("unknown signal type %q (expected BUY, SELL or HOLD)", signalType)
+			}
+			scan.signalTypes[signalType] = true
+		}
+	}
+
+	if cfg.Condition != "" {
+		condition, err := expression.Parse(cfg.Condition)
+		if err != nil {
+			return nil, err
+		}
+		if condition.Type() != expression.TypeBool {
+			return nil, fmt.Errorf("condition must be a true/false expression, e.g. RSI(close, 14) < 40")
+		}
+		scan.condition = condition
+	}
+
+	for name, src := range cfg.Columns {
+		switch name {
+		case "", ScanSortSymbol, ScanSortPrice, ScanSortConfidence, ScanSortStrength:
+			return nil, fmt.Errorf("invalid column name %q", name)
+		}
+		column, err := expression.Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		scan.columns[name] = column
+	}
+
+	if cfg.SortBy == "" {
+		cfg.SortBy = ScanSortSymbol
+		if scan.algorithm != nil {
+			cfg.SortBy = ScanSortConfidence
+		}
+	}
+	switch cfg.SortBy {
+	case ScanSortSymbol, ScanSortPrice:
+	case ScanSortConfidence, ScanSortStrength:
+		if scan.algorithm == nil {
+			return nil, fmt.Errorf("sorting by %s needs an algorithm", cfg.SortBy)
+		}
+	default:
+		if scan.columns[cfg.SortBy] == nil {
+			return nil, fmt.Errorf("sort_by %q is not a column or one of symbol, price, confidence, strength", cfg.SortBy)
+		}
+	}
+
+	switch cfg.Order {
+	case "":
+		cfg.Order = "desc"
+		if cfg.SortBy == ScanSortSymbol {
+			cfg.Order = "asc"
+		}
+	case "asc", "desc":
+	default:
+		return nil, fmt.Errorf("order must be asc or desc")
+	}
+
+	scan.config = cfg
+	return scan, nil
+}
+
+// Scan evaluates every symbol on a bounded worker pool and returns the
+// ranked matches. Errors are returned only for an invalid configuration;
+// symbols that can't be evaluated are listed in the result.
+func (s *ScannerService) Scan(ctx context.Context, cfg ScanConfig) (*ScanResult, error) {
+	scan, err := s.prepare(cfg)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	jobs := make(chan string)
+	var mu sync.Mutex
+	result := &ScanResult{}
+	var rows []ScanRow
+
+	workers := s.workers
+	if workers > len(scan.symbols) {
+		workers = len(scan.symbols)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for symbol := range jobs {
+				row, matched, err := s.scanSymbolSafely(scan, symbol)
+
+				mu.Lock()
+				result.Scanned++
+				if err != nil {
+					result.Failed = append(result.Failed, ScanFailure{Symbol: symbol, Error: err.Error()})
+				} else if matched {
+					rows = append(rows, *row)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, symbol := range scan.symbols {
+		select {
+		case jobs <- symbol:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sortScanRows(rows, scan.config.SortBy, scan.config.Order == "desc")
+	sort.Slice(result.Failed, func(i, j int) bool { return result.Failed[i].Symbol < result.Failed[j].Symbol })
+
+	result.Matched = len(rows)
+	if len(rows) > scan.config.Limit {
+		rows = rows[:scan.config.Limit]
+	}
+	result.Results = rows
+	if result.Results == nil {
+		result.Results = []ScanRow{}
+	}
+	result.Duration = time.Since(start).String()
+
+	s.logger.WithFields(logrus.Fields{
+		"scanned":  result.Scanned,
+		"matched":  result.Matched,
+		"failed":   len(result.Failed),
+		"duration": result.Duration,
+	}).Info("Scan completed")
+
+	return result, nil
+}
+
+// scanSymbolSafely runs scanSymbol, turning a panic into an error for that
+// symbol. Workers run outside the handler goroutine, so an unrecovered
+// panic would take down the whole service.
+func (s *ScannerService) scanSymbolSafely(scan *preparedScan, symbol string) (row *ScanRow, matched bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.WithField("symbol", symbol).Errorf("Scan panicked: %v", r)
+			row, matched, err = nil, false, fmt.Errorf("internal error evaluating symbol: %v", r)
+		}
+	}()
+	return s.scanSymbol(scan, symbol)
+}
+
+// scanSymbol evaluates one symbol, returning its row and whether it matched
+func (s *ScannerService) scanSymbol(scan *preparedScan, symbol string) (*ScanRow, bool, error) {
+	to := time.Now()
+	from := to.AddDate(0, 0, -scan.config.Period)
+	data, err := s.marketDataService.GetHistoricalData(symbol, from, to)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) == 0 {
+		return nil, false, fmt.Errorf("no price history")
+	}
+
+	last := data[len(data)-1]
+	price, _ := last.Close.Float64()
+	row := &ScanRow{Symbol: symbol, Date: last.Date, Price: price}
+
+	if scan.condition != nil {
+		result, err := scan.condition.Evaluate(data)
+		if err != nil {
+			return nil, false, err
+		}
+		if !result.Bool(result.Len() - 1) {
+			return nil, false, nil
+		}
+	}
+
+	if scan.algorithm != nil {
+		signal, err := scan.algorithm.Clone().Analyze(data)
+		if err != nil {
+			return nil, false, err
+		}
+		confidence, _ := signal.Confidence.Float64()
+		if scan.signalTypes != nil && !scan.signalTypes[signal.Type] || confidence < scan.config.MinConfidence {
+			return nil, false, nil
+		}
+		row.Signal = signal
+	}
+
+	if len(scan.columns) > 0 {
+		row.Values = make(map[string]float64, len(scan.columns))
+		for name, column := range scan.columns {
+			result, err := column.Evaluate(data)
+			if err != nil {
+				return nil, false, err
+			}
+			if value, ok := result.Last(); ok {
+				row.Values[name] = value
+			}
+		}
+	}
+	return row, true, nil
+}
+
+// sortScanRows orders rows by the key; rows without a value for it go last
+func sortScanRows(rows []ScanRow, sortBy string, descending bool) {
+	key := func(row ScanRow) (float64, bool) {
+		switch sortBy {
+		case ScanSortPrice:
+			return row.Price, true
+		case ScanSortConfidence:
+			value, _ := row.Signal.Confidence.Float64()
+			return value, true
+		case ScanSortStrength:
+			value, _ := row.Signal.Strength.Float64()
+			return value, true
+		}
+		value, ok := row.Values[sortBy]
+		return value, ok && !math.IsNaN(value)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if sortBy == ScanSortSymbol {
+			if descending {
+				return rows[i].Symbol > rows[j].Symbol
+			}
+			return rows[i].Symbol < rows[j].Symbol
+		}
+
+		a, okA := key(rows[i])
+		b, okB := key(rows[j])
+		switch {
+		case okA != okB:
+			return okA
+		case !okA || a == b:
+			return rows[i].Symbol < rows[j].Symbol
+		case descending:
+			return a > b
+		default:
+			return a < b
+		}
+	})
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/models"
+	"trading-service/internal/providers"
+)
+
+// fixtureProvider serves fixed closing prices per symbol, ending on the
+// requested day. Bars before a symbol's listing date are dropped, and
+// symbols listed in panics panic instead of returning data.
+type fixtureProvider struct {
+	closes map[string][]float64
+	listed map[string]time.Time
+	panics map[string]bool
+	delay  time.Duration
+
+	mu      sync.Mutex
+	active  int
+	maxSeen int
+}
+
+func (p *fixtureProvider) GetRealtimeData(symbol string) (*models.MarketData, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (p *fixtureProvider) GetHistoricalData(symbol string, from, to time.Time) ([]models.HistoricalData, error) {
+	p.mu.Lock()
+	p.active++
+	if p.active > p.maxSeen {
+		p.maxSeen = p.active
+	}
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.active--
+		p.mu.Unlock()
+	}()
+	time.Sleep(p.delay)
+
+	if p.panics[symbol] {
+		panic("runtime error: index out of range")
+	}
+	closes, ok := p.closes[symbol]
+	if !ok {
+		return nil, fmt.Errorf("unknown symbol %s", symbol)
+	}
+	data := make([]models.HistoricalData, 0, len(closes))
+	for i, c := range closes {
+		date := to.AddDate(0, 0, i-len(closes)+1)
+		if date.Before(p.listed[symbol]) {
+			continue
+		}
+		price := decimal.NewFromFloat(c)
+		data = append(data, models.HistoricalData{
+			Symbol: symbol,
+			Date:   date,
+			Open:   price,
+			High:   price.Add(decimal.NewFromInt(1)),
+			Low:    price.Sub(decimal.NewFromInt(1)),
+			Close:  price,
+			Volume: 1000,
+		})
+	}
+	return data, nil
+}
+
+func (p *fixtureProvider) GetIntradayData(symbol string, interval string) ([]models.HistoricalData, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (p *fixtureProvider) IsReady() bool           { return true }
+func (p *fixtureProvider) GetProviderName() string { return "fixture" }
+
+// linearCloses returns n closes moving from start by step each bar
+func linearCloses(start, step float64, n int) []float64 {
+	closes := make([]float64, n)
+	for i := range closes {
+		closes[i] = start + step*float64(i)
+	}
+	return closes
+}
+
+func newTestScanner(provider *fixtureProvider, workers int) *ScannerService {
+	marketData := NewMarketDataService(providers.NewMarketDataAggregator([]providers.MarketDataProvider{provider}), testLogger())
+	return NewScannerService(marketData, NewAnalysisService(testLogger()), workers, testLogger())
+}
+
+func TestScannerPrepare(t *testing.T) {
+	scanner := newTestScanner(&fixtureProvider{}, 2)
+	tooMany := make([]string, maxScanSymbols+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("S%d", i)
+	}
+
+	tests := []struct {
+		name  string
+		cfg   ScanConfig
+		valid bool
+	}{
+		{"defaults", ScanConfig{}, true},
+		{"condition and columns", ScanConfig{Condition: "RSI(close, 14) < 40", Columns: map[string]string{"rsi": "RSI(close, 14)"}, SortBy: "rsi"}, true},
+		{"algorithm", ScanConfig{Algorithm: "momentum", SignalTypes: []string{"buy"}, MinConfidence: 0.5}, true},
+		{"too many symbols", ScanConfig{Symbols: tooMany}, false},
+		{"blank symbols", ScanConfig{Symbols: []string{" ", ""}}, false},
+		{"min confidence above 1", ScanConfig{Algorithm: "momentum", MinConfidence: 1.5}, false},
+		{"signal types without algorithm", ScanConfig{SignalTypes: []string{"BUY"}}, false},
+		{"unknown signal type", ScanConfig{Algorithm: "momentum", SignalTypes: []string{"HODL"}}, false},
+		{"unknown algorithm", ScanConfig{Algorithm: "astrology"}, false},
+		{"unknown parameter", ScanConfig{Algorithm: "momentum", Parameters: map[string]interface{}{"nope": 1}}, false},
+		{"numeric condition", ScanConfig{Condition: "close"}, false},
+		{"invalid condition", ScanConfig{Condition: "close >"}, false},
+		{"oversized period", ScanConfig{Condition: "CHANGE(close, 9223372036854775807) > 0"}, false},
+		{"reserved column", ScanConfig{Columns: map[string]string{"price": "close"}}, false},
+		{"confidence without algorithm", ScanConfig{SortBy: ScanSortConfidence}, false},
+		{"unknown sort", ScanConfig{SortBy: "rsi"}, false},
+		{"bad order", ScanConfig{Order: "up"}, false},
+	}
+	for _, tt := range tests {
+		if _, err := scanner.prepare(tt.cfg); (err == nil) != tt.valid {
+			t.Fatalf("%s: error = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+
+	scan, err := scanner.prepare(ScanConfig{Symbols: []string{" aapl", "AAPL", "msft"}, Algorithm: "momentum"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(scan.symbols, ",") != "AAPL,MSFT" {
+		t.Fatalf("symbols = %v, want AAPL and MSFT", scan.symbols)
+	}
+	if scan.config.Period != 365 || scan.config.Limit != 50 || scan.config.SortBy != ScanSortConfidence || scan.config.Order != "desc" {
+		t.Fatalf("defaults = %+v, want period 365, limit 50, confidence desc", scan.config)
+	}
+}
+
+func TestScannerFiltersAndRanks(t *testing.T) {
+	provider := &fixtureProvider{closes: map[string][]float64{
+		"UP":   linearCloses(100, 1, 30),
+		"FAST": linearCloses(100, 2, 30),
+		"DOWN": linearCloses(130, -1, 30),
+		"FLAT": linearCloses(50, 0, 30),
+	}}
+	scanner := newTestScanner(provider, 2)
+
+	result, err := scanner.Scan(context.Background(), ScanConfig{
+		Symbols:   []string{"UP", "FAST", "DOWN", "FLAT", "GONE"},
+		Condition: "close > 60",
+		Columns:   map[string]string{"chg": "CHANGE(close, 5)", "late": "REF(close, 40)"},
+		SortBy:    "chg",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Scanned != 5 || result.Matched != 3 {
+		t.Fatalf("scanned %d matched %d, want 5 and 3", result.Scanned, result.Matched)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].Symbol != "GONE" {
+		t.Fatalf("failed = %+v, want GONE", result.Failed)
+	}
+
+	var order []string
+	for _, row := range result.Results {
+		order = append(order, row.Symbol)
+		if _, ok := row.Values["late"]; ok {
+			t.Fatalf("%s reports a column that isn't computable yet", row.Symbol)
+		}
+	}
+	if strings.Join(order, ",") != "FAST,UP,DOWN" {
+		t.Fatalf("order = %v, want FAST, UP, DOWN by change descending", order)
+	}
+	if result.Results[0].Values["chg"] != 10 || result.Results[2].Price != 101 {
+		t.Fatalf("rows = %+v, want FAST change 10 and DOWN price 101", result.Results)
+	}
+
+	limited, err := scanner.Scan(context.Background(), ScanConfig{Symbols: []string{"UP", "FAST", "DOWN"}, SortBy: ScanSortPrice, Order: "asc", Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limited.Matched != 3 || len(limited.Results) != 1 || limited.Results[0].Symbol != "DOWN" {
+		t.Fatalf("limited = %+v, want DOWN only of 3 matches", limited)
+	}
+}
+
+func TestScannerRecoversWorkerPanics(t *testing.T) {
+	provider := &fixtureProvider{
+		closes: map[string][]float64{"OK": linearCloses(100, 1, 10)},
+		panics: map[string]bool{"BOOM": true},
+	}
+	scanner := newTestScanner(provider, 2)
+
+	result, err := scanner.Scan(context.Background(), ScanConfig{Symbols: []string{"BOOM", "OK"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Symbol != "OK" {
+		t.Fatalf("results = %+v, want OK", result.Results)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].Symbol != "BOOM" || !strings.Contains(result.Failed[0].Error, "internal error") {
+		t.Fatalf("failed = %+v, want the BOOM panic recorded", result.Failed)
+	}
+}
+
+func TestScannerWorkerPool(t *testing.T) {
+	provider := &fixtureProvider{closes: map[string][]float64{}, delay: 2 * time.Millisecond}
+	var symbols []string
+	for i := 0; i < 40; i++ {
+		symbol := fmt.Sprintf("S%02d", i)
+		symbols = append(symbols, symbol)
+		provider.closes[symbol] = linearCloses(float64(100+i), 0, 5)
+	}
+	scanner := newTestScanner(provider, 3)
+
+	result, err := scanner.Scan(context.Background(), ScanConfig{Symbols: symbols, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Scanned != 40 || len(result.Results) != 40 || len(result.Failed) != 0 {
+		t.Fatalf("scanned %d, %d results, %d failures, want all 40 matched", result.Scanned, len(result.Results), len(result.Failed))
+	}
+	if provider.maxSeen > 3 {
+		t.Fatalf("%d symbols evaluated at once, want at most 3", provider.maxSeen)
+	}
+	if result.Results[0].Symbol != "S00" || result.Results[39].Symbol != "S39" {
+		t.Fatalf("results not sorted by symbol: first %s last %s", result.Results[0].Symbol, result.Results[39].Symbol)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := scanner.Scan(ctx, ScanConfig{Symbols: symbols}); err != context.Canceled {
+		t.Fatalf("cancelled scan error = %v, want context.Canceled", err)
+	}
+}
+
+func TestSortScanRows(t *testing.T) {
+	signal := func(confidence float64) *models.TradingSignal {
+		return &models.TradingSignal{Confidence: decimal.NewFromFloat(confidence), Strength: decimal.NewFromFloat(1 - confidence)}
+	}
+	rows := func() []ScanRow {
+		return []ScanRow{
+			{Symbol: "B", Price: 20, Signal: signal(0.2), Values: map[string]float64{"x": 1}},
+			{Symbol: "A", Price: 30, Signal: signal(0.9)},
+			{Symbol: "C", Price: 10, Signal: signal(0.5), Values: map[string]float64{"x": 3}},
+			{Symbol: "D", Price: 20, Signal: signal(0.5), Values: map[string]float64{"x": 1}},
+		}
+	}
+	symbols := func(rows []ScanRow) string {
+		var s []string
+		for _, row := range rows {
+			s = append(s, row.Symbol)
+		}
+		return strings.Join(s, ",")
+	}
+
+	tests := []struct {
+		sortBy     string
+		descending bool
+		want       string
+	}{
+		{ScanSortSymbol, false, "A,B,C,D"},
+		{ScanSortSymbol, true, "D,C,B,A"},
+		{ScanSortPrice, false, "C,B,D,A"},
+		{ScanSortConfidence, true, "A,C,D,B"},
+		{ScanSortStrength, true, "B,C,D,A"},
+		{"x", true, "C,B,D,A"},
+		{"x", false, "B,D,C,A"},
+	}
+	for _, tt := range tests {
+		sorted := rows()
+		sortScanRows(sorted, tt.sortBy, tt.descending)
+		if got := symbols(sorted); got != tt.want {
+			t.Fatalf("sort by %s descending %v = %s, want %s", tt.sortBy, tt.descending, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,551 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/models"
+)
+
+// Signal outcomes
+const (
+	OutcomePending   = "PENDING"
+	OutcomeTargetHit = "TARGET_HIT"
+	OutcomeStopHit   = "STOP_HIT"
+	OutcomeExpired   = "EXPIRED"
+)
+
+// maxSignalRecords caps the history; the oldest signals are dropped first
+const maxSignalRecords = 10000
+
+// defaultSignalLifetime applies to signals without an expiration time
+const defaultSignalLifetime = 24 * time.Hour
+
+// defaultEvaluationInterval is used by Run when it is given no positive
+// interval, such as SIGNAL_EVALUATION_INTERVAL=0
+const defaultEvaluationInterval = 5 * time.Minute
+
+// intradayEvaluationSpan is the longest signal lifetime evaluated against
+// intraday bars; longer-lived signals use daily bars
+const intradayEvaluationSpan = 48 * time.Hour
+
+// SignalRecord is a stored signal and, once resolved, its outcome.
+// Returns are direction-adjusted fractions: positive means the call was
+// right. HOLD signals resolve as EXPIRED with the undirected price change.
+type SignalRecord struct {
+	ID           string                `json:"id"`
+	AlgorithmID  string                `json:"algorithm_id"` // Name the algorithm was requested by
+	Signal       *models.TradingSignal `json:"signal"`
+	Outcome      string                `json:"outcome"`
+	ExitPrice    float64               `json:"exit_price,omitempty"`
+	ExitTime     *time.Time            `json:"exit_time,omitempty"`
+	Return       float64               `json:"return"`
+	MaxFavorable float64               `json:"max_favorable"` // Best excursion in the signal's direction
+	MaxAdverse   float64               `json:"max_adverse"`   // Worst excursion against it, as a negative fraction
+	LastPrice    float64               `json:"last_price,omitempty"`
+	LastSeen     *time.Time            `json:"last_seen,omitempty"`
+	EvaluatedAt  *time.Time            `json:"evaluated_at,omitempty"`
+}
+
+// SignalFilter selects history records; zero fields match everything
+type SignalFilter struct {
+	Symbol    string
+	Algorithm string // Algorithm ID as requested, e.g. momentum
+	Outcome   string
+	Since     time.Time
+	Limit     int
+}
+
+func (f SignalFilter) matches(record *SignalRecord) bool {
+	return (f.Symbol == "" || record.Signal.Symbol == f.Symbol) &&
+		(f.Algorithm == "" || record.AlgorithmID == f.Algorithm) &&
+		(f.Outcome == "" || record.Outcome == f.Outcome) &&
+		(f.Since.IsZero() || !record.Signal.CreatedAt.Before(f.Since))
+}
+
+// SignalHistoryService stores generated signals and resolves them against
+// later prices. With a path set, records are appended to a JSON Lines file
+// and replayed on startup; an updated record is appended again and the
+// latest line for an ID wins.
+type SignalHistoryService struct {
+	marketDataService *MarketDataService
+	logger            *logrus.Logger
+
+	mu      sync.RWMutex
+	records []*SignalRecord // Oldest first
+	byID    map[string]*SignalRecord
+	seq     uint64
+
+	path  string
+	file  *os.File
+	lines int // Lines in the file, to decide when to compact
+}
+
+// NewSignalHistoryService creates an in-memory signal history
+func NewSignalHistoryService(marketDataService *MarketDataService, logger *logrus.Logger) *SignalHistoryService {
+	return &SignalHistoryService{
+		marketDataService: marketDataService,
+		logger:            logger,
+		byID:              make(map[string]*SignalRecord),
+	}
+}
+
+// Open loads the history file at path, creating it if missing, and
+// persists every later change to it
+func (s *SignalHistoryService) Open(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create signal history directory: %v", err)
+		}
+	}
+
+	lines, err := s.load(path)
+	if err != nil {
+		return err
+	}
+	s.path = path
+
+	// Rewrite the file when superseded lines dominate it
+	if lines > 2*len(s.records) || lines > len(s.records)+maxSignalRecords {
+		return s.compact()
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open signal history: %v", err)
+	}
+	s.file, s.lines = file, lines
+	return nil
+}
+
+// load replays the history file into memory, returning its line count
+func (s *SignalHistoryService) load(path string) (int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read signal history: %v", err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		lines++
+		var record SignalRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.ID == "" || record.Signal == nil {
+			s.logger.WithField("line", lines).Warn("Skipping invalid signal history line")
+			continue
+		}
+		s.put(&record)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read signal history: %v", err)
+	}
+	s.prune()
+	return lines, nil
+}
+
+// compact rewrites the file with one line per record, replacing it
+// atomically. Callers hold s.mu.
+func (s *SignalHistoryService) compact() error {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+
+	tmp := s.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compact signal history: %v", err)
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, record := range s.records {
+		if err := encoder.Encode(record); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to compact signal history: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to compact signal history: %v", err)
+	}
+	file.Close()
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to compact signal history: %v", err)
+	}
+
+	s.file, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open signal history: %v", err)
+	}
+	s.lines = len(s.records)
+	return nil
+}
+
+// persist appends a record to the file. Callers hold s.mu.
+func (s *SignalHistoryService) persist(record *SignalRecord) {
+	if s.file == nil {
+		return
+	}
+	data, err := json.Marshal(record)
+	if err == nil {
+		_, err = s.file.Write(append(data, '\n'))
+	}
+	if err != nil {
+		s.logger.WithError(err).WithField("signal_id", record.ID).Warn("Failed to persist signal record")
+		return
+	}
+
+	s.lines++
+	if s.lines > 2*len(s.records)+1000 {
+		if err := s.compact(); err != nil {
+			s.logger.WithError(err).Warn("Failed to compact signal history")
+		}
+	}
+}
+
+// put inserts or replaces a record. Callers hold s.mu.
+func (s *SignalHistoryService) put(record *SignalRecord) {
+	if existing, ok := s.byID[record.ID]; ok {
+		*existing = *record
+		return
+	}
+	s.records = append(s.records, record)
+	s.byID[record.ID] = record
+}
+
+// prune drops the oldest records beyond maxSignalRecords. Callers hold s.mu.
+func (s *SignalHistoryService) prune() {
+	excess := len(s.records) - maxSignalRecords
+	if excess <= 0 {
+		return
+	}
+	for _, record := range s.records[:excess] {
+		delete(s.byID, record.ID)
+	}
+	s.records = append([]*SignalRecord(nil), s.records[excess:]...)
+}
+
+// Close flushes and closes the history file
+func (s *SignalHistoryService) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Record stores signals keyed by the algorithm ID they were requested by,
+// assigning each an ID. The IDs are written back to the given signals.
+func (s *SignalHistoryService) Record(signals map[string]*models.TradingSignal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Store in a stable order so IDs follow algorithm names
+	algorithmIDs := make([]string, 0, len(signals))
+	for algorithmID := range signals {
+		algorithmIDs = append(algorithmIDs, algorithmID)
+	}
+	sort.Strings(algorithmIDs)
+
+	for _, algorithmID := range algorithmIDs {
+		signal := signals[algorithmID]
+		if signal == nil {
+			continue
+		}
+		s.seq++
+		if signal.ID == "" {
+			signal.ID = fmt.Sprintf("sig_%d_%d", time.Now().UnixNano(), s.seq)
+		}
+		stored := *signal
+		record := &SignalRecord{
+			ID:          signal.ID,
+			AlgorithmID: algorithmID,
+			Signal:      &stored,
+			Outcome:     OutcomePending,
+		}
+		s.put(record)
+		s.persist(record)
+	}
+	s.prune()
+}
+
+// History returns matching records, newest first
+func (s *SignalHistoryService) History(filter SignalFilter) []*SignalRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var records []*SignalRecord
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(records) >= filter.Limit {
+			break
+		}
+		if filter.matches(s.records[i]) {
+			record := *s.records[i]
+			records = append(records, &record)
+		}
+	}
+	return records
+}
+
+// GetRecord returns a record by signal ID
+func (s *SignalHistoryService) GetRecord(id string) (*SignalRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("signal '%s' not found", id)
+	}
+	copied := *record
+	return &copied, nil
+}
+
+// Run evaluates pending signals every interval until the context ends. A
+// non-positive interval falls back to defaultEvaluationInterval.
+func (s *SignalHistoryService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		s.logger.WithFields(logrus.Fields{
+			"interval": interval,
+			"default":  defaultEvaluationInterval,
+		}).Warn("Signal evaluation interval must be positive; using the default")
+		interval = defaultEvaluationInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if resolved := s.EvaluatePending(time.Now()); resolved > 0 {
+				s.logger.WithField("resolved", resolved).Info("Signal outcomes evaluated")
+			}
+		}
+	}
+}
+
+// EvaluatePending checks pending signals against prices since they were
+// generated and returns how many were resolved
+func (s *SignalHistoryService) EvaluatePending(now time.Time) int {
+	s.mu.RLock()
+	pendingBySymbol := make(map[string][]SignalRecord)
+	for _, record := range s.records {
+		if record.Outcome == OutcomePending {
+			pendingBySymbol[record.Signal.Symbol] = append(pendingBySymbol[record.Signal.Symbol], *record)
+		}
+	}
+	s.mu.RUnlock()
+
+	resolved := 0
+	for symbol, pending := range pendingBySymbol {
+		bars, quote := s.evaluationPrices(symbol, pending, now)
+
+		s.mu.Lock()
+		for i := range pending {
+			updated := evaluateSignal(pending[i], bars, quote, now)
+			current, ok := s.byID[updated.ID]
+			if !ok || current.Outcome != OutcomePending {
+				continue // Pruned or resolved meanwhile
+			}
+			*current = updated
+			// Pending records only gain the last price seen; that is cheap to
+			// rebuild, so only resolutions are written
+			if updated.Outcome != OutcomePending {
+				s.persist(current)
+				resolved++
+			}
+		}
+		s.mu.Unlock()
+	}
+	return resolved
+}
+
+// evaluationPrices fetches bars covering the pending signals of a symbol
+// and the current quote. Intraday bars are used when every signal is
+// short-lived and the provider has them.
+func (s *SignalHistoryService) evaluationPrices(symbol string, pending []SignalRecord, now time.Time) ([]models.HistoricalData, *models.MarketData) {
+	earliest := now
+	shortLived := true
+	for _, record := range pending {
+		if record.Signal.CreatedAt.Before(earliest) {
+			earliest = record.Signal.CreatedAt
+		}
+		if signalExpiry(record.Signal).Sub(record.Signal.CreatedAt) > intradayEvaluationSpan {
+			shortLived = false
+		}
+	}
+
+	var bars []models.HistoricalData
+	if shortLived {
+		intraday, err := s.marketDataService.GetIntradayData(symbol, "5m")
+		if err == nil && len(intraday) > 0 && !intraday[0].Date.After(earliest) {
+			bars = intraday
+		}
+	}
+	if bars == nil {
+		daily, err := s.marketDataService.GetHistoricalData(symbol, earliest.AddDate(0, 0, -1), now)
+		if err != nil {
+			s.logger.WithError(err).WithField("symbol", symbol).Warn("Failed to get prices for signal evaluation")
+		}
+		bars = daily
+	}
+
+	quote, err := s.marketDataService.GetRealTimeData(symbol)
+	if err != nil {
+		quote = nil
+	}
+	return bars, quote
+}
+
+// signalExpiry is the signal's expiration, defaulting to a day after creation
+func signalExpiry(signal *models.TradingSignal) time.Time {
+	if signal.ExpirationTime.IsZero() || !signal.ExpirationTime.After(signal.CreatedAt) {
+		return signal.CreatedAt.Add(defaultSignalLifetime)
+	}
+	return signal.ExpirationTime
+}
+
+// evaluateSignal walks the bars after the signal was generated, then the
+// current quote, looking for the stop or target before expiry. A bar that
+// spans both levels counts as a stop, and one that opens beyond a level
+// fills at its open. Once expired unresolved, the signal
+// exits at the last price seen before expiry, or the first one after it
+// if nothing was seen in time.
+func evaluateSignal(record SignalRecord, bars []models.HistoricalData, quote *models.MarketData, now time.Time) SignalRecord {
+	signal := record.Signal
+	entry, _ := signal.Price.Float64()
+	if entry <= 0 {
+		return record
+	}
+	target, _ := signal.TargetPrice.Float64()
+	stop, _ := signal.StopLoss.Float64()
+	expiry := signalExpiry(signal)
+
+	direction := 0.0
+	switch signal.Type {
+	case "BUY":
+		direction = 1
+	case "SELL":
+		direction = -1
+	}
+
+	resolve := func(outcome string, price float64, at time.Time) SignalRecord {
+		record.Outcome = outcome
+		record.ExitPrice = price
+		record.ExitTime = &at
+		record.Return = price/entry - 1
+		if direction != 0 {
+			record.Return *= direction
+		}
+		record.EvaluatedAt = &now
+		return record
+	}
+
+	// excursion updates MFE/MAE and reports a stop or target hit
+	excursion := func(high, low float64) string {
+		if direction == 0 {
+			return ""
+		}
+		favorable, adverse := (high/entry-1)*direction, (low/entry-1)*direction
+		if direction < 0 {
+			favorable, adverse = adverse, favorable
+		}
+		if favorable > record.MaxFavorable {
+			record.MaxFavorable = favorable
+		}
+		if adverse < record.MaxAdverse {
+			record.MaxAdverse = adverse
+		}
+
+		switch {
+		case stop > 0 && direction > 0 && low <= stop, stop > 0 && direction < 0 && high >= stop:
+			return OutcomeStopHit
+		case target > 0 && direction > 0 && high >= target, target > 0 && direction < 0 && low <= target:
+			return OutcomeTargetHit
+		}
+		return ""
+	}
+
+	var firstAfterExpiry *models.HistoricalData
+	for i := range bars {
+		bar := bars[i]
+		if !bar.Date.After(signal.CreatedAt) {
+			continue
+		}
+		if bar.Date.After(expiry) {
+			if firstAfterExpiry == nil {
+				firstAfterExpiry = &bars[i]
+			}
+			continue
+		}
+
+		high, _ := bar.High.Float64()
+		low, _ := bar.Low.Float64()
+		open, _ := bar.Open.Float64()
+		closePrice, _ := bar.Close.Float64()
+		switch excursion(high, low) {
+		case OutcomeStopHit:
+			return resolve(OutcomeStopHit, gapFill(open, stop, -direction), bar.Date)
+		case OutcomeTargetHit:
+			return resolve(OutcomeTargetHit, gapFill(open, target, direction), bar.Date)
+		}
+		if record.LastSeen == nil || bar.Date.After(*record.LastSeen) {
+			seen := bar.Date
+			record.LastPrice, record.LastSeen = closePrice, &seen
+		}
+	}
+
+	if quote != nil && !now.After(expiry) {
+		price, _ := quote.Price.Float64()
+		if price > 0 {
+			switch excursion(price, price) {
+			case OutcomeStopHit:
+				return resolve(OutcomeStopHit, price, now)
+			case OutcomeTargetHit:
+				return resolve(OutcomeTargetHit, price, now)
+			}
+			record.LastPrice, record.LastSeen = price, &now
+		}
+	}
+
+	if now.After(expiry) {
+		if record.LastSeen != nil {
+			return resolve(OutcomeExpired, record.LastPrice, *record.LastSeen)
+		}
+		if firstAfterExpiry != nil {
+			open, _ := firstAfterExpiry.Open.Float64()
+			return resolve(OutcomeExpired, open, firstAfterExpiry.Date)
+		}
+	}
+	return record
+}
+
+// gapFill is the fill for a level crossed in the given direction (1 up,
+// -1 down): the level itself, or the open if the bar opened beyond it
+func gapFill(open, level, direction float64) float64 {
+	if open > 0 && (open-level)*direction > 0 {
+		return open
+	}
+	return level
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"trading-service/internal/models"
+	"trading-service/internal/providers"
+)
+
+func TestSignalHistoryRunNonPositiveInterval(t *testing.T) {
+	s := NewSignalHistoryService(nil, testLogger())
+	for _, interval := range []time.Duration{0, -time.Second} {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			s.Run(ctx, interval)
+		}()
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("Run(%v) did not stop when its context ended", interval)
+		}
+	}
+}
+
+var signalStart = time.Date(2024, 3, 4, 15, 0, 0, 0, time.UTC)
+
+// testSignal is created at signalStart and expires five days later
+func testSignal(signalType string, entry, target, stop float64) *models.TradingSignal {
+	return &models.TradingSignal{
+		ID:             "sig_1",
+		Symbol:         "TEST",
+		Type:           signalType,
+		Price:          decimal.NewFromFloat(entry),
+		TargetPrice:    decimal.NewFromFloat(target),
+		StopLoss:       decimal.NewFromFloat(stop),
+		Confidence:     decimal.NewFromFloat(0.7),
+		CreatedAt:      signalStart,
+		ExpirationTime: signalStart.AddDate(0, 0, 5),
+	}
+}
+
+// ohlc is a bar the given number of days after signalStart
+func ohlc(day int, open, high, low, close float64) models.HistoricalData {
+	return models.HistoricalData{
+		Date:  signalStart.AddDate(0, 0, day),
+		Open:  decimal.NewFromFloat(open),
+		High:  decimal.NewFromFloat(high),
+		Low:   decimal.NewFromFloat(low),
+		Close: decimal.NewFromFloat(close),
+	}
+}
+
+func TestEvaluateSignal(t *testing.T) {
+	afterExpiry := signalStart.AddDate(0, 0, 7)
+	beforeExpiry := signalStart.AddDate(0, 0, 3)
+	quote := func(price float64) *models.MarketData {
+		return &models.MarketData{Price: decimal.NewFromFloat(price)}
+	}
+
+	tests := []struct {
+		name    string
+		signal  *models.TradingSignal
+		bars    []models.HistoricalData
+		quote   *models.MarketData
+		now     time.Time
+		outcome string
+		exit    float64
+		exitDay int
+		ret     float64
+	}{
+		{"target hit", testSignal("BUY", 100, 110, 95),
+			[]models.HistoricalData{ohlc(1, 100, 105, 99, 104), ohlc(2, 104, 111, 103, 109)},
+			nil, beforeExpiry, OutcomeTargetHit, 110, 2, 0.1},
+		{"stop hit", testSignal("BUY", 100, 110, 95),
+			[]models.HistoricalData{ohlc(1, 99, 101, 94, 96)},
+			nil, beforeExpiry, OutcomeStopHit, 95, 1, -0.05},
+		{"bar spanning stop and target stops", testSignal("BUY", 100, 110, 95),
+			[]models.HistoricalData{ohlc(1, 100, 112, 94, 108)},
+			nil, beforeExpiry, OutcomeStopHit, 95, 1, -0.05},
+		{"gap down through the stop fills at the open", testSignal("BUY", 100, 110, 95),
+			[]models.HistoricalData{ohlc(1, 100, 101, 99, 100), ohlc(2, 90, 92, 88, 91)},
+			nil, beforeExpiry, OutcomeStopHit, 90, 2, -0.1},
+		{"gap up through the target fills at the open", testSignal("BUY", 100, 110, 95),
+			[]models.HistoricalData{ohlc(1, 115, 118, 114, 116)},
+			nil, beforeExpiry, OutcomeTargetHit, 115, 1, 0.15},
+		{"bars before the signal are ignored", testSignal("BUY", 100, 110, 95),
+			[]models.HistoricalData{ohlc(0, 100, 120, 80, 100), ohlc(1, 100, 101, 99, 100)},
+			nil, beforeExpiry, OutcomePending, 0, 0, 0},
+		{"expiry exits at the last price seen", testSignal("BUY", 100, 110, 95),
+			[]models.HistoricalData{ohlc(1, 100, 103, 99, 102), ohlc(4, 102, 104, 101, 103), ohlc(6, 90, 91, 89, 90)},
+			nil, afterExpiry, OutcomeExpired, 103, 4, 0.03},
+		{"expiry with nothing seen exits at the next open", testSignal("BUY", 100, 110, 95),
+			[]models.HistoricalData{ohlc(6, 104, 120, 80, 106)},
+			nil, afterExpiry, OutcomeExpired, 104, 6, 0.04},
+		{"quote reaches the target", testSignal("BUY", 100, 110, 95),
+			[]models.HistoricalData{ohlc(1, 100, 105, 99, 104)},
+			quote(110.5), beforeExpiry, OutcomeTargetHit, 110.5, 3, 0.105},
+		{"sell target", testSignal("SELL", 100, 90, 105),
+			[]models.HistoricalData{ohlc(1, 99, 100, 89, 90)},
+			nil, beforeExpiry, OutcomeTargetHit, 90, 1, 0.1},
+		{"sell stop", testSignal("SELL", 100, 90, 105),
+			[]models.HistoricalData{ohlc(1, 101, 106, 100, 104)},
+			nil, beforeExpiry, OutcomeStopHit, 105, 1, -0.05},
+		{"sell gap up through the stop", testSignal("SELL", 100, 90, 105),
+			[]models.HistoricalData{ohlc(1, 108, 109, 107, 108)},
+			nil, beforeExpiry, OutcomeStopHit, 108, 1, -0.08},
+		{"sell expiry", testSignal("SELL", 100, 90, 105),
+			[]models.HistoricalData{ohlc(2, 100, 101, 96, 97)},
+			nil, afterExpiry, OutcomeExpired, 97, 2, 0.03},
+		{"hold expiry is undirected", testSignal("HOLD", 100, 0, 0),
+			[]models.HistoricalData{ohlc(2, 100, 130, 70, 97)},
+			nil, afterExpiry, OutcomeExpired, 97, 2, -0.03},
+	}
+	for _, tt := range tests {
+		record := evaluateSignal(SignalRecord{ID: "sig_1", Signal: tt.signal, Outcome: OutcomePending}, tt.bars, tt.quote, tt.now)
+		if record.Outcome != tt.outcome {
+			t.Fatalf("%s: outcome %s, want %s", tt.name, record.Outcome, tt.outcome)
+		}
+		if tt.outcome == OutcomePending {
+			if record.ExitTime != nil || record.LastSeen == nil || !record.LastSeen.Equal(signalStart.AddDate(0, 0, 1)) {
+				t.Fatalf("%s: pending record %+v should only track the last bar", tt.name, record)
+			}
+			continue
+		}
+		exitTime := signalStart.AddDate(0, 0, tt.exitDay)
+		if tt.quote != nil {
+			exitTime = tt.now
+		}
+		if math.Abs(record.ExitPrice-tt.exit) > 1e-9 || record.ExitTime == nil || !record.ExitTime.Equal(exitTime) {
+			t.Fatalf("%s: exit %v at %v, want %v at %v", tt.name, record.ExitPrice, record.ExitTime, tt.exit, exitTime)
+		}
+		if math.Abs(record.Return-tt.ret) > 1e-9 {
+			t.Fatalf("%s: return %v, want %v", tt.name, record.Return, tt.ret)
+		}
+	}
+}
+
+func TestEvaluateSignalExcursions(t *testing.T) {
+	bars := []models.HistoricalData{ohlc(1, 100, 106, 97, 104), ohlc(2, 104, 108, 98, 100)}
+
+	buy := evaluateSignal(SignalRecord{Signal: testSignal("BUY", 100, 120, 90)}, bars, nil, signalStart.AddDate(0, 0, 3))
+	if math.Abs(buy.MaxFavorable-0.08) > 1e-9 || math.Abs(buy.MaxAdverse+0.03) > 1e-9 {
+		t.Fatalf("BUY excursions %v / %v, want 0.08 / -0.03", buy.MaxFavorable, buy.MaxAdverse)
+	}
+	sell := evaluateSignal(SignalRecord{Signal: testSignal("SELL", 100, 80, 110)}, bars, nil, signalStart.AddDate(0, 0, 3))
+	if math.Abs(sell.MaxFavorable-0.03) > 1e-9 || math.Abs(sell.MaxAdverse+0.08) > 1e-9 {
+		t.Fatalf("SELL excursions %v / %v, want 0.03 / -0.08", sell.MaxFavorable, sell.MaxAdverse)
+	}
+}
+
+func TestGapFill(t *testing.T) {
+	tests := []struct {
+		open, level, direction, want float64
+	}{
+		{100, 95, -1, 95}, // Opened above a stop below: fills at the stop
+		{90, 95, -1, 90},  // Gapped down through it: fills at the open
+		{105, 110, 1, 110},
+		{115, 110, 1, 115},
+		{0, 110, 1, 110}, // No open recorded
+	}
+	for _, tt := range tests {
+		if got := gapFill(tt.open, tt.level, tt.direction); got != tt.want {
+			t.Fatalf("gapFill(%v, %v, %v) = %v, want %v", tt.open, tt.level, tt.direction, got, tt.want)
+		}
+	}
+}
+
+func TestComputeSignalStats(t *testing.T) {
+	record := func(algorithm, signalType, outcome string, confidence, ret float64) *SignalRecord {
+		return &SignalRecord{
+			AlgorithmID: algorithm,
+			Signal:      &models.TradingSignal{Type: signalType, Confidence: decimal.NewFromFloat(confidence)},
+			Outcome:     outcome,
+			Return:      ret,
+		}
+	}
+	records := []*SignalRecord{
+		record("momentum", "BUY", OutcomeTargetHit, 0.85, 0.1),
+		record("momentum", "SELL", OutcomeStopHit, 0.82, -0.05),
+		record("trend", "BUY", OutcomeExpired, 0.35, -0.02),
+		record("trend", "HOLD", OutcomeExpired, 0.5, 0.01),
+		record("trend", "BUY", OutcomePending, 0.9, 0),
+		record("trend", "SELL", OutcomeTargetHit, 1, 0.04),
+	}
+	stats := computeSignalStats(records)
+
+	if stats.Total != 6 || stats.Pending != 1 || stats.Resolved != 5 || stats.TargetHit != 2 || stats.StopHit != 1 || stats.Expired != 2 || stats.Directional != 4 {
+		t.Fatalf("counts = %+v", stats)
+	}
+	near := func(got, want float64) bool { return math.Abs(got-want) < 1e-9 }
+	if !near(stats.HitRate, 0.5) || !near(stats.TargetRate, 0.5) || !near(stats.AverageReturn, 0.0175) {
+		t.Fatalf("hit rate %v target rate %v average %v, want 0.5, 0.5, 0.0175", stats.HitRate, stats.TargetRate, stats.AverageReturn)
+	}
+	if !near(stats.AverageWin, 0.07) || !near(stats.AverageLoss, -0.035) {
+		t.Fatalf("average win %v loss %v, want 0.07 and -0.035", stats.AverageWin, stats.AverageLoss)
+	}
+	wantBrier := (0.15*0.15 + 0.82*0.82 + 0.35*0.35 + 0) / 4
+	if !near(stats.Brier, wantBrier) {
+		t.Fatalf("brier %v, want %v", stats.Brier, wantBrier)
+	}
+
+	// Confidence 1 lands in the top bin rather than past it
+	wantBins := []struct {
+		lower               float64
+		count               int
+		predicted, observed float64
+	}{
+		{0.3, 1, 0.35, 0},
+		{0.8, 2, 0.835, 0.5},
+		{0.9, 1, 1, 1},
+	}
+	if len(stats.Calibration) != len(wantBins) {
+		t.Fatalf("calibration = %+v, want %d bins", stats.Calibration, len(wantBins))
+	}
+	for i, want := range wantBins {
+		bin := stats.Calibration[i]
+		if !near(bin.Lower, want.lower) || bin.Count != want.count || !near(bin.Predicted, want.predicted) || !near(bin.Observed, want.observed) {
+			t.Fatalf("bin %d = %+v, want %+v", i, bin, want)
+		}
+	}
+
+	if empty := computeSignalStats(records[3:5]); empty.Directional != 0 || empty.HitRate != 0 || empty.Calibration != nil {
+		t.Fatalf("stats without directional outcomes = %+v", empty)
+	}
+}
+
+func TestSignalHistoryStatsByAlgorithm(t *testing.T) {
+	s := NewSignalHistoryService(nil, testLogger())
+	s.Record(map[string]*models.TradingSignal{
+		"momentum": {Symbol: "AAPL", Type: "BUY", Confidence: decimal.NewFromFloat(0.8)},
+		"trend":    {Symbol: "MSFT", Type: "SELL", Confidence: decimal.NewFromFloat(0.6)},
+	})
+	for _, record := range s.records {
+		record.Outcome, record.Return = OutcomeTargetHit, 0.05
+		if record.AlgorithmID == "trend" {
+			record.Outcome, record.Return = OutcomeStopHit, -0.02
+		}
+	}
+
+	report := s.Stats(SignalFilter{Limit: 1})
+	if report.Overall.Total != 2 || report.Overall.HitRate != 0.5 {
+		t.Fatalf("overall = %+v, want both records with a 50%% hit rate despite the limit", report.Overall)
+	}
+	if report.ByAlgorithm["momentum"].TargetHit != 1 || report.ByAlgorithm["trend"].StopHit != 1 {
+		t.Fatalf("by algorithm = %+v", report.ByAlgorithm)
+	}
+	if symbol := s.Stats(SignalFilter{Symbol: "MSFT"}); symbol.Overall.Total != 1 || len(symbol.ByAlgorithm) != 1 {
+		t.Fatalf("MSFT stats = %+v, want the trend signal only", symbol)
+	}
+}
+
+func TestSignalHistoryPersistence(t *testing.T) {
+	provider := &fixtureProvider{closes: map[string][]float64{"AAPL": {100, 100, 100, 112, 113}}}
+	marketData := NewMarketDataService(providers.NewMarketDataAggregator([]providers.MarketDataProvider{provider}), testLogger())
+	path := filepath.Join(t.TempDir(), "history", "signals.jsonl")
+	now := time.Now()
+
+	s := NewSignalHistoryService(marketData, testLogger())
+	if err := s.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	signal := &models.TradingSignal{
+		Symbol:         "AAPL",
+		Type:           "BUY",
+		Price:          decimal.NewFromInt(100),
+		TargetPrice:    decimal.NewFromInt(110),
+		StopLoss:       decimal.NewFromInt(90),
+		CreatedAt:      now.Add(-60 * time.Hour),
+		ExpirationTime: now.Add(48 * time.Hour),
+	}
+	s.Record(map[string]*models.TradingSignal{"momentum": signal})
+	if signal.ID == "" {
+		t.Fatal("Record should write the assigned ID back to the signal")
+	}
+	if resolved := s.EvaluatePending(now); resolved != 1 {
+		t.Fatalf("resolved %d signals, want 1", resolved)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := fileLines(t, path); len(lines) != 2 {
+		t.Fatalf("file has %d lines, want the signal and its resolution", len(lines))
+	}
+
+	reloaded := NewSignalHistoryService(marketData, testLogger())
+	if err := reloaded.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer reloaded.Close()
+	record, err := reloaded.GetRecord(signal.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The day-after bar opens at 112, beyond the target
+	if record.Outcome != OutcomeTargetHit || record.ExitPrice != 112 || record.AlgorithmID != "momentum" {
+		t.Fatalf("reloaded %+v, want the target hit at the 112 open", record)
+	}
+}
+
+func TestSignalHistoryCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signals.jsonl")
+	line := func(outcome string) string {
+		return `{"id":"sig_1","algorithm_id":"momentum","signal":{"id":"sig_1","symbol":"AAPL","type":"BUY"},"outcome":"` + outcome + `"}`
+	}
+	content := strings.Join([]string{
+		line(OutcomePending),
+		"not json",
+		line(OutcomePending),
+		line(OutcomeStopHit),
+		`{"id":"sig_2","algorithm_id":"trend","signal":{"id":"sig_2","symbol":"MSFT","type":"SELL"},"outcome":"PENDING"}`,
+	}, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSignalHistoryService(nil, testLogger())
+	if err := s.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	if records := s.History(SignalFilter{}); len(records) != 2 || records[1].Outcome != OutcomeStopHit {
+		t.Fatalf("records = %+v, want sig_1 at its latest outcome and sig_2", records)
+	}
+	if lines := fileLines(t, path); len(lines) != 2 {
+		t.Fatalf("compacted file has %d lines, want one per record", len(lines))
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temporary file left behind: %v", err)
+	}
+
+	// Appends continue on the compacted file
+	s.Record(map[string]*models.TradingSignal{"momentum": {ID: "sig_3", Symbol: "AAPL", Type: "BUY"}})
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if lines := fileLines(t, path); len(lines) != 3 {
+		t.Fatalf("file has %d lines after an append, want 3", len(lines))
+	}
+}
+
+func fileLines(t *testing.T, path string) []string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
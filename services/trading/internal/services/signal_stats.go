@@ -0,0 +1,135 @@
+package services
+
+import (
+	"math"
+
+	"trading-service/internal/ml"
+)
+
+// SignalStats summarizes resolved signals. Hit rate, returns and
+// calibration cover directional (BUY/SELL) signals only.
+type SignalStats struct {
+	Total         int                 `json:"total"`
+	Pending       int                 `json:"pending"`
+	Resolved      int                 `json:"resolved"`
+	TargetHit     int                 `json:"target_hit"`
+	StopHit       int                 `json:"stop_hit"`
+	Expired       int                 `json:"expired"`
+	Directional   int                 `json:"directional"`    // Resolved BUY/SELL signals
+	HitRate       float64             `json:"hit_rate"`       // Share with a positive return
+	TargetRate    float64             `json:"target_rate"`    // Share that reached the target
+	AverageReturn float64             `json:"average_return"` // Mean direction-adjusted return
+	AverageWin    float64             `json:"average_win"`
+	AverageLoss   float64             `json:"average_loss"`
+	Brier         float64             `json:"brier"` // Mean squared error of confidence as P(profit)
+	Calibration   []ml.CalibrationBin `json:"calibration"`
+}
+
+// SignalStatsReport holds overall and per-algorithm statistics
+type SignalStatsReport struct {
+	Overall     SignalStats            `json:"overall"`
+	ByAlgorithm map[string]SignalStats `json:"by_algorithm"`
+}
+
+// Stats summarizes matching records overall and per algorithm ID
+func (s *SignalHistoryService) Stats(filter SignalFilter) *SignalStatsReport {
+	filter.Limit = 0
+	records := s.History(filter)
+
+	byAlgorithm := make(map[string][]*SignalRecord)
+	for _, record := range records {
+		byAlgorithm[record.AlgorithmID] = append(byAlgorithm[record.AlgorithmID], record)
+	}
+
+	report := &SignalStatsReport{
+		Overall:     computeSignalStats(records),
+		ByAlgorithm: make(map[string]SignalStats, len(byAlgorithm)),
+	}
+	for algorithmID, group := range byAlgorithm {
+		report.ByAlgorithm[algorithmID] = computeSignalStats(group)
+	}
+	return report
+}
+
+func computeSignalStats(records []*SignalRecord) SignalStats {
+	stats := SignalStats{Total: len(records)}
+
+	var wins, losses, targetHits int
+	var returnSum, winSum, lossSum float64
+	bins := make([]ml.CalibrationBin, 10)
+	for b := range bins {
+		bins[b].Lower = float64(b) / 10
+		bins[b].Upper = float64(b+1) / 10
+	}
+
+	for _, record := range records {
+		switch record.Outcome {
+		case OutcomePending:
+			stats.Pending++
+			continue
+		case OutcomeTargetHit:
+			stats.TargetHit++
+		case OutcomeStopHit:
+			stats.StopHit++
+		case OutcomeExpired:
+			stats.Expired++
+		}
+		stats.Resolved++
+
+		if record.Signal.Type != "BUY" && record.Signal.Type != "SELL" {
+			continue
+		}
+		stats.Directional++
+		returnSum += record.Return
+		if record.Outcome == OutcomeTargetHit {
+			targetHits++
+		}
+
+		profitable := 0.0
+		if record.Return > 0 {
+			profitable = 1
+			wins++
+			winSum += record.Return
+		} else {
+			losses++
+			lossSum += record.Return
+		}
+
+		confidence, _ := record.Signal.Confidence.Float64()
+		confidence = math.Min(math.Max(confidence, 0), 1)
+		stats.Brier += (confidence - profitable) * (confidence - profitable)
+
+		b := int(confidence * 10)
+		if b > 9 {
+			b = 9
+		}
+		bins[b].Count++
+		bins[b].Predicted += confidence
+		bins[b].Observed += profitable
+	}
+
+	if stats.Directional == 0 {
+		return stats
+	}
+	n := float64(stats.Directional)
+	stats.HitRate = float64(wins) / n
+	stats.TargetRate = float64(targetHits) / n
+	stats.AverageReturn = returnSum / n
+	stats.Brier /= n
+	if wins > 0 {
+		stats.AverageWin = winSum / float64(wins)
+	}
+	if losses > 0 {
+		stats.AverageLoss = lossSum / float64(losses)
+	}
+
+	for _, bin := range bins {
+		if bin.Count == 0 {
+			continue
+		}
+		bin.Predicted /= float64(bin.Count)
+		bin.Observed /= float64(bin.Count)
+		stats.Calibration = append(stats.Calibration, bin)
+	}
+	return stats
+}
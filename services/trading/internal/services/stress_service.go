@@ -0,0 +1,473 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+	"trading-service/internal/risk"
+)
+
+// Stress scenario types
+const (
+	StressHistorical   = "historical"
+	StressHypothetical = "hypothetical"
+)
+
+// Sources of a position's stressed move
+const (
+	MoveHistory = "history" // The symbol's own return over the window
+	MoveProxy   = "proxy"   // The market proxy's return scaled by the position's beta
+	MoveMarket  = "market"  // The equity shock scaled by the position's beta
+	MoveSector  = "sector"  // The shock for the position's sector
+	MoveSymbol  = "symbol"  // The shock for the symbol
+)
+
+// maxStressScenarios caps the scenarios run in one stress test
+const maxStressScenarios = 20
+
+// StressWindow is a historical scenario: positions move as they did between
+// the start and end dates
+type StressWindow struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+}
+
+func stressDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// historicalScenarios are the built-in historical windows, peak to trough
+var historicalScenarios = []StressWindow{
+	{ID: "dotcom_2000", Name: "Dot-com crash", Description: "S&P 500 peak of March 2000 to the October 2002 low", Start: stressDate(2000, time.March, 24), End: stressDate(2002, time.October, 9)},
+	{ID: "gfc_2008", Name: "2008 financial crisis", Description: "Lehman Brothers' collapse to the March 2009 low", Start: stressDate(2008, time.September, 12), End: stressDate(2009, time.March, 9)},
+	{ID: "flash_crash_2010", Name: "2010 flash crash", Description: "April 2010 high to the July 2010 low", Start: stressDate(2010, time.April, 23), End: stressDate(2010, time.July, 2)},
+	{ID: "volmageddon_2018", Name: "February 2018 volatility spike", Description: "January 2018 high to the February low as short-volatility products unwound", Start: stressDate(2018, time.January, 26), End: stressDate(2018, time.February, 8)},
+	{ID: "covid_2020", Name: "March 2020 COVID-19 drawdown", Description: "February 2020 high to the March 23 low", Start: stressDate(2020, time.February, 19), End: stressDate(2020, time.March, 23)},
+	{ID: "rate_shock_2022", Name: "2022 rate shock", Description: "January 2022 high to the October low as rates rose", Start: stressDate(2022, time.January, 3), End: stressDate(2022, time.October, 12)},
+}
+
+// StressShock is a hypothetical scenario. Moves are fractional price
+// changes, e.g. -0.2 for a 20% fall. A position takes its symbol's move,
+// else its sector's, else the equity move scaled by its beta.
+type StressShock struct {
+	Name       string             `json:"name"`
+	Equity     float64            `json:"equity"`     // Market-wide move
+	Volatility float64            `json:"volatility"` // Relative change in volatility, e.g. 0.3 for +30%; reported as stressed VaR
+	Sectors    map[string]float64 `json:"sectors"`    // Sector name to move, e.g. {"Technology": -0.3}
+	Symbols    map[string]float64 `json:"symbols"`    // Symbol to move
+}
+
+// StressTestRequest selects the scenarios to run. With none selected every
+// built-in historical scenario runs.
+type StressTestRequest struct {
+	Scenarios []string       `json:"scenarios"` // Built-in historical scenario IDs
+	Windows   []StressWindow `json:"windows"`   // Custom historical windows
+	Shocks    []StressShock  `json:"shocks"`    // Hypothetical shocks
+	Benchmark string         `json:"benchmark"` // Market proxy for betas, default the configured benchmark
+}
+
+// StressPosition is one position's outcome under a scenario
+type StressPosition struct {
+	Symbol      string   `json:"symbol"`
+	Sector      string   `json:"sector,omitempty"`
+	MarketValue float64  `json:"market_value"`
+	Move        float64  `json:"move"` // Fractional price change
+	Source      string   `json:"source"`
+	Beta        *float64 `json:"beta,omitempty"` // When the move is beta-scaled
+	PnL         float64  `json:"pnl"`
+	StressedVaR *float64 `json:"stressed_var,omitempty"` // Share of the portfolio's stressed VaR, in currency
+}
+
+// StressResult is a portfolio's outcome under one scenario
+type StressResult struct {
+	Scenario    string           `json:"scenario"`
+	Name        string           `json:"name"`
+	Type        string           `json:"type"`
+	Start       *time.Time       `json:"start,omitempty"`
+	End         *time.Time       `json:"end,omitempty"`
+	PnL         float64          `json:"pnl"`
+	PnLPercent  float64          `json:"pnl_percent"`            // Of portfolio value including cash
+	StressedVaR *float64         `json:"stressed_var,omitempty"` // One-day 99% parametric VaR with volatility shocked, in currency
+	Positions   []StressPosition `json:"positions,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// StressReport is the outcome of a stress test
+type StressReport struct {
+	PortfolioID    string         `json:"portfolio_id"`
+	PortfolioValue float64        `json:"portfolio_value"`
+	Benchmark      string         `json:"benchmark"`
+	Results        []StressResult `json:"results"`
+	Worst          string         `json:"worst,omitempty"` // Scenario with the largest loss
+}
+
+// StressTestService applies historical and hypothetical scenarios to a
+// portfolio's current positions
+type StressTestService struct {
+	marketDataService *MarketDataService
+	benchmarkService  *BenchmarkService
+	benchmarkID       string
+	logger            *logrus.Logger
+}
+
+// NewStressTestService creates a stress tester that measures betas against
+// the benchmark unless a request names another
+func NewStressTestService(marketDataService *MarketDataService, benchmarkService *BenchmarkService, benchmarkID string, logger *logrus.Logger) *StressTestService {
+	return &StressTestService{
+		marketDataService: marketDataService,
+		benchmarkService:  benchmarkService,
+		benchmarkID:       benchmarkID,
+		logger:            logger,
+	}
+}
+
+// Scenarios returns the built-in historical scenarios
+func (s *StressTestService) Scenarios() []StressWindow {
+	return append([]StressWindow(nil), historicalScenarios...)
+}
+
+// stressRun holds what the scenarios of one stress test share
+type stressRun struct {
+	positions []*models.Position
+	value     float64
+	sectors   map[string]string
+	benchmark Benchmark
+	betas     map[string]float64 // Measured on first use
+	noBeta    map[string]error   // Why a position's beta couldn't be measured
+}
+
+// Run applies the requested scenarios to the positions. Errors are returned
+// only for an invalid request; a scenario that can't be evaluated reports
+// its error in its result.
+func (s *StressTestService) Run(portfolio *models.Portfolio, positions []*models.Position, req StressTestRequest) (*StressReport, error) {
+	windows, err := s.selectWindows(req)
+	if err != nil {
+		return nil, err
+	}
+	for i, shock := range req.Shocks {
+		if err := validateShock(shock); err != nil {
+			return nil, fmt.Errorf("shock %d: %v", i+1, err)
+		}
+	}
+	if len(windows)+len(req.Shocks) > maxStressScenarios {
+		return nil, fmt.Errorf("at most %d scenarios can run at once", maxStressScenarios)
+	}
+	if len(positions) == 0 {
+		return nil, fmt.Errorf("portfolio has no positions")
+	}
+
+	run := &stressRun{
+		positions: positions,
+		value:     portfolio.Cash.InexactFloat64(),
+		sectors:   make(map[string]string),
+	}
+	for _, position := range positions {
+		run.value += position.MarketValue.InexactFloat64()
+	}
+	if run.value <= 0 {
+		return nil, fmt.Errorf("portfolio has no value")
+	}
+	for _, info := range s.marketDataService.GetSupportedSymbols() {
+		run.sectors[info.Symbol] = info.Sector
+	}
+	benchmarkID := req.Benchmark
+	if benchmarkID == "" {
+		benchmarkID = s.benchmarkID
+	}
+	run.benchmark = s.benchmarkService.Resolve(benchmarkID)
+
+	report := &StressReport{
+		PortfolioID:    portfolio.ID,
+		PortfolioValue: run.value,
+		Benchmark:      run.benchmark.ID,
+	}
+	for _, window := range windows {
+		report.Results = append(report.Results, s.runWindow(run, window))
+	}
+	for i, shock := range req.Shocks {
+		report.Results = append(report.Results, s.runShock(run, i+1, shock))
+	}
+
+	worst := 0.0
+	for _, result := range report.Results {
+		if result.Error == "" && result.PnL < worst {
+			worst = result.PnL
+			report.Worst = result.Scenario
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"portfolio_id": portfolio.ID,
+		"scenarios":    len(report.Results),
+		"worst":        report.Worst,
+	}).Info("Stress test completed")
+
+	return report, nil
+}
+
+// selectWindows resolves the requested built-in scenarios and validates
+// custom windows
+func (s *StressTestService) selectWindows(req StressTestRequest) ([]StressWindow, error) {
+	if len(req.Scenarios) == 0 && len(req.Windows) == 0 && len(req.Shocks) == 0 {
+		return s.Scenarios(), nil
+	}
+
+	byID := make(map[string]StressWindow, len(historicalScenarios))
+	for _, window := range historicalScenarios {
+		byID[window.ID] = window
+	}
+
+	var windows []StressWindow
+	for _, id := range req.Scenarios {
+		window, ok := byID[strings.ToLower(id)]
+		if !ok {
+			return nil, fmt.Errorf("unknown scenario %q", id)
+		}
+		windows = append(windows, window)
+	}
+	for i, window := range req.Windows {
+		if window.Start.IsZero() || window.End.IsZero() || !window.End.After(window.Start) {
+			return nil, fmt.Errorf("window %d needs a start before its end", i+1)
+		}
+		if window.End.After(time.Now()) {
+			return nil, fmt.Errorf("window %d ends in the future", i+1)
+		}
+		if window.ID == "" {
+			window.ID = fmt.Sprintf("window_%d", i+1)
+		}
+		if window.Name == "" {
+			window.Name = fmt.Sprintf("%s to %s", window.Start.Format("2006-01-02"), window.End.Format("2006-01-02"))
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// validateShock rejects moves that would take a price below zero
+func validateShock(shock StressShock) error {
+	if shock.Equity < -1 {
+		return fmt.Errorf("equity move can't be below -1")
+	}
+	if shock.Volatility <= -1 {
+		return fmt.Errorf("volatility change must be above -1")
+	}
+	for sector, move := range shock.Sectors {
+		if move < -1 {
+			return fmt.Errorf("move for sector %s can't be below -1", sector)
+		}
+	}
+	for symbol, move := range shock.Symbols {
+		if move < -1 {
+			return fmt.Errorf("move for %s can't be below -1", symbol)
+		}
+	}
+	return nil
+}
+
+// runWindow replays a historical window. Positions without prices over the
+// window take the market proxy's move scaled by their beta.
+func (s *StressTestService) runWindow(run *stressRun, window StressWindow) StressResult {
+	start, end := window.Start, window.End
+	result := StressResult{Scenario: window.ID, Name: window.Name, Type: StressHistorical, Start: &start, End: &end}
+
+	var proxyMove float64
+	var proxyMeasured bool
+	for _, position := range run.positions {
+		line := run.line(position)
+		if move, ok := s.windowMove(position.Symbol, window); ok {
+			line.Move, line.Source = move, MoveHistory
+		} else {
+			if !proxyMeasured {
+				benchmarkData, err := s.benchmarkService.HistoricalData(run.benchmark, window.Start, window.End)
+				if err == nil {
+					proxyMove, proxyMeasured = barsMove(benchmarkData)
+				}
+				if !proxyMeasured {
+					result.Error = fmt.Sprintf("no prices for %s or proxy %s over the window", position.Symbol, run.benchmark.ID)
+					return result
+				}
+			}
+			beta, err := s.beta(run, position.Symbol)
+			if err != nil {
+				result.Error = err.Error()
+				return result
+			}
+			line.Move, line.Source, line.Beta = betaMove(beta, proxyMove), MoveProxy, &beta
+		}
+		result.add(line)
+	}
+	result.finish(run.value)
+	return result
+}
+
+// windowMove returns the symbol's price change over the window
+func (s *StressTestService) windowMove(symbol string, window StressWindow) (float64, bool) {
+	data, err := s.marketDataService.GetHistoricalData(symbol, window.Start, window.End)
+	if err != nil {
+		return 0, false
+	}
+	return barsMove(data)
+}
+
+// barsMove is the change from the first close to the last. A price can't
+// fall below zero, so neither can the move below -1.
+func barsMove(data []models.HistoricalData) (float64, bool) {
+	if len(data) < 2 {
+		return 0, false
+	}
+	first := data[0].Close.InexactFloat64()
+	if first <= 0 {
+		return 0, false
+	}
+	return math.Max(-1, data[len(data)-1].Close.InexactFloat64()/first-1), true
+}
+
+// betaMove scales a market move by beta. Like barsMove it never goes below
+// -1: a long position can't lose more than its value.
+func betaMove(beta, marketMove float64) float64 {
+	return math.Max(-1, beta*marketMove)
+}
+
+// runShock applies the n-th hypothetical shock
+func (s *StressTestService) runShock(run *stressRun, n int, shock StressShock) StressResult {
+	id := fmt.Sprintf("shock_%d", n)
+	name := shock.Name
+	if name == "" {
+		name = fmt.Sprintf("Hypothetical shock %d", n)
+	}
+	result := StressResult{Scenario: id, Name: name, Type: StressHypothetical}
+
+	sectorMoves := make(map[string]float64, len(shock.Sectors))
+	for sector, move := range shock.Sectors {
+		sectorMoves[strings.ToLower(sector)] = move
+	}
+	symbolMoves := make(map[string]float64, len(shock.Symbols))
+	for symbol, move := range shock.Symbols {
+		symbolMoves[strings.ToUpper(symbol)] = move
+	}
+
+	for _, position := range run.positions {
+		line := run.line(position)
+		if move, ok := symbolMoves[position.Symbol]; ok {
+			line.Move, line.Source = move, MoveSymbol
+		} else if move, ok := sectorMoves[strings.ToLower(line.Sector)]; ok && line.Sector != "" {
+			line.Move, line.Source = move, MoveSector
+		} else {
+			line.Source = MoveMarket
+			if shock.Equity != 0 {
+				beta, err := s.beta(run, position.Symbol)
+				if err != nil {
+					result.Error = err.Error()
+					return result
+				}
+				line.Move, line.Beta = betaMove(beta, shock.Equity), &beta
+			}
+		}
+		result.add(line)
+	}
+
+	if shock.Volatility != 0 {
+		if err := s.stressVaR(run, shock.Volatility, &result); err != nil {
+			result.Error = fmt.Sprintf("stressed VaR: %v", err)
+		}
+	}
+	result.finish(run.value)
+	return result
+}
+
+// stressVaR sets the one-day 99% parametric VaR of the positions with their
+// returns scaled by 1 + the volatility change, apportioned to positions by
+// risk contribution. Positions must still be in portfolio order.
+func (s *StressTestService) stressVaR(run *stressRun, volatilityChange float64, result *StressResult) error {
+	to := time.Now()
+	from := to.AddDate(-1, 0, 0)
+	series := make([][]models.HistoricalData, len(run.positions))
+	weights := make([]float64, len(run.positions))
+	for i, position := range run.positions {
+		data, err := s.marketDataService.GetHistoricalData(position.Symbol, from, to)
+		if err != nil {
+			return err
+		}
+		series[i] = data
+		weights[i] = position.MarketValue.InexactFloat64() / run.value
+	}
+
+	_, closes := alignCloses(series...)
+	returns := make([][]float64, len(closes))
+	for i := range closes {
+		returns[i] = indicators.Returns(closes[i])
+		for t := range returns[i] {
+			returns[i][t] *= 1 + volatilityChange
+		}
+	}
+
+	estimate, err := risk.PortfolioRisk(weights, returns, risk.PortfolioRiskConfig{
+		VaR:            risk.VaRConfig{Method: risk.VaRParametric, Confidence: 0.99},
+		Shrinkage:      risk.AutoShrinkage,
+		PeriodsPerYear: tradingDaysPerYear,
+	})
+	if err != nil {
+		return err
+	}
+
+	total := estimate.VaR.VaR * run.value
+	result.StressedVaR = &total
+	for i, contribution := range estimate.Contributions {
+		share := contribution.ComponentVaR * run.value
+		result.Positions[i].StressedVaR = &share
+	}
+	return nil
+}
+
+// beta returns the position's beta against the run's benchmark over the
+// last year, measuring every position's on first use
+func (s *StressTestService) beta(run *stressRun, symbol string) (float64, error) {
+	if run.betas == nil {
+		symbols := make([]string, len(run.positions))
+		for i, position := range run.positions {
+			symbols[i] = position.Symbol
+		}
+		to := time.Now()
+		betas, unmeasured, err := s.benchmarkService.Betas(symbols, run.benchmark, to.AddDate(-1, 0, 0), to)
+		if err != nil {
+			return 0, fmt.Errorf("measuring betas against %s: %v", run.benchmark.ID, err)
+		}
+		run.betas, run.noBeta = betas, unmeasured
+	}
+	beta, ok := run.betas[symbol]
+	if !ok {
+		return 0, fmt.Errorf("measuring %s's beta against %s: %v", symbol, run.benchmark.ID, run.noBeta[symbol])
+	}
+	return beta, nil
+}
+
+// line starts a position's result
+func (r *stressRun) line(position *models.Position) StressPosition {
+	return StressPosition{
+		Symbol:      position.Symbol,
+		Sector:      r.sectors[position.Symbol],
+		MarketValue: position.MarketValue.InexactFloat64(),
+	}
+}
+
+// add prices the position's move and records it
+func (r *StressResult) add(line StressPosition) {
+	line.PnL = line.MarketValue * line.Move
+	r.PnL += line.PnL
+	r.Positions = append(r.Positions, line)
+}
+
+// finish orders positions from the largest loss and sets the total return
+func (r *StressResult) finish(portfolioValue float64) {
+	r.PnLPercent = r.PnL / portfolioValue * 100
+	sort.SliceStable(r.Positions, func(i, j int) bool { return r.Positions[i].PnL < r.Positions[j].PnL })
+}
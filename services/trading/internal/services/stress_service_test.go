@@ -0,0 +1,193 @@
+package services
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"trading-service/internal/models"
+	"trading-service/internal/providers"
+)
+
+// newTestStress serves SPY, AAPL and JPM with beta 1 and LEV with beta 2.
+// LEV only has prices from 2021.
+func newTestStress() *StressTestService {
+	returns := crashReturns()
+	provider := &fixtureProvider{
+		closes: map[string][]float64{
+			"SPY":  closesFromReturns(returns, 1),
+			"AAPL": closesFromReturns(returns, 1),
+			"JPM":  closesFromReturns(returns, 1),
+			"MSFT": closesFromReturns(returns, 1),
+			"LEV":  closesFromReturns(returns, 2),
+		},
+		listed: map[string]time.Time{"LEV": stressDate(2021, time.January, 4)},
+	}
+	marketData := NewMarketDataService(providers.NewMarketDataAggregator([]providers.MarketDataProvider{provider}), testLogger())
+	return NewStressTestService(marketData, NewBenchmarkService(marketData, testLogger()), "SPY", testLogger())
+}
+
+func stressLine(t *testing.T, result StressResult, symbol string) StressPosition {
+	t.Helper()
+	for _, line := range result.Positions {
+		if line.Symbol == symbol {
+			return line
+		}
+	}
+	t.Fatalf("%s missing from %s", symbol, result.Scenario)
+	return StressPosition{}
+}
+
+func TestStressSelectWindows(t *testing.T) {
+	s := newTestStress()
+	start, end := stressDate(2020, time.January, 2), stressDate(2020, time.June, 30)
+
+	windows, err := s.selectWindows(StressTestRequest{})
+	if err != nil || len(windows) != len(historicalScenarios) {
+		t.Fatalf("default windows = %d (%v), want every built-in scenario", len(windows), err)
+	}
+	if windows, err := s.selectWindows(StressTestRequest{Shocks: []StressShock{{Equity: -0.1}}}); err != nil || len(windows) != 0 {
+		t.Fatalf("shock-only windows = %v (%v), want none", windows, err)
+	}
+
+	windows, err = s.selectWindows(StressTestRequest{Scenarios: []string{"COVID_2020"}, Windows: []StressWindow{{Start: start, End: end}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(windows) != 2 || windows[0].ID != "covid_2020" || windows[1].ID != "window_1" || windows[1].Name != "2020-01-02 to 2020-06-30" {
+		t.Fatalf("windows = %+v, want covid_2020 and a named window_1", windows)
+	}
+
+	for name, req := range map[string]StressTestRequest{
+		"unknown scenario": {Scenarios: []string{"tulips_1637"}},
+		"missing start":    {Windows: []StressWindow{{End: end}}},
+		"end before start": {Windows: []StressWindow{{Start: end, End: start}}},
+		"future end":       {Windows: []StressWindow{{Start: start, End: time.Now().AddDate(0, 1, 0)}}},
+	} {
+		if _, err := s.selectWindows(req); err == nil {
+			t.Fatalf("%s: selectWindows succeeded, want an error", name)
+		}
+	}
+}
+
+func TestStressHistoricalProxyFallback(t *testing.T) {
+	s := newTestStress()
+	closes := closesFromReturns(crashReturns(), 1)
+	proxyMove := closes[len(closes)-1]/closes[0] - 1
+	if proxyMove > -0.5 {
+		t.Fatalf("fixture proxy move %v should be worse than -50%%", proxyMove)
+	}
+
+	portfolio := &models.Portfolio{ID: "p1"}
+	report, err := s.Run(portfolio, testPositions("AAPL", "LEV"), StressTestRequest{Scenarios: []string{"covid_2020"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := report.Results[0]
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+
+	aapl := stressLine(t, result, "AAPL")
+	if aapl.Source != MoveHistory || math.Abs(aapl.Move-proxyMove) > 1e-9 || aapl.Beta != nil {
+		t.Fatalf("AAPL = %+v, want its own move %v", aapl, proxyMove)
+	}
+	lev := stressLine(t, result, "LEV")
+	if lev.Source != MoveProxy || lev.Beta == nil || math.Abs(*lev.Beta-2) > 1e-6 {
+		t.Fatalf("LEV = %+v, want the proxy move with beta 2", lev)
+	}
+	if lev.Move != -1 || lev.PnL != -1000 {
+		t.Fatalf("LEV move %v pnl %v, want the beta-scaled move clamped to -1", lev.Move, lev.PnL)
+	}
+	if report.Worst != "covid_2020" || result.Positions[0].Symbol != "LEV" {
+		t.Fatalf("worst %q first %s, want covid_2020 led by LEV", report.Worst, result.Positions[0].Symbol)
+	}
+
+	report, err = s.Run(portfolio, testPositions("LEV"), StressTestRequest{Scenarios: []string{"covid_2020"}, Benchmark: "NOPE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report.Results[0].Error, "proxy NOPE") {
+		t.Fatalf("error = %q, want the missing proxy reported", report.Results[0].Error)
+	}
+}
+
+func TestStressShockPrecedence(t *testing.T) {
+	s := newTestStress()
+	shock := StressShock{
+		Equity:  -0.6,
+		Sectors: map[string]float64{"technology": -0.1, "Financial Services": -0.2},
+		Symbols: map[string]float64{"aapl": -0.5},
+	}
+	report, err := s.Run(&models.Portfolio{ID: "p1"}, testPositions("AAPL", "MSFT", "JPM", "LEV", "SPY"), StressTestRequest{Shocks: []StressShock{shock}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := report.Results[0]
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+
+	tests := []struct {
+		symbol string
+		source string
+		move   float64
+	}{
+		{"AAPL", MoveSymbol, -0.5},
+		{"MSFT", MoveSector, -0.1},
+		{"JPM", MoveSector, -0.2},
+		{"LEV", MoveMarket, -1},
+		{"SPY", MoveMarket, -0.6},
+	}
+	for _, tt := range tests {
+		line := stressLine(t, result, tt.symbol)
+		if line.Source != tt.source || math.Abs(line.Move-tt.move) > 1e-6 {
+			t.Fatalf("%s = %s %v, want %s %v", tt.symbol, line.Source, line.Move, tt.source, tt.move)
+		}
+	}
+	if math.Abs(result.PnL+2400) > 1e-3 || math.Abs(result.PnLPercent+48) > 1e-4 {
+		t.Fatalf("pnl %v (%v%%), want -2400 (-48%%)", result.PnL, result.PnLPercent)
+	}
+
+	if _, err := s.Run(&models.Portfolio{ID: "p1"}, testPositions("AAPL"), StressTestRequest{Shocks: []StressShock{{Equity: -1.5}}}); err == nil {
+		t.Fatal("an equity move below -1 should be rejected")
+	}
+}
+
+func TestStressedVaR(t *testing.T) {
+	s := newTestStress()
+	positions := testPositions("AAPL", "LEV")
+	report, err := s.Run(&models.Portfolio{ID: "p1"}, positions, StressTestRequest{Shocks: []StressShock{
+		{Volatility: 0.5},
+		{Volatility: 1},
+		{Equity: -0.1},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars := make([]float64, 2)
+	for i, result := range report.Results[:2] {
+		if result.Error != "" || result.StressedVaR == nil || *result.StressedVaR >= 0 {
+			t.Fatalf("%s stressed VaR = %v (%s), want a loss", result.Scenario, result.StressedVaR, result.Error)
+		}
+		var shares float64
+		for _, line := range result.Positions {
+			if line.StressedVaR == nil {
+				t.Fatalf("%s has no stressed VaR share for %s", result.Scenario, line.Symbol)
+			}
+			shares += *line.StressedVaR
+		}
+		if math.Abs(shares-*result.StressedVaR) > 1e-6 {
+			t.Fatalf("%s shares sum to %v, want %v", result.Scenario, shares, *result.StressedVaR)
+		}
+		vars[i] = *result.StressedVaR
+	}
+	if ratio := vars[1] / vars[0]; math.Abs(ratio-2/1.5) > 1e-6 {
+		t.Fatalf("doubling volatility against +50%% scales VaR by %v, want %v", ratio, 2/1.5)
+	}
+	if report.Results[2].StressedVaR != nil {
+		t.Fatal("a shock without a volatility change shouldn't report stressed VaR")
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/sirupsen/logrus"
+	"trading-service/internal/indicators"
+	"trading-service/internal/models"
+	"trading-service/internal/risk"
+)
+
+// VolatilityHistoryPoint is the annualized volatility a model assigned to
+// the return ending on Date
+type VolatilityHistoryPoint struct {
+	Date       time.Time `json:"date"`
+	Volatility float64   `json:"volatility"`
+}
+
+// VolatilityReport is a fitted volatility model for one symbol
+type VolatilityReport struct {
+	Symbol string `json:"symbol"`
+	*risk.VolatilityForecast
+	Level   string                   `json:"level"` // LOW, MEDIUM or HIGH by the next-bar forecast
+	History []VolatilityHistoryPoint `json:"history"`
+}
+
+// ForecastVolatility fits a volatility model to the daily returns of data
+// and forecasts its term structure
+func (s *AnalysisService) ForecastVolatility(symbol string, data []models.HistoricalData, cfg risk.VolatilityConfig) (*VolatilityReport, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("insufficient data for volatility forecast")
+	}
+	bars := indicators.NewBars(data)
+	forecast, err := risk.ForecastVolatility(indicators.Returns(bars.Close), cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return i runs from bar i to bar i+1; the history is annualized like
+	// the forecast
+	annualize := math.Sqrt(float64(forecast.PeriodsPerYear))
+	report := &VolatilityReport{
+		Symbol:             symbol,
+		VolatilityForecast: forecast,
+		Level:              s.getVolatilityLevel(decimal.NewFromFloat(forecast.Current)),
+		History:            make([]VolatilityHistoryPoint, len(forecast.Conditional)),
+	}
+	for i, sigma := range forecast.Conditional {
+		report.History[i] = VolatilityHistoryPoint{Date: data[i+1].Date, Volatility: sigma * annualize}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"symbol":   symbol,
+		"model":    forecast.Model,
+		"current":  forecast.Current,
+		"realized": forecast.Realized,
+	}).Debug("Volatility forecast calculated")
+
+	return report, nil
+}
+
+// forecastVolatility fits GARCH(1,1) to the returns, falling back to
+// RiskMetrics EWMA when there's too little history for GARCH or its fit
+// fails
+func forecastVolatility(returns []float64) (*risk.VolatilityForecast, error) {
+	if len(returns) >= risk.MinGARCHReturns {
+		if forecast, err := risk.ForecastVolatility(returns, risk.VolatilityConfig{Model: risk.VolatilityGARCH}); err == nil {
+			return forecast, nil
+		}
+	}
+	return risk.ForecastVolatility(returns, risk.VolatilityConfig{Model: risk.VolatilityEWMA, Lambda: risk.RiskMetricsLambda})
+}
+
+// forecastReturns fits the volatility model to each return series and
+// rescales it to the model's forecast over horizon bars, one bar when
+// unset. With no model, or historical, the series come back unchanged and
+// forecasts is nil.
+func forecastReturns(symbols []string, returns [][]float64, model string, horizon int) (rescaled [][]float64, forecasts []*risk.VolatilityForecast, err error) {
+	if model == "" || model == risk.VolatilityHistorical {
+		return returns, nil, nil
+	}
+	if horizon <= 0 {
+		horizon = 1
+	}
+	rescaled = make([][]float64, len(returns))
+	forecasts = make([]*risk.VolatilityForecast, len(returns))
+	for i, series := range returns {
+		forecast, err := risk.ForecastVolatility(series, risk.VolatilityConfig{Model: model, Horizons: []int{horizon}})
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s volatility forecast for %s failed: %v", model, symbols[i], err)
+		}
+		rescaled[i] = forecast.Rescale(series, horizon)
+		forecasts[i] = forecast
+	}
+	return rescaled, forecasts, nil
+}
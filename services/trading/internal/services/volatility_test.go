@@ -0,0 +1,43 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"trading-service/internal/risk"
+)
+
+func TestForecastVolatilityHistoryUnits(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	closes := []float64{100}
+	for i := 0; i < 300; i++ {
+		closes = append(closes, closes[i]*(1+rng.NormFloat64()*0.01))
+	}
+	data := dailyBars("TEST", closes...)
+	service := NewAnalysisService(testLogger())
+
+	for _, periods := range []int{0, 52, 365} {
+		cfg := risk.VolatilityConfig{Model: risk.VolatilityEWMA, Lambda: risk.RiskMetricsLambda, PeriodsPerYear: periods}
+		report, err := service.ForecastVolatility("TEST", data, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := periods
+		if want == 0 {
+			want = 252
+		}
+		if report.PeriodsPerYear != want || len(report.History) != len(data)-1 {
+			t.Fatalf("periods %d: annualized with %d over %d points", periods, report.PeriodsPerYear, len(report.History))
+		}
+		// The history and the forecast share units
+		for i, point := range report.History {
+			if math.Abs(point.Volatility-report.Conditional[i]*math.Sqrt(float64(want))) > 1e-12 {
+				t.Fatalf("periods %d: history %d = %v, want %v annualized over %d bars", periods, i, point.Volatility, report.Conditional[i], want)
+			}
+		}
+		if !report.History[0].Date.Equal(data[1].Date) {
+			t.Fatalf("periods %d: history starts %v, want the first return's end %v", periods, report.History[0].Date, data[1].Date)
+		}
+	}
+}
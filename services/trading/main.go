@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"trading-service/internal/config"
 	"trading-service/internal/handlers"
+	"trading-service/internal/ledger"
 	"trading-service/internal/providers"
 	"trading-service/internal/services"
 )
@@ -36,8 +39,45 @@ func main() {
 
 	// Initialize services
 	marketDataService := services.NewMarketDataService(aggregator, logger)
+	marketDataService.SetHistoryCacheTTL(cfg.Performance.HistoryCacheTTL)
 	analysisService := services.NewAnalysisService(logger)
-	portfolioService := services.NewPortfolioService(logger)
+	analysisService.SetRiskFreeRate(cfg.Trading.RiskFreeRate)
+	analysisService.SetTargetVolatility(cfg.Trading.TargetVolatility)
+	if cfg.Trading.StrategiesDir != "" {
+		analysisService.LoadStrategies(cfg.Trading.StrategiesDir)
+	}
+	if cfg.Trading.ModelPath != "" {
+		if err := analysisService.LoadModel(cfg.Trading.ModelPath); err != nil {
+			logger.WithError(err).WithField("path", cfg.Trading.ModelPath).Warn("Failed to load ML model")
+		}
+	}
+	benchmarkService := services.NewBenchmarkService(marketDataService, logger)
+	portfolioService := services.NewPortfolioService(marketDataService, logger)
+	portfolioService.SetBenchmark(benchmarkService, cfg.Trading.DefaultBenchmark)
+	ledgerService := services.NewLedgerService(marketDataService, logger)
+	if cfg.Trading.LedgerPath != "" {
+		if err := ledgerService.Open(cfg.Trading.LedgerPath); err != nil {
+			logger.WithError(err).WithField("path", cfg.Trading.LedgerPath).Warn("Failed to open transaction ledger; keeping it in memory")
+		}
+	}
+	costBasisMethod := strings.ToLower(cfg.Trading.CostBasisMethod)
+	if !ledger.ValidMethod(costBasisMethod) {
+		logger.WithField("method", cfg.Trading.CostBasisMethod).Warn("Unknown cost basis method; using fifo")
+		costBasisMethod = ledger.MethodFIFO
+	}
+	portfolioService.SetLedger(ledgerService, costBasisMethod)
+	optimizationService := services.NewOptimizationService(analysisService.AlgorithmManager(), logger)
+	signalHistory := services.NewSignalHistoryService(marketDataService, logger)
+	if cfg.Trading.SignalHistoryPath != "" {
+		if err := signalHistory.Open(cfg.Trading.SignalHistoryPath); err != nil {
+			logger.WithError(err).WithField("path", cfg.Trading.SignalHistoryPath).Warn("Failed to open signal history; keeping it in memory")
+		}
+	}
+	stressTestService := services.NewStressTestService(marketDataService, benchmarkService, cfg.Trading.DefaultBenchmark, logger)
+	correlationService := services.NewCorrelationService(marketDataService, logger)
+	scannerService := services.NewScannerService(marketDataService, analysisService, cfg.Performance.WorkerPoolSize, logger)
+	evaluationCtx, stopEvaluation := context.WithCancel(context.Background())
+	go signalHistory.Run(evaluationCtx, cfg.Trading.SignalEvaluationInterval)
 
 	// Initialize WebSocket hub
 	websocketHub := handlers.NewWebSocketHub(logger)
@@ -48,6 +88,13 @@ func main() {
 		marketDataService,
 		analysisService,
 		portfolioService,
+		optimizationService,
+		signalHistory,
+		scannerService,
+		benchmarkService,
+		stressTestService,
+		correlationService,
+		ledgerService,
 		websocketHub,
 		logger,
 	)
@@ -59,7 +106,7 @@ func main() {
 	tradingHandler.SetupRoutes(router)
 
 	// Start market data streaming service (for demo purposes)
-	go startMarketDataStreaming(marketDataService, websocketHub, logger)
+	go startMarketDataStreaming(marketDataService, portfolioService, websocketHub, logger)
 
 	// Start HTTP server
 	server := &http.Server{
@@ -86,6 +133,13 @@ func main() {
 	logger.Info("Shutting down Trading Analysis Service...")
 	
 	// Graceful shutdown implementation would go here
+	stopEvaluation()
+	if err := signalHistory.Close(); err != nil {
+		logger.WithError(err).Warn("Failed to close signal history")
+	}
+	if err := ledgerService.Close(); err != nil {
+		logger.WithError(err).Warn("Failed to close transaction ledger")
+	}
 	logger.Info("Trading Analysis Service stopped")
 }
 
@@ -265,7 +319,7 @@ func rateLimitMiddleware(cfg *config.Config) gin.HandlerFunc {
 }
 
 // startMarketDataStreaming simulates real-time market data streaming
-func startMarketDataStreaming(marketDataService *services.MarketDataService, hub *handlers.WebSocketHub, logger *logrus.Logger) {
+func startMarketDataStreaming(marketDataService *services.MarketDataService, portfolioService *services.PortfolioService, hub *handlers.WebSocketHub, logger *logrus.Logger) {
 	logger.Info("Starting market data streaming service")
 	
 	// Popular symbols to stream
@@ -284,7 +338,8 @@ func startMarketDataStreaming(marketDataService *services.MarketDataService, hub
 			
 			for _, symbol := range symbols {
 				// Only stream data for symbols that have subscribers
-				if subscribers, exists := stats[symbol]; !exists || subscribers == 0 {
+				subscribers, exists := stats[symbol]
+				if !exists || subscribers == 0 {
 					continue
 				}
 
@@ -304,6 +359,13 @@ func startMarketDataStreaming(marketDataService *services.MarketDataService, hub
 					"subscribers": subscribers,
 				}).Debug("Streamed market data")
 			}
+
+			// Revalue subscribed portfolios at the latest prices
+			if portfolioIDs := hub.SubscribedPortfolios(); len(portfolioIDs) > 0 {
+				for _, valuation := range portfolioService.ValuePortfolios(portfolioIDs) {
+					broadcaster.BroadcastPortfolioValuation(valuation)
+				}
+			}
 		}
 	}
 }
\ No newline at end of file